@@ -38,6 +38,8 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/ec2"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/aws/aws-sdk-go/service/eks"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/timewindow"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
 )
 
@@ -255,6 +257,69 @@ func (m *AwsManager) GetAsgOptions(asg asg, defaults config.NodeGroupAutoscaling
 		}
 	}
 
+	if stringOpt, found := options[config.DefaultMaxGracefulTerminationSecKey]; found {
+		if opt, err := strconv.Atoi(stringOpt); err != nil {
+			klog.Warningf("failed to convert asg %s %s tag to int: %v",
+				asg.Name, config.DefaultMaxGracefulTerminationSecKey, err)
+		} else {
+			defaults.MaxGracefulTerminationSec = opt
+		}
+	}
+
+	if stringOpt, found := options[config.DefaultHeadroomNodesKey]; found {
+		if opt, err := strconv.Atoi(stringOpt); err != nil {
+			klog.Warningf("failed to convert asg %s %s tag to int: %v",
+				asg.Name, config.DefaultHeadroomNodesKey, err)
+		} else {
+			defaults.HeadroomNodes = opt
+		}
+	}
+
+	if stringOpt, found := options[config.DefaultHeadroomRatioKey]; found {
+		if opt, err := strconv.ParseFloat(stringOpt, 64); err != nil {
+			klog.Warningf("failed to convert asg %s %s tag to float: %v",
+				asg.Name, config.DefaultHeadroomRatioKey, err)
+		} else {
+			defaults.HeadroomRatio = opt
+		}
+	}
+
+	if stringOpt, found := options[config.DefaultScaleDownWindowKey]; found {
+		if _, err := timewindow.Parse(stringOpt); err != nil {
+			klog.Warningf("failed to parse asg %s %s tag: %v",
+				asg.Name, config.DefaultScaleDownWindowKey, err)
+		} else {
+			defaults.ScaleDownWindow = stringOpt
+		}
+	}
+
+	if stringOpt, found := options[config.DefaultMaxDrainParallelismKey]; found {
+		if opt, err := strconv.Atoi(stringOpt); err != nil {
+			klog.Warningf("failed to convert asg %s %s tag to int: %v",
+				asg.Name, config.DefaultMaxDrainParallelismKey, err)
+		} else {
+			defaults.MaxDrainParallelism = opt
+		}
+	}
+
+	if stringOpt, found := options[config.DefaultMaxPodEvictionsPerMinuteKey]; found {
+		if opt, err := strconv.Atoi(stringOpt); err != nil {
+			klog.Warningf("failed to convert asg %s %s tag to int: %v",
+				asg.Name, config.DefaultMaxPodEvictionsPerMinuteKey, err)
+		} else {
+			defaults.MaxPodEvictionsPerMinute = opt
+		}
+	}
+
+	if stringOpt, found := options[config.DefaultScaleDownUtilizationModeKey]; found {
+		if _, err := utilization.ParseMode(stringOpt); err != nil {
+			klog.Warningf("failed to parse asg %s %s tag: %v",
+				asg.Name, config.DefaultScaleDownUtilizationModeKey, err)
+		} else {
+			defaults.ScaleDownUtilizationMode = stringOpt
+		}
+	}
+
 	return &defaults
 }
 
@@ -134,6 +134,7 @@ func TestGetAsgOptions(t *testing.T) {
 		ScaleDownUnneededTime:            time.Second,
 		ScaleDownUnreadyTime:             time.Minute,
 		IgnoreDaemonSetsUtilization:      false,
+		MaxGracefulTerminationSec:        600,
 	}
 
 	tests := []struct {
@@ -153,6 +154,13 @@ func TestGetAsgOptions(t *testing.T) {
 				config.DefaultScaleDownUnneededTimeKey:         "not-a-duration",
 				"ScaleDownUnreadyTime":                         "",
 				config.DefaultIgnoreDaemonSetsUtilizationKey:   "not-a-bool",
+				config.DefaultMaxGracefulTerminationSecKey:     "not-an-int",
+				config.DefaultHeadroomNodesKey:                 "not-an-int",
+				config.DefaultHeadroomRatioKey:                 "not-a-float",
+				config.DefaultScaleDownWindowKey:               "not-a-window",
+				config.DefaultMaxDrainParallelismKey:           "not-an-int",
+				config.DefaultMaxPodEvictionsPerMinuteKey:      "not-an-int",
+				config.DefaultScaleDownUtilizationModeKey:      "not-a-mode",
 			},
 			expected: &defaultOptions,
 		},
@@ -169,6 +177,7 @@ func TestGetAsgOptions(t *testing.T) {
 				ScaleDownUnneededTime:            time.Hour,
 				ScaleDownUnreadyTime:             defaultOptions.ScaleDownUnreadyTime,
 				IgnoreDaemonSetsUtilization:      true,
+				MaxGracefulTerminationSec:        defaultOptions.MaxGracefulTerminationSec,
 			},
 		},
 		{
@@ -179,6 +188,13 @@ func TestGetAsgOptions(t *testing.T) {
 				config.DefaultScaleDownGpuUtilizationThresholdKey: "0.7",
 				config.DefaultScaleDownUnreadyTimeKey:             "25m",
 				config.DefaultIgnoreDaemonSetsUtilizationKey:      "true",
+				config.DefaultMaxGracefulTerminationSecKey:        "30",
+				config.DefaultHeadroomNodesKey:                    "2",
+				config.DefaultHeadroomRatioKey:                    "0.1",
+				config.DefaultScaleDownWindowKey:                  "* 22-23 * * 1-5",
+				config.DefaultMaxDrainParallelismKey:              "3",
+				config.DefaultMaxPodEvictionsPerMinuteKey:         "60",
+				config.DefaultScaleDownUtilizationModeKey:         "hybrid",
 			},
 			expected: &config.NodeGroupAutoscalingOptions{
 				ScaleDownUtilizationThreshold:    0.42,
@@ -186,6 +202,13 @@ func TestGetAsgOptions(t *testing.T) {
 				ScaleDownUnneededTime:            time.Hour,
 				ScaleDownUnreadyTime:             25 * time.Minute,
 				IgnoreDaemonSetsUtilization:      true,
+				MaxGracefulTerminationSec:        30,
+				HeadroomNodes:                    2,
+				HeadroomRatio:                    0.1,
+				ScaleDownWindow:                  "* 22-23 * * 1-5",
+				MaxDrainParallelism:              3,
+				MaxPodEvictionsPerMinute:         60,
+				ScaleDownUtilizationMode:         "hybrid",
 			},
 		},
 		{
@@ -203,6 +226,7 @@ func TestGetAsgOptions(t *testing.T) {
 				ScaleDownUnneededTime:            time.Minute,
 				ScaleDownUnreadyTime:             time.Hour,
 				IgnoreDaemonSetsUtilization:      false,
+				MaxGracefulTerminationSec:        defaultOptions.MaxGracefulTerminationSec,
 			},
 		},
 	}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodegroupconfigcrd
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+)
+
+// Kind is the kind of the custom resource configuring node groups.
+const Kind = "NodeGroupConfig"
+
+// Resource is the resource of the NodeGroupConfig custom resource definition.
+var Resource = schema.GroupVersionResource{Group: "autoscaling.x-k8s.io", Version: "v1alpha1", Resource: "nodegroupconfigs"}
+
+// NodeGroupConfig configures a node group of the cloud provider.
+type NodeGroupConfig struct {
+	// Name is the name of the custom resource.
+	Name string
+	Spec NodeGroupConfigSpec
+}
+
+// NodeGroupConfigSpec is the spec of a NodeGroupConfig.
+type NodeGroupConfigSpec struct {
+	// NodeGroup is the id of the node group in the cloud provider.
+	NodeGroup string `json:"nodeGroup"`
+	// MinSize overrides the minimum size of the node group, if set.
+	MinSize *int `json:"minSize,omitempty"`
+	// MaxSize overrides the maximum size of the node group, if set.
+	MaxSize *int `json:"maxSize,omitempty"`
+	// Template describes the nodes of the node group, for cloud providers
+	// which can't build template nodes themselves.
+	Template *NodeTemplate `json:"template,omitempty"`
+	// DrainOptions override how nodes of the node group are drained.
+	DrainOptions *DrainOptions `json:"drainOptions,omitempty"`
+}
+
+// NodeTemplate describes the nodes of a node group.
+type NodeTemplate struct {
+	Labels   map[string]string  `json:"labels,omitempty"`
+	Taints   []apiv1.Taint      `json:"taints,omitempty"`
+	Capacity apiv1.ResourceList `json:"capacity,omitempty"`
+	// Allocatable defaults to the capacity.
+	Allocatable apiv1.ResourceList `json:"allocatable,omitempty"`
+}
+
+// DrainOptions override the per node group drain options.
+type DrainOptions struct {
+	MaxGracefulTerminationSec *int `json:"maxGracefulTerminationSec,omitempty"`
+	MaxDrainParallelism       *int `json:"maxDrainParallelism,omitempty"`
+	MaxPodEvictionsPerMinute  *int `json:"maxPodEvictionsPerMinute,omitempty"`
+}
+
+// NewLister returns a lister of NodeGroupConfigs, backed by an informer
+// running until stopChannel is closed.
+func NewLister(client dynamic.Interface, stopChannel <-chan struct{}) cache.GenericLister {
+	informer := dynamicinformer.NewFilteredDynamicInformer(client, Resource, metav1.NamespaceAll, time.Hour, cache.Indexers{}, nil)
+	go informer.Informer().Run(stopChannel)
+	return informer.Lister()
+}
+
+// ListConfigs returns the valid NodeGroupConfigs by node group id. Invalid
+// configs, and configs of node groups already configured by a config with an
+// earlier name, are logged and skipped.
+func ListConfigs(lister cache.GenericLister) (map[string]*NodeGroupConfig, error) {
+	objs, err := lister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var parsed []*NodeGroupConfig
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		config, err := parseConfig(u)
+		if err != nil {
+			klog.Warningf("Invalid %s %s: %v", Kind, u.GetName(), err)
+			continue
+		}
+		parsed = append(parsed, config)
+	}
+	sort.Slice(parsed, func(i, j int) bool { return parsed[i].Name < parsed[j].Name })
+	configs := make(map[string]*NodeGroupConfig, len(parsed))
+	for _, config := range parsed {
+		if other, found := configs[config.Spec.NodeGroup]; found {
+			klog.Warningf("Ignoring %s %s, node group %s is already configured by %s", Kind, config.Name, config.Spec.NodeGroup, other.Name)
+			continue
+		}
+		configs[config.Spec.NodeGroup] = config
+	}
+	return configs, nil
+}
+
+func parseConfig(u *unstructured.Unstructured) (*NodeGroupConfig, error) {
+	specMap, found, err := unstructured.NestedMap(u.Object, "spec")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("spec not set")
+	}
+	config := &NodeGroupConfig{Name: u.GetName()}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specMap, &config.Spec); err != nil {
+		return nil, err
+	}
+	if err := config.Spec.validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (s *NodeGroupConfigSpec) validate() error {
+	if s.NodeGroup == "" {
+		return fmt.Errorf("spec.nodeGroup not set")
+	}
+	if s.MinSize != nil && *s.MinSize < 0 {
+		return fmt.Errorf("spec.minSize %d is negative", *s.MinSize)
+	}
+	if s.MaxSize != nil && *s.MaxSize < 0 {
+		return fmt.Errorf("spec.maxSize %d is negative", *s.MaxSize)
+	}
+	if s.MinSize != nil && s.MaxSize != nil && *s.MinSize > *s.MaxSize {
+		return fmt.Errorf("spec.minSize %d is bigger than spec.maxSize %d", *s.MinSize, *s.MaxSize)
+	}
+	if s.Template != nil && len(s.Template.Capacity) == 0 {
+		return fmt.Errorf("spec.template.capacity not set")
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodegroupconfigcrd
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Reconciler is implemented by cloud providers which reconcile their cloud
+// resources against NodeGroupConfigs, e.g. discover or resize the node groups
+// they describe. Configs are passed before each refresh of the provider.
+type Reconciler interface {
+	ReconcileNodeGroupConfigs(configs map[string]*NodeGroupConfig) error
+}
+
+// cloudProvider overrides the node groups of the wrapped cloud provider with
+// NodeGroupConfigs.
+type cloudProvider struct {
+	cloudprovider.CloudProvider
+	lister cache.GenericLister
+
+	mutex   sync.RWMutex
+	configs map[string]*NodeGroupConfig
+}
+
+// NewCloudProvider wraps the cloud provider, so that its node groups are
+// configured by the NodeGroupConfigs listed by the lister. Configs are
+// reloaded on each refresh of the provider.
+func NewCloudProvider(provider cloudprovider.CloudProvider, lister cache.GenericLister) cloudprovider.CloudProvider {
+	return &cloudProvider{
+		CloudProvider: provider,
+		lister:        lister,
+	}
+}
+
+// NodeGroups returns all node groups configured for this cloud provider.
+func (p *cloudProvider) NodeGroups() []cloudprovider.NodeGroup {
+	nodeGroups := p.CloudProvider.NodeGroups()
+	result := make([]cloudprovider.NodeGroup, 0, len(nodeGroups))
+	for _, nodeGroup := range nodeGroups {
+		result = append(result, p.wrap(nodeGroup))
+	}
+	return result
+}
+
+// NodeGroupForNode returns the node group for the given node.
+func (p *cloudProvider) NodeGroupForNode(node *apiv1.Node) (cloudprovider.NodeGroup, error) {
+	nodeGroup, err := p.CloudProvider.NodeGroupForNode(node)
+	if err != nil || nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+		return nodeGroup, err
+	}
+	return p.wrap(nodeGroup), nil
+}
+
+// Refresh reloads the NodeGroupConfigs, passes them to the wrapped cloud
+// provider if it reconciles them, and refreshes it.
+func (p *cloudProvider) Refresh() error {
+	configs, err := ListConfigs(p.lister)
+	if err != nil {
+		klog.Errorf("Failed to list %ss, using the previous ones: %v", Kind, err)
+	} else {
+		p.mutex.Lock()
+		p.configs = configs
+		p.mutex.Unlock()
+	}
+	if reconciler, ok := p.CloudProvider.(Reconciler); ok {
+		if err := reconciler.ReconcileNodeGroupConfigs(p.getConfigs()); err != nil {
+			klog.Errorf("Failed to reconcile %ss: %v", Kind, err)
+		}
+	}
+	return p.CloudProvider.Refresh()
+}
+
+func (p *cloudProvider) getConfigs() map[string]*NodeGroupConfig {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.configs
+}
+
+func (p *cloudProvider) wrap(nodeGroup cloudprovider.NodeGroup) cloudprovider.NodeGroup {
+	config, found := p.getConfigs()[nodeGroup.Id()]
+	if !found {
+		return nodeGroup
+	}
+	return &configuredNodeGroup{NodeGroup: nodeGroup, config: config}
+}
+
+// configuredNodeGroup is a node group overridden by a NodeGroupConfig.
+type configuredNodeGroup struct {
+	cloudprovider.NodeGroup
+	config *NodeGroupConfig
+}
+
+// MinSize returns the minimum size of the node group.
+func (ng *configuredNodeGroup) MinSize() int {
+	if ng.config.Spec.MinSize != nil {
+		return *ng.config.Spec.MinSize
+	}
+	return ng.NodeGroup.MinSize()
+}
+
+// MaxSize returns the maximum size of the node group.
+func (ng *configuredNodeGroup) MaxSize() int {
+	if ng.config.Spec.MaxSize != nil {
+		return *ng.config.Spec.MaxSize
+	}
+	return ng.NodeGroup.MaxSize()
+}
+
+// TemplateNodeInfo returns the template node of the node group. It's built
+// from the config only if the wrapped node group can't build it.
+func (ng *configuredNodeGroup) TemplateNodeInfo() (*schedulerframework.NodeInfo, error) {
+	nodeInfo, err := ng.NodeGroup.TemplateNodeInfo()
+	if err != cloudprovider.ErrNotImplemented || ng.config.Spec.Template == nil {
+		return nodeInfo, err
+	}
+	template := ng.config.Spec.Template
+	name := fmt.Sprintf("template-node-for-%s-%d", ng.Id(), rand.Int63())
+	node := &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{apiv1.LabelHostname: name},
+		},
+		Spec: apiv1.NodeSpec{
+			Taints: append([]apiv1.Taint(nil), template.Taints...),
+		},
+		Status: apiv1.NodeStatus{
+			Capacity:    template.Capacity.DeepCopy(),
+			Allocatable: template.Capacity.DeepCopy(),
+			Conditions:  cloudprovider.BuildReadyConditions(),
+		},
+	}
+	if len(template.Allocatable) > 0 {
+		node.Status.Allocatable = template.Allocatable.DeepCopy()
+	}
+	for k, v := range template.Labels {
+		node.Labels[k] = v
+	}
+	nodeInfo = schedulerframework.NewNodeInfo(cloudprovider.BuildKubeProxy(ng.Id()))
+	nodeInfo.SetNode(node)
+	return nodeInfo, nil
+}
+
+// GetOptions returns the autoscaling options of the node group, with the
+// drain options of the config applied.
+func (ng *configuredNodeGroup) GetOptions(defaults config.NodeGroupAutoscalingOptions) (*config.NodeGroupAutoscalingOptions, error) {
+	opts, err := ng.NodeGroup.GetOptions(defaults)
+	drain := ng.config.Spec.DrainOptions
+	if drain == nil {
+		return opts, err
+	}
+	if err == cloudprovider.ErrNotImplemented || opts == nil {
+		opts = &defaults
+	} else if err != nil {
+		return opts, err
+	} else {
+		copied := *opts
+		opts = &copied
+	}
+	if drain.MaxGracefulTerminationSec != nil {
+		opts.MaxGracefulTerminationSec = *drain.MaxGracefulTerminationSec
+	}
+	if drain.MaxDrainParallelism != nil {
+		opts.MaxDrainParallelism = *drain.MaxDrainParallelism
+	}
+	if drain.MaxPodEvictionsPerMinute != nil {
+		opts.MaxPodEvictionsPerMinute = *drain.MaxPodEvictionsPerMinute
+	}
+	return opts, nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodegroupconfigcrd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestListConfigs(t *testing.T) {
+	lister := testLister(t,
+		testConfig("a", map[string]interface{}{"nodeGroup": "ng1", "minSize": int64(1), "maxSize": int64(5)}),
+		testConfig("b", map[string]interface{}{"nodeGroup": "ng1", "maxSize": int64(7)}),
+		testConfig("c", map[string]interface{}{"nodeGroup": "ng2", "minSize": int64(5), "maxSize": int64(1)}),
+		testConfig("d", map[string]interface{}{"nodeGroup": "ng3", "minSize": int64(-1)}),
+		testConfig("e", map[string]interface{}{"minSize": int64(1)}),
+		testConfig("f", map[string]interface{}{"nodeGroup": "ng4", "template": map[string]interface{}{"labels": map[string]interface{}{"a": "b"}}}),
+		testConfig("g", map[string]interface{}{"nodeGroup": "ng5", "maxSize": "many"}),
+		testConfig("h", map[string]interface{}{"nodeGroup": "ng6"}),
+	)
+	configs, err := ListConfigs(lister)
+	assert.NoError(t, err)
+	assert.Len(t, configs, 2)
+	assert.Equal(t, "a", configs["ng1"].Name)
+	assert.Equal(t, 5, *configs["ng1"].Spec.MaxSize)
+	assert.Equal(t, "h", configs["ng6"].Name)
+}
+
+func TestCloudProvider(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 1)
+	provider.AddNodeGroup("ng2", 1, 10, 1)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	provider.AddNode("ng1", n1)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	provider.AddNode("ng2", n2)
+
+	lister := testLister(t, testConfig("config", map[string]interface{}{
+		"nodeGroup": "ng1",
+		"minSize":   int64(0),
+		"maxSize":   int64(3),
+		"template": map[string]interface{}{
+			"labels":   map[string]interface{}{"pool": "fast"},
+			"taints":   []interface{}{map[string]interface{}{"key": "dedicated", "value": "fast", "effect": "NoSchedule"}},
+			"capacity": map[string]interface{}{"cpu": "4", "memory": "16Gi", "pods": "110"},
+		},
+		"drainOptions": map[string]interface{}{"maxGracefulTerminationSec": int64(30), "maxDrainParallelism": int64(2)},
+	}))
+	wrapped := NewCloudProvider(provider, lister)
+	assert.NoError(t, wrapped.Refresh())
+
+	nodeGroups := wrapped.NodeGroups()
+	assert.Len(t, nodeGroups, 2)
+	for _, nodeGroup := range nodeGroups {
+		switch nodeGroup.Id() {
+		case "ng1":
+			assert.Equal(t, 0, nodeGroup.MinSize())
+			assert.Equal(t, 3, nodeGroup.MaxSize())
+		case "ng2":
+			assert.Equal(t, 1, nodeGroup.MinSize())
+			assert.Equal(t, 10, nodeGroup.MaxSize())
+		}
+	}
+
+	nodeGroup, err := wrapped.NodeGroupForNode(n1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, nodeGroup.MaxSize())
+
+	nodeInfo, err := nodeGroup.TemplateNodeInfo()
+	assert.NoError(t, err)
+	node := nodeInfo.Node()
+	assert.Equal(t, "fast", node.Labels["pool"])
+	assert.Equal(t, []apiv1.Taint{{Key: "dedicated", Value: "fast", Effect: apiv1.TaintEffectNoSchedule}}, node.Spec.Taints)
+	assert.Equal(t, int64(4000), node.Status.Allocatable.Cpu().MilliValue())
+	assert.Len(t, nodeInfo.Pods, 1)
+
+	defaults := config.NodeGroupAutoscalingOptions{MaxGracefulTerminationSec: 600, MaxDrainParallelism: 1, MaxPodEvictionsPerMinute: 60}
+	opts, err := nodeGroup.GetOptions(defaults)
+	assert.NoError(t, err)
+	assert.Equal(t, config.NodeGroupAutoscalingOptions{MaxGracefulTerminationSec: 30, MaxDrainParallelism: 2, MaxPodEvictionsPerMinute: 60}, *opts)
+	assert.Equal(t, 600, defaults.MaxGracefulTerminationSec)
+
+	nodeGroup, err = wrapped.NodeGroupForNode(n2)
+	assert.NoError(t, err)
+	_, err = nodeGroup.TemplateNodeInfo()
+	assert.Equal(t, cloudprovider.ErrNotImplemented, err)
+}
+
+type reconcilingCloudProvider struct {
+	*testprovider.TestCloudProvider
+	configs map[string]*NodeGroupConfig
+}
+
+func (p *reconcilingCloudProvider) ReconcileNodeGroupConfigs(configs map[string]*NodeGroupConfig) error {
+	p.configs = configs
+	return nil
+}
+
+func TestRefreshReconciles(t *testing.T) {
+	provider := &reconcilingCloudProvider{TestCloudProvider: testprovider.NewTestCloudProvider(nil, nil)}
+	lister := testLister(t, testConfig("config", map[string]interface{}{"nodeGroup": "ng1", "maxSize": int64(3)}))
+	assert.NoError(t, NewCloudProvider(provider, lister).Refresh())
+	assert.Len(t, provider.configs, 1)
+	assert.Equal(t, "config", provider.configs["ng1"].Name)
+}
+
+func testLister(t *testing.T, configs ...*unstructured.Unstructured) cache.GenericLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, config := range configs {
+		assert.NoError(t, indexer.Add(config))
+	}
+	return cache.NewGenericLister(indexer, Resource.GroupResource())
+}
+
+func testConfig(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetAPIVersion(Resource.GroupVersion().String())
+	u.SetKind(Kind)
+	u.SetName(name)
+	return u
+}
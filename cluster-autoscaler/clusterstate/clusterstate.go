@@ -449,6 +449,29 @@ func (csr *ClusterStateRegistry) IsNodeGroupSafeToScaleUp(nodeGroup cloudprovide
 	return !csr.backoff.IsBackedOff(nodeGroup, csr.nodeInfosForGroups[nodeGroup.Id()], now)
 }
 
+// BackoffEntries returns the current backoffs of node groups, or nil if the
+// backoff can't be persisted.
+func (csr *ClusterStateRegistry) BackoffEntries() []backoff.Entry {
+	csr.Lock()
+	defer csr.Unlock()
+	if persistable, ok := csr.backoff.(backoff.Persistable); ok {
+		return persistable.Entries()
+	}
+	return nil
+}
+
+// RestoreBackoff restores backoffs of node groups, e.g. saved by a previous
+// run of Cluster Autoscaler. It returns false if the backoff can't be restored.
+func (csr *ClusterStateRegistry) RestoreBackoff(entries []backoff.Entry) bool {
+	csr.Lock()
+	defer csr.Unlock()
+	persistable, ok := csr.backoff.(backoff.Persistable)
+	if ok {
+		persistable.Restore(entries)
+	}
+	return ok
+}
+
 func (csr *ClusterStateRegistry) getProvisionedAndTargetSizesForNodeGroup(nodeGroupName string) (provisioned, target int, ok bool) {
 	if len(csr.acceptableRanges) == 0 {
 		klog.Warningf("AcceptableRanges have not been populated yet. Skip checking")
@@ -52,6 +52,30 @@ type NodeGroupAutoscalingOptions struct {
 	ZeroOrMaxNodeScaling bool
 	// IgnoreDaemonSetsUtilization sets if daemonsets utilization should be considered during node scale-down
 	IgnoreDaemonSetsUtilization bool
+	// NodeReadinessTimeout is the maximum time CA waits for a new node to pass readiness gates before replacing it.
+	// Zero disables readiness gates for the node group.
+	NodeReadinessTimeout time.Duration
+	// MaxGracefulTerminationSec is maximum number of seconds scale down waits for pods to terminate before
+	// removing the node from the node group. Zero means the global MaxGracefulTerminationSec is used.
+	MaxGracefulTerminationSec int
+	// HeadroomNodes is the number of spare nodes kept in the node group, by simulating placeholder pods
+	// requesting the whole capacity of a node.
+	HeadroomNodes int
+	// HeadroomRatio is the spare capacity kept in the node group, as a fraction of its target size. The
+	// number of spare nodes is rounded up, and the larger of HeadroomNodes and HeadroomRatio is used.
+	HeadroomRatio float64
+	// ScaleDownWindow restricts removal of nodes from the node group to the time windows described by
+	// cron-like expressions, see the timewindow package. Empty means nodes can be removed at any time.
+	ScaleDownWindow string
+	// MaxDrainParallelism is the maximum number of nodes needing drain from the node group that can be drained
+	// and deleted in parallel. Zero means only the global MaxDrainParallelism applies.
+	MaxDrainParallelism int
+	// MaxPodEvictionsPerMinute is the maximum number of pods evicted from nodes of the node group within a
+	// minute. Zero means only the global MaxPodEvictionsPerMinute applies.
+	MaxPodEvictionsPerMinute int
+	// ScaleDownUtilizationMode selects how utilization of nodes of the node group is calculated for scale-down:
+	// "requests" (the default), "usage" or "hybrid", see the utilization package.
+	ScaleDownUtilizationMode string
 }
 
 // GCEOptions contain autoscaling options specific to GCE cloud provider.
@@ -111,8 +135,20 @@ type AutoscalingOptions struct {
 	MinMemoryTotal int64
 	// GpuTotal is a list of strings with configuration of min/max limits for different GPUs.
 	GpuTotal []GpuLimits
+	// ScaleDownCPUReserveRatio is the fraction of allocatable CPU in the cluster which has to stay unrequested by pods
+	// after scale down. 0 disables the reserve.
+	ScaleDownCPUReserveRatio float64
+	// ScaleDownMemoryReserveRatio is the fraction of allocatable memory in the cluster which has to stay unrequested by
+	// pods after scale down. 0 disables the reserve.
+	ScaleDownMemoryReserveRatio float64
+	// ScaleDownReservePerZone tells if the scale down reserves have to be kept in each zone, in addition to the whole
+	// cluster.
+	ScaleDownReservePerZone bool
 	// NodeGroupAutoDiscovery represents one or more definition(s) of node group auto-discovery
 	NodeGroupAutoDiscovery []string
+	// NodeGroupConfigsEnabled tells if node groups of the cloud provider are configured by NodeGroupConfig custom
+	// resources, overriding their size limits, template and drain options.
+	NodeGroupConfigsEnabled bool
 	// EstimatorName is the estimator used to estimate the number of needed nodes in scale up.
 	EstimatorName string
 	// ExpanderNames sets the chain of node group expanders to be used in scale up
@@ -121,11 +157,16 @@ type AutoscalingOptions struct {
 	GRPCExpanderCert string
 	// GRPCExpanderURL is the url of the gRPC server when using the gRPC expander
 	GRPCExpanderURL string
+	// GRPCExpanderStreaming tells if the gRPC expander streams the details of the options to the gRPC server, which can reply with capacity hints
+	GRPCExpanderStreaming bool
 	// IgnoreMirrorPodsUtilization is whether CA will ignore Mirror pods when calculating resource utilization for scaling down
 	IgnoreMirrorPodsUtilization bool
 	// MaxGracefulTerminationSec is maximum number of seconds scale down waits for pods to terminate before
 	// removing the node from cloud provider.
 	MaxGracefulTerminationSec int
+	// WindowsMaxGracefulTerminationSec replaces MaxGracefulTerminationSec on Windows nodes. Zero means
+	// MaxGracefulTerminationSec is used on all nodes.
+	WindowsMaxGracefulTerminationSec int
 	// MaxTotalUnreadyPercentage is the maximum percentage of unready nodes after which CA halts operations
 	MaxTotalUnreadyPercentage float64
 	// OkTotalUnreadyCount is the number of allowed unready nodes, irrespective of max-total-unready-percentage
@@ -177,8 +218,14 @@ type AutoscalingOptions struct {
 	WriteStatusConfigMap bool
 	// StaticConfigMapName
 	StatusConfigMapName string
+	// PersistentStateConfigMapName is the name of the ConfigMap in which node deletions in progress and node group
+	// backoffs are saved, so that they are restored after a restart. The state isn't persisted if empty.
+	PersistentStateConfigMapName string
 	// BalanceSimilarNodeGroups enables logic that identifies node groups with similar machines and tries to balance node count between them.
 	BalanceSimilarNodeGroups bool
+	// ValidateBalancedScaleUp enables scheduling the pods triggering a scale-up balanced between similar node groups in
+	// simulation, and scaling up only the best node group if more of the pods fit it.
+	ValidateBalancedScaleUp bool
 	// ConfigNamespace is the namespace cluster-autoscaler is running in and all related configmaps live in
 	ConfigNamespace string
 	// ClusterName if available
@@ -189,6 +236,18 @@ type AutoscalingOptions struct {
 	MaxAutoprovisionedNodeGroupCount int
 	// UnremovableNodeRecheckTimeout is the timeout before we check again a node that couldn't be removed before
 	UnremovableNodeRecheckTimeout time.Duration
+	// UnremovableNodeStateCacheEnabled tells if unremovable nodes should be re-checked as soon as they or their pods
+	// change, and nodes blocked by pods for reasons depending only on the pods themselves shouldn't be re-checked
+	// until then, or until UnremovableNodeRecheckMaxTimeout passes.
+	UnremovableNodeStateCacheEnabled bool
+	// UnremovableNodeRecheckMaxTimeout is the maximum timeout before checking again a node that couldn't be removed
+	// before. The timeout starts at UnremovableNodeRecheckTimeout and doubles each time the node is found unremovable
+	// again, until the node or its pods change if UnremovableNodeStateCacheEnabled is set. Disabled if not above
+	// UnremovableNodeRecheckTimeout.
+	UnremovableNodeRecheckMaxTimeout time.Duration
+	// DrainabilityEvaluationParallelism is the maximum number of nodes for which drainability of pods is evaluated
+	// concurrently during scale down simulation. Drainability is evaluated sequentially if lower than 2.
+	DrainabilityEvaluationParallelism int
 	// Pods with priority below cutoff are expendable. They can be killed without any consideration during scale down and they don't cause scale-up.
 	// Pods with null priority (PodPriority disabled) are non-expendable.
 	ExpendablePodsPriorityCutoff int
@@ -196,6 +255,13 @@ type AutoscalingOptions struct {
 	Regional bool
 	// Pods newer than this will not be considered as unschedulable for scale-up.
 	NewPodScaleUpDelay time.Duration
+	// ScaleUpPreemptionPolicy determines how pending pods which could be scheduled on existing nodes by preempting
+	// lower priority pods are treated by scale-up: "None" doesn't simulate preemption, "Skip" doesn't trigger scale-up
+	// for such pods and "Delay" doesn't trigger it until they are pending for ScaleUpPreemptionDelay.
+	ScaleUpPreemptionPolicy string
+	// ScaleUpPreemptionDelay is how long pods which could preempt lower priority pods are given to do so before
+	// triggering scale-up, with the "Delay" ScaleUpPreemptionPolicy.
+	ScaleUpPreemptionDelay time.Duration
 	// MaxBulkSoftTaint sets the maximum number of nodes that can be (un)tainted PreferNoSchedule during single scaling down run.
 	// Value of 0 turns turn off such tainting.
 	MaxBulkSoftTaintCount int
@@ -232,10 +298,19 @@ type AutoscalingOptions struct {
 	ClusterAPICloudConfigAuthoritative bool
 	// Enable or disable cordon nodes functionality before terminating the node during downscale process
 	CordonNodeBeforeTerminate bool
+	// ScaleDownCordonOnly tells if nodes chosen for scale down should only be cordoned, tainted and reported with
+	// NodeScaleDownReady events, leaving their drain and deletion to an external system.
+	ScaleDownCordonOnly bool
+	// MaxCordonedNodeDeletionTime is the maximum time an external system has to delete a node cordoned in cordon-only
+	// mode. Afterwards, the node is untainted and uncordoned and its deletion fails. There is no limit if it is 0.
+	MaxCordonedNodeDeletionTime time.Duration
 	// DaemonSetEvictionForEmptyNodes is whether CA will gracefully terminate DaemonSet pods from empty nodes.
 	DaemonSetEvictionForEmptyNodes bool
 	// DaemonSetEvictionForOccupiedNodes is whether CA will gracefully terminate DaemonSet pods from non-empty nodes.
 	DaemonSetEvictionForOccupiedNodes bool
+	// RespectDaemonSetPdbs is whether DaemonSet pods are subject to the pod disruption budgets matching them during
+	// scale down, like other pods.
+	RespectDaemonSetPdbs bool
 	// User agent to use for HTTP calls.
 	UserAgent string
 	// InitialNodeGroupBackoffDuration is the duration of first backoff after a new node failed to start
@@ -248,6 +323,9 @@ type AutoscalingOptions struct {
 	MaxScaleDownParallelism int
 	// MaxDrainParallelism is the maximum number of nodes needing drain, that can be drained and deleted in parallel.
 	MaxDrainParallelism int
+	// MaxPodEvictionsPerMinute is the maximum number of pods evicted from all nodes being drained within a minute.
+	// Zero means evictions aren't rate limited.
+	MaxPodEvictionsPerMinute int
 	// RecordDuplicatedEvents controls whether events should be duplicated within a 5 minute window.
 	RecordDuplicatedEvents bool
 	// MaxNodesPerScaleUp controls how many nodes can be added in a single scale-up.
@@ -259,15 +337,185 @@ type AutoscalingOptions struct {
 	MaxNodeGroupBinpackingDuration time.Duration
 	// NodeDeletionBatcherInterval is a time for how long CA ScaleDown gather nodes to delete them in batch.
 	NodeDeletionBatcherInterval time.Duration
+	// MaxEmptyNodeDeletionBatchSize is the maximum number of empty nodes from the same node group deleted with a single
+	// cloud provider call. Values lower than 2 delete empty nodes one at a time.
+	MaxEmptyNodeDeletionBatchSize int
 	// SkipNodesWithSystemPods tells if nodes with pods from kube-system should be deleted (except for DaemonSet or mirror pods)
 	SkipNodesWithSystemPods bool
+	// SystemPodNamespaces are namespaces, e.g. monitoring or istio-system, whose pods are treated like pods from
+	// kube-system in scale down, in addition to kube-system itself
+	SystemPodNamespaces []string
 	// SkipNodesWithLocalStorage tells if nodes with pods with local storage, e.g. EmptyDir or HostPath, should be deleted
 	SkipNodesWithLocalStorage bool
+	// LocalPersistentVolumesDrainPolicy tells how pods using persistent volumes bound to their node, e.g. local
+	// persistent volumes, are handled in scale down: "Ignore", "Warn" (log, but don't block) or "Block".
+	LocalPersistentVolumesDrainPolicy string
+	// PinnedPodDrainPolicy tells how pods whose node selector or required node affinity doesn't match any other node
+	// are handled in scale down: "Ignore" (simulate them like other pods) or "Block" (block with a distinct reason).
+	PinnedPodDrainPolicy string
+	// SacrificablePinnedPodSelector is a label selector of pods pinned to their node which are evicted with the node
+	// on scale down without being rescheduled. No pinned pods are sacrificed if empty.
+	SacrificablePinnedPodSelector string
+	// SingletonPodSelectors are label selectors of singleton infrastructure pods, e.g. CSI controllers, which block
+	// drain of their node unless another pod matching the same selector, in the same namespace, is running and ready
+	// on another node.
+	SingletonPodSelectors []string
+	// MaxRolloutDrainDelay is the maximum time pods of a ReplicaSet being replaced by a newer ReplicaSet of the same
+	// Deployment block drain of their node while the newer ReplicaSet doesn't have all of its replicas available,
+	// counted from its creation. Disabled if 0.
+	MaxRolloutDrainDelay time.Duration
+	// InitialEvictionFailureBackoff is how long pods whose eviction failed during scale down block drain of their node,
+	// doubling with each consecutive failure. Disabled if 0.
+	InitialEvictionFailureBackoff time.Duration
+	// MaxEvictionFailureBackoff is the maximum time pods whose evictions failed block drain of their node.
+	MaxEvictionFailureBackoff time.Duration
+	// DebugContainerDrainMaxAge is how long a running ephemeral container, e.g. a kubectl debug session, blocks scale
+	// down of its node. Ephemeral containers running for longer are considered abandoned. Disabled if 0.
+	DebugContainerDrainMaxAge time.Duration
+	// WriteScaleDownCandidatesResource tells if unneeded and unremovable nodes should be written to a
+	// ScaleDownCandidates custom resource each loop.
+	WriteScaleDownCandidatesResource bool
+	// AuditLogSink is where audit records of scaling decisions are written, either "stdout", "file:<path>" or a
+	// webhook URL. Decisions aren't audited if empty.
+	AuditLogSink string
+	// NodeReadinessTaints are keys of taints which have to be removed from a new node before it is treated as ready.
+	NodeReadinessTaints []string
+	// NodeReadinessConditions are types of node conditions which have to be True on a new node before it is treated as ready.
+	NodeReadinessConditions []string
+	// NodeReadinessPodSelectors are label selectors of pods, one of each has to be running and ready on a new node
+	// before it is treated as ready.
+	NodeReadinessPodSelectors []string
+	// NodeAutoRepairEnabled tells if persistently unhealthy nodes should be drained and replaced.
+	NodeAutoRepairEnabled bool
+	// NodeAutoRepairUnhealthyTime is how long a node has to be NotReady, or report one of NodeAutoRepairConditions,
+	// before it is repaired.
+	NodeAutoRepairUnhealthyTime time.Duration
+	// NodeAutoRepairConditions are types of node conditions, e.g. reported by node-problem-detector, which make a node
+	// unhealthy when True.
+	NodeAutoRepairConditions []string
+	// MaxNodeAutoRepairs is the maximum number of unhealthy nodes whose repair is started in one loop.
+	MaxNodeAutoRepairs int
+	// EnableProvisioningRequests tells if ProvisioningRequests should be processed, scaling up for each of them
+	// all-or-nothing.
+	EnableProvisioningRequests bool
+	// WriteNodeGroupResizeRecommendations tells if NodeGroupResizeRecommendation custom resources should be written
+	// for node groups whose nodes could be replaced with nodes of a smaller machine type.
+	WriteNodeGroupResizeRecommendations bool
+	// NodeGroupResizeUtilizationThreshold is the utilization below which all nodes of a node group have to be for a
+	// smaller machine type to be recommended.
+	NodeGroupResizeUtilizationThreshold float64
+	// NodeGroupResizeRecommendationDelay is how long all nodes of a node group have to stay underutilized before a
+	// smaller machine type is recommended.
+	NodeGroupResizeRecommendationDelay time.Duration
+	// DynamicResourceAllocationEnabled tells if DRA resource claims of pods should be taken into account, so that
+	// pods are only simulated to fit on nodes providing their devices and nodes providing in-use devices aren't removed.
+	DynamicResourceAllocationEnabled bool
 	// SkipNodesWithCustomControllerPods tells if nodes with custom-controller owned pods should be skipped from deletion (skip if 'true')
 	SkipNodesWithCustomControllerPods bool
+	// CustomControllerScaleDiscovery tells if pods owned by custom controllers should be treated as replicated, despite
+	// SkipNodesWithCustomControllerPods, if their controller implements the scale subresource and has more than 1 replica.
+	CustomControllerScaleDiscovery bool
+	// SkipNodesWithStaticPods tells if nodes with kubelet static pods should be skipped from deletion, unless the
+	// static pods are annotated with cluster-autoscaler.kubernetes.io/static-pod-drainable: "true".
+	SkipNodesWithStaticPods bool
 	// MinReplicaCount controls the minimum number of replicas that a replica set or replication controller should have
 	// to allow their pods deletion in scale down
 	MinReplicaCount int
+	// DrainabilityNamespacesConfigMapName is the name of the ConfigMap in ConfigNamespace listing namespaces whose pods
+	// always or never block scale down. Namespace drainability overrides are disabled if empty.
+	DrainabilityNamespacesConfigMapName string
+	// DrainOptionsConfigMapName is the name of the ConfigMap in ConfigNamespace from which the skip flags, the
+	// minimum replica count and the default utilization thresholds are reloaded at runtime. Disabled if empty.
+	DrainOptionsConfigMapName string
+	// DrainabilityOverrideNamespaces are namespaces in which DrainabilityOverride custom resources are honored, making
+	// the pods selected by them drainable. Drainability overrides are disabled if empty.
+	DrainabilityOverrideNamespaces []string
+	// DrainabilityShadowRules are names of drainability rules evaluated in shadow mode: their outcomes are reported by
+	// metrics, but never enforced.
+	DrainabilityShadowRules []string
+	// DrainabilityPolicyName is the name of the AutoscalerDrainPolicy custom resource configuring drainability rules
+	// on top of the flags, reconciled without restarts. Drainability policies are disabled if empty.
+	DrainabilityPolicyName string
+	// DrainabilityWebhookURL is the URL of a webhook deciding about drainability of pods. The webhook is disabled if empty.
+	DrainabilityWebhookURL string
+	// DrainabilityWebhookTimeout is the timeout of a single drainability webhook call.
+	DrainabilityWebhookTimeout time.Duration
+	// DrainabilityWebhookFailurePolicy tells how drainability webhook errors are handled: "Ignore" leaves the decision to
+	// the remaining drainability rules, "Fail" blocks drain of the pod.
+	DrainabilityWebhookFailurePolicy string
+	// DrainabilityWebhookCacheTTL is how long drainability webhook responses are reused for unchanged pods.
+	DrainabilityWebhookCacheTTL time.Duration
+	// RecordScaleDownBlockingPods tells if events should be emitted for nodes blocked by pods, and for the blocking
+	// pods, and if blocked nodes should be annotated with the blocking pod.
+	RecordScaleDownBlockingPods bool
+	// LongTerminatingPodThreshold is the time after which a pod that has run over its termination grace period is
+	// ignored by scale down, i.e. it doesn't count towards node utilization and doesn't block node removal.
+	LongTerminatingPodThreshold time.Duration
+	// DrainMode determines how pods are removed from nodes during scale down: "Evict" uses the eviction subresource
+	// only, "EvictOrDelete" falls back to deleting pods whose evictions are persistently denied by admission
+	// webhooks.
+	DrainMode string
+	// NodeDrainTimeout is the maximum time to wait for evicted pods to terminate when draining a node. Pods remaining
+	// afterwards are force deleted, and nodes expected to drain faster are preferred for scale down. If 0, pods are
+	// awaited for their drain grace period plus headroom and the drain fails if any of them remain.
+	NodeDrainTimeout time.Duration
+	// WebhookDenialPolicy determines how evictions denied by validating admission webhooks are handled during scale
+	// down: "Retry" retries them like other failed evictions, "SkipNode" aborts drain of the node on the first denial
+	// and "ForceDelete" deletes pods whose evictions keep being denied for WebhookDenialTimeout.
+	WebhookDenialPolicy string
+	// WebhookDenialTimeout is how long evictions of a pod have to be denied by admission webhooks before the pod is
+	// deleted, if the "ForceDelete" WebhookDenialPolicy is used.
+	WebhookDenialTimeout time.Duration
+	// DrainWaitForRescheduling tells if evictions of pods with at least DrainCriticalPodPriority should start only once
+	// the lower-priority pods evicted from the node before them have been rescheduled.
+	DrainWaitForRescheduling bool
+	// DrainCriticalPodPriority is the lowest priority of pods which are evicted only once lower-priority pods have
+	// been rescheduled, if DrainWaitForRescheduling is set.
+	DrainCriticalPodPriority int
+	// OneOffPodMaxLifetime is how long unreplicated pods with restartPolicy Never or OnFailure block node drain.
+	// Afterwards they are removed like replicated pods. If 0, they block node drain like other unreplicated pods.
+	OneOffPodMaxLifetime time.Duration
+	// DrainStatefulSetsInOrdinalOrder tells if pods of the same StatefulSet on a drained node should be evicted one at
+	// a time in reverse ordinal order, waiting for replacements to be ready in between for OrderedReady StatefulSets.
+	DrainStatefulSetsInOrdinalOrder bool
+	// DrainCancellationDelay is how long pods have to be pending, while they would fit on a node being drained if it
+	// wasn't being drained, for the drain of the node to be cancelled. Drains are never cancelled if 0.
+	DrainCancellationDelay time.Duration
+	// EvictionDryRunPreflight tells if dry-run evictions of pods should be issued before draining their nodes, so that
+	// nodes whose drains would be rejected by disruption budgets or admission webhooks aren't drained.
+	EvictionDryRunPreflight bool
+	// GuidedDrain tells if pods replacing the pods evicted from drained nodes should be nominated to the nodes the
+	// evicted pods were simulated to move to, so that they are rescheduled as scale down simulation expected.
+	GuidedDrain bool
+	// ScaleDownRecordingFile is the path of a file the state of the cluster is written to before each scale-down
+	// simulation, so that the simulation can be replayed offline. Recording is disabled if empty.
+	ScaleDownRecordingFile string
+	// EnableScaleDownConsolidation tells if replacing several underutilized nodes with a single larger node from a
+	// different node group should be simulated. Experimental: consolidation opportunities are only logged, nodes
+	// aren't replaced.
+	EnableScaleDownConsolidation bool
+	// ScaleDownConsolidationMaxNodes is the maximum number of underutilized nodes considered for replacement with a
+	// single larger node from a different node group. Consolidation is disabled if lower than 2.
+	ScaleDownConsolidationMaxNodes int
+	// ScaleDownCandidateOrder is the order in which removable nodes are scaled down: "Default" leaves the order to
+	// ScaleDownScorers, "MostExpensiveFirst" adds the Price scorer, weighted as much as all other scorers together, to
+	// remove the most expensive nodes first.
+	ScaleDownCandidateOrder string
+	// ScaleDownScorers are scorers deciding the order in which removable nodes are scaled down, as <name>[:<weight>].
+	// Nodes with the highest weighted sum of scores are scaled down first. If empty, the default scorers are used.
+	ScaleDownScorers []string
+	// ScaleDownDisabledTaints are taints, as <key>[:<effect>], marking nodes as not eligible for scale down, in
+	// addition to the scale-down-disabled annotation.
+	ScaleDownDisabledTaints []string
+	// ScaleDownDisabledNodeSelectors are node label selectors marking nodes as not eligible for scale down, in
+	// addition to the scale-down-disabled annotation.
+	ScaleDownDisabledNodeSelectors []string
+	// ScaleDownPreferredTaints are taints, as <key>[:<effect>], marking nodes which should be scaled down before
+	// other removable nodes.
+	ScaleDownPreferredTaints []string
+	// ScaleDownPreferredNodeSelectors are node label selectors marking nodes which should be scaled down before
+	// other removable nodes.
+	ScaleDownPreferredNodeSelectors []string
 	// NodeDeleteDelayAfterTaint is the duration to wait before deleting a node after tainting it
 	NodeDeleteDelayAfterTaint time.Duration
 	// ParallelDrain is whether CA can drain nodes in parallel.
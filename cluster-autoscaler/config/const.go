@@ -40,6 +40,20 @@ const (
 	DefaultMaxNodeProvisionTimeKey = "maxnodeprovisiontime"
 	// DefaultIgnoreDaemonSetsUtilizationKey identifies IgnoreDaemonSetsUtilization autoscaling option
 	DefaultIgnoreDaemonSetsUtilizationKey = "ignoredaemonsetsutilization"
+	// DefaultMaxGracefulTerminationSecKey identifies MaxGracefulTerminationSec autoscaling option
+	DefaultMaxGracefulTerminationSecKey = "maxgracefulterminationsec"
+	// DefaultHeadroomNodesKey identifies HeadroomNodes autoscaling option
+	DefaultHeadroomNodesKey = "headroomnodes"
+	// DefaultHeadroomRatioKey identifies HeadroomRatio autoscaling option
+	DefaultHeadroomRatioKey = "headroomratio"
+	// DefaultScaleDownWindowKey identifies ScaleDownWindow autoscaling option
+	DefaultScaleDownWindowKey = "scaledownwindow"
+	// DefaultMaxDrainParallelismKey identifies MaxDrainParallelism autoscaling option
+	DefaultMaxDrainParallelismKey = "maxdrainparallelism"
+	// DefaultMaxPodEvictionsPerMinuteKey identifies MaxPodEvictionsPerMinute autoscaling option
+	DefaultMaxPodEvictionsPerMinuteKey = "maxpodevictionsperminute"
+	// DefaultScaleDownUtilizationModeKey identifies ScaleDownUtilizationMode autoscaling option
+	DefaultScaleDownUtilizationModeKey = "scaledownutilizationmode"
 	// DefaultScaleDownUnneededTime identifies ScaleDownUnneededTime autoscaling option
 	DefaultScaleDownUnneededTime = 10 * time.Minute
 	// DefaultScaleDownUnreadyTime identifies ScaleDownUnreadyTime autoscaling option
@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+)
+
+// NodeDeleteOptions returns node delete options extracted from autoscaling
+// options. The conversion lives here rather than in the options package, so
+// that the drainability module doesn't depend on this package.
+func (o AutoscalingOptions) NodeDeleteOptions() options.NodeDeleteOptions {
+	return options.NodeDeleteOptions{
+		SkipNodesWithSystemPods:               o.SkipNodesWithSystemPods,
+		SystemPodNamespaces:                   o.SystemPodNamespaces,
+		SkipNodesWithLocalStorage:             o.SkipNodesWithLocalStorage,
+		SkipNodesWithCustomControllerPods:     o.SkipNodesWithCustomControllerPods,
+		SkipNodesWithStaticPods:               o.SkipNodesWithStaticPods,
+		MinReplicaCount:                       o.MinReplicaCount,
+		MaxGracefulTerminationSec:             o.MaxGracefulTerminationSec,
+		WindowsMaxGracefulTerminationSec:      o.WindowsMaxGracefulTerminationSec,
+		LongTerminatingPodThreshold:           o.LongTerminatingPodThreshold,
+		DrainMode:                             options.DrainMode(o.DrainMode),
+		NodeDrainTimeout:                      o.NodeDrainTimeout,
+		WebhookDenialPolicy:                   options.WebhookDenialPolicy(o.WebhookDenialPolicy),
+		WebhookDenialTimeout:                  o.WebhookDenialTimeout,
+		WaitForReschedulingBeforeCriticalPods: o.DrainWaitForRescheduling,
+		CriticalPodPriority:                   int32(o.DrainCriticalPodPriority),
+		OneOffPodMaxLifetime:                  o.OneOffPodMaxLifetime,
+		StatefulSetOrdinalOrder:               o.DrainStatefulSetsInOrdinalOrder,
+		RespectDaemonSetPdbs:                  o.RespectDaemonSetPdbs,
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reload
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	kube_record "k8s.io/client-go/tools/record"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// ConfigMapKey is the key of the ConfigMap with the reloaded options.
+	ConfigMapKey = "options"
+	// DrainPolicyChangedReason is the reason of events emitted on the
+	// ConfigMap when the effective drain policy changes.
+	DrainPolicyChangedReason = "DrainPolicyChanged"
+	// InvalidDrainPolicyReason is the reason of events emitted on the
+	// ConfigMap when its update is ignored because it's invalid.
+	InvalidDrainPolicyReason = "InvalidDrainPolicy"
+)
+
+// Config lists the options which can be reloaded at runtime. Options which
+// aren't set keep the values configured by flags.
+type Config struct {
+	SkipNodesWithSystemPods          *bool    `yaml:"skipNodesWithSystemPods"`
+	SkipNodesWithLocalStorage        *bool    `yaml:"skipNodesWithLocalStorage"`
+	MinReplicaCount                  *int     `yaml:"minReplicaCount"`
+	ScaleDownUtilizationThreshold    *float64 `yaml:"scaleDownUtilizationThreshold"`
+	ScaleDownGpuUtilizationThreshold *float64 `yaml:"scaleDownGpuUtilizationThreshold"`
+}
+
+// Reloader reloads the options affecting drain of nodes in scale down from a
+// ConfigMap, on top of the values configured by flags. It implements both
+// options.Reloader and nodegroupconfig.DefaultsReloader.
+type Reloader struct {
+	configMapLister v1lister.ConfigMapNamespaceLister
+	configMapName   string
+	recorder        kube_record.EventRecorder
+
+	mutex           sync.RWMutex
+	resourceVersion string
+	config          Config
+	lastConfigMap   *apiv1.ConfigMap
+}
+
+// New creates a new Reloader. The ConfigMap with the given name is checked on
+// every Refresh, so changes are picked up without a restart. Events are
+// emitted on the ConfigMap whenever the effective drain policy changes.
+func New(configMapLister v1lister.ConfigMapNamespaceLister, configMapName string, recorder kube_record.EventRecorder) *Reloader {
+	return &Reloader{
+		configMapLister: configMapLister,
+		configMapName:   configMapName,
+		recorder:        recorder,
+	}
+}
+
+// Reload returns the node delete options with the reloaded values applied.
+func (r *Reloader) Reload(o options.NodeDeleteOptions) options.NodeDeleteOptions {
+	c := r.current()
+	if c.SkipNodesWithSystemPods != nil {
+		o.SkipNodesWithSystemPods = *c.SkipNodesWithSystemPods
+	}
+	if c.SkipNodesWithLocalStorage != nil {
+		o.SkipNodesWithLocalStorage = *c.SkipNodesWithLocalStorage
+	}
+	if c.MinReplicaCount != nil {
+		o.MinReplicaCount = *c.MinReplicaCount
+	}
+	return o
+}
+
+// ReloadNodeGroupDefaults returns the default node group options with the
+// reloaded values applied.
+func (r *Reloader) ReloadNodeGroupDefaults(defaults config.NodeGroupAutoscalingOptions) config.NodeGroupAutoscalingOptions {
+	c := r.current()
+	if c.ScaleDownUtilizationThreshold != nil {
+		defaults.ScaleDownUtilizationThreshold = *c.ScaleDownUtilizationThreshold
+	}
+	if c.ScaleDownGpuUtilizationThreshold != nil {
+		defaults.ScaleDownGpuUtilizationThreshold = *c.ScaleDownGpuUtilizationThreshold
+	}
+	return defaults
+}
+
+// Refresh reads the ConfigMap from the lister. It's meant to be called once
+// per autoscaler loop, Reload and ReloadNodeGroupDefaults only use the
+// configuration read by the last Refresh.
+func (r *Reloader) Refresh() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cm, err := r.configMapLister.Get(r.configMapName)
+	if err != nil {
+		klog.V(4).Infof("Drain options config map %s not found: %v", r.configMapName, err)
+		var ref runtime.Object
+		if r.lastConfigMap != nil {
+			// The deleted ConfigMap can't be referenced by its UID anymore.
+			ref = &apiv1.ObjectReference{Kind: "ConfigMap", APIVersion: "v1", Namespace: r.lastConfigMap.Namespace, Name: r.lastConfigMap.Name}
+		}
+		r.resourceVersion = ""
+		r.lastConfigMap = nil
+		r.update(ref, Config{})
+		return
+	}
+	if cm.ResourceVersion != "" && cm.ResourceVersion == r.resourceVersion {
+		return
+	}
+	r.resourceVersion = cm.ResourceVersion
+	r.lastConfigMap = cm
+
+	c, err := parseConfig(cm)
+	if err != nil {
+		// Keep the last valid configuration.
+		klog.Warningf("Wrong configuration of drain options: %v. Ignoring update.", err)
+		r.recorder.Eventf(cm, apiv1.EventTypeWarning, InvalidDrainPolicyReason, "Ignoring invalid drain options: %v", err)
+		return
+	}
+	r.update(cm, *c)
+}
+
+func (r *Reloader) current() Config {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.config
+}
+
+// update replaces the configuration, emitting an event on the given object if
+// the effective drain policy changes.
+func (r *Reloader) update(obj runtime.Object, c Config) {
+	if reflect.DeepEqual(r.config, c) {
+		return
+	}
+	r.config = c
+	klog.Infof("Effective drain policy changed: %s", c)
+	if obj != nil {
+		r.recorder.Eventf(obj, apiv1.EventTypeNormal, DrainPolicyChangedReason, "Effective drain policy changed: %s", c)
+	}
+}
+
+// String describes the options set by the configuration.
+func (c Config) String() string {
+	var set []string
+	if c.SkipNodesWithSystemPods != nil {
+		set = append(set, "skipNodesWithSystemPods="+strconv.FormatBool(*c.SkipNodesWithSystemPods))
+	}
+	if c.SkipNodesWithLocalStorage != nil {
+		set = append(set, "skipNodesWithLocalStorage="+strconv.FormatBool(*c.SkipNodesWithLocalStorage))
+	}
+	if c.MinReplicaCount != nil {
+		set = append(set, "minReplicaCount="+strconv.Itoa(*c.MinReplicaCount))
+	}
+	if c.ScaleDownUtilizationThreshold != nil {
+		set = append(set, "scaleDownUtilizationThreshold="+strconv.FormatFloat(*c.ScaleDownUtilizationThreshold, 'g', -1, 64))
+	}
+	if c.ScaleDownGpuUtilizationThreshold != nil {
+		set = append(set, "scaleDownGpuUtilizationThreshold="+strconv.FormatFloat(*c.ScaleDownGpuUtilizationThreshold, 'g', -1, 64))
+	}
+	if len(set) == 0 {
+		return "all options as configured by flags"
+	}
+	return strings.Join(set, ", ") + ", other options as configured by flags"
+}
+
+func parseConfig(cm *apiv1.ConfigMap) (*Config, error) {
+	configString, found := cm.Data[ConfigMapKey]
+	if !found {
+		return nil, fmt.Errorf("config map %s doesn't contain %s key", cm.Name, ConfigMapKey)
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict([]byte(configString), c); err != nil {
+		return nil, fmt.Errorf("can't parse YAML with drain options in config map %s: %v", cm.Name, err)
+	}
+	if c.MinReplicaCount != nil && *c.MinReplicaCount < 0 {
+		return nil, fmt.Errorf("minReplicaCount can't be negative, got %d", *c.MinReplicaCount)
+	}
+	for name, threshold := range map[string]*float64{
+		"scaleDownUtilizationThreshold":    c.ScaleDownUtilizationThreshold,
+		"scaleDownGpuUtilizationThreshold": c.ScaleDownGpuUtilizationThreshold,
+	} {
+		if threshold != nil && (*threshold < 0 || *threshold > 1) {
+			return nil, fmt.Errorf("%s should be between 0 and 1, got %v", name, *threshold)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reload
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	kube_record "k8s.io/client-go/tools/record"
+)
+
+func TestReloader(t *testing.T) {
+	base := options.NodeDeleteOptions{SkipNodesWithSystemPods: true, SkipNodesWithLocalStorage: true, MinReplicaCount: 0}
+	baseDefaults := config.NodeGroupAutoscalingOptions{ScaleDownUtilizationThreshold: 0.5, ScaleDownGpuUtilizationThreshold: 0.5}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	recorder := kube_record.NewFakeRecorder(10)
+	r := New(v1lister.NewConfigMapLister(indexer).ConfigMaps("kube-system"), "drain-options", recorder)
+
+	for _, step := range []struct {
+		desc         string
+		options      string
+		deleted      bool
+		want         options.NodeDeleteOptions
+		wantDefaults config.NodeGroupAutoscalingOptions
+		wantEvent    string
+	}{
+		{
+			desc:         "no config map",
+			deleted:      true,
+			want:         base,
+			wantDefaults: baseDefaults,
+		},
+		{
+			desc:         "options reloaded",
+			options:      "skipNodesWithSystemPods: false\nminReplicaCount: 2\nscaleDownUtilizationThreshold: 0.7\n",
+			want:         options.NodeDeleteOptions{SkipNodesWithSystemPods: false, SkipNodesWithLocalStorage: true, MinReplicaCount: 2},
+			wantDefaults: config.NodeGroupAutoscalingOptions{ScaleDownUtilizationThreshold: 0.7, ScaleDownGpuUtilizationThreshold: 0.5},
+			wantEvent:    "Normal DrainPolicyChanged Effective drain policy changed: skipNodesWithSystemPods=false, minReplicaCount=2, scaleDownUtilizationThreshold=0.7, other options as configured by flags",
+		},
+		{
+			desc:         "invalid update ignored",
+			options:      "scaleDownUtilizationThreshold: 1.5\n",
+			want:         options.NodeDeleteOptions{SkipNodesWithSystemPods: false, SkipNodesWithLocalStorage: true, MinReplicaCount: 2},
+			wantDefaults: config.NodeGroupAutoscalingOptions{ScaleDownUtilizationThreshold: 0.7, ScaleDownGpuUtilizationThreshold: 0.5},
+			wantEvent:    "Warning InvalidDrainPolicy Ignoring invalid drain options: scaleDownUtilizationThreshold should be between 0 and 1, got 1.5",
+		},
+		{
+			desc:         "unknown option ignored",
+			options:      "skipNodesWithCustomControllerPods: false\n",
+			want:         options.NodeDeleteOptions{SkipNodesWithSystemPods: false, SkipNodesWithLocalStorage: true, MinReplicaCount: 2},
+			wantDefaults: config.NodeGroupAutoscalingOptions{ScaleDownUtilizationThreshold: 0.7, ScaleDownGpuUtilizationThreshold: 0.5},
+			wantEvent:    "Warning InvalidDrainPolicy",
+		},
+		{
+			desc:         "config map deleted",
+			deleted:      true,
+			want:         base,
+			wantDefaults: baseDefaults,
+			wantEvent:    "Normal DrainPolicyChanged Effective drain policy changed: all options as configured by flags",
+		},
+	} {
+		if step.deleted {
+			for _, obj := range indexer.List() {
+				if err := indexer.Delete(obj); err != nil {
+					t.Fatalf("%s: failed to delete config map: %v", step.desc, err)
+				}
+			}
+		} else {
+			cm := &apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "drain-options", Namespace: "kube-system", ResourceVersion: step.desc},
+				Data:       map[string]string{ConfigMapKey: step.options},
+			}
+			if err := indexer.Update(cm); err != nil {
+				t.Fatalf("%s: failed to update config map: %v", step.desc, err)
+			}
+		}
+
+		r.Refresh()
+		if diff := cmp.Diff(step.want, r.Reload(base)); diff != "" {
+			t.Errorf("%s: Reload() diff (-want +got):\n%s", step.desc, diff)
+		}
+		if diff := cmp.Diff(step.wantDefaults, r.ReloadNodeGroupDefaults(baseDefaults)); diff != "" {
+			t.Errorf("%s: ReloadNodeGroupDefaults() diff (-want +got):\n%s", step.desc, diff)
+		}
+		var gotEvent string
+		select {
+		case gotEvent = <-recorder.Events:
+		default:
+		}
+		if (step.wantEvent == "" && gotEvent != "") || !strings.HasPrefix(gotEvent, step.wantEvent) {
+			t.Errorf("%s: got event %q, want %q", step.desc, gotEvent, step.wantEvent)
+		}
+	}
+}
+
+func TestReloaderReadsOnlyOnRefresh(t *testing.T) {
+	base := options.NodeDeleteOptions{SkipNodesWithSystemPods: true}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	r := New(v1lister.NewConfigMapLister(indexer).ConfigMaps("kube-system"), "drain-options", kube_record.NewFakeRecorder(10))
+	cm := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "drain-options", Namespace: "kube-system", ResourceVersion: "1"},
+		Data:       map[string]string{ConfigMapKey: "skipNodesWithSystemPods: false\n"},
+	}
+	if err := indexer.Add(cm); err != nil {
+		t.Fatalf("failed to add config map: %v", err)
+	}
+
+	if got := r.Reload(base); !got.SkipNodesWithSystemPods {
+		t.Errorf("Reload() before Refresh(): got %+v, want the options configured by flags", got)
+	}
+	r.Refresh()
+	if got := r.Reload(base); got.SkipNodesWithSystemPods {
+		t.Errorf("Reload() after Refresh(): got %+v, want the reloaded SkipNodesWithSystemPods", got)
+	}
+	if err := indexer.Delete(cm); err != nil {
+		t.Fatalf("failed to delete config map: %v", err)
+	}
+	if got := r.Reload(base); got.SkipNodesWithSystemPods {
+		t.Errorf("Reload() after deletion, before Refresh(): got %+v, want the reloaded SkipNodesWithSystemPods", got)
+	}
+}
@@ -47,6 +47,21 @@ profiles:
         weight: 1
   schedulerName: custom-scheduler`
 
+	// SchedulerConfigMultipleProfiles is scheduler config with
+	// the default profile and a profile with `NodeResourcesFit`
+	// plugin disabled
+	SchedulerConfigMultipleProfiles = `
+apiVersion: kubescheduler.config.k8s.io/v1
+kind: KubeSchedulerConfiguration
+profiles:
+- schedulerName: default-scheduler
+- plugins:
+    multiPoint:
+      disabled:
+      - name: NodeResourcesFit
+        weight: 1
+  schedulerName: custom-scheduler`
+
 	// SchedulerConfigMinimalCorrect is the minimal
 	// correct scheduler config
 	SchedulerConfigMinimalCorrect = `
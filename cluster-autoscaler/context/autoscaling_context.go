@@ -17,18 +17,24 @@ limitations under the License.
 package context
 
 import (
+	"time"
+
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
 	processor_callbacks "k8s.io/autoscaler/cluster-autoscaler/processors/callbacks"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/evictionbackoff"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/expendable"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"k8s.io/client-go/informers"
 	kube_client "k8s.io/client-go/kubernetes"
@@ -64,6 +70,16 @@ type AutoscalingContext struct {
 	RemainingPdbTracker pdb.RemainingPdbTracker
 	// ClusterStateRegistry tracks the health of the node groups and pending scale-ups and scale-downs
 	ClusterStateRegistry *clusterstate.ClusterStateRegistry
+	// ExpendablePods decides which pods are expendable, i.e. ignored in scale up and scale down
+	ExpendablePods *expendable.Rule
+	// EvictionBackoff records failed pod evictions, nil if eviction failures aren't backed off
+	EvictionBackoff *evictionbackoff.Ledger
+	// NodeUsage provides actual resource usage of nodes for usage based utilization modes, can be nil
+	NodeUsage utilization.UsageProvider
+	// PodsToMove determines pods to move from drained nodes, simulator.GetPodsToMove is used if nil
+	PodsToMove simulator.PodsToMoveFunc
+	// LoopStartTime is the time the current autoscaling loop started at, zero outside of the loop
+	LoopStartTime time.Time
 }
 
 // AutoscalingKubeClients contains all Kubernetes API clients,
@@ -111,6 +127,10 @@ func NewAutoscalingContext(
 	debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter,
 	remainingPdbTracker pdb.RemainingPdbTracker,
 	clusterStateRegistry *clusterstate.ClusterStateRegistry,
+	expendablePods *expendable.Rule,
+	evictionBackoff *evictionbackoff.Ledger,
+	nodeUsage utilization.UsageProvider,
+	podsToMove simulator.PodsToMoveFunc,
 ) *AutoscalingContext {
 	return &AutoscalingContext{
 		AutoscalingOptions:     options,
@@ -124,6 +144,10 @@ func NewAutoscalingContext(
 		DebuggingSnapshotter:   debuggingSnapshotter,
 		RemainingPdbTracker:    remainingPdbTracker,
 		ClusterStateRegistry:   clusterStateRegistry,
+		ExpendablePods:         expendablePods,
+		EvictionBackoff:        evictionBackoff,
+		NodeUsage:              nodeUsage,
+		PodsToMove:             podsToMove,
 	}
 }
 
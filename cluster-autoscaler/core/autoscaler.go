@@ -24,19 +24,26 @@ import (
 	cloudBuilder "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/builder"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaleup"
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/factory"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/priority"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/evictionbackoff"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/expendable"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/dynamicresources"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/persistentstate"
 	"k8s.io/client-go/informers"
 	kube_client "k8s.io/client-go/kubernetes"
 )
@@ -60,6 +67,13 @@ type AutoscalerOptions struct {
 	ScaleUpOrchestrator    scaleup.Orchestrator
 	DeleteOptions          options.NodeDeleteOptions
 	DrainabilityRules      rules.Rules
+	ExpendablePods         *expendable.Rule
+	EvictionBackoff        *evictionbackoff.Ledger
+	NodeUsage              utilization.UsageProvider
+	DynamicResources       *dynamicresources.Provider
+	PodsToMove             simulator.PodsToMoveFunc
+	PriorityExpanderStatus *priority.Status
+	StateStore             *persistentstate.Store
 }
 
 // Autoscaler is the main component of CA which scales up/down node groups according to its configuration
@@ -94,6 +108,12 @@ func NewAutoscaler(opts AutoscalerOptions) (Autoscaler, errors.AutoscalerError)
 		opts.ScaleUpOrchestrator,
 		opts.DeleteOptions,
 		opts.DrainabilityRules,
+		opts.ExpendablePods,
+		opts.EvictionBackoff,
+		opts.NodeUsage,
+		opts.DynamicResources,
+		opts.PodsToMove,
+		opts.StateStore,
 	), nil
 }
 
@@ -108,6 +128,9 @@ func initializeDefaultOptions(opts *AutoscalerOptions) error {
 	if opts.ClusterSnapshot == nil {
 		opts.ClusterSnapshot = clustersnapshot.NewBasicClusterSnapshot()
 	}
+	if opts.ExpendablePods == nil {
+		opts.ExpendablePods = expendable.New(opts.ExpendablePodsPriorityCutoff, nil)
+	}
 	if opts.RemainingPdbTracker == nil {
 		opts.RemainingPdbTracker = pdb.NewBasicRemainingPdbTracker()
 	}
@@ -116,7 +139,7 @@ func initializeDefaultOptions(opts *AutoscalerOptions) error {
 	}
 	if opts.ExpanderStrategy == nil {
 		expanderFactory := factory.NewFactory()
-		expanderFactory.RegisterDefaultExpanders(opts.CloudProvider, opts.AutoscalingKubeClients, opts.KubeClient, opts.ConfigNamespace, opts.GRPCExpanderCert, opts.GRPCExpanderURL)
+		expanderFactory.RegisterDefaultExpanders(opts.CloudProvider, opts.AutoscalingKubeClients, opts.KubeClient, opts.ConfigNamespace, opts.GRPCExpanderCert, opts.GRPCExpanderURL, opts.GRPCExpanderStreaming, opts.PriorityExpanderStatus)
 		expanderStrategy, err := expanderFactory.Build(strings.Split(opts.ExpanderNames, ","))
 		if err != nil {
 			return err
@@ -0,0 +1,234 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderepair
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	klog "k8s.io/klog/v2"
+)
+
+// Repairer replaces nodes which are persistently unhealthy, i.e. NotReady or
+// reporting one of the configured problem conditions for longer than the
+// unhealthy time. Nodes are drained like on scale down, respecting
+// drainability rules and disruption budgets, and their node groups are
+// increased to bring up replacements.
+type Repairer struct {
+	context       *context.AutoscalingContext
+	clusterState  *clusterstate.ClusterStateRegistry
+	actuator      scaledown.Actuator
+	rs            simulator.NodeRemovalSimulator
+	unhealthyTime time.Duration
+	conditions    map[apiv1.NodeConditionType]bool
+	maxRepairs    int
+	// respectDaemonSetPdbs tells if removal of DaemonSet pods uses up their
+	// disruption budgets.
+	respectDaemonSetPdbs bool
+	// blockedNodes maps names of nodes whose repair is blocked by a pod to
+	// the blocking pod and reason, so that events are only emitted on changes.
+	blockedNodes map[string]string
+}
+
+// New creates a new Repairer. Nodes are drained and deleted by the actuator.
+func New(context *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, actuator scaledown.Actuator, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules) *Repairer {
+	rs := simulator.NewRemovalSimulator(context.ListerRegistry, context.ClusterSnapshot, context.PredicateChecker, simulator.NewUsageTracker(), deleteOptions, drainabilityRules, false)
+	rs.SetPodsToMoveFunc(context.PodsToMove)
+	conditions := make(map[apiv1.NodeConditionType]bool, len(context.NodeAutoRepairConditions))
+	for _, condition := range context.NodeAutoRepairConditions {
+		conditions[apiv1.NodeConditionType(condition)] = true
+	}
+	return &Repairer{
+		context:              context,
+		clusterState:         csr,
+		actuator:             actuator,
+		rs:                   rs,
+		unhealthyTime:        context.NodeAutoRepairUnhealthyTime,
+		conditions:           conditions,
+		maxRepairs:           context.MaxNodeAutoRepairs,
+		respectDaemonSetPdbs: deleteOptions.RespectDaemonSetPdbs,
+		blockedNodes:         make(map[string]string),
+	}
+}
+
+// RunOnce starts repairs of nodes unhealthy at the timestamp. Returns true if
+// any repair was started.
+func (r *Repairer) RunOnce(nodes []*apiv1.Node, timestamp time.Time) (bool, errors.AutoscalerError) {
+	toRepair, blocked := r.Plan(nodes, timestamp)
+	r.reportBlocked(blocked)
+	if len(toRepair) == 0 {
+		return false, nil
+	}
+	return r.Repair(toRepair)
+}
+
+// Plan returns the unhealthy nodes which can be repaired at the timestamp, and
+// the unhealthy nodes whose drain is blocked by a pod. Pods disrupted by
+// repairs of earlier nodes are taken into account for disruption budgets.
+func (r *Repairer) Plan(nodes []*apiv1.Node, timestamp time.Time) ([]*apiv1.Node, []*simulator.UnremovableNode) {
+	remainingPdbTracker := r.context.RemainingPdbTracker
+	if remainingPdbTracker == nil {
+		remainingPdbTracker = pdb.NewBasicRemainingPdbTracker()
+	}
+	remainingPdbTracker = remainingPdbTracker.Clone()
+
+	var toRepair []*apiv1.Node
+	var blocked []*simulator.UnremovableNode
+	for _, node := range nodes {
+		if len(toRepair) >= r.maxRepairs {
+			break
+		}
+		since, unhealthy := r.unhealthySince(node)
+		if !unhealthy || timestamp.Sub(since) < r.unhealthyTime || taints.HasToBeDeletedTaint(node) {
+			continue
+		}
+		nodeGroup, err := r.context.CloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			klog.Warningf("Failed to get node group for unhealthy node %s: %v", node.Name, err)
+			continue
+		}
+		if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+			klog.V(4).Infof("Unhealthy node %s isn't in any node group, skipping repair", node.Name)
+			continue
+		}
+		nodeInfo, err := r.context.ClusterSnapshot.NodeInfos().Get(node.Name)
+		if err != nil {
+			klog.Errorf("Can't retrieve unhealthy node %s from snapshot: %v", node.Name, err)
+			continue
+		}
+		simulation := r.rs.PodsToMove(nodeInfo, timestamp, remainingPdbTracker)
+		if err := simulation.Err; err != nil {
+			klog.V(2).Infof("Repair of unhealthy node %s is blocked: %v", node.Name, err)
+			if simulation.BlockingPod != nil {
+				blocked = append(blocked, &simulator.UnremovableNode{Node: node, Reason: simulator.BlockedByPod, BlockingPod: simulation.BlockingPod, BlockingPods: simulation.BlockingPods})
+			}
+			continue
+		}
+		remainingPdbTracker.RemovePods(simulation.PodsToDisrupt(r.respectDaemonSetPdbs))
+		toRepair = append(toRepair, node)
+	}
+	metrics.UpdateNodeAutoRepairBlockedNodesCount(len(blocked))
+	return toRepair, blocked
+}
+
+// Repair starts draining and deleting the nodes, and increases their node
+// groups by the number of nodes being deleted, as far as their max sizes
+// allow. Node groups at max size are replaced by a following scale-up if pods
+// still need the capacity. Returns true if any repair was started.
+func (r *Repairer) Repair(nodes []*apiv1.Node) (bool, errors.AutoscalerError) {
+	scaleDownStatus, err := r.actuator.StartDeletion(nil, nodes)
+	if err != nil {
+		metrics.RegisterNodeAutoRepairs(metrics.NodeAutoRepairFailed, len(nodes))
+		return false, err.AddPrefix("failed to start repairs: ")
+	}
+	if len(scaleDownStatus.ScaledDownNodes) == 0 {
+		return false, nil
+	}
+	metrics.RegisterNodeAutoRepairs(metrics.NodeAutoRepairStarted, len(scaleDownStatus.ScaledDownNodes))
+
+	nodeGroups := make(map[string]cloudprovider.NodeGroup)
+	replacements := make(map[string]int)
+	for _, scaledDown := range scaleDownStatus.ScaledDownNodes {
+		klog.V(0).Infof("Repairing unhealthy node %s", scaledDown.Node.Name)
+		r.context.Recorder.Eventf(scaledDown.Node, apiv1.EventTypeNormal, "NodeRepair", "node is unhealthy, replacing it")
+		nodeGroups[scaledDown.NodeGroup.Id()] = scaledDown.NodeGroup
+		replacements[scaledDown.NodeGroup.Id()]++
+	}
+	var errs []errors.AutoscalerError
+	for id, nodeGroup := range nodeGroups {
+		if err := r.increaseSize(nodeGroup, replacements[id]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return true, errs[0]
+	}
+	return true, nil
+}
+
+func (r *Repairer) increaseSize(nodeGroup cloudprovider.NodeGroup, replacements int) errors.AutoscalerError {
+	size, err := nodeGroup.TargetSize()
+	if err != nil {
+		return errors.ToAutoscalerError(errors.CloudProviderError, err).AddPrefix("failed to get size of node group %s: ", nodeGroup.Id())
+	}
+	if size+replacements > nodeGroup.MaxSize() {
+		replacements = nodeGroup.MaxSize() - size
+	}
+	if replacements <= 0 {
+		klog.V(1).Infof("Node group %s is at max size, replacements of repaired nodes are left to scale-up", nodeGroup.Id())
+		return nil
+	}
+	if err := nodeGroup.IncreaseSize(replacements); err != nil {
+		r.context.LogRecorder.Eventf(apiv1.EventTypeWarning, "FailedToReplaceRepairedNodes", "Failed to increase group %s by %d to replace repaired nodes: %v", nodeGroup.Id(), replacements, err)
+		return errors.ToAutoscalerError(errors.CloudProviderError, err).AddPrefix("failed to increase size of node group %s: ", nodeGroup.Id())
+	}
+	r.clusterState.RegisterOrUpdateScaleUp(nodeGroup, replacements, time.Now())
+	r.context.LogRecorder.Eventf(apiv1.EventTypeNormal, "ReplacedRepairedNodes", "Increased group %s by %d to replace repaired nodes", nodeGroup.Id(), replacements)
+	return nil
+}
+
+// unhealthySince returns since when the node is unhealthy, if it is.
+func (r *Repairer) unhealthySince(node *apiv1.Node) (time.Time, bool) {
+	var since time.Time
+	unhealthy := false
+	for _, condition := range node.Status.Conditions {
+		notReady := condition.Type == apiv1.NodeReady && condition.Status != apiv1.ConditionTrue
+		problem := r.conditions[condition.Type] && condition.Status == apiv1.ConditionTrue
+		if !notReady && !problem {
+			continue
+		}
+		if !unhealthy || condition.LastTransitionTime.Time.Before(since) {
+			since = condition.LastTransitionTime.Time
+		}
+		unhealthy = true
+	}
+	return since, unhealthy
+}
+
+// reportBlocked emits events for nodes whose repair is blocked by a pod, and
+// for the blocking pods, when the blocking pod or its reason changes.
+func (r *Repairer) reportBlocked(blocked []*simulator.UnremovableNode) {
+	stillBlocked := make(map[string]string, len(blocked))
+	for _, unremovableNode := range blocked {
+		node := unremovableNode.Node
+		pod := unremovableNode.BlockingPod.Pod
+		reason := unremovableNode.BlockingPod.ReasonID()
+		summary := fmt.Sprintf("%s/%s: %v", pod.Namespace, pod.Name, reason)
+		stillBlocked[node.Name] = summary
+		if r.blockedNodes[node.Name] == summary {
+			continue
+		}
+		r.context.Recorder.Eventf(node, apiv1.EventTypeWarning, "NodeRepairBlocked",
+			"unhealthy node cannot be repaired: pod %s/%s is blocking its drain: %v", pod.Namespace, pod.Name, reason)
+		r.context.Recorder.Eventf(pod, apiv1.EventTypeNormal, "BlockingNodeRepair",
+			"pod is blocking repair of unhealthy node %s: %v", node.Name, reason)
+	}
+	r.blockedNodes = stillBlocked
+}
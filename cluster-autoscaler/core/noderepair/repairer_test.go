@@ -0,0 +1,206 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package noderepair
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPlan(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+
+	healthy := BuildTestNode("healthy", 1000, 1000)
+	SetNodeReadyState(healthy, true, now.Add(-time.Hour))
+	notReady := BuildTestNode("not-ready", 1000, 1000)
+	SetNodeReadyState(notReady, false, now.Add(-time.Hour))
+	recentlyNotReady := BuildTestNode("recently-not-ready", 1000, 1000)
+	SetNodeReadyState(recentlyNotReady, false, now.Add(-time.Minute))
+	unknown := BuildTestNode("unknown", 1000, 1000)
+	SetNodeCondition(unknown, apiv1.NodeReady, apiv1.ConditionUnknown, now.Add(-time.Hour))
+	deadlocked := BuildTestNode("deadlocked", 1000, 1000)
+	SetNodeReadyState(deadlocked, true, now.Add(-time.Hour))
+	SetNodeCondition(deadlocked, "KernelDeadlock", apiv1.ConditionTrue, now.Add(-time.Hour))
+	otherProblem := BuildTestNode("other-problem", 1000, 1000)
+	SetNodeReadyState(otherProblem, true, now.Add(-time.Hour))
+	SetNodeCondition(otherProblem, "FrequentKubeletRestart", apiv1.ConditionTrue, now.Add(-time.Hour))
+	blocked := BuildTestNode("blocked", 1000, 1000)
+	SetNodeReadyState(blocked, false, now.Add(-time.Hour))
+	beingDeleted := BuildTestNode("being-deleted", 1000, 1000)
+	SetNodeReadyState(beingDeleted, false, now.Add(-time.Hour))
+	beingDeleted.Spec.Taints = append(beingDeleted.Spec.Taints, apiv1.Taint{Key: "ToBeDeletedByClusterAutoscaler", Effect: apiv1.TaintEffectNoSchedule})
+	noNodeGroup := BuildTestNode("no-node-group", 1000, 1000)
+	SetNodeReadyState(noNodeGroup, false, now.Add(-time.Hour))
+
+	unreplicated := BuildScheduledTestPod("unreplicated", 100, 100, "blocked")
+	replicated := SetRSPodSpec(BuildScheduledTestPod("replicated", 100, 100, "not-ready"), "rs")
+
+	for _, tc := range []struct {
+		name        string
+		maxRepairs  int
+		wantRepair  []string
+		wantBlocked []string
+	}{
+		{
+			name:        "unhealthy nodes",
+			maxRepairs:  10,
+			wantRepair:  []string{"not-ready", "unknown", "deadlocked"},
+			wantBlocked: []string{"blocked"},
+		},
+		{
+			name:       "limited by max repairs",
+			maxRepairs: 2,
+			wantRepair: []string{"not-ready", "unknown"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			nodes := []*apiv1.Node{healthy, notReady, recentlyNotReady, unknown, deadlocked, otherProblem, blocked, beingDeleted, noNodeGroup}
+			provider := testprovider.NewTestCloudProvider(nil, nil)
+			provider.AddNodeGroup("ng", 0, 20, len(nodes)-1)
+			for _, node := range nodes[:len(nodes)-1] {
+				provider.AddNode("ng", node)
+			}
+			ctx := newTestContext(t, provider, tc.maxRepairs, nodes, []*apiv1.Pod{unreplicated, replicated})
+			r := New(&ctx, nil, nil, options.NodeDeleteOptions{}, nil)
+
+			toRepair, blockedNodes := r.Plan(nodes, now)
+			var repairNames, blockedNames []string
+			for _, node := range toRepair {
+				repairNames = append(repairNames, node.Name)
+			}
+			for _, node := range blockedNodes {
+				blockedNames = append(blockedNames, node.Node.Name)
+				assert.Equal(t, &drain.BlockingPod{Pod: unreplicated, Reason: drain.NotReplicated, Rule: "Replicated", Remediation: drain.AddSafeToEvictAnnotation}, node.BlockingPod)
+			}
+			assert.Equal(t, tc.wantRepair, repairNames)
+			assert.Equal(t, tc.wantBlocked, blockedNames)
+		})
+	}
+}
+
+func TestRepair(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		maxSize    int
+		scaledDown int
+		wantSize   int
+	}{
+		{
+			name:       "node groups increased by repaired nodes",
+			maxSize:    10,
+			scaledDown: 2,
+			wantSize:   5,
+		},
+		{
+			name:       "increase limited by max size",
+			maxSize:    4,
+			scaledDown: 2,
+			wantSize:   4,
+		},
+		{
+			name:       "node group at max size",
+			maxSize:    3,
+			scaledDown: 2,
+			wantSize:   3,
+		},
+		{
+			name:       "no repair started",
+			maxSize:    10,
+			scaledDown: 0,
+			wantSize:   3,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			nodes := []*apiv1.Node{BuildTestNode("n1", 1000, 1000), BuildTestNode("n2", 1000, 1000), BuildTestNode("n3", 1000, 1000)}
+			provider := testprovider.NewTestCloudProvider(func(string, int) error { return nil }, nil)
+			provider.AddNodeGroup("ng", 0, tc.maxSize, 3)
+			for _, node := range nodes {
+				provider.AddNode("ng", node)
+			}
+			ctx := newTestContext(t, provider, 10, nodes, nil)
+			csr := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, ctx.LogRecorder, NewBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{MaxNodeProvisionTime: 15 * time.Minute}))
+			actuator := &fakeActuator{nodeGroup: provider.GetNodeGroup("ng"), limit: tc.scaledDown}
+			r := New(&ctx, csr, actuator, options.NodeDeleteOptions{}, nil)
+
+			repairedAny, typedErr := r.Repair(nodes)
+			assert.NoError(t, typedErr)
+			assert.Equal(t, tc.scaledDown > 0, repairedAny)
+			assert.Equal(t, nodes, actuator.drain)
+			size, err := provider.GetNodeGroup("ng").TargetSize()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantSize, size)
+		})
+	}
+}
+
+func newTestContext(t *testing.T, provider *testprovider.TestCloudProvider, maxRepairs int, nodes []*apiv1.Node, pods []*apiv1.Pod) context.AutoscalingContext {
+	replicas := int32(3)
+	rsLister, err := kube_util.NewTestReplicaSetLister([]*appsv1.ReplicaSet{{
+		ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default", UID: types.UID("rs")},
+		Spec:       appsv1.ReplicaSetSpec{Replicas: &replicas},
+	}})
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, rsLister, nil)
+	options := config.AutoscalingOptions{
+		NodeAutoRepairUnhealthyTime: 20 * time.Minute,
+		NodeAutoRepairConditions:    []string{"KernelDeadlock"},
+		MaxNodeAutoRepairs:          maxRepairs,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, registry, provider, nil, nil)
+	assert.NoError(t, err)
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, ctx.ClusterSnapshot, nodes, pods)
+	return ctx
+}
+
+// fakeActuator starts deletion of up to limit nodes needing drain.
+type fakeActuator struct {
+	scaledown.Actuator
+	nodeGroup cloudprovider.NodeGroup
+	limit     int
+	drain     []*apiv1.Node
+}
+
+func (a *fakeActuator) StartDeletion(empty, drain []*apiv1.Node) (*status.ScaleDownStatus, errors.AutoscalerError) {
+	a.drain = drain
+	scaleDownStatus := &status.ScaleDownStatus{}
+	for _, node := range drain[:a.limit] {
+		scaleDownStatus.ScaledDownNodes = append(scaleDownStatus.ScaledDownNodes, &status.ScaleDownNode{Node: node, NodeGroup: a.nodeGroup})
+	}
+	return scaleDownStatus, nil
+}
@@ -19,7 +19,7 @@ package podlistprocessor
 import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
-	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 	"k8s.io/klog/v2"
 )
 
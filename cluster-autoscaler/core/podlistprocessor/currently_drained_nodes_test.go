@@ -289,6 +289,10 @@ func (m *mockActuationStatus) RecentEvictions() []*apiv1.Pod {
 	return nil
 }
 
+func (m *mockActuationStatus) DrainStatuses() map[string]status.NodeDrainStatus {
+	return nil
+}
+
 func (m *mockActuationStatus) DeletionsInProgress() ([]string, []string) {
 	return nil, m.drainedNodes
 }
@@ -300,3 +304,7 @@ func (m *mockActuationStatus) DeletionResults() (map[string]status.NodeDeleteRes
 func (m *mockActuationStatus) DeletionsCount(_ string) int {
 	return 0
 }
+
+func (m *mockActuationStatus) DrainsCount(_ string) int {
+	return 0
+}
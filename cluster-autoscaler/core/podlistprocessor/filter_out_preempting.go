@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podlistprocessor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// PreemptionPolicy determines how scale-up treats pending pods which could be
+// scheduled on existing nodes by preempting lower priority pods.
+type PreemptionPolicy string
+
+const (
+	// NonePreemptionPolicy doesn't simulate preemption, pods which could
+	// preempt lower priority pods trigger scale-up like other pending pods.
+	NonePreemptionPolicy PreemptionPolicy = "None"
+	// SkipPreemptionPolicy doesn't trigger scale-up for pods which could
+	// preempt lower priority pods, leaving it to the scheduler to preempt.
+	SkipPreemptionPolicy PreemptionPolicy = "Skip"
+	// DelayPreemptionPolicy doesn't trigger scale-up for pods which could
+	// preempt lower priority pods until they are pending for the preemption
+	// delay, giving the scheduler time to preempt.
+	DelayPreemptionPolicy PreemptionPolicy = "Delay"
+)
+
+// ParsePreemptionPolicy parses a PreemptionPolicy from its name.
+func ParsePreemptionPolicy(name string) (PreemptionPolicy, error) {
+	switch policy := PreemptionPolicy(name); policy {
+	case NonePreemptionPolicy, SkipPreemptionPolicy, DelayPreemptionPolicy:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown scale-up preemption policy %q, expected one of: %s, %s, %s", name, NonePreemptionPolicy, SkipPreemptionPolicy, DelayPreemptionPolicy)
+}
+
+type filterOutPreemptingPodListProcessor struct {
+	predicateChecker predicatechecker.PredicateChecker
+}
+
+// NewFilterOutPreemptingPodListProcessor creates a PodListProcessor filtering
+// out pods which could be scheduled on existing nodes by preempting lower
+// priority pods, according to the scale-up preemption policy.
+func NewFilterOutPreemptingPodListProcessor(predicateChecker predicatechecker.PredicateChecker) *filterOutPreemptingPodListProcessor {
+	return &filterOutPreemptingPodListProcessor{
+		predicateChecker: predicateChecker,
+	}
+}
+
+// Process filters out pods which could preempt lower priority pods on existing nodes.
+func (p *filterOutPreemptingPodListProcessor) Process(context *context.AutoscalingContext, unschedulablePods []*apiv1.Pod) ([]*apiv1.Pod, error) {
+	// The scheduler preempts lower priority pods to make room for pending pods
+	// it can't schedule otherwise. Pods it would schedule this way don't need
+	// new nodes, at least unless the preemption takes too long. Victims are
+	// tracked during the simulation, so that they can't make room for more
+	// than one pending pod, but the snapshot is left unchanged.
+	var delay time.Duration
+	switch PreemptionPolicy(context.ScaleUpPreemptionPolicy) {
+	case SkipPreemptionPolicy:
+	case DelayPreemptionPolicy:
+		delay = context.ScaleUpPreemptionDelay
+	default:
+		return unschedulablePods, nil
+	}
+
+	klog.V(4).Infof("Filtering out pods which can preempt lower priority pods")
+	now := context.LoopStartTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	podsToHelp, err := p.filterOutPreempting(unschedulablePods, context.ClusterSnapshot, delay, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(podsToHelp) != len(unschedulablePods) {
+		klog.V(2).Infof("%v pods can preempt lower priority pods on existing nodes, not triggering scale-up for them", len(unschedulablePods)-len(podsToHelp))
+	}
+	return podsToHelp, nil
+}
+
+func (p *filterOutPreemptingPodListProcessor) CleanUp() {
+}
+
+// filterOutPreempting simulates preemption of lower priority pods on existing
+// nodes for pods from <unschedulableCandidates>, the higher priority pods first,
+// and returns the pods which can't be scheduled this way. Pods pending for at
+// least the delay, if it's positive, are returned without simulating preemption.
+// The snapshot is left unchanged.
+func (p *filterOutPreemptingPodListProcessor) filterOutPreempting(unschedulableCandidates []*apiv1.Pod, clusterSnapshot clustersnapshot.ClusterSnapshot, delay time.Duration, now time.Time) ([]*apiv1.Pod, error) {
+	candidates := make([]*apiv1.Pod, len(unschedulableCandidates))
+	copy(candidates, unschedulableCandidates)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return corev1helpers.PodPriority(candidates[i]) > corev1helpers.PodPriority(candidates[j])
+	})
+
+	preempting := make(map[*apiv1.Pod]bool)
+	preempted := make(map[string]bool)
+	for _, pod := range candidates {
+		if !canPreempt(pod) {
+			continue
+		}
+		if delay > 0 && now.Sub(pod_util.PendingSince(pod)) >= delay {
+			klog.V(4).Infof("Pod %s/%s is pending for over %v, not waiting for it to preempt lower priority pods", pod.Namespace, pod.Name, delay)
+			continue
+		}
+		nodeName, err := p.preemptOnAnyNode(clusterSnapshot, pod, preempted)
+		if err != nil {
+			return nil, err
+		}
+		if nodeName != "" {
+			klog.V(4).Infof("Pod %s/%s can preempt lower priority pods on node %s", pod.Namespace, pod.Name, nodeName)
+			preempting[pod] = true
+		}
+	}
+
+	var unschedulablePods []*apiv1.Pod
+	for _, pod := range unschedulableCandidates {
+		if !preempting[pod] {
+			unschedulablePods = append(unschedulablePods, pod)
+		}
+	}
+	return unschedulablePods, nil
+}
+
+// preemptOnAnyNode simulates preemption of lower priority pods for the pod on
+// the first node where it would fit afterwards, and returns the name of the
+// node, or an empty string if there is no such node. Victims of the preemption
+// are added to preempted.
+func (p *filterOutPreemptingPodListProcessor) preemptOnAnyNode(clusterSnapshot clustersnapshot.ClusterSnapshot, pod *apiv1.Pod, preempted map[string]bool) (string, error) {
+	nodeInfos, err := clusterSnapshot.NodeInfos().List()
+	if err != nil {
+		return "", err
+	}
+	for _, nodeInfo := range nodeInfos {
+		victims, err := p.preemptOnNode(clusterSnapshot, nodeInfo, pod, preempted)
+		if err != nil {
+			return "", err
+		}
+		if len(victims) > 0 {
+			for _, victim := range victims {
+				preempted[podKey(victim)] = true
+			}
+			return nodeInfo.Node().Name, nil
+		}
+	}
+	return "", nil
+}
+
+// preemptOnNode checks if the pod would fit on the node after removing all
+// lower priority pods which aren't already preempted. If so, like the
+// scheduler does, as many of the victims as possible are reprieved, the higher
+// priority ones first, and the rest is returned. No victims are returned if the
+// pod doesn't fit or fits without preempting anything. The simulation runs in
+// a fork of the snapshot, which is always reverted. Preempted pods stay in the
+// snapshot, where they take the place of the pods which preempted them.
+func (p *filterOutPreemptingPodListProcessor) preemptOnNode(clusterSnapshot clustersnapshot.ClusterSnapshot, nodeInfo *schedulerframework.NodeInfo, pod *apiv1.Pod, preempted map[string]bool) ([]*apiv1.Pod, error) {
+	priority := corev1helpers.PodPriority(pod)
+	var candidates []*apiv1.Pod
+	for _, podInfo := range nodeInfo.Pods {
+		if isPreemptible(podInfo.Pod, priority) && !preempted[podKey(podInfo.Pod)] {
+			candidates = append(candidates, podInfo.Pod)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return corev1helpers.PodPriority(candidates[i]) > corev1helpers.PodPriority(candidates[j])
+	})
+
+	nodeName := nodeInfo.Node().Name
+	var victims []*apiv1.Pod
+	err, cleanupErr := clustersnapshot.WithForkedSnapshot(clusterSnapshot, func() (bool, error) {
+		for _, candidate := range candidates {
+			if err := clusterSnapshot.RemovePod(candidate.Namespace, candidate.Name, nodeName); err != nil {
+				return false, err
+			}
+		}
+		if predicateErr := p.predicateChecker.CheckPredicates(clusterSnapshot, pod, nodeName); predicateErr != nil {
+			return false, nil
+		}
+		if err := clusterSnapshot.AddPod(pod, nodeName); err != nil {
+			return false, err
+		}
+		for _, candidate := range candidates {
+			if predicateErr := p.predicateChecker.CheckPredicates(clusterSnapshot, candidate, nodeName); predicateErr == nil {
+				if err := clusterSnapshot.AddPod(candidate, nodeName); err != nil {
+					return false, err
+				}
+			} else {
+				victims = append(victims, candidate)
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return victims, cleanupErr
+}
+
+// canPreempt tells if the pod may preempt other pods. Headroom placeholders
+// don't exist in the cluster, so they can't preempt anything.
+func canPreempt(pod *apiv1.Pod) bool {
+	if pod_util.IsHeadroomPlaceholder(pod) {
+		return false
+	}
+	return pod.Spec.PreemptionPolicy == nil || *pod.Spec.PreemptionPolicy != apiv1.PreemptNever
+}
+
+// isPreemptible tells if the pod can be preempted by a pod with the priority.
+// Only pods bound to the node are taken into account, not pending pods packed
+// onto it in the snapshot. Headroom placeholders don't exist in the cluster,
+// and mirror pods would be recreated by the kubelet right away.
+func isPreemptible(pod *apiv1.Pod, priority int32) bool {
+	if pod.Spec.NodeName == "" || pod_util.IsHeadroomPlaceholder(pod) || pod_util.IsMirrorPod(pod) {
+		return false
+	}
+	return corev1helpers.PodPriority(pod) < priority
+}
+
+func podKey(pod *apiv1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
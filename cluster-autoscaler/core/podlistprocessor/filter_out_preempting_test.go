@@ -0,0 +1,271 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podlistprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+)
+
+func TestFilterOutPreempting(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	never := apiv1.PreemptNever
+
+	neverPreempting := buildPriorityTestPod("never", 1000, 10, 100)
+	neverPreempting.Spec.PreemptionPolicy = &never
+	placeholder := buildPriorityTestPod("placeholder", 1500, 10, 0)
+	placeholder.Annotations = map[string]string{pod_util.HeadroomPlaceholderAnnotationKey: "ng"}
+	longPending := buildPriorityTestPod("long-pending", 1000, 10, 100)
+	longPending.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+	recentlyPending := buildPriorityTestPod("recently-pending", 1000, 10, 100)
+	recentlyPending.CreationTimestamp = metav1.NewTime(now.Add(-time.Minute))
+
+	testCases := map[string]struct {
+		scheduledPods           []*apiv1.Pod
+		unschedulableCandidates []*apiv1.Pod
+		delay                   time.Duration
+		expectedUnscheduledPods []*apiv1.Pod
+	}{
+		"no lower priority pods": {
+			scheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("scheduled", 1500, 10, 100),
+			},
+			unschedulableCandidates: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 100),
+			},
+			expectedUnscheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 100),
+			},
+		},
+		"lower priority pod preempted": {
+			scheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("victim", 1500, 10, 0),
+			},
+			unschedulableCandidates: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 100),
+			},
+		},
+		"preemption not enough": {
+			scheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("scheduled", 1500, 10, 100),
+				buildPriorityTestPod("victim", 500, 10, 0),
+			},
+			unschedulableCandidates: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 100),
+			},
+			expectedUnscheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 100),
+			},
+		},
+		"victims preempted once, higher priority pods first": {
+			scheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("victim", 1500, 10, 0),
+			},
+			unschedulableCandidates: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 50),
+				buildPriorityTestPod("important", 1000, 10, 100),
+			},
+			expectedUnscheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 50),
+			},
+		},
+		"each victim preempted once": {
+			scheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("victim1", 1000, 10, 0),
+				buildPriorityTestPod("victim2", 1000, 10, 0),
+			},
+			unschedulableCandidates: []*apiv1.Pod{
+				buildPriorityTestPod("pending1", 1000, 10, 100),
+				buildPriorityTestPod("pending2", 1000, 10, 100),
+				buildPriorityTestPod("pending3", 1000, 10, 100),
+			},
+			expectedUnscheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("pending3", 1000, 10, 100),
+			},
+		},
+		"victims reprieved": {
+			scheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("scheduled", 500, 10, 100),
+				buildPriorityTestPod("victim1", 500, 10, 5),
+				buildPriorityTestPod("victim2", 1000, 10, 1),
+			},
+			unschedulableCandidates: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 10),
+			},
+		},
+		"pod never preempting": {
+			scheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("victim", 1500, 10, 0),
+			},
+			unschedulableCandidates: []*apiv1.Pod{
+				neverPreempting,
+			},
+			expectedUnscheduledPods: []*apiv1.Pod{
+				neverPreempting,
+			},
+		},
+		"headroom placeholder not preempted": {
+			scheduledPods: []*apiv1.Pod{
+				placeholder,
+			},
+			unschedulableCandidates: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 100),
+			},
+			expectedUnscheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("pending", 1000, 10, 100),
+			},
+		},
+		"pod pending for over delay": {
+			scheduledPods: []*apiv1.Pod{
+				buildPriorityTestPod("victim1", 1000, 10, 0),
+				buildPriorityTestPod("victim2", 1000, 10, 0),
+			},
+			unschedulableCandidates: []*apiv1.Pod{
+				longPending,
+				recentlyPending,
+			},
+			delay: 5 * time.Minute,
+			expectedUnscheduledPods: []*apiv1.Pod{
+				longPending,
+			},
+		},
+	}
+
+	for tn, tc := range testCases {
+		t.Run(tn, func(t *testing.T) {
+			clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+			predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+			assert.NoError(t, err)
+
+			node := buildReadyTestNode("node", 2000, 100)
+			assert.NoError(t, clusterSnapshot.AddNode(node))
+			for _, pod := range tc.scheduledPods {
+				pod.Spec.NodeName = node.Name
+				assert.NoError(t, clusterSnapshot.AddPod(pod, node.Name))
+			}
+
+			processor := NewFilterOutPreemptingPodListProcessor(predicateChecker)
+			unschedulablePods, err := processor.filterOutPreempting(tc.unschedulableCandidates, clusterSnapshot, tc.delay, now)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.expectedUnscheduledPods, unschedulablePods, "unschedulable pods differ")
+
+			nodeInfo, err := clusterSnapshot.NodeInfos().Get(node.Name)
+			assert.NoError(t, err)
+			var scheduledPods []*apiv1.Pod
+			for _, podInfo := range nodeInfo.Pods {
+				pod := podInfo.Pod.DeepCopy()
+				pod.Spec.NodeName = ""
+				scheduledPods = append(scheduledPods, pod)
+			}
+			var expectedScheduledPods []*apiv1.Pod
+			for _, pod := range tc.scheduledPods {
+				pod = pod.DeepCopy()
+				pod.Spec.NodeName = ""
+				expectedScheduledPods = append(expectedScheduledPods, pod)
+			}
+			assert.ElementsMatch(t, expectedScheduledPods, scheduledPods, "snapshot changed")
+		})
+	}
+}
+
+func TestFilterOutPreemptingPolicy(t *testing.T) {
+	for _, policy := range []PreemptionPolicy{"", NonePreemptionPolicy, SkipPreemptionPolicy, DelayPreemptionPolicy} {
+		t.Run(string(policy), func(t *testing.T) {
+			clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+			predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+			assert.NoError(t, err)
+
+			node := buildReadyTestNode("node", 2000, 100)
+			assert.NoError(t, clusterSnapshot.AddNode(node))
+			victim := buildPriorityTestPod("victim", 1500, 10, 0)
+			victim.Spec.NodeName = node.Name
+			assert.NoError(t, clusterSnapshot.AddPod(victim, node.Name))
+			pending := buildPriorityTestPod("pending", 1000, 10, 100)
+			pending.CreationTimestamp = metav1.NewTime(time.Now())
+
+			ctx := &context.AutoscalingContext{
+				AutoscalingOptions: config.AutoscalingOptions{
+					ScaleUpPreemptionPolicy: string(policy),
+					ScaleUpPreemptionDelay:  time.Hour,
+				},
+				ClusterSnapshot: clusterSnapshot,
+			}
+			unschedulablePods, err := NewFilterOutPreemptingPodListProcessor(predicateChecker).Process(ctx, []*apiv1.Pod{pending})
+			assert.NoError(t, err)
+			if policy == SkipPreemptionPolicy || policy == DelayPreemptionPolicy {
+				assert.Empty(t, unschedulablePods)
+			} else {
+				assert.Equal(t, []*apiv1.Pod{pending}, unschedulablePods)
+			}
+		})
+	}
+}
+
+func TestFilterOutPreemptingLoopStartTime(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+
+	node := buildReadyTestNode("node", 2000, 100)
+	assert.NoError(t, clusterSnapshot.AddNode(node))
+	victim := buildPriorityTestPod("victim", 1500, 10, 0)
+	victim.Spec.NodeName = node.Name
+	assert.NoError(t, clusterSnapshot.AddPod(victim, node.Name))
+	pending := buildPriorityTestPod("pending", 1000, 10, 100)
+	pending.CreationTimestamp = metav1.NewTime(now.Add(-10 * time.Minute))
+
+	for loopStartTime, wantUnschedulable := range map[time.Time]bool{
+		now.Add(-9 * time.Minute): false,
+		now:                       true,
+	} {
+		ctx := &context.AutoscalingContext{
+			AutoscalingOptions: config.AutoscalingOptions{
+				ScaleUpPreemptionPolicy: string(DelayPreemptionPolicy),
+				ScaleUpPreemptionDelay:  5 * time.Minute,
+			},
+			ClusterSnapshot: clusterSnapshot,
+			LoopStartTime:   loopStartTime,
+		}
+		unschedulablePods, err := NewFilterOutPreemptingPodListProcessor(predicateChecker).Process(ctx, []*apiv1.Pod{pending})
+		assert.NoError(t, err)
+		if wantUnschedulable {
+			assert.Equal(t, []*apiv1.Pod{pending}, unschedulablePods, "loop start time: %v", loopStartTime)
+		} else {
+			assert.Empty(t, unschedulablePods, "loop start time: %v", loopStartTime)
+		}
+	}
+}
+
+func TestParsePreemptionPolicy(t *testing.T) {
+	for _, name := range []string{"None", "Skip", "Delay"} {
+		policy, err := ParsePreemptionPolicy(name)
+		assert.NoError(t, err)
+		assert.Equal(t, PreemptionPolicy(name), policy)
+	}
+	_, err := ParsePreemptionPolicy("Preempt")
+	assert.Error(t, err)
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podlistprocessor
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type headroomPodListProcessor struct {
+	nodeGroupConfigProcessor nodegroupconfig.NodeGroupConfigProcessor
+}
+
+// NewHeadroomPodListProcessor returns a new processor adding placeholder pods
+// keeping spare capacity of node groups to the unschedulable pods.
+func NewHeadroomPodListProcessor(nodeGroupConfigProcessor nodegroupconfig.NodeGroupConfigProcessor) *headroomPodListProcessor {
+	return &headroomPodListProcessor{
+		nodeGroupConfigProcessor: nodeGroupConfigProcessor,
+	}
+}
+
+// Process adds a placeholder pod requesting the capacity of a whole node for
+// each spare node the node groups should keep. Placeholders which fit on
+// existing nodes are added to the cluster snapshot by the processors filtering
+// out schedulable pods, and block removal of these nodes. Placeholders which
+// don't fit trigger scale-up.
+func (p *headroomPodListProcessor) Process(context *context.AutoscalingContext, unschedulablePods []*apiv1.Pod) ([]*apiv1.Pod, error) {
+	var nodeInfosForGroups map[string]*schedulerframework.NodeInfo
+	for _, nodeGroup := range context.CloudProvider.NodeGroups() {
+		count := p.headroomNodes(nodeGroup)
+		if count <= 0 {
+			continue
+		}
+		if nodeInfosForGroups == nil {
+			nodeInfosForGroups = sampleNodeInfosForGroups(context)
+		}
+		nodeInfo, found := nodeInfosForGroups[nodeGroup.Id()]
+		if !found {
+			var err error
+			nodeInfo, err = nodeGroup.TemplateNodeInfo()
+			if err != nil {
+				klog.Warningf("Couldn't get template node info of node group %s, not keeping its headroom: %v", nodeGroup.Id(), err)
+				continue
+			}
+		}
+		placeholders, err := headroomPlaceholders(nodeGroup.Id(), nodeInfo, count, int32(context.ExpendablePodsPriorityCutoff), taints.NewTaintConfig(context.AutoscalingOptions))
+		if err != nil {
+			klog.Warningf("Not keeping headroom of node group %s: %v", nodeGroup.Id(), err)
+			continue
+		}
+		klog.V(4).Infof("Adding %d headroom placeholder pods for node group %s", len(placeholders), nodeGroup.Id())
+		unschedulablePods = append(unschedulablePods, placeholders...)
+	}
+	return unschedulablePods, nil
+}
+
+func (p *headroomPodListProcessor) CleanUp() {
+}
+
+// headroomNodes returns the number of spare nodes the node group should keep.
+func (p *headroomPodListProcessor) headroomNodes(nodeGroup cloudprovider.NodeGroup) int {
+	nodes, err := p.nodeGroupConfigProcessor.GetHeadroomNodes(nodeGroup)
+	if err != nil {
+		klog.Warningf("Couldn't get headroom nodes of node group %s: %v", nodeGroup.Id(), err)
+		return 0
+	}
+	ratio, err := p.nodeGroupConfigProcessor.GetHeadroomRatio(nodeGroup)
+	if err != nil {
+		klog.Warningf("Couldn't get headroom ratio of node group %s: %v", nodeGroup.Id(), err)
+		return nodes
+	}
+	if ratio <= 0 {
+		return nodes
+	}
+	targetSize, err := nodeGroup.TargetSize()
+	if err != nil {
+		klog.Warningf("Couldn't get target size of node group %s: %v", nodeGroup.Id(), err)
+		return nodes
+	}
+	if ratioNodes := int(math.Ceil(ratio * float64(targetSize))); ratioNodes > nodes {
+		return ratioNodes
+	}
+	return nodes
+}
+
+// sampleNodeInfosForGroups returns a node of each node group from the cluster
+// snapshot, since existing nodes describe the capacity and the daemon set pods
+// of the node group more accurately than templates.
+func sampleNodeInfosForGroups(context *context.AutoscalingContext) map[string]*schedulerframework.NodeInfo {
+	result := make(map[string]*schedulerframework.NodeInfo)
+	nodeInfos, err := context.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Failed to list node infos: %v", err)
+		return result
+	}
+	for _, nodeInfo := range nodeInfos {
+		nodeGroup, err := context.CloudProvider.NodeGroupForNode(nodeInfo.Node())
+		if err != nil || nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+			continue
+		}
+		if _, found := result[nodeGroup.Id()]; !found {
+			result[nodeGroup.Id()] = nodeInfo
+		}
+	}
+	return result
+}
+
+// headroomPlaceholders builds placeholder pods requesting the allocatable
+// resources of the node which aren't requested by daemon set and mirror pods.
+// The placeholders only fit on nodes with the same labels, except hostname
+// and zone, and tolerate the taints of the node group, but not the transient
+// ones, e.g. of nodes being deleted.
+func headroomPlaceholders(nodeGroupId string, nodeInfo *schedulerframework.NodeInfo, count int, priority int32, taintConfig taints.TaintConfig) ([]*apiv1.Pod, error) {
+	node := nodeInfo.Node()
+	cpu := node.Status.Allocatable.Cpu().DeepCopy()
+	memory := node.Status.Allocatable.Memory().DeepCopy()
+	for _, podInfo := range nodeInfo.Pods {
+		if !pod_util.IsDaemonSetPod(podInfo.Pod) && !pod_util.IsMirrorPod(podInfo.Pod) {
+			continue
+		}
+		for _, container := range podInfo.Pod.Spec.Containers {
+			cpu.Sub(*container.Resources.Requests.Cpu())
+			memory.Sub(*container.Resources.Requests.Memory())
+		}
+	}
+	if cpu.Sign() <= 0 || memory.Sign() <= 0 {
+		return nil, fmt.Errorf("no cpu or memory of node %s is left to reserve", node.Name)
+	}
+
+	nodeSelector := make(map[string]string)
+	for key, value := range node.Labels {
+		if key == apiv1.LabelHostname || key == apiv1.LabelTopologyZone || key == apiv1.LabelFailureDomainBetaZone {
+			continue
+		}
+		nodeSelector[key] = value
+	}
+	var tolerations []apiv1.Toleration
+	for _, taint := range taints.SanitizeTaints(node.Spec.Taints, taintConfig) {
+		tolerations = append(tolerations, apiv1.Toleration{Key: taint.Key, Operator: apiv1.TolerationOpExists, Effect: taint.Effect})
+	}
+
+	placeholders := make([]*apiv1.Pod, 0, count)
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("headroom-placeholder-%s-%d", nodeGroupId, i)
+		placeholders = append(placeholders, &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: metav1.NamespaceSystem,
+				UID:       types.UID(name),
+				Annotations: map[string]string{
+					pod_util.HeadroomPlaceholderAnnotationKey: nodeGroupId,
+				},
+			},
+			Spec: apiv1.PodSpec{
+				Containers: []apiv1.Container{{
+					Name: "placeholder",
+					Resources: apiv1.ResourceRequirements{
+						Requests: apiv1.ResourceList{
+							apiv1.ResourceCPU:    cpu,
+							apiv1.ResourceMemory: memory,
+						},
+					},
+				}},
+				NodeSelector: nodeSelector,
+				Tolerations:  tolerations,
+				Priority:     &priority,
+			},
+			Status: apiv1.PodStatus{
+				Phase: apiv1.PodPending,
+			},
+		})
+	}
+	return placeholders, nil
+}
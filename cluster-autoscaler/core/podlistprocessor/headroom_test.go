@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package podlistprocessor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestHeadroomPodListProcessor(t *testing.T) {
+	n1 := BuildTestNode("n1", 4000, 8000)
+	n1.Labels = map[string]string{
+		apiv1.LabelHostname:           "n1",
+		apiv1.LabelTopologyZone:       "zone-a",
+		apiv1.LabelInstanceTypeStable: "large",
+	}
+	n1.Spec.Taints = []apiv1.Taint{
+		{Key: "dedicated", Value: "batch", Effect: apiv1.TaintEffectNoSchedule},
+		{Key: taints.ToBeDeletedTaint, Effect: apiv1.TaintEffectNoSchedule},
+	}
+	n2 := BuildTestNode("n2", 1000, 2000)
+	ds := SetDSPodSpec(BuildTestPod("ds", 500, 1000))
+	ds.Spec.NodeName = "n1"
+	regular := BuildTestPod("regular", 1000, 1000)
+	regular.Spec.NodeName = "n1"
+	template := schedulerframework.NewNodeInfo()
+	template.SetNode(BuildTestNode("template", 2000, 4000))
+
+	provider := testprovider.NewTestAutoprovisioningCloudProvider(nil, nil, nil, nil, nil, map[string]*schedulerframework.NodeInfo{"empty": template})
+	provider.AddNodeGroupWithCustomOptions("nodes", 1, 10, 2, &config.NodeGroupAutoscalingOptions{HeadroomNodes: 1})
+	provider.AddNode("nodes", n1)
+	provider.AddNodeGroupWithCustomOptions("ratio", 1, 10, 10, &config.NodeGroupAutoscalingOptions{HeadroomNodes: 1, HeadroomRatio: 0.25})
+	provider.AddNode("ratio", n2)
+	provider.AddNodeGroup("none", 1, 10, 1)
+	provider.AddNodeGroupWithCustomOptions("empty", 0, 10, 0, &config.NodeGroupAutoscalingOptions{HeadroomNodes: 2})
+	provider.AddNodeGroupWithCustomOptions("missing-template", 0, 10, 0, &config.NodeGroupAutoscalingOptions{HeadroomNodes: 2})
+
+	ctx := context.AutoscalingContext{
+		CloudProvider:   provider,
+		ClusterSnapshot: clustersnapshot.NewBasicClusterSnapshot(),
+		AutoscalingOptions: config.AutoscalingOptions{
+			ExpendablePodsPriorityCutoff: -10,
+		},
+	}
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, ctx.ClusterSnapshot, []*apiv1.Node{n1, n2}, []*apiv1.Pod{ds, regular})
+
+	unschedulable := BuildTestPod("unschedulable", 100, 100)
+	processor := NewHeadroomPodListProcessor(nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{}))
+	pods, err := processor.Process(&ctx, []*apiv1.Pod{unschedulable})
+	assert.NoError(t, err)
+	assert.Equal(t, unschedulable, pods[0])
+
+	placeholders := make(map[string][]*apiv1.Pod)
+	for _, pod := range pods[1:] {
+		assert.True(t, pod_util.IsHeadroomPlaceholder(pod))
+		nodeGroupId := pod.Annotations[pod_util.HeadroomPlaceholderAnnotationKey]
+		placeholders[nodeGroupId] = append(placeholders[nodeGroupId], pod)
+	}
+	assert.Len(t, placeholders["nodes"], 1)
+	assert.Len(t, placeholders["ratio"], 3)
+	assert.Len(t, placeholders["none"], 0)
+	assert.Len(t, placeholders["empty"], 2)
+	assert.Len(t, placeholders["missing-template"], 0)
+
+	// Placeholders request what daemon set pods leave of the node to pods of the node group.
+	placeholder := placeholders["nodes"][0]
+	assert.Equal(t, map[string]string{apiv1.LabelInstanceTypeStable: "large"}, placeholder.Spec.NodeSelector)
+	assert.Equal(t, int64(3500), placeholder.Spec.Containers[0].Resources.Requests.Cpu().MilliValue())
+	assert.Equal(t, int64(7000), placeholder.Spec.Containers[0].Resources.Requests.Memory().Value())
+	assert.Equal(t, []apiv1.Toleration{{Key: "dedicated", Operator: apiv1.TolerationOpExists, Effect: apiv1.TaintEffectNoSchedule}}, placeholder.Spec.Tolerations)
+	assert.Equal(t, int32(-10), *placeholder.Spec.Priority)
+	assert.Equal(t, int64(2000), placeholders["empty"][0].Spec.Containers[0].Resources.Requests.Cpu().MilliValue())
+	assert.NotEqual(t, placeholders["empty"][0].UID, placeholders["empty"][1].UID)
+}
@@ -19,6 +19,7 @@ package podlistprocessor
 import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/pods"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
 )
@@ -29,11 +30,13 @@ type defaultPodListProcessor struct {
 
 // NewDefaultPodListProcessor returns a default implementation of the pod list
 // processor, which wraps and sequentially runs other sub-processors.
-func NewDefaultPodListProcessor(predicateChecker predicatechecker.PredicateChecker) *defaultPodListProcessor {
+func NewDefaultPodListProcessor(predicateChecker predicatechecker.PredicateChecker, nodeGroupConfigProcessor nodegroupconfig.NodeGroupConfigProcessor) *defaultPodListProcessor {
 	return &defaultPodListProcessor{
 		processors: []pods.PodListProcessor{
 			NewCurrentlyDrainedNodesPodListProcessor(),
+			NewHeadroomPodListProcessor(nodeGroupConfigProcessor),
 			NewFilterOutSchedulablePodListProcessor(predicateChecker),
+			NewFilterOutPreemptingPodListProcessor(predicateChecker),
 			NewFilterOutDaemonSetPodListProcessor(),
 		},
 	}
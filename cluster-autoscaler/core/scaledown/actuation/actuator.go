@@ -18,10 +18,12 @@ package actuation
 
 import (
 	"strings"
+	"sync"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
@@ -29,15 +31,15 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/budgets"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
@@ -49,8 +51,14 @@ type Actuator struct {
 	clusterState          *clusterstate.ClusterStateRegistry
 	nodeDeletionTracker   *deletiontracker.NodeDeletionTracker
 	nodeDeletionScheduler *GroupDeletionScheduler
-	deleteOptions         options.NodeDeleteOptions
-	drainabilityRules     rules.Rules
+	evictionScheduler     *EvictionScheduler
+	drainCancellations    *DrainCancellations
+	guidedDrain           *GuidedDrain
+	evictionPlans         *EvictionPlans
+	// cordonedNodes are the nodes cordoned in cordon-only mode, nil unless ScaleDownCordonOnly is set.
+	cordonedNodes     *cordonedNodes
+	deleteOptions     options.NodeDeleteOptions
+	drainabilityRules rules.Rules
 	// TODO: Move budget processor to scaledown planner, potentially merge into PostFilteringScaleDownNodeProcessor
 	// This is a larger change to the code structure which impacts some existing actuator unit tests
 	// as well as Cluster Autoscaler implementations that may override ScaleDownSetProcessor
@@ -64,16 +72,37 @@ type Actuator struct {
 type actuatorNodeGroupConfigGetter interface {
 	// GetIgnoreDaemonSetsUtilization returns IgnoreDaemonSetsUtilization value that should be used for a given NodeGroup.
 	GetIgnoreDaemonSetsUtilization(nodeGroup cloudprovider.NodeGroup) (bool, error)
+	// GetMaxGracefulTerminationSec returns MaxGracefulTerminationSec value that should be used for a given NodeGroup.
+	GetMaxGracefulTerminationSec(nodeGroup cloudprovider.NodeGroup) (int, error)
+	// GetMaxPodEvictionsPerMinute returns MaxPodEvictionsPerMinute value that should be used for a given NodeGroup.
+	GetMaxPodEvictionsPerMinute(nodeGroup cloudprovider.NodeGroup) (int, error)
 }
 
 // NewActuator returns a new instance of Actuator.
 func NewActuator(ctx *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, ndt *deletiontracker.NodeDeletionTracker, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, configGetter actuatorNodeGroupConfigGetter) *Actuator {
 	ndb := NewNodeDeletionBatcher(ctx, csr, ndt, ctx.NodeDeletionBatcherInterval)
+	evictionScheduler := NewEvictionScheduler()
+	drainCancellations := NewDrainCancellations()
+	evictionRateLimiter := NewEvictionRateLimiter(ctx.CloudProvider, ctx.MaxPodEvictionsPerMinute, configGetter)
+	var guidedDrain *GuidedDrain
+	if ctx.GuidedDrain {
+		guidedDrain = NewGuidedDrain()
+	}
+	evictionPlans := NewEvictionPlans()
+	var cordoned *cordonedNodes
+	if ctx.ScaleDownCordonOnly {
+		cordoned = newCordonedNodes(ctx.MaxCordonedNodeDeletionTime)
+	}
 	return &Actuator{
 		ctx:                       ctx,
 		clusterState:              csr,
 		nodeDeletionTracker:       ndt,
-		nodeDeletionScheduler:     NewGroupDeletionScheduler(ctx, ndt, ndb, NewDefaultEvictor(deleteOptions, drainabilityRules, ndt)),
+		nodeDeletionScheduler:     NewGroupDeletionScheduler(ctx, ndt, ndb, NewDefaultEvictor(deleteOptions, drainabilityRules, ndt, ndt, evictionScheduler, evictionRateLimiter, drainCancellations, guidedDrain, evictionPlans, configGetter)),
+		evictionScheduler:         evictionScheduler,
+		drainCancellations:        drainCancellations,
+		guidedDrain:               guidedDrain,
+		evictionPlans:             evictionPlans,
+		cordonedNodes:             cordoned,
 		budgetProcessor:           budgets.NewScaleDownBudgetProcessor(ctx),
 		deleteOptions:             deleteOptions,
 		drainabilityRules:         drainabilityRules,
@@ -82,8 +111,17 @@ func NewActuator(ctx *context.AutoscalingContext, csr *clusterstate.ClusterState
 	}
 }
 
-// CheckStatus should returns an immutable snapshot of ongoing deletions.
+// DrainCancellations returns the cancellations of in-progress drains, checked before each group of evictions.
+func (a *Actuator) DrainCancellations() *DrainCancellations {
+	return a.drainCancellations
+}
+
+// CheckStatus should returns an immutable snapshot of ongoing deletions. In cordon-only mode, deletions of cordoned
+// nodes end once the nodes are gone, or once they time out.
 func (a *Actuator) CheckStatus() scaledown.ActuationStatus {
+	if a.cordonedNodes != nil {
+		a.cordonedNodes.endDeletions(a.ctx.AllNodeLister(), a.ctx.ClientSet, a.ctx.Recorder, a.nodeDeletionTracker, time.Now())
+	}
 	return a.nodeDeletionTracker.Snapshot()
 }
 
@@ -102,14 +140,23 @@ func (a *Actuator) StartDeletion(empty, drain []*apiv1.Node) (*status.ScaleDownS
 	scaleDownStatus := &status.ScaleDownStatus{NodeDeleteResults: results, NodeDeleteResultsAsOf: ts}
 
 	emptyToDelete, drainToDelete := a.budgetProcessor.CropNodes(a.nodeDeletionTracker, empty, drain)
+	if a.ctx.EvictionDryRunPreflight && len(drainToDelete) > 0 {
+		drainToDelete = a.preflightEvictions(drainToDelete)
+	}
 	if len(emptyToDelete) == 0 && len(drainToDelete) == 0 {
 		scaleDownStatus.Result = status.ScaleDownNoNodeDeleted
 		return scaleDownStatus, nil
 	}
+	if a.cordonedNodes != nil {
+		return a.cordonNodes(emptyToDelete, drainToDelete, scaleDownStatus)
+	}
+	if a.guidedDrain != nil && len(drainToDelete) > 0 {
+		a.planGuidedDrain(emptyToDelete, drainToDelete)
+	}
 
 	if len(emptyToDelete) > 0 {
-		// Taint all empty nodes synchronously
-		if err := a.taintNodesSync(emptyToDelete); err != nil {
+		// Taint all empty nodes synchronously, in parallel if they are deleted in bulk.
+		if err := a.taintNodesSync(emptyToDelete, a.emptyNodeTaintParallelism()); err != nil {
 			scaleDownStatus.Result = status.ScaleDownError
 			return scaleDownStatus, err
 		}
@@ -121,7 +168,7 @@ func (a *Actuator) StartDeletion(empty, drain []*apiv1.Node) (*status.ScaleDownS
 	if len(drainToDelete) > 0 {
 		// Taint all nodes that need drain synchronously, but don't start any drain/deletion yet. Otherwise, pods evicted from one to-be-deleted node
 		// could get recreated on another.
-		if err := a.taintNodesSync(drainToDelete); err != nil {
+		if err := a.taintNodesSync(drainToDelete, 1); err != nil {
 			scaleDownStatus.Result = status.ScaleDownError
 			return scaleDownStatus, err
 		}
@@ -160,34 +207,67 @@ func (a *Actuator) deleteAsyncEmpty(NodeGroupViews []*budgets.NodeGroupView) (re
 	return reportedSDNodes
 }
 
-// taintNodesSync synchronously taints all provided nodes with NoSchedule. If tainting fails for any of the nodes, already
-// applied taints are cleaned up.
-func (a *Actuator) taintNodesSync(NodeGroupViews []*budgets.NodeGroupView) errors.AutoscalerError {
+// taintNodesSync synchronously taints all provided nodes with NoSchedule, up to parallelism nodes at a time. If tainting
+// fails for any of the nodes, no more nodes are tainted and already applied taints are cleaned up.
+func (a *Actuator) taintNodesSync(NodeGroupViews []*budgets.NodeGroupView, parallelism int) errors.AutoscalerError {
 	var taintedNodes []*apiv1.Node
+	var failedNode *apiv1.Node
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
 	var updateLatencyTracker *UpdateLatencyTracker
 	if a.ctx.AutoscalingOptions.DynamicNodeDeleteDelayAfterTaintEnabled {
 		updateLatencyTracker = NewUpdateLatencyTracker(a.ctx.AutoscalingKubeClients.ListerRegistry.AllNodeLister())
 		go updateLatencyTracker.Start()
 	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	tokens := make(chan struct{}, parallelism)
+	failed := func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return failedNode != nil
+	}
 	for _, bucket := range NodeGroupViews {
 		for _, node := range bucket.Nodes {
+			tokens <- struct{}{}
+			if failed() {
+				<-tokens
+				break
+			}
 			if a.ctx.AutoscalingOptions.DynamicNodeDeleteDelayAfterTaintEnabled {
 				updateLatencyTracker.StartTimeChan <- nodeTaintStartTime{node.Name, time.Now()}
 			}
-			err := a.taintNode(node)
-			if err != nil {
-				a.ctx.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to mark the node as toBeDeleted/unschedulable: %v", err)
-				// Clean up already applied taints in case of issues.
-				for _, taintedNode := range taintedNodes {
-					_, _ = taints.CleanToBeDeleted(taintedNode, a.ctx.ClientSet, a.ctx.CordonNodeBeforeTerminate)
-				}
-				if a.ctx.AutoscalingOptions.DynamicNodeDeleteDelayAfterTaintEnabled {
-					close(updateLatencyTracker.AwaitOrStopChan)
+			wg.Add(1)
+			go func(node *apiv1.Node) {
+				defer func() {
+					<-tokens
+					wg.Done()
+				}()
+				err := a.taintNode(node)
+				mutex.Lock()
+				defer mutex.Unlock()
+				if err != nil {
+					a.ctx.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to mark the node as toBeDeleted/unschedulable: %v", err)
+					if failedNode == nil {
+						failedNode = node
+					}
+					return
 				}
-				return errors.NewAutoscalerError(errors.ApiCallError, "couldn't taint node %q with ToBeDeleted", node)
-			}
-			taintedNodes = append(taintedNodes, node)
+				taintedNodes = append(taintedNodes, node)
+			}(node)
+		}
+	}
+	wg.Wait()
+	if failedNode != nil {
+		// Clean up already applied taints in case of issues.
+		for _, taintedNode := range taintedNodes {
+			_, _ = taints.CleanToBeDeleted(taintedNode, a.ctx.ClientSet, a.ctx.CordonNodeBeforeTerminate)
+		}
+		if a.ctx.AutoscalingOptions.DynamicNodeDeleteDelayAfterTaintEnabled {
+			close(updateLatencyTracker.AwaitOrStopChan)
 		}
+		return errors.NewAutoscalerError(errors.ApiCallError, "couldn't taint node %q with ToBeDeleted", failedNode)
 	}
 	if a.ctx.AutoscalingOptions.DynamicNodeDeleteDelayAfterTaintEnabled {
 		updateLatencyTracker.AwaitOrStopChan <- true
@@ -202,6 +282,15 @@ func (a *Actuator) taintNodesSync(NodeGroupViews []*budgets.NodeGroupView) error
 	return nil
 }
 
+// emptyNodeTaintParallelism returns how many empty nodes can be tainted at a time. Empty nodes deleted one at a time
+// are also tainted one at a time, otherwise up to MaxScaleDownParallelism nodes are tainted at a time.
+func (a *Actuator) emptyNodeTaintParallelism() int {
+	if a.ctx.MaxEmptyNodeDeletionBatchSize <= 1 {
+		return 1
+	}
+	return a.ctx.MaxScaleDownParallelism
+}
+
 // deleteAsyncDrain asynchronously starts deletions with drain for all provided nodes. scaledDownNodes return value contains all nodes for which
 // deletion successfully started.
 func (a *Actuator) deleteAsyncDrain(NodeGroupViews []*budgets.NodeGroupView) (reportedSDNodes []*status.ScaleDownNode) {
@@ -249,7 +338,7 @@ func (a *Actuator) deleteNodesAsync(nodes []*apiv1.Node, nodeGroup cloudprovider
 		return
 	}
 
-	if drain {
+	if drain || a.deleteOptions.RespectDaemonSetPdbs {
 		pdbs, err := a.ctx.PodDisruptionBudgetLister().List()
 		if err != nil {
 			klog.Errorf("Scale-down: couldn't fetch pod disruption budgets, err: %v", err)
@@ -261,6 +350,8 @@ func (a *Actuator) deleteNodesAsync(nodes []*apiv1.Node, nodeGroup cloudprovider
 		}
 		remainingPdbTracker = pdb.NewBasicRemainingPdbTracker()
 		remainingPdbTracker.SetPdbs(pdbs)
+	}
+	if drain {
 		registry = a.ctx.ListerRegistry
 	}
 
@@ -268,6 +359,9 @@ func (a *Actuator) deleteNodesAsync(nodes []*apiv1.Node, nodeGroup cloudprovider
 		batchSize = len(nodes)
 	}
 
+	// Empty nodes are deleted in bulk, unless they should be deleted one at a time.
+	bulk := !drain && a.ctx.MaxEmptyNodeDeletionBatchSize > 1
+	var emptyNodeInfos []*framework.NodeInfo
 	for _, node := range nodes {
 		nodeInfo, err := clusterSnapshot.NodeInfos().Get(node.Name)
 		if err != nil {
@@ -277,13 +371,14 @@ func (a *Actuator) deleteNodesAsync(nodes []*apiv1.Node, nodeGroup cloudprovider
 			continue
 		}
 
-		podsToRemove, _, _, err := simulator.GetPodsToMove(nodeInfo, a.deleteOptions, a.drainabilityRules, registry, remainingPdbTracker, time.Now())
-		if err != nil {
+		simulation := podsToMove(a.ctx)(nodeInfo, a.deleteOptions, a.drainabilityRules, registry, remainingPdbTracker, time.Now())
+		if err := simulation.Err; err != nil {
 			klog.Errorf("Scale-down: couldn't delete node %q, err: %v", node.Name, err)
 			nodeDeleteResult := status.NodeDeleteResult{ResultType: status.NodeDeleteErrorInternal, Err: errors.NewAutoscalerError(errors.InternalError, "GetPodsToMove for %q returned error: %v", node.Name, err)}
 			a.nodeDeletionScheduler.AbortNodeDeletion(node, nodeGroup.Id(), drain, "failed to get pods to move on node", nodeDeleteResult)
 			continue
 		}
+		podsToRemove := simulation.PodsToMove
 
 		if !drain && len(podsToRemove) != 0 {
 			klog.Errorf("Scale-down: couldn't delete empty node %q, new pods got scheduled", node.Name)
@@ -292,8 +387,35 @@ func (a *Actuator) deleteNodesAsync(nodes []*apiv1.Node, nodeGroup cloudprovider
 			continue
 		}
 
+		if remainingPdbTracker != nil {
+			podsToEvict := podsToRemove
+			if a.deleteOptions.RespectDaemonSetPdbs {
+				evictDaemonSetPods := a.ctx.DaemonSetEvictionForEmptyNodes
+				if drain {
+					evictDaemonSetPods = a.ctx.DaemonSetEvictionForOccupiedNodes
+				}
+				podsToEvict = append(podsToEvict[:len(podsToEvict):len(podsToEvict)], daemonset.PodsToEvict(simulation.DaemonSetPods, daemonSetLister(a.ctx), evictDaemonSetPods)...)
+			}
+			// Let the evictions from nodes sharing disruption budgets be ordered, instead of racing for the budgets.
+			a.evictionScheduler.RegisterNode(node.Name, podsToEvict, remainingPdbTracker)
+			// Account for the evictions, so that the remaining nodes can't over-commit the same disruption budget.
+			remainingPdbTracker.RemovePods(simulation.PodsToDisrupt(a.deleteOptions.RespectDaemonSetPdbs))
+		}
+
+		if drain {
+			// Let the pods be evicted in the order determined by the simulation.
+			a.evictionPlans.SetEvictionGroups(node.Name, simulation.EvictionGroups)
+		}
+
+		if bulk {
+			emptyNodeInfos = append(emptyNodeInfos, nodeInfo)
+			continue
+		}
 		go a.nodeDeletionScheduler.ScheduleDeletion(nodeInfo, nodeGroup, batchSize, drain)
 	}
+	if len(emptyNodeInfos) > 0 {
+		go a.nodeDeletionScheduler.ScheduleEmptyDeletions(emptyNodeInfos, nodeGroup, batchSize, a.ctx.MaxEmptyNodeDeletionBatchSize)
+	}
 }
 
 func (a *Actuator) scaleDownNodeToReport(node *apiv1.Node, drain bool) (*status.ScaleDownNode, error) {
@@ -312,7 +434,7 @@ func (a *Actuator) scaleDownNodeToReport(node *apiv1.Node, drain bool) (*status.
 	}
 
 	gpuConfig := a.ctx.CloudProvider.GetNodeGpuConfig(node)
-	utilInfo, err := utilization.Calculate(nodeInfo, ignoreDaemonSetsUtilization, a.ctx.IgnoreMirrorPodsUtilization, gpuConfig, time.Now())
+	utilInfo, err := utilization.Calculate(nodeInfo, ignoreDaemonSetsUtilization, a.ctx.IgnoreMirrorPodsUtilization, gpuConfig, time.Now(), a.ctx.LongTerminatingPodThreshold)
 	if err != nil {
 		return nil, err
 	}
@@ -348,7 +470,7 @@ func (a *Actuator) createSnapshot(nodes []*apiv1.Node) (clustersnapshot.ClusterS
 	}
 
 	scheduledPods := kube_util.ScheduledPods(pods)
-	nonExpendableScheduledPods := utils.FilterOutExpendablePods(scheduledPods, a.ctx.ExpendablePodsPriorityCutoff)
+	nonExpendableScheduledPods := utils.FilterOutExpendablePods(scheduledPods, a.ctx.ExpendablePods)
 
 	for _, node := range nodes {
 		if err := snapshot.AddNode(node); err != nil {
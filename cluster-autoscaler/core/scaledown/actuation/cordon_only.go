@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_client "k8s.io/client-go/kubernetes"
+	kube_record "k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/budgets"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+)
+
+// NodeScaleDownReadyReason is the reason of events emitted on nodes which are ready to be removed by an external
+// system in cordon-only mode.
+const NodeScaleDownReadyReason = "NodeScaleDownReady"
+
+// cordonedNodes tracks nodes cordoned and tainted in cordon-only mode, whose deletion is left to an external system.
+// The nodes are tracked as being deleted until they disappear from the cluster, until the ToBeDeleted taint is
+// removed from them, or until the timeout passes. It is safe for concurrent use.
+type cordonedNodes struct {
+	mutex sync.Mutex
+	// timeout is the maximum time the external system has to delete a node, the nodes are tracked until they are
+	// gone if it isn't positive.
+	timeout time.Duration
+	// nodes maps names of the nodes to their node groups and the times they were cordoned at.
+	nodes map[string]cordonedNode
+}
+
+type cordonedNode struct {
+	nodeGroupId string
+	cordonTime  time.Time
+}
+
+func newCordonedNodes(timeout time.Duration) *cordonedNodes {
+	return &cordonedNodes{timeout: timeout, nodes: make(map[string]cordonedNode)}
+}
+
+func (c *cordonedNodes) add(nodeName, nodeGroupId string, timestamp time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.nodes[nodeName] = cordonedNode{nodeGroupId: nodeGroupId, cordonTime: timestamp}
+}
+
+func (c *cordonedNodes) contains(nodeName string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, found := c.nodes[nodeName]
+	return found
+}
+
+// endDeletions ends deletions of the nodes which disappeared from the cluster, or from which the ToBeDeleted taint
+// was removed in the meantime, e.g. because the external system decided not to delete them. Deletions of nodes which
+// weren't deleted within the timeout end with an error, and the nodes are untainted and uncordoned, so that they
+// can be used again. Nodes which can't be untainted are retried the next time.
+func (c *cordonedNodes) endDeletions(nodeLister kube_util.NodeLister, client kube_client.Interface, recorder kube_record.EventRecorder, ndt *deletiontracker.NodeDeletionTracker, timestamp time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.nodes) == 0 {
+		return
+	}
+	nodes, err := nodeLister.List()
+	if err != nil {
+		klog.Errorf("Failed to list nodes to check if cordoned nodes were removed: %v", err)
+		return
+	}
+	existing := make(map[string]*apiv1.Node, len(nodes))
+	for _, node := range nodes {
+		existing[node.Name] = node
+	}
+	for nodeName, cordoned := range c.nodes {
+		node, found := existing[nodeName]
+		var result status.NodeDeleteResult
+		switch {
+		case !found:
+			result = status.NodeDeleteResult{ResultType: status.NodeDeleteOk}
+		case !taints.HasToBeDeletedTaint(node):
+			result = status.NodeDeleteResult{ResultType: status.NodeDeleteErrorInternal, Err: errors.NewAutoscalerError(errors.InternalError, "ToBeDeleted taint was removed from node %s before it was deleted", nodeName)}
+		case c.timeout > 0 && timestamp.Sub(cordoned.cordonTime) >= c.timeout:
+			if _, err := taints.CleanToBeDeleted(node, client, true); err != nil {
+				klog.Errorf("Scale-down: couldn't untaint node %s which wasn't deleted within %v, err: %v", nodeName, c.timeout, err)
+				continue
+			}
+			klog.Warningf("Scale-down: node %s wasn't deleted within %v after it was cordoned, it was untainted", nodeName, c.timeout)
+			recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "node wasn't deleted within %v after it was marked as toBeDeleted/unschedulable, unmarked it", c.timeout)
+			result = status.NodeDeleteResult{ResultType: status.NodeDeleteErrorFailedToDelete, Err: errors.NewAutoscalerError(errors.TransientError, "node %s wasn't deleted within %v after it was cordoned", nodeName, c.timeout)}
+		default:
+			continue
+		}
+		ndt.EndDeletion(cordoned.nodeGroupId, nodeName, result)
+		delete(c.nodes, nodeName)
+	}
+}
+
+// cordonNodes cordons and taints the nodes with ToBeDeleted instead of draining and deleting them, and emits
+// NodeScaleDownReady events on them, so that an external system can remove them. Nodes which were already cordoned
+// are skipped.
+func (a *Actuator) cordonNodes(emptyToDelete, drainToDelete []*budgets.NodeGroupView, scaleDownStatus *status.ScaleDownStatus) (*status.ScaleDownStatus, errors.AutoscalerError) {
+	var lastErr errors.AutoscalerError
+	cordoned := 0
+	for _, views := range []struct {
+		nodeGroupViews []*budgets.NodeGroupView
+		drain          bool
+	}{{emptyToDelete, false}, {drainToDelete, true}} {
+		for _, bucket := range views.nodeGroupViews {
+			for _, node := range bucket.Nodes {
+				if a.cordonedNodes.contains(node.Name) {
+					continue
+				}
+				if err := taints.MarkToBeDeleted(node, a.ctx.ClientSet, true); err != nil {
+					klog.Errorf("Scale-down: couldn't cordon node %s, err: %v", node.Name, err)
+					a.ctx.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to mark the node as toBeDeleted/unschedulable: %v", err)
+					lastErr = errors.ToAutoscalerError(errors.ApiCallError, err)
+					continue
+				}
+				if views.drain {
+					a.nodeDeletionTracker.StartDeletionWithDrain(bucket.Group.Id(), node.Name)
+				} else {
+					a.nodeDeletionTracker.StartDeletion(bucket.Group.Id(), node.Name)
+				}
+				a.cordonedNodes.add(node.Name, bucket.Group.Id(), time.Now())
+				cordoned++
+
+				klog.V(0).Infof("Scale-down: node %s cordoned, its deletion is left to an external system", node.Name)
+				a.ctx.Recorder.Eventf(node, apiv1.EventTypeNormal, NodeScaleDownReadyReason, "marked the node as toBeDeleted/unschedulable, it can be removed")
+				a.ctx.LogRecorder.Eventf(apiv1.EventTypeNormal, NodeScaleDownReadyReason, "Scale-down: node %s is ready to be removed", node.Name)
+				if sdNode, err := a.scaleDownNodeToReport(node, views.drain); err == nil {
+					scaleDownStatus.ScaledDownNodes = append(scaleDownStatus.ScaledDownNodes, sdNode)
+				} else {
+					klog.Errorf("Scale-down: couldn't report scaled down node, err: %v", err)
+				}
+			}
+		}
+	}
+	switch {
+	case cordoned > 0:
+		scaleDownStatus.Result = status.ScaleDownNodeDeleteStarted
+	case lastErr != nil:
+		scaleDownStatus.Result = status.ScaleDownError
+		return scaleDownStatus, lastErr
+	default:
+		scaleDownStatus.Result = status.ScaleDownNoNodeDeleted
+	}
+	return scaleDownStatus, nil
+}
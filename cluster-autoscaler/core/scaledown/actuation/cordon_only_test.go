@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/budgets"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestStartDeletionCordonOnly(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	fakeClient := fake.NewSimpleClientset(n1, n2)
+	provider := testprovider.NewTestCloudProvider(nil, func(nodeGroup string, node string) error {
+		t.Errorf("Unexpected deletion of node %s", node)
+		return nil
+	})
+	provider.AddNodeGroup("ng1", 0, 10, 2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+	nodeLister := kube_util.NewTestNodeLister([]*apiv1.Node{n1, n2})
+	registry := kube_util.NewListerRegistry(nodeLister, nodeLister, kube_util.NewTestPodLister(nil), nil, nil, nil, nil, nil, nil)
+
+	opts := config.AutoscalingOptions{
+		MaxScaleDownParallelism: 10,
+		MaxDrainParallelism:     5,
+		ScaleDownCordonOnly:     true,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(opts, fakeClient, registry, provider, nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, ctx.ClusterSnapshot.AddNode(n1))
+	assert.NoError(t, ctx.ClusterSnapshot.AddNode(n2))
+	pod := BuildScheduledTestPod("p1", 100, 0, "n2")
+	assert.NoError(t, ctx.ClusterSnapshot.AddPod(pod, "n2"))
+
+	ndt := deletiontracker.NewNodeDeletionTracker(0)
+	actuator := Actuator{
+		ctx:                 &ctx,
+		nodeDeletionTracker: ndt,
+		// Nodes aren't drained nor deleted, so the scheduler doesn't need a batcher and an evictor.
+		nodeDeletionScheduler: NewGroupDeletionScheduler(&ctx, ndt, nil, Evictor{}),
+		cordonedNodes:         newCordonedNodes(time.Hour),
+		budgetProcessor:       budgets.NewScaleDownBudgetProcessor(&ctx),
+		configGetter:          nodegroupconfig.NewDefaultNodeGroupConfigProcessor(ctx.NodeGroupDefaults),
+	}
+
+	scaleDownStatus, err := actuator.StartDeletion([]*apiv1.Node{n1}, []*apiv1.Node{n2})
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNodeDeleteStarted, scaleDownStatus.Result)
+	assert.Len(t, scaleDownStatus.ScaledDownNodes, 2)
+	var cordoned []*apiv1.Node
+	for _, name := range []string{"n1", "n2"} {
+		node, err := fakeClient.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.True(t, node.Spec.Unschedulable, "node %s should be cordoned", name)
+		assert.True(t, taints.HasToBeDeletedTaint(node), "node %s should be tainted", name)
+		cordoned = append(cordoned, node)
+	}
+	nodeLister.SetNodes(cordoned)
+	empty, drained := actuator.CheckStatus().DeletionsInProgress()
+	assert.ElementsMatch(t, []string{"n1"}, empty)
+	assert.ElementsMatch(t, []string{"n2"}, drained)
+
+	// Nodes which are already cordoned are skipped.
+	scaleDownStatus, err = actuator.StartDeletion([]*apiv1.Node{n1}, []*apiv1.Node{n2})
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleDownNoNodeDeleted, scaleDownStatus.Result)
+
+	// n1 was removed by the external system, the taint was removed from n2.
+	nodeLister.SetNodes([]*apiv1.Node{n2})
+	empty, drained = actuator.CheckStatus().DeletionsInProgress()
+	assert.Empty(t, empty)
+	assert.Empty(t, drained)
+	results, _ := ndt.DeletionResults()
+	assert.Equal(t, status.NodeDeleteOk, results["n1"].ResultType)
+	assert.Equal(t, status.NodeDeleteErrorInternal, results["n2"].ResultType)
+}
+
+func TestCordonedNodesTimeout(t *testing.T) {
+	now := time.Now()
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	fakeClient := fake.NewSimpleClientset(n1, n2)
+	var cordoned []*apiv1.Node
+	for _, node := range []*apiv1.Node{n1, n2} {
+		assert.NoError(t, taints.MarkToBeDeleted(node, fakeClient, true))
+		node, err := fakeClient.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		cordoned = append(cordoned, node)
+	}
+	nodeLister := kube_util.NewTestNodeLister(cordoned)
+	recorder := kube_util.CreateEventRecorder(fakeClient, false)
+	ndt := deletiontracker.NewNodeDeletionTracker(0)
+	nodes := newCordonedNodes(time.Hour)
+	for _, name := range []string{"n1", "n2"} {
+		ndt.StartDeletion("ng1", name)
+	}
+	nodes.add("n1", "ng1", now.Add(-2*time.Hour))
+	nodes.add("n2", "ng1", now.Add(-30*time.Minute))
+
+	nodes.endDeletions(nodeLister, fakeClient, recorder, ndt, now)
+	assert.False(t, nodes.contains("n1"))
+	assert.True(t, nodes.contains("n2"))
+	results, _ := ndt.DeletionResults()
+	assert.Equal(t, status.NodeDeleteErrorFailedToDelete, results["n1"].ResultType)
+	assert.Error(t, results["n1"].Err)
+	_, found := results["n2"]
+	assert.False(t, found)
+	for name, wantCordoned := range map[string]bool{"n1": false, "n2": true} {
+		node, err := fakeClient.CoreV1().Nodes().Get(context.TODO(), name, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, wantCordoned, node.Spec.Unschedulable, "node %s", name)
+		assert.Equal(t, wantCordoned, taints.HasToBeDeletedTaint(node), "node %s", name)
+	}
+
+	// Without a timeout, nodes are tracked until they are gone.
+	nodes = newCordonedNodes(0)
+	nodes.add("n2", "ng1", now.Add(-24*time.Hour))
+	nodes.endDeletions(nodeLister, fakeClient, recorder, ndt, now)
+	assert.True(t, nodes.contains("n2"))
+}
@@ -18,6 +18,7 @@ package actuation
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -33,14 +34,20 @@ const (
 	// DelayDeletionAnnotationPrefix is the prefix of annotation marking node as it needs to wait
 	// for other K8s components before deleting node.
 	DelayDeletionAnnotationPrefix = "delay-deletion.cluster-autoscaler.kubernetes.io/"
+	// DefaultDelayDeletionCheckInterval is the time between checks whether delay deletion annotations were removed.
+	DefaultDelayDeletionCheckInterval = 5 * time.Second
 )
 
 // WaitForDelayDeletion waits until the provided node has no annotations beginning with DelayDeletionAnnotationPrefix,
 // or until the provided timeout is reached - whichever comes first.
 func WaitForDelayDeletion(node *apiv1.Node, nodeLister kubernetes.NodeLister, timeout time.Duration) errors.AutoscalerError {
+	return waitForDelayDeletion(node, nodeLister, timeout, DefaultDelayDeletionCheckInterval)
+}
+
+func waitForDelayDeletion(node *apiv1.Node, nodeLister kubernetes.NodeLister, timeout, checkInterval time.Duration) errors.AutoscalerError {
 	if timeout != 0 && hasDelayDeletionAnnotation(node) {
 		klog.V(1).Infof("Wait for removing %s annotations on node %v", DelayDeletionAnnotationPrefix, node.Name)
-		err := wait.Poll(5*time.Second, timeout, func() (bool, error) {
+		err := wait.Poll(checkInterval, timeout, func() (bool, error) {
 			klog.V(5).Infof("Waiting for removing %s annotations on node %v", DelayDeletionAnnotationPrefix, node.Name)
 			freshNode, err := nodeLister.Get(node.Name)
 			if err != nil || freshNode == nil {
@@ -60,6 +67,19 @@ func WaitForDelayDeletion(node *apiv1.Node, nodeLister kubernetes.NodeLister, ti
 	return nil
 }
 
+// delayDeletionHooks returns the names of the hooks delaying deletion of the node, i.e. the suffixes of its
+// annotations beginning with DelayDeletionAnnotationPrefix, sorted.
+func delayDeletionHooks(node *apiv1.Node) []string {
+	var hooks []string
+	for annotation := range node.Annotations {
+		if strings.HasPrefix(annotation, DelayDeletionAnnotationPrefix) {
+			hooks = append(hooks, strings.TrimPrefix(annotation, DelayDeletionAnnotationPrefix))
+		}
+	}
+	sort.Strings(hooks)
+	return hooks
+}
+
 func hasDelayDeletionAnnotation(node *apiv1.Node) bool {
 	for annotation := range node.Annotations {
 		if strings.HasPrefix(annotation, DelayDeletionAnnotationPrefix) {
@@ -28,6 +28,17 @@ import (
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 )
 
+func TestDelayDeletionHooks(t *testing.T) {
+	node := BuildTestNode("n1", 1000, 10)
+	assert.Empty(t, delayDeletionHooks(node))
+	node.Annotations = map[string]string{
+		DelayDeletionAnnotationPrefix + "storage": "true",
+		DelayDeletionAnnotationPrefix + "cmdb":    "true",
+		"other":                                   "true",
+	}
+	assert.Equal(t, []string{"cmdb", "storage"}, delayDeletionHooks(node))
+}
+
 func TestWaitForDelayDeletion(t *testing.T) {
 	type testcase struct {
 		name                 string
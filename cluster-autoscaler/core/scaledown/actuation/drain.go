@@ -18,25 +18,34 @@ package actuation
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	kube_errors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	v1appslister "k8s.io/client-go/listers/apps/v1"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
 	"k8s.io/klog/v2"
+	podv1 "k8s.io/kubernetes/pkg/api/v1/pod"
 
 	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
-	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
@@ -50,33 +59,73 @@ const (
 	DefaultDsEvictionEmptyNodeTimeout = 10 * time.Second
 	// DefaultDsEvictionRetryTime is a time between retries to create eviction that uses for DaemonSet eviction for empty nodes
 	DefaultDsEvictionRetryTime = 3 * time.Second
+	// DefaultReschedulingCheckInterval is the time between checks whether evicted pods have been rescheduled, if
+	// critical pods wait for the rescheduling.
+	DefaultReschedulingCheckInterval = 5 * time.Second
+	// DefaultMaxEvictionRejections is the number of consecutive eviction denials by admission webhooks after which
+	// pods are deleted instead, if options.EvictOrDeleteDrainMode is used.
+	DefaultMaxEvictionRejections = 3
 )
 
+// ErrDaemonSetPodBudget is wrapped by errors of EvictDaemonSetPods when a DaemonSet pod covered by a disruption budget
+// failed to be evicted, so that the node shouldn't be deleted.
+var ErrDaemonSetPodBudget = stderrors.New("DaemonSet pods covered by disruption budgets failed to evict")
+
 type evictionRegister interface {
 	RegisterEviction(*apiv1.Pod)
 }
 
+type drainStatusRegister interface {
+	RegisterDrainStatus(string, status.NodeDrainStatus)
+}
+
 // Evictor can be used to evict pods from nodes.
 type Evictor struct {
 	EvictionRetryTime          time.Duration
 	DsEvictionRetryTime        time.Duration
 	DsEvictionEmptyNodeTimeout time.Duration
 	PodEvictionHeadroom        time.Duration
+	MaxEvictionRejections      int
+	ReschedulingCheckInterval  time.Duration
+	GuidedDrainCheckInterval   time.Duration
 	evictionRegister           evictionRegister
+	drainStatusRegister        drainStatusRegister
 	deleteOptions              options.NodeDeleteOptions
 	drainabilityRules          rules.Rules
+	evictionScheduler          *EvictionScheduler
+	evictionRateLimiter        *EvictionRateLimiter
+	drainCancellations         *DrainCancellations
+	// guidedDrain provides the destination nodes to nominate for pods replacing the evicted ones. Drains aren't
+	// guided if nil.
+	guidedDrain *GuidedDrain
+	// evictionPlans provides the eviction groups determined by the drain simulation of drained nodes. Pods are
+	// grouped by EvictionOrder if nil.
+	evictionPlans *EvictionPlans
+	// configGetter provides per node group MaxGracefulTerminationSec. If nil,
+	// MaxGracefulTerminationSec from the autoscaling context is used.
+	configGetter nodegroupconfig.MaxGracefulTerminationSecGetter
 }
 
 // NewDefaultEvictor returns an instance of Evictor using the default parameters.
-func NewDefaultEvictor(deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, evictionRegister evictionRegister) Evictor {
+func NewDefaultEvictor(deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, evictionRegister evictionRegister, drainStatusRegister drainStatusRegister, evictionScheduler *EvictionScheduler, evictionRateLimiter *EvictionRateLimiter, drainCancellations *DrainCancellations, guidedDrain *GuidedDrain, evictionPlans *EvictionPlans, configGetter nodegroupconfig.MaxGracefulTerminationSecGetter) Evictor {
 	return Evictor{
 		EvictionRetryTime:          DefaultEvictionRetryTime,
 		DsEvictionRetryTime:        DefaultDsEvictionRetryTime,
 		DsEvictionEmptyNodeTimeout: DefaultDsEvictionEmptyNodeTimeout,
 		PodEvictionHeadroom:        DefaultPodEvictionHeadroom,
+		MaxEvictionRejections:      DefaultMaxEvictionRejections,
+		ReschedulingCheckInterval:  DefaultReschedulingCheckInterval,
+		GuidedDrainCheckInterval:   DefaultGuidedDrainCheckInterval,
 		evictionRegister:           evictionRegister,
+		drainStatusRegister:        drainStatusRegister,
 		deleteOptions:              deleteOptions,
 		drainabilityRules:          drainabilityRules,
+		evictionScheduler:          evictionScheduler,
+		evictionRateLimiter:        evictionRateLimiter,
+		drainCancellations:         drainCancellations,
+		guidedDrain:                guidedDrain,
+		evictionPlans:              evictionPlans,
+		configGetter:               configGetter,
 	}
 }
 
@@ -89,23 +138,92 @@ func (e Evictor) DrainNode(ctx *acontext.AutoscalingContext, nodeInfo *framework
 }
 
 // DrainNodeWithPods performs drain logic on the node. Marks the node as unschedulable and later removes all pods, giving
-// them up to MaxGracefulTerminationTime to finish. The list of pods to evict has to be provided.
+// them up to MaxGracefulTerminationTime of the node's node group to finish. The list of pods to evict has to be provided.
+// If NodeDrainTimeout is set, pods are given up to NodeDrainTimeout to finish instead, and are force deleted afterwards.
 func (e Evictor) DrainNodeWithPods(ctx *acontext.AutoscalingContext, node *apiv1.Node, pods []*apiv1.Pod, daemonSetPods []*apiv1.Pod) (map[string]status.PodEvictionResult, error) {
+	defer e.evictionScheduler.ForgetNode(node.Name)
+	defer e.drainCancellations.Forget(node.Name)
+	defer e.guidedDrain.Forget(node.Name)
+	defer e.evictionPlans.Forget(node.Name)
 	evictionResults := make(map[string]status.PodEvictionResult)
+	drainStatus := status.NodeDrainStatus{StartTime: time.Now(), PodsToRemove: len(pods), PodsRemaining: len(pods)}
 	retryUntil := time.Now().Add(ctx.MaxPodEvictionTime)
+	maxGracefulTerminationSec := e.maxGracefulTerminationSec(ctx, node)
+	e.registerDrainStatus(node, &drainStatus, status.NodeDrainEvicting, retryUntil)
 	confirmations := make(chan status.PodEvictionResult, len(pods))
 	daemonSetConfirmations := make(chan status.PodEvictionResult, len(daemonSetPods))
 	for _, pod := range pods {
 		evictionResults[pod.Name] = status.PodEvictionResult{Pod: pod, TimedOut: true, Err: nil}
-		go func(podToEvict *apiv1.Pod) {
-			confirmations <- evictPod(ctx, podToEvict, false, retryUntil, e.EvictionRetryTime, e.evictionRegister)
-		}(pod)
 	}
+	// Pods are evicted lowest priority first, and cheapest first among pods with equal priority: evictions of a
+	// group of pods start once all evictions of the previous groups have been created. With
+	// WaitForReschedulingBeforeCriticalPods, evictions of critical pods also wait for the pods evicted before them to
+	// be rescheduled, and are skipped if they aren't in time. Groups determined by the drain simulation of the node
+	// are kept: with StatefulSetOrdinalOrder, pods of the same StatefulSet are evicted in reverse ordinal order, and
+	// wait for replacements of OrderedReady StatefulSet pods evicted before them to be ready. With
+	// SkipNodeWebhookDenialPolicy, evictions which haven't started yet are skipped once an eviction is denied by an
+	// admission webhook. Evictions of groups which haven't started yet are also skipped once the drain is cancelled.
+	// With guided drain, pods replacing the evicted ones are nominated to the nodes the evicted pods were simulated
+	// to move to.
+	nominator := newReplacementNominator(e.guidedDrain.Destinations(node.Name))
+	if nominator != nil {
+		go nominator.run(ctx, e.GuidedDrainCheckInterval, retryUntil)
+	}
+	var deniedByWebhook atomic.Bool
+	go func() {
+		var evicted []*apiv1.Pod
+		var reschedulingErr, cancellationErr error
+		planned, found := e.evictionPlans.EvictionGroups(node.Name)
+		for _, group := range plannedEvictionGroups(planned, found, pods) {
+			if reason, cancelled := e.drainCancellations.Cancelled(node.Name); cancelled && cancellationErr == nil {
+				cancellationErr = fmt.Errorf("drain of node %s cancelled: %s", node.Name, reason)
+			}
+			if reschedulingErr == nil && cancellationErr == nil && e.shouldWaitForRescheduling(group.Pods, evicted) {
+				reschedulingErr = e.waitForRescheduling(ctx, node, evicted, retryUntil)
+			}
+			if reschedulingErr == nil && cancellationErr == nil && len(group.AwaitReady) > 0 {
+				reschedulingErr = e.waitForReadyReplacements(ctx, node, group.AwaitReady, retryUntil)
+			}
+			var wg sync.WaitGroup
+			for _, pod := range group.Pods {
+				wg.Add(1)
+				go func(podToEvict *apiv1.Pod) {
+					defer wg.Done()
+					if cancellationErr != nil {
+						confirmations <- status.PodEvictionResult{Pod: podToEvict, TimedOut: false, Err: fmt.Errorf("skipped eviction of pod %s/%s: %v", podToEvict.Namespace, podToEvict.Name, cancellationErr)}
+						return
+					}
+					if reschedulingErr != nil {
+						confirmations <- status.PodEvictionResult{Pod: podToEvict, TimedOut: true, Err: fmt.Errorf("skipped eviction of pod %s/%s: %v", podToEvict.Namespace, podToEvict.Name, reschedulingErr)}
+						return
+					}
+					if !e.evictionRateLimiter.Wait(node, retryUntil) {
+						confirmations <- status.PodEvictionResult{Pod: podToEvict, TimedOut: true, Err: fmt.Errorf("failed to evict pod %s/%s within allowed timeout: pod eviction rate limit exceeded", podToEvict.Namespace, podToEvict.Name)}
+						return
+					}
+					if deniedByWebhook.Load() {
+						confirmations <- status.PodEvictionResult{Pod: podToEvict, TimedOut: false, Err: fmt.Errorf("skipped eviction of pod %s/%s: drain of node %s aborted after an eviction was denied by an admission webhook", podToEvict.Namespace, podToEvict.Name, node.Name)}
+						return
+					}
+					result := e.evictPod(ctx, podToEvict, false, maxGracefulTerminationSec, retryUntil, e.EvictionRetryTime)
+					if result.DeniedByWebhook && e.deleteOptions.WebhookDenialPolicy == options.SkipNodeWebhookDenialPolicy {
+						deniedByWebhook.Store(true)
+					}
+					confirmations <- result
+				}(pod)
+			}
+			wg.Wait()
+			nominator.evicted(group.Pods)
+			evicted = append(evicted, group.Pods...)
+		}
+	}()
 
-	// Perform eviction of daemonset. We don't want to raise an error if daemonsetPod wasn't evict properly
+	// Perform eviction of daemonset. We don't want to raise an error if daemonsetPod wasn't evict properly, unless
+	// disruption budgets of DaemonSet pods are respected and the pod is covered by one: deleting the node anyway
+	// would disrupt the pod beyond its budget.
 	for _, daemonSetPod := range daemonSetPods {
 		go func(podToEvict *apiv1.Pod) {
-			daemonSetConfirmations <- evictPod(ctx, podToEvict, true, retryUntil, e.EvictionRetryTime, e.evictionRegister)
+			daemonSetConfirmations <- e.evictPod(ctx, podToEvict, true, maxGracefulTerminationSec, retryUntil, e.EvictionRetryTime)
 		}(daemonSetPod)
 
 	}
@@ -118,10 +236,16 @@ func (e Evictor) DrainNodeWithPods(ctx *acontext.AutoscalingContext, node *apiv1
 			evictionResults[evictionResult.Pod.Name] = evictionResult
 			if evictionResult.WasEvictionSuccessful() {
 				metrics.RegisterEvictions(1)
+				drainStatus.PodsEvicted++
+				e.registerDrainStatus(node, &drainStatus, drainStatus.Phase, drainStatus.Deadline)
+			}
+		case evictionResult := <-daemonSetConfirmations:
+			if !evictionResult.WasEvictionSuccessful() && e.deleteOptions.RespectDaemonSetPdbs && e.evictionScheduler.covers(evictionResult.Pod) {
+				evictionResults[evictionResult.Pod.Name] = evictionResult
 			}
-		case <-daemonSetConfirmations:
 		case <-time.After(retryUntil.Sub(time.Now()) + 5*time.Second):
 			if podsEvictionCounter < len(pods) {
+				e.registerDrainStatus(node, &drainStatus, status.NodeDrainFailed, time.Time{})
 				// All pods initially had results with TimedOut set to true, so the ones that didn't receive an actual result are correctly marked as timed out.
 				return evictionResults, errors.NewAutoscalerError(errors.ApiCallError, "Failed to drain node %s/%s: timeout when waiting for creating evictions", node.Namespace, node.Name)
 			}
@@ -136,31 +260,40 @@ func (e Evictor) DrainNodeWithPods(ctx *acontext.AutoscalingContext, node *apiv1
 		}
 	}
 	if len(evictionErrs) != 0 {
+		e.registerDrainStatus(node, &drainStatus, status.NodeDrainFailed, time.Time{})
 		return evictionResults, errors.NewAutoscalerError(errors.ApiCallError, "Failed to drain node %s/%s, due to following errors: %v", node.Namespace, node.Name, evictionErrs)
 	}
 
-	// Evictions created successfully, wait maxGracefulTerminationSec + podEvictionHeadroom to see if pods really disappeared.
-	var allGone bool
-	for start := time.Now(); time.Now().Sub(start) < time.Duration(ctx.MaxGracefulTerminationSec)*time.Second+e.PodEvictionHeadroom; time.Sleep(5 * time.Second) {
-		allGone = true
-		for _, pod := range pods {
-			podreturned, err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
-			if err == nil && (podreturned == nil || podreturned.Spec.NodeName == node.Name) {
-				klog.V(1).Infof("Not deleted yet %s/%s", pod.Namespace, pod.Name)
-				allGone = false
-				break
-			}
-			if err != nil && !kube_errors.IsNotFound(err) {
-				klog.Errorf("Failed to check pod %s/%s: %v", pod.Namespace, pod.Name, err)
-				allGone = false
-				break
-			}
+	// Evictions created successfully, wait maxGracefulTerminationSec (or the longest per-pod drain grace period
+	// override) + podEvictionHeadroom, or NodeDrainTimeout if set, to see if pods really disappeared.
+	waitTime := time.Duration(maxGracefulTerminationSec) * time.Second
+	for _, pod := range pods {
+		if gracePeriod := time.Duration(drain.GetPodDrainGracePeriod(pod, maxGracefulTerminationSec)) * time.Second; gracePeriod > waitTime {
+			waitTime = gracePeriod
 		}
-		if allGone {
+	}
+	waitTime += e.PodEvictionHeadroom
+	if e.deleteOptions.NodeDrainTimeout > 0 {
+		waitTime = e.deleteOptions.NodeDrainTimeout
+	}
+	e.registerDrainStatus(node, &drainStatus, status.NodeDrainWaitingForTermination, time.Now().Add(waitTime))
+	for start := time.Now(); time.Now().Sub(start) < waitTime; time.Sleep(5 * time.Second) {
+		remaining := remainingPods(ctx, node, pods)
+		if len(remaining) == 0 {
 			klog.V(1).Infof("All pods removed from %s", node.Name)
+			drainStatus.PodsRemaining = 0
+			e.registerDrainStatus(node, &drainStatus, status.NodeDrainSucceeded, time.Time{})
 			// Let the deferred function know there is no need for cleanup
 			return evictionResults, nil
 		}
+		if len(remaining) != drainStatus.PodsRemaining {
+			drainStatus.PodsRemaining = len(remaining)
+			e.registerDrainStatus(node, &drainStatus, status.NodeDrainWaitingForTermination, drainStatus.Deadline)
+		}
+	}
+
+	if e.deleteOptions.NodeDrainTimeout > 0 {
+		return e.forceDeleteRemainingPods(ctx, node, pods, evictionResults, &drainStatus)
 	}
 
 	for _, pod := range pods {
@@ -174,40 +307,123 @@ func (e Evictor) DrainNodeWithPods(ctx *acontext.AutoscalingContext, node *apiv1
 		}
 	}
 
+	e.registerDrainStatus(node, &drainStatus, status.NodeDrainFailed, time.Time{})
 	return evictionResults, errors.NewAutoscalerError(errors.TransientError, "Failed to drain node %s/%s: pods remaining after timeout", node.Namespace, node.Name)
 }
 
-// EvictDaemonSetPods creates eviction objects for all DaemonSet pods on the node.
+// maxGracefulTerminationSec returns the maximum number of seconds pods on the node are given to terminate.
+func (e Evictor) maxGracefulTerminationSec(ctx *acontext.AutoscalingContext, node *apiv1.Node) int {
+	maxGracefulTerminationSec := ctx.MaxGracefulTerminationSec
+	if e.configGetter != nil {
+		maxGracefulTerminationSec = nodegroupconfig.GetMaxGracefulTerminationSecForNode(ctx.CloudProvider, e.configGetter, node, ctx.MaxGracefulTerminationSec)
+	}
+	return e.deleteOptions.MaxGracefulTerminationSecForOS(node, maxGracefulTerminationSec)
+}
+
+// forceDeleteRemainingPods deletes pods which didn't terminate before NodeDrainTimeout, without a grace period.
+func (e Evictor) forceDeleteRemainingPods(ctx *acontext.AutoscalingContext, node *apiv1.Node, pods []*apiv1.Pod, evictionResults map[string]status.PodEvictionResult, drainStatus *status.NodeDrainStatus) (map[string]status.PodEvictionResult, error) {
+	remaining := remainingPods(ctx, node, pods)
+	drainStatus.PodsRemaining = len(remaining)
+	e.registerDrainStatus(node, drainStatus, status.NodeDrainForceDeleting, time.Time{})
+
+	var deleteErrs []error
+	for _, pod := range remaining {
+		klog.Warningf("Pod %s/%s didn't terminate within drain timeout of node %s, force deleting it", pod.Namespace, pod.Name, node.Name)
+		ctx.Recorder.Eventf(pod, apiv1.EventTypeWarning, "ScaleDownForceDelete", "pod didn't terminate within node drain timeout, force deleting it")
+		var gracePeriodSeconds int64
+		err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{
+			GracePeriodSeconds: &gracePeriodSeconds,
+			Preconditions:      metav1.NewUIDPreconditions(string(pod.UID)),
+		})
+		if err != nil && !kube_errors.IsNotFound(err) {
+			evictionResults[pod.Name] = status.PodEvictionResult{Pod: pod, TimedOut: true, Err: err}
+			deleteErrs = append(deleteErrs, err)
+			continue
+		}
+		evictionResults[pod.Name] = status.PodEvictionResult{Pod: pod, TimedOut: false, Err: nil, Deleted: true}
+	}
+	if len(deleteErrs) > 0 {
+		e.registerDrainStatus(node, drainStatus, status.NodeDrainFailed, time.Time{})
+		return evictionResults, errors.NewAutoscalerError(errors.ApiCallError, "Failed to drain node %s/%s, due to following errors when force deleting pods: %v", node.Namespace, node.Name, deleteErrs)
+	}
+	drainStatus.PodsRemaining = 0
+	e.registerDrainStatus(node, drainStatus, status.NodeDrainSucceeded, time.Time{})
+	return evictionResults, nil
+}
+
+// remainingPods returns pods which are still running on the node.
+func remainingPods(ctx *acontext.AutoscalingContext, node *apiv1.Node, pods []*apiv1.Pod) []*apiv1.Pod {
+	var remaining []*apiv1.Pod
+	for _, pod := range pods {
+		podReturned, err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		if err == nil && (podReturned == nil || podReturned.Spec.NodeName == node.Name) {
+			klog.V(1).Infof("Not deleted yet %s/%s", pod.Namespace, pod.Name)
+			remaining = append(remaining, pod)
+		} else if err != nil && !kube_errors.IsNotFound(err) {
+			klog.Errorf("Failed to check pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			remaining = append(remaining, pod)
+		}
+	}
+	return remaining
+}
+
+// registerDrainStatus moves the drain of the node to the given phase, which times out at deadline.
+func (e Evictor) registerDrainStatus(node *apiv1.Node, drainStatus *status.NodeDrainStatus, phase status.NodeDrainPhase, deadline time.Time) {
+	if drainStatus.Phase != phase {
+		klog.V(1).Infof("Drain of node %s: %s, %d out of %d pods remaining", node.Name, phase, drainStatus.PodsRemaining, drainStatus.PodsToRemove)
+	}
+	if drainStatus.Phase != phase && (phase == status.NodeDrainSucceeded || phase == status.NodeDrainFailed) {
+		metrics.RegisterNodeDrain(phase == status.NodeDrainSucceeded, time.Since(drainStatus.StartTime), drainStatus.PodsToRemove-drainStatus.PodsRemaining)
+	}
+	drainStatus.Phase = phase
+	drainStatus.Deadline = deadline
+	if e.drainStatusRegister != nil {
+		e.drainStatusRegister.RegisterDrainStatus(node.Name, *drainStatus)
+	}
+}
+
+// EvictDaemonSetPods creates eviction objects for all DaemonSet pods on the node. If disruption budgets of DaemonSet
+// pods are respected, the returned error wraps ErrDaemonSetPodBudget when a pod covered by a budget failed to be
+// evicted.
 func (e Evictor) EvictDaemonSetPods(ctx *acontext.AutoscalingContext, nodeInfo *framework.NodeInfo, timeNow time.Time) error {
 	nodeToDelete := nodeInfo.Node()
-	_, daemonSetPods, _, err := simulator.GetPodsToMove(nodeInfo, e.deleteOptions, e.drainabilityRules, nil, nil, timeNow)
-	if err != nil {
+	defer e.evictionScheduler.ForgetNode(nodeToDelete.Name)
+	simulation := podsToMove(ctx)(nodeInfo, e.deleteOptions, e.drainabilityRules, nil, nil, timeNow)
+	if err := simulation.Err; err != nil {
 		return fmt.Errorf("failed to get DaemonSet pods for %s (error: %v)", nodeToDelete.Name, err)
 	}
 
-	daemonSetPods = daemonset.PodsToEvict(daemonSetPods, ctx.DaemonSetEvictionForEmptyNodes)
+	daemonSetPods := daemonset.PodsToEvict(simulation.DaemonSetPods, daemonSetLister(ctx), ctx.DaemonSetEvictionForEmptyNodes)
 
 	dsEviction := make(chan status.PodEvictionResult, len(daemonSetPods))
+	maxGracefulTerminationSec := e.maxGracefulTerminationSec(ctx, nodeToDelete)
 
 	// Perform eviction of DaemonSet pods
 	for _, daemonSetPod := range daemonSetPods {
 		go func(podToEvict *apiv1.Pod) {
-			dsEviction <- evictPod(ctx, podToEvict, true, timeNow.Add(e.DsEvictionEmptyNodeTimeout), e.DsEvictionRetryTime, e.evictionRegister)
+			dsEviction <- e.evictPod(ctx, podToEvict, true, maxGracefulTerminationSec, timeNow.Add(e.DsEvictionEmptyNodeTimeout), e.DsEvictionRetryTime)
 		}(daemonSetPod)
 	}
 	// Wait for creating eviction of DaemonSet pods
 	var failedPodErrors []string
+	budgetViolated := false
 	for range daemonSetPods {
 		select {
 		case status := <-dsEviction:
 			if status.Err != nil {
 				failedPodErrors = append(failedPodErrors, status.Err.Error())
+				if e.deleteOptions.RespectDaemonSetPdbs && e.evictionScheduler.covers(status.Pod) {
+					budgetViolated = true
+				}
 			}
 		// adding waitBetweenRetries in order to have a bigger time interval than evictPod()
 		case <-time.After(e.DsEvictionEmptyNodeTimeout):
 			return fmt.Errorf("failed to create DaemonSet eviction for %v seconds on the %s", e.DsEvictionEmptyNodeTimeout, nodeToDelete.Name)
 		}
 	}
+	if budgetViolated {
+		return fmt.Errorf("%w on the %s:\n%s", ErrDaemonSetPodBudget, nodeToDelete.Name, strings.Join(failedPodErrors, "\n"))
+	}
 	if len(failedPodErrors) > 0 {
 
 		return fmt.Errorf("following DaemonSet pod failed to evict on the %s:\n%s", nodeToDelete.Name, fmt.Errorf(strings.Join(failedPodErrors, "\n")))
@@ -215,21 +431,44 @@ func (e Evictor) EvictDaemonSetPods(ctx *acontext.AutoscalingContext, nodeInfo *
 	return nil
 }
 
-func evictPod(ctx *acontext.AutoscalingContext, podToEvict *apiv1.Pod, isDaemonSetPod bool, retryUntil time.Time, waitBetweenRetries time.Duration, evictionRegister evictionRegister) status.PodEvictionResult {
+func (e Evictor) evictPod(ctx *acontext.AutoscalingContext, podToEvict *apiv1.Pod, isDaemonSetPod bool, maxGracefulTerminationSec int, retryUntil time.Time, waitBetweenRetries time.Duration) status.PodEvictionResult {
 	ctx.Recorder.Eventf(podToEvict, apiv1.EventTypeNormal, "ScaleDown", "deleting pod for node scale down")
 
-	maxTermination := int64(apiv1.DefaultTerminationGracePeriodSeconds)
-	if podToEvict.Spec.TerminationGracePeriodSeconds != nil {
-		if *podToEvict.Spec.TerminationGracePeriodSeconds < int64(ctx.MaxGracefulTerminationSec) {
-			maxTermination = *podToEvict.Spec.TerminationGracePeriodSeconds
-		} else {
-			maxTermination = int64(ctx.MaxGracefulTerminationSec)
-		}
+	maxTermination := drain.GetPodDrainGracePeriod(podToEvict, maxGracefulTerminationSec)
+
+	// Pods sharing disruption budgets with pods on other nodes being drained wait for their turn, so that the
+	// budgets are consumed node by node instead of evictions being rejected on all of the nodes. DaemonSet pods
+	// are registered only if their budgets are respected.
+	if pdbs, release, ok := e.evictionScheduler.acquire(podToEvict, retryUntil); ok {
+		defer release()
+		waitForDisruptionsAllowed(ctx, podToEvict, pdbs, retryUntil, waitBetweenRetries)
 	}
 
 	var lastError error
+	var deniedSince time.Time
+	rejections := 0
 	for first := true; first || time.Now().Before(retryUntil); time.Sleep(waitBetweenRetries) {
 		first = false
+		var deleteReason string
+		switch {
+		case e.shouldDelete(rejections):
+			deleteReason = "evictions were persistently rejected"
+		case e.shouldDeleteDenied(deniedSince):
+			deleteReason = fmt.Sprintf("evictions were denied by admission webhooks for longer than %v", e.deleteOptions.WebhookDenialTimeout)
+		}
+		if deleteReason != "" {
+			lastError = deletePod(ctx, podToEvict, maxTermination, deleteReason)
+			if lastError == nil || kube_errors.IsNotFound(lastError) {
+				if e.evictionRegister != nil {
+					e.evictionRegister.RegisterEviction(podToEvict)
+				}
+				if ctx.EvictionBackoff != nil && !isDaemonSetPod {
+					ctx.EvictionBackoff.RegisterSuccess(podToEvict)
+				}
+				return status.PodEvictionResult{Pod: podToEvict, TimedOut: false, Err: nil, Deleted: true}
+			}
+			continue
+		}
 		eviction := &policyv1beta1.Eviction{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: podToEvict.Namespace,
@@ -240,20 +479,184 @@ func evictPod(ctx *acontext.AutoscalingContext, podToEvict *apiv1.Pod, isDaemonS
 			},
 		}
 		lastError = ctx.ClientSet.CoreV1().Pods(podToEvict.Namespace).Evict(context.TODO(), eviction)
+		metrics.RegisterEvictionRequest(evictionRequestResult(lastError))
 		if lastError == nil || kube_errors.IsNotFound(lastError) {
-			if evictionRegister != nil {
-				evictionRegister.RegisterEviction(podToEvict)
+			if e.evictionRegister != nil {
+				e.evictionRegister.RegisterEviction(podToEvict)
+			}
+			if ctx.EvictionBackoff != nil && !isDaemonSetPod {
+				ctx.EvictionBackoff.RegisterSuccess(podToEvict)
 			}
 			return status.PodEvictionResult{Pod: podToEvict, TimedOut: false, Err: nil}
 		}
+		if !isDaemonSetPod && drain.IsAdmissionWebhookDenial(lastError) {
+			if deniedSince.IsZero() {
+				deniedSince = time.Now()
+				klog.Warningf("Eviction of pod %s/%s was denied by an admission webhook: %v", podToEvict.Namespace, podToEvict.Name, lastError)
+				ctx.Recorder.Eventf(podToEvict, apiv1.EventTypeWarning, "ScaleDownEvictionDeniedByWebhook", "eviction denied by admission webhook: %v", lastError)
+			}
+			if e.deleteOptions.WebhookDenialPolicy == options.SkipNodeWebhookDenialPolicy {
+				break
+			}
+		} else {
+			deniedSince = time.Time{}
+		}
+		// Only denials by admission webhooks count as rejections. Other errors, e.g. TooManyRequests for
+		// evictions which would violate a disruption budget, errors for pods covered by multiple budgets, or
+		// transient API errors, must not lead to bypassing disruption budgets.
+		if drain.IsAdmissionWebhookDenial(lastError) {
+			rejections++
+		} else {
+			rejections = 0
+		}
 	}
 	if !isDaemonSetPod {
 		klog.Errorf("Failed to evict pod %s, error: %v", podToEvict.Name, lastError)
 		ctx.Recorder.Eventf(podToEvict, apiv1.EventTypeWarning, "ScaleDownFailed", "failed to delete pod for ScaleDown")
+		if ctx.EvictionBackoff != nil {
+			ctx.EvictionBackoff.RegisterFailure(podToEvict, lastError, time.Now())
+		}
+	}
+	if drain.IsAdmissionWebhookDenial(lastError) {
+		return status.PodEvictionResult{Pod: podToEvict, TimedOut: false, Err: fmt.Errorf("eviction of pod %s/%s denied by admission webhook: %v", podToEvict.Namespace, podToEvict.Name, lastError), DeniedByWebhook: true}
 	}
 	return status.PodEvictionResult{Pod: podToEvict, TimedOut: true, Err: fmt.Errorf("failed to evict pod %s/%s within allowed timeout (last error: %v)", podToEvict.Namespace, podToEvict.Name, lastError)}
 }
 
+// evictionRequestResult returns the metrics result of an eviction request which returned the error.
+func evictionRequestResult(err error) metrics.EvictionRequestResult {
+	switch {
+	case err == nil || kube_errors.IsNotFound(err):
+		return metrics.EvictionSucceeded
+	case kube_errors.IsTooManyRequests(err):
+		return metrics.EvictionRejectedByBudget
+	case drain.IsAdmissionWebhookDenial(err):
+		return metrics.EvictionDeniedByWebhook
+	default:
+		return metrics.EvictionFailed
+	}
+}
+
+// shouldDelete tells if a pod should be deleted instead of evicted after the given number of consecutive eviction
+// denials by admission webhooks.
+func (e Evictor) shouldDelete(rejections int) bool {
+	return e.deleteOptions.DrainMode == options.EvictOrDeleteDrainMode && e.MaxEvictionRejections > 0 && rejections >= e.MaxEvictionRejections
+}
+
+// shouldDeleteDenied tells if a pod should be deleted instead of evicted, because its evictions have been denied by
+// admission webhooks since deniedSince.
+func (e Evictor) shouldDeleteDenied(deniedSince time.Time) bool {
+	return e.deleteOptions.WebhookDenialPolicy == options.ForceDeleteWebhookDenialPolicy && !deniedSince.IsZero() && time.Since(deniedSince) >= e.deleteOptions.WebhookDenialTimeout
+}
+
+// deletePod deletes the pod instead of evicting it for the given reason, unless it was replaced by another pod with the
+// same name in the meantime.
+func deletePod(ctx *acontext.AutoscalingContext, pod *apiv1.Pod, gracePeriodSeconds int64, reason string) error {
+	klog.Warningf("Deleting pod %s/%s instead of evicting it: %s", pod.Namespace, pod.Name, reason)
+	ctx.Recorder.Eventf(pod, apiv1.EventTypeWarning, "ScaleDownEvictionRejected", "%s, deleting pod for node scale down", reason)
+	return ctx.ClientSet.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{
+		GracePeriodSeconds: &gracePeriodSeconds,
+		Preconditions:      metav1.NewUIDPreconditions(string(pod.UID)),
+	})
+}
+
+// shouldWaitForRescheduling tells if evictions of the group of pods have to
+// wait for the pods evicted before them to be rescheduled. Groups are evicted
+// in increasing order of priority, so only the first group of critical pods
+// has to wait.
+func (e Evictor) shouldWaitForRescheduling(group []*apiv1.Pod, evicted []*apiv1.Pod) bool {
+	if !e.deleteOptions.WaitForReschedulingBeforeCriticalPods || len(group) == 0 || len(evicted) == 0 {
+		return false
+	}
+	critical := e.deleteOptions.CriticalPodPriority
+	return corev1helpers.PodPriority(group[0]) >= critical && corev1helpers.PodPriority(evicted[len(evicted)-1]) < critical
+}
+
+// waitForRescheduling waits until the evicted pods have left the node and
+// pods replacing them, i.e. pods with the same controller, are no longer
+// pending.
+func (e Evictor) waitForRescheduling(ctx *acontext.AutoscalingContext, node *apiv1.Node, evicted []*apiv1.Pod, deadline time.Time) error {
+	for {
+		remaining := remainingPods(ctx, node, evicted)
+		pending := pendingReplacements(ctx, evicted)
+		if len(remaining) == 0 && pending == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("lower-priority pods weren't rescheduled within allowed timeout: %d still on node %s, %d replacements pending", len(remaining), node.Name, pending)
+		}
+		klog.V(2).Infof("Waiting for %d pods to leave node %s and %d replacements to be scheduled before evicting critical pods", len(remaining), node.Name, pending)
+		time.Sleep(e.ReschedulingCheckInterval)
+	}
+}
+
+// waitForReadyReplacements waits until the evicted StatefulSet pods have left
+// the node and were recreated, with the same name, as running and ready pods.
+func (e Evictor) waitForReadyReplacements(ctx *acontext.AutoscalingContext, node *apiv1.Node, evicted []*apiv1.Pod, deadline time.Time) error {
+	for {
+		notReady := 0
+		for _, pod := range evicted {
+			replacement, err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+			if err != nil || replacement.UID == pod.UID || replacement.Spec.NodeName == node.Name || !podv1.IsPodReady(replacement) {
+				notReady++
+			}
+		}
+		if notReady == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("replacements of StatefulSet pods evicted before weren't ready within allowed timeout: %d not ready", notReady)
+		}
+		klog.V(2).Infof("Waiting for %d replacements of StatefulSet pods evicted from node %s to be ready", notReady, node.Name)
+		time.Sleep(e.ReschedulingCheckInterval)
+	}
+}
+
+// pendingReplacements returns the number of unscheduled pods with the same
+// controller as one of the given pods.
+func pendingReplacements(ctx *acontext.AutoscalingContext, pods []*apiv1.Pod) int {
+	if ctx.ListerRegistry == nil {
+		return 0
+	}
+	controllers := make(map[types.UID]bool)
+	for _, pod := range pods {
+		if ref := drain.ControllerRef(pod); ref != nil {
+			controllers[ref.UID] = true
+		}
+	}
+	if len(controllers) == 0 {
+		return 0
+	}
+	allPods, err := ctx.AllPodLister().List()
+	if err != nil {
+		klog.Errorf("Failed to list pods: %v", err)
+		return 0
+	}
+	pending := 0
+	for _, pod := range allPods {
+		if ref := drain.ControllerRef(pod); ref != nil && controllers[ref.UID] && pod.Spec.NodeName == "" && pod.DeletionTimestamp == nil {
+			pending++
+		}
+	}
+	return pending
+}
+
+// daemonSetLister returns the DaemonSet lister of ctx, or nil if there are no listers.
+func daemonSetLister(ctx *acontext.AutoscalingContext) v1appslister.DaemonSetLister {
+	if ctx.ListerRegistry == nil {
+		return nil
+	}
+	return ctx.ListerRegistry.DaemonSetLister()
+}
+
+// podsToMove returns the function determining pods to move from drained nodes.
+func podsToMove(ctx *acontext.AutoscalingContext) simulator.PodsToMoveFunc {
+	if ctx.PodsToMove != nil {
+		return ctx.PodsToMove
+	}
+	return simulator.SimulateDrain
+}
+
 func podsToEvict(ctx *acontext.AutoscalingContext, nodeInfo *framework.NodeInfo) (dsPods, nonDsPods []*apiv1.Pod) {
 	for _, podInfo := range nodeInfo.Pods {
 		if pod_util.IsMirrorPod(podInfo.Pod) {
@@ -264,6 +667,6 @@ func podsToEvict(ctx *acontext.AutoscalingContext, nodeInfo *framework.NodeInfo)
 			nonDsPods = append(nonDsPods, podInfo.Pod)
 		}
 	}
-	dsPodsToEvict := daemonset.PodsToEvict(dsPods, ctx.DaemonSetEvictionForOccupiedNodes)
+	dsPodsToEvict := daemonset.PodsToEvict(dsPods, daemonSetLister(ctx), ctx.DaemonSetEvictionForOccupiedNodes)
 	return dsPodsToEvict, nonDsPods
 }
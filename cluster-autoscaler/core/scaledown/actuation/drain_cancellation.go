@@ -0,0 +1,221 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	klog "k8s.io/klog/v2"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+)
+
+// DrainCancellations records in-progress drains which should be cancelled. The Evictor checks them before each group
+// of evictions and skips the remaining evictions of a cancelled drain, which fails the drain and frees up the node.
+// It is safe for concurrent use.
+type DrainCancellations struct {
+	mutex   sync.Mutex
+	reasons map[string]string
+}
+
+// NewDrainCancellations returns a new DrainCancellations.
+func NewDrainCancellations() *DrainCancellations {
+	return &DrainCancellations{reasons: make(map[string]string)}
+}
+
+// Cancel requests cancellation of the drain of the node.
+func (c *DrainCancellations) Cancel(nodeName, reason string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.reasons[nodeName] = reason
+}
+
+// Cancelled tells if cancellation of the drain of the node was requested, and why.
+func (c *DrainCancellations) Cancelled(nodeName string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	reason, found := c.reasons[nodeName]
+	return reason, found
+}
+
+// Forget forgets the cancellation of the drain of the node, once the drain is over.
+func (c *DrainCancellations) Forget(nodeName string) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.reasons, nodeName)
+}
+
+// forgetAllExcept forgets cancellations of drains of all nodes but the given ones, so that cancellations requested
+// too late for the drain to notice don't accumulate.
+func (c *DrainCancellations) forgetAllExcept(nodeNames []string) {
+	keep := make(map[string]bool, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		keep[nodeName] = true
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for nodeName := range c.reasons {
+		if !keep[nodeName] {
+			delete(c.reasons, nodeName)
+		}
+	}
+}
+
+// PendingPodsDrainCanceller cancels in-progress drains of nodes on which pending pods would fit if the nodes
+// weren't being drained. To avoid flapping, only pods pending for at least pendingTime are taken into account, and
+// pods owned by the same controllers as pods on, or recently evicted from, drained nodes are ignored, as they are
+// most likely pending because of the drains.
+type PendingPodsDrainCanceller struct {
+	ctx           *acontext.AutoscalingContext
+	cancellations *DrainCancellations
+	pendingTime   time.Duration
+}
+
+// NewPendingPodsDrainCanceller returns a new PendingPodsDrainCanceller.
+func NewPendingPodsDrainCanceller(ctx *acontext.AutoscalingContext, cancellations *DrainCancellations, pendingTime time.Duration) *PendingPodsDrainCanceller {
+	return &PendingPodsDrainCanceller{
+		ctx:           ctx,
+		cancellations: cancellations,
+		pendingTime:   pendingTime,
+	}
+}
+
+// Update cancels in-progress drains of nodes on which any of the pending pods would fit. It has to be called from
+// the main loop, with the cluster snapshot reflecting the current state of the cluster. It returns the names of the
+// nodes whose drains were cancelled.
+func (c *PendingPodsDrainCanceller) Update(pendingPods []*apiv1.Pod, timestamp time.Time) []string {
+	_, drained := c.ctx.ScaleDownActuator.CheckStatus().DeletionsInProgress()
+	c.cancellations.forgetAllExcept(drained)
+	if len(drained) == 0 {
+		return nil
+	}
+	var nodeInfos []*drainedNode
+	drainedControllers := make(map[types.UID]bool)
+	for _, nodeName := range drained {
+		if _, cancelled := c.cancellations.Cancelled(nodeName); cancelled {
+			continue
+		}
+		nodeInfo, err := c.ctx.ClusterSnapshot.NodeInfos().Get(nodeName)
+		if err != nil {
+			continue
+		}
+		node := &drainedNode{node: nodeInfo.Node()}
+		for _, podInfo := range nodeInfo.Pods {
+			node.pods = append(node.pods, podInfo.Pod)
+			addController(drainedControllers, podInfo.Pod)
+		}
+		nodeInfos = append(nodeInfos, node)
+	}
+	for _, pod := range c.ctx.ScaleDownActuator.CheckStatus().RecentEvictions() {
+		addController(drainedControllers, pod)
+	}
+
+	var candidates []*apiv1.Pod
+	for _, pod := range pendingPods {
+		if timestamp.Sub(pod_util.PendingSince(pod)) < c.pendingTime {
+			continue
+		}
+		if controller := metav1.GetControllerOf(pod); controller != nil && drainedControllers[controller.UID] {
+			continue
+		}
+		candidates = append(candidates, pod)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var cancelled []string
+	for _, node := range nodeInfos {
+		pod, err := c.fittingPod(node, candidates)
+		if err != nil {
+			klog.Warningf("Can't check if pending pods fit on drained node %s: %v", node.node.Name, err)
+			continue
+		}
+		if pod == nil {
+			continue
+		}
+		reason := fmt.Sprintf("pod %s/%s pending for at least %v would fit on the node", pod.Namespace, pod.Name, c.pendingTime)
+		klog.V(1).Infof("Cancelling drain of node %s: %s", node.node.Name, reason)
+		c.ctx.Recorder.Eventf(node.node, apiv1.EventTypeNormal, "ScaleDownCancelled", "cancelling drain: %s", reason)
+		c.cancellations.Cancel(node.node.Name, reason)
+		cancelled = append(cancelled, node.node.Name)
+	}
+	return cancelled
+}
+
+type drainedNode struct {
+	node *apiv1.Node
+	pods []*apiv1.Pod
+}
+
+// fittingPod returns the first of the pods which would fit on the node if the node wasn't being drained, or nil if
+// none of them would.
+func (c *PendingPodsDrainCanceller) fittingPod(node *drainedNode, pods []*apiv1.Pod) (*apiv1.Pod, error) {
+	c.ctx.ClusterSnapshot.Fork()
+	defer c.ctx.ClusterSnapshot.Revert()
+	if err := c.ctx.ClusterSnapshot.RemoveNode(node.node.Name); err != nil {
+		return nil, err
+	}
+	if err := c.ctx.ClusterSnapshot.AddNodeWithPods(undrainedNode(node.node, c.ctx.CordonNodeBeforeTerminate), node.pods); err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		if err := c.ctx.PredicateChecker.CheckPredicates(c.ctx.ClusterSnapshot, pod, node.node.Name); err == nil {
+			return pod, nil
+		}
+	}
+	return nil, nil
+}
+
+// undrainedNode returns a copy of the node without the taints added by scale down, and without the cordon if nodes
+// are cordoned before they are drained.
+func undrainedNode(node *apiv1.Node, cordoned bool) *apiv1.Node {
+	node = node.DeepCopy()
+	var nodeTaints []apiv1.Taint
+	for _, taint := range node.Spec.Taints {
+		if taint.Key != taints.ToBeDeletedTaint && taint.Key != taints.DeletionCandidateTaint {
+			nodeTaints = append(nodeTaints, taint)
+		}
+	}
+	node.Spec.Taints = nodeTaints
+	if cordoned {
+		node.Spec.Unschedulable = false
+	}
+	return node
+}
+
+func addController(controllers map[types.UID]bool, pod *apiv1.Pod) {
+	if controller := metav1.GetControllerOf(pod); controller != nil {
+		controllers[controller.UID] = true
+	}
+}
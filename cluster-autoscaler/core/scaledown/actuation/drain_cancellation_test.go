@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestDrainCancellations(t *testing.T) {
+	cancellations := NewDrainCancellations()
+	_, cancelled := cancellations.Cancelled("n1")
+	assert.False(t, cancelled)
+
+	cancellations.Cancel("n1", "pods pending")
+	cancellations.Cancel("n2", "pods pending")
+	reason, cancelled := cancellations.Cancelled("n1")
+	assert.True(t, cancelled)
+	assert.Equal(t, "pods pending", reason)
+
+	cancellations.Forget("n1")
+	_, cancelled = cancellations.Cancelled("n1")
+	assert.False(t, cancelled)
+
+	cancellations.forgetAllExcept(nil)
+	_, cancelled = cancellations.Cancelled("n2")
+	assert.False(t, cancelled)
+
+	var nilCancellations *DrainCancellations
+	nilCancellations.Cancel("n1", "pods pending")
+	_, cancelled = nilCancellations.Cancelled("n1")
+	assert.False(t, cancelled)
+	nilCancellations.Forget("n1")
+}
+
+func TestPendingPodsDrainCancellerUpdate(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	drainingRs := "draining-rs"
+
+	for _, tc := range []struct {
+		name          string
+		pendingPod    *apiv1.Pod
+		cordoned      bool
+		wantCancelled []string
+	}{
+		{
+			name:          "pod pending long enough fits on drained node",
+			pendingPod:    pendingPod("p", 500, now.Add(-2*time.Minute), ""),
+			wantCancelled: []string{"drained"},
+		},
+		{
+			name:          "pod fits on drained node cordoned by scale down",
+			pendingPod:    pendingPod("p", 500, now.Add(-2*time.Minute), ""),
+			cordoned:      true,
+			wantCancelled: []string{"drained"},
+		},
+		{
+			name:       "pod not pending long enough",
+			pendingPod: pendingPod("p", 500, now.Add(-30*time.Second), ""),
+		},
+		{
+			name:       "pod of the same controller as pods on drained node",
+			pendingPod: pendingPod("p", 500, now.Add(-2*time.Minute), drainingRs),
+		},
+		{
+			name:       "pod doesn't fit on drained node",
+			pendingPod: pendingPod("p", 1500, now.Add(-2*time.Minute), ""),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			drained := BuildTestNode("drained", 1000, 1000)
+			drained.Spec.Taints = []apiv1.Taint{{Key: taints.ToBeDeletedTaint, Effect: apiv1.TaintEffectNoSchedule}}
+			drained.Spec.Unschedulable = tc.cordoned
+			other := BuildTestNode("other", 1000, 1000)
+			drainedPod := BuildTestPod("drained-pod", 400, 0)
+			drainedPod.Spec.NodeName = drained.Name
+			drainedPod.OwnerReferences = GenerateOwnerReferences(drainingRs, "ReplicaSet", "apps/v1", "draining-rs-uid")
+			otherPod := BuildTestPod("other-pod", 1000, 0)
+			otherPod.Spec.NodeName = other.Name
+
+			options := config.AutoscalingOptions{CordonNodeBeforeTerminate: tc.cordoned}
+			ctx, err := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, nil, nil, nil, nil)
+			assert.NoError(t, err)
+			clustersnapshot.InitializeClusterSnapshotOrDie(t, ctx.ClusterSnapshot, []*apiv1.Node{drained, other}, []*apiv1.Pod{drainedPod, otherPod})
+			tracker := deletiontracker.NewNodeDeletionTracker(time.Hour)
+			tracker.StartDeletionWithDrain("ng", drained.Name)
+			ctx.ScaleDownActuator = &fakeStatusActuator{tracker: tracker}
+
+			cancellations := NewDrainCancellations()
+			canceller := NewPendingPodsDrainCanceller(&ctx, cancellations, time.Minute)
+			assert.Equal(t, tc.wantCancelled, canceller.Update([]*apiv1.Pod{tc.pendingPod}, now))
+			_, cancelled := cancellations.Cancelled(drained.Name)
+			assert.Equal(t, len(tc.wantCancelled) > 0, cancelled)
+
+			// The snapshot is left intact.
+			nodeInfo, err := ctx.ClusterSnapshot.NodeInfos().Get(drained.Name)
+			assert.NoError(t, err)
+			assert.Equal(t, drained, nodeInfo.Node())
+		})
+	}
+}
+
+func pendingPod(name string, cpu int64, pendingSince time.Time, controller string) *apiv1.Pod {
+	pod := BuildTestPod(name, cpu, 0)
+	pod.Status.Conditions = []apiv1.PodCondition{{
+		Type:               apiv1.PodScheduled,
+		Status:             apiv1.ConditionFalse,
+		LastTransitionTime: metav1.NewTime(pendingSince),
+	}}
+	if controller != "" {
+		pod.OwnerReferences = GenerateOwnerReferences(controller, "ReplicaSet", "apps/v1", "draining-rs-uid")
+	}
+	return pod
+}
+
+// fakeStatusActuator reports the status of deletions recorded by the tracker.
+type fakeStatusActuator struct {
+	scaledown.Actuator
+	tracker *deletiontracker.NodeDeletionTracker
+}
+
+func (a *fakeStatusActuator) CheckStatus() scaledown.ActuationStatus {
+	return a.tracker.Snapshot()
+}
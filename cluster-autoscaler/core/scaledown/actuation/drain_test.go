@@ -18,6 +18,7 @@ package actuation
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
 	"sync"
 	"testing"
@@ -34,13 +35,21 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
 	"k8s.io/kubernetes/pkg/kubelet/types"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 
 	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
 	"k8s.io/autoscaler/cluster-autoscaler/core/utils"
+	"k8s.io/autoscaler/cluster-autoscaler/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	sdoptions "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/evictionbackoff"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
@@ -236,6 +245,402 @@ func TestDrainNodeWithPods(t *testing.T) {
 	assert.Equal(t, p2.Name, deleted[2])
 }
 
+func TestDrainNodeWithPodsDeletionCostOrder(t *testing.T) {
+	deletedPods := make(chan string, 10)
+	fakeClient := &fake.Clientset{}
+
+	expensive := BuildTestPod("expensive", 100, 0)
+	expensive.Annotations = map[string]string{apiv1.PodDeletionCost: "100"}
+	free := BuildTestPod("free", 100, 0)
+	cheap := BuildTestPod("cheap", 100, 0)
+	cheap.Annotations = map[string]string{apiv1.PodDeletionCost: "-10"}
+	n1 := BuildTestNode("n1", 1000, 1000)
+
+	SetNodeReadyState(n1, true, time.Time{})
+
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		deletedPods <- eviction.Name
+		return true, nil, nil
+	})
+
+	options := config.AutoscalingOptions{
+		MaxGracefulTerminationSec: 20,
+		MaxPodEvictionTime:        5 * time.Second,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	evictor := Evictor{EvictionRetryTime: 0, PodEvictionHeadroom: DefaultPodEvictionHeadroom}
+	_, err = evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{expensive, free, cheap}, nil)
+	assert.NoError(t, err)
+	deleted := make([]string, 0)
+	deleted = append(deleted, utils.GetStringFromChan(deletedPods))
+	deleted = append(deleted, utils.GetStringFromChan(deletedPods))
+	deleted = append(deleted, utils.GetStringFromChan(deletedPods))
+	assert.Equal(t, []string{"cheap", "free", "expensive"}, deleted)
+}
+
+func TestDrainNodeWithPodsPriorityOrder(t *testing.T) {
+	deletedPods := make(chan string, 10)
+	fakeClient := &fake.Clientset{}
+
+	critical := BuildTestPod("critical", 100, 0)
+	critical.Spec.Priority = int32Ptr(1000)
+	cheapCritical := BuildTestPod("cheap-critical", 100, 0)
+	cheapCritical.Spec.Priority = int32Ptr(1000)
+	cheapCritical.Annotations = map[string]string{apiv1.PodDeletionCost: "-100"}
+	regular := BuildTestPod("regular", 100, 0)
+	expensiveLow := BuildTestPod("expensive-low", 100, 0)
+	expensiveLow.Spec.Priority = int32Ptr(-10)
+	expensiveLow.Annotations = map[string]string{apiv1.PodDeletionCost: "100"}
+	n1 := BuildTestNode("n1", 1000, 1000)
+
+	SetNodeReadyState(n1, true, time.Time{})
+
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		deletedPods <- eviction.Name
+		return true, nil, nil
+	})
+
+	options := config.AutoscalingOptions{
+		MaxGracefulTerminationSec: 20,
+		MaxPodEvictionTime:        5 * time.Second,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	evictor := Evictor{EvictionRetryTime: 0, PodEvictionHeadroom: DefaultPodEvictionHeadroom}
+	_, err = evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{critical, cheapCritical, regular, expensiveLow}, nil)
+	assert.NoError(t, err)
+	deleted := make([]string, 0)
+	for i := 0; i < 4; i++ {
+		deleted = append(deleted, utils.GetStringFromChan(deletedPods))
+	}
+	assert.Equal(t, []string{"expensive-low", "regular", "cheap-critical", "critical"}, deleted)
+}
+
+func TestDrainNodeWithPodsCancelled(t *testing.T) {
+	evictedPods := make(chan string, 10)
+	fakeClient := &fake.Clientset{}
+	cancellations := NewDrainCancellations()
+
+	low := BuildTestPod("low", 100, 0)
+	low.Spec.Priority = int32Ptr(-10)
+	critical := BuildTestPod("critical", 100, 0)
+	critical.Spec.Priority = int32Ptr(1000)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		// The drain is cancelled while the first group of pods is evicted.
+		cancellations.Cancel(n1.Name, "pending pods would fit")
+		evictedPods <- eviction.Name
+		return true, nil, nil
+	})
+
+	options := config.AutoscalingOptions{
+		MaxGracefulTerminationSec: 20,
+		MaxPodEvictionTime:        5 * time.Second,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	evictor := Evictor{EvictionRetryTime: 0, PodEvictionHeadroom: DefaultPodEvictionHeadroom, drainCancellations: cancellations}
+	results, err := evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{critical, low}, nil)
+	assert.Error(t, err)
+	assert.True(t, results[low.Name].WasEvictionSuccessful())
+	assert.False(t, results[critical.Name].WasEvictionSuccessful())
+	assert.False(t, results[critical.Name].TimedOut)
+	assert.Contains(t, results[critical.Name].Err.Error(), "pending pods would fit")
+	assert.Equal(t, low.Name, utils.GetStringFromChan(evictedPods))
+	assert.Empty(t, evictedPods)
+	// The cancellation is forgotten once the drain is over.
+	_, cancelled := cancellations.Cancelled(n1.Name)
+	assert.False(t, cancelled)
+}
+
+type fakePodLister struct {
+	sync.Mutex
+	pods []*apiv1.Pod
+}
+
+func (l *fakePodLister) List() ([]*apiv1.Pod, error) {
+	l.Lock()
+	defer l.Unlock()
+	return l.pods, nil
+}
+
+func (l *fakePodLister) set(pods ...*apiv1.Pod) {
+	l.Lock()
+	defer l.Unlock()
+	l.pods = pods
+}
+
+func TestDrainNodeWithPodsWaitForRescheduling(t *testing.T) {
+	for _, tc := range []struct {
+		desc        string
+		rescheduled bool
+		wantDeleted []string
+		wantErr     bool
+	}{
+		{
+			desc:        "critical pod evicted once replacement is scheduled",
+			rescheduled: true,
+			wantDeleted: []string{"low", "critical"},
+		},
+		{
+			desc:        "critical pod not evicted if replacement stays pending",
+			wantDeleted: []string{"low"},
+			wantErr:     true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			deletedPods := make(chan string, 10)
+			fakeClient := &fake.Clientset{}
+
+			low := BuildTestPod("low", 100, 0)
+			low.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "rs-uid")
+			critical := BuildTestPod("critical", 100, 0)
+			critical.Spec.Priority = int32Ptr(2000000000)
+			replacement := BuildTestPod("replacement", 100, 0)
+			replacement.OwnerReferences = low.OwnerReferences
+			n1 := BuildTestNode("n1", 1000, 1000)
+			SetNodeReadyState(n1, true, time.Time{})
+
+			podLister := &fakePodLister{}
+			fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+			})
+			fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+				if eviction.Name == "low" {
+					podLister.set(replacement)
+					if tc.rescheduled {
+						go func() {
+							time.Sleep(50 * time.Millisecond)
+							scheduled := replacement.DeepCopy()
+							scheduled.Spec.NodeName = "n2"
+							podLister.set(scheduled)
+						}()
+					}
+				}
+				deletedPods <- eviction.Name
+				return true, nil, nil
+			})
+
+			options := config.AutoscalingOptions{
+				MaxGracefulTerminationSec: 0,
+				MaxPodEvictionTime:        500 * time.Millisecond,
+			}
+			registry := kube_util.NewListerRegistry(nil, nil, podLister, nil, nil, nil, nil, nil, nil)
+			ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, registry, nil, nil, nil)
+			assert.NoError(t, err)
+
+			evictor := Evictor{
+				ReschedulingCheckInterval: 10 * time.Millisecond,
+				deleteOptions: sdoptions.NodeDeleteOptions{
+					WaitForReschedulingBeforeCriticalPods: true,
+					CriticalPodPriority:                   2000000000,
+				},
+			}
+			results, err := evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{critical, low}, nil)
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, results["critical"].Err.Error(), "weren't rescheduled")
+			} else {
+				assert.NoError(t, err)
+			}
+			var deleted []string
+			for len(deletedPods) > 0 {
+				deleted = append(deleted, <-deletedPods)
+			}
+			assert.Equal(t, tc.wantDeleted, deleted)
+		})
+	}
+}
+
+func TestDrainNodeWithPodsStatefulSetOrdinalOrder(t *testing.T) {
+	for _, tc := range []struct {
+		desc        string
+		ready       bool
+		wantDeleted []string
+		wantErr     bool
+	}{
+		{
+			desc:        "pods evicted in reverse ordinal order once replacements are ready",
+			ready:       true,
+			wantDeleted: []string{"web-2", "web-1", "web-0"},
+		},
+		{
+			desc:        "pods not evicted if replacement doesn't become ready",
+			wantDeleted: []string{"web-2"},
+			wantErr:     true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			deletedPods := make(chan string, 10)
+			fakeClient := &fake.Clientset{}
+
+			var pods []*apiv1.Pod
+			for _, name := range []string{"web-0", "web-2", "web-1"} {
+				pod := BuildTestPod(name, 100, 0)
+				pod.OwnerReferences = GenerateOwnerReferences("web", "StatefulSet", "apps/v1", "web-uid")
+				pods = append(pods, pod)
+			}
+			n1 := BuildTestNode("n1", 1000, 1000)
+			SetNodeReadyState(n1, true, time.Time{})
+
+			var lock sync.Mutex
+			evicted := make(map[string]bool)
+			fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				name := action.(core.GetAction).GetName()
+				lock.Lock()
+				defer lock.Unlock()
+				if !evicted[name] {
+					return true, nil, errors.NewNotFound(apiv1.Resource("pod"), name)
+				}
+				replacement := BuildScheduledTestPod(name, 100, 0, "n2")
+				replacement.UID = "replacement"
+				if tc.ready {
+					replacement.Status.Conditions = []apiv1.PodCondition{{Type: apiv1.PodReady, Status: apiv1.ConditionTrue}}
+				}
+				return true, replacement, nil
+			})
+			fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+				lock.Lock()
+				evicted[eviction.Name] = true
+				lock.Unlock()
+				deletedPods <- eviction.Name
+				return true, nil, nil
+			})
+
+			options := config.AutoscalingOptions{
+				MaxGracefulTerminationSec: 0,
+				MaxPodEvictionTime:        500 * time.Millisecond,
+			}
+			ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+			assert.NoError(t, err)
+
+			nodeInfo := framework.NewNodeInfo(pods...)
+			nodeInfo.SetNode(n1)
+			deleteOptions := sdoptions.NodeDeleteOptions{StatefulSetOrdinalOrder: true}
+			simulation := simulator.SimulateDrain(nodeInfo, deleteOptions, nil, nil, nil, time.Now())
+			evictionPlans := NewEvictionPlans()
+			evictionPlans.SetEvictionGroups(n1.Name, simulation.EvictionGroups)
+			evictor := Evictor{
+				ReschedulingCheckInterval: 10 * time.Millisecond,
+				deleteOptions:             deleteOptions,
+				evictionPlans:             evictionPlans,
+			}
+			results, err := evictor.DrainNodeWithPods(&ctx, n1, pods, nil)
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.Contains(t, results["web-1"].Err.Error(), "weren't ready")
+			} else {
+				assert.NoError(t, err)
+			}
+			var deleted []string
+			for len(deletedPods) > 0 {
+				deleted = append(deleted, <-deletedPods)
+			}
+			assert.Equal(t, tc.wantDeleted, deleted)
+		})
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestDrainNodeWithPodsDrainGracePeriodOverride(t *testing.T) {
+	gracePeriods := make(chan int64, 10)
+	fakeClient := &fake.Clientset{}
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Annotations = map[string]string{drain.PodDrainGracePeriodKey: "120"}
+	n1 := BuildTestNode("n1", 1000, 1000)
+
+	SetNodeReadyState(n1, true, time.Time{})
+
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		createAction := action.(core.CreateAction)
+		if createAction == nil {
+			return false, nil, nil
+		}
+		eviction := createAction.GetObject().(*policyv1beta1.Eviction)
+		if eviction == nil {
+			return false, nil, nil
+		}
+		gracePeriods <- *eviction.DeleteOptions.GracePeriodSeconds
+		return true, nil, nil
+	})
+
+	options := config.AutoscalingOptions{
+		MaxGracefulTerminationSec: 20,
+		MaxPodEvictionTime:        5 * time.Second,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	evictor := Evictor{EvictionRetryTime: 0, PodEvictionHeadroom: DefaultPodEvictionHeadroom}
+	_, err = evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{p1}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(120), <-gracePeriods)
+}
+
+func TestDrainNodeWithPodsNodeGroupMaxGracefulTermination(t *testing.T) {
+	gracePeriods := make(chan int64, 10)
+	fakeClient := &fake.Clientset{}
+
+	p1 := BuildTestPod("p1", 100, 0)
+	terminationGracePeriod := int64(300)
+	p1.Spec.TerminationGracePeriodSeconds = &terminationGracePeriod
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroupWithCustomOptions("ng1", 1, 10, 1, &config.NodeGroupAutoscalingOptions{MaxGracefulTerminationSec: 5})
+	provider.AddNode("ng1", n1)
+
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		gracePeriods <- *eviction.DeleteOptions.GracePeriodSeconds
+		return true, nil, nil
+	})
+
+	options := config.AutoscalingOptions{
+		NodeGroupDefaults:         config.NodeGroupAutoscalingOptions{MaxGracefulTerminationSec: 20},
+		MaxGracefulTerminationSec: 20,
+		MaxPodEvictionTime:        5 * time.Second,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, provider, nil, nil)
+	assert.NoError(t, err)
+
+	evictor := Evictor{EvictionRetryTime: 0, PodEvictionHeadroom: DefaultPodEvictionHeadroom, configGetter: nodegroupconfig.NewDefaultNodeGroupConfigProcessor(options.NodeGroupDefaults)}
+	_, err = evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{p1}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), <-gracePeriods)
+}
+
 func TestDrainNodeWithPodsWithRescheduled(t *testing.T) {
 	deletedPods := make(chan string, 10)
 	fakeClient := &fake.Clientset{}
@@ -351,6 +756,283 @@ func TestDrainNodeWithPodsWithRetries(t *testing.T) {
 	assert.Equal(t, p3.Name, deleted[3])
 }
 
+func TestDrainNodeWithPodsEvictOrDelete(t *testing.T) {
+	webhookDenial := &errors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Message: `admission webhook "deny.example.com" denied the request: pod is protected`,
+	}}
+	for _, tc := range []struct {
+		name        string
+		drainMode   sdoptions.DrainMode
+		evictionErr error
+		// alternateErr, if set, is returned by every other eviction.
+		alternateErr error
+		wantDeleted  bool
+	}{
+		{
+			name:        "evictions denied by webhook, deleted",
+			drainMode:   sdoptions.EvictOrDeleteDrainMode,
+			evictionErr: webhookDenial,
+			wantDeleted: true,
+		},
+		{
+			name:        "evictions denied by webhook, evict only",
+			drainMode:   sdoptions.EvictDrainMode,
+			evictionErr: webhookDenial,
+		},
+		{
+			name:        "evictions rejected by disruption budget, not deleted",
+			drainMode:   sdoptions.EvictOrDeleteDrainMode,
+			evictionErr: errors.NewTooManyRequests("cannot evict pod as it would violate the pod's disruption budget", 0),
+		},
+		{
+			name:        "evictions failing with internal errors, not deleted",
+			drainMode:   sdoptions.EvictOrDeleteDrainMode,
+			evictionErr: errors.NewInternalError(fmt.Errorf("failed calling webhook")),
+		},
+		{
+			name:        "pod covered by multiple disruption budgets, not deleted",
+			drainMode:   sdoptions.EvictOrDeleteDrainMode,
+			evictionErr: errors.NewInternalError(fmt.Errorf("this pod has more than one PodDisruptionBudget, which the eviction subresource does not support")),
+		},
+		{
+			name:         "webhook denials interleaved with internal errors, not deleted",
+			drainMode:    sdoptions.EvictOrDeleteDrainMode,
+			evictionErr:  webhookDenial,
+			alternateErr: errors.NewInternalError(fmt.Errorf("etcdserver: request timed out")),
+		},
+		{
+			name:        "evictions forbidden, not deleted",
+			drainMode:   sdoptions.EvictOrDeleteDrainMode,
+			evictionErr: errors.NewForbidden(apiv1.Resource("pods"), "p1", fmt.Errorf("not allowed")),
+		},
+		{
+			name:        "evictions conflicting, not deleted",
+			drainMode:   sdoptions.EvictOrDeleteDrainMode,
+			evictionErr: errors.NewConflict(apiv1.Resource("pods"), "p1", fmt.Errorf("conflict")),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			deletedPods := make(chan *metav1.DeleteOptions, 10)
+			fakeClient := &fake.Clientset{}
+
+			p1 := BuildTestPod("p1", 100, 0)
+			n1 := BuildTestNode("n1", 1000, 1000)
+			SetNodeReadyState(n1, true, time.Time{})
+
+			fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+			})
+			evictions := 0
+			fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				evictions++
+				if tc.alternateErr != nil && evictions%2 == 0 {
+					return true, nil, tc.alternateErr
+				}
+				return true, nil, tc.evictionErr
+			})
+			fakeClient.Fake.AddReactor("delete", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				deleteAction := action.(core.DeleteActionImpl)
+				deletedPods <- &deleteAction.DeleteOptions
+				return true, nil, nil
+			})
+
+			options := config.AutoscalingOptions{
+				MaxGracefulTerminationSec: 20,
+				MaxPodEvictionTime:        time.Second,
+			}
+			ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+			assert.NoError(t, err)
+
+			evictor := Evictor{
+				EvictionRetryTime:     10 * time.Millisecond,
+				PodEvictionHeadroom:   DefaultPodEvictionHeadroom,
+				MaxEvictionRejections: 2,
+				deleteOptions:         sdoptions.NodeDeleteOptions{DrainMode: tc.drainMode},
+			}
+			results, err := evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{p1}, nil)
+			assert.Equal(t, tc.wantDeleted, results[p1.Name].Deleted)
+			assert.Equal(t, tc.wantDeleted, results[p1.Name].WasEvictionSuccessful())
+			if tc.wantDeleted {
+				assert.NoError(t, err)
+				deleteOptions := <-deletedPods
+				assert.Equal(t, p1.UID, *deleteOptions.Preconditions.UID)
+				assert.NotNil(t, deleteOptions.GracePeriodSeconds)
+			} else {
+				assert.Error(t, err)
+				assert.Empty(t, deletedPods)
+			}
+		})
+	}
+}
+
+func TestDrainNodeWithPodsWebhookDenialPolicy(t *testing.T) {
+	webhookDenial := &errors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Message: `admission webhook "deny.example.com" denied the request: pod is protected`,
+	}}
+	for _, tc := range []struct {
+		name              string
+		policy            sdoptions.WebhookDenialPolicy
+		wantRetried       bool
+		wantOtherEvicted  bool
+		wantDeleted       bool
+		wantDeniedResult  bool
+		wantDrainFinished bool
+	}{
+		{
+			name:             "retry",
+			policy:           sdoptions.RetryWebhookDenialPolicy,
+			wantRetried:      true,
+			wantOtherEvicted: true,
+			wantDeniedResult: true,
+		},
+		{
+			name:             "skip node",
+			policy:           sdoptions.SkipNodeWebhookDenialPolicy,
+			wantDeniedResult: true,
+		},
+		{
+			name:              "force delete",
+			policy:            sdoptions.ForceDeleteWebhookDenialPolicy,
+			wantOtherEvicted:  true,
+			wantDeleted:       true,
+			wantDrainFinished: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			evictions := map[string]int{}
+			deletedPods := make(chan string, 10)
+			fakeClient := &fake.Clientset{}
+
+			protected := BuildTestPod("protected", 100, 0)
+			protected.Annotations = map[string]string{apiv1.PodDeletionCost: "-10"}
+			other := BuildTestPod("other", 100, 0)
+			n1 := BuildTestNode("n1", 1000, 1000)
+			SetNodeReadyState(n1, true, time.Time{})
+
+			fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+			})
+			fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+				mutex.Lock()
+				defer mutex.Unlock()
+				evictions[eviction.Name]++
+				if eviction.Name == protected.Name {
+					return true, nil, webhookDenial
+				}
+				return true, nil, nil
+			})
+			fakeClient.Fake.AddReactor("delete", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				deletedPods <- action.(core.DeleteActionImpl).Name
+				return true, nil, nil
+			})
+
+			options := config.AutoscalingOptions{
+				MaxGracefulTerminationSec: 20,
+				MaxPodEvictionTime:        200 * time.Millisecond,
+			}
+			ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+			assert.NoError(t, err)
+
+			evictor := Evictor{
+				EvictionRetryTime:   10 * time.Millisecond,
+				PodEvictionHeadroom: DefaultPodEvictionHeadroom,
+				deleteOptions:       sdoptions.NodeDeleteOptions{WebhookDenialPolicy: tc.policy},
+			}
+			results, err := evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{protected, other}, nil)
+			if tc.wantDrainFinished {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			assert.Equal(t, tc.wantDeleted, results[protected.Name].Deleted)
+			assert.Equal(t, tc.wantDeniedResult, results[protected.Name].DeniedByWebhook)
+			assert.Equal(t, tc.wantOtherEvicted, results[other.Name].WasEvictionSuccessful())
+			mutex.Lock()
+			defer mutex.Unlock()
+			assert.Equal(t, tc.wantRetried, evictions[protected.Name] > 1)
+			assert.Equal(t, tc.wantOtherEvicted, evictions[other.Name] == 1)
+			if tc.wantDeleted {
+				assert.Equal(t, protected.Name, utils.GetStringFromChan(deletedPods))
+			} else {
+				assert.Empty(t, deletedPods)
+			}
+		})
+	}
+}
+
+func TestDrainNodeWithPodsForceDeleteAfterDrainTimeout(t *testing.T) {
+	deletedPods := make(chan string, 10)
+	fakeClient := &fake.Clientset{}
+
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.Spec.NodeName = "n1"
+	p2 := BuildTestPod("p2", 300, 0)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		getAction := action.(core.GetAction)
+		if getAction.GetName() == p1.Name {
+			// p1 ignores its eviction and keeps running on the node.
+			return true, p1, nil
+		}
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+	fakeClient.Fake.AddReactor("delete", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		deleteAction := action.(core.DeleteActionImpl)
+		assert.Equal(t, int64(0), *deleteAction.DeleteOptions.GracePeriodSeconds)
+		deletedPods <- deleteAction.Name
+		return true, nil, nil
+	})
+
+	options := config.AutoscalingOptions{
+		MaxGracefulTerminationSec: 20,
+		MaxPodEvictionTime:        5 * time.Second,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	r := &drainStatusRecorder{}
+	evictor := Evictor{
+		EvictionRetryTime:   0,
+		PodEvictionHeadroom: DefaultPodEvictionHeadroom,
+		drainStatusRegister: r,
+		deleteOptions:       sdoptions.NodeDeleteOptions{NodeDrainTimeout: time.Millisecond},
+	}
+	results, err := evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{p1, p2}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, p1.Name, utils.GetStringFromChan(deletedPods))
+	assert.Empty(t, deletedPods)
+	assert.True(t, results[p1.Name].Deleted)
+	assert.True(t, results[p1.Name].WasEvictionSuccessful())
+	assert.False(t, results[p2.Name].Deleted)
+	assert.True(t, results[p2.Name].WasEvictionSuccessful())
+
+	var phases []status.NodeDrainPhase
+	for _, drainStatus := range r.statuses {
+		phases = append(phases, drainStatus.Phase)
+	}
+	// Evicting statuses report each created eviction, the second WaitingForTermination status reports progress: p2
+	// is gone.
+	assert.Equal(t, []status.NodeDrainPhase{status.NodeDrainEvicting, status.NodeDrainEvicting, status.NodeDrainEvicting, status.NodeDrainWaitingForTermination, status.NodeDrainWaitingForTermination, status.NodeDrainForceDeleting, status.NodeDrainSucceeded}, phases)
+	assert.Equal(t, 1, r.statuses[1].PodsEvicted)
+	assert.Equal(t, 2, r.statuses[2].PodsEvicted)
+	assert.Equal(t, 2, r.statuses[3].PodsRemaining)
+	assert.Equal(t, 1, r.statuses[4].PodsRemaining)
+	assert.Equal(t, 1, r.statuses[5].PodsRemaining)
+	assert.Equal(t, 0, r.statuses[6].PodsRemaining)
+	assert.Equal(t, 2, r.statuses[6].PodsToRemove)
+}
+
 func TestDrainNodeWithPodsDaemonSetEvictionFailure(t *testing.T) {
 	fakeClient := &fake.Clientset{}
 
@@ -441,6 +1123,7 @@ func TestDrainNodeWithPodsEvictionFailure(t *testing.T) {
 	}
 	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
 	assert.NoError(t, err)
+	ctx.EvictionBackoff = evictionbackoff.NewLedger(time.Minute, time.Hour)
 	r := evRegister{}
 	evictor := Evictor{EvictionRetryTime: 0, PodEvictionHeadroom: DefaultPodEvictionHeadroom, evictionRegister: &r}
 	evictionResults, err := evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{p1, p2, p3, p4}, []*apiv1.Pod{})
@@ -463,6 +1146,11 @@ func TestDrainNodeWithPodsEvictionFailure(t *testing.T) {
 	assert.True(t, evictionResults["p3"].WasEvictionSuccessful())
 	assert.False(t, evictionResults["p4"].WasEvictionSuccessful())
 	assert.Contains(t, r.pods, p1, p3)
+	now := time.Now()
+	assert.False(t, ctx.EvictionBackoff.IsBackedOff(p1, now))
+	assert.True(t, ctx.EvictionBackoff.IsBackedOff(p2, now))
+	assert.False(t, ctx.EvictionBackoff.IsBackedOff(p3, now))
+	assert.True(t, ctx.EvictionBackoff.IsBackedOff(p4, now))
 }
 
 func TestDrainWithPodsNodeDisappearanceFailure(t *testing.T) {
@@ -604,6 +1292,41 @@ func TestPodsToEvict(t *testing.T) {
 	}
 }
 
+func TestEvictionRequestResult(t *testing.T) {
+	for tn, tc := range map[string]struct {
+		err  error
+		want metrics.EvictionRequestResult
+	}{
+		"no error": {
+			want: metrics.EvictionSucceeded,
+		},
+		"pod not found": {
+			err:  errors.NewNotFound(apiv1.Resource("pods"), "pod"),
+			want: metrics.EvictionSucceeded,
+		},
+		"rejected by disruption budget": {
+			err:  errors.NewTooManyRequests("budget exceeded", 0),
+			want: metrics.EvictionRejectedByBudget,
+		},
+		"denied by admission webhook": {
+			err: &errors.StatusError{ErrStatus: metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusForbidden,
+				Message: `admission webhook "deny.example.com" denied the request`,
+			}},
+			want: metrics.EvictionDeniedByWebhook,
+		},
+		"other error": {
+			err:  fmt.Errorf("connection refused"),
+			want: metrics.EvictionFailed,
+		},
+	} {
+		t.Run(tn, func(t *testing.T) {
+			assert.Equal(t, tc.want, evictionRequestResult(tc.err))
+		})
+	}
+}
+
 func regularPod(name string) *apiv1.Pod {
 	return &apiv1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -646,3 +1369,14 @@ func (eR *evRegister) RegisterEviction(pod *apiv1.Pod) {
 	defer eR.Unlock()
 	eR.pods = append(eR.pods, pod)
 }
+
+type drainStatusRecorder struct {
+	sync.Mutex
+	statuses []status.NodeDrainStatus
+}
+
+func (r *drainStatusRecorder) RegisterDrainStatus(_ string, drainStatus status.NodeDrainStatus) {
+	r.Lock()
+	defer r.Unlock()
+	r.statuses = append(r.statuses, drainStatus)
+}
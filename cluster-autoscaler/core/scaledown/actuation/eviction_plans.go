@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+)
+
+// EvictionPlans keeps the eviction groups determined by the drain simulation of drained nodes, so that their pods
+// are evicted in the simulated order, e.g. StatefulSet pods in reverse ordinal order. It is safe for concurrent use,
+// and methods of a nil EvictionPlans do nothing.
+type EvictionPlans struct {
+	mutex  sync.Mutex
+	groups map[string][]simulator.EvictionGroup
+}
+
+// NewEvictionPlans creates a new EvictionPlans.
+func NewEvictionPlans() *EvictionPlans {
+	return &EvictionPlans{
+		groups: make(map[string][]simulator.EvictionGroup),
+	}
+}
+
+// SetEvictionGroups sets the eviction groups of the pods evicted from the node.
+func (p *EvictionPlans) SetEvictionGroups(nodeName string, groups []simulator.EvictionGroup) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.groups[nodeName] = groups
+}
+
+// EvictionGroups returns the eviction groups of the pods evicted from the node, and whether they were set.
+func (p *EvictionPlans) EvictionGroups(nodeName string) ([]simulator.EvictionGroup, bool) {
+	if p == nil {
+		return nil, false
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	groups, found := p.groups[nodeName]
+	return groups, found
+}
+
+// Forget forgets the eviction groups of the pods evicted from the node.
+func (p *EvictionPlans) Forget(nodeName string) {
+	if p == nil {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.groups, nodeName)
+}
+
+// plannedEvictionGroups returns the pods grouped according to the planned eviction groups. Pods which weren't part of
+// the plan, e.g. terminal pods, are evicted with the first group. Pods are grouped by EvictionOrder if there is no plan.
+func plannedEvictionGroups(planned []simulator.EvictionGroup, found bool, pods []*apiv1.Pod) []simulator.EvictionGroup {
+	var groups []simulator.EvictionGroup
+	if !found {
+		for _, group := range simulator.EvictionOrder(pods) {
+			groups = append(groups, simulator.EvictionGroup{Pods: group})
+		}
+		return groups
+	}
+	toEvict := make(map[string]*apiv1.Pod, len(pods))
+	for _, pod := range pods {
+		toEvict[pod.Namespace+"/"+pod.Name] = pod
+	}
+	for _, group := range planned {
+		var groupPods []*apiv1.Pod
+		for _, pod := range group.Pods {
+			key := pod.Namespace + "/" + pod.Name
+			if podToEvict, found := toEvict[key]; found {
+				groupPods = append(groupPods, podToEvict)
+				delete(toEvict, key)
+			}
+		}
+		if len(groupPods) > 0 {
+			groups = append(groups, simulator.EvictionGroup{Pods: groupPods, AwaitReady: group.AwaitReady})
+		}
+	}
+	var unplanned []*apiv1.Pod
+	for _, pod := range pods {
+		if _, found := toEvict[pod.Namespace+"/"+pod.Name]; found {
+			unplanned = append(unplanned, pod)
+		}
+	}
+	if len(unplanned) == 0 {
+		return groups
+	}
+	if len(groups) == 0 {
+		return []simulator.EvictionGroup{{Pods: unplanned}}
+	}
+	groups[0].Pods = append(unplanned, groups[0].Pods...)
+	return groups
+}
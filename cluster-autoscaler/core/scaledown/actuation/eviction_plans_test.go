@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestPlannedEvictionGroups(t *testing.T) {
+	web0 := BuildTestPod("web-0", 100, 0)
+	web1 := BuildTestPod("web-1", 100, 0)
+	other := BuildTestPod("other", 100, 0)
+	low := BuildTestPod("low", 100, 0)
+	low.Spec.Priority = int32Ptr(-10)
+	planned := []simulator.EvictionGroup{
+		{Pods: []*apiv1.Pod{web1}},
+		{Pods: []*apiv1.Pod{web0}, AwaitReady: []*apiv1.Pod{web1}},
+		{Pods: []*apiv1.Pod{BuildTestPod("gone", 100, 0)}},
+	}
+
+	for desc, tc := range map[string]struct {
+		planned []simulator.EvictionGroup
+		found   bool
+		pods    []*apiv1.Pod
+		want    []simulator.EvictionGroup
+	}{
+		"no plan": {
+			pods: []*apiv1.Pod{other, low},
+			want: []simulator.EvictionGroup{{Pods: []*apiv1.Pod{low}}, {Pods: []*apiv1.Pod{other}}},
+		},
+		"planned pods": {
+			planned: planned,
+			found:   true,
+			pods:    []*apiv1.Pod{web0, web1},
+			want:    planned[:2],
+		},
+		"unplanned pods evicted with the first group": {
+			planned: planned,
+			found:   true,
+			pods:    []*apiv1.Pod{web0, other, web1},
+			want: []simulator.EvictionGroup{
+				{Pods: []*apiv1.Pod{other, web1}},
+				{Pods: []*apiv1.Pod{web0}, AwaitReady: []*apiv1.Pod{web1}},
+			},
+		},
+		"empty plan": {
+			found: true,
+			pods:  []*apiv1.Pod{other},
+			want:  []simulator.EvictionGroup{{Pods: []*apiv1.Pod{other}}},
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			assert.Equal(t, tc.want, plannedEvictionGroups(tc.planned, tc.found, tc.pods))
+		})
+	}
+}
+
+func TestEvictionPlans(t *testing.T) {
+	groups := []simulator.EvictionGroup{{Pods: []*apiv1.Pod{BuildTestPod("p", 100, 0)}}}
+	plans := NewEvictionPlans()
+	plans.SetEvictionGroups("n1", groups)
+
+	got, found := plans.EvictionGroups("n1")
+	assert.True(t, found)
+	assert.Equal(t, groups, got)
+	plans.Forget("n1")
+	_, found = plans.EvictionGroups("n1")
+	assert.False(t, found)
+
+	var nilPlans *EvictionPlans
+	nilPlans.SetEvictionGroups("n1", groups)
+	_, found = nilPlans.EvictionGroups("n1")
+	assert.False(t, found)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+)
+
+// evictionRateWindow is the window over which the evictions are rate limited.
+const evictionRateWindow = time.Minute
+
+// maxPodEvictionsPerMinuteGetter is the part of NodeGroupConfigProcessor
+// returning MaxPodEvictionsPerMinute.
+type maxPodEvictionsPerMinuteGetter interface {
+	// GetMaxPodEvictionsPerMinute returns MaxPodEvictionsPerMinute value that should be used for a given NodeGroup.
+	GetMaxPodEvictionsPerMinute(nodeGroup cloudprovider.NodeGroup) (int, error)
+}
+
+// EvictionRateLimiter keeps the number of pods evicted within any minute within the cluster-wide limit and the
+// limits of node groups of the drained nodes.
+type EvictionRateLimiter struct {
+	cloudProvider cloudprovider.CloudProvider
+	clusterLimit  int
+	configGetter  maxPodEvictionsPerMinuteGetter
+	now           func() time.Time
+	sleep         func(time.Duration)
+
+	mutex sync.Mutex
+	// cluster contains the times of evictions within the last window.
+	cluster []time.Time
+	// nodeGroups contains the times of evictions within the last window per node group.
+	nodeGroups map[string][]time.Time
+}
+
+// NewEvictionRateLimiter returns a new EvictionRateLimiter. A clusterLimit of 0 means that only the node group
+// limits apply. configGetter can be nil, in which case only the cluster-wide limit applies.
+func NewEvictionRateLimiter(cloudProvider cloudprovider.CloudProvider, clusterLimit int, configGetter maxPodEvictionsPerMinuteGetter) *EvictionRateLimiter {
+	return &EvictionRateLimiter{
+		cloudProvider: cloudProvider,
+		clusterLimit:  clusterLimit,
+		configGetter:  configGetter,
+		now:           time.Now,
+		sleep:         time.Sleep,
+		nodeGroups:    make(map[string][]time.Time),
+	}
+}
+
+// Wait blocks until a pod can be evicted from the node within the limits, and
+// reserves the eviction. It returns false without reserving the eviction if
+// that isn't possible before deadline.
+func (l *EvictionRateLimiter) Wait(node *apiv1.Node, deadline time.Time) bool {
+	if l == nil {
+		return true
+	}
+	nodeGroupId, nodeGroupLimit := l.nodeGroupLimit(node)
+	if l.clusterLimit <= 0 && nodeGroupLimit <= 0 {
+		return true
+	}
+	for {
+		next, ok := l.tryReserve(nodeGroupId, nodeGroupLimit)
+		if ok {
+			return true
+		}
+		if next.After(deadline) {
+			return false
+		}
+		l.sleep(next.Sub(l.now()))
+	}
+}
+
+// tryReserve reserves an eviction if it is within the limits. Otherwise, it
+// returns the time at which the limits may allow it. Evictions are only
+// recorded for the limits which are set.
+func (l *EvictionRateLimiter) tryReserve(nodeGroupId string, nodeGroupLimit int) (time.Time, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	var next time.Time
+	if l.clusterLimit > 0 {
+		l.cluster = dropExpired(l.cluster, now)
+		if len(l.cluster) >= l.clusterLimit {
+			next = l.cluster[len(l.cluster)-l.clusterLimit].Add(evictionRateWindow)
+		}
+	}
+	if nodeGroupLimit > 0 {
+		evictions := dropExpired(l.nodeGroups[nodeGroupId], now)
+		l.nodeGroups[nodeGroupId] = evictions
+		if len(evictions) >= nodeGroupLimit {
+			if groupNext := evictions[len(evictions)-nodeGroupLimit].Add(evictionRateWindow); groupNext.After(next) {
+				next = groupNext
+			}
+		}
+	}
+	if !next.IsZero() {
+		return next, false
+	}
+	if l.clusterLimit > 0 {
+		l.cluster = append(l.cluster, now)
+	}
+	if nodeGroupLimit > 0 {
+		l.nodeGroups[nodeGroupId] = append(l.nodeGroups[nodeGroupId], now)
+	}
+	return now, true
+}
+
+func (l *EvictionRateLimiter) nodeGroupLimit(node *apiv1.Node) (string, int) {
+	if l.configGetter == nil {
+		return "", 0
+	}
+	nodeGroup, err := l.cloudProvider.NodeGroupForNode(node)
+	if err != nil || nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+		return "", 0
+	}
+	limit, err := l.configGetter.GetMaxPodEvictionsPerMinute(nodeGroup)
+	if err != nil {
+		klog.Warningf("Failed to get max pod evictions per minute for node group %s, using cluster-wide limit only: %v", nodeGroup.Id(), err)
+		return "", 0
+	}
+	return nodeGroup.Id(), limit
+}
+
+// dropExpired drops the times of evictions which are out of the window.
+func dropExpired(evictions []time.Time, now time.Time) []time.Time {
+	i := 0
+	for i < len(evictions) && !now.Before(evictions[i].Add(evictionRateWindow)) {
+		i++
+	}
+	return evictions[i:]
+}
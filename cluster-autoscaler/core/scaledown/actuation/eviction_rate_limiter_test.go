@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestEvictionRateLimiter(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	limited := testprovider.NewTestNodeGroup("limited", 10, 0, 2, true, false, "", nil, nil)
+	limited.SetOptions(&config.NodeGroupAutoscalingOptions{MaxPodEvictionsPerMinute: 2})
+	provider.InsertNodeGroup(limited)
+	provider.InsertNodeGroup(testprovider.NewTestNodeGroup("unlimited", 10, 0, 2, true, false, "", nil, nil))
+	limitedNode := BuildTestNode("limited-node", 1000, 1000)
+	unlimitedNode := BuildTestNode("unlimited-node", 1000, 1000)
+	provider.AddNode("limited", limitedNode)
+	provider.AddNode("unlimited", unlimitedNode)
+
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	limiter := NewEvictionRateLimiter(provider, 3, nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{}))
+	limiter.now = func() time.Time { return now }
+	var slept []time.Duration
+	limiter.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+
+	// The node group limit applies to nodes of the node group only.
+	assert.True(t, limiter.Wait(limitedNode, now))
+	now = now.Add(10 * time.Second)
+	assert.True(t, limiter.Wait(limitedNode, now))
+	assert.False(t, limiter.Wait(limitedNode, now.Add(49*time.Second)))
+	assert.True(t, limiter.Wait(unlimitedNode, now))
+	assert.Empty(t, slept)
+
+	// The cluster-wide limit applies to all nodes.
+	assert.True(t, limiter.Wait(unlimitedNode, now.Add(time.Minute)))
+	assert.Equal(t, []time.Duration{50 * time.Second}, slept)
+
+	// Evictions are allowed again once the window passes.
+	assert.True(t, limiter.Wait(limitedNode, now.Add(time.Minute)))
+	assert.Equal(t, []time.Duration{50 * time.Second, 10 * time.Second}, slept)
+}
+
+func TestEvictionRateLimiterUnlimited(t *testing.T) {
+	var limiter *EvictionRateLimiter
+	assert.True(t, limiter.Wait(BuildTestNode("node", 1000, 1000), time.Now()))
+
+	limiter = NewEvictionRateLimiter(testprovider.NewTestCloudProvider(nil, nil), 0, nil)
+	for i := 0; i < 100; i++ {
+		assert.True(t, limiter.Wait(BuildTestNode("node", 1000, 1000), time.Now()))
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+)
+
+// EvictionScheduler orders evictions of pods covered by the same pod disruption budgets on nodes drained in parallel.
+// A pod is evicted once the disruption budgets matching it allow that, one eviction per budget at a time and in the
+// order in which the nodes were registered. As a result, nodes sharing a budget are drained one after another as
+// replacement pods become ready, instead of evictions being rejected and retried on all of the nodes at once.
+type EvictionScheduler struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	nextSeq int
+	pods    map[types.UID]scheduledPod
+	nodes   map[string][]types.UID
+	// busy contains budgets for which an eviction is in progress.
+	busy map[types.NamespacedName]bool
+	// waiting contains pods waiting for their turn to be evicted.
+	waiting map[types.UID]scheduledPod
+}
+
+type scheduledPod struct {
+	seq  int
+	pdbs []types.NamespacedName
+}
+
+// NewEvictionScheduler returns a new EvictionScheduler.
+func NewEvictionScheduler() *EvictionScheduler {
+	s := &EvictionScheduler{
+		pods:    make(map[types.UID]scheduledPod),
+		nodes:   make(map[string][]types.UID),
+		busy:    make(map[types.NamespacedName]bool),
+		waiting: make(map[types.UID]scheduledPod),
+	}
+	s.cond = sync.NewCond(&s.mutex)
+	return s
+}
+
+// RegisterNode registers pods to be evicted from the node, grouped by the disruption budgets matching them according
+// to remainingPdbTracker used by the scale down simulation. Pods not covered by any budget are not registered and
+// are evicted without waiting.
+func (s *EvictionScheduler) RegisterNode(nodeName string, pods []*apiv1.Pod, remainingPdbTracker pdb.RemainingPdbTracker) {
+	if s == nil || remainingPdbTracker == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	seq := s.nextSeq
+	s.nextSeq++
+	for _, pod := range pods {
+		var pdbs []types.NamespacedName
+		for _, budget := range remainingPdbTracker.MatchingPdbs(pod) {
+			pdbs = append(pdbs, types.NamespacedName{Namespace: budget.Namespace, Name: budget.Name})
+		}
+		if len(pdbs) == 0 {
+			continue
+		}
+		s.pods[pod.UID] = scheduledPod{seq: seq, pdbs: pdbs}
+		s.nodes[nodeName] = append(s.nodes[nodeName], pod.UID)
+	}
+}
+
+// ForgetNode drops the pods registered for the node.
+func (s *EvictionScheduler) ForgetNode(nodeName string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, uid := range s.nodes[nodeName] {
+		delete(s.pods, uid)
+	}
+	delete(s.nodes, nodeName)
+}
+
+// covers tells if the pod is registered, i.e. covered by disruption budgets.
+func (s *EvictionScheduler) covers(pod *apiv1.Pod) bool {
+	if s == nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, found := s.pods[pod.UID]
+	return found
+}
+
+// acquire blocks until it is the turn of the pod to be evicted, or until deadline. If the turn came, disruption
+// budgets of the pod are returned and marked busy until release is called.
+func (s *EvictionScheduler) acquire(pod *apiv1.Pod, deadline time.Time) (pdbs []types.NamespacedName, release func(), ok bool) {
+	if s == nil {
+		return nil, nil, false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	scheduled, found := s.pods[pod.UID]
+	if !found {
+		return nil, nil, false
+	}
+	s.waiting[pod.UID] = scheduled
+	defer delete(s.waiting, pod.UID)
+
+	timer := time.AfterFunc(time.Until(deadline), func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.cond.Broadcast()
+	})
+	defer timer.Stop()
+	for !s.isTurnOf(pod.UID, scheduled) {
+		if !time.Now().Before(deadline) {
+			return nil, nil, false
+		}
+		s.cond.Wait()
+	}
+	for _, budget := range scheduled.pdbs {
+		s.busy[budget] = true
+	}
+	return scheduled.pdbs, func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for _, budget := range scheduled.pdbs {
+			delete(s.busy, budget)
+		}
+		s.cond.Broadcast()
+	}, true
+}
+
+// isTurnOf tells if none of the budgets of the pod is busy and no pod from a node registered earlier waits for any
+// of them.
+func (s *EvictionScheduler) isTurnOf(uid types.UID, scheduled scheduledPod) bool {
+	for _, budget := range scheduled.pdbs {
+		if s.busy[budget] {
+			return false
+		}
+	}
+	for otherUID, other := range s.waiting {
+		if otherUID == uid || other.seq >= scheduled.seq {
+			continue
+		}
+		if sharesBudget(scheduled.pdbs, other.pdbs) {
+			return false
+		}
+	}
+	return true
+}
+
+func sharesBudget(a, b []types.NamespacedName) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// waitForDisruptionsAllowed waits until all the budgets allow a disruption, or until deadline. Budgets that can't be
+// fetched are assumed to allow it, in which case the eviction itself decides.
+func waitForDisruptionsAllowed(ctx *acontext.AutoscalingContext, pod *apiv1.Pod, pdbs []types.NamespacedName, deadline time.Time, waitBetweenRetries time.Duration) {
+	for first := true; first || time.Now().Before(deadline); time.Sleep(waitBetweenRetries) {
+		first = false
+		allowed := true
+		for _, budget := range pdbs {
+			podDisruptionBudget, err := ctx.ClientSet.PolicyV1().PodDisruptionBudgets(budget.Namespace).Get(context.TODO(), budget.Name, metav1.GetOptions{})
+			if err != nil {
+				klog.V(4).Infof("Failed to get pod disruption budget %s of pod %s/%s: %v", budget, pod.Namespace, pod.Name, err)
+				continue
+			}
+			if podDisruptionBudget.Status.DisruptionsAllowed < 1 {
+				klog.V(4).Infof("Pod disruption budget %s doesn't allow evicting pod %s/%s yet", budget, pod.Namespace, pod.Name)
+				allowed = false
+			}
+		}
+		if allowed {
+			return
+		}
+	}
+}
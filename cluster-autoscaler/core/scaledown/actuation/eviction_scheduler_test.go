@@ -0,0 +1,304 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	sdoptions "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestEvictionSchedulerOrder(t *testing.T) {
+	p1 := testPodWithLabel("p1", "app", "a")
+	p2 := testPodWithLabel("p2", "app", "a")
+	p3 := testPodWithLabel("p3", "app", "a")
+	other := testPodWithLabel("other", "app", "b")
+	tracker := pdb.NewBasicRemainingPdbTracker()
+	assert.NoError(t, tracker.SetPdbs([]*policyv1.PodDisruptionBudget{testPdb("pdb-a", "app", "a", 1)}))
+
+	s := NewEvictionScheduler()
+	s.RegisterNode("n1", []*apiv1.Pod{p1, p2}, tracker)
+	s.RegisterNode("n2", []*apiv1.Pod{p3, other}, tracker)
+	deadline := time.Now().Add(time.Minute)
+
+	// Pods not covered by any budget don't wait.
+	_, _, ok := s.acquire(other, deadline)
+	assert.False(t, ok)
+
+	pdbs, release, ok := s.acquire(p1, deadline)
+	assert.True(t, ok)
+	assert.Len(t, pdbs, 1)
+
+	var mutex sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for _, pod := range []*apiv1.Pod{p3, p2} {
+		wg.Add(1)
+		go func(pod *apiv1.Pod) {
+			defer wg.Done()
+			_, release, ok := s.acquire(pod, deadline)
+			assert.True(t, ok)
+			mutex.Lock()
+			order = append(order, pod.Name)
+			mutex.Unlock()
+			release()
+		}(pod)
+	}
+	assert.Eventually(t, func() bool {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		return len(s.waiting) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	release()
+	wg.Wait()
+	// Pods from the node registered first go first.
+	assert.Equal(t, []string{"p2", "p3"}, order)
+
+	s.ForgetNode("n1")
+	s.ForgetNode("n2")
+	_, _, ok = s.acquire(p1, deadline)
+	assert.False(t, ok)
+}
+
+func TestEvictionSchedulerDeadline(t *testing.T) {
+	p1 := testPodWithLabel("p1", "app", "a")
+	p2 := testPodWithLabel("p2", "app", "a")
+	tracker := pdb.NewBasicRemainingPdbTracker()
+	assert.NoError(t, tracker.SetPdbs([]*policyv1.PodDisruptionBudget{testPdb("pdb-a", "app", "a", 1)}))
+
+	s := NewEvictionScheduler()
+	s.RegisterNode("n1", []*apiv1.Pod{p1}, tracker)
+	s.RegisterNode("n2", []*apiv1.Pod{p2}, tracker)
+
+	_, release, ok := s.acquire(p1, time.Now().Add(time.Minute))
+	assert.True(t, ok)
+	defer release()
+	_, _, ok = s.acquire(p2, time.Now().Add(50*time.Millisecond))
+	assert.False(t, ok)
+}
+
+func TestDrainNodesSharingDisruptionBudget(t *testing.T) {
+	p1 := testPodWithLabel("p1", "app", "a")
+	p2 := testPodWithLabel("p2", "app", "a")
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	budget := testPdb("pdb-a", "app", "a", 1)
+
+	var mutex sync.Mutex
+	disruptionsAllowed := int32(1)
+	rejections := 0
+	var evicted []string
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("get", "poddisruptionbudgets", func(action core.Action) (bool, runtime.Object, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		current := budget.DeepCopy()
+		current.Status.DisruptionsAllowed = disruptionsAllowed
+		return true, current, nil
+	})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		mutex.Lock()
+		defer mutex.Unlock()
+		if disruptionsAllowed < 1 {
+			rejections++
+			return true, nil, errors.NewTooManyRequests("disruption budget exceeded", 0)
+		}
+		disruptionsAllowed--
+		evicted = append(evicted, eviction.Name)
+		// The replacement pod becomes ready shortly after the eviction.
+		time.AfterFunc(100*time.Millisecond, func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+			disruptionsAllowed++
+		})
+		return true, nil, nil
+	})
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+
+	options := config.AutoscalingOptions{
+		MaxGracefulTerminationSec: 20,
+		MaxPodEvictionTime:        5 * time.Second,
+	}
+	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+	assert.NoError(t, err)
+
+	tracker := pdb.NewBasicRemainingPdbTracker()
+	assert.NoError(t, tracker.SetPdbs([]*policyv1.PodDisruptionBudget{budget}))
+	scheduler := NewEvictionScheduler()
+	scheduler.RegisterNode(n1.Name, []*apiv1.Pod{p1}, tracker)
+	scheduler.RegisterNode(n2.Name, []*apiv1.Pod{p2}, tracker)
+	evictor := Evictor{EvictionRetryTime: 10 * time.Millisecond, PodEvictionHeadroom: 0, evictionScheduler: scheduler}
+
+	var wg sync.WaitGroup
+	for node, pod := range map[*apiv1.Node]*apiv1.Pod{n1: p1, n2: p2} {
+		wg.Add(1)
+		go func(node *apiv1.Node, pod *apiv1.Pod) {
+			defer wg.Done()
+			_, err := evictor.DrainNodeWithPods(&ctx, node, []*apiv1.Pod{pod}, nil)
+			assert.NoError(t, err)
+		}(node, pod)
+	}
+	wg.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.ElementsMatch(t, []string{"p1", "p2"}, evicted)
+	assert.Zero(t, rejections)
+}
+
+func TestDrainNodesSharingDaemonSetPodBudget(t *testing.T) {
+	for desc, tc := range map[string]struct {
+		respectPdbs        bool
+		disruptionsAllowed int32
+		wantErr            bool
+		wantEvicted        []string
+		wantRejections     bool
+	}{
+		"evictions ordered across nodes": {
+			respectPdbs:        true,
+			disruptionsAllowed: 1,
+			wantEvicted:        []string{"d1", "d2"},
+		},
+		"budget exhausted, drain fails": {
+			respectPdbs:    true,
+			wantErr:        true,
+			wantEvicted:    nil,
+			wantRejections: true,
+		},
+		"budget exhausted, budgets not respected": {
+			wantEvicted:    nil,
+			wantRejections: true,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			d1 := testPodWithLabel("d1", "app", "agent")
+			d2 := testPodWithLabel("d2", "app", "agent")
+			for _, pod := range []*apiv1.Pod{d1, d2} {
+				pod.OwnerReferences = GenerateOwnerReferences("agent", "DaemonSet", "apps/v1", "")
+			}
+			n1 := BuildTestNode("n1", 1000, 1000)
+			n2 := BuildTestNode("n2", 1000, 1000)
+			budget := testPdb("pdb-agent", "app", "agent", tc.disruptionsAllowed)
+
+			var mutex sync.Mutex
+			disruptionsAllowed := tc.disruptionsAllowed
+			rejections := 0
+			var evicted []string
+			fakeClient := &fake.Clientset{}
+			fakeClient.Fake.AddReactor("get", "poddisruptionbudgets", func(action core.Action) (bool, runtime.Object, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				current := budget.DeepCopy()
+				current.Status.DisruptionsAllowed = disruptionsAllowed
+				return true, current, nil
+			})
+			fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+				mutex.Lock()
+				defer mutex.Unlock()
+				if disruptionsAllowed < 1 {
+					rejections++
+					return true, nil, errors.NewTooManyRequests("disruption budget exceeded", 0)
+				}
+				disruptionsAllowed--
+				evicted = append(evicted, eviction.Name)
+				// The recreated pod becomes ready shortly after the eviction.
+				time.AfterFunc(100*time.Millisecond, func() {
+					mutex.Lock()
+					defer mutex.Unlock()
+					disruptionsAllowed++
+				})
+				return true, nil, nil
+			})
+			fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+			})
+
+			options := config.AutoscalingOptions{
+				MaxGracefulTerminationSec: 20,
+				MaxPodEvictionTime:        time.Second,
+			}
+			ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, nil, nil, nil, nil)
+			assert.NoError(t, err)
+
+			scheduler := NewEvictionScheduler()
+			if tc.respectPdbs {
+				tracker := pdb.NewBasicRemainingPdbTracker()
+				assert.NoError(t, tracker.SetPdbs([]*policyv1.PodDisruptionBudget{budget}))
+				scheduler.RegisterNode(n1.Name, []*apiv1.Pod{d1}, tracker)
+				scheduler.RegisterNode(n2.Name, []*apiv1.Pod{d2}, tracker)
+			}
+			evictor := Evictor{
+				EvictionRetryTime:   10 * time.Millisecond,
+				PodEvictionHeadroom: 0,
+				evictionScheduler:   scheduler,
+				deleteOptions:       sdoptions.NodeDeleteOptions{RespectDaemonSetPdbs: tc.respectPdbs},
+			}
+
+			var wg sync.WaitGroup
+			for node, pod := range map[*apiv1.Node]*apiv1.Pod{n1: d1, n2: d2} {
+				wg.Add(1)
+				go func(node *apiv1.Node, pod *apiv1.Pod) {
+					defer wg.Done()
+					_, err := evictor.DrainNodeWithPods(&ctx, node, nil, []*apiv1.Pod{pod})
+					assert.Equal(t, tc.wantErr, err != nil, "DrainNodeWithPods(%s): unexpected error: %v", node.Name, err)
+				}(node, pod)
+			}
+			wg.Wait()
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			assert.ElementsMatch(t, tc.wantEvicted, evicted)
+			assert.Equal(t, tc.wantRejections, rejections > 0)
+		})
+	}
+}
+
+func testPodWithLabel(name, key, value string) *apiv1.Pod {
+	pod := BuildTestPod(name, 100, 0)
+	pod.Labels = map[string]string{key: value}
+	return pod
+}
+
+func testPdb(name, key, value string, disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{key: value}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+	}
+}
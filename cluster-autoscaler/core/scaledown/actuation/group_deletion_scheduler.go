@@ -17,6 +17,8 @@ limitations under the License.
 package actuation
 
 import (
+	stderrors "errors"
+	"strings"
 	"sync"
 	"time"
 
@@ -47,17 +49,20 @@ type GroupDeletionScheduler struct {
 	evictor             Evictor
 	nodeQueue           map[string][]*apiv1.Node
 	failuresForGroup    map[string]bool
+	// delayDeletionCheckInterval is the time between checks whether deletion of a node is still delayed.
+	delayDeletionCheckInterval time.Duration
 }
 
 // NewGroupDeletionScheduler creates an instance of GroupDeletionScheduler.
 func NewGroupDeletionScheduler(ctx *context.AutoscalingContext, ndt *deletiontracker.NodeDeletionTracker, b batcher, evictor Evictor) *GroupDeletionScheduler {
 	return &GroupDeletionScheduler{
-		ctx:                 ctx,
-		nodeDeletionTracker: ndt,
-		nodeDeletionBatcher: b,
-		evictor:             evictor,
-		nodeQueue:           map[string][]*apiv1.Node{},
-		failuresForGroup:    map[string]bool{},
+		ctx:                        ctx,
+		nodeDeletionTracker:        ndt,
+		nodeDeletionBatcher:        b,
+		evictor:                    evictor,
+		nodeQueue:                  map[string][]*apiv1.Node{},
+		failuresForGroup:           map[string]bool{},
+		delayDeletionCheckInterval: DefaultDelayDeletionCheckInterval,
 	}
 }
 
@@ -96,6 +101,61 @@ func (ds *GroupDeletionScheduler) ScheduleDeletion(nodeInfo *framework.NodeInfo,
 	ds.addToBatcher(nodeInfo, nodeGroup, batchSize, drain, opts.ZeroOrMaxNodeScaling)
 }
 
+// ScheduleEmptyDeletions schedules deletion of empty nodes from the same node group. The nodes are prepared for
+// deletion in parallel and passed over to NodeDeletionBatcher together, in chunks of at most maxBatchSize nodes
+// (all at once if maxBatchSize is 0), so that cloud providers can delete them with fewer DeleteNodes calls. Nodes
+// that should be deleted in groups are queued until whole group is scheduled for deletion, as in ScheduleDeletion.
+func (ds *GroupDeletionScheduler) ScheduleEmptyDeletions(nodeInfos []*framework.NodeInfo, nodeGroup cloudprovider.NodeGroup, batchSize, maxBatchSize int) {
+	opts, err := nodeGroup.GetOptions(ds.ctx.NodeGroupDefaults)
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		nodeDeleteResult := status.NodeDeleteResult{ResultType: status.NodeDeleteErrorInternal, Err: errors.NewAutoscalerError(errors.InternalError, "GetOptions returned error %v", err)}
+		for _, nodeInfo := range nodeInfos {
+			ds.AbortNodeDeletion(nodeInfo.Node(), nodeGroup.Id(), false, "failed to get autoscaling options for a node group", nodeDeleteResult)
+		}
+		return
+	}
+	if opts == nil {
+		opts = &config.NodeGroupAutoscalingOptions{}
+	}
+
+	results := make([]status.NodeDeleteResult, len(nodeInfos))
+	var wg sync.WaitGroup
+	for i := range nodeInfos {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = ds.prepareNodeForDeletion(nodeInfos[i], false)
+		}(i)
+	}
+	wg.Wait()
+
+	var prepared []*framework.NodeInfo
+	for i, nodeInfo := range nodeInfos {
+		if results[i].Err != nil {
+			ds.AbortNodeDeletion(nodeInfo.Node(), nodeGroup.Id(), false, "prepareNodeForDeletion failed", results[i])
+			continue
+		}
+		prepared = append(prepared, nodeInfo)
+	}
+	if opts.ZeroOrMaxNodeScaling {
+		for _, nodeInfo := range prepared {
+			ds.addToBatcher(nodeInfo, nodeGroup, batchSize, false, true)
+		}
+		return
+	}
+	if maxBatchSize <= 0 {
+		maxBatchSize = len(prepared)
+	}
+	for start := 0; start < len(prepared); start += maxBatchSize {
+		end := min(start+maxBatchSize, len(prepared))
+		nodes := make([]*apiv1.Node, 0, end-start)
+		for _, nodeInfo := range prepared[start:end] {
+			nodes = append(nodes, nodeInfo.Node())
+		}
+		ds.nodeDeletionBatcher.AddNodes(nodes, nodeGroup, false)
+	}
+}
+
 // prepareNodeForDeletion is a long-running operation, so it needs to avoid locking the AtomicDeletionScheduler object
 func (ds *GroupDeletionScheduler) prepareNodeForDeletion(nodeInfo *framework.NodeInfo, drain bool) status.NodeDeleteResult {
 	node := nodeInfo.Node()
@@ -104,17 +164,51 @@ func (ds *GroupDeletionScheduler) prepareNodeForDeletion(nodeInfo *framework.Nod
 			return status.NodeDeleteResult{ResultType: status.NodeDeleteErrorFailedToEvictPods, Err: err, PodEvictionResults: evictionResults}
 		}
 	} else {
-		if err := ds.evictor.EvictDaemonSetPods(ds.ctx, nodeInfo, time.Now()); err != nil {
+		if err := ds.evictor.EvictDaemonSetPods(ds.ctx, nodeInfo, time.Now()); stderrors.Is(err, ErrDaemonSetPodBudget) {
+			return status.NodeDeleteResult{ResultType: status.NodeDeleteErrorFailedToEvictPods, Err: errors.NewAutoscalerError(errors.ApiCallError, "%v", err)}
+		} else if err != nil {
 			// Evicting DS pods is best-effort, so proceed with the deletion even if there are errors.
 			klog.Warningf("Error while evicting DS pods from an empty node %q: %v", node.Name, err)
 		}
 	}
-	if err := WaitForDelayDeletion(node, ds.ctx.ListerRegistry.AllNodeLister(), ds.ctx.AutoscalingOptions.NodeDeletionDelayTimeout); err != nil {
+	nodeLister := ds.ctx.ListerRegistry.AllNodeLister()
+	timeout := ds.ctx.AutoscalingOptions.NodeDeletionDelayTimeout
+	if timeout != 0 {
+		// External controllers, e.g. cleaning up storage, can delay deletion of the node once it's marked for
+		// deletion, so the annotations are checked on the current version of the node.
+		if freshNode, err := nodeLister.Get(node.Name); err == nil && freshNode != nil {
+			node = freshNode
+		}
+	}
+	if hooks := delayDeletionHooks(node); timeout != 0 && len(hooks) > 0 {
+		ds.ctx.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDownDelayed", "waiting up to %v for %s before deleting the node", timeout, strings.Join(hooks, ", "))
+		if drain {
+			ds.registerDeletionDelay(node.Name, hooks, time.Now().Add(timeout))
+			defer ds.registerDeletionDelay(node.Name, nil, time.Time{})
+		}
+	}
+	if err := waitForDelayDeletion(node, nodeLister, timeout, ds.delayDeletionCheckInterval); err != nil {
 		return status.NodeDeleteResult{ResultType: status.NodeDeleteErrorFailedToDelete, Err: err}
 	}
 	return status.NodeDeleteResult{ResultType: status.NodeDeleteOk}
 }
 
+// registerDeletionDelay reports in the drain status of the node that its deletion is delayed by the hooks until the
+// deadline, or that it's not delayed anymore if there are no hooks.
+func (ds *GroupDeletionScheduler) registerDeletionDelay(nodeName string, hooks []string, deadline time.Time) {
+	drainStatus, found := ds.nodeDeletionTracker.DrainStatuses()[nodeName]
+	if !found {
+		return
+	}
+	drainStatus.Phase = status.NodeDrainDelayingDeletion
+	if len(hooks) == 0 {
+		drainStatus.Phase = status.NodeDrainSucceeded
+	}
+	drainStatus.Deadline = deadline
+	drainStatus.DelayedBy = hooks
+	ds.nodeDeletionTracker.RegisterDrainStatus(nodeName, drainStatus)
+}
+
 func (ds *GroupDeletionScheduler) addToBatcher(nodeInfo *framework.NodeInfo, nodeGroup cloudprovider.NodeGroup, batchSize int, drain, atomic bool) {
 	ds.Lock()
 	defer ds.Unlock()
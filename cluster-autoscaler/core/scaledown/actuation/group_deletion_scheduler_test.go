@@ -18,7 +18,9 @@ package actuation
 
 import (
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -33,11 +35,76 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/kubernetes/pkg/scheduler/framework"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
+func TestPrepareNodeForDeletionDelayed(t *testing.T) {
+	node := BuildTestNode("n1", 1000, 10)
+	// The annotation is added after the node was marked for deletion.
+	annotatedNode := node.DeepCopy()
+	annotatedNode.Annotations = map[string]string{DelayDeletionAnnotationPrefix + "storage": "true"}
+	allNodeLister := &syncNodeLister{TestNodeLister: kube_util.NewTestNodeLister([]*apiv1.Node{annotatedNode})}
+	registry := kube_util.NewListerRegistry(allNodeLister, nil, nil, nil, nil, nil, nil, nil, nil)
+	ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{NodeDeletionDelayTimeout: time.Minute}, &fake.Clientset{}, registry, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Couldn't create context: %v", err)
+	}
+	tracker := deletiontracker.NewNodeDeletionTracker(0)
+	tracker.StartDeletionWithDrain("ng", node.Name)
+	tracker.RegisterDrainStatus(node.Name, status.NodeDrainStatus{Phase: status.NodeDrainSucceeded})
+	scheduler := NewGroupDeletionScheduler(&ctx, tracker, nil, Evictor{PodEvictionHeadroom: DefaultPodEvictionHeadroom})
+	scheduler.delayDeletionCheckInterval = time.Millisecond
+
+	go func() {
+		for {
+			if drainStatus := tracker.DrainStatuses()[node.Name]; drainStatus.Phase == status.NodeDrainDelayingDeletion {
+				if diff := cmp.Diff([]string{"storage"}, drainStatus.DelayedBy); diff != "" {
+					t.Errorf("DelayedBy: diff (-want +got):\n%s", diff)
+				}
+				allNodeLister.SetNodes([]*apiv1.Node{node})
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(node)
+	result := scheduler.prepareNodeForDeletion(nodeInfo, true)
+	if result.Err != nil {
+		t.Errorf("prepareNodeForDeletion(): unexpected error: %v", result.Err)
+	}
+	if got := tracker.DrainStatuses()[node.Name]; got.Phase != status.NodeDrainSucceeded || len(got.DelayedBy) != 0 {
+		t.Errorf("prepareNodeForDeletion(): got drain status %+v after deletion delay, want %v phase", got, status.NodeDrainSucceeded)
+	}
+}
+
+// syncNodeLister is a TestNodeLister which can be updated while it's used by other goroutines.
+type syncNodeLister struct {
+	sync.Mutex
+	*kube_util.TestNodeLister
+}
+
+func (l *syncNodeLister) List() ([]*apiv1.Node, error) {
+	l.Lock()
+	defer l.Unlock()
+	return l.TestNodeLister.List()
+}
+
+func (l *syncNodeLister) Get(name string) (*apiv1.Node, error) {
+	l.Lock()
+	defer l.Unlock()
+	return l.TestNodeLister.Get(name)
+}
+
+func (l *syncNodeLister) SetNodes(nodes []*apiv1.Node) {
+	l.Lock()
+	defer l.Unlock()
+	l.TestNodeLister.SetNodes(nodes)
+}
+
 func TestScheduleDeletion(t *testing.T) {
 	testNg := testprovider.NewTestNodeGroup("test", 100, 0, 3, true, false, "n1-standard-2", nil, nil)
 	atomic2 := sizedNodeGroup("atomic-2", 2, true, false)
@@ -152,12 +219,80 @@ func TestScheduleDeletion(t *testing.T) {
 	}
 }
 
+func TestScheduleEmptyDeletions(t *testing.T) {
+	testNg := testprovider.NewTestNodeGroup("test", 100, 0, 5, true, false, "n1-standard-2", nil, nil)
+	atomic4 := sizedNodeGroup("atomic-4", 4, true, false)
+
+	testCases := []struct {
+		name          string
+		toSchedule    *budgets.NodeGroupView
+		maxBatchSize  int
+		wantBatchSize []int
+	}{
+		{
+			name:          "nodes deleted in chunks of max batch size",
+			toSchedule:    generateNodeGroupViewList(testNg, 0, 5)[0],
+			maxBatchSize:  2,
+			wantBatchSize: []int{2, 2, 1},
+		},
+		{
+			name:          "all nodes deleted at once without max batch size",
+			toSchedule:    generateNodeGroupViewList(testNg, 0, 5)[0],
+			wantBatchSize: []int{5},
+		},
+		{
+			name:          "atomic node group deleted as a whole",
+			toSchedule:    generateNodeGroupViewList(atomic4, 0, 4)[0],
+			maxBatchSize:  2,
+			wantBatchSize: []int{4},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			provider := testprovider.NewTestCloudProvider(nil, func(nodeGroup string, node string) error {
+				return nil
+			})
+			tc.toSchedule.Group.(*testprovider.TestNodeGroup).SetCloudProvider(provider)
+			provider.InsertNodeGroup(tc.toSchedule.Group)
+			var nodeInfos []*framework.NodeInfo
+			for _, node := range tc.toSchedule.Nodes {
+				provider.AddNode(tc.toSchedule.Group.Id(), node)
+				nodeInfos = append(nodeInfos, infoForNode(node))
+			}
+
+			batcher := &countingBatcher{}
+			tracker := deletiontracker.NewNodeDeletionTracker(0)
+			podLister := kube_util.NewTestPodLister([]*apiv1.Pod{})
+			pdbLister := kube_util.NewTestPodDisruptionBudgetLister([]*policyv1.PodDisruptionBudget{})
+			dsLister, err := kube_util.NewTestDaemonSetLister([]*appsv1.DaemonSet{})
+			if err != nil {
+				t.Fatalf("Couldn't create daemonset lister")
+			}
+			registry := kube_util.NewListerRegistry(nil, nil, podLister, pdbLister, dsLister, nil, nil, nil, nil)
+			ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, &fake.Clientset{}, registry, provider, nil, nil)
+			if err != nil {
+				t.Fatalf("Couldn't set up autoscaling context: %v", err)
+			}
+			scheduler := NewGroupDeletionScheduler(&ctx, tracker, batcher, Evictor{PodEvictionHeadroom: DefaultPodEvictionHeadroom})
+
+			scheduler.ScheduleEmptyDeletions(nodeInfos, tc.toSchedule.Group, len(nodeInfos), tc.maxBatchSize)
+
+			if diff := cmp.Diff(tc.wantBatchSize, batcher.batchSizes); diff != "" {
+				t.Errorf("AddNodes() batch sizes diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 type countingBatcher struct {
 	addedNodes int
+	batchSizes []int
 }
 
 func (b *countingBatcher) AddNodes(nodes []*apiv1.Node, nodeGroup cloudprovider.NodeGroup, drain bool) {
 	b.addedNodes += len(nodes)
+	b.batchSizes = append(b.batchSizes, len(nodes))
 }
 
 func scheduleAll(toSchedule []*budgets.NodeGroupView, scheduler *GroupDeletionScheduler) error {
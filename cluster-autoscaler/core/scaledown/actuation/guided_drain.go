@@ -0,0 +1,235 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	klog "k8s.io/klog/v2"
+
+	acontext "k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/budgets"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+)
+
+// DefaultGuidedDrainCheckInterval is the time between checks for pods replacing the pods evicted from a node, if the
+// drain is guided.
+const DefaultGuidedDrainCheckInterval = time.Second
+
+// GuidedDrain keeps the destination nodes picked by the fit simulation for the pods evicted from drained nodes, so
+// that the pods replacing them can be nominated to the same nodes. The scheduler tries the nominated node of a pod
+// first, and accounts for the pod on that node when scheduling other pods of lower or equal priority, so the
+// replacements are likely to end up where the simulation expected them to. It is safe for concurrent use, and
+// methods of a nil GuidedDrain do nothing.
+type GuidedDrain struct {
+	mutex        sync.Mutex
+	destinations map[string]map[string]string
+}
+
+// NewGuidedDrain creates a new GuidedDrain.
+func NewGuidedDrain() *GuidedDrain {
+	return &GuidedDrain{
+		destinations: make(map[string]map[string]string),
+	}
+}
+
+// SetDestinations sets the destination nodes of the pods evicted from the node, keyed by pod namespace/name.
+func (g *GuidedDrain) SetDestinations(nodeName string, destinations map[string]string) {
+	if g == nil {
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.destinations[nodeName] = destinations
+}
+
+// Destinations returns the destination nodes of the pods evicted from the node, keyed by pod namespace/name.
+func (g *GuidedDrain) Destinations(nodeName string) map[string]string {
+	if g == nil {
+		return nil
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.destinations[nodeName]
+}
+
+// Forget forgets the destination nodes of the pods evicted from the node.
+func (g *GuidedDrain) Forget(nodeName string) {
+	if g == nil {
+		return
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.destinations, nodeName)
+}
+
+// planGuidedDrain simulates draining the nodes one after another, the same way scale down simulation does, and sets
+// the destination nodes of their pods. Pods are only moved to nodes which aren't being deleted.
+func (a *Actuator) planGuidedDrain(empty, drain []*budgets.NodeGroupView) {
+	removed := make(map[string]bool)
+	emptyInProgress, drainInProgress := a.nodeDeletionTracker.DeletionsInProgress()
+	for _, nodeName := range append(emptyInProgress, drainInProgress...) {
+		removed[nodeName] = true
+	}
+	for _, bucket := range append(empty, drain...) {
+		for _, node := range bucket.Nodes {
+			removed[node.Name] = true
+		}
+	}
+	nodeInfos, err := a.ctx.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Scale-down: not guiding drains, failed to list nodes: %v", err)
+		return
+	}
+	destinations := make(map[string]bool)
+	for _, nodeInfo := range nodeInfos {
+		if !removed[nodeInfo.Node().Name] {
+			destinations[nodeInfo.Node().Name] = true
+		}
+	}
+
+	// Avoid persisting changes done by the simulation.
+	a.ctx.ClusterSnapshot.Fork()
+	defer a.ctx.ClusterSnapshot.Revert()
+	rs := simulator.NewRemovalSimulator(a.ctx.ListerRegistry, a.ctx.ClusterSnapshot, a.ctx.PredicateChecker, simulator.NewUsageTracker(), a.deleteOptions, a.drainabilityRules, true)
+	rs.SetPodsToMoveFunc(a.ctx.PodsToMove)
+	timestamp := time.Now()
+	for _, bucket := range drain {
+		for _, node := range bucket.Nodes {
+			// The node itself has to be in the destinations to be simulated, pods are never moved to it anyway.
+			destinations[node.Name] = true
+			toRemove, _ := rs.SimulateNodeRemoval(node.Name, destinations, timestamp, nil)
+			delete(destinations, node.Name)
+			if toRemove == nil {
+				klog.V(2).Infof("Scale-down: not guiding drain of node %s, no place found for its pods", node.Name)
+				continue
+			}
+			a.guidedDrain.SetDestinations(node.Name, toRemove.Destinations)
+		}
+	}
+}
+
+// replacementNominator nominates the destination nodes of pods evicted from a node for pods replacing them, i.e.
+// pending pods with the same controller. Replacements are nominated in order of creation, to the destinations of the
+// pods in order of eviction.
+type replacementNominator struct {
+	destinations map[string]string
+
+	mutex     sync.Mutex
+	remaining int
+	queues    map[types.UID][]string
+	nominated map[types.UID]bool
+}
+
+func newReplacementNominator(destinations map[string]string) *replacementNominator {
+	if len(destinations) == 0 {
+		return nil
+	}
+	return &replacementNominator{
+		destinations: destinations,
+		remaining:    len(destinations),
+		queues:       make(map[types.UID][]string),
+		nominated:    make(map[types.UID]bool),
+	}
+}
+
+// evicted queues the destinations of the evicted pods for their replacements.
+func (n *replacementNominator) evicted(pods []*apiv1.Pod) {
+	if n == nil {
+		return
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for _, pod := range pods {
+		destination, found := n.destinations[pod.Namespace+"/"+pod.Name]
+		if !found {
+			continue
+		}
+		ref := drain.ControllerRef(pod)
+		if ref == nil {
+			// Nothing replaces the pod.
+			n.remaining--
+			continue
+		}
+		n.queues[ref.UID] = append(n.queues[ref.UID], destination)
+	}
+}
+
+// run nominates replacements every interval, until all destinations are nominated or the deadline passes. It keeps
+// running after the drain finishes, as pods may be replaced only once they terminate.
+func (n *replacementNominator) run(ctx *acontext.AutoscalingContext, interval time.Duration, deadline time.Time) {
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		if n.nominate(ctx) == 0 {
+			return
+		}
+	}
+}
+
+// nominate nominates the pending replacements which aren't nominated to any node yet, and returns the number of
+// destinations left to nominate.
+func (n *replacementNominator) nominate(ctx *acontext.AutoscalingContext) int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if len(n.queues) == 0 || ctx.ListerRegistry == nil {
+		return n.remaining
+	}
+	allPods, err := ctx.AllPodLister().List()
+	if err != nil {
+		klog.Errorf("Failed to list pods: %v", err)
+		return n.remaining
+	}
+	var replacements []*apiv1.Pod
+	for _, pod := range allPods {
+		if ref := drain.ControllerRef(pod); ref != nil && len(n.queues[ref.UID]) > 0 && pod.Spec.NodeName == "" && pod.Status.NominatedNodeName == "" && pod.DeletionTimestamp == nil && !n.nominated[pod.UID] {
+			replacements = append(replacements, pod)
+		}
+	}
+	sort.SliceStable(replacements, func(i, j int) bool {
+		return replacements[i].CreationTimestamp.Before(&replacements[j].CreationTimestamp)
+	})
+	for _, pod := range replacements {
+		uid := drain.ControllerRef(pod).UID
+		destination := n.queues[uid][0]
+		n.queues[uid] = n.queues[uid][1:]
+		if len(n.queues[uid]) == 0 {
+			delete(n.queues, uid)
+		}
+		n.nominated[pod.UID] = true
+		n.remaining--
+		if err := nominateNode(ctx, pod, destination); err != nil {
+			klog.Warningf("Failed to nominate node %s for pod %s/%s: %v", destination, pod.Namespace, pod.Name, err)
+			continue
+		}
+		klog.V(2).Infof("Nominated node %s for pod %s/%s replacing an evicted pod", destination, pod.Namespace, pod.Name)
+	}
+	return n.remaining
+}
+
+func nominateNode(ctx *acontext.AutoscalingContext, pod *apiv1.Pod, nodeName string) error {
+	patch := []byte(fmt.Sprintf(`{"status":{"nominatedNodeName":%q}}`, nodeName))
+	_, err := ctx.ClientSet.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.MergePatchType, patch, metav1.PatchOptions{}, "status")
+	return err
+}
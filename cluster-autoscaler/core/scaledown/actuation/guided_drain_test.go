@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/budgets"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestPlanGuidedDrain(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n3 := BuildTestNode("n3", 600, 1000)
+	n4 := BuildTestNode("n4", 600, 1000)
+	empty := BuildTestNode("empty", 2000, 1000)
+	ownerRefs := GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "rs-uid")
+	p1 := BuildTestPod("p1", 500, 0)
+	p1.Spec.NodeName = n1.Name
+	p1.OwnerReferences = ownerRefs
+	p2 := BuildTestPod("p2", 500, 0)
+	p2.Spec.NodeName = n2.Name
+	p2.OwnerReferences = ownerRefs
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng", 0, 10, 5)
+	for _, node := range []*apiv1.Node{n1, n2, n3, n4, empty} {
+		provider.AddNode("ng", node)
+	}
+	ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, &fake.Clientset{}, nil, provider, nil, nil)
+	assert.NoError(t, err)
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, ctx.ClusterSnapshot, []*apiv1.Node{n1, n2, n3, n4, empty}, []*apiv1.Pod{p1, p2})
+
+	actuator := Actuator{ctx: &ctx, nodeDeletionTracker: deletiontracker.NewNodeDeletionTracker(0), guidedDrain: NewGuidedDrain()}
+	nodeGroup := provider.GetNodeGroup("ng")
+	actuator.planGuidedDrain(
+		[]*budgets.NodeGroupView{{Group: nodeGroup, Nodes: []*apiv1.Node{empty}}},
+		[]*budgets.NodeGroupView{{Group: nodeGroup, Nodes: []*apiv1.Node{n1, n2}}})
+
+	// Pods don't move to the empty node being deleted, nor to each other's nodes, and the pod of the node drained
+	// first takes up the room for the one of the node drained next.
+	d1 := actuator.guidedDrain.Destinations(n1.Name)
+	d2 := actuator.guidedDrain.Destinations(n2.Name)
+	assert.Len(t, d1, 1)
+	assert.Len(t, d2, 1)
+	assert.ElementsMatch(t, []string{n3.Name, n4.Name}, []string{d1["default/p1"], d2["default/p2"]})
+
+	// The simulation doesn't modify the snapshot.
+	nodeInfo, err := ctx.ClusterSnapshot.NodeInfos().Get(n1.Name)
+	assert.NoError(t, err)
+	assert.Len(t, nodeInfo.Pods, 1)
+}
+
+func TestDrainNodeWithPodsGuidedDrain(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+	ownerRefs := GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "rs-uid")
+	p1 := BuildTestPod("p1", 100, 0)
+	p1.OwnerReferences = ownerRefs
+	p2 := BuildTestPod("p2", 100, 0)
+	p2.OwnerReferences = ownerRefs
+	p3 := BuildTestPod("p3", 100, 0)
+	p3.OwnerReferences = GenerateOwnerReferences("other", "ReplicaSet", "apps/v1", "other-uid")
+
+	now := time.Now()
+	r1 := BuildTestPod("r1", 100, 0)
+	r1.OwnerReferences = ownerRefs
+	r1.CreationTimestamp = metav1.NewTime(now.Add(-time.Second))
+	r2 := BuildTestPod("r2", 100, 0)
+	r2.OwnerReferences = ownerRefs
+	r2.CreationTimestamp = metav1.NewTime(now)
+	scheduled := BuildTestPod("scheduled", 100, 0)
+	scheduled.OwnerReferences = ownerRefs
+	scheduled.Spec.NodeName = "n5"
+	nominated := BuildTestPod("nominated", 100, 0)
+	nominated.OwnerReferences = ownerRefs
+	nominated.Status.NominatedNodeName = "n5"
+	unrelated := BuildTestPod("unrelated", 100, 0)
+	unrelated.OwnerReferences = p3.OwnerReferences
+
+	podLister := &fakePodLister{}
+	nominations := make(chan string, 10)
+	fakeClient := &fake.Clientset{}
+	fakeClient.Fake.AddReactor("get", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.NewNotFound(apiv1.Resource("pod"), "whatever")
+	})
+	fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		if eviction.Name == "p2" {
+			podLister.set(r2, r1, scheduled, nominated, unrelated)
+		}
+		return true, nil, nil
+	})
+	fakeClient.Fake.AddReactor("patch", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		patch := action.(core.PatchAction)
+		assert.Equal(t, "status", patch.GetSubresource())
+		nominations <- patch.GetName() + ":" + string(patch.GetPatch())
+		return true, nil, nil
+	})
+
+	options := config.AutoscalingOptions{
+		MaxGracefulTerminationSec: 0,
+		MaxPodEvictionTime:        time.Second,
+	}
+	registry := kube_util.NewListerRegistry(nil, nil, podLister, nil, nil, nil, nil, nil, nil)
+	ctx, err := NewScaleTestAutoscalingContext(options, fakeClient, registry, nil, nil, nil)
+	assert.NoError(t, err)
+
+	guidedDrain := NewGuidedDrain()
+	guidedDrain.SetDestinations(n1.Name, map[string]string{"default/p1": "n2", "default/p2": "n3", "default/p3": "n4"})
+	evictor := Evictor{GuidedDrainCheckInterval: 10 * time.Millisecond, guidedDrain: guidedDrain}
+	_, err = evictor.DrainNodeWithPods(&ctx, n1, []*apiv1.Pod{p1, p2}, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, guidedDrain.Destinations(n1.Name))
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case nomination := <-nominations:
+			got = append(got, nomination)
+		case <-time.After(time.Second):
+			t.Fatalf("Timeout waiting for nominations, got %v", got)
+		}
+	}
+	// The earliest replacement gets the destination of the pod evicted first. Replacements already scheduled or
+	// nominated and pods of other controllers are left alone.
+	assert.Equal(t, []string{`r1:{"status":{"nominatedNodeName":"n2"}}`, `r2:{"status":{"nominatedNodeName":"n3"}}`}, got)
+	time.Sleep(50 * time.Millisecond)
+	assert.Empty(t, nominations)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/budgets"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+)
+
+// preflightEvictions issues dry-run evictions of pods on the nodes to drain, and drops the nodes on which any of the
+// evictions would be rejected, either by a disruption budget or by an admission webhook. Rejected evictions are
+// registered as failed evictions, so that scale down simulation treats the pods as blocking until they are backed
+// off. Node groups deleted in batches are dropped as a whole if any of their nodes is dropped.
+func (a *Actuator) preflightEvictions(nodeGroupViews []*budgets.NodeGroupView) []*budgets.NodeGroupView {
+	var result []*budgets.NodeGroupView
+	for _, bucket := range nodeGroupViews {
+		var nodes []*apiv1.Node
+		for _, node := range bucket.Nodes {
+			if err := a.preflightNodeEvictions(node); err != nil {
+				klog.Warningf("Scale-down: not draining node %s, eviction dry-run failed: %v", node.Name, err)
+				a.ctx.Recorder.Eventf(node, apiv1.EventTypeWarning, "ScaleDownPreflightFailed", "not draining the node, eviction dry-run failed: %v", err)
+				continue
+			}
+			nodes = append(nodes, node)
+		}
+		if len(nodes) == 0 || (bucket.BatchSize > 0 && len(nodes) < len(bucket.Nodes)) {
+			continue
+		}
+		result = append(result, &budgets.NodeGroupView{Group: bucket.Group, Nodes: nodes, BatchSize: bucket.BatchSize})
+	}
+	return result
+}
+
+// preflightNodeEvictions returns an error if a dry-run eviction of any of the pods to move from the node is
+// rejected. Errors not predicting a failed drain, e.g. webhooks not supporting dry-run requests, are ignored.
+func (a *Actuator) preflightNodeEvictions(node *apiv1.Node) error {
+	nodeInfo, err := a.ctx.ClusterSnapshot.NodeInfos().Get(node.Name)
+	if err != nil {
+		return nil
+	}
+	simulation := podsToMove(a.ctx)(nodeInfo, a.deleteOptions, a.drainabilityRules, a.ctx.ListerRegistry, nil, time.Now())
+	if simulation.Err != nil {
+		// The deletion doesn't drain nodes with pods blocking the drain either, no need to check them.
+		return nil
+	}
+	for _, pod := range simulation.PodsToMove {
+		eviction := &policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: pod.Namespace,
+				Name:      pod.Name,
+			},
+			DeleteOptions: &metav1.DeleteOptions{
+				DryRun: []string{metav1.DryRunAll},
+			},
+		}
+		err := a.ctx.ClientSet.CoreV1().Pods(pod.Namespace).Evict(context.TODO(), eviction)
+		if err == nil || kube_errors.IsNotFound(err) {
+			continue
+		}
+		if !kube_errors.IsTooManyRequests(err) && !drain.IsAdmissionWebhookDenial(err) {
+			klog.V(4).Infof("Scale-down: ignoring failed eviction dry-run of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			continue
+		}
+		if a.ctx.EvictionBackoff != nil {
+			a.ctx.EvictionBackoff.RegisterFailure(pod, err, time.Now())
+		}
+		return fmt.Errorf("eviction of pod %s/%s would be rejected: %v", pod.Namespace, pod.Name, err)
+	}
+	return nil
+}
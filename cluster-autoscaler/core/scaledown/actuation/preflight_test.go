@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package actuation
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/budgets"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	sdoptions "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/evictionbackoff"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestPreflightEvictions(t *testing.T) {
+	webhookDenial := &errors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Reason:  metav1.StatusReasonForbidden,
+		Message: `admission webhook "deny.example.com" denied the request: pod is protected`,
+	}}
+	dryRunUnsupported := errors.NewBadRequest(`admission webhook "side-effects.example.com" does not support dry run`)
+	pdbRejection := errors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+
+	for _, tc := range []struct {
+		name        string
+		errors      map[string]error
+		batchSize   int
+		wantNodes   []string
+		wantBackoff []string
+	}{
+		{
+			name:      "all evictions would succeed",
+			wantNodes: []string{"n1", "n2"},
+		},
+		{
+			name:        "eviction denied by webhook",
+			errors:      map[string]error{"p1": webhookDenial},
+			wantNodes:   []string{"n2"},
+			wantBackoff: []string{"p1"},
+		},
+		{
+			name:        "eviction rejected by disruption budget",
+			errors:      map[string]error{"p2": pdbRejection},
+			wantNodes:   []string{"n1"},
+			wantBackoff: []string{"p2"},
+		},
+		{
+			name:      "other errors are ignored",
+			errors:    map[string]error{"p1": dryRunUnsupported, "p2": errors.NewNotFound(apiv1.Resource("pod"), "p2")},
+			wantNodes: []string{"n1", "n2"},
+		},
+		{
+			name:        "node group deleted in batches is dropped as a whole",
+			errors:      map[string]error{"p1": webhookDenial},
+			batchSize:   2,
+			wantBackoff: []string{"p1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			n1 := BuildTestNode("n1", 1000, 1000)
+			n2 := BuildTestNode("n2", 1000, 1000)
+			p1 := BuildTestPod("p1", 100, 0)
+			p1.Spec.NodeName = n1.Name
+			p1.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "")
+			p2 := BuildTestPod("p2", 100, 0)
+			p2.Spec.NodeName = n2.Name
+			p2.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "")
+
+			fakeClient := &fake.Clientset{}
+			fakeClient.Fake.AddReactor("create", "pods", func(action core.Action) (bool, runtime.Object, error) {
+				eviction := action.(core.CreateAction).GetObject().(*policyv1beta1.Eviction)
+				if !assert.Equal(t, []string{metav1.DryRunAll}, eviction.DeleteOptions.DryRun) {
+					return true, nil, nil
+				}
+				return true, nil, tc.errors[eviction.Name]
+			})
+			provider := testprovider.NewTestCloudProvider(nil, nil)
+			provider.AddNodeGroup("ng", 0, 10, 2)
+			provider.AddNode("ng", n1)
+			provider.AddNode("ng", n2)
+
+			ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, fakeClient, nil, provider, nil, nil)
+			assert.NoError(t, err)
+			clustersnapshot.InitializeClusterSnapshotOrDie(t, ctx.ClusterSnapshot, []*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2})
+			ledger := evictionbackoff.NewLedger(time.Minute, time.Hour)
+			ctx.EvictionBackoff = ledger
+
+			actuator := Actuator{ctx: &ctx, deleteOptions: sdoptions.NodeDeleteOptions{}}
+			nodeGroup := provider.GetNodeGroup("ng")
+			got := actuator.preflightEvictions([]*budgets.NodeGroupView{{Group: nodeGroup, Nodes: []*apiv1.Node{n1, n2}, BatchSize: tc.batchSize}})
+			var gotNodes []string
+			for _, bucket := range got {
+				assert.Equal(t, nodeGroup, bucket.Group)
+				assert.Equal(t, tc.batchSize, bucket.BatchSize)
+				for _, node := range bucket.Nodes {
+					gotNodes = append(gotNodes, node.Name)
+				}
+			}
+			assert.Equal(t, tc.wantNodes, gotNodes)
+			var gotBackoff []string
+			for _, pod := range []*apiv1.Pod{p1, p2} {
+				if ledger.IsBackedOff(pod, time.Now()) {
+					gotBackoff = append(gotBackoff, pod.Name)
+				}
+			}
+			assert.Equal(t, tc.wantBackoff, gotBackoff)
+		})
+	}
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package budgets
 
 import (
+	"math"
 	"reflect"
+	"sort"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
@@ -25,6 +27,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 )
 
 // NodeGroupView is a subset of nodes from a given NodeGroup
@@ -142,11 +145,111 @@ func (bp *ScaleDownBudgetProcessor) CropNodes(as scaledown.ActuationStatus, empt
 	parallelismBudget -= allowedCount
 	drainBudget = min(parallelismBudget, drainBudget)
 
+	drainIndividual = bp.cropToNodeGroupDrainBudgets(as, drainIndividual)
 	drainToDelete, _ = cropIndividualNodes(drainToDelete, drainIndividual, drainBudget)
 
 	return emptyToDelete, drainToDelete
 }
 
+// CropDrainCandidates crops the candidates needing drain, ordered by preference, to the ones which can be drained
+// within the node group and cluster-wide drain parallelism and pod eviction rate budgets. Candidates from node groups
+// using atomic scaling are kept, as they can only be deleted all at once. Candidates are kept as long as some of the
+// eviction rate budgets is left, so that nodes running more pods than the budgets allow per minute aren't starved;
+// their evictions are spread over time by the actuator. The budgets are given to the candidates expected to drain
+// faster first, so that nodes with long drains don't take up the parallelism budgets of nodes which could be drained
+// quickly. The kept candidates stay in the order of preference.
+func (bp *ScaleDownBudgetProcessor) CropDrainCandidates(as scaledown.ActuationStatus, candidates []simulator.NodeToBeRemoved) []simulator.NodeToBeRemoved {
+	_, drainInProgress := as.DeletionsInProgress()
+	drainBudget := bp.ctx.MaxDrainParallelism - len(drainInProgress)
+	evictionBudget := math.MaxInt
+	if bp.ctx.MaxPodEvictionsPerMinute > 0 {
+		evictionBudget = bp.ctx.MaxPodEvictionsPerMinute
+	}
+	groupDrainBudgets := map[string]int{}
+	groupEvictionBudgets := map[string]int{}
+	byDrainDuration := make([]int, len(candidates))
+	for i := range candidates {
+		byDrainDuration[i] = i
+	}
+	sort.SliceStable(byDrainDuration, func(i, j int) bool {
+		return candidates[byDrainDuration[i]].EstimatedDrainDuration < candidates[byDrainDuration[j]].EstimatedDrainDuration
+	})
+	kept := make([]bool, len(candidates))
+	for _, i := range byDrainDuration {
+		candidate := candidates[i]
+		nodeGroup, err := bp.ctx.CloudProvider.NodeGroupForNode(candidate.Node)
+		if err != nil || nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+			klog.Errorf("Failed to find node group for %s: %v", candidate.Node.Name, err)
+			continue
+		}
+		autoscalingOptions, err := nodeGroup.GetOptions(bp.ctx.NodeGroupDefaults)
+		if err != nil && err != cloudprovider.ErrNotImplemented {
+			klog.Errorf("Failed to get autoscaling options for node group %s: %v", nodeGroup.Id(), err)
+			continue
+		}
+		if autoscalingOptions != nil && autoscalingOptions.ZeroOrMaxNodeScaling {
+			kept[i] = true
+			continue
+		}
+		id := nodeGroup.Id()
+		if _, found := groupDrainBudgets[id]; !found {
+			groupDrainBudgets[id], groupEvictionBudgets[id] = math.MaxInt, math.MaxInt
+			if autoscalingOptions != nil && autoscalingOptions.MaxDrainParallelism > 0 {
+				groupDrainBudgets[id] = autoscalingOptions.MaxDrainParallelism - as.DrainsCount(id)
+			}
+			if autoscalingOptions != nil && autoscalingOptions.MaxPodEvictionsPerMinute > 0 {
+				groupEvictionBudgets[id] = autoscalingOptions.MaxPodEvictionsPerMinute
+			}
+		}
+		if drainBudget < 1 || groupDrainBudgets[id] < 1 {
+			klog.V(4).Infof("Skipping %s - drain parallelism budget of node group %s or the cluster exceeded", candidate.Node.Name, id)
+			continue
+		}
+		if evictionBudget < 1 || groupEvictionBudgets[id] < 1 {
+			klog.V(4).Infof("Skipping %s - pod eviction rate budget of node group %s or the cluster exceeded", candidate.Node.Name, id)
+			continue
+		}
+		kept[i] = true
+		drainBudget--
+		groupDrainBudgets[id]--
+		evictionBudget -= len(candidate.PodsToReschedule)
+		groupEvictionBudgets[id] -= len(candidate.PodsToReschedule)
+	}
+	var cropped []simulator.NodeToBeRemoved
+	for i, candidate := range candidates {
+		if kept[i] {
+			cropped = append(cropped, candidate)
+		}
+	}
+	return cropped
+}
+
+// cropToNodeGroupDrainBudgets crops the nodes needing drain, so that the number of nodes drained in parallel in
+// each node group doesn't exceed its MaxDrainParallelism.
+func (bp *ScaleDownBudgetProcessor) cropToNodeGroupDrainBudgets(as scaledown.ActuationStatus, groups []*NodeGroupView) []*NodeGroupView {
+	var cropped []*NodeGroupView
+	for _, view := range groups {
+		autoscalingOptions, err := view.Group.GetOptions(bp.ctx.NodeGroupDefaults)
+		if err != nil && err != cloudprovider.ErrNotImplemented {
+			klog.Errorf("Failed to get autoscaling options for node group %s: %v", view.Group.Id(), err)
+			continue
+		}
+		if autoscalingOptions == nil || autoscalingOptions.MaxDrainParallelism <= 0 {
+			cropped = append(cropped, view)
+			continue
+		}
+		budget := autoscalingOptions.MaxDrainParallelism - as.DrainsCount(view.Group.Id())
+		if budget < 1 {
+			continue
+		}
+		if budget < len(view.Nodes) {
+			view.Nodes = view.Nodes[:budget]
+		}
+		cropped = append(cropped, view)
+	}
+	return cropped
+}
+
 func groupBuckets(buckets []*NodeGroupView) map[string]*NodeGroupView {
 	grouped := map[string]*NodeGroupView{}
 	for _, bucket := range buckets {
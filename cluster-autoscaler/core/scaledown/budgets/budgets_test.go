@@ -23,6 +23,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/assert"
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,6 +33,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 )
 
 func TestCropNodesToBudgets(t *testing.T) {
@@ -467,6 +469,169 @@ func TestCropNodesToBudgets(t *testing.T) {
 	}
 }
 
+func TestCropNodesToNodeGroupDrainBudgets(t *testing.T) {
+	limited := testprovider.NewTestNodeGroup("limited", 100, 0, 10, true, false, "n1-standard-2", nil, nil)
+	limited.SetOptions(&config.NodeGroupAutoscalingOptions{MaxDrainParallelism: 2})
+	unlimited := testprovider.NewTestNodeGroup("unlimited", 100, 0, 10, true, false, "n1-standard-2", nil, nil)
+	for tn, tc := range map[string]struct {
+		drainDeletionsInProgress int
+		drain                    []*NodeGroupView
+		wantDrain                []*NodeGroupView
+	}{
+		"node group budget exceeded": {
+			drain:     append(generateNodeGroupViewList(limited, 0, 3), generateNodeGroupViewList(unlimited, 0, 2)...),
+			wantDrain: append(generateNodeGroupViewList(limited, 0, 2), generateNodeGroupViewList(unlimited, 0, 2)...),
+		},
+		"node group budget exceeded with deletions in progress": {
+			drainDeletionsInProgress: 1,
+			drain:                    append(generateNodeGroupViewList(limited, 0, 3), generateNodeGroupViewList(unlimited, 0, 2)...),
+			wantDrain:                append(generateNodeGroupViewList(limited, 0, 1), generateNodeGroupViewList(unlimited, 0, 2)...),
+		},
+		"node group budget used up by deletions in progress": {
+			drainDeletionsInProgress: 2,
+			drain:                    append(generateNodeGroupViewList(limited, 0, 3), generateNodeGroupViewList(unlimited, 0, 2)...),
+			wantDrain:                generateNodeGroupViewList(unlimited, 0, 2),
+		},
+	} {
+		t.Run(tn, func(t *testing.T) {
+			provider := testprovider.NewTestCloudProvider(nil, nil)
+			for _, bucket := range tc.drain {
+				bucket.Group.(*testprovider.TestNodeGroup).SetCloudProvider(provider)
+				provider.InsertNodeGroup(bucket.Group)
+				for _, node := range bucket.Nodes {
+					provider.AddNode(bucket.Group.Id(), node)
+				}
+			}
+			ctx := &context.AutoscalingContext{
+				AutoscalingOptions: config.AutoscalingOptions{
+					MaxScaleDownParallelism: 10,
+					MaxDrainParallelism:     10,
+				},
+				CloudProvider: provider,
+			}
+			ndt := deletiontracker.NewNodeDeletionTracker(1 * time.Hour)
+			for i := 0; i < tc.drainDeletionsInProgress; i++ {
+				ndt.StartDeletionWithDrain(limited.Id(), fmt.Sprintf("drain-node-%d", i))
+			}
+			drainList := []*apiv1.Node{}
+			for _, bucket := range tc.drain {
+				drainList = append(drainList, bucket.Nodes...)
+			}
+
+			_, gotDrain := NewScaleDownBudgetProcessor(ctx).CropNodes(ndt, nil, drainList)
+			if diff := cmp.Diff(tc.wantDrain, gotDrain, cmpopts.EquateEmpty(), transformNodeGroupView); diff != "" {
+				t.Errorf("CropNodes drain nodes diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCropDrainCandidates(t *testing.T) {
+	limited := testprovider.NewTestNodeGroup("limited", 100, 0, 10, true, false, "n1-standard-2", nil, nil)
+	limited.SetOptions(&config.NodeGroupAutoscalingOptions{MaxDrainParallelism: 2, MaxPodEvictionsPerMinute: 5})
+	unlimited := testprovider.NewTestNodeGroup("unlimited", 100, 0, 10, true, false, "n1-standard-2", nil, nil)
+	atomic := sizedNodeGroup("atomic", 3, true)
+	for tn, tc := range map[string]struct {
+		maxDrainParallelism      int
+		maxPodEvictionsPerMinute int
+		drainDeletionsInProgress int
+		candidates               []testCandidate
+		drainDurations           map[string]time.Duration
+		want                     []string
+	}{
+		"all candidates fit": {
+			maxDrainParallelism: 10,
+			candidates:          []testCandidate{{"u1", unlimited, 10}, {"u2", unlimited, 10}, {"l1", limited, 1}},
+			want:                []string{"u1", "u2", "l1"},
+		},
+		"cluster drain budget exceeded": {
+			maxDrainParallelism:      3,
+			drainDeletionsInProgress: 1,
+			candidates:               []testCandidate{{"u1", unlimited, 1}, {"u2", unlimited, 1}, {"u3", unlimited, 1}},
+			want:                     []string{"u1", "u2"},
+		},
+		"node group drain budget exceeded": {
+			maxDrainParallelism: 10,
+			candidates:          []testCandidate{{"l1", limited, 1}, {"l2", limited, 1}, {"l3", limited, 1}, {"u1", unlimited, 1}},
+			want:                []string{"l1", "l2", "u1"},
+		},
+		"node group drain budget used up by deletions in progress": {
+			maxDrainParallelism:      10,
+			drainDeletionsInProgress: 2,
+			candidates:               []testCandidate{{"l1", limited, 1}, {"u1", unlimited, 1}},
+			want:                     []string{"u1"},
+		},
+		"node group eviction budget exceeded": {
+			maxDrainParallelism: 10,
+			candidates:          []testCandidate{{"l1", limited, 6}, {"l2", limited, 1}, {"u1", unlimited, 6}},
+			want:                []string{"l1", "u1"},
+		},
+		"cluster eviction budget exceeded": {
+			maxDrainParallelism:      10,
+			maxPodEvictionsPerMinute: 10,
+			candidates:               []testCandidate{{"u1", unlimited, 5}, {"u2", unlimited, 5}, {"u3", unlimited, 1}},
+			want:                     []string{"u1", "u2"},
+		},
+		"atomic node group kept": {
+			maxDrainParallelism: 1,
+			candidates:          []testCandidate{{"u1", unlimited, 1}, {"a1", atomic, 1}, {"a2", atomic, 1}, {"u2", unlimited, 1}},
+			want:                []string{"u1", "a1", "a2"},
+		},
+		"faster drains get the drain budget first": {
+			maxDrainParallelism: 2,
+			candidates:          []testCandidate{{"u1", unlimited, 1}, {"u2", unlimited, 1}, {"u3", unlimited, 1}},
+			drainDurations:      map[string]time.Duration{"u1": time.Hour, "u2": time.Minute, "u3": 30 * time.Second},
+			want:                []string{"u2", "u3"},
+		},
+		"faster drains get the eviction budget first": {
+			maxDrainParallelism: 10,
+			candidates:          []testCandidate{{"l1", limited, 5}, {"l2", limited, 5}},
+			drainDurations:      map[string]time.Duration{"l1": time.Hour, "l2": time.Minute},
+			want:                []string{"l2"},
+		},
+	} {
+		t.Run(tn, func(t *testing.T) {
+			provider := testprovider.NewTestCloudProvider(nil, nil)
+			var candidates []simulator.NodeToBeRemoved
+			for _, c := range tc.candidates {
+				if provider.GetNodeGroup(c.group.Id()) == nil {
+					c.group.(*testprovider.TestNodeGroup).SetCloudProvider(provider)
+					provider.InsertNodeGroup(c.group)
+				}
+				candidate := simulator.NodeToBeRemoved{Node: generateNode(c.name), EstimatedDrainDuration: tc.drainDurations[c.name]}
+				for i := 0; i < c.pods; i++ {
+					candidate.PodsToReschedule = append(candidate.PodsToReschedule, &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-pod-%d", c.name, i)}})
+				}
+				provider.AddNode(c.group.Id(), candidate.Node)
+				candidates = append(candidates, candidate)
+			}
+			ctx := &context.AutoscalingContext{
+				AutoscalingOptions: config.AutoscalingOptions{
+					MaxDrainParallelism:      tc.maxDrainParallelism,
+					MaxPodEvictionsPerMinute: tc.maxPodEvictionsPerMinute,
+				},
+				CloudProvider: provider,
+			}
+			ndt := deletiontracker.NewNodeDeletionTracker(1 * time.Hour)
+			for i := 0; i < tc.drainDeletionsInProgress; i++ {
+				ndt.StartDeletionWithDrain(limited.Id(), fmt.Sprintf("drain-node-%d", i))
+			}
+
+			var got []string
+			for _, c := range NewScaleDownBudgetProcessor(ctx).CropDrainCandidates(ndt, candidates) {
+				got = append(got, c.Node.Name)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+type testCandidate struct {
+	name  string
+	group cloudprovider.NodeGroup
+	pods  int
+}
+
 // transformNodeGroupView transforms a NodeGroupView to a structure that can be directly compared with other node bucket.
 var transformNodeGroupView = cmp.Transformer("transformNodeGroupView", func(b NodeGroupView) interface{} {
 	return struct {
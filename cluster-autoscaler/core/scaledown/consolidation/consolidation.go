@@ -0,0 +1,237 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consolidation
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Consolidation describes replacing a set of nodes with a single new node.
+type Consolidation struct {
+	// NodesToRemove are the nodes whose pods can be moved to the remaining
+	// nodes and the replacement node.
+	NodesToRemove []*apiv1.Node
+	// NodeGroup is the node group in which the replacement node is created.
+	NodeGroup cloudprovider.NodeGroup
+	// Savings is the hourly price of NodesToRemove minus the hourly price of
+	// the replacement node. If the cloud provider doesn't expose pricing, it
+	// is the number of nodes saved.
+	Savings float64
+}
+
+// Planner finds consolidations: sets of underutilized nodes that can be
+// replaced with a single, larger node from a different node group. Pod moves
+// are validated with the same removal simulation as regular scale down.
+type Planner struct {
+	context              *context.AutoscalingContext
+	rs                   simulator.NodeRemovalSimulator
+	maxNodes             int
+	respectDaemonSetPdbs bool
+}
+
+// New creates a new Planner replacing up to maxNodes nodes at once.
+func New(context *context.AutoscalingContext, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, maxNodes int) *Planner {
+	// Successful simulations are persisted, so that pods moved from one
+	// candidate are taken into account when simulating the next one. All of it
+	// happens in a forked snapshot, which is reverted afterwards.
+	rs := simulator.NewRemovalSimulator(context.ListerRegistry, context.ClusterSnapshot, context.PredicateChecker, simulator.NewUsageTracker(), deleteOptions, drainabilityRules, true)
+	rs.SetPodsToMoveFunc(context.PodsToMove)
+	return &Planner{
+		context:              context,
+		rs:                   rs,
+		maxNodes:             maxNodes,
+		respectDaemonSetPdbs: deleteOptions.RespectDaemonSetPdbs,
+	}
+}
+
+// Plan returns the consolidation with the highest savings, or nil if there
+// is none. Candidates are tried in the given order, so the least utilized
+// nodes should go first. Pods can be moved only to podDestinations and to the
+// replacement node, built from the template in nodeInfosForGroups.
+func (p *Planner) Plan(candidates, podDestinations []*apiv1.Node, nodeInfosForGroups map[string]*schedulerframework.NodeInfo, timestamp time.Time) (*Consolidation, error) {
+	defer p.rs.DropOldHints()
+	if len(candidates) < 2 || p.maxNodes < 2 {
+		return nil, nil
+	}
+
+	var best *Consolidation
+	for _, nodeGroup := range p.context.CloudProvider.NodeGroups() {
+		template, found := nodeInfosForGroups[nodeGroup.Id()]
+		if !found {
+			continue
+		}
+		targetSize, err := nodeGroup.TargetSize()
+		if err != nil {
+			klog.Warningf("Failed to get target size of node group %s: %v", nodeGroup.Id(), err)
+			continue
+		}
+		if targetSize >= nodeGroup.MaxSize() {
+			continue
+		}
+		consolidation, err := p.simulate(candidates, podDestinations, nodeGroup, template, timestamp)
+		if err != nil {
+			return nil, err
+		}
+		if consolidation != nil && (best == nil || consolidation.Savings > best.Savings) {
+			best = consolidation
+		}
+	}
+	return best, nil
+}
+
+// simulate removes as many candidates as possible, up to maxNodes, after
+// adding a replacement node from nodeGroup to the cluster.
+func (p *Planner) simulate(candidates, podDestinations []*apiv1.Node, nodeGroup cloudprovider.NodeGroup, template *schedulerframework.NodeInfo, timestamp time.Time) (*Consolidation, error) {
+	p.context.ClusterSnapshot.Fork()
+	defer p.context.ClusterSnapshot.Revert()
+
+	replacement := scheduler.DeepCopyTemplateNode(template, "consolidation")
+	var replacementPods []*apiv1.Pod
+	for _, podInfo := range replacement.Pods {
+		replacementPods = append(replacementPods, podInfo.Pod)
+	}
+	if err := p.context.ClusterSnapshot.AddNodeWithPods(replacement.Node(), replacementPods); err != nil {
+		return nil, fmt.Errorf("failed to add replacement node from node group %s to snapshot: %v", nodeGroup.Id(), err)
+	}
+
+	destinations := make(map[string]bool, len(podDestinations)+1)
+	for _, node := range podDestinations {
+		destinations[node.Name] = true
+	}
+	destinations[replacement.Node().Name] = true
+
+	var remainingPdbTracker pdb.RemainingPdbTracker
+	if p.context.RemainingPdbTracker != nil {
+		remainingPdbTracker = p.context.RemainingPdbTracker.Clone()
+	}
+	// removableCount tracks how many more nodes can be removed from each
+	// node group without going below its min size.
+	removableCount := make(map[string]int)
+	var removed []*apiv1.Node
+	for _, node := range candidates {
+		if len(removed) >= p.maxNodes {
+			break
+		}
+		candidateGroup, err := p.context.CloudProvider.NodeGroupForNode(node)
+		if err != nil || candidateGroup == nil || reflect.ValueOf(candidateGroup).IsNil() {
+			continue
+		}
+		if candidateGroup.Id() == nodeGroup.Id() {
+			continue
+		}
+		count, found := removableCount[candidateGroup.Id()]
+		if !found {
+			size, err := candidateGroup.TargetSize()
+			if err != nil {
+				klog.Warningf("Failed to get target size of node group %s: %v", candidateGroup.Id(), err)
+				continue
+			}
+			count = size - candidateGroup.MinSize()
+		}
+		if count <= 0 {
+			continue
+		}
+		removable, _ := p.rs.SimulateNodeRemoval(node.Name, destinations, timestamp, remainingPdbTracker)
+		if removable == nil {
+			continue
+		}
+		delete(destinations, node.Name)
+		if remainingPdbTracker != nil {
+			remainingPdbTracker.RemovePods(removable.PodsToDisrupt(p.respectDaemonSetPdbs))
+		}
+		removableCount[candidateGroup.Id()] = count - 1
+		removed = append(removed, node)
+	}
+	if len(removed) < 2 {
+		return nil, nil
+	}
+
+	// If no pods landed on the replacement node, the nodes are removable
+	// without it and regular scale down takes care of them.
+	replacementInfo, err := p.context.ClusterSnapshot.NodeInfos().Get(replacement.Node().Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get replacement node from snapshot: %v", err)
+	}
+	if len(replacementInfo.Pods) == len(replacementPods) {
+		return nil, nil
+	}
+
+	savings, err := p.savings(removed, replacement.Node(), timestamp)
+	if err != nil {
+		klog.Warningf("Failed to compute savings of replacing %d nodes with a node from node group %s: %v", len(removed), nodeGroup.Id(), err)
+		return nil, nil
+	}
+	if savings <= 0 {
+		return nil, nil
+	}
+	return &Consolidation{
+		NodesToRemove: removed,
+		NodeGroup:     nodeGroup,
+		Savings:       savings,
+	}, nil
+}
+
+func (p *Planner) savings(removed []*apiv1.Node, replacement *apiv1.Node, timestamp time.Time) (float64, error) {
+	pricing, pricingErr := p.context.CloudProvider.Pricing()
+	if pricingErr != nil {
+		return float64(len(removed) - 1), nil
+	}
+	end := timestamp.Add(time.Hour)
+	replacementPrice, err := pricing.NodePrice(replacement, timestamp, end)
+	if err != nil {
+		return 0, err
+	}
+	savings := -replacementPrice
+	for _, node := range removed {
+		price, err := pricing.NodePrice(node, timestamp, end)
+		if err != nil {
+			return 0, err
+		}
+		savings += price
+	}
+	return savings, nil
+}
+
+// SortByUtilization sorts nodes by utilization, the least utilized first.
+// Nodes missing from utilizationMap are dropped.
+func SortByUtilization(nodes []*apiv1.Node, utilizationMap map[string]utilization.Info) []*apiv1.Node {
+	result := make([]*apiv1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if _, found := utilizationMap[node.Name]; found {
+			result = append(result, node)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return utilizationMap[result[i].Name].Utilization < utilizationMap[result[j].Name].Utilization
+	})
+	return result
+}
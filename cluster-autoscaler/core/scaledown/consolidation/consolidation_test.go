@@ -0,0 +1,203 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package consolidation
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/kubernetes/fake"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type testPricingModel struct {
+	nodePrice map[string]float64
+}
+
+func (tpm *testPricingModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	if price, found := tpm.nodePrice[node.Name]; found {
+		return price, nil
+	}
+	return 0.0, fmt.Errorf("price for node %v not found", node.Name)
+}
+
+func (tpm *testPricingModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	return 0.0, nil
+}
+
+func TestPlan(t *testing.T) {
+	const replacementName = "large-template-consolidation"
+
+	testCases := []struct {
+		name          string
+		maxNodes      int
+		largeMaxSize  int
+		smallMinSize  int
+		pricing       map[string]float64
+		wantRemoved   []string
+		wantNodeGroup string
+		wantSavings   float64
+	}{
+		{
+			name:          "three nodes replaced without pricing",
+			maxNodes:      5,
+			largeMaxSize:  5,
+			wantRemoved:   []string{"n1", "n2", "n3"},
+			wantNodeGroup: "large",
+			wantSavings:   2,
+		},
+		{
+			name:          "limited by max nodes",
+			maxNodes:      2,
+			largeMaxSize:  5,
+			wantRemoved:   []string{"n1", "n2"},
+			wantNodeGroup: "large",
+			wantSavings:   1,
+		},
+		{
+			name:          "limited by min size",
+			maxNodes:      5,
+			largeMaxSize:  5,
+			smallMinSize:  1,
+			wantRemoved:   []string{"n1", "n2"},
+			wantNodeGroup: "large",
+			wantSavings:   1,
+		},
+		{
+			name:          "cheaper replacement",
+			maxNodes:      5,
+			largeMaxSize:  5,
+			pricing:       map[string]float64{"n1": 1, "n2": 1, "n3": 1, replacementName: 2.5},
+			wantRemoved:   []string{"n1", "n2", "n3"},
+			wantNodeGroup: "large",
+			wantSavings:   0.5,
+		},
+		{
+			name:         "more expensive replacement",
+			maxNodes:     5,
+			largeMaxSize: 5,
+			pricing:      map[string]float64{"n1": 1, "n2": 1, "n3": 1, replacementName: 4},
+		},
+		{
+			name:         "replacement node group at max size",
+			maxNodes:     5,
+			largeMaxSize: 0,
+		},
+		{
+			name:         "consolidation disabled",
+			maxNodes:     1,
+			largeMaxSize: 5,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			nodes := []*apiv1.Node{
+				BuildTestNode("n1", 1000, 10),
+				BuildTestNode("n2", 1000, 10),
+				BuildTestNode("n3", 1000, 10),
+			}
+			// None of the pods fit any other small node, so regular scale
+			// down can't remove any of them.
+			pods := []*apiv1.Pod{
+				SetRSPodSpec(BuildScheduledTestPod("p1", 600, 1, "n1"), "rs"),
+				SetRSPodSpec(BuildScheduledTestPod("p2", 600, 1, "n2"), "rs"),
+				SetRSPodSpec(BuildScheduledTestPod("p3", 600, 1, "n3"), "rs"),
+			}
+			template := schedulerframework.NewNodeInfo()
+			template.SetNode(BuildTestNode("large-template", 4000, 10))
+
+			provider := testprovider.NewTestCloudProvider(nil, nil)
+			provider.AddNodeGroup("small", tc.smallMinSize, 10, 3)
+			provider.AddNodeGroup("large", 0, tc.largeMaxSize, 0)
+			for _, node := range nodes {
+				provider.AddNode("small", node)
+			}
+			if tc.pricing != nil {
+				provider.SetPricingModel(&testPricingModel{nodePrice: tc.pricing})
+			}
+
+			replicas := int32(3)
+			rsLister, err := kube_util.NewTestReplicaSetLister([]*appsv1.ReplicaSet{{
+				ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default", UID: types.UID("rs")},
+				Spec:       appsv1.ReplicaSetSpec{Replicas: &replicas},
+			}})
+			assert.NoError(t, err)
+			registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, rsLister, nil)
+			context, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{}, &fake.Clientset{}, registry, provider, nil, nil)
+			assert.NoError(t, err)
+			clustersnapshot.InitializeClusterSnapshotOrDie(t, context.ClusterSnapshot, nodes, pods)
+
+			p := New(&context, options.NodeDeleteOptions{}, nil, tc.maxNodes)
+			plan, err := p.Plan(nodes, nodes, map[string]*schedulerframework.NodeInfo{"large": template}, time.Now())
+			assert.NoError(t, err)
+			if tc.wantRemoved == nil {
+				assert.Nil(t, plan)
+			} else if assert.NotNil(t, plan) {
+				var removed []string
+				for _, node := range plan.NodesToRemove {
+					removed = append(removed, node.Name)
+				}
+				assert.Equal(t, tc.wantRemoved, removed)
+				assert.Equal(t, tc.wantNodeGroup, plan.NodeGroup.Id())
+				assert.InDelta(t, tc.wantSavings, plan.Savings, 0.001)
+			}
+
+			// The simulation must not leak into the snapshot.
+			nodeInfos, err := context.ClusterSnapshot.NodeInfos().List()
+			assert.NoError(t, err)
+			assert.Len(t, nodeInfos, len(nodes))
+			for _, nodeInfo := range nodeInfos {
+				assert.Len(t, nodeInfo.Pods, 1)
+			}
+		})
+	}
+}
+
+func TestSortByUtilization(t *testing.T) {
+	nodes := []*apiv1.Node{
+		BuildTestNode("n1", 1000, 10),
+		BuildTestNode("n2", 1000, 10),
+		BuildTestNode("n3", 1000, 10),
+		BuildTestNode("n4", 1000, 10),
+	}
+	utilizationMap := map[string]utilization.Info{
+		"n1": {Utilization: 0.4},
+		"n2": {Utilization: 0.1},
+		"n4": {Utilization: 0.3},
+	}
+	var names []string
+	for _, node := range SortByUtilization(nodes, utilizationMap) {
+		names = append(names, node.Name)
+	}
+	assert.Equal(t, []string{"n2", "n4", "n1"}, names)
+}
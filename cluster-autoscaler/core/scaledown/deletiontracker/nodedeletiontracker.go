@@ -34,6 +34,9 @@ type NodeDeletionTracker struct {
 	// A map which keeps track of deletions in progress for nodepools.
 	// Key is a node group id and value is a number of node deletions in progress.
 	deletionsPerNodeGroup map[string]int
+	// A map which keeps track of deletions with drain in progress for nodepools.
+	// Key is a node group id and value is a number of drained node deletions in progress.
+	drainsPerNodeGroup map[string]int
 	// This mapping contains node names of all empty nodes currently undergoing deletion.
 	emptyNodeDeletions map[string]bool
 	// This mapping contains node names of all nodes currently undergoing drain and deletion.
@@ -46,6 +49,8 @@ type NodeDeletionTracker struct {
 	evictionsTTL time.Duration
 	// Helper struct for tracking deletion results.
 	deletionResults *expiring.List
+	// This mapping contains the progress of drains of nodes currently undergoing drain and deletion.
+	drainStatuses map[string]status.NodeDrainStatus
 }
 
 type deletionResult struct {
@@ -57,12 +62,14 @@ type deletionResult struct {
 func NewNodeDeletionTracker(podEvictionsTTL time.Duration) *NodeDeletionTracker {
 	return &NodeDeletionTracker{
 		deletionsPerNodeGroup: make(map[string]int),
+		drainsPerNodeGroup:    make(map[string]int),
 		emptyNodeDeletions:    make(map[string]bool),
 		drainedNodeDeletions:  make(map[string]bool),
 		clock:                 clock.RealClock{},
 		evictions:             expiring.NewList(),
 		evictionsTTL:          podEvictionsTTL,
 		deletionResults:       expiring.NewList(),
+		drainStatuses:         make(map[string]status.NodeDrainStatus),
 	}
 }
 
@@ -79,6 +86,7 @@ func (n *NodeDeletionTracker) StartDeletionWithDrain(nodeGroupId, nodeName strin
 	n.Lock()
 	defer n.Unlock()
 	n.deletionsPerNodeGroup[nodeGroupId]++
+	n.drainsPerNodeGroup[nodeGroupId]++
 	n.drainedNodeDeletions[nodeName] = true
 }
 
@@ -100,8 +108,15 @@ func (n *NodeDeletionTracker) EndDeletion(nodeGroupId, nodeName string, result s
 	if n.deletionsPerNodeGroup[nodeGroupId] <= 0 {
 		delete(n.deletionsPerNodeGroup, nodeGroupId)
 	}
+	if n.drainedNodeDeletions[nodeName] {
+		n.drainsPerNodeGroup[nodeGroupId]--
+		if n.drainsPerNodeGroup[nodeGroupId] <= 0 {
+			delete(n.drainsPerNodeGroup, nodeGroupId)
+		}
+	}
 	delete(n.emptyNodeDeletions, nodeName)
 	delete(n.drainedNodeDeletions, nodeName)
+	delete(n.drainStatuses, nodeName)
 }
 
 // DeletionsInProgress returns a list of all node names currently undergoing deletion.
@@ -121,6 +136,27 @@ func mapKeysSlice(m map[string]bool) []string {
 	return s
 }
 
+// RegisterDrainStatus stores the progress of a drain of a node currently undergoing drain and deletion.
+func (n *NodeDeletionTracker) RegisterDrainStatus(nodeName string, drainStatus status.NodeDrainStatus) {
+	n.Lock()
+	defer n.Unlock()
+	if !n.drainedNodeDeletions[nodeName] {
+		return
+	}
+	n.drainStatuses[nodeName] = drainStatus
+}
+
+// DrainStatuses returns the progress of drains of nodes currently undergoing drain and deletion.
+func (n *NodeDeletionTracker) DrainStatuses() map[string]status.NodeDrainStatus {
+	n.Lock()
+	defer n.Unlock()
+	drainStatuses := make(map[string]status.NodeDrainStatus, len(n.drainStatuses))
+	for k, val := range n.drainStatuses {
+		drainStatuses[k] = val
+	}
+	return drainStatuses
+}
+
 // RegisterEviction stores information about a pod that was recently evicted.
 func (n *NodeDeletionTracker) RegisterEviction(pod *apiv1.Pod) {
 	n.Lock()
@@ -148,6 +184,13 @@ func (n *NodeDeletionTracker) DeletionsCount(nodeGroupId string) int {
 	return n.deletionsPerNodeGroup[nodeGroupId]
 }
 
+// DrainsCount returns the number of deletions with drain in progress for the given node group.
+func (n *NodeDeletionTracker) DrainsCount(nodeGroupId string) int {
+	n.Lock()
+	defer n.Unlock()
+	return n.drainsPerNodeGroup[nodeGroupId]
+}
+
 // DeletionResults returns deletion results in a map form, along with the timestamp of last result.
 func (n *NodeDeletionTracker) DeletionResults() (map[string]status.NodeDeleteResult, time.Time) {
 	n.Lock()
@@ -183,11 +226,17 @@ func (n *NodeDeletionTracker) Snapshot() *NodeDeletionTracker {
 	for k, val := range n.deletionsPerNodeGroup {
 		snapshot.deletionsPerNodeGroup[k] = val
 	}
+	for k, val := range n.drainsPerNodeGroup {
+		snapshot.drainsPerNodeGroup[k] = val
+	}
 	for _, eviction := range n.evictions.ToSlice() {
 		snapshot.evictions.RegisterElement(eviction)
 	}
 	for _, result := range n.deletionResults.ToSlice() {
 		snapshot.deletionResults.RegisterElement(result)
 	}
+	for k, val := range n.drainStatuses {
+		snapshot.drainStatuses[k] = val
+	}
 	return snapshot
 }
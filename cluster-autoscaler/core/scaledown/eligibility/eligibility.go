@@ -24,6 +24,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/actuation"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unremovable"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/nodeselector"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/klogx"
@@ -41,7 +42,8 @@ const (
 
 // Checker is responsible for deciding which nodes pass the criteria for scale down.
 type Checker struct {
-	configGetter nodeGroupConfigGetter
+	configGetter  nodeGroupConfigGetter
+	disabledNodes nodeselector.Selector
 }
 
 type nodeGroupConfigGetter interface {
@@ -51,6 +53,8 @@ type nodeGroupConfigGetter interface {
 	GetScaleDownGpuUtilizationThreshold(nodeGroup cloudprovider.NodeGroup) (float64, error)
 	// GetIgnoreDaemonSetsUtilization returns IgnoreDaemonSetsUtilization value that should be used for a given NodeGroup.
 	GetIgnoreDaemonSetsUtilization(nodeGroup cloudprovider.NodeGroup) (bool, error)
+	// GetScaleDownUtilizationMode returns ScaleDownUtilizationMode value that should be used for a given NodeGroup.
+	GetScaleDownUtilizationMode(nodeGroup cloudprovider.NodeGroup) (string, error)
 }
 
 // NewChecker creates a new Checker object.
@@ -60,6 +64,13 @@ func NewChecker(configGetter nodeGroupConfigGetter) *Checker {
 	}
 }
 
+// SetScaleDownDisabledNodes sets the selector of nodes which aren't eligible
+// for scale down, in addition to nodes with the scale-down-disabled
+// annotation.
+func (c *Checker) SetScaleDownDisabledNodes(disabledNodes nodeselector.Selector) {
+	c.disabledNodes = disabledNodes
+}
+
 // FilterOutUnremovable accepts a list of nodes that are candidates for
 // scale down and filters out nodes that cannot be removed, along with node
 // utilization info.
@@ -119,6 +130,10 @@ func (c *Checker) unremovableReasonAndNodeUtilization(context *context.Autoscali
 		klog.V(1).Infof("Skipping %s from delete consideration - the node is marked as no scale down", node.Name)
 		return simulator.ScaleDownDisabledAnnotation, nil
 	}
+	if c.disabledNodes.Matches(node) {
+		klog.V(1).Infof("Skipping %s from delete consideration - the node has a taint or labels disabling scale down", node.Name)
+		return simulator.ScaleDownDisabledBySelector, nil
+	}
 
 	nodeGroup, err := context.CloudProvider.NodeGroupForNode(node)
 	if err != nil {
@@ -138,8 +153,19 @@ func (c *Checker) unremovableReasonAndNodeUtilization(context *context.Autoscali
 		return simulator.UnexpectedError, nil
 	}
 
+	utilizationMode, err := c.configGetter.GetScaleDownUtilizationMode(nodeGroup)
+	if err != nil {
+		klog.Warningf("Couldn't retrieve `ScaleDownUtilizationMode` option for node %v: %v", node.Name, err)
+		return simulator.UnexpectedError, nil
+	}
+	mode, err := utilization.ParseMode(utilizationMode)
+	if err != nil {
+		klog.Warningf("Invalid `ScaleDownUtilizationMode` option for node %v, calculating utilization from requests: %v", node.Name, err)
+		mode = utilization.RequestsMode
+	}
+
 	gpuConfig := context.CloudProvider.GetNodeGpuConfig(node)
-	utilInfo, err := utilization.Calculate(nodeInfo, ignoreDaemonSetsUtilization, context.IgnoreMirrorPodsUtilization, gpuConfig, timestamp)
+	utilInfo, err := utilization.NewCalculator(mode, context.NodeUsage).Calculate(nodeInfo, ignoreDaemonSetsUtilization, context.IgnoreMirrorPodsUtilization, gpuConfig, timestamp, context.LongTerminatingPodThreshold)
 	if err != nil {
 		klog.Warningf("Failed to calculate utilization for %s: %v", node.Name, err)
 	}
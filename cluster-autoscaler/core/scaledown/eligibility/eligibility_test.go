@@ -26,12 +26,16 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unremovable"
 	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/nodeselector"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 
 	"github.com/stretchr/testify/assert"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -183,3 +187,147 @@ func TestFilterOutUnremovable(t *testing.T) {
 		})
 	}
 }
+
+type fakeUsage map[string]apiv1.ResourceList
+
+func (u fakeUsage) NodeUsage(nodeName string) (apiv1.ResourceList, bool) {
+	usage, found := u[nodeName]
+	return usage, found
+}
+
+func TestFilterOutUnremovableUtilizationMode(t *testing.T) {
+	now := time.Now()
+	node := BuildTestNode("regular", 1000, 10)
+	SetNodeReadyState(node, true, time.Time{})
+	smallPod := BuildTestPod("smallPod", 100, 0)
+	smallPod.Spec.NodeName = "regular"
+	bigPod := BuildTestPod("bigPod", 600, 0)
+	bigPod.Spec.NodeName = "regular"
+	lowUsage := fakeUsage{"regular": {apiv1.ResourceCPU: resource.MustParse("100m")}}
+	highUsage := fakeUsage{"regular": {apiv1.ResourceCPU: resource.MustParse("800m")}}
+
+	for _, tc := range []struct {
+		desc  string
+		mode  string
+		pod   *apiv1.Pod
+		usage utilization.UsageProvider
+		want  []string
+	}{
+		{
+			desc:  "requests mode ignores usage",
+			mode:  "requests",
+			pod:   smallPod,
+			usage: highUsage,
+			want:  []string{"regular"},
+		},
+		{
+			desc:  "usage mode filters out heavily used node",
+			mode:  "usage",
+			pod:   smallPod,
+			usage: highUsage,
+			want:  []string{},
+		},
+		{
+			desc:  "usage mode keeps lightly used node",
+			mode:  "usage",
+			pod:   bigPod,
+			usage: lowUsage,
+			want:  []string{"regular"},
+		},
+		{
+			desc:  "usage mode falls back to requests without usage",
+			mode:  "usage",
+			pod:   bigPod,
+			usage: fakeUsage{},
+			want:  []string{},
+		},
+		{
+			desc:  "hybrid mode filters out heavily used node",
+			mode:  "hybrid",
+			pod:   smallPod,
+			usage: highUsage,
+			want:  []string{},
+		},
+		{
+			desc:  "hybrid mode filters out heavily requested node",
+			mode:  "hybrid",
+			pod:   bigPod,
+			usage: lowUsage,
+			want:  []string{},
+		},
+		{
+			desc:  "hybrid mode keeps lightly used and requested node",
+			mode:  "hybrid",
+			pod:   smallPod,
+			usage: lowUsage,
+			want:  []string{"regular"},
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			options := config.AutoscalingOptions{
+				UnremovableNodeRecheckTimeout: 5 * time.Minute,
+				ScaleDownUnreadyEnabled:       true,
+				NodeGroupDefaults: config.NodeGroupAutoscalingOptions{
+					ScaleDownUtilizationThreshold:    config.DefaultScaleDownUtilizationThreshold,
+					ScaleDownGpuUtilizationThreshold: config.DefaultScaleDownGpuUtilizationThreshold,
+					ScaleDownUtilizationMode:         tc.mode,
+				},
+			}
+			c := NewChecker(nodegroupconfig.NewDefaultNodeGroupConfigProcessor(options.NodeGroupDefaults))
+			provider := testprovider.NewTestCloudProvider(nil, nil)
+			provider.AddNodeGroup("ng1", 1, 10, 2)
+			provider.AddNode("ng1", node)
+			context, err := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, nil, provider, nil, nil)
+			if err != nil {
+				t.Fatalf("Could not create autoscaling context: %v", err)
+			}
+			context.NodeUsage = tc.usage
+			clustersnapshot.InitializeClusterSnapshotOrDie(t, context.ClusterSnapshot, []*apiv1.Node{node}, []*apiv1.Pod{tc.pod})
+			got, _, _ := c.FilterOutUnremovable(&context, []*apiv1.Node{node}, now, unremovable.NewNodes())
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestFilterOutUnremovableDisabledNodes(t *testing.T) {
+	now := time.Now()
+	regularNode := BuildTestNode("regular", 1000, 10)
+	SetNodeReadyState(regularNode, true, time.Time{})
+	taintedNode := BuildTestNode("tainted", 1000, 10)
+	taintedNode.Spec.Taints = []apiv1.Taint{{Key: "never-drain", Effect: apiv1.TaintEffectNoSchedule}}
+	SetNodeReadyState(taintedNode, true, time.Time{})
+	labeledNode := BuildTestNode("labeled", 1000, 10)
+	labeledNode.Labels = map[string]string{"never-drain": "true"}
+	SetNodeReadyState(labeledNode, true, time.Time{})
+	nodes := []*apiv1.Node{regularNode, taintedNode, labeledNode}
+
+	options := config.AutoscalingOptions{
+		UnremovableNodeRecheckTimeout: 5 * time.Minute,
+		ScaleDownUnreadyEnabled:       true,
+		NodeGroupDefaults: config.NodeGroupAutoscalingOptions{
+			ScaleDownUtilizationThreshold:    config.DefaultScaleDownUtilizationThreshold,
+			ScaleDownGpuUtilizationThreshold: config.DefaultScaleDownGpuUtilizationThreshold,
+		},
+	}
+	c := NewChecker(nodegroupconfig.NewDefaultNodeGroupConfigProcessor(options.NodeGroupDefaults))
+	disabledNodes, err := nodeselector.Parse([]string{"never-drain"}, []string{"never-drain=true"})
+	assert.NoError(t, err)
+	c.SetScaleDownDisabledNodes(disabledNodes)
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 1, 10, 3)
+	for _, n := range nodes {
+		provider.AddNode("ng1", n)
+	}
+	context, err := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, nil, provider, nil, nil)
+	if err != nil {
+		t.Fatalf("Could not create autoscaling context: %v", err)
+	}
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, context.ClusterSnapshot, nodes, nil)
+	got, _, ineligible := c.FilterOutUnremovable(&context, nodes, now, unremovable.NewNodes())
+	assert.Equal(t, []string{"regular"}, got)
+	assert.Len(t, ineligible, 2)
+	for _, unremovableNode := range ineligible {
+		assert.Equal(t, simulator.ScaleDownDisabledBySelector, unremovableNode.Reason)
+	}
+}
@@ -31,9 +31,10 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unremovable"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/nodeselector"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 
@@ -51,7 +52,7 @@ type ScaleDown struct {
 	nodeUtilizationMap   map[string]utilization.Info
 	usageTracker         *simulator.UsageTracker
 	nodeDeletionTracker  *deletiontracker.NodeDeletionTracker
-	removalSimulator     *simulator.RemovalSimulator
+	removalSimulator     simulator.NodeRemovalSimulator
 	eligibilityChecker   *eligibility.Checker
 	resourceLimitsFinder *resource.LimitsFinder
 }
@@ -60,8 +61,15 @@ type ScaleDown struct {
 func NewScaleDown(context *context.AutoscalingContext, processors *processors.AutoscalingProcessors, ndt *deletiontracker.NodeDeletionTracker, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules) *ScaleDown {
 	usageTracker := simulator.NewUsageTracker()
 	removalSimulator := simulator.NewRemovalSimulator(context.ListerRegistry, context.ClusterSnapshot, context.PredicateChecker, usageTracker, deleteOptions, drainabilityRules, false)
+	removalSimulator.SetPodsToMoveFunc(context.PodsToMove)
 	unremovableNodes := unremovable.NewNodes()
 	resourceLimitsFinder := resource.NewLimitsFinder(processors.CustomResourcesProcessor)
+	eligibilityChecker := eligibility.NewChecker(processors.NodeGroupConfigProcessor)
+	disabledNodes, err := nodeselector.Parse(context.AutoscalingOptions.ScaleDownDisabledTaints, context.AutoscalingOptions.ScaleDownDisabledNodeSelectors)
+	if err != nil {
+		klog.Errorf("Invalid taints or node selectors disabling scale down, ignoring them: %v", err)
+	}
+	eligibilityChecker.SetScaleDownDisabledNodes(disabledNodes)
 	return &ScaleDown{
 		context:              context,
 		processors:           processors,
@@ -71,7 +79,7 @@ func NewScaleDown(context *context.AutoscalingContext, processors *processors.Au
 		usageTracker:         usageTracker,
 		nodeDeletionTracker:  ndt,
 		removalSimulator:     removalSimulator,
-		eligibilityChecker:   eligibility.NewChecker(processors.NodeGroupConfigProcessor),
+		eligibilityChecker:   eligibilityChecker,
 		resourceLimitsFinder: resourceLimitsFinder,
 	}
 }
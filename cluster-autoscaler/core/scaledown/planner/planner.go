@@ -19,37 +19,58 @@ package planner
 import (
 	"fmt"
 	"math"
+	"sort"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/budgets"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/eligibility"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/resource"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unneeded"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unremovable"
 	"k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodes"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/nodeselector"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/scoring"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/evictionbackoff"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/scheduling"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
-	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
 	klog "k8s.io/klog/v2"
 )
 
-type eligibilityChecker interface {
-	FilterOutUnremovable(context *context.AutoscalingContext, scaleDownCandidates []*apiv1.Node, timestamp time.Time, unremovableNodes *unremovable.Nodes) ([]string, map[string]utilization.Info, []*simulator.UnremovableNode)
+// CandidateOrder defines the order in which removable nodes are scaled down.
+type CandidateOrder string
+
+const (
+	// DefaultCandidateOrder leaves the order to the scale down scorers.
+	DefaultCandidateOrder CandidateOrder = "Default"
+	// MostExpensiveFirstCandidateOrder scales down nodes with the highest
+	// price according to the cloud provider pricing model first, by adding
+	// the Price scorer weighted as much as all other scorers together.
+	MostExpensiveFirstCandidateOrder CandidateOrder = "MostExpensiveFirst"
+)
+
+// ParseCandidateOrder parses a CandidateOrder from its name.
+func ParseCandidateOrder(name string) (CandidateOrder, error) {
+	switch order := CandidateOrder(name); order {
+	case DefaultCandidateOrder, MostExpensiveFirstCandidateOrder:
+		return order, nil
+	}
+	return "", fmt.Errorf("unknown scale down candidate order %q, expected one of: %s, %s", name, DefaultCandidateOrder, MostExpensiveFirstCandidateOrder)
 }
 
-type removalSimulator interface {
-	DropOldHints()
-	SimulateNodeRemoval(node string, podDestinations map[string]bool, timestamp time.Time, remainingPdbTracker pdb.RemainingPdbTracker) (*simulator.NodeToBeRemoved, *simulator.UnremovableNode)
+type eligibilityChecker interface {
+	FilterOutUnremovable(context *context.AutoscalingContext, scaleDownCandidates []*apiv1.Node, timestamp time.Time, unremovableNodes *unremovable.Nodes) ([]string, map[string]utilization.Info, []*simulator.UnremovableNode)
 }
 
 // controllerReplicasCalculator calculates a number of target and expected replicas for a given controller.
@@ -66,7 +87,7 @@ type Planner struct {
 	context               *context.AutoscalingContext
 	unremovableNodes      *unremovable.Nodes
 	unneededNodes         *unneeded.Nodes
-	rs                    removalSimulator
+	rs                    simulator.NodeRemovalSimulator
 	actuationInjector     *scheduling.HintingSimulator
 	latestUpdate          time.Time
 	minUpdateInterval     time.Duration
@@ -76,6 +97,10 @@ type Planner struct {
 	resourceLimitsFinder  *resource.LimitsFinder
 	cc                    controllerReplicasCalculator
 	scaleDownSetProcessor nodes.ScaleDownSetProcessor
+	budgetProcessor       *budgets.ScaleDownBudgetProcessor
+	deleteOptions         options.NodeDeleteOptions
+	scorer                *scoring.Framework
+	preferredNodes        nodeselector.Selector
 }
 
 // New creates a new Planner object.
@@ -85,18 +110,41 @@ func New(context *context.AutoscalingContext, processors *processors.Autoscaling
 	if minUpdateInterval == 0*time.Nanosecond {
 		minUpdateInterval = 1 * time.Nanosecond
 	}
+	candidateOrder, err := ParseCandidateOrder(context.AutoscalingOptions.ScaleDownCandidateOrder)
+	if err != nil {
+		candidateOrder = DefaultCandidateOrder
+	}
+	eligibilityChecker := eligibility.NewChecker(processors.NodeGroupConfigProcessor)
+	disabledNodes, err := nodeselector.Parse(context.AutoscalingOptions.ScaleDownDisabledTaints, context.AutoscalingOptions.ScaleDownDisabledNodeSelectors)
+	if err != nil {
+		klog.Errorf("Invalid taints or node selectors disabling scale down, ignoring them: %v", err)
+	}
+	eligibilityChecker.SetScaleDownDisabledNodes(disabledNodes)
+	preferredNodes, err := nodeselector.Parse(context.AutoscalingOptions.ScaleDownPreferredTaints, context.AutoscalingOptions.ScaleDownPreferredNodeSelectors)
+	if err != nil {
+		klog.Errorf("Invalid taints or node selectors of nodes preferred for scale down, ignoring them: %v", err)
+	}
+	rs := simulator.NewRemovalSimulator(context.ListerRegistry, context.ClusterSnapshot, context.PredicateChecker, simulator.NewUsageTracker(), deleteOptions, drainabilityRules, true)
+	rs.SetMaxGracefulTerminationSecGetter(func(node *apiv1.Node) int {
+		return nodegroupconfig.GetMaxGracefulTerminationSecForNode(context.CloudProvider, processors.NodeGroupConfigProcessor, node, deleteOptions.MaxGracefulTerminationSec)
+	})
+	rs.SetPodsToMoveFunc(context.PodsToMove)
 	return &Planner{
 		context:               context,
 		unremovableNodes:      unremovable.NewNodes(),
 		unneededNodes:         unneeded.NewNodes(processors.NodeGroupConfigProcessor, resourceLimitsFinder),
-		rs:                    simulator.NewRemovalSimulator(context.ListerRegistry, context.ClusterSnapshot, context.PredicateChecker, simulator.NewUsageTracker(), deleteOptions, drainabilityRules, true),
+		rs:                    rs,
 		actuationInjector:     scheduling.NewHintingSimulator(context.PredicateChecker),
-		eligibilityChecker:    eligibility.NewChecker(processors.NodeGroupConfigProcessor),
+		eligibilityChecker:    eligibilityChecker,
 		nodeUtilizationMap:    make(map[string]utilization.Info),
 		resourceLimitsFinder:  resourceLimitsFinder,
 		cc:                    newControllerReplicasCalculator(context.ListerRegistry),
 		scaleDownSetProcessor: processors.ScaleDownSetProcessor,
+		budgetProcessor:       budgets.NewScaleDownBudgetProcessor(context),
 		minUpdateInterval:     minUpdateInterval,
+		deleteOptions:         deleteOptions,
+		scorer:                candidateScorer(processors.ScaleDownCandidatesScorer, candidateOrder),
+		preferredNodes:        preferredNodes,
 	}
 }
 
@@ -151,7 +199,19 @@ func (p *Planner) NodesToDelete(_ time.Time) (empty, needDrain []*apiv1.Node) {
 	for _, u := range unremovable {
 		p.unremovableNodes.Add(u)
 	}
+	// Operator preference, eviction failures and risk take precedence over
+	// scores, the latter two make scale down likely to fail.
+	p.scorer.Sort(p.context, emptyRemovable, p.nodeUtilizationMap, p.latestUpdate)
+	p.scorer.Sort(p.context, needDrainRemovable, p.nodeUtilizationMap, p.latestUpdate)
+	sortByPreference(emptyRemovable, p.preferredNodes)
+	sortByPreference(needDrainRemovable, p.preferredNodes)
+	if p.context.EvictionBackoff != nil {
+		sortByEvictionFailures(needDrainRemovable, p.context.EvictionBackoff, p.latestUpdate)
+	}
 	needDrainRemovable = sortByRisk(needDrainRemovable)
+	// Don't pick nodes which couldn't be drained right away because of the disruption budgets, so that
+	// the nodes which can be drained are picked instead.
+	needDrainRemovable = p.budgetProcessor.CropDrainCandidates(p.actuationStatus, needDrainRemovable)
 	nodesToRemove := p.scaleDownSetProcessor.GetNodesToRemove(
 		p.context,
 		// We need to pass empty nodes first, as there might be some non-empty scale
@@ -268,6 +328,7 @@ func (p *Planner) categorizeNodes(podDestinations map[string]bool, scaleDownCand
 	}
 	p.nodeUtilizationMap = utilizationMap
 	timer := time.NewTimer(p.context.ScaleDownSimulationTimeout)
+	p.rs.PrecomputeDrainability(currentlyUnneededNodeNames, p.latestUpdate, p.context.RemainingPdbTracker, p.context.DrainabilityEvaluationParallelism)
 
 	for i, node := range currentlyUnneededNodeNames {
 		if timedOut(timer) {
@@ -280,25 +341,42 @@ func (p *Planner) categorizeNodes(podDestinations map[string]bool, scaleDownCand
 		}
 		removable, unremovable := p.rs.SimulateNodeRemoval(node, podDestinations, p.latestUpdate, p.context.RemainingPdbTracker)
 		if removable != nil {
-			_, inParallel, _ := p.context.RemainingPdbTracker.CanRemovePods(removable.PodsToReschedule)
+			podsToDisrupt := removable.PodsToDisrupt(p.deleteOptions.RespectDaemonSetPdbs)
+			_, inParallel, _ := p.context.RemainingPdbTracker.CanRemovePods(podsToDisrupt)
 			if !inParallel {
 				removable.IsRisky = true
 			}
 			delete(podDestinations, removable.Node.Name)
-			p.context.RemainingPdbTracker.RemovePods(removable.PodsToReschedule)
+			p.context.RemainingPdbTracker.RemovePods(podsToDisrupt)
 			removableList = append(removableList, *removable)
 		}
 		if unremovable != nil {
 			unremovableCount += 1
-			p.unremovableNodes.AddTimeout(unremovable, unremovableTimeout)
+			recheckTimeout := p.unremovableNodes.RecheckTimeout(node, p.context.AutoscalingOptions.UnremovableNodeRecheckTimeout, p.context.AutoscalingOptions.UnremovableNodeRecheckMaxTimeout)
+			p.addUnremovable(unremovable, p.latestUpdate.Add(recheckTimeout))
 		}
 	}
 	p.unneededNodes.Update(removableList, p.latestUpdate)
 	if unremovableCount > 0 {
-		klog.V(1).Infof("%v nodes found to be unremovable in simulation, will re-check them at %v", unremovableCount, unremovableTimeout)
+		klog.V(1).Infof("%v nodes found to be unremovable in simulation, will re-check them at %v at the earliest", unremovableCount, unremovableTimeout)
 	}
 }
 
+// addUnremovable marks a node found unremovable in simulation as recently
+// unremovable, so that it isn't simulated again until the timeout passes.
+func (p *Planner) addUnremovable(unremovable *simulator.UnremovableNode, unremovableTimeout time.Time) {
+	if p.context.AutoscalingOptions.UnremovableNodeStateCacheEnabled {
+		nodeInfo, err := p.context.ClusterSnapshot.NodeInfos().Get(unremovable.Node.Name)
+		if err == nil {
+			maxTimeout := p.latestUpdate.Add(p.context.AutoscalingOptions.UnremovableNodeRecheckMaxTimeout)
+			p.unremovableNodes.AddTimeoutWithState(unremovable, unremovableTimeout, maxTimeout, nodeInfo)
+			return
+		}
+		klog.Warningf("Can't retrieve unremovable node %s from snapshot, err: %v", unremovable.Node.Name, err)
+	}
+	p.unremovableNodes.AddTimeout(unremovable, unremovableTimeout)
+}
+
 // unneededNodesLimit returns the number of nodes after which calculating more
 // unneeded nodes is a waste of time. The reasoning behind it is essentially as
 // follows.
@@ -396,6 +474,46 @@ func sortByRisk(nodes []simulator.NodeToBeRemoved) []simulator.NodeToBeRemoved {
 	return append(okNodes, riskyNodes...)
 }
 
+// sortByEvictionFailures sorts nodes so that the ones with fewer pods whose
+// evictions recently failed come first.
+func sortByEvictionFailures(nodes []simulator.NodeToBeRemoved, ledger *evictionbackoff.Ledger, timestamp time.Time) {
+	failures := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		for _, pod := range node.PodsToReschedule {
+			if _, found := ledger.Failure(pod, timestamp); found {
+				failures[node.Node.Name]++
+			}
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return failures[nodes[i].Node.Name] < failures[nodes[j].Node.Name]
+	})
+}
+
+// sortByPreference sorts nodes so that the ones selected by the operator, e.g.
+// with a "drain-me-first" taint, come first.
+func sortByPreference(nodes []simulator.NodeToBeRemoved, preferredNodes nodeselector.Selector) {
+	if preferredNodes.Empty() {
+		return
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return preferredNodes.Matches(nodes[i].Node) && !preferredNodes.Matches(nodes[j].Node)
+	})
+}
+
+// candidateScorer returns the scorer ordering removable nodes for the given
+// candidate order.
+func candidateScorer(scorer *scoring.Framework, order CandidateOrder) *scoring.Framework {
+	if order != MostExpensiveFirstCandidateOrder || scorer.Has(scoring.PriceScorerName) {
+		return scorer
+	}
+	weight := scorer.TotalWeight()
+	if weight == 0 {
+		weight = 1
+	}
+	return scorer.With(scoring.WeightedScorer{Scorer: scoring.NewPriceScorer(), Weight: weight})
+}
+
 func timedOut(timer *time.Timer) bool {
 	select {
 	case <-timer.C:
@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
@@ -31,13 +32,16 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/unremovable"
 	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/nodeselector"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/scoring"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/evictionbackoff"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
@@ -623,6 +627,65 @@ func TestUpdateClusterStatUnneededNodesLimit(t *testing.T) {
 	}
 }
 
+func TestUpdateClusterStateDaemonSetPdbs(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		respectPdbs     bool
+		wantUnneeded    []string
+		wantUnremovable []string
+	}{
+		{
+			name:            "daemon set pods use up their budget",
+			respectPdbs:     true,
+			wantUnneeded:    []string{"n1"},
+			wantUnremovable: []string{"n2", "n3"},
+		},
+		{
+			name:         "budgets of daemon set pods not respected",
+			wantUnneeded: []string{"n1", "n2", "n3"},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var nodes []*apiv1.Node
+			var pods []*apiv1.Pod
+			for _, name := range []string{"n1", "n2", "n3"} {
+				nodes = append(nodes, BuildTestNode(name, 1000, 10))
+				pod := BuildScheduledTestPod("agent-"+name, 100, 1, name)
+				pod.Labels = map[string]string{"app": "agent"}
+				pod.OwnerReferences = GenerateOwnerReferences("agent", "DaemonSet", "apps/v1", "")
+				pods = append(pods, pod)
+			}
+			provider := testprovider.NewTestCloudProvider(nil, nil)
+			context, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{
+				NodeGroupDefaults: config.NodeGroupAutoscalingOptions{
+					ScaleDownUnneededTime: 10 * time.Minute,
+				},
+				ScaleDownSimulationTimeout: 1 * time.Second,
+				MaxScaleDownParallelism:    10,
+			}, &fake.Clientset{}, nil, provider, nil, nil)
+			assert.NoError(t, err)
+			assert.NoError(t, context.RemainingPdbTracker.SetPdbs([]*policyv1.PodDisruptionBudget{{
+				ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "agent"}}},
+				Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+			}}))
+			clustersnapshot.InitializeClusterSnapshotOrDie(t, context.ClusterSnapshot, nodes, pods)
+			deleteOptions := options.NodeDeleteOptions{RespectDaemonSetPdbs: tc.respectPdbs}
+			p := New(&context, NewTestProcessors(&context), deleteOptions, nil)
+			p.eligibilityChecker = &fakeEligibilityChecker{eligible: asMap(nodeNames(nodes))}
+			assert.NoError(t, p.UpdateClusterState(nodes, nodes, &fakeActuationStatus{}, time.Now()))
+			wantUnneeded := asMap(tc.wantUnneeded)
+			wantUnremovable := asMap(tc.wantUnremovable)
+			for _, n := range nodes {
+				assert.Equal(t, wantUnneeded[n.Name], p.unneededNodes.Contains(n.Name), []string{n.Name, "unneeded"})
+				assert.Equal(t, wantUnremovable[n.Name], p.unremovableNodes.Contains(n.Name), []string{n.Name, "unremovable"})
+			}
+		})
+	}
+}
+
 func TestNodesToDelete(t *testing.T) {
 	testCases := []struct {
 		name      string
@@ -777,6 +840,7 @@ func TestNodesToDelete(t *testing.T) {
 					ScaleDownUnneededTime: 10 * time.Minute,
 					ScaleDownUnreadyTime:  0 * time.Minute,
 				},
+				MaxDrainParallelism: 10,
 			}, &fake.Clientset{}, nil, provider, nil, nil)
 			assert.NoError(t, err)
 			clustersnapshot.InitializeClusterSnapshotOrDie(t, context.ClusterSnapshot, allNodes, nil)
@@ -793,6 +857,130 @@ func TestNodesToDelete(t *testing.T) {
 	}
 }
 
+func TestCandidateScorerMostExpensiveFirst(t *testing.T) {
+	testCases := []struct {
+		name      string
+		prices    map[string]float64
+		wantOrder []string
+	}{
+		{
+			name:      "pricing not available",
+			wantOrder: []string{"spot", "on-demand", "large", "unknown"},
+		},
+		{
+			name:      "most expensive first",
+			prices:    map[string]float64{"spot": 0.1, "on-demand": 0.3, "large": 1.2},
+			wantOrder: []string{"large", "on-demand", "spot", "unknown"},
+		},
+		{
+			name:      "equal prices keep order",
+			prices:    map[string]float64{"spot": 0.3, "on-demand": 0.3, "large": 0.3, "unknown": 0.3},
+			wantOrder: []string{"spot", "on-demand", "large", "unknown"},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			provider := testprovider.NewTestCloudProvider(nil, nil)
+			if tc.prices != nil {
+				provider.SetPricingModel(&fakePricingModel{prices: tc.prices})
+			}
+			var nodes []simulator.NodeToBeRemoved
+			for _, name := range []string{"spot", "on-demand", "large", "unknown"} {
+				nodes = append(nodes, buildRemovableNode(name, 1))
+			}
+			scorer := candidateScorer(scoring.NewFramework(nil), MostExpensiveFirstCandidateOrder)
+			scorer.Sort(&context.AutoscalingContext{CloudProvider: provider}, nodes, nil, time.Now())
+			var gotOrder []string
+			for _, node := range nodes {
+				gotOrder = append(gotOrder, node.Node.Name)
+			}
+			assert.Equal(t, tc.wantOrder, gotOrder)
+		})
+	}
+}
+
+func TestSortByPreference(t *testing.T) {
+	preferredNodes, err := nodeselector.Parse([]string{"*:PreferNoSchedule"}, []string{"drain-me-first"})
+	assert.NoError(t, err)
+	tainted := buildRemovableNode("tainted", 1)
+	tainted.Node.Spec.Taints = []apiv1.Taint{{Key: "soon-gone", Effect: apiv1.TaintEffectPreferNoSchedule}}
+	labeled := buildRemovableNode("labeled", 1)
+	labeled.Node.Labels = map[string]string{"drain-me-first": ""}
+	regular := buildRemovableNode("regular", 1)
+	alsoRegular := buildRemovableNode("also-regular", 1)
+	nodes := []simulator.NodeToBeRemoved{regular, tainted, alsoRegular, labeled}
+	sortByPreference(nodes, preferredNodes)
+	var gotOrder []string
+	for _, node := range nodes {
+		gotOrder = append(gotOrder, node.Node.Name)
+	}
+	assert.Equal(t, []string{"tainted", "labeled", "regular", "also-regular"}, gotOrder)
+}
+
+func TestSortByEvictionFailures(t *testing.T) {
+	now := time.Now()
+	ledger := evictionbackoff.NewLedger(time.Minute, time.Hour)
+	failing := buildRemovableNode("failing", 2)
+	failing.PodsToReschedule[0].UID = "failing-0"
+	failing.PodsToReschedule[1].UID = "failing-1"
+	ledger.RegisterFailure(failing.PodsToReschedule[0], nil, now.Add(-2*time.Minute))
+	ledger.RegisterFailure(failing.PodsToReschedule[1], nil, now.Add(-2*time.Minute))
+	slightlyFailing := buildRemovableNode("slightly-failing", 2)
+	slightlyFailing.PodsToReschedule[0].UID = "slightly-failing-0"
+	ledger.RegisterFailure(slightlyFailing.PodsToReschedule[0], nil, now.Add(-2*time.Minute))
+	ok := buildRemovableNode("ok", 1)
+	ok.PodsToReschedule[0].UID = "ok-0"
+	alsoOk := buildRemovableNode("also-ok", 1)
+	alsoOk.PodsToReschedule[0].UID = "also-ok-0"
+	nodes := []simulator.NodeToBeRemoved{failing, ok, slightlyFailing, alsoOk}
+	sortByEvictionFailures(nodes, ledger, now)
+	var gotOrder []string
+	for _, node := range nodes {
+		gotOrder = append(gotOrder, node.Node.Name)
+	}
+	assert.Equal(t, []string{"ok", "also-ok", "slightly-failing", "failing"}, gotOrder)
+}
+
+func TestCandidateScorer(t *testing.T) {
+	defaultScorer := scoring.NewFramework(scoring.DefaultScorers())
+	assert.Same(t, defaultScorer, candidateScorer(defaultScorer, DefaultCandidateOrder))
+
+	mostExpensiveFirst := candidateScorer(defaultScorer, MostExpensiveFirstCandidateOrder)
+	assert.True(t, mostExpensiveFirst.Has(scoring.PriceScorerName))
+	assert.False(t, defaultScorer.Has(scoring.PriceScorerName))
+	assert.Equal(t, 2*defaultScorer.TotalWeight(), mostExpensiveFirst.TotalWeight())
+
+	withPrice := scoring.NewFramework([]scoring.WeightedScorer{{Scorer: scoring.NewPriceScorer(), Weight: 3}})
+	assert.Same(t, withPrice, candidateScorer(withPrice, MostExpensiveFirstCandidateOrder))
+}
+
+func TestParseCandidateOrder(t *testing.T) {
+	for _, name := range []string{"Default", "MostExpensiveFirst"} {
+		order, err := ParseCandidateOrder(name)
+		assert.NoError(t, err)
+		assert.Equal(t, CandidateOrder(name), order)
+	}
+	_, err := ParseCandidateOrder("mostExpensiveFirst")
+	assert.Error(t, err)
+}
+
+type fakePricingModel struct {
+	prices map[string]float64
+}
+
+func (f *fakePricingModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	if price, found := f.prices[node.Name]; found {
+		return price, nil
+	}
+	return 0, fmt.Errorf("price for node %s not found", node.Name)
+}
+
+func (f *fakePricingModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	return 0, nil
+}
+
 func sizedNodeGroup(id string, size int, atomic bool) cloudprovider.NodeGroup {
 	ng := testprovider.NewTestNodeGroup(id, 10000, 0, size, true, false, "n1-standard-2", nil, nil)
 	ng.SetOptions(&config.NodeGroupAutoscalingOptions{
@@ -865,6 +1053,10 @@ func (f *fakeActuationStatus) RecentEvictions() []*apiv1.Pod {
 	return f.recentEvictions
 }
 
+func (f *fakeActuationStatus) DrainStatuses() map[string]status.NodeDrainStatus {
+	return nil
+}
+
 func (f *fakeActuationStatus) DeletionsInProgress() ([]string, []string) {
 	return nil, nil
 }
@@ -877,6 +1069,10 @@ func (f *fakeActuationStatus) DeletionsCount(nodeGroup string) int {
 	return 0
 }
 
+func (f *fakeActuationStatus) DrainsCount(nodeGroup string) int {
+	return 0
+}
+
 type fakeEligibilityChecker struct {
 	eligible map[string]bool
 }
@@ -895,13 +1091,19 @@ func (f *fakeEligibilityChecker) FilterOutUnremovable(context *context.Autoscali
 	return eligible, utilMap, nil
 }
 
+// fakeRemovalSimulator implements the methods used by the planner, calls to
+// the other methods of the embedded nil interface panic.
 type fakeRemovalSimulator struct {
+	simulator.NodeRemovalSimulator
 	nodes []*apiv1.Node
 	sleep time.Duration
 }
 
 func (r *fakeRemovalSimulator) DropOldHints() {}
 
+func (r *fakeRemovalSimulator) PrecomputeDrainability([]string, time.Time, pdb.RemainingPdbTracker, int) {
+}
+
 func (r *fakeRemovalSimulator) SimulateNodeRemoval(name string, _ map[string]bool, _ time.Time, _ pdb.RemainingPdbTracker) (*simulator.NodeToBeRemoved, *simulator.UnremovableNode) {
 	time.Sleep(r.sleep)
 	node := &apiv1.Node{}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reserve
+
+import (
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/actuation"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Policy defines the fraction of cluster allocatable resources which has to
+// stay unrequested by pods after scale down.
+type Policy struct {
+	// CPURatio is the fraction of allocatable CPU which has to stay unrequested. 0 disables the reserve.
+	CPURatio float64
+	// MemoryRatio is the fraction of allocatable memory which has to stay unrequested. 0 disables the reserve.
+	MemoryRatio float64
+	// PerZone tells if reserves have to be kept in each zone, in addition to the whole cluster.
+	PerZone bool
+}
+
+// Enabled tells if the policy reserves any resources.
+func (p Policy) Enabled() bool {
+	return p.CPURatio > 0 || p.MemoryRatio > 0
+}
+
+type totals struct {
+	allocatableCPU, requestedCPU       int64
+	allocatableMemory, requestedMemory int64
+}
+
+func (t *totals) add(nodeInfo *schedulerframework.NodeInfo) {
+	t.allocatableCPU += nodeInfo.Allocatable.MilliCPU
+	t.allocatableMemory += nodeInfo.Allocatable.Memory
+	t.requestedCPU += nodeInfo.Requested.MilliCPU
+	t.requestedMemory += nodeInfo.Requested.Memory
+}
+
+// Tracker tracks the resources left in the cluster as nodes are removed, and
+// decides which removals would violate the reserve policy. Pods of removed
+// nodes are assumed to be rescheduled in the same zone, so requested resources
+// never decrease.
+type Tracker struct {
+	policy  Policy
+	cluster totals
+	zones   map[string]*totals
+}
+
+// NewTracker returns a Tracker of the resources of the nodes. Nodes being
+// deleted don't count towards the cluster resources.
+func NewTracker(policy Policy, nodeInfos []*schedulerframework.NodeInfo, timestamp time.Time) *Tracker {
+	t := &Tracker{
+		policy: policy,
+		zones:  make(map[string]*totals),
+	}
+	for _, nodeInfo := range nodeInfos {
+		node := nodeInfo.Node()
+		if node == nil || actuation.IsNodeBeingDeleted(node, timestamp) {
+			continue
+		}
+		t.cluster.add(nodeInfo)
+		if zone := nodeZone(node); zone != "" {
+			if t.zones[zone] == nil {
+				t.zones[zone] = &totals{}
+			}
+			t.zones[zone].add(nodeInfo)
+		}
+	}
+	return t
+}
+
+// CanRemove tells if removing the node keeps the reserves. It returns the
+// scope of the violated reserve, either "cluster" or the zone of the node.
+func (t *Tracker) CanRemove(nodeInfo *schedulerframework.NodeInfo) (bool, string) {
+	if !t.policy.Enabled() {
+		return true, ""
+	}
+	if !t.keepsReserve(t.cluster, nodeInfo) {
+		return false, "cluster"
+	}
+	if t.policy.PerZone {
+		zone := nodeZone(nodeInfo.Node())
+		if zoneTotals, found := t.zones[zone]; found && !t.keepsReserve(*zoneTotals, nodeInfo) {
+			return false, zone
+		}
+	}
+	return true, ""
+}
+
+// Remove removes the allocatable resources of the node from the tracked ones.
+func (t *Tracker) Remove(nodeInfo *schedulerframework.NodeInfo) {
+	t.cluster.allocatableCPU -= nodeInfo.Allocatable.MilliCPU
+	t.cluster.allocatableMemory -= nodeInfo.Allocatable.Memory
+	if zoneTotals, found := t.zones[nodeZone(nodeInfo.Node())]; found {
+		zoneTotals.allocatableCPU -= nodeInfo.Allocatable.MilliCPU
+		zoneTotals.allocatableMemory -= nodeInfo.Allocatable.Memory
+	}
+}
+
+func (t *Tracker) keepsReserve(before totals, nodeInfo *schedulerframework.NodeInfo) bool {
+	return keepsReserve(before.allocatableCPU-nodeInfo.Allocatable.MilliCPU, before.requestedCPU, t.policy.CPURatio) &&
+		keepsReserve(before.allocatableMemory-nodeInfo.Allocatable.Memory, before.requestedMemory, t.policy.MemoryRatio)
+}
+
+func keepsReserve(allocatable, requested int64, ratio float64) bool {
+	if ratio <= 0 {
+		return true
+	}
+	return float64(allocatable-requested) >= ratio*float64(allocatable)
+}
+
+func nodeZone(node *apiv1.Node) string {
+	return node.Labels[apiv1.LabelTopologyZone]
+}
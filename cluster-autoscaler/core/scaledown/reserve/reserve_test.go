@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reserve
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestTracker(t *testing.T) {
+	nodeInfos := []*schedulerframework.NodeInfo{
+		testNodeInfo("a1", "a", 500),
+		testNodeInfo("a2", "a", 0),
+		testNodeInfo("b1", "b", 600),
+		testNodeInfo("b2", "b", 0),
+	}
+	for desc, tc := range map[string]struct {
+		policy        Policy
+		wantRemovable []string
+		wantScope     string
+	}{
+		"no reserves": {
+			policy:        Policy{},
+			wantRemovable: []string{"a2", "b2"},
+		},
+		"cluster reserve": {
+			policy:        Policy{CPURatio: 0.5},
+			wantRemovable: []string{"a2"},
+			wantScope:     "cluster",
+		},
+		"cluster reserve kept by both removals": {
+			policy:        Policy{CPURatio: 0.45},
+			wantRemovable: []string{"a2", "b2"},
+		},
+		"zone reserve": {
+			policy:        Policy{CPURatio: 0.45, PerZone: true},
+			wantRemovable: []string{"a2"},
+			wantScope:     "b",
+		},
+		"memory reserve": {
+			policy:        Policy{MemoryRatio: 0.9},
+			wantRemovable: []string{},
+			wantScope:     "cluster",
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			tracker := NewTracker(tc.policy, nodeInfos, time.Now())
+			removable := []string{}
+			scope := ""
+			for _, nodeInfo := range []*schedulerframework.NodeInfo{nodeInfos[1], nodeInfos[3]} {
+				if ok, s := tracker.CanRemove(nodeInfo); !ok {
+					scope = s
+					continue
+				}
+				tracker.Remove(nodeInfo)
+				removable = append(removable, nodeInfo.Node().Name)
+			}
+			assert.Equal(t, tc.wantRemovable, removable)
+			assert.Equal(t, tc.wantScope, scope)
+		})
+	}
+}
+
+func testNodeInfo(name, zone string, requestedCPU int64) *schedulerframework.NodeInfo {
+	node := BuildTestNode(name, 1000, 1000)
+	node.Labels = map[string]string{apiv1.LabelTopologyZone: zone}
+	nodeInfo := schedulerframework.NewNodeInfo()
+	if requestedCPU > 0 {
+		nodeInfo.AddPod(BuildTestPod(name+"-pod", requestedCPU, 200))
+	}
+	nodeInfo.SetNode(node)
+	return nodeInfo
+}
@@ -74,8 +74,14 @@ type ActuationStatus interface {
 	// DeletionsCount returns total number of ongoing deletions in a given
 	// node group.
 	DeletionsCount(nodeGroupId string) int
+	// DrainsCount returns total number of ongoing deletions of nodes
+	// needing drain in a given node group.
+	DrainsCount(nodeGroupId string) int
 	// RecentEvictions returns a list of pods that were recently removed by
 	// the Actuator and hence are likely to get recreated elsewhere in the
 	// cluster.
 	RecentEvictions() (pods []*apiv1.Pod)
+	// DrainStatuses returns the progress of drains of nodes that are
+	// currently undergoing drain and deletion, keyed by node name.
+	DrainStatuses() map[string]status.NodeDrainStatus
 }
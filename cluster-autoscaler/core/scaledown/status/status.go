@@ -22,8 +22,8 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
 	"k8s.io/klog/v2"
 )
 
@@ -32,6 +32,7 @@ type ScaleDownStatus struct {
 	Result                ScaleDownResult
 	ScaledDownNodes       []*ScaleDownNode
 	UnremovableNodes      []*UnremovableNode
+	UnneededNodes         []*UnneededNode
 	RemovedNodeGroups     []cloudprovider.NodeGroup
 	NodeDeleteResults     map[string]NodeDeleteResult
 	NodeDeleteResultsAsOf time.Time
@@ -55,15 +56,51 @@ func (s *ScaleDownStatus) SetUnremovableNodesInfo(unremovableNodes []*simulator.
 		}
 
 		s.UnremovableNodes = append(s.UnremovableNodes, &UnremovableNode{
-			Node:        unremovableNode.Node,
-			NodeGroup:   nodeGroup,
-			UtilInfo:    utilInfoPtr,
-			Reason:      unremovableNode.Reason,
-			BlockingPod: unremovableNode.BlockingPod,
+			Node:             unremovableNode.Node,
+			NodeGroup:        nodeGroup,
+			UtilInfo:         utilInfoPtr,
+			Reason:           unremovableNode.Reason,
+			BlockingPod:      unremovableNode.BlockingPod,
+			BlockingPods:     unremovableNode.BlockingPods,
+			UnschedulablePod: unremovableNode.UnschedulablePod,
+			UnremovableSince: unremovableNode.UnremovableSince,
+			ReasonHistory:    unremovableNode.ReasonHistory,
 		})
 	}
 }
 
+// SetUnneededNodesInfo sets the status of nodes that were found to be unneeded.
+func (s *ScaleDownStatus) SetUnneededNodesInfo(unneededNodes []*apiv1.Node, nodeUtilizationMap map[string]utilization.Info, cp cloudprovider.CloudProvider) {
+	s.UnneededNodes = make([]*UnneededNode, 0, len(unneededNodes))
+
+	for _, node := range unneededNodes {
+		nodeGroup, err := cp.NodeGroupForNode(node)
+		if err != nil {
+			klog.Errorf("Couldn't find node group for unneeded node in cloud provider %s", node.Name)
+			continue
+		}
+
+		var utilInfoPtr *utilization.Info
+		if utilInfo, found := nodeUtilizationMap[node.Name]; found {
+			utilInfoPtr = &utilInfo
+		}
+
+		s.UnneededNodes = append(s.UnneededNodes, &UnneededNode{
+			Node:      node,
+			NodeGroup: nodeGroup,
+			UtilInfo:  utilInfoPtr,
+		})
+	}
+}
+
+// UnneededNode represents the state of a node that can be removed now or
+// in the near future.
+type UnneededNode struct {
+	Node      *apiv1.Node
+	NodeGroup cloudprovider.NodeGroup
+	UtilInfo  *utilization.Info
+}
+
 // UnremovableNode represents the state of a node that couldn't be removed.
 type UnremovableNode struct {
 	Node        *apiv1.Node
@@ -71,6 +108,18 @@ type UnremovableNode struct {
 	UtilInfo    *utilization.Info
 	Reason      simulator.UnremovableReason
 	BlockingPod *drain.BlockingPod
+	// BlockingPods are all pods blocking drain of the node, starting with
+	// BlockingPod, if Reason is simulator.BlockedByPod.
+	BlockingPods []*drain.BlockingPod
+	// UnschedulablePod is the first pod that couldn't be moved to any other
+	// node, if Reason is simulator.NoPlaceToMovePods.
+	UnschedulablePod *simulator.UnschedulablePod
+	// UnremovableSince is the time since which the node has been unremovable
+	// in consecutive loops.
+	UnremovableSince time.Time
+	// ReasonHistory lists the recent reasons for which the node has been
+	// unremovable since UnremovableSince, oldest first.
+	ReasonHistory []simulator.UnremovableReasonChange
 }
 
 // ScaleDownNode represents the state of a node that's being scaled down.
@@ -135,6 +184,49 @@ type PodEvictionResult struct {
 	Pod      *apiv1.Pod
 	TimedOut bool
 	Err      error
+	// Deleted is true if the pod was deleted instead of evicted, because its
+	// evictions were persistently rejected.
+	Deleted bool
+	// DeniedByWebhook is true if the last failed eviction of the pod was
+	// denied by a validating admission webhook.
+	DeniedByWebhook bool
+}
+
+// NodeDrainPhase is the phase of a node drain.
+type NodeDrainPhase string
+
+const (
+	// NodeDrainEvicting - evictions of pods are being created.
+	NodeDrainEvicting NodeDrainPhase = "Evicting"
+	// NodeDrainWaitingForTermination - evictions were created, waiting for pods to terminate.
+	NodeDrainWaitingForTermination NodeDrainPhase = "WaitingForTermination"
+	// NodeDrainForceDeleting - pods didn't terminate before the drain timeout and are being force deleted.
+	NodeDrainForceDeleting NodeDrainPhase = "ForceDeleting"
+	// NodeDrainSucceeded - all pods were removed from the node.
+	NodeDrainSucceeded NodeDrainPhase = "Succeeded"
+	// NodeDrainFailed - some pods couldn't be removed from the node.
+	NodeDrainFailed NodeDrainPhase = "Failed"
+	// NodeDrainDelayingDeletion - all pods were removed from the node, deletion of the node waits for external
+	// controllers to remove their delay-deletion annotations from it.
+	NodeDrainDelayingDeletion NodeDrainPhase = "DelayingDeletion"
+)
+
+// NodeDrainStatus contains the progress of a node drain.
+type NodeDrainStatus struct {
+	Phase NodeDrainPhase
+	// StartTime is the time the drain started at.
+	StartTime time.Time
+	// Deadline is the time at which the current phase times out.
+	Deadline time.Time
+	// PodsToRemove is the number of pods removed from the node, excluding
+	// DaemonSet pods.
+	PodsToRemove int
+	// PodsEvicted is the number of PodsToRemove whose evictions were created.
+	PodsEvicted int
+	// PodsRemaining is the number of pods that are still running on the node.
+	PodsRemaining int
+	// DelayedBy are the names of the hooks delaying deletion of the node, in the DelayingDeletion phase.
+	DelayedBy []string
 }
 
 // WasEvictionSuccessful tells if the pod was successfully evicted.
@@ -24,14 +24,17 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/context"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/eligibility"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/reserve"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/resource"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/timewindow"
 	"k8s.io/autoscaler/cluster-autoscaler/utils"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 
 	apiv1 "k8s.io/api/core/v1"
 	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 // Nodes tracks the state of cluster nodes that are not needed.
@@ -52,6 +55,8 @@ type scaleDownTimeGetter interface {
 	GetScaleDownUnneededTime(nodeGroup cloudprovider.NodeGroup) (time.Duration, error)
 	// GetScaleDownUnreadyTime returns ScaleDownUnreadyTime value that should be used for a given NodeGroup.
 	GetScaleDownUnreadyTime(nodeGroup cloudprovider.NodeGroup) (time.Duration, error)
+	// GetScaleDownWindow returns ScaleDownWindow value that should be used for a given NodeGroup.
+	GetScaleDownWindow(nodeGroup cloudprovider.NodeGroup) (string, error)
 }
 
 // NewNodes returns a new initialized Nodes object.
@@ -120,11 +125,12 @@ func (n *Nodes) Drop(node string) {
 func (n *Nodes) RemovableAt(context *context.AutoscalingContext, ts time.Time, resourcesLeft resource.Limits, resourcesWithLimits []string, as scaledown.ActuationStatus) (empty, needDrain []simulator.NodeToBeRemoved, unremovable []*simulator.UnremovableNode) {
 	nodeGroupSize := utils.GetNodeGroupSizeMap(context.CloudProvider)
 	resourcesLeftCopy := resourcesLeft.DeepCopy()
+	reserves := newReserveTracker(context, ts)
 	emptyNodes, drainNodes := n.splitEmptyAndNonEmptyNodes()
 
 	for nodeName, v := range emptyNodes {
 		klog.V(2).Infof("%s was unneeded for %s", nodeName, ts.Sub(v.since).String())
-		if r := n.unremovableReason(context, v, ts, nodeGroupSize, resourcesLeftCopy, resourcesWithLimits, as); r != simulator.NoReason {
+		if r := n.unremovableReason(context, v, ts, nodeGroupSize, resourcesLeftCopy, resourcesWithLimits, reserves, as); r != simulator.NoReason {
 			unremovable = append(unremovable, &simulator.UnremovableNode{Node: v.ntbr.Node, Reason: r})
 			continue
 		}
@@ -132,7 +138,7 @@ func (n *Nodes) RemovableAt(context *context.AutoscalingContext, ts time.Time, r
 	}
 	for nodeName, v := range drainNodes {
 		klog.V(2).Infof("%s was unneeded for %s", nodeName, ts.Sub(v.since).String())
-		if r := n.unremovableReason(context, v, ts, nodeGroupSize, resourcesLeftCopy, resourcesWithLimits, as); r != simulator.NoReason {
+		if r := n.unremovableReason(context, v, ts, nodeGroupSize, resourcesLeftCopy, resourcesWithLimits, reserves, as); r != simulator.NoReason {
 			unremovable = append(unremovable, &simulator.UnremovableNode{Node: v.ntbr.Node, Reason: r})
 			continue
 		}
@@ -141,7 +147,7 @@ func (n *Nodes) RemovableAt(context *context.AutoscalingContext, ts time.Time, r
 	return
 }
 
-func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node, ts time.Time, nodeGroupSize map[string]int, resourcesLeft resource.Limits, resourcesWithLimits []string, as scaledown.ActuationStatus) simulator.UnremovableReason {
+func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node, ts time.Time, nodeGroupSize map[string]int, resourcesLeft resource.Limits, resourcesWithLimits []string, reserves *reserve.Tracker, as scaledown.ActuationStatus) simulator.UnremovableReason {
 	node := v.ntbr.Node
 	// Check if node is marked with no scale down annotation.
 	if eligibility.HasNoScaleDownAnnotation(node) {
@@ -182,10 +188,27 @@ func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node,
 		}
 	}
 
+	if reason := n.verifyScaleDownWindow(node.Name, nodeGroup, ts); reason != simulator.NoReason {
+		return reason
+	}
+
 	if reason := verifyMinSize(node.Name, nodeGroup, nodeGroupSize, as); reason != simulator.NoReason {
 		return reason
 	}
 
+	var nodeInfo *schedulerframework.NodeInfo
+	if reserves != nil {
+		nodeInfo, err = context.ClusterSnapshot.NodeInfos().Get(node.Name)
+		if err != nil {
+			klog.Errorf("Error getting node info of %s: %v", node.Name, err)
+			return simulator.UnexpectedError
+		}
+		if ok, scope := reserves.CanRemove(nodeInfo); !ok {
+			klog.V(4).Infof("Skipping %s - resource reserve of %s would be violated", node.Name, scope)
+			return simulator.BlockedByReservePolicy
+		}
+	}
+
 	resourceDelta, err := n.limitsFinder.DeltaForNode(context, node, nodeGroup, resourcesWithLimits)
 	if err != nil {
 		klog.Errorf("Error getting node resources: %v", err)
@@ -208,10 +231,32 @@ func (n *Nodes) unremovableReason(context *context.AutoscalingContext, v *node,
 		return simulator.MinimalResourceLimitExceeded
 	}
 
+	if reserves != nil {
+		reserves.Remove(nodeInfo)
+	}
 	nodeGroupSize[nodeGroup.Id()]--
 	return simulator.NoReason
 }
 
+// newReserveTracker returns a tracker of the resources reserved by the reserve
+// policy, or nil if the policy doesn't reserve any.
+func newReserveTracker(context *context.AutoscalingContext, ts time.Time) *reserve.Tracker {
+	policy := reserve.Policy{
+		CPURatio:    context.ScaleDownCPUReserveRatio,
+		MemoryRatio: context.ScaleDownMemoryReserveRatio,
+		PerZone:     context.ScaleDownReservePerZone,
+	}
+	if !policy.Enabled() {
+		return nil
+	}
+	nodeInfos, err := context.ClusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Error listing node infos, resource reserves are ignored: %v", err)
+		return nil
+	}
+	return reserve.NewTracker(policy, nodeInfos, ts)
+}
+
 func (n *Nodes) splitEmptyAndNonEmptyNodes() (empty, needDrain map[string]*node) {
 	empty = make(map[string]*node)
 	needDrain = make(map[string]*node)
@@ -225,6 +270,29 @@ func (n *Nodes) splitEmptyAndNonEmptyNodes() (empty, needDrain map[string]*node)
 	return
 }
 
+// verifyScaleDownWindow checks if the scale-down window of the node group is
+// open. Invalid windows block scale-down, rather than allowing it at any time.
+func (n *Nodes) verifyScaleDownWindow(nodeName string, nodeGroup cloudprovider.NodeGroup, ts time.Time) simulator.UnremovableReason {
+	spec, err := n.sdtg.GetScaleDownWindow(nodeGroup)
+	if err != nil {
+		klog.Errorf("Error trying to get ScaleDownWindow for node %s (in group: %s)", nodeName, nodeGroup.Id())
+		return simulator.UnexpectedError
+	}
+	if spec == "" {
+		return simulator.NoReason
+	}
+	window, err := timewindow.Parse(spec)
+	if err != nil {
+		klog.Errorf("Invalid ScaleDownWindow %q for node %s (in group: %s): %v", spec, nodeName, nodeGroup.Id(), err)
+		return simulator.UnexpectedError
+	}
+	if !window.Contains(ts) {
+		klog.V(4).Infof("Skipping %s - outside of scale-down window %q of node group %s", nodeName, spec, nodeGroup.Id())
+		return simulator.OutsideScaleDownWindow
+	}
+	return simulator.NoReason
+}
+
 func verifyMinSize(nodeName string, nodeGroup cloudprovider.NodeGroup, nodeGroupSize map[string]int, as scaledown.ActuationStatus) simulator.UnremovableReason {
 	size, found := nodeGroupSize[nodeGroup.Id()]
 	if !found {
@@ -29,6 +29,7 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/client-go/kubernetes/fake"
@@ -135,6 +136,7 @@ func TestRemovableAt(t *testing.T) {
 		minSize             int
 		targetSize          int
 		numOngoingDeletions int
+		scaleDownWindow     string
 		numEmptyToRemove    int
 		numDrainToRemove    int
 	}{
@@ -168,6 +170,36 @@ func TestRemovableAt(t *testing.T) {
 			numEmptyToRemove:    2,
 			numDrainToRemove:    0,
 		},
+		{
+			name:             "Scale-down window is open",
+			numEmpty:         3,
+			numDrain:         2,
+			minSize:          1,
+			targetSize:       10,
+			scaleDownWindow:  "* * * * *",
+			numEmptyToRemove: 3,
+			numDrainToRemove: 2,
+		},
+		{
+			name:             "Scale-down window is closed",
+			numEmpty:         3,
+			numDrain:         2,
+			minSize:          1,
+			targetSize:       10,
+			scaleDownWindow:  "* * 31 2 *",
+			numEmptyToRemove: 0,
+			numDrainToRemove: 0,
+		},
+		{
+			name:             "Scale-down window is invalid",
+			numEmpty:         3,
+			numDrain:         2,
+			minSize:          1,
+			targetSize:       10,
+			scaleDownWindow:  "not-a-window",
+			numEmptyToRemove: 0,
+			numDrainToRemove: 0,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -201,7 +233,7 @@ func TestRemovableAt(t *testing.T) {
 			ctx, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{ScaleDownSimulationTimeout: 5 * time.Minute}, &fake.Clientset{}, registry, provider, nil, nil)
 			assert.NoError(t, err)
 
-			n := NewNodes(&fakeScaleDownTimeGetter{}, &resource.LimitsFinder{})
+			n := NewNodes(&fakeScaleDownTimeGetter{scaleDownWindow: tc.scaleDownWindow}, &resource.LimitsFinder{})
 			n.Update(nodes, time.Now())
 			gotEmptyToRemove, gotDrainToRemove, _ := n.RemovableAt(&ctx, time.Now(), resource.Limits{}, []string{}, as)
 			if len(gotDrainToRemove) != tc.numDrainToRemove || len(gotEmptyToRemove) != tc.numEmptyToRemove {
@@ -211,6 +243,42 @@ func TestRemovableAt(t *testing.T) {
 	}
 }
 
+func TestRemovableAtReserves(t *testing.T) {
+	ng := testprovider.NewTestNodeGroup("ng", 100, 0, 4, true, false, "", nil, nil)
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.InsertNodeGroup(ng)
+	busy := BuildTestNode("busy", 1000, 1000)
+	provider.AddNode("ng", busy)
+	pod := BuildTestPod("pod", 600, 100)
+	pod.Spec.NodeName = busy.Name
+	var empty []simulator.NodeToBeRemoved
+	nodes := []*apiv1.Node{busy}
+	for i := 0; i < 3; i++ {
+		node := BuildTestNode(fmt.Sprintf("empty-%d", i), 1000, 1000)
+		provider.AddNode("ng", node)
+		nodes = append(nodes, node)
+		empty = append(empty, simulator.NodeToBeRemoved{Node: node})
+	}
+
+	rsLister, err := kube_util.NewTestReplicaSetLister(nil)
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, rsLister, nil)
+	options := config.AutoscalingOptions{ScaleDownSimulationTimeout: 5 * time.Minute, ScaleDownCPUReserveRatio: 0.5}
+	ctx, err := NewScaleTestAutoscalingContext(options, &fake.Clientset{}, registry, provider, nil, nil)
+	assert.NoError(t, err)
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, ctx.ClusterSnapshot, nodes, []*apiv1.Pod{pod})
+
+	n := NewNodes(&fakeScaleDownTimeGetter{}, &resource.LimitsFinder{})
+	n.Update(empty, time.Now())
+	gotEmpty, gotDrain, unremovable := n.RemovableAt(&ctx, time.Now(), resource.Limits{}, []string{}, &fakeActuationStatus{})
+	// Removing two nodes leaves 1400m of 2000m unrequested, removing the third would leave 400m of 1000m.
+	assert.Len(t, gotEmpty, 2)
+	assert.Empty(t, gotDrain)
+	if assert.Len(t, unremovable, 1) {
+		assert.Equal(t, simulator.BlockedByReservePolicy, unremovable[0].Reason)
+	}
+}
+
 type fakeActuationStatus struct {
 	recentEvictions []*apiv1.Pod
 	deletionCount   map[string]int
@@ -220,6 +288,10 @@ func (f *fakeActuationStatus) RecentEvictions() []*apiv1.Pod {
 	return f.recentEvictions
 }
 
+func (f *fakeActuationStatus) DrainStatuses() map[string]status.NodeDrainStatus {
+	return nil
+}
+
 func (f *fakeActuationStatus) DeletionsInProgress() ([]string, []string) {
 	return nil, nil
 }
@@ -232,7 +304,13 @@ func (f *fakeActuationStatus) DeletionsCount(nodeGroup string) int {
 	return f.deletionCount[nodeGroup]
 }
 
-type fakeScaleDownTimeGetter struct{}
+func (f *fakeActuationStatus) DrainsCount(nodeGroup string) int {
+	return 0
+}
+
+type fakeScaleDownTimeGetter struct {
+	scaleDownWindow string
+}
 
 func (f *fakeScaleDownTimeGetter) GetScaleDownUnneededTime(cloudprovider.NodeGroup) (time.Duration, error) {
 	return 0 * time.Second, nil
@@ -241,3 +319,7 @@ func (f *fakeScaleDownTimeGetter) GetScaleDownUnneededTime(cloudprovider.NodeGro
 func (f *fakeScaleDownTimeGetter) GetScaleDownUnreadyTime(cloudprovider.NodeGroup) (time.Duration, error) {
 	return 0 * time.Second, nil
 }
+
+func (f *fakeScaleDownTimeGetter) GetScaleDownWindow(cloudprovider.NodeGroup) (string, error) {
+	return f.scaleDownWindow, nil
+}
@@ -17,26 +17,52 @@ limitations under the License.
 package unremovable
 
 import (
+	"hash/fnv"
+	"sort"
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 
 	apiv1 "k8s.io/api/core/v1"
 	klog "k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
+// maxReasonHistory is the maximum number of reasons recorded for a node.
+const maxReasonHistory = 10
+
 // Nodes tracks the state of cluster nodes that cannot be removed.
 type Nodes struct {
-	ttls    map[string]time.Time
-	reasons map[string]*simulator.UnremovableNode
+	ttls       map[string]time.Time
+	states     map[string]nodeState
+	reasons    map[string]*simulator.UnremovableNode
+	history    map[string]*history
+	lastUpdate time.Time
+}
+
+// history tracks a node found unremovable in consecutive loops.
+type history struct {
+	since   time.Time
+	reasons []simulator.UnremovableReasonChange
+	// simulations is the number of times the node was found unremovable in
+	// simulation since its state last changed.
+	simulations int
+}
+
+// nodeState identifies the state of a node, and the pods running on it, at
+// the time the node was found unremovable.
+type nodeState struct {
+	hash uint64
 }
 
 // NewNodes returns a new initialized Nodes object.
 func NewNodes() *Nodes {
 	return &Nodes{
 		ttls:    make(map[string]time.Time),
+		states:  make(map[string]nodeState),
 		reasons: make(map[string]*simulator.UnremovableNode),
+		history: make(map[string]*history),
 	}
 }
 
@@ -46,25 +72,47 @@ type NodeInfoGetter interface {
 }
 
 // Update updates the internal structure according to current state of the
-// cluster. Removes the nodes that are no longer in the nodes list.
+// cluster. Removes the nodes that are no longer in the nodes list. Nodes which
+// weren't reported unremovable since the previous update are considered
+// removable again, so their history is dropped.
 func (n *Nodes) Update(nodeInfos NodeInfoGetter, timestamp time.Time) {
+	for name := range n.history {
+		if _, found := n.reasons[name]; !found {
+			delete(n.history, name)
+		}
+	}
+	n.lastUpdate = timestamp
 	n.reasons = make(map[string]*simulator.UnremovableNode)
 	if len(n.ttls) <= 0 {
 		return
 	}
 	newTTLs := make(map[string]time.Time, len(n.ttls))
+	newStates := make(map[string]nodeState, len(n.states))
 	for name, ttl := range n.ttls {
-		if _, err := nodeInfos.Get(name); err != nil {
+		nodeInfo, err := nodeInfos.Get(name)
+		if err != nil {
 			// Not logging on error level as most likely cause is that node is no longer in the cluster.
 			klog.Infof("Can't retrieve node %s from snapshot, removing from unremovable nodes, err: %v", name, err)
 			continue
 		}
+		state, hasState := n.states[name]
+		if hasState && nodeInfo != nil && state.hash != StateHash(nodeInfo) {
+			klog.V(4).Infof("Node %s or its pods changed since it was found unremovable, removing from unremovable nodes", name)
+			if h, found := n.history[name]; found {
+				h.simulations = 0
+			}
+			continue
+		}
 		if ttl.After(timestamp) {
 			// Keep nodes that are still in the cluster and haven't expired yet.
 			newTTLs[name] = ttl
+			if hasState {
+				newStates[name] = state
+			}
 		}
 	}
 	n.ttls = newTTLs
+	n.states = newStates
 }
 
 // Contains returns true iff a given node is unremovable.
@@ -73,9 +121,27 @@ func (n *Nodes) Contains(nodeName string) bool {
 	return found
 }
 
-// Add adds an unremovable node.
+// Add adds an unremovable node. The node is reported with the time since
+// which it has been unremovable, and the reasons for which it was unremovable
+// since then.
 func (n *Nodes) Add(node *simulator.UnremovableNode) {
-	n.reasons[node.Node.Name] = node
+	h, found := n.history[node.Node.Name]
+	if !found {
+		h = &history{since: n.lastUpdate}
+		n.history[node.Node.Name] = h
+	}
+	// Recently unremovable nodes weren't checked again, so they are still
+	// unremovable for the last known reason.
+	if node.Reason != simulator.RecentlyUnremovable && (len(h.reasons) == 0 || h.reasons[len(h.reasons)-1].Reason != node.Reason) {
+		h.reasons = append(h.reasons, simulator.UnremovableReasonChange{Reason: node.Reason, Since: n.lastUpdate})
+		if len(h.reasons) > maxReasonHistory {
+			h.reasons = append([]simulator.UnremovableReasonChange{}, h.reasons[len(h.reasons)-maxReasonHistory:]...)
+		}
+	}
+	tracked := *node
+	tracked.UnremovableSince = h.since
+	tracked.ReasonHistory = append([]simulator.UnremovableReasonChange{}, h.reasons...)
+	n.reasons[node.Node.Name] = &tracked
 }
 
 // AddTimeout adds a new unremovable node with a timeout until which the node
@@ -83,6 +149,40 @@ func (n *Nodes) Add(node *simulator.UnremovableNode) {
 func (n *Nodes) AddTimeout(node *simulator.UnremovableNode, timeout time.Time) {
 	n.ttls[node.Node.Name] = timeout
 	n.Add(node)
+	n.history[node.Node.Name].simulations++
+}
+
+// RecheckTimeout returns for how long a node found unremovable in simulation
+// shouldn't be simulated again: the base timeout, doubled for each previous
+// time the node was found unremovable in simulation since its state last
+// changed, up to the max timeout. Chronically blocked nodes are thus simulated
+// less and less often. The base timeout is returned if max isn't above it.
+func (n *Nodes) RecheckTimeout(nodeName string, base, max time.Duration) time.Duration {
+	h, found := n.history[nodeName]
+	if !found || max <= base {
+		return base
+	}
+	timeout := base
+	for i := 0; i < h.simulations && timeout < max; i++ {
+		timeout *= 2
+	}
+	if timeout > max {
+		return max
+	}
+	return timeout
+}
+
+// AddTimeoutWithState adds a new unremovable node with a timeout until which
+// the node should be considered unremovable, unless the node or pods running
+// on it change earlier. Nodes blocked by pods for reasons which only depend on
+// the pods themselves are considered unremovable until the max timeout, if it
+// is later than the timeout.
+func (n *Nodes) AddTimeoutWithState(node *simulator.UnremovableNode, timeout, maxTimeout time.Time, nodeInfo *schedulerframework.NodeInfo) {
+	if node.Reason == simulator.BlockedByPod && node.BlockingPod != nil && isPodIntrinsic(node.BlockingPod.Reason) && maxTimeout.After(timeout) {
+		timeout = maxTimeout
+	}
+	n.states[node.Node.Name] = nodeState{hash: StateHash(nodeInfo)}
+	n.AddTimeout(node, timeout)
 }
 
 // AddReason adds an unremovable node due to the specified reason.
@@ -110,3 +210,33 @@ func (n *Nodes) IsRecent(nodeName string) bool {
 	_, found := n.ttls[nodeName]
 	return found
 }
+
+// StateHash returns a hash of the node's and its pods' resource versions.
+// Pods are identified by their UIDs, so the hash changes whenever the node,
+// or any of the pods running on it, is modified, added or removed.
+func StateHash(nodeInfo *schedulerframework.NodeInfo) uint64 {
+	pods := make([]string, 0, len(nodeInfo.Pods))
+	for _, podInfo := range nodeInfo.Pods {
+		pods = append(pods, string(podInfo.Pod.UID)+"@"+podInfo.Pod.ResourceVersion)
+	}
+	sort.Strings(pods)
+	h := fnv.New64a()
+	if node := nodeInfo.Node(); node != nil {
+		h.Write([]byte(node.Name + "@" + node.ResourceVersion))
+	}
+	for _, pod := range pods {
+		h.Write([]byte{0})
+		h.Write([]byte(pod))
+	}
+	return h.Sum64()
+}
+
+// isPodIntrinsic returns true if the blocking reason only depends on the pod
+// itself, and not on the state of other objects in the cluster.
+func isPodIntrinsic(reason drain.BlockingPodReason) bool {
+	switch reason {
+	case drain.NotReplicated, drain.LocalStorageRequested, drain.NotSafeToEvictAnnotation:
+		return true
+	}
+	return false
+}
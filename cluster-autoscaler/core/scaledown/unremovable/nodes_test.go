@@ -18,10 +18,12 @@ package unremovable
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -81,6 +83,89 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateWithState(t *testing.T) {
+	node := BuildTestNode("n1", 1000, 1000)
+	pod := BuildTestPod("p1", 100, 100)
+	pod.UID = "p1-uid"
+	pod.ResourceVersion = "1"
+	nodeInfo := schedulerframework.NewNodeInfo(pod)
+	nodeInfo.SetNode(node)
+
+	changedPod := pod.DeepCopy()
+	changedPod.ResourceVersion = "2"
+	changedNodeInfo := schedulerframework.NewNodeInfo(changedPod)
+	changedNodeInfo.SetNode(node)
+
+	blockedByPod := func(reason drain.BlockingPodReason) *simulator.UnremovableNode {
+		return &simulator.UnremovableNode{Node: node, Reason: simulator.BlockedByPod, BlockingPod: &drain.BlockingPod{Pod: pod, Reason: reason}}
+	}
+
+	testCases := map[string]struct {
+		unremovable *simulator.UnremovableNode
+		timeout     time.Time
+		maxTimeout  time.Time
+		current     *schedulerframework.NodeInfo
+		want        bool
+	}{
+		"unchanged before timeout stays": {
+			unremovable: &simulator.UnremovableNode{Node: node, Reason: simulator.NoPlaceToMovePods},
+			timeout:     afterUpdate,
+			current:     nodeInfo,
+			want:        true,
+		},
+		"changed before timeout is removed": {
+			unremovable: &simulator.UnremovableNode{Node: node, Reason: simulator.NoPlaceToMovePods},
+			timeout:     afterUpdate,
+			current:     changedNodeInfo,
+			want:        false,
+		},
+		"unchanged after timeout is removed": {
+			unremovable: &simulator.UnremovableNode{Node: node, Reason: simulator.NoPlaceToMovePods},
+			timeout:     beforeUpdate,
+			current:     nodeInfo,
+			want:        false,
+		},
+		"unchanged blocked by pod intrinsic reason after timeout before max timeout stays": {
+			unremovable: blockedByPod(drain.LocalStorageRequested),
+			timeout:     beforeUpdate,
+			maxTimeout:  afterUpdate,
+			current:     nodeInfo,
+			want:        true,
+		},
+		"unchanged blocked by pod intrinsic reason after max timeout is removed": {
+			unremovable: blockedByPod(drain.LocalStorageRequested),
+			timeout:     beforeUpdate,
+			maxTimeout:  beforeUpdate,
+			current:     nodeInfo,
+			want:        false,
+		},
+		"changed blocked by pod intrinsic reason before max timeout is removed": {
+			unremovable: blockedByPod(drain.LocalStorageRequested),
+			timeout:     beforeUpdate,
+			maxTimeout:  afterUpdate,
+			current:     changedNodeInfo,
+			want:        false,
+		},
+		"unchanged blocked by pdb after timeout before max timeout is removed": {
+			unremovable: blockedByPod(drain.NotEnoughPdb),
+			timeout:     beforeUpdate,
+			maxTimeout:  afterUpdate,
+			current:     nodeInfo,
+			want:        false,
+		},
+	}
+	for desc, tc := range testCases {
+		t.Run(desc, func(t *testing.T) {
+			n := NewNodes()
+			n.AddTimeoutWithState(tc.unremovable, tc.timeout, tc.maxTimeout, nodeInfo)
+			n.Update(&fakeNodeInfos{map[string]*schedulerframework.NodeInfo{"n1": tc.current}}, updateTime)
+			if got := n.IsRecent("n1"); got != tc.want {
+				t.Errorf("IsRecent(n1) = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestContains(t *testing.T) {
 	n := NewNodes()
 	nodes := []string{"n1", "n2", "n3"}
@@ -103,6 +188,83 @@ func TestContains(t *testing.T) {
 	}
 }
 
+func TestHistory(t *testing.T) {
+	n := NewNodes()
+	nodes := newFakeNodeInfoGetter([]string{"n1", "n2"})
+	node := BuildTestNode("n1", 0, 0)
+	first, second, third, fourth := updateTime, updateTime.Add(time.Minute), updateTime.Add(2*time.Minute), updateTime.Add(3*time.Minute)
+
+	n.Update(nodes, first)
+	n.AddReason(node, simulator.NotUnderutilized)
+	n.Update(nodes, second)
+	n.AddReason(node, simulator.RecentlyUnremovable)
+	n.Update(nodes, third)
+	n.AddReason(node, simulator.NoPlaceToMovePods)
+	got := n.AsList()
+	if len(got) != 1 {
+		t.Fatalf("AsList() returned %d nodes, want 1", len(got))
+	}
+	wantHistory := []simulator.UnremovableReasonChange{
+		{Reason: simulator.NotUnderutilized, Since: first},
+		{Reason: simulator.NoPlaceToMovePods, Since: third},
+	}
+	if got[0].UnremovableSince != first {
+		t.Errorf("UnremovableSince = %v, want %v", got[0].UnremovableSince, first)
+	}
+	if !reflect.DeepEqual(got[0].ReasonHistory, wantHistory) {
+		t.Errorf("ReasonHistory = %v, want %v", got[0].ReasonHistory, wantHistory)
+	}
+
+	// The node wasn't reported unremovable after the fourth update, so its
+	// history restarts.
+	n.Update(nodes, fourth)
+	n.Update(nodes, fourth.Add(time.Minute))
+	n.AddReason(node, simulator.NotUnderutilized)
+	got = n.AsList()
+	if got[0].UnremovableSince != fourth.Add(time.Minute) {
+		t.Errorf("UnremovableSince = %v, want %v", got[0].UnremovableSince, fourth.Add(time.Minute))
+	}
+	if len(got[0].ReasonHistory) != 1 {
+		t.Errorf("ReasonHistory = %v, want a single reason", got[0].ReasonHistory)
+	}
+}
+
+func TestRecheckTimeout(t *testing.T) {
+	node := BuildTestNode("n1", 1000, 1000)
+	pod := BuildTestPod("p1", 100, 100)
+	pod.UID = "p1-uid"
+	pod.ResourceVersion = "1"
+	nodeInfo := schedulerframework.NewNodeInfo(pod)
+	nodeInfo.SetNode(node)
+	changedPod := pod.DeepCopy()
+	changedPod.ResourceVersion = "2"
+	changedNodeInfo := schedulerframework.NewNodeInfo(changedPod)
+	changedNodeInfo.SetNode(node)
+
+	n := NewNodes()
+	unremovableNode := &simulator.UnremovableNode{Node: node, Reason: simulator.NoPlaceToMovePods}
+	var got []time.Duration
+	for i := 0; i < 5; i++ {
+		n.Update(&fakeNodeInfos{map[string]*schedulerframework.NodeInfo{"n1": nodeInfo}}, updateTime)
+		timeout := n.RecheckTimeout("n1", time.Minute, 5*time.Minute)
+		got = append(got, timeout)
+		n.AddTimeoutWithState(unremovableNode, updateTime.Add(timeout), updateTime.Add(5*time.Minute), nodeInfo)
+	}
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RecheckTimeout() = %v, want %v", got, want)
+	}
+	if got := n.RecheckTimeout("n1", time.Minute, 0); got != time.Minute {
+		t.Errorf("RecheckTimeout() without max = %v, want %v", got, time.Minute)
+	}
+
+	// The backoff restarts once the node changes.
+	n.Update(&fakeNodeInfos{map[string]*schedulerframework.NodeInfo{"n1": changedNodeInfo}}, updateTime)
+	if got := n.RecheckTimeout("n1", time.Minute, 5*time.Minute); got != time.Minute {
+		t.Errorf("RecheckTimeout() after change = %v, want %v", got, time.Minute)
+	}
+}
+
 type fakeNodeInfoGetter struct {
 	names map[string]bool
 }
@@ -116,6 +278,17 @@ func (f *fakeNodeInfoGetter) Get(name string) (*schedulerframework.NodeInfo, err
 	return nil, fmt.Errorf("not found")
 }
 
+type fakeNodeInfos struct {
+	nodeInfos map[string]*schedulerframework.NodeInfo
+}
+
+func (f *fakeNodeInfos) Get(name string) (*schedulerframework.NodeInfo, error) {
+	if nodeInfo, found := f.nodeInfos[name]; found {
+		return nodeInfo, nil
+	}
+	return nil, fmt.Errorf("not found")
+}
+
 func newFakeNodeInfoGetter(ns []string) *fakeNodeInfoGetter {
 	names := make(map[string]bool, len(ns))
 	for _, n := range ns {
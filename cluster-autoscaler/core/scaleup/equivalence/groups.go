@@ -24,8 +24,8 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
-	pod_utils "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_utils "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 )
 
 // PodGroup contains a group of pods that are equivalent in terms of schedulability.
@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orchestrator
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
+)
+
+// validateBalancedScaleUp schedules the pods triggering the scale-up in simulation on the nodes planned by the scale-up
+// balanced between similar node groups, and if not all of them fit, on the nodes planned by scaling up only the best
+// node group. The balanced scale-up is kept unless fewer pods fit it, e.g. pods with zonal volumes or topology spread
+// constraints which can't go to the zones the nodes were split to.
+func (o *ScaleUpOrchestrator) validateBalancedScaleUp(
+	bestOption *expander.Option,
+	scaleUpInfos []nodegroupset.ScaleUpInfo,
+	nodeInfos map[string]*schedulerframework.NodeInfo,
+	newNodes int,
+) ([]nodegroupset.ScaleUpInfo, *status.BalancingInfo, errors.AutoscalerError) {
+	balancing := newBalancingInfo(scaleUpInfos, nodeInfos)
+	balancedPods, err := o.simulateScaleUp(bestOption.Pods, scaleUpInfos, nodeInfos)
+	if err != nil {
+		klog.Warningf("Failed to validate scale-up balanced between similar node groups in simulation: %v", err)
+		return scaleUpInfos, balancing, nil
+	}
+	balancing.Validated = true
+	balancing.BalancedPods = balancedPods
+	if balancedPods == len(bestOption.Pods) {
+		return scaleUpInfos, balancing, nil
+	}
+
+	bestScaleUpInfos, aErr := o.processors.NodeGroupSetProcessor.BalanceScaleUpBetweenGroups(o.autoscalingContext, []cloudprovider.NodeGroup{bestOption.NodeGroup}, newNodes)
+	if aErr != nil {
+		return nil, nil, aErr
+	}
+	bestPods, err := o.simulateScaleUp(bestOption.Pods, bestScaleUpInfos, nodeInfos)
+	if err != nil {
+		klog.Warningf("Failed to simulate scale-up of node group %s only: %v", bestOption.NodeGroup.Id(), err)
+		return scaleUpInfos, balancing, nil
+	}
+	balancing.BestNodeGroupPods = bestPods
+	if bestPods <= balancedPods {
+		klog.V(2).Infof("%d of %d pods fit the scale-up balanced between similar node groups in simulation, no less than scaling up only %s", balancedPods, len(bestOption.Pods), bestOption.NodeGroup.Id())
+		return scaleUpInfos, balancing, nil
+	}
+	klog.V(1).Infof("Only %d of %d pods fit the scale-up balanced between similar node groups in simulation, and %d fit scaling up only %s, not balancing", balancedPods, len(bestOption.Pods), bestPods, bestOption.NodeGroup.Id())
+	fallback := newBalancingInfo(bestScaleUpInfos, nodeInfos)
+	fallback.Validated = true
+	fallback.FellBack = true
+	fallback.BalancedPods = balancedPods
+	fallback.BestNodeGroupPods = bestPods
+	return bestScaleUpInfos, fallback, nil
+}
+
+// simulateScaleUp adds the nodes planned by the scale-up to a fork of the cluster snapshot, and returns how many of
+// the pods can be scheduled on them.
+func (o *ScaleUpOrchestrator) simulateScaleUp(pods []*apiv1.Pod, scaleUpInfos []nodegroupset.ScaleUpInfo, nodeInfos map[string]*schedulerframework.NodeInfo) (int, error) {
+	snapshot := o.autoscalingContext.ClusterSnapshot
+	snapshot.Fork()
+	defer snapshot.Revert()
+
+	newNodeNames := make(map[string]bool)
+	for i, info := range scaleUpInfos {
+		template, found := nodeInfos[info.Group.Id()]
+		if !found {
+			return 0, fmt.Errorf("no node info for %s", info.Group.Id())
+		}
+		for j := 0; j < info.NewSize-info.CurrentSize; j++ {
+			nodeInfo := scheduler.DeepCopyTemplateNode(template, fmt.Sprintf("balance-%d-%d", i, j))
+			var nodePods []*apiv1.Pod
+			for _, podInfo := range nodeInfo.Pods {
+				nodePods = append(nodePods, podInfo.Pod)
+			}
+			if err := snapshot.AddNodeWithPods(nodeInfo.Node(), nodePods); err != nil {
+				return 0, err
+			}
+			newNodeNames[nodeInfo.Node().Name] = true
+		}
+	}
+
+	scheduled := 0
+	for _, pod := range pods {
+		nodeName, err := o.autoscalingContext.PredicateChecker.FitsAnyNodeMatching(snapshot, pod, func(nodeInfo *schedulerframework.NodeInfo) bool {
+			return newNodeNames[nodeInfo.Node().Name]
+		})
+		if err != nil {
+			continue
+		}
+		if err := snapshot.AddPod(pod, nodeName); err != nil {
+			return 0, err
+		}
+		scheduled++
+	}
+	return scheduled, nil
+}
+
+// newBalancingInfo returns how the nodes added by the scale-up are split between zones.
+func newBalancingInfo(scaleUpInfos []nodegroupset.ScaleUpInfo, nodeInfos map[string]*schedulerframework.NodeInfo) *status.BalancingInfo {
+	zones := make(map[string]int)
+	for _, info := range scaleUpInfos {
+		zone := ""
+		if nodeInfo, found := nodeInfos[info.Group.Id()]; found && nodeInfo.Node() != nil {
+			zone = nodeZone(nodeInfo.Node())
+		}
+		zones[zone] += info.NewSize - info.CurrentSize
+	}
+	return &status.BalancingInfo{Zones: zones}
+}
+
+func nodeZone(node *apiv1.Node) string {
+	if zone, found := node.Labels[apiv1.LabelTopologyZone]; found {
+		return zone
+	}
+	return node.Labels[apiv1.LabelFailureDomainBetaZone]
+}
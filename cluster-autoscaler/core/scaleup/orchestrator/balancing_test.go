@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orchestrator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestValidateBalancedScaleUp(t *testing.T) {
+	zonalPods := func(zone string, count int) []*apiv1.Pod {
+		var pods []*apiv1.Pod
+		for i := 0; i < count; i++ {
+			pod := BuildTestPod(fmt.Sprintf("%s-pod-%d", zone, i), 800, 0)
+			if zone != "" {
+				pod.Spec.NodeSelector = map[string]string{apiv1.LabelTopologyZone: zone}
+			}
+			pods = append(pods, pod)
+		}
+		return pods
+	}
+
+	testCases := []struct {
+		desc          string
+		pods          []*apiv1.Pod
+		bestMaxSize   int
+		wantTargets   map[string]int
+		wantBalancing *status.BalancingInfo
+	}{
+		{
+			desc:          "all pods fit the balanced scale-up",
+			pods:          zonalPods("", 2),
+			bestMaxSize:   10,
+			wantTargets:   map[string]int{"ng-a": 2, "ng-b": 2},
+			wantBalancing: &status.BalancingInfo{Zones: map[string]int{"zone-a": 1, "zone-b": 1}, Validated: true, BalancedPods: 2},
+		},
+		{
+			desc:          "zonal pods fit only the best node group",
+			pods:          zonalPods("zone-a", 2),
+			bestMaxSize:   10,
+			wantTargets:   map[string]int{"ng-a": 3},
+			wantBalancing: &status.BalancingInfo{Zones: map[string]int{"zone-a": 2}, Validated: true, FellBack: true, BalancedPods: 1, BestNodeGroupPods: 2},
+		},
+		{
+			desc:          "best node group can't fit more pods",
+			pods:          zonalPods("zone-a", 2),
+			bestMaxSize:   2,
+			wantTargets:   map[string]int{"ng-a": 2, "ng-b": 2},
+			wantBalancing: &status.BalancingInfo{Zones: map[string]int{"zone-a": 1, "zone-b": 1}, Validated: true, BalancedPods: 1, BestNodeGroupPods: 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			provider := testprovider.NewTestCloudProvider(nil, nil)
+			provider.AddNodeGroup("ng-a", 1, tc.bestMaxSize, 1)
+			provider.AddNodeGroup("ng-b", 1, 10, 1)
+			nodeInfos := map[string]*schedulerframework.NodeInfo{}
+			for _, ng := range []struct{ id, zone string }{{"ng-a", "zone-a"}, {"ng-b", "zone-b"}} {
+				node := BuildTestNode(ng.id+"-template", 1000, 1000)
+				node.Labels[apiv1.LabelTopologyZone] = ng.zone
+				SetNodeReadyState(node, true, node.CreationTimestamp.Time)
+				nodeInfo := schedulerframework.NewNodeInfo()
+				nodeInfo.SetNode(node)
+				nodeInfos[ng.id] = nodeInfo
+			}
+
+			listers := kube_util.NewListerRegistry(nil, nil, kube_util.NewTestPodLister(nil), nil, nil, nil, nil, nil, nil)
+			context, err := NewScaleTestAutoscalingContext(config.AutoscalingOptions{BalanceSimilarNodeGroups: true, ValidateBalancedScaleUp: true}, &fake.Clientset{}, listers, provider, nil, nil)
+			assert.NoError(t, err)
+			clusterState := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, context.LogRecorder, NewBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{}))
+			o := New().(*ScaleUpOrchestrator)
+			o.Initialize(&context, NewTestProcessors(&context), clusterState, taints.TaintConfig{})
+
+			ngA, ngB := provider.GetNodeGroup("ng-a"), provider.GetNodeGroup("ng-b")
+			scaleUpInfos := []nodegroupset.ScaleUpInfo{
+				{Group: ngA, CurrentSize: 1, NewSize: 2, MaxSize: tc.bestMaxSize},
+				{Group: ngB, CurrentSize: 1, NewSize: 2, MaxSize: 10},
+			}
+			bestOption := &expander.Option{NodeGroup: ngA, NodeCount: 2, Pods: tc.pods}
+			gotInfos, gotBalancing, aErr := o.validateBalancedScaleUp(bestOption, scaleUpInfos, nodeInfos, 2)
+			assert.NoError(t, aErr)
+
+			gotTargets := map[string]int{}
+			for _, info := range gotInfos {
+				gotTargets[info.Group.Id()] = info.NewSize
+			}
+			assert.Equal(t, tc.wantTargets, gotTargets)
+			assert.Equal(t, tc.wantBalancing, gotBalancing)
+			// The simulation doesn't leave nodes in the snapshot.
+			nodeInfoList, err := context.ClusterSnapshot.NodeInfos().List()
+			assert.NoError(t, err)
+			assert.Empty(t, nodeInfoList)
+		})
+	}
+}
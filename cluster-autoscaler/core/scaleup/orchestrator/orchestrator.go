@@ -80,12 +80,14 @@ func (o *ScaleUpOrchestrator) Initialize(
 
 // ScaleUp tries to scale the cluster up. Returns appropriate status or error if
 // an unexpected error occurred. Assumes that all nodes in the cluster are ready
-// and in sync with instance groups.
+// and in sync with instance groups. If allOrNothing is set, the scale-up only
+// happens if all the pods would be scheduled on the new nodes.
 func (o *ScaleUpOrchestrator) ScaleUp(
 	unschedulablePods []*apiv1.Pod,
 	nodes []*apiv1.Node,
 	daemonSets []*appsv1.DaemonSet,
 	nodeInfos map[string]*schedulerframework.NodeInfo,
+	allOrNothing bool,
 ) (*status.ScaleUpStatus, errors.AutoscalerError) {
 	if !o.initialized {
 		return scaleUpError(&status.ScaleUpStatus{}, errors.NewAutoscalerError(errors.InternalError, "ScaleUpOrchestrator is not initialized"))
@@ -155,6 +157,8 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 
 		if len(option.Pods) == 0 || option.NodeCount == 0 {
 			klog.V(4).Infof("No pod can fit to %s", nodeGroup.Id())
+		} else if allOrNothing && len(option.Pods) < len(unschedulablePods) {
+			klog.V(4).Infof("Only %d of %d pods can fit to %s, skipping it in all-or-nothing scale-up", len(option.Pods), len(unschedulablePods), nodeGroup.Id())
 		} else {
 			options = append(options, option)
 		}
@@ -171,7 +175,7 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 		klog.V(1).Info("No expansion options")
 		return &status.ScaleUpStatus{
 			Result:                  status.ScaleUpNoOptionsAvailable,
-			PodsRemainUnschedulable: GetRemainingPods(podEquivalenceGroups, skippedNodeGroups),
+			PodsRemainUnschedulable: getRemainingPods(podEquivalenceGroups, skippedNodeGroups, allOrNothing),
 			ConsideredNodeGroups:    nodeGroups,
 		}, nil
 	}
@@ -181,7 +185,7 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 	if bestOption == nil || bestOption.NodeCount <= 0 {
 		return &status.ScaleUpStatus{
 			Result:                  status.ScaleUpNoOptionsAvailable,
-			PodsRemainUnschedulable: GetRemainingPods(podEquivalenceGroups, skippedNodeGroups),
+			PodsRemainUnschedulable: getRemainingPods(podEquivalenceGroups, skippedNodeGroups, allOrNothing),
 			ConsideredNodeGroups:    nodeGroups,
 		}, nil
 	}
@@ -195,6 +199,14 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 	if aErr != nil {
 		return scaleUpError(&status.ScaleUpStatus{PodsTriggeredScaleUp: bestOption.Pods}, aErr)
 	}
+	if allOrNothing && newNodes < bestOption.NodeCount {
+		klog.V(1).Infof("Can't add all %d nodes needed in %s, skipping all-or-nothing scale-up", bestOption.NodeCount, bestOption.NodeGroup.Id())
+		return &status.ScaleUpStatus{
+			Result:                  status.ScaleUpNoOptionsAvailable,
+			PodsRemainUnschedulable: getRemainingPods(podEquivalenceGroups, skippedNodeGroups, allOrNothing),
+			ConsideredNodeGroups:    nodeGroups,
+		}, nil
+	}
 
 	createNodeGroupResults := make([]nodegroups.CreateNodeGroupResult, 0)
 	if !bestOption.NodeGroup.Exist() {
@@ -274,6 +286,15 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 			&status.ScaleUpStatus{CreateNodeGroupResults: createNodeGroupResults, PodsTriggeredScaleUp: bestOption.Pods},
 			aErr)
 	}
+	if allOrNothing && newNodes < bestOption.NodeCount {
+		klog.V(1).Infof("Resource limits allow only %d of %d nodes needed in %s, skipping all-or-nothing scale-up", newNodes, bestOption.NodeCount, bestOption.NodeGroup.Id())
+		return &status.ScaleUpStatus{
+			Result:                  status.ScaleUpNoOptionsAvailable,
+			PodsRemainUnschedulable: getRemainingPods(podEquivalenceGroups, skippedNodeGroups, allOrNothing),
+			ConsideredNodeGroups:    nodeGroups,
+			CreateNodeGroupResults:  createNodeGroupResults,
+		}, nil
+	}
 
 	targetNodeGroups := []cloudprovider.NodeGroup{bestOption.NodeGroup}
 	for _, ng := range bestOption.SimilarNodeGroups {
@@ -295,6 +316,36 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 			aErr)
 	}
 
+	var balancing *status.BalancingInfo
+	if len(scaleUpInfos) > 1 {
+		if o.autoscalingContext.ValidateBalancedScaleUp {
+			scaleUpInfos, balancing, aErr = o.validateBalancedScaleUp(bestOption, scaleUpInfos, nodeInfos, newNodes)
+			if aErr != nil {
+				return scaleUpError(
+					&status.ScaleUpStatus{CreateNodeGroupResults: createNodeGroupResults, PodsTriggeredScaleUp: bestOption.Pods},
+					aErr)
+			}
+		} else {
+			balancing = newBalancingInfo(scaleUpInfos, nodeInfos)
+		}
+	}
+
+	if allOrNothing {
+		plannedNodes := 0
+		for _, info := range scaleUpInfos {
+			plannedNodes += info.NewSize - info.CurrentSize
+		}
+		if plannedNodes < bestOption.NodeCount {
+			klog.V(1).Infof("Node group size limits allow only %d of %d nodes needed, skipping all-or-nothing scale-up", plannedNodes, bestOption.NodeCount)
+			return &status.ScaleUpStatus{
+				Result:                  status.ScaleUpNoOptionsAvailable,
+				PodsRemainUnschedulable: getRemainingPods(podEquivalenceGroups, skippedNodeGroups, allOrNothing),
+				ConsideredNodeGroups:    nodeGroups,
+				CreateNodeGroupResults:  createNodeGroupResults,
+			}, nil
+		}
+	}
+
 	klog.V(1).Infof("Final scale-up plan: %v", scaleUpInfos)
 	aErr, failedNodeGroups := o.scaleUpExecutor.ExecuteScaleUps(scaleUpInfos, nodeInfos, now)
 	if aErr != nil {
@@ -317,6 +368,7 @@ func (o *ScaleUpOrchestrator) ScaleUp(
 		CreateNodeGroupResults:  createNodeGroupResults,
 		PodsTriggeredScaleUp:    bestOption.Pods,
 		PodsAwaitEvaluation:     GetPodsAwaitingEvaluation(podEquivalenceGroups, bestOption.NodeGroup.Id()),
+		Balancing:               balancing,
 	}, nil
 }
 
@@ -708,6 +760,26 @@ func GetRemainingPods(egs []*equivalence.PodGroup, skipped map[string]status.Rea
 	return remaining
 }
 
+// getRemainingPods returns information about pods that remain unschedulable
+// after a scale-up which didn't happen. In all-or-nothing scale-up all the pods
+// remain unschedulable, even if some of them could be helped.
+func getRemainingPods(egs []*equivalence.PodGroup, skipped map[string]status.Reasons, allOrNothing bool) []status.NoScaleUpInfo {
+	if !allOrNothing {
+		return GetRemainingPods(egs, skipped)
+	}
+	remaining := []status.NoScaleUpInfo{}
+	for _, eg := range egs {
+		for _, pod := range eg.Pods {
+			remaining = append(remaining, status.NoScaleUpInfo{
+				Pod:                pod,
+				RejectedNodeGroups: eg.SchedulingErrors,
+				SkippedNodeGroups:  skipped,
+			})
+		}
+	}
+	return remaining
+}
+
 // GetPodsAwaitingEvaluation returns list of pods for which CA was unable to help
 // this scale up loop (but should be able to help).
 func GetPodsAwaitingEvaluation(egs []*equivalence.PodGroup, bestOption string) []*apiv1.Pod {
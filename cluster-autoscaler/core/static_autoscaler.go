@@ -24,13 +24,16 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/core/noderepair"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/consolidation"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/planner"
 	scaledownstatus "k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
 	"k8s.io/autoscaler/cluster-autoscaler/core/scaleup"
 	orchestrator "k8s.io/autoscaler/cluster-autoscaler/core/scaleup/orchestrator"
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/persistentstate"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
@@ -51,9 +54,16 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	drainabilitymetrics "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/evictionbackoff"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/expendable"
+	drainabilitytrace "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/trace"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/dynamicresources"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/replay"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
 	caerrors "k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	scheduler_utils "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
@@ -89,11 +99,22 @@ type StaticAutoscaler struct {
 	lastScaleDownFailTime   time.Time
 	scaleDownPlanner        scaledown.Planner
 	scaleDownActuator       scaledown.Actuator
+	consolidationPlanner    *consolidation.Planner
+	nodeRepairer            *noderepair.Repairer
+	drainCanceller          *actuation.PendingPodsDrainCanceller
 	scaleUpOrchestrator     scaleup.Orchestrator
 	processors              *ca_processors.AutoscalingProcessors
 	processorCallbacks      *staticAutoscalerProcessorCallbacks
 	initialized             bool
 	taintConfig             taints.TaintConfig
+	dynamicResources        *dynamicresources.Provider
+	stateStore              *persistentstate.Store
+	deleteOptions           options.NodeDeleteOptions
+	// resumedDeletions are the node deletions of a previous run of CA which
+	// are yet to be resumed.
+	resumedDeletions []persistentstate.NodeDeletion
+	// deletionsSince tells since when each saved node deletion is in progress.
+	deletionsSince map[string]time.Time
 }
 
 type staticAutoscalerProcessorCallbacks struct {
@@ -145,7 +166,13 @@ func NewStaticAutoscaler(
 	remainingPdbTracker pdb.RemainingPdbTracker,
 	scaleUpOrchestrator scaleup.Orchestrator,
 	deleteOptions options.NodeDeleteOptions,
-	drainabilityRules rules.Rules) *StaticAutoscaler {
+	drainabilityRules rules.Rules,
+	expendablePods *expendable.Rule,
+	evictionBackoff *evictionbackoff.Ledger,
+	nodeUsage utilization.UsageProvider,
+	dynamicResources *dynamicresources.Provider,
+	podsToMove simulator.PodsToMoveFunc,
+	stateStore *persistentstate.Store) *StaticAutoscaler {
 
 	clusterStateConfig := clusterstate.ClusterStateRegistryConfig{
 		MaxTotalUnreadyPercentage: opts.MaxTotalUnreadyPercentage,
@@ -164,7 +191,11 @@ func NewStaticAutoscaler(
 		processorCallbacks,
 		debuggingSnapshotter,
 		remainingPdbTracker,
-		clusterStateRegistry)
+		clusterStateRegistry,
+		expendablePods,
+		evictionBackoff,
+		nodeUsage,
+		podsToMove)
 
 	taintConfig := taints.NewTaintConfig(opts)
 	processors.ScaleDownCandidatesNotifier.Register(clusterStateRegistry)
@@ -189,6 +220,21 @@ func NewStaticAutoscaler(
 	}
 	processorCallbacks.scaleDownPlanner = scaleDownPlanner
 
+	var consolidationPlanner *consolidation.Planner
+	if opts.EnableScaleDownConsolidation && opts.ScaleDownConsolidationMaxNodes >= 2 {
+		consolidationPlanner = consolidation.New(autoscalingContext, deleteOptions, drainabilityRules, opts.ScaleDownConsolidationMaxNodes)
+	}
+
+	var nodeRepairer *noderepair.Repairer
+	if opts.NodeAutoRepairEnabled {
+		nodeRepairer = noderepair.New(autoscalingContext, clusterStateRegistry, actuator, deleteOptions, drainabilityRules)
+	}
+
+	var drainCanceller *actuation.PendingPodsDrainCanceller
+	if opts.DrainCancellationDelay > 0 {
+		drainCanceller = actuation.NewPendingPodsDrainCanceller(autoscalingContext, actuator.DrainCancellations(), opts.DrainCancellationDelay)
+	}
+
 	if scaleUpOrchestrator == nil {
 		scaleUpOrchestrator = orchestrator.New()
 	}
@@ -204,11 +250,18 @@ func NewStaticAutoscaler(
 		lastScaleDownFailTime:   initialScaleTime,
 		scaleDownPlanner:        scaleDownPlanner,
 		scaleDownActuator:       scaleDownActuator,
+		consolidationPlanner:    consolidationPlanner,
+		nodeRepairer:            nodeRepairer,
+		drainCanceller:          drainCanceller,
 		scaleUpOrchestrator:     scaleUpOrchestrator,
 		processors:              processors,
 		processorCallbacks:      processorCallbacks,
 		clusterStateRegistry:    clusterStateRegistry,
 		taintConfig:             taintConfig,
+		dynamicResources:        dynamicResources,
+		stateStore:              stateStore,
+		deleteOptions:           deleteOptions,
+		deletionsSince:          make(map[string]time.Time),
 	}
 }
 
@@ -219,7 +272,8 @@ func (a *StaticAutoscaler) Start() error {
 }
 
 // cleanUpIfRequired removes ToBeDeleted taints added by a previous run of CA
-// the taints are removed only once per runtime
+// the taints are removed only once per runtime. Taints of node deletions
+// restored from the persisted state are kept, the deletions are resumed.
 func (a *StaticAutoscaler) cleanUpIfRequired() {
 	if a.initialized {
 		return
@@ -229,7 +283,8 @@ func (a *StaticAutoscaler) cleanUpIfRequired() {
 	if allNodes, err := a.AllNodeLister().List(); err != nil {
 		klog.Errorf("Failed to list ready nodes, not cleaning up taints: %v", err)
 	} else {
-		taints.CleanAllToBeDeleted(allNodes,
+		a.restoreState(allNodes)
+		taints.CleanAllToBeDeleted(a.withoutResumedDeletions(allNodes),
 			a.AutoscalingContext.ClientSet, a.Recorder, a.CordonNodeBeforeTerminate)
 		if a.AutoscalingContext.AutoscalingOptions.MaxBulkSoftTaintCount == 0 {
 			// Clean old taints if soft taints handling is disabled
@@ -262,6 +317,19 @@ func (a *StaticAutoscaler) initializeClusterSnapshot(nodes []*apiv1.Node, schedu
 	return nil
 }
 
+func (a *StaticAutoscaler) initializeResourceClaims() caerrors.AutoscalerError {
+	if a.dynamicResources == nil {
+		return nil
+	}
+	claims, err := a.dynamicResources.Claims()
+	if err != nil {
+		klog.Errorf("Failed to list resource claims: %v", err)
+		return caerrors.NewAutoscalerError(caerrors.ApiCallError, err.Error())
+	}
+	a.ClusterSnapshot.SetResourceClaims(claims)
+	return nil
+}
+
 func (a *StaticAutoscaler) initializeRemainingPdbTracker() caerrors.AutoscalerError {
 	a.RemainingPdbTracker.Clear()
 
@@ -281,9 +349,15 @@ func (a *StaticAutoscaler) initializeRemainingPdbTracker() caerrors.AutoscalerEr
 func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerError {
 	a.cleanUpIfRequired()
 	a.processorCallbacks.reset()
+	a.AutoscalingContext.LoopStartTime = currentTime
+	if a.deleteOptions.Reloader != nil {
+		a.deleteOptions.Reloader.Refresh()
+	}
 	a.clusterStateRegistry.PeriodicCleanup()
 	a.DebuggingSnapshotter.StartDataCollection()
 	defer a.DebuggingSnapshotter.Flush()
+	defer drainabilitymetrics.ObserveLoopEvaluationDuration()
+	defer drainabilitytrace.Flush()
 
 	podLister := a.AllPodLister()
 	autoscalingContext := a.AutoscalingContext
@@ -293,7 +367,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 	stateUpdateStart := time.Now()
 
 	// Get nodes and pods currently living on cluster
-	allNodes, readyNodes, typedErr := a.obtainNodeLists(a.CloudProvider)
+	allNodes, readyNodes, typedErr := a.obtainNodeLists(currentTime)
 	if typedErr != nil {
 		klog.Errorf("Failed to get node list: %v", typedErr)
 		return typedErr
@@ -344,11 +418,14 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 	} else {
 		metrics.UpdateMaxNodesCount(maxNodesCount)
 	}
-	nonExpendableScheduledPods := core_utils.FilterOutExpendablePods(originalScheduledPods, a.ExpendablePodsPriorityCutoff)
+	nonExpendableScheduledPods := core_utils.FilterOutExpendablePods(originalScheduledPods, a.ExpendablePods)
 	// Initialize cluster state to ClusterSnapshot
 	if typedErr := a.initializeClusterSnapshot(allNodes, nonExpendableScheduledPods); typedErr != nil {
 		return typedErr.AddPrefix("failed to initialize ClusterSnapshot: ")
 	}
+	if typedErr := a.initializeResourceClaims(); typedErr != nil {
+		return typedErr.AddPrefix("failed to initialize resource claims: ")
+	}
 	// Initialize Pod Disruption Budget tracking
 	if typedErr := a.initializeRemainingPdbTracker(); typedErr != nil {
 		return typedErr.AddPrefix("failed to initialize RemainingPdbTracker: ")
@@ -372,6 +449,11 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		klog.Errorf("Failed to update cluster state: %v", typedErr)
 		return typedErr
 	}
+	if len(a.resumedDeletions) > 0 {
+		a.resumeNodeDeletions(allNodes, currentTime)
+		// Resumed deletions are in progress, so they aren't planned again.
+		scaleDownActuationStatus = a.scaleDownActuator.CheckStatus()
+	}
 	metrics.UpdateDurationFromStart(metrics.UpdateState, stateUpdateStart)
 
 	scaleUpStatus := &status.ScaleUpStatus{Result: status.ScaleUpNotTried}
@@ -386,6 +468,9 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 			utils.WriteStatusConfigMap(autoscalingContext.ClientSet, autoscalingContext.ConfigNamespace,
 				status.GetReadableString(), a.AutoscalingContext.LogRecorder, a.AutoscalingContext.StatusConfigMapName)
 		}
+		if a.stateStore != nil {
+			a.saveState(currentTime)
+		}
 
 		// This deferred processor execution allows the processors to handle a situation when a scale-(up|down)
 		// wasn't even attempted because e.g. the iteration exited earlier.
@@ -394,6 +479,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		}
 		if !scaleDownStatusProcessorAlreadyCalled && a.processors != nil && a.processors.ScaleDownStatusProcessor != nil {
 			scaleDownStatus.SetUnremovableNodesInfo(a.scaleDownPlanner.UnremovableNodes(), a.scaleDownPlanner.NodeUtilizationMap(), a.CloudProvider)
+			scaleDownStatus.SetUnneededNodesInfo(a.scaleDownPlanner.UnneededNodes(), a.scaleDownPlanner.NodeUtilizationMap(), a.CloudProvider)
 			a.processors.ScaleDownStatusProcessor.Process(a.AutoscalingContext, scaleDownStatus)
 		}
 
@@ -436,6 +522,28 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		return nil
 	}
 
+	if nodesToReplace := a.processors.NodeReadinessProcessor.NodesToReplace(); len(nodesToReplace) > 0 {
+		removedAny, err := a.removeNodesFailingReadiness(nodesToReplace)
+		if err != nil {
+			klog.Warningf("Failed to remove nodes that didn't become ready: %v", err)
+		}
+		if removedAny {
+			klog.V(0).Infof("Some nodes that didn't become ready were removed, skipping iteration")
+			return nil
+		}
+	}
+
+	if a.nodeRepairer != nil {
+		repairedAny, err := a.nodeRepairer.RunOnce(allNodes, currentTime)
+		if err != nil {
+			klog.Warningf("Failed to repair unhealthy nodes: %v", err)
+		}
+		if repairedAny {
+			klog.V(0).Infof("Some unhealthy nodes are being repaired, skipping iteration")
+			return nil
+		}
+	}
+
 	// Check if there has been a constant difference between the number of nodes in k8s and
 	// the number of nodes on the cloud provider side.
 	// TODO: andrewskim - add protection for ready AWS nodes.
@@ -458,7 +566,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 	// todo: move split and append below to separate PodListProcessor
 	// Some unschedulable pods can be waiting for lower priority pods preemption so they have nominated node to run.
 	// Such pods don't require scale up but should be considered during scale down.
-	unschedulablePods, unschedulableWaitingForLowerPriorityPreemption := core_utils.FilterOutExpendableAndSplit(unschedulablePods, allNodes, a.ExpendablePodsPriorityCutoff)
+	unschedulablePods, unschedulableWaitingForLowerPriorityPreemption := core_utils.FilterOutExpendableAndSplit(unschedulablePods, allNodes, a.ExpendablePods)
 
 	// modify the snapshot simulating scheduling of pods waiting for preemption.
 	// this is not strictly correct as we are not simulating preemption itself but it matches
@@ -515,6 +623,10 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		a.AutoscalingContext.DebuggingSnapshotter.SetClusterNodes(l)
 	}
 
+	if a.drainCanceller != nil {
+		a.drainCanceller.Update(unschedulablePods, currentTime)
+	}
+
 	unschedulablePodsToHelp, _ := a.processors.PodListProcessor.Process(a.AutoscalingContext, unschedulablePods)
 
 	// finally, filter out pods that are too "young" to safely be considered for a scale-up (delay is configurable)
@@ -563,7 +675,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 		klog.V(1).Info("Unschedulable pods are very new, waiting one iteration for more")
 	} else {
 		scaleUpStart := preScaleUp()
-		scaleUpStatus, typedErr = a.scaleUpOrchestrator.ScaleUp(unschedulablePodsToHelp, readyNodes, daemonsets, nodeInfosForGroups)
+		scaleUpStatus, typedErr = a.scaleUpOrchestrator.ScaleUp(unschedulablePodsToHelp, readyNodes, daemonsets, nodeInfosForGroups, false)
 		if exit, err := postScaleUp(scaleUpStart); exit {
 			return err
 		}
@@ -601,6 +713,10 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 			}
 		}
 
+		if a.ScaleDownRecordingFile != "" {
+			a.recordScaleDown(scaleDownCandidates, podDestinations, currentTime)
+		}
+
 		typedErr := a.scaleDownPlanner.UpdateClusterState(podDestinations, scaleDownCandidates, scaleDownActuationStatus, currentTime)
 		// Update clusterStateRegistry and metrics regardless of whether ScaleDown was successful or not.
 		unneededNodes := a.scaleDownPlanner.UnneededNodes()
@@ -614,6 +730,10 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 
 		metrics.UpdateDurationFromStart(metrics.FindUnneeded, unneededStart)
 
+		if a.consolidationPlanner != nil {
+			a.planConsolidation(scaleDownCandidates, podDestinations, unneededNodes, nodeInfosForGroups, currentTime)
+		}
+
 		scaleDownInCooldown := a.processorCallbacks.disableScaleDownForLoop ||
 			a.lastScaleUpTime.Add(a.ScaleDownDelayAfterAdd).After(currentTime) ||
 			a.lastScaleDownFailTime.Add(a.ScaleDownDelayAfterFailure).After(currentTime) ||
@@ -653,6 +773,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 			a.scaleDownActuator.ClearResultsNotNewerThan(scaleDownStatus.NodeDeleteResultsAsOf)
 			metrics.UpdateDurationFromStart(metrics.ScaleDown, scaleDownStart)
 			metrics.UpdateUnremovableNodesCount(countsByReason(a.scaleDownPlanner.UnremovableNodes()))
+			metrics.UpdateUnremovableNodesMaxDuration(maxDurationsByReason(a.scaleDownPlanner.UnremovableNodes(), currentTime))
 
 			scaleDownStatus.RemovedNodeGroups = removedNodeGroups
 
@@ -671,6 +792,7 @@ func (a *StaticAutoscaler) RunOnce(currentTime time.Time) caerrors.AutoscalerErr
 
 			if a.processors != nil && a.processors.ScaleDownStatusProcessor != nil {
 				scaleDownStatus.SetUnremovableNodesInfo(a.scaleDownPlanner.UnremovableNodes(), a.scaleDownPlanner.NodeUtilizationMap(), a.CloudProvider)
+				scaleDownStatus.SetUnneededNodesInfo(a.scaleDownPlanner.UnneededNodes(), a.scaleDownPlanner.NodeUtilizationMap(), a.CloudProvider)
 				a.processors.ScaleDownStatusProcessor.Process(autoscalingContext, scaleDownStatus)
 				scaleDownStatusProcessorAlreadyCalled = true
 			}
@@ -882,6 +1004,69 @@ func (a *StaticAutoscaler) deleteCreatedNodesWithErrors() (bool, error) {
 	return deletedAny, nil
 }
 
+// removeNodesFailingReadiness removes nodes that didn't pass readiness gates
+// within the readiness timeout of their node group, so that they are replaced
+// in a following scale-up. Returns true if anything was removed and error if
+// such occurred.
+func (a *StaticAutoscaler) removeNodesFailingReadiness(nodes []*apiv1.Node) (bool, error) {
+	nodeGroups := a.nodeGroupsById()
+	nodesToBeDeletedByNodeGroupId := make(map[string][]*apiv1.Node)
+	for _, node := range nodes {
+		nodeGroup, err := a.CloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			klog.Warningf("Failed to get node group for %s: %v", node.Name, err)
+			continue
+		}
+		if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+			klog.Warningf("No node group for node %s, skipping", node.Name)
+			continue
+		}
+		nodesToBeDeletedByNodeGroupId[nodeGroup.Id()] = append(nodesToBeDeletedByNodeGroupId[nodeGroup.Id()], node)
+	}
+
+	removedAny := false
+	for nodeGroupId, nodesToDelete := range nodesToBeDeletedByNodeGroupId {
+		nodeGroup := nodeGroups[nodeGroupId]
+		if nodeGroup == nil {
+			klog.Warningf("Node group %s not found, skipping removal of %v nodes that didn't become ready", nodeGroupId, len(nodesToDelete))
+			continue
+		}
+
+		klog.V(0).Infof("Removing %v nodes that didn't become ready from node group %v", len(nodesToDelete), nodeGroupId)
+		size, err := nodeGroup.TargetSize()
+		if err != nil {
+			klog.Warningf("Failed to get node group size; nodeGroup=%v; err=%v", nodeGroup.Id(), err)
+			continue
+		}
+		possibleToDelete := size - nodeGroup.MinSize()
+		if possibleToDelete <= 0 {
+			klog.Warningf("Node group %s min size reached, skipping removal of %v nodes that didn't become ready", nodeGroupId, len(nodesToDelete))
+			continue
+		}
+		if len(nodesToDelete) > possibleToDelete {
+			klog.Warningf("Capping node group %s removal of nodes that didn't become ready to %d nodes, removing all %d would exceed min size constaint", nodeGroupId, possibleToDelete, len(nodesToDelete))
+			nodesToDelete = nodesToDelete[:possibleToDelete]
+		}
+
+		err = nodeGroup.DeleteNodes(nodesToDelete)
+		a.clusterStateRegistry.InvalidateNodeInstancesCacheEntry(nodeGroup)
+		if err != nil {
+			klog.Warningf("Failed to remove %v nodes that didn't become ready from node group %s: %v", len(nodesToDelete), nodeGroupId, err)
+			for _, node := range nodesToDelete {
+				a.LogRecorder.Eventf(apiv1.EventTypeWarning, "DeleteNotReadyFailed",
+					"Failed to remove node %s: %v", node.Name, err)
+			}
+			return removedAny, err
+		}
+		for _, node := range nodesToDelete {
+			a.LogRecorder.Eventf(apiv1.EventTypeNormal, "DeleteNotReady",
+				"Removed node %v that didn't pass readiness gates", node.Name)
+		}
+		removedAny = true
+	}
+	return removedAny, nil
+}
+
 // instancesToNodes returns a list of fake nodes with just names populated,
 // so that they can be passed as nodes to delete
 func instancesToFakeNodes(instances []cloudprovider.Instance) []*apiv1.Node {
@@ -944,7 +1129,7 @@ func (a *StaticAutoscaler) ExitCleanUp() {
 	a.clusterStateRegistry.Stop()
 }
 
-func (a *StaticAutoscaler) obtainNodeLists(cp cloudprovider.CloudProvider) ([]*apiv1.Node, []*apiv1.Node, caerrors.AutoscalerError) {
+func (a *StaticAutoscaler) obtainNodeLists(currentTime time.Time) ([]*apiv1.Node, []*apiv1.Node, caerrors.AutoscalerError) {
 	allNodes, err := a.AllNodeLister().List()
 	if err != nil {
 		klog.Errorf("Failed to list all nodes: %v", err)
@@ -963,6 +1148,7 @@ func (a *StaticAutoscaler) obtainNodeLists(cp cloudprovider.CloudProvider) ([]*a
 	// TODO: Remove this call when we handle dynamically provisioned resources.
 	allNodes, readyNodes = a.processors.CustomResourcesProcessor.FilterOutNodesWithUnreadyResources(a.AutoscalingContext, allNodes, readyNodes)
 	allNodes, readyNodes = taints.FilterOutNodesWithStartupTaints(a.taintConfig, allNodes, readyNodes)
+	allNodes, readyNodes = a.processors.NodeReadinessProcessor.FilterOutUnreadyNodes(a.AutoscalingContext, allNodes, readyNodes, currentTime)
 	return allNodes, readyNodes, nil
 }
 
@@ -1038,6 +1224,63 @@ func countsByReason(nodes []*simulator.UnremovableNode) map[simulator.Unremovabl
 	return counts
 }
 
+func maxDurationsByReason(nodes []*simulator.UnremovableNode, now time.Time) map[simulator.UnremovableReason]time.Duration {
+	durations := make(map[simulator.UnremovableReason]time.Duration)
+
+	for _, node := range nodes {
+		if node.UnremovableSince.IsZero() {
+			continue
+		}
+		// Recently unremovable nodes are reported for the reason they were
+		// last found unremovable for.
+		reason := node.Reason
+		if len(node.ReasonHistory) > 0 {
+			reason = node.ReasonHistory[len(node.ReasonHistory)-1].Reason
+		}
+		if duration := now.Sub(node.UnremovableSince); duration > durations[reason] {
+			durations[reason] = duration
+		}
+	}
+
+	return durations
+}
+
+// planConsolidation looks for underutilized nodes that could be replaced with
+// a single larger node. Consolidations aren't actuated yet, only logged.
+// recordScaleDown writes the state scale-down simulation is about to run
+// against to ScaleDownRecordingFile, for offline replay.
+func (a *StaticAutoscaler) recordScaleDown(scaleDownCandidates, podDestinations []*apiv1.Node, currentTime time.Time) {
+	recording, err := replay.Record(a.ClusterSnapshot, a.ListerRegistry, a.AutoscalingOptions.NodeDeleteOptions(), scaleDownCandidates, podDestinations, currentTime)
+	if err != nil {
+		klog.Errorf("Failed to record scale down state: %v", err)
+		return
+	}
+	recording.IgnoreDaemonSetsUtilization = a.NodeGroupDefaults.IgnoreDaemonSetsUtilization
+	recording.IgnoreMirrorPodsUtilization = a.IgnoreMirrorPodsUtilization
+	if err := replay.WriteFile(a.ScaleDownRecordingFile, recording); err != nil {
+		klog.Errorf("Failed to write scale down recording to %s: %v", a.ScaleDownRecordingFile, err)
+	}
+}
+
+func (a *StaticAutoscaler) planConsolidation(scaleDownCandidates, podDestinations, unneededNodes []*apiv1.Node, nodeInfosForGroups map[string]*schedulerframework.NodeInfo, currentTime time.Time) {
+	// Unneeded nodes are going to be removed by regular scale down anyway.
+	candidates := consolidation.SortByUtilization(subtractNodes(scaleDownCandidates, unneededNodes), a.scaleDownPlanner.NodeUtilizationMap())
+	plan, err := a.consolidationPlanner.Plan(candidates, subtractNodes(podDestinations, unneededNodes), nodeInfosForGroups, currentTime)
+	if err != nil {
+		klog.Errorf("Failed to plan consolidation: %v", err)
+		return
+	}
+	if plan == nil {
+		klog.V(4).Infof("No consolidation opportunities found")
+		return
+	}
+	names := make([]string, 0, len(plan.NodesToRemove))
+	for _, node := range plan.NodesToRemove {
+		names = append(names, node.Name)
+	}
+	klog.V(1).Infof("Nodes %v can be replaced with a single node from node group %s, saving %.2f", names, plan.NodeGroup.Id(), plan.Savings)
+}
+
 func subtractNodesByName(nodes []*apiv1.Node, namesToRemove []string) []*apiv1.Node {
 	var c []*apiv1.Node
 	removeSet := make(map[string]bool)
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/actuation"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/persistentstate"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	klog "k8s.io/klog/v2"
+)
+
+// restoreState restores node group backoffs and node deletions saved by a
+// previous run of CA. Deletions are resumed only if their node still exists
+// and is still tainted, as the taint may have been removed in the meantime.
+func (a *StaticAutoscaler) restoreState(allNodes []*apiv1.Node) {
+	if a.stateStore == nil {
+		return
+	}
+	state, err := a.stateStore.Load()
+	if err != nil {
+		klog.Errorf("Failed to load persisted state, starting from scratch: %v", err)
+		return
+	}
+	if len(state.NodeGroupBackoffs) > 0 && !a.clusterStateRegistry.RestoreBackoff(state.NodeGroupBackoffs) {
+		klog.Warningf("Node group backoff can't be restored, dropping %d persisted backoffs", len(state.NodeGroupBackoffs))
+	}
+	nodes := make(map[string]*apiv1.Node, len(allNodes))
+	for _, node := range allNodes {
+		nodes[node.Name] = node
+	}
+	for _, deletion := range state.NodeDeletions {
+		if node, found := nodes[deletion.Node]; !found || !taints.HasToBeDeletedTaint(node) {
+			klog.V(1).Infof("Not resuming deletion of node %s, it's gone or not tainted anymore", deletion.Node)
+			continue
+		}
+		a.resumedDeletions = append(a.resumedDeletions, deletion)
+		a.deletionsSince[deletion.Node] = deletion.Since
+	}
+	klog.V(1).Infof("Restored %d node group backoffs and %d node deletions", len(state.NodeGroupBackoffs), len(a.resumedDeletions))
+}
+
+// withoutResumedDeletions returns the nodes, except the ones whose deletion
+// is yet to be resumed.
+func (a *StaticAutoscaler) withoutResumedDeletions(nodes []*apiv1.Node) []*apiv1.Node {
+	if len(a.resumedDeletions) == 0 {
+		return nodes
+	}
+	resumed := make(map[string]bool, len(a.resumedDeletions))
+	for _, deletion := range a.resumedDeletions {
+		resumed[deletion.Node] = true
+	}
+	var result []*apiv1.Node
+	for _, node := range nodes {
+		if !resumed[node.Name] {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// resumeNodeDeletions drains and deletes the nodes whose deletion was
+// restored. Nodes which don't fit the deletion budgets, or whose deletion has
+// been in progress for longer than a deletion can take, are untainted, so that
+// they are simulated again like any other node. Otherwise a drain which keeps
+// failing would be resumed after every restart forever.
+func (a *StaticAutoscaler) resumeNodeDeletions(allNodes []*apiv1.Node, currentTime time.Time) {
+	var nodes, stale []*apiv1.Node
+	for _, node := range allNodes {
+		for _, deletion := range a.resumedDeletions {
+			if node.Name != deletion.Node || !taints.HasToBeDeletedTaint(node) {
+				continue
+			}
+			if currentTime.Sub(deletion.Since) > a.maxNodeDeletionDuration(node) {
+				klog.V(1).Infof("Deletion of node %s is in progress since %v, giving up on it and removing its taint", node.Name, deletion.Since)
+				stale = append(stale, node)
+			} else {
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	a.resumedDeletions = nil
+	for _, node := range stale {
+		if _, err := taints.CleanToBeDeleted(node, a.ClientSet, a.CordonNodeBeforeTerminate); err != nil {
+			klog.Errorf("Failed to remove taint from node %s: %v", node.Name, err)
+		}
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	klog.V(1).Infof("Resuming deletion of %d nodes started by a previous run", len(nodes))
+	// Nodes may have been partially drained, so they are all drained again.
+	if _, err := a.AutoscalingContext.ScaleDownActuator.StartDeletion(nil, nodes); err != nil {
+		klog.Errorf("Failed to resume node deletions: %v", err)
+	}
+	empty, drained := a.AutoscalingContext.ScaleDownActuator.CheckStatus().DeletionsInProgress()
+	inProgress := make(map[string]bool, len(empty)+len(drained))
+	for _, name := range append(empty, drained...) {
+		inProgress[name] = true
+	}
+	for _, node := range nodes {
+		if inProgress[node.Name] {
+			continue
+		}
+		klog.V(1).Infof("Deletion of node %s wasn't resumed, removing its taint", node.Name)
+		if _, err := taints.CleanToBeDeleted(node, a.ClientSet, a.CordonNodeBeforeTerminate); err != nil {
+			klog.Errorf("Failed to remove taint from node %s: %v", node.Name, err)
+		}
+	}
+}
+
+// maxNodeDeletionDuration returns how long the deletion of the node can take
+// with the timeouts configured for it: evicting the pods, waiting for them to
+// terminate and waiting for the removal of delay-deletion annotations. Like in
+// actuation, the max graceful termination of the node group of the node, and
+// node delete options reloaded for the node are taken into account.
+func (a *StaticAutoscaler) maxNodeDeletionDuration(node *apiv1.Node) time.Duration {
+	deleteOptions := a.deleteOptions.ForNode(node)
+	terminationSec := deleteOptions.MaxGracefulTerminationSec
+	if a.processors != nil && a.processors.NodeGroupConfigProcessor != nil {
+		terminationSec = nodegroupconfig.GetMaxGracefulTerminationSecForNode(a.CloudProvider, a.processors.NodeGroupConfigProcessor, node, terminationSec)
+	}
+	terminationSec = deleteOptions.MaxGracefulTerminationSecForOS(node, terminationSec)
+	termination := time.Duration(terminationSec)*time.Second + actuation.DefaultPodEvictionHeadroom
+	if deleteOptions.NodeDrainTimeout > 0 {
+		termination = deleteOptions.NodeDrainTimeout
+	}
+	return a.MaxPodEvictionTime + termination + a.NodeDeletionDelayTimeout
+}
+
+// saveState saves node group backoffs and node deletions in progress,
+// including restored ones which are yet to be resumed.
+func (a *StaticAutoscaler) saveState(currentTime time.Time) {
+	empty, drained := a.AutoscalingContext.ScaleDownActuator.CheckStatus().DeletionsInProgress()
+	names := append(empty, drained...)
+	for _, deletion := range a.resumedDeletions {
+		names = append(names, deletion.Node)
+	}
+	sort.Strings(names)
+	since := make(map[string]time.Time, len(names))
+	state := &persistentstate.State{
+		NodeGroupBackoffs: a.clusterStateRegistry.BackoffEntries(),
+	}
+	for _, name := range names {
+		if _, found := since[name]; found {
+			continue
+		}
+		since[name] = currentTime
+		if s, found := a.deletionsSince[name]; found {
+			since[name] = s
+		}
+		state.NodeDeletions = append(state.NodeDeletions, persistentstate.NodeDeletion{Node: name, Since: since[name]})
+	}
+	a.deletionsSince = since
+	if err := a.stateStore.Save(state); err != nil {
+		klog.Errorf("Failed to save state: %v", err)
+	}
+}
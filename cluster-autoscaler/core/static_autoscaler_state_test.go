@@ -0,0 +1,183 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	ctx "context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	. "k8s.io/autoscaler/cluster-autoscaler/core/test"
+	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/persistentstate"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// limitedActuator starts deletions of up to limit nodes.
+type limitedActuator struct {
+	scaledown.Actuator
+	ndt   *deletiontracker.NodeDeletionTracker
+	limit int
+}
+
+func (a *limitedActuator) StartDeletion(empty, drain []*apiv1.Node) (*status.ScaleDownStatus, errors.AutoscalerError) {
+	for i, node := range drain {
+		if i < a.limit {
+			a.ndt.StartDeletionWithDrain("ng1", node.Name)
+		}
+	}
+	return &status.ScaleDownStatus{}, nil
+}
+
+func (a *limitedActuator) CheckStatus() scaledown.ActuationStatus {
+	return a.ndt.Snapshot()
+}
+
+func TestPersistentState(t *testing.T) {
+	restarted := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	since := restarted.Add(-5 * time.Minute)
+	tainted := func(name string) *apiv1.Node {
+		node := BuildTestNode(name, 1000, 1000)
+		node.Spec.Taints = []apiv1.Taint{{Key: taints.ToBeDeletedTaint, Effect: apiv1.TaintEffectNoSchedule}}
+		return node
+	}
+	resumed := tainted("resumed")
+	overBudget := tainted("over-budget")
+	untainted := BuildTestNode("untainted", 1000, 1000)
+	notSaved := tainted("not-saved")
+	stale := tainted("stale")
+	// The stale deletion would be resumed first, leaving the others over the budget.
+	nodes := []*apiv1.Node{stale, resumed, overBudget, untainted, notSaved}
+	backoffs := []backoff.Entry{{Key: "ng1", Duration: time.Minute, BackoffUntil: restarted.Add(time.Minute), LastFailedExecution: restarted}}
+
+	client := fake.NewSimpleClientset(resumed, overBudget, untainted, notSaved, stale)
+	assert.NoError(t, persistentstate.NewStore(client, "kube-system", "state").Save(&persistentstate.State{
+		NodeDeletions: []persistentstate.NodeDeletion{
+			{Node: "gone", Since: since},
+			{Node: "over-budget", Since: since},
+			{Node: "resumed", Since: since},
+			{Node: "stale", Since: restarted.Add(-time.Hour)},
+			{Node: "untainted", Since: since},
+		},
+		NodeGroupBackoffs: backoffs,
+	}))
+
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroup("ng1", 0, 10, 4)
+	csr := clusterstate.NewClusterStateRegistry(provider, clusterstate.ClusterStateRegistryConfig{}, nil, NewBackoff(), nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{}))
+	actuator := &limitedActuator{ndt: deletiontracker.NewNodeDeletionTracker(0), limit: 1}
+	store := persistentstate.NewStore(client, "kube-system", "state")
+	a := &StaticAutoscaler{
+		AutoscalingContext: &context.AutoscalingContext{
+			// A deletion can take up to 10m30s.
+			AutoscalingOptions:     config.AutoscalingOptions{MaxPodEvictionTime: 2 * time.Minute, MaxGracefulTerminationSec: 480},
+			AutoscalingKubeClients: context.AutoscalingKubeClients{ClientSet: client},
+			CloudProvider:          provider,
+			ScaleDownActuator:      actuator,
+		},
+		clusterStateRegistry: csr,
+		processors:           &ca_processors.AutoscalingProcessors{NodeGroupConfigProcessor: nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{})},
+		stateStore:           store,
+		deleteOptions:        options.NodeDeleteOptions{MaxGracefulTerminationSec: 480},
+		deletionsSince:       make(map[string]time.Time),
+	}
+
+	a.restoreState(nodes)
+	assert.Equal(t, backoffs, csr.BackoffEntries())
+	assert.Equal(t, []persistentstate.NodeDeletion{{Node: "over-budget", Since: since}, {Node: "resumed", Since: since}, {Node: "stale", Since: restarted.Add(-time.Hour)}}, a.resumedDeletions)
+	assert.Equal(t, []*apiv1.Node{untainted, notSaved}, a.withoutResumedDeletions(nodes))
+
+	// Deletions which aren't resumed yet are still saved.
+	a.saveState(restarted)
+	state, err := persistentstate.NewStore(client, "kube-system", "state").Load()
+	assert.NoError(t, err)
+	assert.Equal(t, &persistentstate.State{
+		NodeDeletions:     []persistentstate.NodeDeletion{{Node: "over-budget", Since: since}, {Node: "resumed", Since: since}, {Node: "stale", Since: restarted.Add(-time.Hour)}},
+		NodeGroupBackoffs: backoffs,
+	}, state)
+
+	a.resumeNodeDeletions(nodes, restarted)
+	assert.Empty(t, a.resumedDeletions)
+	_, drained := actuator.CheckStatus().DeletionsInProgress()
+	assert.Equal(t, []string{"resumed"}, drained)
+	node, err := client.CoreV1().Nodes().Get(ctx.TODO(), "resumed", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, taints.HasToBeDeletedTaint(node))
+	// Nodes over the deletion budgets are untainted.
+	node, err = client.CoreV1().Nodes().Get(ctx.TODO(), "over-budget", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, taints.HasToBeDeletedTaint(node))
+	// Deletions in progress for longer than a deletion can take aren't resumed again.
+	node, err = client.CoreV1().Nodes().Get(ctx.TODO(), "stale", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.False(t, taints.HasToBeDeletedTaint(node))
+
+	// New deletions are saved since the time they were first seen.
+	actuator.ndt.StartDeletion("ng1", "new")
+	a.saveState(restarted.Add(time.Minute))
+	state, err = persistentstate.NewStore(client, "kube-system", "state").Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []persistentstate.NodeDeletion{{Node: "new", Since: restarted.Add(time.Minute)}, {Node: "resumed", Since: since}}, state.NodeDeletions)
+}
+
+func TestResumeNodeDeletionsNodeGroupMaxGracefulTermination(t *testing.T) {
+	restarted := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	node := BuildTestNode("db", 1000, 1000)
+	node.Spec.Taints = []apiv1.Taint{{Key: taints.ToBeDeletedTaint, Effect: apiv1.TaintEffectNoSchedule}}
+	client := fake.NewSimpleClientset(node)
+
+	// The node group gives pods 2h to terminate, much longer than the global 8m.
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	provider.AddNodeGroupWithCustomOptions("db-pool", 0, 10, 1, &config.NodeGroupAutoscalingOptions{MaxGracefulTerminationSec: 7200})
+	provider.AddNode("db-pool", node)
+	actuator := &limitedActuator{ndt: deletiontracker.NewNodeDeletionTracker(0), limit: 1}
+	a := &StaticAutoscaler{
+		AutoscalingContext: &context.AutoscalingContext{
+			AutoscalingOptions:     config.AutoscalingOptions{MaxPodEvictionTime: 2 * time.Minute, MaxGracefulTerminationSec: 480},
+			AutoscalingKubeClients: context.AutoscalingKubeClients{ClientSet: client},
+			CloudProvider:          provider,
+			ScaleDownActuator:      actuator,
+		},
+		processors:       &ca_processors.AutoscalingProcessors{NodeGroupConfigProcessor: nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{})},
+		deleteOptions:    options.NodeDeleteOptions{MaxGracefulTerminationSec: 480},
+		resumedDeletions: []persistentstate.NodeDeletion{{Node: "db", Since: restarted.Add(-time.Hour)}},
+		deletionsSince:   map[string]time.Time{"db": restarted.Add(-time.Hour)},
+	}
+
+	a.resumeNodeDeletions([]*apiv1.Node{node}, restarted)
+	_, drained := actuator.CheckStatus().DeletionsInProgress()
+	assert.Equal(t, []string{"db"}, drained)
+	node, err := client.CoreV1().Nodes().Get(ctx.TODO(), "db", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.True(t, taints.HasToBeDeletedTaint(node))
+}
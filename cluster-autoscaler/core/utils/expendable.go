@@ -18,13 +18,14 @@ package utils
 
 import (
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/expendable"
 	klog "k8s.io/klog/v2"
 )
 
 // FilterOutExpendableAndSplit filters out expendable pods and splits into:
 //   - waiting for lower priority pods preemption
 //   - other pods.
-func FilterOutExpendableAndSplit(unschedulableCandidates []*apiv1.Pod, nodes []*apiv1.Node, expendablePodsPriorityCutoff int) ([]*apiv1.Pod, []*apiv1.Pod) {
+func FilterOutExpendableAndSplit(unschedulableCandidates []*apiv1.Pod, nodes []*apiv1.Node, expendablePods *expendable.Rule) ([]*apiv1.Pod, []*apiv1.Pod) {
 	var unschedulableNonExpendable []*apiv1.Pod
 	var waitingForLowerPriorityPreemption []*apiv1.Pod
 
@@ -34,8 +35,8 @@ func FilterOutExpendableAndSplit(unschedulableCandidates []*apiv1.Pod, nodes []*
 	}
 
 	for _, pod := range unschedulableCandidates {
-		if pod.Spec.Priority != nil && int(*pod.Spec.Priority) < expendablePodsPriorityCutoff {
-			klog.V(4).Infof("Pod %s has priority below %d (%d) and will scheduled when enough resources is free. Ignoring in scale up.", pod.Name, expendablePodsPriorityCutoff, *pod.Spec.Priority)
+		if expendablePods.IsExpendable(pod) {
+			klog.V(4).Infof("Pod %s is expendable and will scheduled when enough resources is free. Ignoring in scale up.", pod.Name)
 		} else if nominatedNodeName := pod.Status.NominatedNodeName; nominatedNodeName != "" {
 			if nodeNames[nominatedNodeName] {
 				klog.V(4).Infof("Pod %s will be scheduled after low priority pods are preempted on %s. Ignoring in scale up.", pod.Name, nominatedNodeName)
@@ -52,10 +53,10 @@ func FilterOutExpendableAndSplit(unschedulableCandidates []*apiv1.Pod, nodes []*
 }
 
 // FilterOutExpendablePods filters out expendable pods.
-func FilterOutExpendablePods(pods []*apiv1.Pod, expendablePodsPriorityCutoff int) []*apiv1.Pod {
+func FilterOutExpendablePods(pods []*apiv1.Pod, expendablePods *expendable.Rule) []*apiv1.Pod {
 	var result []*apiv1.Pod
 	for _, pod := range pods {
-		if !IsExpendablePod(pod, expendablePodsPriorityCutoff) {
+		if !expendablePods.IsExpendable(pod) {
 			result = append(result, pod)
 		}
 	}
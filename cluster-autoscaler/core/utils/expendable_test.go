@@ -19,6 +19,7 @@ package utils
 import (
 	"testing"
 
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/expendable"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 
 	"github.com/stretchr/testify/assert"
@@ -43,7 +44,7 @@ func TestFilterOutExpendableAndSplit(t *testing.T) {
 	podWaitingForPreemption2.Spec.Priority = &priority100
 	podWaitingForPreemption2.Status.NominatedNodeName = "node2"
 
-	res1, res2 := FilterOutExpendableAndSplit([]*apiv1.Pod{p1, p2, podWaitingForPreemption1, podWaitingForPreemption2}, []*apiv1.Node{n1, n2}, 0)
+	res1, res2 := FilterOutExpendableAndSplit([]*apiv1.Pod{p1, p2, podWaitingForPreemption1, podWaitingForPreemption2}, []*apiv1.Node{n1, n2}, expendable.New(0, nil))
 	assert.Equal(t, 2, len(res1))
 	assert.Equal(t, p1, res1[0])
 	assert.Equal(t, p2, res1[1])
@@ -51,14 +52,14 @@ func TestFilterOutExpendableAndSplit(t *testing.T) {
 	assert.Equal(t, podWaitingForPreemption1, res2[0])
 	assert.Equal(t, podWaitingForPreemption2, res2[1])
 
-	res1, res2 = FilterOutExpendableAndSplit([]*apiv1.Pod{p1, p2, podWaitingForPreemption1, podWaitingForPreemption2}, []*apiv1.Node{n1, n2}, 10)
+	res1, res2 = FilterOutExpendableAndSplit([]*apiv1.Pod{p1, p2, podWaitingForPreemption1, podWaitingForPreemption2}, []*apiv1.Node{n1, n2}, expendable.New(10, nil))
 	assert.Equal(t, 1, len(res1))
 	assert.Equal(t, p2, res1[0])
 	assert.Equal(t, 1, len(res2))
 	assert.Equal(t, podWaitingForPreemption2, res2[0])
 
 	// if node2 is missing podWaitingForPreemption2 should be treated as standard pod not one waiting for preemption
-	res1, res2 = FilterOutExpendableAndSplit([]*apiv1.Pod{p1, p2, podWaitingForPreemption1, podWaitingForPreemption2}, []*apiv1.Node{n1}, 0)
+	res1, res2 = FilterOutExpendableAndSplit([]*apiv1.Pod{p1, p2, podWaitingForPreemption1, podWaitingForPreemption2}, []*apiv1.Node{n1}, expendable.New(0, nil))
 	assert.Equal(t, 3, len(res1))
 	assert.Equal(t, p1, res1[0])
 	assert.Equal(t, p2, res1[1])
@@ -81,7 +82,7 @@ func TestFilterOutExpendablePods(t *testing.T) {
 	podWaitingForPreemption2.Spec.Priority = &priority2
 	podWaitingForPreemption2.Status.NominatedNodeName = "node1"
 
-	res := FilterOutExpendablePods([]*apiv1.Pod{p1, p2, podWaitingForPreemption1, podWaitingForPreemption2}, 0)
+	res := FilterOutExpendablePods([]*apiv1.Pod{p1, p2, podWaitingForPreemption1, podWaitingForPreemption2}, expendable.New(0, nil))
 	assert.Equal(t, 3, len(res))
 	assert.Equal(t, p1, res[0])
 	assert.Equal(t, p2, res[1])
@@ -65,6 +65,8 @@ func NewBinpackingNodeEstimator(
 // While it is a multi-dimensional bin packing (cpu, mem, ports) in most cases the main dimension
 // will be cpu thus the estimated overprovisioning of 11/9 * optimal + 6/9 should be
 // still be maintained.
+// Pods with required inter-pod affinity which don't fit because the pods they
+// need to run next to come later in the order are retried after the rest.
 // It is assumed that all pods from the given list can fit to nodeTemplate.
 // Returns the number of nodes needed to accommodate all pods from the list.
 func (e *BinpackingNodeEstimator) Estimate(
@@ -77,83 +79,138 @@ func (e *BinpackingNodeEstimator) Estimate(
 
 	pods = e.podOrderer.Order(pods, nodeTemplate, nodeGroup)
 
-	newNodeNames := make(map[string]bool)
-	newNodesWithPods := make(map[string]bool)
-
 	e.clusterSnapshot.Fork()
 	defer func() {
 		e.clusterSnapshot.Revert()
 	}()
 
-	newNodeNameIndex := 0
-	scheduledPods := []*apiv1.Pod{}
-	lastNodeName := ""
-
+	state := newEstimationState()
+	// Pods with required affinity to other pending pods can't schedule until those pods are placed, which
+	// may happen only later in the order. Such pods are retried afterwards, as long as retries place more pods.
+	var podsToRetry []*apiv1.Pod
+	limitReached := false
 	for _, pod := range pods {
-		found := false
-
-		nodeName, err := e.predicateChecker.FitsAnyNodeMatching(e.clusterSnapshot, pod, func(nodeInfo *schedulerframework.NodeInfo) bool {
-			return newNodeNames[nodeInfo.Node().Name]
-		})
-		if err == nil {
-			found = true
-			if err := e.clusterSnapshot.AddPod(pod, nodeName); err != nil {
-				klog.Errorf("Error adding pod %v.%v to node %v in ClusterSnapshot; %v", pod.Namespace, pod.Name, nodeName, err)
-				return 0, nil
-			}
-			scheduledPods = append(scheduledPods, pod)
-			newNodesWithPods[nodeName] = true
+		scheduled, canAddNode, err := e.tryToSchedule(state, pod, nodeTemplate)
+		if err != nil {
+			return 0, nil
 		}
-
-		if !found {
-			// If the last node we've added is empty and the pod couldn't schedule on it, it wouldn't be able to schedule
-			// on a new node either. There is no point adding more nodes to snapshot in such case, especially because of
-			// performance cost each extra node adds to future FitsAnyNodeMatching calls.
-			if lastNodeName != "" && !newNodesWithPods[lastNodeName] {
-				continue
-			}
-
-			// Stop binpacking if we reach the limit of nodes we can add.
-			// We return the result of the binpacking that we already performed.
-			//
-			// The thresholdBasedEstimationLimiter implementation assumes that for
-			// each call that returns true, one node gets added. Therefore this
-			// must be the last check right before really adding a node.
-			if !e.limiter.PermissionToAddNode() {
-				break
-			}
-
-			// Add new node
-			newNodeName, err := e.addNewNodeToSnapshot(nodeTemplate, newNodeNameIndex)
+		if !canAddNode {
+			limitReached = true
+			break
+		}
+		if !scheduled && hasRequiredPodAffinity(pod) {
+			podsToRetry = append(podsToRetry, pod)
+		}
+	}
+	for !limitReached && len(podsToRetry) > 0 {
+		var stillUnscheduled []*apiv1.Pod
+		for _, pod := range podsToRetry {
+			scheduled, canAddNode, err := e.tryToSchedule(state, pod, nodeTemplate)
 			if err != nil {
-				klog.Errorf("Error while adding new node for template to ClusterSnapshot; %v", err)
 				return 0, nil
 			}
-			newNodeNameIndex++
-			newNodeNames[newNodeName] = true
-			lastNodeName = newNodeName
-
-			// And try to schedule pod to it.
-			// Note that this may still fail (ex. if topology spreading with zonal topologyKey is used);
-			// in this case we can't help the pending pod. We keep the node in clusterSnapshot to avoid
-			// adding and removing node to snapshot for each such pod.
-			if err := e.predicateChecker.CheckPredicates(e.clusterSnapshot, pod, newNodeName); err != nil {
-				continue
+			if !canAddNode {
+				limitReached = true
+				break
 			}
-			if err := e.clusterSnapshot.AddPod(pod, newNodeName); err != nil {
-				klog.Errorf("Error adding pod %v.%v to node %v in ClusterSnapshot; %v", pod.Namespace, pod.Name, newNodeName, err)
-				return 0, nil
+			if !scheduled {
+				stillUnscheduled = append(stillUnscheduled, pod)
 			}
-			newNodesWithPods[newNodeName] = true
-			scheduledPods = append(scheduledPods, pod)
 		}
+		if len(stillUnscheduled) == len(podsToRetry) {
+			break
+		}
+		podsToRetry = stillUnscheduled
 	}
 
 	if e.estimationAnalyserFunc != nil {
-		e.estimationAnalyserFunc(e.clusterSnapshot, nodeGroup, newNodesWithPods)
+		e.estimationAnalyserFunc(e.clusterSnapshot, nodeGroup, state.newNodesWithPods)
 	}
 
-	return len(newNodesWithPods), scheduledPods
+	return len(state.newNodesWithPods), state.scheduledPods
+}
+
+// estimationState is the state of a single binpacking estimation.
+type estimationState struct {
+	newNodeNameIndex int
+	lastNodeName     string
+	newNodeNames     map[string]bool
+	newNodesWithPods map[string]bool
+	scheduledPods    []*apiv1.Pod
+}
+
+func newEstimationState() *estimationState {
+	return &estimationState{
+		newNodeNames:     make(map[string]bool),
+		newNodesWithPods: make(map[string]bool),
+		scheduledPods:    []*apiv1.Pod{},
+	}
+}
+
+// tryToSchedule tries to schedule the pod on the nodes added so far, or otherwise on a new node. It returns whether
+// the pod got scheduled, and false for canAddNode if it didn't fit on the nodes added so far and the limiter didn't
+// allow adding another one. Errors are logged and mean the estimation can't continue.
+func (e *BinpackingNodeEstimator) tryToSchedule(state *estimationState, pod *apiv1.Pod, nodeTemplate *schedulerframework.NodeInfo) (scheduled bool, canAddNode bool, err error) {
+	nodeName, err := e.predicateChecker.FitsAnyNodeMatching(e.clusterSnapshot, pod, func(nodeInfo *schedulerframework.NodeInfo) bool {
+		return state.newNodeNames[nodeInfo.Node().Name]
+	})
+	if err == nil {
+		if err := e.clusterSnapshot.AddPod(pod, nodeName); err != nil {
+			klog.Errorf("Error adding pod %v.%v to node %v in ClusterSnapshot; %v", pod.Namespace, pod.Name, nodeName, err)
+			return false, false, err
+		}
+		state.scheduledPods = append(state.scheduledPods, pod)
+		state.newNodesWithPods[nodeName] = true
+		return true, true, nil
+	}
+
+	// If the last node we've added is empty and the pod couldn't schedule on it, it wouldn't be able to schedule
+	// on a new node either. There is no point adding more nodes to snapshot in such case, especially because of
+	// performance cost each extra node adds to future FitsAnyNodeMatching calls.
+	if state.lastNodeName != "" && !state.newNodesWithPods[state.lastNodeName] {
+		return false, true, nil
+	}
+
+	// Stop binpacking if we reach the limit of nodes we can add.
+	// We return the result of the binpacking that we already performed.
+	//
+	// The thresholdBasedEstimationLimiter implementation assumes that for
+	// each call that returns true, one node gets added. Therefore this
+	// must be the last check right before really adding a node.
+	if !e.limiter.PermissionToAddNode() {
+		return false, false, nil
+	}
+
+	// Add new node
+	newNodeName, err := e.addNewNodeToSnapshot(nodeTemplate, state.newNodeNameIndex)
+	if err != nil {
+		klog.Errorf("Error while adding new node for template to ClusterSnapshot; %v", err)
+		return false, false, err
+	}
+	state.newNodeNameIndex++
+	state.newNodeNames[newNodeName] = true
+	state.lastNodeName = newNodeName
+
+	// And try to schedule pod to it.
+	// Note that this may still fail (ex. if topology spreading with zonal topologyKey is used);
+	// in this case we can't help the pending pod. We keep the node in clusterSnapshot to avoid
+	// adding and removing node to snapshot for each such pod.
+	if err := e.predicateChecker.CheckPredicates(e.clusterSnapshot, pod, newNodeName); err != nil {
+		return false, true, nil
+	}
+	if err := e.clusterSnapshot.AddPod(pod, newNodeName); err != nil {
+		klog.Errorf("Error adding pod %v.%v to node %v in ClusterSnapshot; %v", pod.Namespace, pod.Name, newNodeName, err)
+		return false, false, err
+	}
+	state.newNodesWithPods[newNodeName] = true
+	state.scheduledPods = append(state.scheduledPods, pod)
+	return true, true, nil
+}
+
+// hasRequiredPodAffinity tells if the pod can only be scheduled next to pods matching its required affinity terms.
+func hasRequiredPodAffinity(pod *apiv1.Pod) bool {
+	affinity := pod.Spec.Affinity
+	return affinity != nil && affinity.PodAffinity != nil && len(affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0
 }
 
 func (e *BinpackingNodeEstimator) addNewNodeToSnapshot(
@@ -82,6 +82,31 @@ func makePods(cpuPerPod int64, memoryPerPod int64, hostport int32, maxSkew int32
 	return pods
 }
 
+func withPodAffinity(pods []*apiv1.Pod, app string, affinity *apiv1.Affinity) []*apiv1.Pod {
+	var result []*apiv1.Pod
+	for _, pod := range pods {
+		pod = pod.DeepCopy()
+		pod.Name = app
+		pod.Labels = map[string]string{"app": app}
+		pod.Spec.Affinity = affinity
+		result = append(result, pod)
+	}
+	return result
+}
+
+func requiredAffinityTerms(app string) []apiv1.PodAffinityTerm {
+	return []apiv1.PodAffinityTerm{
+		{
+			TopologyKey: "kubernetes.io/hostname",
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": app,
+				},
+			},
+		},
+	}
+}
+
 func makeNode(cpu int64, mem int64, name string, zone string) *apiv1.Node {
 	node := &apiv1.Node{
 		ObjectMeta: metav1.ObjectMeta{
@@ -106,6 +131,16 @@ func makeNode(cpu int64, mem int64, name string, zone string) *apiv1.Node {
 
 func TestBinpackingEstimate(t *testing.T) {
 	highResourcePodList := makePods(500, 1000, 0, 0, "", 10)
+	antiAffinityPods := withPodAffinity(makePods(100, 100, 0, 0, "", 4), "anti", &apiv1.Affinity{
+		PodAntiAffinity: &apiv1.PodAntiAffinity{RequiredDuringSchedulingIgnoredDuringExecution: requiredAffinityTerms("anti")},
+	})
+	targetPods := withPodAffinity(makePods(100, 100, 0, 0, "", 2), "target", nil)
+	followerPods := withPodAffinity(makePods(200, 100, 0, 0, "", 2), "follower", &apiv1.Affinity{
+		PodAffinity: &apiv1.PodAffinity{RequiredDuringSchedulingIgnoredDuringExecution: requiredAffinityTerms("target")},
+	})
+	chainedPods := withPodAffinity(makePods(300, 100, 0, 0, "", 1), "chained", &apiv1.Affinity{
+		PodAffinity: &apiv1.PodAffinity{RequiredDuringSchedulingIgnoredDuringExecution: requiredAffinityTerms("follower")},
+	})
 	testCases := []struct {
 		name                 string
 		millicores           int64
@@ -176,6 +211,30 @@ func TestBinpackingEstimate(t *testing.T) {
 			expectNodeCount: 1,
 			expectPodCount:  2,
 		},
+		{
+			name:            "hostname anti-affinity among pending pods forces pod-per-node",
+			millicores:      1000,
+			memory:          5000,
+			pods:            antiAffinityPods,
+			expectNodeCount: 4,
+			expectPodCount:  4,
+		},
+		{
+			name:            "pods with affinity to pods later in the order are retried",
+			millicores:      1000,
+			memory:          5000,
+			pods:            append(append(append([]*apiv1.Pod{}, chainedPods...), followerPods...), targetPods...),
+			expectNodeCount: 1,
+			expectPodCount:  5,
+		},
+		{
+			name:            "pods with unsatisfiable affinity aren't retried forever",
+			millicores:      1000,
+			memory:          5000,
+			pods:            append(append([]*apiv1.Pod{}, chainedPods...), followerPods...),
+			expectNodeCount: 0,
+			expectPodCount:  0,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
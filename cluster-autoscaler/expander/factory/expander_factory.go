@@ -78,7 +78,9 @@ func (f *Factory) Build(names []string) (expander.Strategy, errors.AutoscalerErr
 }
 
 // RegisterDefaultExpanders is a convenience function, registering all known expanders in the Factory.
-func (f *Factory) RegisterDefaultExpanders(cloudProvider cloudprovider.CloudProvider, autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface, configNamespace string, GRPCExpanderCert string, GRPCExpanderURL string) {
+// Evaluations of the priority expander are recorded in priorityStatus, if it isn't nil. The gRPC expander streams
+// the options to the server if GRPCExpanderStreaming is set.
+func (f *Factory) RegisterDefaultExpanders(cloudProvider cloudprovider.CloudProvider, autoscalingKubeClients *context.AutoscalingKubeClients, kubeClient kube_client.Interface, configNamespace string, GRPCExpanderCert string, GRPCExpanderURL string, GRPCExpanderStreaming bool, priorityStatus *priority.Status) {
 	f.RegisterFilter(expander.RandomExpanderName, random.NewFilter)
 	f.RegisterFilter(expander.MostPodsExpanderName, mostpods.NewFilter)
 	f.RegisterFilter(expander.LeastWasteExpanderName, waste.NewFilter)
@@ -93,7 +95,23 @@ func (f *Factory) RegisterDefaultExpanders(cloudProvider cloudprovider.CloudProv
 		// This should be currently OK.
 		stopChannel := make(chan struct{})
 		lister := kubernetes.NewConfigMapListerForNamespace(kubeClient, stopChannel, configNamespace)
-		return priority.NewFilter(lister.ConfigMaps(configNamespace), autoscalingKubeClients.Recorder)
+		// Pricing is optional, node prices just aren't available to priority expressions without it.
+		return priority.NewFilterWithStatus(lister.ConfigMaps(configNamespace), autoscalingKubeClients.Recorder, optionalPricing(cloudProvider), priorityStatus)
 	})
-	f.RegisterFilter(expander.GRPCExpanderName, func() expander.Filter { return grpcplugin.NewFilter(GRPCExpanderCert, GRPCExpanderURL) })
+	f.RegisterFilter(expander.GRPCExpanderName, func() expander.Filter {
+		if GRPCExpanderStreaming {
+			// Pricing is optional, node prices just aren't sent to the gRPC server without it.
+			return grpcplugin.NewStreamingFilter(GRPCExpanderCert, GRPCExpanderURL, optionalPricing(cloudProvider))
+		}
+		return grpcplugin.NewFilter(GRPCExpanderCert, GRPCExpanderURL)
+	})
+}
+
+// optionalPricing returns the pricing model of the cloud provider, or nil if it isn't available.
+func optionalPricing(cloudProvider cloudprovider.CloudProvider) cloudprovider.PricingModel {
+	pricing, err := cloudProvider.Pricing()
+	if err != nil {
+		return nil
+	}
+	return pricing
 }
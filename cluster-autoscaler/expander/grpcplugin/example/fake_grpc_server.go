@@ -19,6 +19,7 @@ package example
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net"
 
@@ -101,3 +102,36 @@ func (ServerImpl *ExpanderServerImpl) BestOptions(ctx context.Context, req *prot
 		Options: []*protos.Option{choice},
 	}, nil
 }
+
+// BestOptionsStream method receives the details of all options passed from the gRPC Client in CA one by one, and returns a capacity hint
+// for the best of them, according to the defined strategy.
+func (ServerImpl *ExpanderServerImpl) BestOptionsStream(stream protos.Expander_BestOptionsStreamServer) error {
+	// This strategy simply chooses the cheapest Option, but can be replaced with any arbitrary logic
+	var choice *protos.CandidateOption
+	for {
+		candidate, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("Received candidate option %v with price %v", candidate.GetOption().GetNodeGroupId(), candidate.Price)
+		if choice == nil || (candidate.Price >= 0 && (choice.Price < 0 || candidate.Price < choice.Price)) {
+			choice = candidate
+		}
+	}
+	if choice == nil {
+		return stream.SendAndClose(&protos.CapacityHintsResponse{})
+	}
+
+	// Add at most 10 nodes at once, the rest will be added by the following scale-ups
+	nodeCount := choice.GetOption().GetNodeCount()
+	if nodeCount > 10 {
+		nodeCount = 10
+	}
+	log.Printf("returned capacity hint with option %v and %v nodes", choice.GetOption().GetNodeGroupId(), nodeCount)
+	return stream.SendAndClose(&protos.CapacityHintsResponse{
+		Hints: []*protos.CapacityHint{{NodeGroupId: choice.GetOption().GetNodeGroupId(), NodeCount: nodeCount}},
+	})
+}
@@ -18,17 +18,22 @@ package grpcplugin
 
 import (
 	"context"
+	"io"
 	"log"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/grpcplugin/protos"
 	"k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -38,6 +43,10 @@ const (
 
 type grpcclientstrategy struct {
 	grpcClient protos.ExpanderClient
+	// streaming tells if the options are sent with BestOptionsStream, it's
+	// reset if the server doesn't implement it.
+	streaming bool
+	pricing   cloudprovider.PricingModel
 }
 
 // NewFilter returns an expansion filter that creates a gRPC client, and calls out to a gRPC server
@@ -49,6 +58,13 @@ func NewFilter(expanderCert string, expanderUrl string) expander.Filter {
 	return &grpcclientstrategy{grpcClient: client}
 }
 
+// NewStreamingFilter returns an expansion filter that creates a gRPC client, and streams the details of the options,
+// including node prices from the pricing model if it isn't nil, to a gRPC server. The server can choose to add fewer
+// nodes than proposed by the options. If the server doesn't implement streaming, options are sent like by NewFilter.
+func NewStreamingFilter(expanderCert string, expanderUrl string, pricing cloudprovider.PricingModel) expander.Filter {
+	return &grpcclientstrategy{grpcClient: createGRPCClient(expanderCert, expanderUrl), streaming: true, pricing: pricing}
+}
+
 func createGRPCClient(expanderCert string, expanderUrl string) protos.ExpanderClient {
 	if expanderCert == "" {
 		log.Fatalf("GRPC Expander Cert not specified, insecure connections not allowed")
@@ -78,6 +94,22 @@ func (g *grpcclientstrategy) BestOptions(expansionOptions []expander.Option, nod
 		return expansionOptions
 	}
 
+	if g.streaming {
+		options, err := g.bestOptionsStream(expansionOptions, nodeInfo)
+		if status.Code(err) == codes.Unimplemented {
+			klog.Warningf("GRPC server doesn't implement streaming of options, falling back to sending all options at once: %v", err)
+			g.streaming = false
+		} else if err != nil {
+			klog.V(4).Infof("GRPC stream failed, no options filtered: %v", err)
+			return expansionOptions
+		} else if options == nil {
+			klog.V(4).Info("Unable to sanitize GPRC returned capacity hints, no options filtered")
+			return expansionOptions
+		} else {
+			return options
+		}
+	}
+
 	// Transform inputs to gRPC inputs
 	grpcOptionsSlice, nodeGroupIDOptionMap := populateOptionsForGRPC(expansionOptions)
 	grpcNodeMap := populateNodeInfoForGRPC(nodeInfo)
@@ -105,6 +137,101 @@ func (g *grpcclientstrategy) BestOptions(expansionOptions []expander.Option, nod
 	return options
 }
 
+// bestOptionsStream sends the details of each option in a separate message to the gRPC server, and returns the options
+// chosen by it with the capacity hints applied.
+func (g *grpcclientstrategy) bestOptionsStream(expansionOptions []expander.Option, nodeInfo map[string]*schedulerframework.NodeInfo) ([]expander.Option, error) {
+	klog.V(2).Infof("GPRC stream of best options to server with %v options", len(expansionOptions))
+	ctx, cancel := context.WithTimeout(context.Background(), gRPCTimeout)
+	defer cancel()
+	stream, err := g.grpcClient.BestOptionsStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeGroupIDOptionMap := make(map[string]expander.Option)
+	now := time.Now()
+	for _, option := range expansionOptions {
+		nodeGroupIDOptionMap[option.NodeGroup.Id()] = option
+		// io.EOF means that the server closed the stream, its status is returned by CloseAndRecv.
+		if err := stream.Send(g.newCandidateOptionMessage(option, nodeInfo, now)); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, err
+	}
+	return applyCapacityHintsFromGRPC(response.GetHints(), nodeGroupIDOptionMap), nil
+}
+
+func (g *grpcclientstrategy) newCandidateOptionMessage(option expander.Option, nodeInfos map[string]*schedulerframework.NodeInfo, now time.Time) *protos.CandidateOption {
+	id := option.NodeGroup.Id()
+	candidate := &protos.CandidateOption{
+		Option:      newOptionMessage(id, int32(option.NodeCount), option.Debug, option.Pods),
+		Price:       -1,
+		CurrentSize: -1,
+		MinSize:     int32(option.NodeGroup.MinSize()),
+		MaxSize:     int32(option.NodeGroup.MaxSize()),
+	}
+	if currentSize, err := option.NodeGroup.TargetSize(); err == nil {
+		candidate.CurrentSize = int32(currentSize)
+	} else {
+		klog.V(4).Infof("Failed to get target size of node group %s for gRPC expander: %v", id, err)
+	}
+	if nodeInfo, found := nodeInfos[id]; found && nodeInfo.Node() != nil {
+		candidate.Template = nodeInfo.Node()
+		if g.pricing != nil {
+			if price, err := g.pricing.NodePrice(candidate.Template, now, now.Add(time.Hour)); err == nil {
+				candidate.Price = price
+			} else {
+				klog.V(4).Infof("Failed to get price of node group %s for gRPC expander: %v", id, err)
+			}
+		}
+	}
+	return candidate
+}
+
+// applyCapacityHintsFromGRPC returns the options chosen by the capacity hints, with their node count lowered to the
+// hinted one. Hints can't increase the node count, and are ignored for node groups scaled all at once.
+func applyCapacityHintsFromGRPC(hints []*protos.CapacityHint, nodeGroupIDOptionMap map[string]expander.Option) []expander.Option {
+	var options []expander.Option
+	seen := make(map[string]bool)
+	for _, hint := range hints {
+		if hint == nil {
+			klog.Errorf("GRPC server returned nil CapacityHint")
+			continue
+		}
+		option, found := nodeGroupIDOptionMap[hint.NodeGroupId]
+		if !found {
+			klog.Errorf("GRPC server returned invalid nodeGroup ID: %s", hint.NodeGroupId)
+			continue
+		}
+		if seen[hint.NodeGroupId] {
+			klog.Errorf("GRPC server returned multiple capacity hints for nodeGroup ID: %s", hint.NodeGroupId)
+			continue
+		}
+		seen[hint.NodeGroupId] = true
+		switch {
+		case hint.NodeCount < 0 || int(hint.NodeCount) > option.NodeCount:
+			klog.Errorf("GRPC server returned invalid node count %d for nodeGroup ID %s, %d nodes are needed", hint.NodeCount, hint.NodeGroupId, option.NodeCount)
+		case hint.NodeCount == 0 || int(hint.NodeCount) == option.NodeCount:
+		case isZeroOrMaxNodeScaling(option.NodeGroup):
+			klog.Warningf("Ignoring node count %d returned by GRPC server for nodeGroup ID %s, it's scaled all at once", hint.NodeCount, hint.NodeGroupId)
+		default:
+			klog.V(2).Infof("GRPC server lowered node count of nodeGroup ID %s from %d to %d", hint.NodeGroupId, option.NodeCount, hint.NodeCount)
+			option.NodeCount = int(hint.NodeCount)
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+func isZeroOrMaxNodeScaling(nodeGroup cloudprovider.NodeGroup) bool {
+	opts, err := nodeGroup.GetOptions(config.NodeGroupAutoscalingOptions{})
+	return err == nil && opts != nil && opts.ZeroOrMaxNodeScaling
+}
+
 // populateOptionsForGRPC creates a map of nodegroup ID and options, as well as a slice of Options objects for the gRPC call
 func populateOptionsForGRPC(expansionOptions []expander.Option) ([]*protos.Option, map[string]expander.Option) {
 	grpcOptionsSlice := []*protos.Option{}
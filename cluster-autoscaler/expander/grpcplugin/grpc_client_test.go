@@ -18,11 +18,17 @@ package grpcplugin
 
 import (
 	"errors"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/grpcplugin/protos"
 	"k8s.io/autoscaler/cluster-autoscaler/expander/mocks"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
@@ -176,7 +182,7 @@ func TestBestOptionsValid(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockClient := mocks.NewMockExpanderClient(ctrl)
-	g := &grpcclientstrategy{mockClient}
+	g := &grpcclientstrategy{grpcClient: mockClient}
 
 	nodeInfos := makeFakeNodeInfos()
 	grpcNodeInfoMap := make(map[string]*v1.Node)
@@ -202,7 +208,7 @@ func TestBestOptionsErrors(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockClient := mocks.NewMockExpanderClient(ctrl)
-	g := grpcclientstrategy{mockClient}
+	g := grpcclientstrategy{grpcClient: mockClient}
 
 	badProtosOption := protos.Option{
 		NodeGroupId: "badID",
@@ -220,7 +226,7 @@ func TestBestOptionsErrors(t *testing.T) {
 	}{
 		{
 			desc:         "Bad gRPC client config",
-			client:       grpcclientstrategy{nil},
+			client:       grpcclientstrategy{grpcClient: nil},
 			nodeInfo:     makeFakeNodeInfos(),
 			mockResponse: protos.BestOptionsResponse{},
 			errResponse:  nil,
@@ -274,3 +280,201 @@ func TestBestOptionsErrors(t *testing.T) {
 		assert.Equal(t, resp, options)
 	}
 }
+
+type fakeBestOptionsStream struct {
+	grpc.ClientStream
+	sent     []*protos.CandidateOption
+	sendErr  error
+	response *protos.CapacityHintsResponse
+	recvErr  error
+}
+
+func (s *fakeBestOptionsStream) Send(candidate *protos.CandidateOption) error {
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+	s.sent = append(s.sent, candidate)
+	return nil
+}
+
+func (s *fakeBestOptionsStream) CloseAndRecv() (*protos.CapacityHintsResponse, error) {
+	return s.response, s.recvErr
+}
+
+type fakePricingModel struct {
+	prices map[string]float64
+}
+
+func (p *fakePricingModel) NodePrice(node *v1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	if price, found := p.prices[node.Name]; found {
+		return price, nil
+	}
+	return 0, errors.New("unknown node")
+}
+
+func (p *fakePricingModel) PodPrice(pod *v1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	return 0, nil
+}
+
+func makeStreamedOptions(nodeCount int) []expander.Option {
+	var opts []expander.Option
+	for _, opt := range options {
+		opt.NodeCount = nodeCount
+		opts = append(opts, opt)
+	}
+	return opts
+}
+
+func TestBestOptionsStreamValid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mocks.NewMockExpanderClient(ctrl)
+	pricing := &fakePricingModel{prices: map[string]float64{"n1": 1, "n2": 2, "n3": 3}}
+	g := &grpcclientstrategy{grpcClient: mockClient, streaming: true, pricing: pricing}
+
+	opts := makeStreamedOptions(5)
+	stream := &fakeBestOptionsStream{response: &protos.CapacityHintsResponse{
+		Hints: []*protos.CapacityHint{
+			{NodeGroupId: eoT3Large.NodeGroup.Id(), NodeCount: 3},
+			{NodeGroupId: eoT2Micro.NodeGroup.Id()},
+		},
+	}}
+	mockClient.EXPECT().BestOptionsStream(gomock.Any()).Return(stream, nil)
+
+	resp := g.BestOptions(opts, makeFakeNodeInfos())
+
+	hinted := opts[2]
+	hinted.NodeCount = 3
+	assert.Equal(t, []expander.Option{hinted, opts[0]}, resp)
+	assert.Len(t, stream.sent, len(opts))
+	for i, candidate := range stream.sent {
+		assert.Equal(t, opts[i].NodeGroup.Id(), candidate.GetOption().GetNodeGroupId())
+		assert.Equal(t, int32(5), candidate.GetOption().GetNodeCount())
+		assert.Equal(t, nodes[i], candidate.Template)
+		assert.Equal(t, int32(1), candidate.CurrentSize)
+		assert.Equal(t, int32(1), candidate.MinSize)
+		assert.Equal(t, int32(10), candidate.MaxSize)
+	}
+	assert.Equal(t, []float64{1, 2, 3, -1}, []float64{stream.sent[0].Price, stream.sent[1].Price, stream.sent[2].Price, stream.sent[3].Price})
+	assert.True(t, g.streaming)
+}
+
+func TestBestOptionsStreamUnimplemented(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockClient := mocks.NewMockExpanderClient(ctrl)
+	g := &grpcclientstrategy{grpcClient: mockClient, streaming: true}
+
+	stream := &fakeBestOptionsStream{sendErr: io.EOF, recvErr: status.Error(codes.Unimplemented, "unknown method BestOptionsStream")}
+	mockClient.EXPECT().BestOptionsStream(gomock.Any()).Return(stream, nil)
+	mockClient.EXPECT().BestOptions(gomock.Any(), gomock.Any()).Return(&protos.BestOptionsResponse{Options: []*protos.Option{&grpcEoT3Large}}, nil).Times(2)
+
+	assert.Equal(t, []expander.Option{eoT3Large}, g.BestOptions(options, makeFakeNodeInfos()))
+	assert.False(t, g.streaming)
+	// The stream isn't retried once the server doesn't implement it.
+	assert.Equal(t, []expander.Option{eoT3Large}, g.BestOptions(options, makeFakeNodeInfos()))
+}
+
+// All test cases should error or return invalid hints, and no options should be filtered
+func TestBestOptionsStreamErrors(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		streamErr error
+		stream    *fakeBestOptionsStream
+	}{
+		{
+			desc:      "stream error",
+			streamErr: errors.New("connection refused"),
+		},
+		{
+			desc:   "send error",
+			stream: &fakeBestOptionsStream{sendErr: errors.New("connection reset")},
+		},
+		{
+			desc:   "server error response",
+			stream: &fakeBestOptionsStream{sendErr: io.EOF, recvErr: status.Error(codes.Internal, "internal error")},
+		},
+		{
+			desc:   "no hints",
+			stream: &fakeBestOptionsStream{response: &protos.CapacityHintsResponse{}},
+		},
+		{
+			desc: "invalid hints",
+			stream: &fakeBestOptionsStream{response: &protos.CapacityHintsResponse{
+				Hints: []*protos.CapacityHint{nil, {NodeGroupId: "badID", NodeCount: 1}},
+			}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockClient := mocks.NewMockExpanderClient(ctrl)
+			g := &grpcclientstrategy{grpcClient: mockClient, streaming: true}
+			if tc.streamErr != nil {
+				mockClient.EXPECT().BestOptionsStream(gomock.Any()).Return(nil, tc.streamErr)
+			} else {
+				mockClient.EXPECT().BestOptionsStream(gomock.Any()).Return(tc.stream, nil)
+			}
+
+			opts := makeStreamedOptions(5)
+			assert.Equal(t, opts, g.BestOptions(opts, makeFakeNodeInfos()))
+			assert.True(t, g.streaming)
+		})
+	}
+}
+
+func TestApplyCapacityHintsFromGRPC(t *testing.T) {
+	atomic := expander.Option{
+		NodeCount: 5,
+		NodeGroup: test.NewTestNodeGroup("my-asg.atomic", 10, 0, 0, true, false, "t3.large", nil, nil),
+	}
+	atomic.NodeGroup.(*test.TestNodeGroup).SetOptions(&config.NodeGroupAutoscalingOptions{ZeroOrMaxNodeScaling: true})
+	opts := append(makeStreamedOptions(5), atomic)
+	nodeGroupIDOptionMap := make(map[string]expander.Option)
+	for _, opt := range opts {
+		nodeGroupIDOptionMap[opt.NodeGroup.Id()] = opt
+	}
+
+	testCases := []struct {
+		desc     string
+		hints    []*protos.CapacityHint
+		expected []expander.Option
+	}{
+		{
+			desc:     "lower node count",
+			hints:    []*protos.CapacityHint{{NodeGroupId: opts[1].NodeGroup.Id(), NodeCount: 2}},
+			expected: []expander.Option{{Debug: opts[1].Debug, NodeGroup: opts[1].NodeGroup, NodeCount: 2}},
+		},
+		{
+			desc:     "option node count",
+			hints:    []*protos.CapacityHint{{NodeGroupId: opts[1].NodeGroup.Id()}, {NodeGroupId: opts[2].NodeGroup.Id(), NodeCount: 5}},
+			expected: []expander.Option{opts[1], opts[2]},
+		},
+		{
+			desc:     "node count too high",
+			hints:    []*protos.CapacityHint{{NodeGroupId: opts[1].NodeGroup.Id(), NodeCount: 6}},
+			expected: []expander.Option{opts[1]},
+		},
+		{
+			desc:     "negative node count",
+			hints:    []*protos.CapacityHint{{NodeGroupId: opts[1].NodeGroup.Id(), NodeCount: -1}},
+			expected: []expander.Option{opts[1]},
+		},
+		{
+			desc:     "duplicated hint",
+			hints:    []*protos.CapacityHint{{NodeGroupId: opts[1].NodeGroup.Id(), NodeCount: 2}, {NodeGroupId: opts[1].NodeGroup.Id(), NodeCount: 3}},
+			expected: []expander.Option{{Debug: opts[1].Debug, NodeGroup: opts[1].NodeGroup, NodeCount: 2}},
+		},
+		{
+			desc:     "node group scaled all at once",
+			hints:    []*protos.CapacityHint{{NodeGroupId: atomic.NodeGroup.Id(), NodeCount: 2}},
+			expected: []expander.Option{atomic},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, applyCapacityHintsFromGRPC(tc.hints, nodeGroupIDOptionMap))
+		})
+	}
+}
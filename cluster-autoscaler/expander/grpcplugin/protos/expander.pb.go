@@ -211,6 +211,201 @@ func (x *Option) GetPod() []*v1.Pod {
 	return nil
 }
 
+type CandidateOption struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Option *Option `protobuf:"bytes,1,opt,name=option,proto3" json:"option,omitempty"`
+	// template node of the option's node group.
+	Template *v1.Node `protobuf:"bytes,2,opt,name=template,proto3" json:"template,omitempty"`
+	// price of the template node for an hour, -1 if the cloud provider doesn't support pricing.
+	Price float64 `protobuf:"fixed64,3,opt,name=price,proto3" json:"price,omitempty"`
+	// target size of the node group, -1 if it couldn't be determined.
+	CurrentSize int32 `protobuf:"varint,4,opt,name=currentSize,proto3" json:"currentSize,omitempty"`
+	MinSize     int32 `protobuf:"varint,5,opt,name=minSize,proto3" json:"minSize,omitempty"`
+	MaxSize     int32 `protobuf:"varint,6,opt,name=maxSize,proto3" json:"maxSize,omitempty"`
+}
+
+func (x *CandidateOption) Reset() {
+	*x = CandidateOption{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CandidateOption) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CandidateOption) ProtoMessage() {}
+
+func (x *CandidateOption) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CandidateOption.ProtoReflect.Descriptor instead.
+func (*CandidateOption) Descriptor() ([]byte, []int) {
+	return file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CandidateOption) GetOption() *Option {
+	if x != nil {
+		return x.Option
+	}
+	return nil
+}
+
+func (x *CandidateOption) GetTemplate() *v1.Node {
+	if x != nil {
+		return x.Template
+	}
+	return nil
+}
+
+func (x *CandidateOption) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *CandidateOption) GetCurrentSize() int32 {
+	if x != nil {
+		return x.CurrentSize
+	}
+	return 0
+}
+
+func (x *CandidateOption) GetMinSize() int32 {
+	if x != nil {
+		return x.MinSize
+	}
+	return 0
+}
+
+func (x *CandidateOption) GetMaxSize() int32 {
+	if x != nil {
+		return x.MaxSize
+	}
+	return 0
+}
+
+type CapacityHint struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// ID of the node group of one of the candidate options.
+	NodeGroupId string `protobuf:"bytes,1,opt,name=nodeGroupId,proto3" json:"nodeGroupId,omitempty"`
+	// number of nodes to add to the node group, at most the option's nodeCount.
+	// 0 means the option's nodeCount.
+	NodeCount int32 `protobuf:"varint,2,opt,name=nodeCount,proto3" json:"nodeCount,omitempty"`
+}
+
+func (x *CapacityHint) Reset() {
+	*x = CapacityHint{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CapacityHint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapacityHint) ProtoMessage() {}
+
+func (x *CapacityHint) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapacityHint.ProtoReflect.Descriptor instead.
+func (*CapacityHint) Descriptor() ([]byte, []int) {
+	return file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CapacityHint) GetNodeGroupId() string {
+	if x != nil {
+		return x.NodeGroupId
+	}
+	return ""
+}
+
+func (x *CapacityHint) GetNodeCount() int32 {
+	if x != nil {
+		return x.NodeCount
+	}
+	return 0
+}
+
+type CapacityHintsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hints []*CapacityHint `protobuf:"bytes,1,rep,name=hints,proto3" json:"hints,omitempty"`
+}
+
+func (x *CapacityHintsResponse) Reset() {
+	*x = CapacityHintsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CapacityHintsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapacityHintsResponse) ProtoMessage() {}
+
+func (x *CapacityHintsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapacityHintsResponse.ProtoReflect.Descriptor instead.
+func (*CapacityHintsResponse) Descriptor() ([]byte, []int) {
+	return file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CapacityHintsResponse) GetHints() []*CapacityHint {
+	if x != nil {
+		return x.Hints
+	}
+	return nil
+}
+
 var File_cluster_autoscaler_expander_grpcplugin_protos_expander_proto protoreflect.FileDescriptor
 
 var file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_rawDesc = []byte{
@@ -248,16 +443,45 @@ var file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_rawDesc =
 	0x05, 0x64, 0x65, 0x62, 0x75, 0x67, 0x12, 0x29, 0x0a, 0x03, 0x70, 0x6f, 0x64, 0x18, 0x04, 0x20,
 	0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x6b, 0x38, 0x73, 0x2e, 0x69, 0x6f, 0x2e, 0x61, 0x70, 0x69,
 	0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x64, 0x52, 0x03, 0x70, 0x6f,
-	0x64, 0x32, 0x5c, 0x0a, 0x08, 0x45, 0x78, 0x70, 0x61, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x50, 0x0a,
-	0x0b, 0x42, 0x65, 0x73, 0x74, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1e, 0x2e, 0x67,
-	0x72, 0x70, 0x63, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2e, 0x42, 0x65, 0x73, 0x74, 0x4f, 0x70,
-	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x67,
-	0x72, 0x70, 0x63, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2e, 0x42, 0x65, 0x73, 0x74, 0x4f, 0x70,
-	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42,
-	0x2f, 0x5a, 0x2d, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x2d, 0x61, 0x75, 0x74, 0x6f, 0x73,
-	0x63, 0x61, 0x6c, 0x65, 0x72, 0x2f, 0x65, 0x78, 0x70, 0x61, 0x6e, 0x64, 0x65, 0x72, 0x2f, 0x67,
-	0x72, 0x70, 0x63, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x64, 0x22, 0xdf, 0x01, 0x0a, 0x0f, 0x43, 0x61, 0x6e, 0x64, 0x69, 0x64, 0x61, 0x74, 0x65, 0x4f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2a, 0x0a, 0x06, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x70, 0x6c, 0x75, 0x67,
+	0x69, 0x6e, 0x2e, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x6f, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x34, 0x0a, 0x08, 0x74, 0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x6b, 0x38, 0x73, 0x2e, 0x69, 0x6f, 0x2e, 0x61, 0x70, 0x69,
+	0x2e, 0x63, 0x6f, 0x72, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x52, 0x08, 0x74,
+	0x65, 0x6d, 0x70, 0x6c, 0x61, 0x74, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x12, 0x20, 0x0a,
+	0x0b, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x0b, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x12,
+	0x18, 0x0a, 0x07, 0x6d, 0x69, 0x6e, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x07, 0x6d, 0x69, 0x6e, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x61, 0x78,
+	0x53, 0x69, 0x7a, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x6d, 0x61, 0x78, 0x53,
+	0x69, 0x7a, 0x65, 0x22, 0x4e, 0x0a, 0x0c, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x48,
+	0x69, 0x6e, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x6e, 0x6f, 0x64, 0x65, 0x47, 0x72, 0x6f, 0x75, 0x70,
+	0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x6e, 0x6f, 0x64, 0x65, 0x47, 0x72,
+	0x6f, 0x75, 0x70, 0x49, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x6e, 0x6f, 0x64, 0x65, 0x43, 0x6f, 0x75,
+	0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x6e, 0x6f, 0x64, 0x65, 0x43, 0x6f,
+	0x75, 0x6e, 0x74, 0x22, 0x47, 0x0a, 0x15, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x48,
+	0x69, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2e, 0x0a, 0x05,
+	0x68, 0x69, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x67, 0x72,
+	0x70, 0x63, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2e, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74,
+	0x79, 0x48, 0x69, 0x6e, 0x74, 0x52, 0x05, 0x68, 0x69, 0x6e, 0x74, 0x73, 0x32, 0xb5, 0x01, 0x0a,
+	0x08, 0x45, 0x78, 0x70, 0x61, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x50, 0x0a, 0x0b, 0x42, 0x65, 0x73,
+	0x74, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1e, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x70,
+	0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2e, 0x42, 0x65, 0x73, 0x74, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x70,
+	0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2e, 0x42, 0x65, 0x73, 0x74, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x57, 0x0a, 0x11, 0x42,
+	0x65, 0x73, 0x74, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x12, 0x1b, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2e, 0x43, 0x61,
+	0x6e, 0x64, 0x69, 0x64, 0x61, 0x74, 0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x1a, 0x21, 0x2e,
+	0x67, 0x72, 0x70, 0x63, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2e, 0x43, 0x61, 0x70, 0x61, 0x63,
+	0x69, 0x74, 0x79, 0x48, 0x69, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x00, 0x28, 0x01, 0x42, 0x2f, 0x5a, 0x2d, 0x63, 0x6c, 0x75, 0x73, 0x74, 0x65, 0x72, 0x2d,
+	0x61, 0x75, 0x74, 0x6f, 0x73, 0x63, 0x61, 0x6c, 0x65, 0x72, 0x2f, 0x65, 0x78, 0x70, 0x61, 0x6e,
+	0x64, 0x65, 0x72, 0x2f, 0x67, 0x72, 0x70, 0x63, 0x70, 0x6c, 0x75, 0x67, 0x69, 0x6e, 0x2f, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x73, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -272,28 +496,36 @@ func file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_rawDescGZ
 	return file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_rawDescData
 }
 
-var file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_goTypes = []interface{}{
-	(*BestOptionsRequest)(nil),  // 0: grpcplugin.BestOptionsRequest
-	(*BestOptionsResponse)(nil), // 1: grpcplugin.BestOptionsResponse
-	(*Option)(nil),              // 2: grpcplugin.Option
-	nil,                         // 3: grpcplugin.BestOptionsRequest.NodeMapEntry
-	(*v1.Pod)(nil),              // 4: k8s.io.api.core.v1.Pod
-	(*v1.Node)(nil),             // 5: k8s.io.api.core.v1.Node
+	(*BestOptionsRequest)(nil),    // 0: grpcplugin.BestOptionsRequest
+	(*BestOptionsResponse)(nil),   // 1: grpcplugin.BestOptionsResponse
+	(*Option)(nil),                // 2: grpcplugin.Option
+	(*CandidateOption)(nil),       // 3: grpcplugin.CandidateOption
+	(*CapacityHint)(nil),          // 4: grpcplugin.CapacityHint
+	(*CapacityHintsResponse)(nil), // 5: grpcplugin.CapacityHintsResponse
+	nil,                           // 6: grpcplugin.BestOptionsRequest.NodeMapEntry
+	(*v1.Pod)(nil),                // 7: k8s.io.api.core.v1.Pod
+	(*v1.Node)(nil),               // 8: k8s.io.api.core.v1.Node
 }
 var file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_depIdxs = []int32{
-	2, // 0: grpcplugin.BestOptionsRequest.options:type_name -> grpcplugin.Option
-	3, // 1: grpcplugin.BestOptionsRequest.nodeMap:type_name -> grpcplugin.BestOptionsRequest.NodeMapEntry
-	2, // 2: grpcplugin.BestOptionsResponse.options:type_name -> grpcplugin.Option
-	4, // 3: grpcplugin.Option.pod:type_name -> k8s.io.api.core.v1.Pod
-	5, // 4: grpcplugin.BestOptionsRequest.NodeMapEntry.value:type_name -> k8s.io.api.core.v1.Node
-	0, // 5: grpcplugin.Expander.BestOptions:input_type -> grpcplugin.BestOptionsRequest
-	1, // 6: grpcplugin.Expander.BestOptions:output_type -> grpcplugin.BestOptionsResponse
-	6, // [6:7] is the sub-list for method output_type
-	5, // [5:6] is the sub-list for method input_type
-	5, // [5:5] is the sub-list for extension type_name
-	5, // [5:5] is the sub-list for extension extendee
-	0, // [0:5] is the sub-list for field type_name
+	2,  // 0: grpcplugin.BestOptionsRequest.options:type_name -> grpcplugin.Option
+	6,  // 1: grpcplugin.BestOptionsRequest.nodeMap:type_name -> grpcplugin.BestOptionsRequest.NodeMapEntry
+	2,  // 2: grpcplugin.BestOptionsResponse.options:type_name -> grpcplugin.Option
+	7,  // 3: grpcplugin.Option.pod:type_name -> k8s.io.api.core.v1.Pod
+	2,  // 4: grpcplugin.CandidateOption.option:type_name -> grpcplugin.Option
+	8,  // 5: grpcplugin.CandidateOption.template:type_name -> k8s.io.api.core.v1.Node
+	4,  // 6: grpcplugin.CapacityHintsResponse.hints:type_name -> grpcplugin.CapacityHint
+	8,  // 7: grpcplugin.BestOptionsRequest.NodeMapEntry.value:type_name -> k8s.io.api.core.v1.Node
+	0,  // 8: grpcplugin.Expander.BestOptions:input_type -> grpcplugin.BestOptionsRequest
+	3,  // 9: grpcplugin.Expander.BestOptionsStream:input_type -> grpcplugin.CandidateOption
+	1,  // 10: grpcplugin.Expander.BestOptions:output_type -> grpcplugin.BestOptionsResponse
+	5,  // 11: grpcplugin.Expander.BestOptionsStream:output_type -> grpcplugin.CapacityHintsResponse
+	10, // [10:12] is the sub-list for method output_type
+	8,  // [8:10] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_init() }
@@ -338,6 +570,42 @@ func file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_init() {
 				return nil
 			}
 		}
+		file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CandidateOption); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CapacityHint); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CapacityHintsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -345,7 +613,7 @@ func file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_cluster_autoscaler_expander_grpcplugin_protos_expander_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -372,6 +640,9 @@ const _ = grpc.SupportPackageIsVersion6
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type ExpanderClient interface {
 	BestOptions(ctx context.Context, in *BestOptionsRequest, opts ...grpc.CallOption) (*BestOptionsResponse, error)
+	// Streams the details of each candidate option in a separate message, and
+	// returns the chosen options, optionally with fewer nodes than proposed.
+	BestOptionsStream(ctx context.Context, opts ...grpc.CallOption) (Expander_BestOptionsStreamClient, error)
 }
 
 type expanderClient struct {
@@ -391,9 +662,46 @@ func (c *expanderClient) BestOptions(ctx context.Context, in *BestOptionsRequest
 	return out, nil
 }
 
+func (c *expanderClient) BestOptionsStream(ctx context.Context, opts ...grpc.CallOption) (Expander_BestOptionsStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Expander_serviceDesc.Streams[0], "/grpcplugin.Expander/BestOptionsStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &expanderBestOptionsStreamClient{stream}
+	return x, nil
+}
+
+type Expander_BestOptionsStreamClient interface {
+	Send(*CandidateOption) error
+	CloseAndRecv() (*CapacityHintsResponse, error)
+	grpc.ClientStream
+}
+
+type expanderBestOptionsStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *expanderBestOptionsStreamClient) Send(m *CandidateOption) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *expanderBestOptionsStreamClient) CloseAndRecv() (*CapacityHintsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(CapacityHintsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ExpanderServer is the server API for Expander service.
 type ExpanderServer interface {
 	BestOptions(context.Context, *BestOptionsRequest) (*BestOptionsResponse, error)
+	// Streams the details of each candidate option in a separate message, and
+	// returns the chosen options, optionally with fewer nodes than proposed.
+	BestOptionsStream(Expander_BestOptionsStreamServer) error
 }
 
 // UnimplementedExpanderServer can be embedded to have forward compatible implementations.
@@ -403,6 +711,9 @@ type UnimplementedExpanderServer struct {
 func (*UnimplementedExpanderServer) BestOptions(context.Context, *BestOptionsRequest) (*BestOptionsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BestOptions not implemented")
 }
+func (*UnimplementedExpanderServer) BestOptionsStream(Expander_BestOptionsStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method BestOptionsStream not implemented")
+}
 
 func RegisterExpanderServer(s *grpc.Server, srv ExpanderServer) {
 	s.RegisterService(&_Expander_serviceDesc, srv)
@@ -426,6 +737,32 @@ func _Expander_BestOptions_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Expander_BestOptionsStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ExpanderServer).BestOptionsStream(&expanderBestOptionsStreamServer{stream})
+}
+
+type Expander_BestOptionsStreamServer interface {
+	SendAndClose(*CapacityHintsResponse) error
+	Recv() (*CandidateOption, error)
+	grpc.ServerStream
+}
+
+type expanderBestOptionsStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *expanderBestOptionsStreamServer) SendAndClose(m *CapacityHintsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *expanderBestOptionsStreamServer) Recv() (*CandidateOption, error) {
+	m := new(CandidateOption)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Expander_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "grpcplugin.Expander",
 	HandlerType: (*ExpanderServer)(nil),
@@ -435,6 +772,12 @@ var _Expander_serviceDesc = grpc.ServiceDesc{
 			Handler:    _Expander_BestOptions_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BestOptionsStream",
+			Handler:       _Expander_BestOptionsStream_Handler,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "cluster-autoscaler/expander/grpcplugin/protos/expander.proto",
 }
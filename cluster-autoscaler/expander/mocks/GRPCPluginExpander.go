@@ -68,6 +68,26 @@ func (mr *MockExpanderClientMockRecorder) BestOptions(ctx, in interface{}, opts
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BestOptions", reflect.TypeOf((*MockExpanderClient)(nil).BestOptions), varargs...)
 }
 
+// BestOptionsStream mocks base method.
+func (m *MockExpanderClient) BestOptionsStream(ctx context.Context, opts ...grpc.CallOption) (protos.Expander_BestOptionsStreamClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{ctx}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "BestOptionsStream", varargs...)
+	ret0, _ := ret[0].(protos.Expander_BestOptionsStreamClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BestOptionsStream indicates an expected call of BestOptionsStream.
+func (mr *MockExpanderClientMockRecorder) BestOptionsStream(ctx interface{}, opts ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{ctx}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BestOptionsStream", reflect.TypeOf((*MockExpanderClient)(nil).BestOptionsStream), varargs...)
+}
+
 // MockExpanderServer is a mock of ExpanderServer interface.
 type MockExpanderServer struct {
 	ctrl     *gomock.Controller
@@ -105,3 +125,17 @@ func (mr *MockExpanderServerMockRecorder) BestOptions(arg0, arg1 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BestOptions", reflect.TypeOf((*MockExpanderServer)(nil).BestOptions), arg0, arg1)
 }
+
+// BestOptionsStream mocks base method.
+func (m *MockExpanderServer) BestOptionsStream(arg0 protos.Expander_BestOptionsStreamServer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BestOptionsStream", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// BestOptionsStream indicates an expected call of BestOptionsStream.
+func (mr *MockExpanderServerMockRecorder) BestOptionsStream(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BestOptionsStream", reflect.TypeOf((*MockExpanderServer)(nil).BestOptionsStream), arg0)
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"gopkg.in/yaml.v2"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// ExpressionsConfigMapKey defines the key used in the ConfigMap to configure priorities with CEL expressions
+	ExpressionsConfigMapKey = "expressions"
+	// NodeGroupVariable is the name of the expression variable describing the node group of the expansion option
+	NodeGroupVariable = "nodeGroup"
+	// PodsVariable is the name of the expression variable listing the pending pods of the expansion option
+	PodsVariable = "pods"
+)
+
+type expressions map[int][]*expression
+
+// expression is a compiled CEL expression evaluating to a bool.
+type expression struct {
+	source  string
+	program cel.Program
+}
+
+// input holds the variables available to expressions for an expansion option.
+type input struct {
+	nodeGroupID string
+	vars        map[string]interface{}
+}
+
+func newExpressionEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable(NodeGroupVariable, cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable(PodsVariable, cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+	)
+}
+
+func parseExpressionsYAMLString(expressionsYAML string) (expressions, error) {
+	if expressionsYAML == "" {
+		return nil, fmt.Errorf("expressions configuration in %s configmap is empty; please provide valid configuration",
+			PriorityConfigMapName)
+	}
+	var config map[int][]string
+	if err := yaml.Unmarshal([]byte(expressionsYAML), &config); err != nil {
+		return nil, fmt.Errorf("Can't parse YAML with expressions in the configmap: %v", err)
+	}
+
+	env, err := newExpressionEnv()
+	if err != nil {
+		return nil, fmt.Errorf("Can't create expression environment: %v", err)
+	}
+	newExpressions := make(expressions)
+	for prio, sourceList := range config {
+		for _, source := range sourceList {
+			ast, issues := env.Compile(source)
+			if issues != nil && issues.Err() != nil {
+				return nil, fmt.Errorf("Can't compile expression for priority %d and rule %s: %v", prio, source, issues.Err())
+			}
+			if outputType := ast.OutputType(); !outputType.IsExactType(cel.BoolType) && !outputType.IsExactType(cel.DynType) {
+				return nil, fmt.Errorf("Expression for priority %d and rule %s evaluates to %v, expected bool", prio, source, outputType)
+			}
+			program, err := env.Program(ast)
+			if err != nil {
+				return nil, fmt.Errorf("Can't build program for priority %d and rule %s: %v", prio, source, err)
+			}
+			newExpressions[prio] = append(newExpressions[prio], &expression{source: source, program: program})
+		}
+	}
+	return newExpressions, nil
+}
+
+// matches evaluates the expression with the variables.
+func (e *expression) matches(vars map[string]interface{}) (bool, error) {
+	val, _, err := e.program.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	matches, ok := val.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to %v, expected bool", val.Value())
+	}
+	return matches, nil
+}
+
+// inputs returns the variables available to expressions for each of the
+// expansion options. The node group is described by:
+//   - id: the node group id,
+//   - labels: the labels of the node group's template node,
+//   - zone: the topology zone of the template node,
+//   - minSize, maxSize: the size limits of the node group,
+//   - nodeCount: the number of nodes the option adds,
+//   - price: the price of a template node for an hour, only set if the
+//     cloud provider supports pricing.
+//
+// Each pod is described by its name, namespace, labels, priorityClassName
+// and priority.
+func (p *priority) inputs(expansionOptions []expander.Option, nodeInfos map[string]*schedulerframework.NodeInfo) []input {
+	now := p.now()
+	inputs := make([]input, 0, len(expansionOptions))
+	for _, option := range expansionOptions {
+		id := option.NodeGroup.Id()
+		nodeGroup := map[string]interface{}{
+			"id":        id,
+			"minSize":   int64(option.NodeGroup.MinSize()),
+			"maxSize":   int64(option.NodeGroup.MaxSize()),
+			"nodeCount": int64(option.NodeCount),
+		}
+		labels := map[string]string{}
+		if nodeInfo, found := nodeInfos[id]; found && nodeInfo.Node() != nil {
+			node := nodeInfo.Node()
+			for key, value := range node.Labels {
+				labels[key] = value
+			}
+			if p.pricing != nil {
+				price, err := p.pricing.NodePrice(node, now, now.Add(time.Hour))
+				if err != nil {
+					klog.V(4).Infof("Priority expander: can't get price of node group %s: %v", id, err)
+				} else {
+					nodeGroup["price"] = price
+				}
+			}
+		}
+		nodeGroup["labels"] = labels
+		zone, found := labels[apiv1.LabelTopologyZone]
+		if !found {
+			zone = labels[apiv1.LabelFailureDomainBetaZone]
+		}
+		nodeGroup["zone"] = zone
+
+		pods := make([]interface{}, 0, len(option.Pods))
+		for _, pod := range option.Pods {
+			var podPriority int64
+			if pod.Spec.Priority != nil {
+				podPriority = int64(*pod.Spec.Priority)
+			}
+			podLabels := pod.Labels
+			if podLabels == nil {
+				podLabels = map[string]string{}
+			}
+			pods = append(pods, map[string]interface{}{
+				"name":              pod.Name,
+				"namespace":         pod.Namespace,
+				"labels":            podLabels,
+				"priorityClassName": pod.Spec.PriorityClassName,
+				"priority":          podPriority,
+			})
+		}
+
+		inputs = append(inputs, input{
+			nodeGroupID: id,
+			vars: map[string]interface{}{
+				NodeGroupVariable: nodeGroup,
+				PodsVariable:      pods,
+			},
+		})
+	}
+	return inputs
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type testPricingModel struct {
+	nodePrice map[string]float64
+}
+
+func (tpm *testPricingModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	if price, found := tpm.nodePrice[node.Name]; found {
+		return price, nil
+	}
+	return 0.0, fmt.Errorf("price for node %v not found", node.Name)
+}
+
+func (tpm *testPricingModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	return 0.0, fmt.Errorf("price for pod %v not found", pod.Name)
+}
+
+func getExpressionFilterInstance(t *testing.T, data map[string]string, status *Status) (expander.Filter, *record.FakeRecorder, *apiv1.ConfigMap) {
+	cm := &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNamespace,
+			Name:      PriorityConfigMapName,
+		},
+		Data: data,
+	}
+	lister, err := kubernetes.NewTestConfigMapLister([]*apiv1.ConfigMap{cm})
+	assert.Nil(t, err)
+	r := record.NewFakeRecorder(100)
+	pricing := &testPricingModel{nodePrice: map[string]float64{"spot-template": 0.1, "ondemand-template": 0.4}}
+	s := NewFilterWithStatus(lister.ConfigMaps(testNamespace), r, pricing, status)
+	return s, r, cm
+}
+
+func testExpressionOptions() ([]expander.Option, map[string]*schedulerframework.NodeInfo) {
+	spot := expander.Option{
+		Debug:     "spot",
+		NodeGroup: test.NewTestNodeGroup("spot", 10, 1, 1, true, false, "n1-standard-2", nil, nil),
+		NodeCount: 2,
+		Pods:      []*apiv1.Pod{BuildTestPod("batch", 100, 0)},
+	}
+	ondemand := expander.Option{
+		Debug:     "ondemand",
+		NodeGroup: test.NewTestNodeGroup("ondemand", 10, 1, 1, true, false, "n1-standard-2", nil, nil),
+		NodeCount: 1,
+		Pods:      []*apiv1.Pod{BuildTestPod("batch", 100, 0)},
+	}
+	unpriced := expander.Option{
+		Debug:     "unpriced",
+		NodeGroup: test.NewTestNodeGroup("unpriced", 10, 1, 1, true, false, "n1-standard-2", nil, nil),
+		NodeCount: 1,
+	}
+	spotNode := BuildTestNode("spot-template", 1000, 1000)
+	spotNode.Labels = map[string]string{"capacity": "spot", apiv1.LabelTopologyZone: "zone-a"}
+	onDemandNode := BuildTestNode("ondemand-template", 1000, 1000)
+	onDemandNode.Labels = map[string]string{"capacity": "on-demand", apiv1.LabelTopologyZone: "zone-b"}
+	unpricedNode := BuildTestNode("unpriced-template", 1000, 1000)
+	nodeInfos := map[string]*schedulerframework.NodeInfo{
+		"spot":     schedulerframework.NewNodeInfo(),
+		"ondemand": schedulerframework.NewNodeInfo(),
+		"unpriced": schedulerframework.NewNodeInfo(),
+	}
+	nodeInfos["spot"].SetNode(spotNode)
+	nodeInfos["ondemand"].SetNode(onDemandNode)
+	nodeInfos["unpriced"].SetNode(unpricedNode)
+	return []expander.Option{spot, ondemand, unpriced}, nodeInfos
+}
+
+func TestPriorityExpanderExpressions(t *testing.T) {
+	for desc, tc := range map[string]struct {
+		data map[string]string
+		want []string
+	}{
+		"labels": {
+			data: map[string]string{ExpressionsConfigMapKey: `
+10:
+  - nodeGroup.labels["capacity"] == "spot"
+5:
+  - nodeGroup.labels["capacity"] == "on-demand"
+`},
+			want: []string{"spot"},
+		},
+		"zone": {
+			data: map[string]string{ExpressionsConfigMapKey: `
+10:
+  - nodeGroup.zone == "zone-b"
+1:
+  - "true"
+`},
+			want: []string{"ondemand"},
+		},
+		"price": {
+			data: map[string]string{ExpressionsConfigMapKey: `
+10:
+  - has(nodeGroup.price) && nodeGroup.price * double(nodeGroup.nodeCount) < 0.3
+1:
+  - has(nodeGroup.price)
+`},
+			want: []string{"spot"},
+		},
+		"pending pods": {
+			data: map[string]string{ExpressionsConfigMapKey: `
+10:
+  - pods.exists(p, p.name == "batch") && nodeGroup.id != "spot"
+`},
+			want: []string{"ondemand"},
+		},
+		"failing expression doesn't match": {
+			data: map[string]string{ExpressionsConfigMapKey: `
+10:
+  - nodeGroup.price > 0.2
+1:
+  - nodeGroup.id == "unpriced"
+`},
+			want: []string{"ondemand"},
+		},
+		"regexps and expressions": {
+			data: map[string]string{
+				ConfigMapKey: `
+20:
+  - unpriced
+`,
+				ExpressionsConfigMapKey: `
+10:
+  - nodeGroup.labels["capacity"] == "spot"
+`},
+			want: []string{"unpriced"},
+		},
+		"equal priorities": {
+			data: map[string]string{
+				ConfigMapKey: `
+10:
+  - unpriced
+`,
+				ExpressionsConfigMapKey: `
+10:
+  - nodeGroup.labels["capacity"] == "spot"
+`},
+			want: []string{"spot", "unpriced"},
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			options, nodeInfos := testExpressionOptions()
+			s, _, _ := getExpressionFilterInstance(t, tc.data, nil)
+			var got []string
+			for _, option := range s.BestOptions(options, nodeInfos) {
+				got = append(got, option.NodeGroup.Id())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPriorityExpanderSkipsInvalidExpressions(t *testing.T) {
+	for desc, expressions := range map[string]string{
+		"empty":           "",
+		"invalid yaml":    "10: [",
+		"syntax error":    "10:\n  - nodeGroup.id ==",
+		"undeclared":      "10:\n  - nodes.size() > 0",
+		"not a bool":      "10:\n  - pods.size()",
+		"wrong arguments": "10:\n  - pods.exists(p)",
+	} {
+		t.Run(desc, func(t *testing.T) {
+			options, nodeInfos := testExpressionOptions()
+			s, r, _ := getExpressionFilterInstance(t, map[string]string{ExpressionsConfigMapKey: expressions}, nil)
+			assert.Equal(t, options, s.BestOptions(options, nodeInfos))
+			assert.Equal(t, 1, s.(*priority).badConfigUpdates)
+			assert.Contains(t, <-r.Events, "PriorityConfigMapInvalid")
+		})
+	}
+}
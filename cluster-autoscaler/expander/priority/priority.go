@@ -17,12 +17,13 @@ limitations under the License.
 package priority
 
 import (
-	"errors"
 	"fmt"
 	"regexp"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -41,45 +42,94 @@ const (
 
 type priorities map[int][]*regexp.Regexp
 
+// configuration is the configuration of the priority expander, consisting of
+// regexps matching node group ids and expressions, either of which can be
+// empty.
+type configuration struct {
+	priorities  priorities
+	expressions expressions
+}
+
 type priority struct {
 	logRecorder      record.EventRecorder
 	okConfigUpdates  int
 	badConfigUpdates int
 	configMapLister  v1lister.ConfigMapNamespaceLister
+	pricing          cloudprovider.PricingModel
+	status           *Status
+	now              func() time.Time
 }
 
 // NewFilter returns an expansion filter that picks node groups based on user-defined priorities
 func NewFilter(configMapLister v1lister.ConfigMapNamespaceLister,
 	logRecorder record.EventRecorder) expander.Filter {
+	return NewFilterWithStatus(configMapLister, logRecorder, nil, nil)
+}
+
+// NewFilterWithStatus returns the expansion filter of NewFilter, which also
+// makes node prices of the pricing model available to expressions, if it
+// isn't nil, and records its evaluations in status, if it isn't nil.
+func NewFilterWithStatus(configMapLister v1lister.ConfigMapNamespaceLister,
+	logRecorder record.EventRecorder, pricing cloudprovider.PricingModel, status *Status) expander.Filter {
 	res := &priority{
 		logRecorder:     logRecorder,
 		configMapLister: configMapLister,
+		pricing:         pricing,
+		status:          status,
+		now:             time.Now,
+	}
+	if status != nil {
+		status.setFilter(res)
 	}
 	return res
 }
 
-func (p *priority) reloadConfigMap() (priorities, *apiv1.ConfigMap, error) {
+func (p *priority) reloadConfigMap() (*configuration, *apiv1.ConfigMap, error) {
 	cm, err := p.configMapLister.Get(PriorityConfigMapName)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Priority expander config map %s not found: %v", PriorityConfigMapName, err)
 	}
 
-	prioString, found := cm.Data[ConfigMapKey]
-	if !found {
-		msg := fmt.Sprintf("Wrong configmap for priority expander, doesn't contain %s key. Ignoring update.",
-			ConfigMapKey)
-		p.logConfigWarning(cm, "PriorityConfigMapInvalid", msg)
-		return nil, cm, errors.New(msg)
-	}
-
-	newPriorities, err := p.parsePrioritiesYAMLString(prioString)
+	newConfig, err := p.parseConfigMap(cm)
 	if err != nil {
 		msg := fmt.Sprintf("Wrong configuration for priority expander: %v. Ignoring update.", err)
 		p.logConfigWarning(cm, "PriorityConfigMapInvalid", msg)
 		return nil, cm, err
 	}
 
-	return newPriorities, cm, nil
+	p.okConfigUpdates++
+	msg := "Successfully loaded priority configuration from configmap."
+	klog.V(4).Info(msg)
+
+	return newConfig, cm, nil
+}
+
+// parseConfigMap parses the configuration from the ConfigMap, which has to
+// contain regexps under ConfigMapKey, expressions under
+// ExpressionsConfigMapKey, or both.
+func (p *priority) parseConfigMap(cm *apiv1.ConfigMap) (*configuration, error) {
+	prioString, prioFound := cm.Data[ConfigMapKey]
+	exprString, exprFound := cm.Data[ExpressionsConfigMapKey]
+	if !prioFound && !exprFound {
+		return nil, fmt.Errorf("configmap doesn't contain %s or %s key", ConfigMapKey, ExpressionsConfigMapKey)
+	}
+
+	newConfig := &configuration{}
+	if prioFound {
+		newPriorities, err := p.parsePrioritiesYAMLString(prioString)
+		if err != nil {
+			return nil, err
+		}
+		newConfig.priorities = newPriorities
+	}
+	if exprFound {
+		newExpressions, err := parseExpressionsYAMLString(exprString)
+		if err != nil {
+			return nil, err
+		}
+		newConfig.expressions = newExpressions
+	}
+	return newConfig, nil
 }
 
 func (p *priority) logConfigWarning(cm *apiv1.ConfigMap, reason, msg string) {
@@ -109,10 +159,6 @@ func (p *priority) parsePrioritiesYAMLString(prioritiesYAML string) (priorities,
 		}
 	}
 
-	p.okConfigUpdates++
-	msg := "Successfully loaded priority configuration from configmap."
-	klog.V(4).Info(msg)
-
 	return newPriorities, nil
 }
 
@@ -121,36 +167,31 @@ func (p *priority) BestOptions(expansionOptions []expander.Option, nodeInfo map[
 		return nil
 	}
 
-	priorities, cm, err := p.reloadConfigMap()
+	inputs := p.inputs(expansionOptions, nodeInfo)
+	config, cm, err := p.reloadConfigMap()
 	if err != nil {
+		p.recordStatus(inputs, &Evaluation{Timestamp: p.now(), Error: err.Error()})
 		return expansionOptions
 	}
 
-	maxPrio := -1
-	best := []expander.Option{}
-	for _, option := range expansionOptions {
-		id := option.NodeGroup.Id()
-		found := false
-		for prio, nameRegexpList := range priorities {
-			if !p.groupIDMatchesList(id, nameRegexpList) {
-				continue
-			}
-			found = true
-			if prio < maxPrio {
-				continue
-			}
-			if prio > maxPrio {
-				maxPrio = prio
-				best = nil
-			}
-			best = append(best, option)
+	evaluation := config.evaluate(inputs, p.now())
+	p.recordStatus(inputs, evaluation)
 
+	best := []expander.Option{}
+	for i, option := range expansionOptions {
+		optionEvaluation := evaluation.Options[i]
+		for _, exprErr := range optionEvaluation.Errors {
+			klog.Warningf("Priority expander: expression %q for priority %d failed for node group %s: %s",
+				exprErr.Expression, exprErr.Priority, optionEvaluation.NodeGroup, exprErr.Error)
 		}
-		if !found {
+		if optionEvaluation.Priority == nil {
 			msg := fmt.Sprintf("Priority expander: node group %s not found in priority expander configuration. "+
-				"The group won't be used.", id)
+				"The group won't be used.", optionEvaluation.NodeGroup)
 			p.logConfigWarning(cm, "PriorityConfigMapNotMatchedGroup", msg)
 		}
+		if optionEvaluation.Chosen {
+			best = append(best, option)
+		}
 	}
 
 	if len(best) == 0 {
@@ -165,11 +206,69 @@ func (p *priority) BestOptions(expansionOptions []expander.Option, nodeInfo map[
 	return best
 }
 
-func (p *priority) groupIDMatchesList(id string, nameRegexpList []*regexp.Regexp) bool {
-	for _, re := range nameRegexpList {
-		if re.FindStringIndex(id) != nil {
-			return true
+func (p *priority) recordStatus(inputs []input, evaluation *Evaluation) {
+	if p.status != nil {
+		p.status.record(inputs, evaluation)
+	}
+}
+
+// dryRun evaluates the current configuration for the inputs, without
+// emitting events or counting config updates.
+func (p *priority) dryRun(inputs []input) *Evaluation {
+	cm, err := p.configMapLister.Get(PriorityConfigMapName)
+	if err == nil {
+		var config *configuration
+		if config, err = p.parseConfigMap(cm); err == nil {
+			evaluation := config.evaluate(inputs, p.now())
+			evaluation.DryRun = true
+			return evaluation
+		}
+	}
+	return &Evaluation{Timestamp: p.now(), DryRun: true, Error: err.Error()}
+}
+
+// evaluate evaluates the configuration for the inputs. Options are chosen if
+// they have the highest priority among the options matched by any rule.
+func (c *configuration) evaluate(inputs []input, timestamp time.Time) *Evaluation {
+	evaluation := &Evaluation{
+		Timestamp: timestamp,
+		Options:   make([]OptionEvaluation, 0, len(inputs)),
+	}
+	maxPrio := -1
+	for _, in := range inputs {
+		optionEvaluation := OptionEvaluation{NodeGroup: in.nodeGroupID, Matches: []RuleMatch{}}
+		for prio, nameRegexpList := range c.priorities {
+			for _, re := range nameRegexpList {
+				if re.FindStringIndex(in.nodeGroupID) != nil {
+					optionEvaluation.Matches = append(optionEvaluation.Matches, RuleMatch{Priority: prio, Regexp: re.String()})
+				}
+			}
+		}
+		for prio, exprList := range c.expressions {
+			for _, expr := range exprList {
+				matches, err := expr.matches(in.vars)
+				if err != nil {
+					optionEvaluation.Errors = append(optionEvaluation.Errors, ExpressionError{Priority: prio, Expression: expr.source, Error: err.Error()})
+				} else if matches {
+					optionEvaluation.Matches = append(optionEvaluation.Matches, RuleMatch{Priority: prio, Expression: expr.source})
+				}
+			}
+		}
+		sortMatches(optionEvaluation.Matches)
+		sortExpressionErrors(optionEvaluation.Errors)
+		if len(optionEvaluation.Matches) > 0 {
+			prio := optionEvaluation.Matches[0].Priority
+			optionEvaluation.Priority = &prio
+			if prio > maxPrio {
+				maxPrio = prio
+			}
+		}
+		evaluation.Options = append(evaluation.Options, optionEvaluation)
+	}
+	for i := range evaluation.Options {
+		if prio := evaluation.Options[i].Priority; prio != nil && *prio == maxPrio {
+			evaluation.Options[i].Chosen = true
 		}
 	}
-	return false
+	return evaluation
 }
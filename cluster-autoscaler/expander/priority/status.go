@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	klog "k8s.io/klog/v2"
+)
+
+// DryRunQueryParam is the name of the query parameter which, if set to
+// "true", makes Status evaluate the current configuration for the expansion
+// options of the last evaluation, instead of returning the last evaluation.
+const DryRunQueryParam = "dryRun"
+
+// RuleMatch is a rule of the configuration matching an expansion option.
+type RuleMatch struct {
+	Priority int `json:"priority"`
+	// Regexp is the regexp matching the node group id, set for rules
+	// configured under ConfigMapKey.
+	Regexp string `json:"regexp,omitempty"`
+	// Expression is the expression evaluated to true, set for rules
+	// configured under ExpressionsConfigMapKey.
+	Expression string `json:"expression,omitempty"`
+}
+
+// ExpressionError is an error evaluating an expression for an expansion
+// option. The expression doesn't match the option then.
+type ExpressionError struct {
+	Priority   int    `json:"priority"`
+	Expression string `json:"expression"`
+	Error      string `json:"error"`
+}
+
+// OptionEvaluation is the evaluation of the configuration for an expansion
+// option.
+type OptionEvaluation struct {
+	NodeGroup string `json:"nodeGroup"`
+	// Priority is the highest priority of the matching rules, unset if no
+	// rule matched.
+	Priority *int              `json:"priority,omitempty"`
+	Matches  []RuleMatch       `json:"matches"`
+	Errors   []ExpressionError `json:"errors,omitempty"`
+	// Chosen tells if the option has the highest priority of all options.
+	Chosen bool `json:"chosen"`
+}
+
+// Evaluation is the evaluation of the configuration for expansion options.
+type Evaluation struct {
+	Timestamp time.Time `json:"timestamp"`
+	// DryRun tells if the evaluation was requested with DryRunQueryParam,
+	// rather than done in a scale-up.
+	DryRun bool `json:"dryRun"`
+	// Error is set if the configuration couldn't be loaded, no options are
+	// filtered then.
+	Error   string             `json:"error,omitempty"`
+	Options []OptionEvaluation `json:"options"`
+}
+
+// Status keeps the last evaluation of the priority expander configuration in
+// a scale-up and serves it as JSON.
+type Status struct {
+	mutex      sync.RWMutex
+	filter     *priority
+	inputs     []input
+	evaluation *Evaluation
+}
+
+// NewStatus returns a new Status.
+func NewStatus() *Status {
+	return &Status{}
+}
+
+func (s *Status) setFilter(filter *priority) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.filter = filter
+}
+
+func (s *Status) record(inputs []input, evaluation *Evaluation) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.inputs = inputs
+	s.evaluation = evaluation
+}
+
+// ServeHTTP returns the last evaluation, or a dry-run evaluation of the
+// current configuration if DryRunQueryParam is set.
+func (s *Status) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.RLock()
+	filter, inputs, evaluation := s.filter, s.inputs, s.evaluation
+	s.mutex.RUnlock()
+	if evaluation == nil {
+		http.Error(w, "priority expander wasn't evaluated yet", http.StatusServiceUnavailable)
+		return
+	}
+	if r.URL.Query().Get(DryRunQueryParam) == "true" {
+		evaluation = filter.dryRun(inputs)
+	}
+	body, err := json.Marshal(evaluation)
+	if err != nil {
+		klog.Errorf("Failed to marshal priority expander evaluation: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// sortMatches sorts matches by decreasing priority, so that the first one
+// determines the priority of the option.
+func sortMatches(matches []RuleMatch) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Priority != matches[j].Priority {
+			return matches[i].Priority > matches[j].Priority
+		}
+		if matches[i].Regexp != matches[j].Regexp {
+			return matches[i].Regexp < matches[j].Regexp
+		}
+		return matches[i].Expression < matches[j].Expression
+	})
+}
+
+func sortExpressionErrors(errs []ExpressionError) {
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Priority != errs[j].Priority {
+			return errs[i].Priority > errs[j].Priority
+		}
+		return errs[i].Expression < errs[j].Expression
+	})
+}
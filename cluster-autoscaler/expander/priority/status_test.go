@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package priority
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getEvaluation(t *testing.T, status *Status, url string) (int, *Evaluation) {
+	w := httptest.NewRecorder()
+	status.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	if w.Code != http.StatusOK {
+		return w.Code, nil
+	}
+	evaluation := &Evaluation{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), evaluation))
+	return w.Code, evaluation
+}
+
+func TestStatus(t *testing.T) {
+	status := NewStatus()
+	options, nodeInfos := testExpressionOptions()
+	s, r, cm := getExpressionFilterInstance(t, map[string]string{ExpressionsConfigMapKey: `
+10:
+  - nodeGroup.labels["capacity"] == "spot"
+5:
+  - nodeGroup.price > 0.2
+`}, status)
+
+	code, _ := getEvaluation(t, status, "/priorityexpanderz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	s.BestOptions(options, nodeInfos)
+	<-r.Events
+	code, evaluation := getEvaluation(t, status, "/priorityexpanderz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, evaluation.DryRun)
+	assert.Empty(t, evaluation.Error)
+	if assert.Len(t, evaluation.Options, 3) {
+		spot, ondemand, unpriced := evaluation.Options[0], evaluation.Options[1], evaluation.Options[2]
+		assert.Equal(t, "spot", spot.NodeGroup)
+		assert.True(t, spot.Chosen)
+		assert.Equal(t, 10, *spot.Priority)
+		assert.Equal(t, []RuleMatch{{Priority: 10, Expression: `nodeGroup.labels["capacity"] == "spot"`}}, spot.Matches)
+		assert.Equal(t, "ondemand", ondemand.NodeGroup)
+		assert.False(t, ondemand.Chosen)
+		assert.Equal(t, 5, *ondemand.Priority)
+		assert.Equal(t, "unpriced", unpriced.NodeGroup)
+		assert.False(t, unpriced.Chosen)
+		assert.Nil(t, unpriced.Priority)
+		assert.Empty(t, unpriced.Matches)
+		// Missing map keys are errors in CEL, the node has neither the label nor a price.
+		if assert.Len(t, unpriced.Errors, 2) {
+			assert.Equal(t, 10, unpriced.Errors[0].Priority)
+			assert.Equal(t, 5, unpriced.Errors[1].Priority)
+			assert.Equal(t, "nodeGroup.price > 0.2", unpriced.Errors[1].Expression)
+		}
+	}
+
+	cm.Data[ConfigMapKey] = "20:\n  - unpriced"
+	code, evaluation = getEvaluation(t, status, "/priorityexpanderz?dryRun=true")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, evaluation.DryRun)
+	if assert.Len(t, evaluation.Options, 3) {
+		assert.False(t, evaluation.Options[0].Chosen)
+		assert.True(t, evaluation.Options[2].Chosen)
+		assert.Equal(t, []RuleMatch{{Priority: 20, Regexp: "unpriced"}}, evaluation.Options[2].Matches)
+	}
+	assert.Equal(t, 1, s.(*priority).okConfigUpdates)
+	assert.Len(t, r.Events, 0)
+
+	code, evaluation = getEvaluation(t, status, "/priorityexpanderz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, evaluation.DryRun)
+	assert.True(t, evaluation.Options[0].Chosen)
+
+	cm.Data[ExpressionsConfigMapKey] = "10: ["
+	code, evaluation = getEvaluation(t, status, "/priorityexpanderz?dryRun=true")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, evaluation.DryRun)
+	assert.Contains(t, evaluation.Error, "Can't parse YAML with expressions")
+}
@@ -18,6 +18,7 @@ require (
 	github.com/gogo/protobuf v1.3.2
 	github.com/golang/mock v1.6.0
 	github.com/golang/protobuf v1.5.3
+	github.com/google/cel-go v0.17.6
 	github.com/google/go-cmp v0.5.9
 	github.com/google/go-querystring v1.0.0
 	github.com/google/uuid v1.3.0
@@ -42,6 +43,7 @@ require (
 	k8s.io/api v0.29.0-alpha.1
 	k8s.io/apimachinery v0.29.0-alpha.1
 	k8s.io/apiserver v0.29.0-alpha.1
+	k8s.io/autoscaler/cluster-autoscaler/simulator/drainability v0.0.0
 	k8s.io/client-go v0.29.0-alpha.1
 	k8s.io/cloud-provider v0.29.0-alpha.1
 	k8s.io/cloud-provider-aws v1.27.0
@@ -107,7 +109,6 @@ require (
 	github.com/golang-jwt/jwt/v4 v4.5.0 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/google/cadvisor v0.47.3 // indirect
-	github.com/google/cel-go v0.17.6 // indirect
 	github.com/google/gnostic-models v0.6.8 // indirect
 	github.com/google/gofuzz v1.2.0 // indirect
 	github.com/google/pprof v0.0.0-20210720184732-4bb14d4b1be1 // indirect
@@ -266,3 +267,5 @@ replace k8s.io/dynamic-resource-allocation => k8s.io/dynamic-resource-allocation
 replace k8s.io/kms => k8s.io/kms v0.29.0-alpha.1
 
 replace k8s.io/endpointslice => k8s.io/endpointslice v0.29.0-alpha.1
+
+replace k8s.io/autoscaler/cluster-autoscaler/simulator/drainability => ./simulator/drainability
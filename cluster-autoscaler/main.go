@@ -33,33 +33,69 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apiserver/pkg/server/mux"
 	"k8s.io/apiserver/pkg/server/routes"
 	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	cloudBuilder "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/builder"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider/nodegroupconfigcrd"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/config/reload"
 	"k8s.io/autoscaler/cluster-autoscaler/core"
 	"k8s.io/autoscaler/cluster-autoscaler/core/podlistprocessor"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/planner"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaleup/orchestrator"
 	"k8s.io/autoscaler/cluster-autoscaler/debuggingsnapshot"
 	"k8s.io/autoscaler/cluster-autoscaler/estimator"
 	"k8s.io/autoscaler/cluster-autoscaler/expander"
+	"k8s.io/autoscaler/cluster-autoscaler/expander/priority"
 	"k8s.io/autoscaler/cluster-autoscaler/metrics"
 	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/audit"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupresize"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodeinfosprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodereadiness"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/pods"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/emptycandidates"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/nodeselector"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/previouscandidates"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/scoring"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/provisioningrequest"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	drainpolicy "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/policy"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	debugcontainerrule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/debugcontainer"
+	evictionbackoffrule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/evictionbackoff"
+	expendablerule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/expendable"
+	localpvrule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/localpv"
+	namespacerule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/namespace"
+	overriderule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/override"
+	pinnedrule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/pinned"
+	replicatedrule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/replicated"
+	rolloutrule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/rollout"
+	singletonrule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/singleton"
+	webhookrule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/webhook"
+	drainabilitytrace "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/trace"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/timewindow"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/dryrun"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/dynamicresources"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/intern"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/persistentstate"
 	scheduler_util "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/units"
 	"k8s.io/autoscaler/cluster-autoscaler/version"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	kube_client "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -74,6 +110,7 @@ import (
 	_ "k8s.io/component-base/logs/json/register"
 	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/apis/scheduling"
 	scheduler_config "k8s.io/kubernetes/pkg/scheduler/apis/config"
 )
 
@@ -105,6 +142,7 @@ var (
 	kubeAPIContentType      = flag.String("kube-api-content-type", "application/vnd.kubernetes.protobuf", "Content type of requests sent to apiserver.")
 	kubeClientBurst         = flag.Int("kube-client-burst", rest.DefaultBurst, "Burst value for kubernetes client.")
 	kubeClientQPS           = flag.Float64("kube-client-qps", float64(rest.DefaultQPS), "QPS value for kubernetes client.")
+	internObjectStrings     = flag.Bool("intern-object-strings", false, "Should CA deduplicate strings repeated across pods and nodes received from apiserver, like labels and image names, to reduce memory usage in big clusters.")
 	cloudConfig             = flag.String("cloud-config", "", "The path to the cloud provider configuration file.  Empty string for no configuration file.")
 	namespace               = flag.String("namespace", "kube-system", "Namespace in which cluster-autoscaler run.")
 	enforceNodeGroupMinSize = flag.Bool("enforce-node-group-min-size", false, "Should CA scale up the node group to the configured min size if needed.")
@@ -141,13 +179,18 @@ var (
 			"for scale down when some candidates from previous iteration are no longer valid."+
 			"When calculating the pool size for additional candidates we take"+
 			"max(#nodes * scale-down-candidates-pool-ratio, scale-down-candidates-pool-min-count).")
-	schedulerConfigFile         = flag.String(config.SchedulerConfigFileFlag, "", "scheduler-config allows changing configuration of in-tree scheduler plugins acting on PreFilter and Filter extension points")
+	schedulerConfigFile         = flag.String(config.SchedulerConfigFileFlag, "", "scheduler-config allows changing configuration of in-tree scheduler plugins acting on PreFilter and Filter extension points. Pods are simulated with the profile of their scheduler, or the first profile if their scheduler has none")
 	nodeDeletionDelayTimeout    = flag.Duration("node-deletion-delay-timeout", 2*time.Minute, "Maximum time CA waits for removing delay-deletion.cluster-autoscaler.kubernetes.io/ annotations before deleting the node.")
 	nodeDeletionBatcherInterval = flag.Duration("node-deletion-batcher-interval", 0*time.Second, "How long CA ScaleDown gather nodes to delete them in batch.")
+	maxEmptyDeletionBatchSize   = flag.Int("max-empty-node-deletion-batch-size", 1, "Maximum number of empty nodes from the same node group deleted with a single cloud provider call. Values above 1 taint empty nodes in parallel (up to --max-scale-down-parallelism) and delete them in bulk.")
 	scanInterval                = flag.Duration("scan-interval", 10*time.Second, "How often cluster is reevaluated for scale up or down")
+	schedulingGatesBatchWindow  = flag.Duration("scheduling-gates-batch-window", 2*time.Second, "How long CA waits for more pods after pods released from their scheduling gates are found unschedulable, before reevaluating the cluster without waiting for scan-interval. 0 disables the early reevaluation.")
 	maxNodesTotal               = flag.Int("max-nodes-total", 0, "Maximum number of nodes in all node groups. Cluster autoscaler will not grow the cluster beyond this number.")
 	coresTotal                  = flag.String("cores-total", minMaxFlagString(0, config.DefaultMaxClusterCores), "Minimum and maximum number of cores in cluster, in the format <min>:<max>. Cluster autoscaler will not scale the cluster beyond these numbers.")
 	memoryTotal                 = flag.String("memory-total", minMaxFlagString(0, config.DefaultMaxClusterMemory), "Minimum and maximum number of gigabytes of memory in cluster, in the format <min>:<max>. Cluster autoscaler will not scale the cluster beyond these numbers.")
+	scaleDownCPUReserveRatio    = flag.Float64("scale-down-cpu-reserve-ratio", 0, "Fraction of allocatable CPU in the cluster which has to stay unrequested by pods after scale down. Nodes whose removal would violate it are not removed. 0 disables the reserve.")
+	scaleDownMemoryReserveRatio = flag.Float64("scale-down-memory-reserve-ratio", 0, "Fraction of allocatable memory in the cluster which has to stay unrequested by pods after scale down. Nodes whose removal would violate it are not removed. 0 disables the reserve.")
+	scaleDownReservePerZone     = flag.Bool("scale-down-reserve-per-zone", false, "Whether the scale down CPU and memory reserves have to be kept in each zone, in addition to the whole cluster.")
 	gpuTotal                    = multiStringFlag("gpu-total", "Minimum and maximum number of different GPUs in cluster, in the format <gpu_type>:<min>:<max>. Cluster autoscaler will not scale the cluster beyond these numbers. Can be passed multiple times. CURRENTLY THIS FLAG ONLY WORKS ON GKE.")
 	cloudProviderFlag           = flag.String("cloud-provider", cloudBuilder.DefaultCloudProvider,
 		"Cloud provider type. Available values: ["+strings.Join(cloudBuilder.AvailableCloudProviders, ",")+"]")
@@ -155,12 +198,18 @@ var (
 	maxBulkSoftTaintTime       = flag.Duration("max-bulk-soft-taint-time", 3*time.Second, "Maximum duration of tainting/untainting nodes as PreferNoSchedule at the same time.")
 	maxEmptyBulkDeleteFlag     = flag.Int("max-empty-bulk-delete", 10, "Maximum number of empty nodes that can be deleted at the same time.")
 	maxGracefulTerminationFlag = flag.Int("max-graceful-termination-sec", 10*60, "Maximum number of seconds CA waits for pod termination when trying to scale down a node.")
+	windowsGracefulTermination = flag.Int("windows-max-graceful-termination-sec", 0, "Maximum number of seconds CA waits for pod termination when trying to scale down a Windows node, overriding max-graceful-termination-sec and its node group overrides. 0 means max-graceful-termination-sec is used for Windows nodes too.")
 	maxTotalUnreadyPercentage  = flag.Float64("max-total-unready-percentage", 45, "Maximum percentage of unready nodes in the cluster.  After this is exceeded, CA halts operations")
 	okTotalUnreadyCount        = flag.Int("ok-total-unready-count", 3, "Number of allowed unready nodes, irrespective of max-total-unready-percentage")
 	scaleUpFromZero            = flag.Bool("scale-up-from-zero", true, "Should CA scale up when there are 0 ready nodes.")
 	parallelScaleUp            = flag.Bool("parallel-scale-up", false, "Whether to allow parallel node groups scale up. Experimental: may not work on some cloud providers, enable at your own risk.")
 	maxNodeProvisionTime       = flag.Duration("max-node-provision-time", 15*time.Minute, "The default maximum time CA waits for node to be provisioned - the value can be overridden per node group")
+	nodeReadinessTimeout       = flag.Duration("node-readiness-timeout", 15*time.Minute, "The default maximum time CA waits for a new node to pass readiness gates before replacing it - the value can be overridden per node group. 0 disables readiness gates")
 	maxPodEvictionTime         = flag.Duration("max-pod-eviction-time", 2*time.Minute, "Maximum time CA tries to evict a pod before giving up")
+	headroomNodes              = flag.Int("headroom-nodes", 0, "The default number of spare nodes CA keeps in each node group - the value can be overridden per node group")
+	headroomRatio              = flag.Float64("headroom-ratio", 0, "The default spare capacity CA keeps in each node group, as a fraction of its target size - the value can be overridden per node group. The larger of the spare nodes following from headroom-nodes and headroom-ratio is kept")
+	scaleDownWindow            = flag.String("scale-down-window", "", "The default cron-like expressions, separated with ';', describing when CA can remove nodes from each node group, e.g. '* 22-23,0-5 * * 1-5' - the value can be overridden per node group. Empty means nodes can be removed at any time")
+	scaleDownUtilizationMode   = flag.String("scale-down-utilization-mode", "requests", "The default way utilization of nodes is calculated for scale-down, one of 'requests' (sum of pod requests), 'usage' (actual usage reported by metrics-server) or 'hybrid' (the larger of both) - the value can be overridden per node group")
 	nodeGroupsFlag             = multiStringFlag(
 		"nodes",
 		"sets min,max size and other configuration data for a node group in a format accepted by cloud provider. Can be used multiple times. Format: <min>:<max>:<other...>")
@@ -171,14 +220,16 @@ var (
 			"The `aws` and `gce` cloud providers are currently supported. AWS matches by ASG tags, e.g. `asg:tag=tagKey,anotherTagKey`. "+
 			"GCE matches by IG name prefix, and requires you to specify min and max nodes per IG, e.g. `mig:namePrefix=pfx,min=0,max=10` "+
 			"Can be used multiple times.")
+	nodeGroupConfigsEnabled = flag.Bool("node-group-configs-enabled", false, "Whether node groups of the cloud provider are configured by NodeGroupConfig custom resources, overriding their min and max size, template and drain options. Requires the NodeGroupConfig CRD to be installed.")
 
 	estimatorFlag = flag.String("estimator", estimator.BinpackingEstimatorName,
 		"Type of resource estimator to be used in scale up. Available values: ["+strings.Join(estimator.AvailableEstimators, ",")+"]")
 
 	expanderFlag = flag.String("expander", expander.RandomExpanderName, "Type of node group expander to be used in scale up. Available values: ["+strings.Join(expander.AvailableExpanders, ",")+"]. Specifying multiple values separated by commas will call the expanders in succession until there is only one option remaining. Ties still existing after this process are broken randomly.")
 
-	grpcExpanderCert = flag.String("grpc-expander-cert", "", "Path to cert used by gRPC server over TLS")
-	grpcExpanderURL  = flag.String("grpc-expander-url", "", "URL to reach gRPC expander server.")
+	grpcExpanderCert      = flag.String("grpc-expander-cert", "", "Path to cert used by gRPC server over TLS")
+	grpcExpanderURL       = flag.String("grpc-expander-url", "", "URL to reach gRPC expander server.")
+	grpcExpanderStreaming = flag.Bool("grpc-expander-streaming", false, "Should the gRPC expander stream the details of the options to the gRPC server, which can reply with capacity hints. Falls back to sending all options at once if the server doesn't support streaming.")
 
 	ignoreDaemonSetsUtilization = flag.Bool("ignore-daemonsets-utilization", false,
 		"Should CA ignore DaemonSet pods when calculating resource utilization for scaling down")
@@ -187,9 +238,13 @@ var (
 
 	writeStatusConfigMapFlag         = flag.Bool("write-status-configmap", true, "Should CA write status information to a configmap")
 	statusConfigMapName              = flag.String("status-config-map-name", "cluster-autoscaler-status", "Status configmap name")
+	persistentStateConfigMapName     = flag.String("persistent-state-config-map-name", "", "Name of the configmap, in the namespace of CA, in which node deletions in progress and node group backoffs are saved, so that a restarted CA resumes the deletions and keeps the backoffs. The state isn't persisted if empty.")
+	writeScaleDownCandidatesResource = flag.Bool("write-scale-down-candidates-resource", false, "Should CA write unneeded and unremovable nodes to a ScaleDownCandidates custom resource. Requires the ScaleDownCandidates CRD to be installed.")
+	auditLogSink                     = flag.String("audit-log-sink", "", "Where CA appends audit records of its scaling decisions as JSON lines: 'stdout', 'file:<path>' or an http(s) webhook URL to which each record is posted. Decisions aren't audited if empty.")
 	maxInactivityTimeFlag            = flag.Duration("max-inactivity", 10*time.Minute, "Maximum time from last recorded autoscaler activity before automatic restart")
 	maxFailingTimeFlag               = flag.Duration("max-failing-time", 15*time.Minute, "Maximum time from last recorded successful autoscaler run before automatic restart")
 	balanceSimilarNodeGroupsFlag     = flag.Bool("balance-similar-node-groups", false, "Detect similar node groups and balance the number of nodes between them")
+	validateBalancedScaleUp          = flag.Bool("validate-balanced-scale-up", false, "Should CA schedule the pods triggering a scale-up balanced between similar node groups in simulation, and scale up only the best node group if more of the pods fit it")
 	nodeAutoprovisioningEnabled      = flag.Bool("node-autoprovisioning-enabled", false, "Should CA autoprovision node groups when needed")
 	maxAutoprovisionedNodeGroupCount = flag.Int("max-autoprovisioned-node-group-count", 15, "The maximum number of autoprovisioned groups in the cluster.")
 
@@ -202,9 +257,36 @@ var (
 	startupTaintsFlag         = multiStringFlag("startup-taint", "Specifies a taint to ignore in node templates when considering to scale a node group (Equivalent to ignore-taint)")
 	statusTaintsFlag          = multiStringFlag("status-taint", "Specifies a taint to ignore in node templates when considering to scale a node group but nodes will not be treated as unready")
 	balancingIgnoreLabelsFlag = multiStringFlag("balancing-ignore-label", "Specifies a label to ignore in addition to the basic and cloud-provider set of labels when comparing if two node groups are similar")
+	scaleDownScorersFlag      = multiStringFlag("scale-down-scorer", "Specifies a scorer deciding the order in which removable nodes are scaled down, as <name>[:<weight>], e.g. Utilization:2. Built-in scorers are Utilization, Age, DeletionCost, SpreadSkew, DrainDuration and Price. Nodes with the highest weighted sum of scores are scaled down first. Can be passed multiple times. If not passed, all built-in scorers except Price are used with weight 1.")
 	balancingLabelsFlag       = multiStringFlag("balancing-label", "Specifies a label to use for comparing if two node groups are similar, rather than the built in heuristics. Setting this flag disables all other comparison logic, and cannot be combined with --balancing-ignore-label.")
 	awsUseStaticInstanceList  = flag.Bool("aws-use-static-instance-list", false, "Should CA fetch instance types in runtime or use a static list. AWS only")
 
+	scaleDownDisabledTaintsFlag         = multiStringFlag("scale-down-disabled-taint", "Specifies a taint, as <key>[:<effect>], marking nodes as not eligible for scale down, like the scale-down-disabled annotation. The key can be * to match taints with any key, e.g. *:NoExecute. Can be passed multiple times.")
+	scaleDownDisabledNodeSelectorsFlag  = multiStringFlag("scale-down-disabled-node-selector", "Specifies a node label selector, e.g. never-drain=true, marking nodes as not eligible for scale down, like the scale-down-disabled annotation. Can be passed multiple times.")
+	scaleDownPreferredTaintsFlag        = multiStringFlag("scale-down-preferred-taint", "Specifies a taint, as <key>[:<effect>], marking nodes which are scaled down before other removable nodes. The key can be * to match taints with any key, e.g. *:PreferNoSchedule. Can be passed multiple times.")
+	scaleDownPreferredNodeSelectorsFlag = multiStringFlag("scale-down-preferred-node-selector", "Specifies a node label selector, e.g. drain-me-first=true, marking nodes which are scaled down before other removable nodes. Can be passed multiple times.")
+
+	nodeReadinessTaintsFlag     = multiStringFlag("node-readiness-taint", "Specifies a taint which has to be removed from a new node before it is treated as ready. Can be passed multiple times.")
+	nodeReadinessConditionsFlag = multiStringFlag("node-readiness-condition", "Specifies a node condition type which has to be True on a new node before it is treated as ready. Can be passed multiple times.")
+	nodeReadinessPodsFlag       = multiStringFlag("node-readiness-pod-selector", "Specifies a label selector of pods, e.g. of a CNI DaemonSet, one of which has to be running and ready on a new node before it is treated as ready. Can be passed multiple times.")
+
+	nodeAutoRepairEnabled        = flag.Bool("node-auto-repair-enabled", false, "Should CA drain and replace nodes which are NotReady, or report one of --node-auto-repair-condition, for longer than --node-auto-repair-unhealthy-time")
+	nodeAutoRepairUnhealthyTime  = flag.Duration("node-auto-repair-unhealthy-time", 20*time.Minute, "How long a node has to be unhealthy before it is repaired")
+	nodeAutoRepairConditionsFlag = multiStringFlag("node-auto-repair-condition", "Specifies a node condition type, e.g. reported by node-problem-detector, which makes a node unhealthy when True. Can be passed multiple times.")
+	maxNodeAutoRepairs           = flag.Int("max-node-auto-repairs", 1, "Maximum number of unhealthy nodes whose repair is started in one loop")
+
+	writeNodeGroupResizeRecommendations = flag.Bool("write-node-group-resize-recommendations", false, "Should CA write NodeGroupResizeRecommendation custom resources recommending a smaller machine type for node groups whose nodes all stay underutilized. Requires the NodeGroupResizeRecommendation CRD to be installed.")
+	nodeGroupResizeUtilizationThreshold = flag.Float64("node-group-resize-utilization-threshold", 0.3, "Utilization below which all nodes of a node group have to be for a smaller machine type to be recommended")
+	nodeGroupResizeRecommendationDelay  = flag.Duration("node-group-resize-recommendation-delay", time.Hour, "How long all nodes of a node group have to stay underutilized before a smaller machine type is recommended")
+
+	systemPodNamespacesFlag            = multiStringFlag("system-pod-namespace", "Specifies a namespace, e.g. monitoring or istio-system, whose pods are treated like pods from kube-system in scale down, in addition to kube-system itself. Can be passed multiple times.")
+	drainabilityOverrideNamespacesFlag = multiStringFlag("drainability-override-namespace", "Specifies a namespace in which DrainabilityOverride custom resources are honored, making the pods in the namespace selected by them drainable. Can be passed multiple times. Requires the DrainabilityOverride CRD to be installed.")
+	drainabilityShadowRulesFlag        = multiStringFlag("drainability-shadow-rule", "Specifies the name of a drainability rule, e.g. LocalPersistentVolume, evaluated in shadow mode: outcomes of the rule which would change whether pods block scale down are reported by metrics, but not enforced. Can be passed multiple times.")
+
+	enableProvisioningRequests = flag.Bool("enable-provisioning-requests", false, "Whether ProvisioningRequests should be processed. For each request, CA either finds room for all its pods in the cluster, scales up so that all of them fit, or marks the request as failed.")
+
+	enableDynamicResourceAllocation = flag.Bool("enable-dynamic-resource-allocation", false, "Whether DRA resource claims of pods should be taken into account, so that pods are only simulated to fit on nodes providing their devices and nodes providing devices of in-use claims aren't scaled down. Requires the resource.k8s.io/v1alpha2 API to be enabled.")
+
 	// GCE specific flags
 	concurrentGceRefreshes             = flag.Int("gce-concurrent-refreshes", 1, "Maximum number of concurrent refreshes per cloud object type.")
 	gceMigInstancesMinRefreshWaitTime  = flag.Duration("gce-mig-instances-min-refresh-wait-time", 5*time.Second, "The minimum time which needs to pass before GCE MIG instances from a given MIG can be refreshed.")
@@ -213,11 +295,19 @@ var (
 	enableProfiling                    = flag.Bool("profiling", false, "Is debug/pprof endpoint enabled")
 	clusterAPICloudConfigAuthoritative = flag.Bool("clusterapi-cloud-config-authoritative", false, "Treat the cloud-config flag authoritatively (do not fallback to using kubeconfig flag). ClusterAPI only")
 	cordonNodeBeforeTerminate          = flag.Bool("cordon-node-before-terminating", false, "Should CA cordon nodes before terminating during downscale process")
+	scaleDownCordonOnly                = flag.Bool("scale-down-cordon-only", false, "Should CA only cordon and taint nodes chosen for scale down and emit NodeScaleDownReady events on them, leaving their drain and deletion to an external system")
+	maxCordonedNodeDeletionTime        = flag.Duration("max-cordoned-node-deletion-time", time.Hour, "Maximum time an external system has to delete a node cordoned with --scale-down-cordon-only. Afterwards, the node is untainted and uncordoned, and its deletion fails. If 0, there is no limit.")
 	daemonSetEvictionForEmptyNodes     = flag.Bool("daemonset-eviction-for-empty-nodes", false, "DaemonSet pods will be gracefully terminated from empty nodes")
 	daemonSetEvictionForOccupiedNodes  = flag.Bool("daemonset-eviction-for-occupied-nodes", true, "DaemonSet pods will be gracefully terminated from non-empty nodes")
+	respectDaemonSetPdbs               = flag.Bool("respect-daemonset-pdbs", true, "Whether DaemonSet pods are subject to the pod disruption budgets matching them during scale down. Nodes are not removed if that would exceed the budgets, and evictions of DaemonSet pods sharing a budget are ordered across nodes")
 	userAgent                          = flag.String("user-agent", "cluster-autoscaler", "User agent used for HTTP calls.")
 	emitPerNodeGroupMetrics            = flag.Bool("emit-per-nodegroup-metrics", false, "If true, emit per node group metrics.")
 	debuggingSnapshotEnabled           = flag.Bool("debugging-snapshot-enabled", false, "Whether the debugging snapshot of cluster autoscaler feature is enabled")
+	drainabilityDryRunEnabled          = flag.Bool("drainability-dry-run-enabled", false, "Whether the /drainabilityz endpoint evaluating drainability of a given node is enabled")
+	drainabilityTraceEnabled           = flag.Bool("drainability-trace-enabled", false, "Whether every drainability rule evaluated for each pod on scale down candidates, and its outcome, should be logged as a single structured trace per loop")
+	priorityExpanderStatusEnabled      = flag.Bool("priority-expander-status-enabled", false, "Whether the /priorityexpanderz endpoint returning the last evaluation of the priority expander configuration, or a dry-run evaluation of the current one, is enabled")
+	scaleUpExplanationEnabled          = flag.Bool("scale-up-explanation-enabled", false, "Whether the /scaleupz endpoint explaining why pending pods didn't trigger a scale-up in the last attempt is enabled")
+	scaleDownPipelineStatusEnabled     = flag.Bool("scale-down-pipeline-status-enabled", false, "Whether the /scaledownz endpoint reporting scale-down candidates, the progress of ongoing node drains and recent node deletion errors is enabled")
 	nodeInfoCacheExpireTime            = flag.Duration("node-info-cache-expire-time", 87600*time.Hour, "Node Info cache expire time for each item. Default value is 10 years.")
 
 	initialNodeGroupBackoffDuration = flag.Duration("initial-node-group-backoff-duration", 5*time.Minute,
@@ -228,13 +318,53 @@ var (
 		"nodeGroupBackoffResetTimeout is the time after last failed scale-up when the backoff duration is reset.")
 	maxScaleDownParallelismFlag             = flag.Int("max-scale-down-parallelism", 10, "Maximum number of nodes (both empty and needing drain) that can be deleted in parallel.")
 	maxDrainParallelismFlag                 = flag.Int("max-drain-parallelism", 1, "Maximum number of nodes needing drain, that can be drained and deleted in parallel.")
+	maxPodEvictionsPerMinuteFlag            = flag.Int("max-pod-evictions-per-minute", 0, "Maximum number of pods evicted from all nodes being drained within a minute. 0 means evictions aren't rate limited.")
+	nodeGroupMaxDrainParallelism            = flag.Int("node-group-max-drain-parallelism", 0, "The default maximum number of nodes needing drain from each node group, that can be drained and deleted in parallel - the value can be overridden per node group. 0 means only max-drain-parallelism applies")
+	nodeGroupMaxPodEvictionsPerMinute       = flag.Int("node-group-max-pod-evictions-per-minute", 0, "The default maximum number of pods evicted from nodes of each node group within a minute - the value can be overridden per node group. 0 means only max-pod-evictions-per-minute applies")
 	recordDuplicatedEvents                  = flag.Bool("record-duplicated-events", false, "enable duplication of similar events within a 5 minute window.")
 	maxNodesPerScaleUp                      = flag.Int("max-nodes-per-scaleup", 1000, "Max nodes added in a single scale-up. This is intended strictly for optimizing CA algorithm latency and not a tool to rate-limit scale-up throughput.")
 	maxNodeGroupBinpackingDuration          = flag.Duration("max-nodegroup-binpacking-duration", 10*time.Second, "Maximum time that will be spent in binpacking simulation for each NodeGroup.")
 	skipNodesWithSystemPods                 = flag.Bool("skip-nodes-with-system-pods", true, "If true cluster autoscaler will never delete nodes with pods from kube-system (except for DaemonSet or mirror pods)")
 	skipNodesWithLocalStorage               = flag.Bool("skip-nodes-with-local-storage", true, "If true cluster autoscaler will never delete nodes with pods with local storage, e.g. EmptyDir or HostPath")
+	pinnedPodDrainPolicy                    = flag.String("pinned-pod-drain-policy", string(pinnedrule.Ignore), "How pods whose node selector or required node affinity, e.g. on the hostname label, doesn't match any other node are handled in scale down. One of: Ignore (simulate them like other pods, so their node is unremovable as there is no place to move them), Block (block scale down with the PinnedToNode reason).")
+	sacrificablePinnedPodSelector           = flag.String("sacrificable-pinned-pod-selector", "", "Label selector of pods pinned to their node which are evicted with the node on scale down, without being rescheduled. No pinned pods are sacrificed if empty.")
+	singletonPodSelectors                   = multiStringFlag("singleton-pod-selector", "Label selector of singleton infrastructure pods, e.g. CSI controllers, which block scale down of their node unless another pod matching the same selector, in the same namespace, is running and ready on another node. Can be passed multiple times.")
+	maxRolloutDrainDelay                    = flag.Duration("max-rollout-drain-delay", 0, "Maximum time pods of a ReplicaSet being replaced by a newer ReplicaSet of the same Deployment block scale down of their node while the newer ReplicaSet doesn't have all of its replicas available, counted from its creation. Disabled if 0.")
+	localPersistentVolumesDrainPolicy       = flag.String("local-persistent-volumes-drain-policy", string(localpvrule.Ignore), "How pods using persistent volumes bound to their node, e.g. local persistent volumes, are handled in scale down. One of: Ignore, Warn (log, but don't block scale down), Block.")
+	initialEvictionFailureBackoff           = flag.Duration("initial-eviction-failure-backoff", 5*time.Minute, "How long pods whose eviction failed during scale down, e.g. rejected by a webhook or a disruption budget, block scale down of their node. The backoff doubles with each consecutive failure, and nodes with such pods are scaled down after other nodes once it passes. Disabled if 0.")
+	maxEvictionFailureBackoff               = flag.Duration("max-eviction-failure-backoff", time.Hour, "Maximum time pods whose evictions failed during scale down block scale down of their node")
+	debugContainerDrainMaxAge               = flag.Duration("debug-container-drain-max-age", 0, "How long a running ephemeral container, e.g. a kubectl debug session, blocks scale down of its node. Ephemeral containers running for longer are considered abandoned. Disabled if 0.")
 	skipNodesWithCustomControllerPods       = flag.Bool("skip-nodes-with-custom-controller-pods", true, "If true cluster autoscaler will never delete nodes with pods owned by custom controllers")
+	skipNodesWithStaticPods                 = flag.Bool("skip-nodes-with-static-pods", false, "If true cluster autoscaler will never delete nodes with kubelet static pods, unless they are annotated with cluster-autoscaler.kubernetes.io/static-pod-drainable: \"true\"")
+	customControllerScaleDiscovery          = flag.Bool("custom-controller-scale-discovery", false, "If true, pods owned by custom controllers don't block scale down despite skip-nodes-with-custom-controller-pods, if their controller implements the scale subresource and has more than 1 replica")
 	minReplicaCount                         = flag.Int("min-replica-count", 0, "Minimum number or replicas that a replica set or replication controller should have to allow their pods deletion in scale down")
+	drainabilityNamespacesConfigMapName     = flag.String("drainability-namespaces-config-map-name", "", "The name of the ConfigMap listing namespaces whose pods always or never block scale down. Pods from always drainable namespaces are still subject to their PodDisruptionBudgets. Disabled if empty.")
+	drainOptionsConfigMapName               = flag.String("drain-options-config-map-name", "", "The name of the ConfigMap from which --skip-nodes-with-system-pods, --skip-nodes-with-local-storage, --min-replica-count and the scale down utilization thresholds are reloaded at runtime, overriding the flags. Disabled if empty.")
+	drainabilityWebhookURL                  = flag.String("drainability-webhook-url", "", "The URL of a webhook deciding whether pods block scale down. Disabled if empty.")
+	drainabilityWebhookTimeout              = flag.Duration("drainability-webhook-timeout", 5*time.Second, "Timeout of a single drainability webhook call")
+	drainabilityWebhookFailurePolicy        = flag.String("drainability-webhook-failure-policy", string(webhookrule.Ignore), "How drainability webhook errors are handled. Ignore leaves the decision to other drainability rules, Fail blocks scale down of the node.")
+	drainabilityWebhookCacheTTL             = flag.Duration("drainability-webhook-cache-ttl", time.Minute, "How long drainability webhook responses are reused for unchanged pods. Caching is disabled if not positive.")
+	drainabilityPolicyName                  = flag.String("drainability-policy-name", "", "Name of the cluster scoped AutoscalerDrainPolicy custom resource configuring drainability rules on top of the flags: their order, mode, namespaces and parameters. Changes of the policy are applied without restarts. Requires the AutoscalerDrainPolicy CRD to be installed. Disabled if empty.")
+	drainMode                               = flag.String("drain-mode", string(options.EvictDrainMode), "How pods are removed from nodes during scale down. Evict uses the eviction subresource only, EvictOrDelete deletes pods whose evictions are persistently denied by admission webhooks, e.g. a misbehaving one. Other eviction errors never lead to deletion.")
+	webhookDenialPolicy                     = flag.String("webhook-denial-policy", string(options.RetryWebhookDenialPolicy), "How pod evictions denied by validating admission webhooks are handled during scale down. Retry retries them like other failed evictions, SkipNode aborts drain of the node on the first denial, ForceDelete deletes pods whose evictions keep being denied for --webhook-denial-timeout.")
+	webhookDenialTimeout                    = flag.Duration("webhook-denial-timeout", time.Minute, "How long evictions of a pod have to be denied by admission webhooks before the pod is deleted, if --webhook-denial-policy is ForceDelete. Should be shorter than --max-pod-eviction-time.")
+	drainWaitForRescheduling                = flag.Bool("drain-wait-for-rescheduling", false, "Whether evictions of pods with at least --drain-critical-pod-priority should start only once the lower-priority pods evicted from the node before them have terminated and their replacements have been scheduled. Pods are always evicted lowest priority first.")
+	drainCriticalPodPriority                = flag.Int("drain-critical-pod-priority", int(scheduling.SystemCriticalPriority), "Lowest priority of pods which are evicted only once lower-priority pods have been rescheduled, if --drain-wait-for-rescheduling is set. Defaults to the priority of system-cluster-critical pods.")
+	oneOffPodMaxLifetime                    = flag.Duration("one-off-pod-max-lifetime", 0, "How long pods not backed by a controller with restartPolicy Never or OnFailure, which are expected to finish on their own, block scale down of their node. Afterwards they are deleted on scale down. If 0, they block scale down like other pods not backed by a controller.")
+	drainStatefulSetsInOrdinalOrder         = flag.Bool("drain-statefulsets-in-ordinal-order", false, "Whether pods of the same StatefulSet on a drained node should be evicted one at a time in reverse ordinal order. For StatefulSets with OrderedReady pod management, each eviction also waits for the pod evicted before to be replaced by a ready pod.")
+	drainCancellationDelay                  = flag.Duration("drain-cancellation-delay", 0, "How long pods have to be pending, while they would fit on a node being drained for scale down if it wasn't being drained, for the drain of the node to be cancelled. Pods of the same controllers as pods on, or recently evicted from, drained nodes are ignored. Drains are never cancelled if 0.")
+	guidedDrain                             = flag.Bool("guided-drain", false, "Whether pending pods replacing the pods evicted from a node drained for scale down should be nominated to the nodes the evicted pods were simulated to move to. The scheduler tries the nominated node first and keeps room for the pod on it.")
+	evictionDryRunPreflight                 = flag.Bool("eviction-dry-run-preflight", false, "Whether dry-run evictions of pods should be issued before draining their node for scale down. Nodes on which an eviction would be rejected by a PodDisruptionBudget or an admission webhook aren't drained, and the evictions are registered as failed.")
+	nodeDrainTimeout                        = flag.Duration("node-drain-timeout", 0, "Maximum time to wait for evicted pods to terminate when draining a node for scale down. Pods remaining afterwards are force deleted, and nodes expected to drain faster are preferred for scale down. If 0, pods are awaited for their drain grace period plus headroom and the drain fails if any of them remain.")
+	scaleDownRecordingFile                  = flag.String("scale-down-recording-file", "", "Path of a file the state of the cluster is written to before each scale-down simulation, for offline replay with the scale-down-replay tool. Disabled if empty.")
+	enableScaleDownConsolidation            = flag.Bool("enable-scale-down-consolidation", false, "Whether replacing several underutilized nodes with a single larger node from a different node group should be simulated. Experimental: consolidation opportunities are only logged, nodes aren't replaced.")
+	scaleDownConsolidationMaxNodes          = flag.Int("scale-down-consolidation-max-nodes", 2, "Maximum number of underutilized nodes considered for replacement with a single larger node from a different node group when enable-scale-down-consolidation is set. Consolidation is disabled if lower than 2.")
+	scaleDownCandidateOrder                 = flag.String("scale-down-candidate-order", string(planner.DefaultCandidateOrder), "Order in which removable nodes are scaled down. Default leaves the order to the scale down scorers, MostExpensiveFirst adds the Price scorer, weighted as much as all other scorers together, to remove the most expensive nodes, e.g. on-demand before spot or larger before smaller, first.")
+	recordScaleDownBlockingPods             = flag.Bool("record-scale-down-blocking-pods", false, "Whether to emit events for nodes whose scale down is blocked by a pod, and for the blocking pods, and annotate such nodes with the blocking pod")
+	longTerminatingPodThreshold             = flag.Duration("long-terminating-pod-threshold", drain.PodLongTerminatingExtraThreshold, "How long a pod has to be terminating past its termination grace period to be ignored by scale down")
+	unremovableNodeRecheckMaxTimeout        = flag.Duration("unremovable-node-recheck-max-timeout", 0, "Maximum timeout before we check again a node that couldn't be removed before. The timeout starts at --unremovable-node-recheck-timeout and doubles each time the node is found unremovable again, so that chronically blocked nodes are simulated less often. Disabled if not above --unremovable-node-recheck-timeout.")
+	unremovableNodeStateCacheEnabled        = flag.Bool("unremovable-node-state-cache-enabled", false, "Whether unremovable nodes should be re-checked as soon as they or their pods change, and nodes blocked by their own pods shouldn't be re-checked until then, or until --unremovable-node-recheck-max-timeout passes")
+	drainabilityEvaluationParallelism       = flag.Int("drainability-evaluation-parallelism", 1, "Maximum number of nodes for which drainability of pods is evaluated concurrently during scale down simulation")
 	nodeDeleteDelayAfterTaint               = flag.Duration("node-delete-delay-after-taint", 5*time.Second, "How long to wait before deleting a node after tainting it")
 	scaleDownSimulationTimeout              = flag.Duration("scale-down-simulation-timeout", 30*time.Second, "How long should we run scale down simulation.")
 	parallelDrain                           = flag.Bool("parallel-drain", true, "Whether to allow parallel drain of nodes. This flag is deprecated and will be removed in future releases.")
@@ -242,6 +372,8 @@ var (
 	maxFreeDifferenceRatio                  = flag.Float64("max-free-difference-ratio", config.DefaultMaxFreeDifferenceRatio, "Maximum difference in free resources between two similar node groups to be considered for balancing. Value is a ratio of the smaller node group's free resource.")
 	maxAllocatableDifferenceRatio           = flag.Float64("max-allocatable-difference-ratio", config.DefaultMaxAllocatableDifferenceRatio, "Maximum difference in allocatable resources between two similar node groups to be considered for balancing. Value is a ratio of the smaller node group's allocatable resource.")
 	forceDaemonSets                         = flag.Bool("force-ds", false, "Blocks scale-up of node groups too small for all suitable Daemon Sets pods.")
+	scaleUpPreemptionPolicy                 = flag.String("scale-up-preemption-policy", string(podlistprocessor.NonePreemptionPolicy), "How pending pods which could be scheduled on existing nodes by preempting lower priority pods are treated by scale up. None doesn't simulate preemption, Skip doesn't trigger scale up for such pods and Delay doesn't trigger it until they are pending for --scale-up-preemption-delay.")
+	scaleUpPreemptionDelay                  = flag.Duration("scale-up-preemption-delay", 2*time.Minute, "How long pods which could preempt lower priority pods are given to do so before triggering scale up, with the Delay scale up preemption policy.")
 	dynamicNodeDeleteDelayAfterTaintEnabled = flag.Bool("dynamic-node-delete-delay-after-taint-enabled", false, "Enables dynamic adjustment of NodeDeleteDelayAfterTaint based of the latency between CA and api-server")
 )
 
@@ -272,6 +404,17 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 	if err != nil {
 		klog.Fatalf("Failed to parse flags: %v", err)
 	}
+	if *scaleDownWindow != "" {
+		if _, err := timewindow.Parse(*scaleDownWindow); err != nil {
+			klog.Fatalf("Failed to parse flags: invalid --scale-down-window: %v", err)
+		}
+	}
+	if _, err := utilization.ParseMode(*scaleDownUtilizationMode); err != nil {
+		klog.Fatalf("Failed to parse flags: invalid --scale-down-utilization-mode: %v", err)
+	}
+	if *scaleDownCPUReserveRatio < 0 || *scaleDownCPUReserveRatio >= 1 || *scaleDownMemoryReserveRatio < 0 || *scaleDownMemoryReserveRatio >= 1 {
+		klog.Fatalf("Failed to parse flags: --scale-down-cpu-reserve-ratio and --scale-down-memory-reserve-ratio have to be in [0, 1)")
+	}
 	if *maxDrainParallelismFlag > 1 && !*parallelDrain {
 		klog.Fatalf("Invalid configuration, could not use --max-drain-parallelism > 1 if --parallel-drain is false")
 	}
@@ -303,10 +446,19 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 			ScaleDownUnreadyTime:             *scaleDownUnreadyTime,
 			IgnoreDaemonSetsUtilization:      *ignoreDaemonSetsUtilization,
 			MaxNodeProvisionTime:             *maxNodeProvisionTime,
+			NodeReadinessTimeout:             *nodeReadinessTimeout,
+			MaxGracefulTerminationSec:        *maxGracefulTerminationFlag,
+			HeadroomNodes:                    *headroomNodes,
+			HeadroomRatio:                    *headroomRatio,
+			ScaleDownWindow:                  *scaleDownWindow,
+			ScaleDownUtilizationMode:         *scaleDownUtilizationMode,
+			MaxDrainParallelism:              *nodeGroupMaxDrainParallelism,
+			MaxPodEvictionsPerMinute:         *nodeGroupMaxPodEvictionsPerMinute,
 		},
 		CloudConfig:                      *cloudConfig,
 		CloudProviderName:                *cloudProviderFlag,
 		NodeGroupAutoDiscovery:           *nodeGroupAutoDiscoveryFlag,
+		NodeGroupConfigsEnabled:          *nodeGroupConfigsEnabled,
 		MaxTotalUnreadyPercentage:        *maxTotalUnreadyPercentage,
 		OkTotalUnreadyCount:              *okTotalUnreadyCount,
 		ScaleUpFromZero:                  *scaleUpFromZero,
@@ -315,11 +467,13 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		ExpanderNames:                    *expanderFlag,
 		GRPCExpanderCert:                 *grpcExpanderCert,
 		GRPCExpanderURL:                  *grpcExpanderURL,
+		GRPCExpanderStreaming:            *grpcExpanderStreaming,
 		IgnoreMirrorPodsUtilization:      *ignoreMirrorPodsUtilization,
 		MaxBulkSoftTaintCount:            *maxBulkSoftTaintCount,
 		MaxBulkSoftTaintTime:             *maxBulkSoftTaintTime,
 		MaxEmptyBulkDelete:               *maxEmptyBulkDeleteFlag,
 		MaxGracefulTerminationSec:        *maxGracefulTerminationFlag,
+		WindowsMaxGracefulTerminationSec: *windowsGracefulTermination,
 		MaxPodEvictionTime:               *maxPodEvictionTime,
 		MaxNodesTotal:                    *maxNodesTotal,
 		MaxCoresTotal:                    maxCoresTotal,
@@ -327,6 +481,9 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		MaxMemoryTotal:                   maxMemoryTotal,
 		MinMemoryTotal:                   minMemoryTotal,
 		GpuTotal:                         parsedGpuTotal,
+		ScaleDownCPUReserveRatio:         *scaleDownCPUReserveRatio,
+		ScaleDownMemoryReserveRatio:      *scaleDownMemoryReserveRatio,
+		ScaleDownReservePerZone:          *scaleDownReservePerZone,
 		NodeGroups:                       *nodeGroupsFlag,
 		EnforceNodeGroupMinSize:          *enforceNodeGroupMinSize,
 		ScaleDownDelayAfterAdd:           *scaleDownDelayAfterAdd,
@@ -340,7 +497,9 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		SchedulerConfig:                  parsedSchedConfig,
 		WriteStatusConfigMap:             *writeStatusConfigMapFlag,
 		StatusConfigMapName:              *statusConfigMapName,
+		PersistentStateConfigMapName:     *persistentStateConfigMapName,
 		BalanceSimilarNodeGroups:         *balanceSimilarNodeGroupsFlag,
+		ValidateBalancedScaleUp:          *validateBalancedScaleUp,
 		ConfigNamespace:                  *namespace,
 		ClusterName:                      *clusterName,
 		NodeAutoprovisioningEnabled:      *nodeAutoprovisioningEnabled,
@@ -365,31 +524,97 @@ func createAutoscalingOptions() config.AutoscalingOptions {
 		},
 		ClusterAPICloudConfigAuthoritative: *clusterAPICloudConfigAuthoritative,
 		CordonNodeBeforeTerminate:          *cordonNodeBeforeTerminate,
+		ScaleDownCordonOnly:                *scaleDownCordonOnly,
+		MaxCordonedNodeDeletionTime:        *maxCordonedNodeDeletionTime,
 		DaemonSetEvictionForEmptyNodes:     *daemonSetEvictionForEmptyNodes,
 		DaemonSetEvictionForOccupiedNodes:  *daemonSetEvictionForOccupiedNodes,
+		RespectDaemonSetPdbs:               *respectDaemonSetPdbs,
 		UserAgent:                          *userAgent,
 		InitialNodeGroupBackoffDuration:    *initialNodeGroupBackoffDuration,
 		MaxNodeGroupBackoffDuration:        *maxNodeGroupBackoffDuration,
 		NodeGroupBackoffResetTimeout:       *nodeGroupBackoffResetTimeout,
 		MaxScaleDownParallelism:            *maxScaleDownParallelismFlag,
 		MaxDrainParallelism:                *maxDrainParallelismFlag,
+		MaxPodEvictionsPerMinute:           *maxPodEvictionsPerMinuteFlag,
 		RecordDuplicatedEvents:             *recordDuplicatedEvents,
 		MaxNodesPerScaleUp:                 *maxNodesPerScaleUp,
 		MaxNodeGroupBinpackingDuration:     *maxNodeGroupBinpackingDuration,
 		NodeDeletionBatcherInterval:        *nodeDeletionBatcherInterval,
+		MaxEmptyNodeDeletionBatchSize:      *maxEmptyDeletionBatchSize,
 		SkipNodesWithSystemPods:            *skipNodesWithSystemPods,
+		SystemPodNamespaces:                *systemPodNamespacesFlag,
 		SkipNodesWithLocalStorage:          *skipNodesWithLocalStorage,
 		MinReplicaCount:                    *minReplicaCount,
 		NodeDeleteDelayAfterTaint:          *nodeDeleteDelayAfterTaint,
 		ScaleDownSimulationTimeout:         *scaleDownSimulationTimeout,
 		ParallelDrain:                      *parallelDrain,
 		SkipNodesWithCustomControllerPods:  *skipNodesWithCustomControllerPods,
+		CustomControllerScaleDiscovery:     *customControllerScaleDiscovery,
+		SkipNodesWithStaticPods:            *skipNodesWithStaticPods,
 		NodeGroupSetRatios: config.NodeGroupDifferenceRatios{
 			MaxCapacityMemoryDifferenceRatio: *maxCapacityMemoryDifferenceRatio,
 			MaxAllocatableDifferenceRatio:    *maxAllocatableDifferenceRatio,
 			MaxFreeDifferenceRatio:           *maxFreeDifferenceRatio,
 		},
 		DynamicNodeDeleteDelayAfterTaintEnabled: *dynamicNodeDeleteDelayAfterTaintEnabled,
+		DrainabilityNamespacesConfigMapName:     *drainabilityNamespacesConfigMapName,
+		DrainOptionsConfigMapName:               *drainOptionsConfigMapName,
+		DrainabilityOverrideNamespaces:          *drainabilityOverrideNamespacesFlag,
+		DrainabilityShadowRules:                 *drainabilityShadowRulesFlag,
+		DrainabilityPolicyName:                  *drainabilityPolicyName,
+		RecordScaleDownBlockingPods:             *recordScaleDownBlockingPods,
+		LongTerminatingPodThreshold:             *longTerminatingPodThreshold,
+		UnremovableNodeStateCacheEnabled:        *unremovableNodeStateCacheEnabled,
+		UnremovableNodeRecheckMaxTimeout:        *unremovableNodeRecheckMaxTimeout,
+		DrainabilityEvaluationParallelism:       *drainabilityEvaluationParallelism,
+		DrainabilityWebhookURL:                  *drainabilityWebhookURL,
+		DrainabilityWebhookTimeout:              *drainabilityWebhookTimeout,
+		DrainabilityWebhookFailurePolicy:        *drainabilityWebhookFailurePolicy,
+		DrainabilityWebhookCacheTTL:             *drainabilityWebhookCacheTTL,
+		DrainMode:                               *drainMode,
+		NodeDrainTimeout:                        *nodeDrainTimeout,
+		WebhookDenialPolicy:                     *webhookDenialPolicy,
+		WebhookDenialTimeout:                    *webhookDenialTimeout,
+		DrainWaitForRescheduling:                *drainWaitForRescheduling,
+		DrainCriticalPodPriority:                *drainCriticalPodPriority,
+		OneOffPodMaxLifetime:                    *oneOffPodMaxLifetime,
+		DrainStatefulSetsInOrdinalOrder:         *drainStatefulSetsInOrdinalOrder,
+		DrainCancellationDelay:                  *drainCancellationDelay,
+		EvictionDryRunPreflight:                 *evictionDryRunPreflight,
+		GuidedDrain:                             *guidedDrain,
+		LocalPersistentVolumesDrainPolicy:       *localPersistentVolumesDrainPolicy,
+		PinnedPodDrainPolicy:                    *pinnedPodDrainPolicy,
+		SacrificablePinnedPodSelector:           *sacrificablePinnedPodSelector,
+		SingletonPodSelectors:                   *singletonPodSelectors,
+		MaxRolloutDrainDelay:                    *maxRolloutDrainDelay,
+		ScaleDownRecordingFile:                  *scaleDownRecordingFile,
+		EnableScaleDownConsolidation:            *enableScaleDownConsolidation,
+		ScaleDownConsolidationMaxNodes:          *scaleDownConsolidationMaxNodes,
+		ScaleDownCandidateOrder:                 *scaleDownCandidateOrder,
+		ScaleDownScorers:                        *scaleDownScorersFlag,
+		ScaleDownDisabledTaints:                 *scaleDownDisabledTaintsFlag,
+		ScaleDownDisabledNodeSelectors:          *scaleDownDisabledNodeSelectorsFlag,
+		ScaleDownPreferredTaints:                *scaleDownPreferredTaintsFlag,
+		ScaleDownPreferredNodeSelectors:         *scaleDownPreferredNodeSelectorsFlag,
+		DebugContainerDrainMaxAge:               *debugContainerDrainMaxAge,
+		InitialEvictionFailureBackoff:           *initialEvictionFailureBackoff,
+		MaxEvictionFailureBackoff:               *maxEvictionFailureBackoff,
+		WriteScaleDownCandidatesResource:        *writeScaleDownCandidatesResource,
+		AuditLogSink:                            *auditLogSink,
+		NodeReadinessTaints:                     *nodeReadinessTaintsFlag,
+		NodeReadinessConditions:                 *nodeReadinessConditionsFlag,
+		NodeReadinessPodSelectors:               *nodeReadinessPodsFlag,
+		NodeAutoRepairEnabled:                   *nodeAutoRepairEnabled,
+		NodeAutoRepairUnhealthyTime:             *nodeAutoRepairUnhealthyTime,
+		NodeAutoRepairConditions:                *nodeAutoRepairConditionsFlag,
+		MaxNodeAutoRepairs:                      *maxNodeAutoRepairs,
+		EnableProvisioningRequests:              *enableProvisioningRequests,
+		WriteNodeGroupResizeRecommendations:     *writeNodeGroupResizeRecommendations,
+		NodeGroupResizeUtilizationThreshold:     *nodeGroupResizeUtilizationThreshold,
+		NodeGroupResizeRecommendationDelay:      *nodeGroupResizeRecommendationDelay,
+		DynamicResourceAllocationEnabled:        *enableDynamicResourceAllocation,
+		ScaleUpPreemptionPolicy:                 *scaleUpPreemptionPolicy,
+		ScaleUpPreemptionDelay:                  *scaleUpPreemptionDelay,
 	}
 }
 
@@ -437,7 +662,7 @@ func registerSignalHandlers(autoscaler core.Autoscaler) {
 	}()
 }
 
-func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter) (core.Autoscaler, error) {
+func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter, drainabilityDryRun *dryrun.Handler, scaleUpExplanation *status.ScaleUpExplanationProcessor, scaleDownPipelineStatus *status.ScaleDownPipelineProcessor, priorityExpanderStatus *priority.Status, schedulingGatesWatcher *kube_util.SchedulingGatesWatcher) (core.Autoscaler, error) {
 	// Create basic config from flags.
 	autoscalingOptions := createAutoscalingOptions()
 
@@ -446,11 +671,18 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 	kubeClientConfig.QPS = float32(autoscalingOptions.KubeClientQPS)
 	kubeClient := createKubeClient(kubeClientConfig)
 
-	// Informer transform to trim ManagedFields for memory efficiency.
+	// Informer transform to trim ManagedFields and optionally intern strings for memory efficiency.
+	var interner *intern.Interner
+	if *internObjectStrings {
+		interner = intern.NewInterner(intern.DefaultMaxEntries)
+	}
 	trim := func(obj interface{}) (interface{}, error) {
 		if accessor, err := meta.Accessor(obj); err == nil {
 			accessor.SetManagedFields(nil)
 		}
+		if interner != nil {
+			return interner.Transform(obj)
+		}
 		return obj, nil
 	}
 	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, informers.WithTransform(trim))
@@ -461,7 +693,154 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 	if err != nil {
 		return nil, err
 	}
-	deleteOptions := options.NewNodeDeleteOptions(autoscalingOptions)
+	if _, err := options.ParseDrainMode(autoscalingOptions.DrainMode); err != nil {
+		return nil, err
+	}
+	if _, err := podlistprocessor.ParsePreemptionPolicy(autoscalingOptions.ScaleUpPreemptionPolicy); err != nil {
+		return nil, err
+	}
+	if _, err := options.ParseWebhookDenialPolicy(autoscalingOptions.WebhookDenialPolicy); err != nil {
+		return nil, err
+	}
+	if _, err := planner.ParseCandidateOrder(autoscalingOptions.ScaleDownCandidateOrder); err != nil {
+		return nil, err
+	}
+	if _, err := nodeselector.Parse(autoscalingOptions.ScaleDownDisabledTaints, autoscalingOptions.ScaleDownDisabledNodeSelectors); err != nil {
+		return nil, err
+	}
+	scaleDownPreferredNodes, err := nodeselector.Parse(autoscalingOptions.ScaleDownPreferredTaints, autoscalingOptions.ScaleDownPreferredNodeSelectors)
+	if err != nil {
+		return nil, err
+	}
+	deleteOptions := autoscalingOptions.NodeDeleteOptions()
+	var drainOptionsReloader *reload.Reloader
+	if autoscalingOptions.DrainOptionsConfigMapName != "" {
+		// The lister lives for the whole lifetime of the process, so it never receives the termination msg.
+		stopChannel := make(chan struct{})
+		lister := kube_util.NewConfigMapListerForNamespace(kubeClient, stopChannel, autoscalingOptions.ConfigNamespace)
+		recorder := kube_util.CreateEventRecorder(kubeClient, *recordDuplicatedEvents)
+		drainOptionsReloader = reload.New(lister.ConfigMaps(autoscalingOptions.ConfigNamespace), autoscalingOptions.DrainOptionsConfigMapName, recorder)
+		deleteOptions.Reloader = drainOptionsReloader
+	}
+	drainabilityRules := rules.Default(deleteOptions)
+	if autoscalingOptions.CustomControllerScaleDiscovery && deleteOptions.SkipNodesWithCustomControllerPods {
+		scaleLookup := replicatedrule.NewDiscoveryScaleLookup(kubeClient.Discovery(), dynamic.NewForConfigOrDie(kubeClientConfig), replicatedrule.DefaultScaleLookupCacheTTL)
+		drainabilityRules = rules.DefaultWithScaleLookup(deleteOptions, scaleLookup)
+	}
+	if autoscalingOptions.DrainabilityNamespacesConfigMapName != "" {
+		// The lister lives for the whole lifetime of the process, so it never receives the termination msg.
+		stopChannel := make(chan struct{})
+		lister := kube_util.NewConfigMapListerForNamespace(kubeClient, stopChannel, autoscalingOptions.ConfigNamespace)
+		namespaces := namespacerule.NewNamespaces(lister.ConfigMaps(autoscalingOptions.ConfigNamespace), autoscalingOptions.DrainabilityNamespacesConfigMapName)
+		// Never drainable namespaces are designated by the cluster operator, so the safe-to-evict annotation on
+		// the pods doesn't override them. Always drainable namespaces make pods drainable the same way the
+		// annotation does, so they don't override disruption budgets.
+		drainabilityRules = append(drainabilityRules,
+			rules.WithPriority(namespacerule.New(namespaces), rules.BudgetPriority),
+			rules.WithPriority(namespacerule.NewDrainable(namespaces), rules.NonBlockingPriority))
+	}
+	if len(autoscalingOptions.DrainabilityOverrideNamespaces) > 0 {
+		// The informer lives for the whole lifetime of the process, so it never receives the termination msg.
+		stopChannel := make(chan struct{})
+		lister := overriderule.NewLister(dynamic.NewForConfigOrDie(kubeClientConfig), stopChannel)
+		overrideRule := overriderule.New(lister, autoscalingOptions.DrainabilityOverrideNamespaces)
+		// Overrides make pods drainable the same way the safe-to-evict annotation does, so they don't override
+		// disruption budgets.
+		drainabilityRules = append(drainabilityRules, rules.WithPriority(overrideRule, rules.NonBlockingPriority))
+	}
+	if autoscalingOptions.DrainabilityWebhookURL != "" {
+		failurePolicy, err := webhookrule.ParseFailurePolicy(autoscalingOptions.DrainabilityWebhookFailurePolicy)
+		if err != nil {
+			return nil, err
+		}
+		webhook := webhookrule.NewWebhook(autoscalingOptions.DrainabilityWebhookURL, autoscalingOptions.DrainabilityWebhookTimeout, failurePolicy, autoscalingOptions.DrainabilityWebhookCacheTTL)
+		// The webhook isn't called for pods skipped by other rules. Pods it accepts are drainable the same way as with
+		// the safe-to-evict annotation, so they don't override disruption budgets.
+		drainabilityRules = append(drainabilityRules,
+			rules.WithPriority(webhookrule.New(webhook), rules.BudgetPriority),
+			rules.WithPriority(webhookrule.NewDrainable(webhook), rules.NonBlockingPriority))
+	}
+	localPVPolicy, err := localpvrule.ParsePolicy(autoscalingOptions.LocalPersistentVolumesDrainPolicy)
+	if err != nil {
+		return nil, err
+	}
+	if localPVPolicy != localpvrule.Ignore {
+		localPVRule := localpvrule.New(informerFactory.Core().V1().PersistentVolumeClaims().Lister(), informerFactory.Core().V1().PersistentVolumes().Lister(), localPVPolicy)
+		drainabilityRules = append(drainabilityRules, rules.WithPriority(localPVRule, rules.BlockingPriority))
+	}
+	pinnedPodPolicy, err := pinnedrule.ParsePolicy(autoscalingOptions.PinnedPodDrainPolicy)
+	if err != nil {
+		return nil, err
+	}
+	var sacrificablePinnedPods labels.Selector
+	if autoscalingOptions.SacrificablePinnedPodSelector != "" {
+		sacrificablePinnedPods, err = labels.Parse(autoscalingOptions.SacrificablePinnedPodSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sacrificable pinned pod selector %q: %v", autoscalingOptions.SacrificablePinnedPodSelector, err)
+		}
+	}
+	if pinnedPodPolicy != pinnedrule.Ignore || sacrificablePinnedPods != nil {
+		// Pinned pods can't be moved regardless of the safe-to-evict
+		// annotation, but sacrificing them is still subject to disruption budgets.
+		pinnedRule := pinnedrule.New(pinnedPodPolicy, sacrificablePinnedPods)
+		drainabilityRules = append(drainabilityRules, rules.WithPriority(pinnedRule, rules.BudgetPriority))
+	}
+	if len(autoscalingOptions.SingletonPodSelectors) > 0 {
+		var singletonPods []labels.Selector
+		for _, s := range autoscalingOptions.SingletonPodSelectors {
+			selector, err := labels.Parse(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid singleton pod selector %q: %v", s, err)
+			}
+			singletonPods = append(singletonPods, selector)
+		}
+		// Singleton infrastructure pods are designated by the cluster operator, so
+		// the safe-to-evict annotation on the pods doesn't override them.
+		drainabilityRules = append(drainabilityRules, rules.WithPriority(singletonrule.New(singletonPods), rules.BudgetPriority))
+	}
+	if autoscalingOptions.MaxRolloutDrainDelay > 0 {
+		// Rollouts are in progress regardless of the safe-to-evict annotation
+		// on the old pods, so it doesn't override the delay.
+		rolloutRule := rolloutrule.New(autoscalingOptions.MaxRolloutDrainDelay)
+		drainabilityRules = append(drainabilityRules, rules.WithPriority(rolloutRule, rules.BudgetPriority))
+	}
+	if autoscalingOptions.DebugContainerDrainMaxAge > 0 {
+		// Debug sessions are not a property of the workload, so the safe-to-evict
+		// annotation doesn't override them.
+		debugContainerRule := debugcontainerrule.New(autoscalingOptions.DebugContainerDrainMaxAge)
+		drainabilityRules = append(drainabilityRules, rules.WithPriority(debugContainerRule, rules.BudgetPriority))
+	}
+	var evictionBackoff *evictionbackoffrule.Ledger
+	if autoscalingOptions.InitialEvictionFailureBackoff > 0 {
+		evictionBackoff = evictionbackoffrule.NewLedger(autoscalingOptions.InitialEvictionFailureBackoff, autoscalingOptions.MaxEvictionFailureBackoff)
+		// Failed evictions were rejected by the API server, so the safe-to-evict
+		// annotation doesn't override them.
+		drainabilityRules = append(drainabilityRules, rules.WithPriority(evictionbackoffrule.New(evictionBackoff), rules.BudgetPriority))
+	}
+	// Expendable pods are ignored on drain the same way they are ignored when
+	// simulating whether other pods fit.
+	priorityClassLister := informerFactory.Scheduling().V1().PriorityClasses().Lister()
+	expendablePods := expendablerule.New(autoscalingOptions.ExpendablePodsPriorityCutoff, priorityClassLister)
+	drainabilityRules = append(drainabilityRules, rules.WithPriority(expendablePods, rules.SkipPriority))
+	if len(autoscalingOptions.DrainabilityShadowRules) > 0 {
+		drainabilityRules, err = drainabilityRules.WithShadowRules(autoscalingOptions.DrainabilityShadowRules)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if autoscalingOptions.DrainabilityPolicyName != "" {
+		// The informer lives for the whole lifetime of the process, so it never receives the termination msg.
+		stopChannel := make(chan struct{})
+		lister := drainpolicy.NewLister(dynamic.NewForConfigOrDie(kubeClientConfig), stopChannel)
+		chain := drainpolicy.NewChain(lister, autoscalingOptions.DrainabilityPolicyName, drainabilityRules, drainpolicy.DefaultFactories(priorityClassLister))
+		drainabilityRules = rules.Rules{chain}
+	}
+
+	var dynamicResources *dynamicresources.Provider
+	if autoscalingOptions.DynamicResourceAllocationEnabled {
+		resourceInformers := informerFactory.Resource().V1alpha2()
+		dynamicResources = dynamicresources.NewProvider(resourceInformers.ResourceClaims().Lister(), resourceInformers.ResourceClaimTemplates().Lister(), resourceInformers.ResourceClasses().Lister())
+	}
 
 	opts := core.AutoscalerOptions{
 		AutoscalingOptions:   autoscalingOptions,
@@ -472,22 +851,102 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 		DebuggingSnapshotter: debuggingSnapshotter,
 		PredicateChecker:     predicateChecker,
 		DeleteOptions:        deleteOptions,
+		DrainabilityRules:    drainabilityRules,
+		ExpendablePods:       expendablePods,
+		EvictionBackoff:      evictionBackoff,
+		NodeUsage:            utilization.NewMetricsServerUsageProvider(kubeClient.Discovery().RESTClient(), *scanInterval),
+		DynamicResources:     dynamicResources,
+	}
+
+	if autoscalingOptions.PersistentStateConfigMapName != "" {
+		opts.StateStore = persistentstate.NewStore(kubeClient, autoscalingOptions.ConfigNamespace, autoscalingOptions.PersistentStateConfigMapName)
 	}
 
 	opts.Processors = ca_processors.DefaultProcessors(autoscalingOptions)
+	if drainOptionsReloader != nil {
+		opts.Processors.NodeGroupConfigProcessor = nodegroupconfig.NewReloadingNodeGroupConfigProcessor(autoscalingOptions.NodeGroupDefaults, drainOptionsReloader)
+	}
 	opts.Processors.TemplateNodeInfoProvider = nodeinfosprovider.NewDefaultTemplateNodeInfoProvider(nodeInfoCacheExpireTime, *forceDaemonSets)
-	opts.Processors.PodListProcessor = podlistprocessor.NewDefaultPodListProcessor(opts.PredicateChecker)
+	opts.Processors.PodListProcessor = podlistprocessor.NewDefaultPodListProcessor(opts.PredicateChecker, opts.Processors.NodeGroupConfigProcessor)
 	scaleDownCandidatesComparers := []scaledowncandidates.CandidatesComparer{}
 	if autoscalingOptions.ParallelDrain {
 		sdCandidatesSorting := previouscandidates.NewPreviousCandidates()
 		scaleDownCandidatesComparers = []scaledowncandidates.CandidatesComparer{
-			emptycandidates.NewEmptySortingProcessor(emptycandidates.NewNodeInfoGetter(opts.ClusterSnapshot), deleteOptions, rules.Default(deleteOptions)),
+			emptycandidates.NewEmptySortingProcessor(emptycandidates.NewNodeInfoGetter(opts.ClusterSnapshot), deleteOptions, drainabilityRules),
 			sdCandidatesSorting,
 		}
 		opts.Processors.ScaleDownCandidatesNotifier.Register(sdCandidatesSorting)
 	}
+	if !scaleDownPreferredNodes.Empty() {
+		scaleDownCandidatesComparers = append([]scaledowncandidates.CandidatesComparer{nodeselector.NewPreferredCandidates(scaleDownPreferredNodes)}, scaleDownCandidatesComparers...)
+	}
 	sdProcessor := scaledowncandidates.NewScaleDownCandidatesSortingProcessor(scaleDownCandidatesComparers)
 	opts.Processors.ScaleDownNodeProcessor = sdProcessor
+	if autoscalingOptions.WriteScaleDownCandidatesResource {
+		opts.Processors.ScaleDownStatusProcessor = status.NewCombinedScaleDownStatusProcessor([]status.ScaleDownStatusProcessor{
+			opts.Processors.ScaleDownStatusProcessor,
+			status.NewScaleDownCandidatesProcessor(dynamic.NewForConfigOrDie(kubeClientConfig)),
+		})
+	}
+	if *priorityExpanderStatusEnabled {
+		opts.PriorityExpanderStatus = priorityExpanderStatus
+	}
+	if *scaleUpExplanationEnabled {
+		opts.Processors.ScaleUpStatusProcessor = status.NewCombinedScaleUpStatusProcessor([]status.ScaleUpStatusProcessor{
+			opts.Processors.ScaleUpStatusProcessor,
+			scaleUpExplanation,
+		})
+	}
+	if *scaleDownPipelineStatusEnabled {
+		opts.Processors.ScaleDownStatusProcessor = status.NewCombinedScaleDownStatusProcessor([]status.ScaleDownStatusProcessor{
+			opts.Processors.ScaleDownStatusProcessor,
+			scaleDownPipelineStatus,
+		})
+	}
+	if autoscalingOptions.AuditLogSink != "" {
+		auditSink, err := audit.ParseSink(autoscalingOptions.AuditLogSink)
+		if err != nil {
+			return nil, err
+		}
+		auditLog := audit.NewLog(auditSink)
+		opts.Processors.ScaleUpStatusProcessor = status.NewCombinedScaleUpStatusProcessor([]status.ScaleUpStatusProcessor{
+			opts.Processors.ScaleUpStatusProcessor,
+			audit.NewScaleUpStatusProcessor(auditLog),
+		})
+		opts.Processors.ScaleDownStatusProcessor = status.NewCombinedScaleDownStatusProcessor([]status.ScaleDownStatusProcessor{
+			opts.Processors.ScaleDownStatusProcessor,
+			audit.NewScaleDownStatusProcessor(auditLog),
+		})
+	}
+	if len(autoscalingOptions.ScaleDownScorers) > 0 {
+		scaleDownScorers, err := scoring.ParseScorers(autoscalingOptions.ScaleDownScorers)
+		if err != nil {
+			return nil, err
+		}
+		opts.Processors.ScaleDownCandidatesScorer = scoring.NewFramework(scaleDownScorers)
+	}
+	readinessGates, err := nodereadiness.ParseReadinessGates(autoscalingOptions.NodeReadinessTaints, autoscalingOptions.NodeReadinessConditions, autoscalingOptions.NodeReadinessPodSelectors)
+	if err != nil {
+		return nil, err
+	}
+	if !readinessGates.Empty() {
+		opts.Processors.NodeReadinessProcessor = nodereadiness.NewGatesNodeReadinessProcessor(readinessGates, opts.Processors.NodeGroupConfigProcessor)
+	}
+	if autoscalingOptions.EnableProvisioningRequests {
+		provisioningRequestClient := provisioningrequest.NewClient(dynamic.NewForConfigOrDie(kubeClientConfig), kubeClient)
+		opts.Processors.PodListProcessor = pods.NewCombinedPodListProcessor([]pods.PodListProcessor{
+			opts.Processors.PodListProcessor,
+			provisioningrequest.NewPodListProcessor(provisioningRequestClient, opts.PredicateChecker),
+		})
+		opts.ScaleUpOrchestrator = provisioningrequest.NewOrchestrator(provisioningRequestClient, orchestrator.New())
+	}
+	if autoscalingOptions.WriteNodeGroupResizeRecommendations {
+		opts.Processors.AutoscalingStatusProcessor = status.NewCombinedAutoscalingStatusProcessor([]status.AutoscalingStatusProcessor{
+			opts.Processors.AutoscalingStatusProcessor,
+			nodegroupresize.NewRecommendationProcessor(dynamic.NewForConfigOrDie(kubeClientConfig), opts.PredicateChecker, opts.Processors.NodeGroupConfigProcessor,
+				autoscalingOptions.NodeGroupResizeUtilizationThreshold, autoscalingOptions.NodeGroupResizeRecommendationDelay),
+		})
+	}
 
 	var nodeInfoComparator nodegroupset.NodeInfoComparator
 	if len(autoscalingOptions.BalancingLabels) > 0 {
@@ -515,12 +974,29 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 	metrics.UpdateCPULimitsCores(autoscalingOptions.MinCoresTotal, autoscalingOptions.MaxCoresTotal)
 	metrics.UpdateMemoryLimitsBytes(autoscalingOptions.MinMemoryTotal, autoscalingOptions.MaxMemoryTotal)
 
+	if autoscalingOptions.NodeGroupConfigsEnabled {
+		// The informer lives for the whole lifetime of the process, so it never receives the termination msg.
+		stopChannel := make(chan struct{})
+		lister := nodegroupconfigcrd.NewLister(dynamic.NewForConfigOrDie(kubeClientConfig), stopChannel)
+		opts.CloudProvider = nodegroupconfigcrd.NewCloudProvider(cloudBuilder.NewCloudProvider(autoscalingOptions), lister)
+	}
+
 	// Create autoscaler.
 	autoscaler, err := core.NewAutoscaler(opts)
 	if err != nil {
 		return nil, err
 	}
 
+	if *drainabilityDryRunEnabled {
+		drainabilityDryRun.SetSource(kube_util.NewListerRegistryWithDefaultListers(informerFactory), deleteOptions, drainabilityRules, opts.PodsToMove)
+	}
+
+	if schedulingGatesWatcher != nil {
+		if err := schedulingGatesWatcher.Watch(informerFactory.Core().V1().Pods().Informer()); err != nil {
+			return nil, err
+		}
+	}
+
 	// Start informers. This must come after fully constructing the autoscaler because
 	// additional informers might have been registered in the factory during NewAutoscaler.
 	stop := make(chan struct{})
@@ -529,10 +1005,15 @@ func buildAutoscaler(debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter
 	return autoscaler, nil
 }
 
-func run(healthCheck *metrics.HealthCheck, debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter) {
+func run(healthCheck *metrics.HealthCheck, debuggingSnapshotter debuggingsnapshot.DebuggingSnapshotter, drainabilityDryRun *dryrun.Handler, scaleUpExplanation *status.ScaleUpExplanationProcessor, scaleDownPipelineStatus *status.ScaleDownPipelineProcessor, priorityExpanderStatus *priority.Status) {
 	metrics.RegisterAll(*emitPerNodeGroupMetrics)
 
-	autoscaler, err := buildAutoscaler(debuggingSnapshotter)
+	var schedulingGatesWatcher *kube_util.SchedulingGatesWatcher
+	if *schedulingGatesBatchWindow > 0 {
+		schedulingGatesWatcher = kube_util.NewSchedulingGatesWatcher(*schedulingGatesBatchWindow)
+	}
+
+	autoscaler, err := buildAutoscaler(debuggingSnapshotter, drainabilityDryRun, scaleUpExplanation, scaleDownPipelineStatus, priorityExpanderStatus, schedulingGatesWatcher)
 	if err != nil {
 		klog.Fatalf("Failed to create autoscaler: %v", err)
 	}
@@ -548,24 +1029,29 @@ func run(healthCheck *metrics.HealthCheck, debuggingSnapshotter debuggingsnapsho
 		klog.Fatalf("Failed to autoscaler background components: %v", err)
 	}
 
+	runOnce := func() {
+		loopStart := time.Now()
+		metrics.UpdateLastTime(metrics.Main, loopStart)
+		healthCheck.UpdateLastActivity(loopStart)
+
+		err := autoscaler.RunOnce(loopStart)
+		if err != nil && err.Type() != errors.TransientError {
+			metrics.RegisterError(err)
+		} else {
+			healthCheck.UpdateLastSuccessfulRun(time.Now())
+		}
+
+		metrics.UpdateDurationFromStart(metrics.Main, loopStart)
+	}
+
 	// Autoscale ad infinitum.
 	for {
 		select {
 		case <-time.After(*scanInterval):
-			{
-				loopStart := time.Now()
-				metrics.UpdateLastTime(metrics.Main, loopStart)
-				healthCheck.UpdateLastActivity(loopStart)
-
-				err := autoscaler.RunOnce(loopStart)
-				if err != nil && err.Type() != errors.TransientError {
-					metrics.RegisterError(err)
-				} else {
-					healthCheck.UpdateLastSuccessfulRun(time.Now())
-				}
-
-				metrics.UpdateDurationFromStart(metrics.Main, loopStart)
-			}
+			runOnce()
+		case <-schedulingGatesWatcher.Released():
+			klog.V(1).Infof("Pods released from scheduling gates are unschedulable, reevaluating the cluster")
+			runOnce()
 		}
 	}
 }
@@ -598,6 +1084,11 @@ func main() {
 	klog.V(1).Infof("Cluster Autoscaler %s", version.ClusterAutoscalerVersion)
 
 	debuggingSnapshotter := debuggingsnapshot.NewDebuggingSnapshotter(*debuggingSnapshotEnabled)
+	drainabilityDryRun := dryrun.NewHandler()
+	drainabilitytrace.SetEnabled(*drainabilityTraceEnabled)
+	scaleUpExplanation := status.NewScaleUpExplanationProcessor()
+	scaleDownPipelineStatus := status.NewScaleDownPipelineProcessor()
+	priorityExpanderStatus := priority.NewStatus()
 
 	go func() {
 		pathRecorderMux := mux.NewPathRecorderMux("cluster-autoscaler")
@@ -608,6 +1099,18 @@ func main() {
 		if *debuggingSnapshotEnabled {
 			pathRecorderMux.HandleFunc("/snapshotz", debuggingSnapshotter.ResponseHandler)
 		}
+		if *drainabilityDryRunEnabled {
+			pathRecorderMux.Handle("/drainabilityz", drainabilityDryRun)
+		}
+		if *scaleUpExplanationEnabled {
+			pathRecorderMux.Handle("/scaleupz", scaleUpExplanation)
+		}
+		if *scaleDownPipelineStatusEnabled {
+			pathRecorderMux.Handle("/scaledownz", scaleDownPipelineStatus)
+		}
+		if *priorityExpanderStatusEnabled {
+			pathRecorderMux.Handle("/priorityexpanderz", priorityExpanderStatus)
+		}
 		pathRecorderMux.HandleFunc("/health-check", healthCheck.ServeHTTP)
 		if *enableProfiling {
 			routes.Profiling{}.Install(pathRecorderMux)
@@ -617,7 +1120,7 @@ func main() {
 	}()
 
 	if !leaderElection.LeaderElect {
-		run(healthCheck, debuggingSnapshotter)
+		run(healthCheck, debuggingSnapshotter, drainabilityDryRun, scaleUpExplanation, scaleDownPipelineStatus, priorityExpanderStatus)
 	} else {
 		id, err := os.Hostname()
 		if err != nil {
@@ -657,7 +1160,7 @@ func main() {
 				OnStartedLeading: func(_ ctx.Context) {
 					// Since we are committing a suicide after losing
 					// mastership, we can safely ignore the argument.
-					run(healthCheck, debuggingSnapshotter)
+					run(healthCheck, debuggingSnapshotter, drainabilityDryRun, scaleUpExplanation, scaleDownPipelineStatus, priorityExpanderStatus)
 				},
 				OnStoppedLeading: func() {
 					klog.Fatalf("lost master")
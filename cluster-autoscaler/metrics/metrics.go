@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	drainabilitymetrics "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/metrics"
 
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/gpu"
@@ -44,6 +45,12 @@ type FunctionLabel string
 // NodeGroupType describes node group relation to CA
 type NodeGroupType string
 
+// EvictionRequestResult describes the result of an eviction API request
+type EvictionRequestResult string
+
+// NodeAutoRepairResult describes the result of starting repairs of unhealthy nodes
+type NodeAutoRepairResult string
+
 const (
 	caNamespace           = "cluster_autoscaler"
 	readyLabel            = "ready"
@@ -66,6 +73,20 @@ const (
 	// Timeout was encountered when trying to scale-up
 	Timeout FailedScaleUpReason = "timeout"
 
+	// EvictionSucceeded means the pod was evicted or was already gone
+	EvictionSucceeded EvictionRequestResult = "succeeded"
+	// EvictionRejectedByBudget means the eviction would violate a disruption budget
+	EvictionRejectedByBudget EvictionRequestResult = "rejectedByBudget"
+	// EvictionDeniedByWebhook means the eviction was denied by a validating admission webhook
+	EvictionDeniedByWebhook EvictionRequestResult = "deniedByWebhook"
+	// EvictionFailed means the eviction failed for another reason
+	EvictionFailed EvictionRequestResult = "failed"
+
+	// NodeAutoRepairStarted means the repaired node is being drained and deleted
+	NodeAutoRepairStarted NodeAutoRepairResult = "started"
+	// NodeAutoRepairFailed means the repair of the node failed to start
+	NodeAutoRepairFailed NodeAutoRepairResult = "failed"
+
 	// DirectionScaleDown is the direction of skipped scaling event when scaling in (shrinking)
 	DirectionScaleDown string = "down"
 	// DirectionScaleUp is the direction of skipped scaling event when scaling out (growing)
@@ -299,6 +320,48 @@ var (
 		},
 	)
 
+	evictionRequestsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "eviction_requests_total",
+			Help:      "Number of eviction API requests sent by CA, by result. Each request evicts a single pod.",
+		}, []string{"result"},
+	)
+
+	nodeDrainDuration = k8smetrics.NewHistogramVec(
+		&k8smetrics.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "node_drain_duration_seconds",
+			Help:      "Time from the start of a node drain until all pods were removed from the node or the drain failed, by result.",
+			Buckets:   k8smetrics.ExponentialBuckets(1, 2, 13), // 1s, 2s, 4s, ... ~68min
+		}, []string{"result"},
+	)
+
+	nodeDrainPodsCount = k8smetrics.NewHistogramVec(
+		&k8smetrics.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "node_drain_pods",
+			Help:      "Number of pods removed from a node by a drain, by result.",
+			Buckets:   k8smetrics.ExponentialBuckets(1, 2, 10), // 1, 2, 4, ... 512
+		}, []string{"result"},
+	)
+
+	nodeAutoRepairsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "node_auto_repairs_total",
+			Help:      "Number of repairs of unhealthy nodes, by whether they started or failed to start.",
+		}, []string{"result"},
+	)
+
+	nodeAutoRepairBlockedNodesCount = k8smetrics.NewGauge(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "node_auto_repair_blocked_nodes_count",
+			Help:      "Number of unhealthy nodes whose repair is blocked by a pod.",
+		},
+	)
+
 	unneededNodesCount = k8smetrics.NewGauge(
 		&k8smetrics.GaugeOpts{
 			Namespace: caNamespace,
@@ -316,6 +379,15 @@ var (
 		[]string{"reason"},
 	)
 
+	unremovableNodesMaxDurationSeconds = k8smetrics.NewGaugeVec(
+		&k8smetrics.GaugeOpts{
+			Namespace: caNamespace,
+			Name:      "unremovable_nodes_max_duration_seconds",
+			Help:      "Longest time a node currently considered unremovable by CA has been unremovable, by the last reason it was found unremovable for.",
+		},
+		[]string{"reason"},
+	)
+
 	scaleDownInCooldown = k8smetrics.NewGauge(
 		&k8smetrics.GaugeOpts{
 			Namespace: caNamespace,
@@ -377,6 +449,7 @@ var (
 
 // RegisterAll registers all metrics.
 func RegisterAll(emitPerNodeGroupMetrics bool) {
+	drainabilitymetrics.Register()
 	legacyregistry.MustRegister(clusterSafeToAutoscale)
 	legacyregistry.MustRegister(nodesCount)
 	legacyregistry.MustRegister(nodeGroupsCount)
@@ -397,8 +470,14 @@ func RegisterAll(emitPerNodeGroupMetrics bool) {
 	legacyregistry.MustRegister(scaleDownCount)
 	legacyregistry.MustRegister(gpuScaleDownCount)
 	legacyregistry.MustRegister(evictionsCount)
+	legacyregistry.MustRegister(evictionRequestsCount)
+	legacyregistry.MustRegister(nodeDrainDuration)
+	legacyregistry.MustRegister(nodeDrainPodsCount)
+	legacyregistry.MustRegister(nodeAutoRepairsCount)
+	legacyregistry.MustRegister(nodeAutoRepairBlockedNodesCount)
 	legacyregistry.MustRegister(unneededNodesCount)
 	legacyregistry.MustRegister(unremovableNodesCount)
+	legacyregistry.MustRegister(unremovableNodesMaxDurationSeconds)
 	legacyregistry.MustRegister(scaleDownInCooldown)
 	legacyregistry.MustRegister(oldUnregisteredNodesRemovedCount)
 	legacyregistry.MustRegister(overflowingControllersCount)
@@ -538,6 +617,31 @@ func RegisterEvictions(podsCount int) {
 	evictionsCount.Add(float64(podsCount))
 }
 
+// RegisterEvictionRequest records an eviction API request with the given result
+func RegisterEvictionRequest(result EvictionRequestResult) {
+	evictionRequestsCount.WithLabelValues(string(result)).Inc()
+}
+
+// RegisterNodeDrain records the duration of a finished node drain and the number of pods it removed
+func RegisterNodeDrain(succeeded bool, duration time.Duration, podsCount int) {
+	result := "failed"
+	if succeeded {
+		result = "succeeded"
+	}
+	nodeDrainDuration.WithLabelValues(result).Observe(duration.Seconds())
+	nodeDrainPodsCount.WithLabelValues(result).Observe(float64(podsCount))
+}
+
+// RegisterNodeAutoRepairs records repairs of unhealthy nodes with the given result
+func RegisterNodeAutoRepairs(result NodeAutoRepairResult, nodesCount int) {
+	nodeAutoRepairsCount.WithLabelValues(string(result)).Add(float64(nodesCount))
+}
+
+// UpdateNodeAutoRepairBlockedNodesCount records number of unhealthy nodes whose repair is blocked by a pod
+func UpdateNodeAutoRepairBlockedNodesCount(nodesCount int) {
+	nodeAutoRepairBlockedNodesCount.Set(float64(nodesCount))
+}
+
 // UpdateUnneededNodesCount records number of currently unneeded nodes
 func UpdateUnneededNodesCount(nodesCount int) {
 	unneededNodesCount.Set(float64(nodesCount))
@@ -550,6 +654,15 @@ func UpdateUnremovableNodesCount(unremovableReasonCounts map[simulator.Unremovab
 	}
 }
 
+// UpdateUnremovableNodesMaxDuration records the longest time currently
+// unremovable nodes have been unremovable, by the last reason they were found unremovable for
+func UpdateUnremovableNodesMaxDuration(unremovableReasonDurations map[simulator.UnremovableReason]time.Duration) {
+	unremovableNodesMaxDurationSeconds.Reset()
+	for reason, duration := range unremovableReasonDurations {
+		unremovableNodesMaxDurationSeconds.WithLabelValues(fmt.Sprintf("%v", reason)).Set(duration.Seconds())
+	}
+}
+
 // UpdateNapEnabled records if NodeAutoprovisioning is enabled
 func UpdateNapEnabled(enabled bool) {
 	if enabled {
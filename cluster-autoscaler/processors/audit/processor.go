@@ -0,0 +1,307 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	scaledownstatus "k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/klog/v2"
+)
+
+var scaleUpResults = map[status.ScaleUpResult]string{
+	status.ScaleUpSuccessful:         "Successful",
+	status.ScaleUpError:              "Error",
+	status.ScaleUpNoOptionsAvailable: "NoOptionsAvailable",
+	status.ScaleUpNotNeeded:          "NotNeeded",
+	status.ScaleUpNotTried:           "NotTried",
+	status.ScaleUpInCooldown:         "InCooldown",
+}
+
+var scaleDownResults = map[scaledownstatus.ScaleDownResult]string{
+	scaledownstatus.ScaleDownError:             "Error",
+	scaledownstatus.ScaleDownNoUnneeded:        "NoUnneeded",
+	scaledownstatus.ScaleDownNoNodeDeleted:     "NoNodeDeleted",
+	scaledownstatus.ScaleDownNodeDeleteStarted: "NodeDeleteStarted",
+	scaledownstatus.ScaleDownNotTried:          "NotTried",
+	scaledownstatus.ScaleDownInCooldown:        "InCooldown",
+	scaledownstatus.ScaleDownInProgress:        "InProgress",
+}
+
+var nodeDeleteResults = map[scaledownstatus.NodeDeleteResultType]string{
+	scaledownstatus.NodeDeleteOk:                           "Ok",
+	scaledownstatus.NodeDeleteErrorFailedToMarkToBeDeleted: "FailedToMarkToBeDeleted",
+	scaledownstatus.NodeDeleteErrorFailedToEvictPods:       "FailedToEvictPods",
+	scaledownstatus.NodeDeleteErrorFailedToDelete:          "FailedToDelete",
+	scaledownstatus.NodeDeleteErrorInternal:                "Internal",
+}
+
+// Log writes audit records to a sink. It is safe for concurrent use if the
+// sink is.
+type Log struct {
+	sink Sink
+	now  func() time.Time
+}
+
+// NewLog creates a new Log.
+func NewLog(sink Sink) *Log {
+	return &Log{sink: sink, now: time.Now}
+}
+
+// Write stamps the record with the schema version and the current time, and
+// writes it to the sink. Errors are logged, so that failures of the sink
+// don't stop autoscaling.
+func (l *Log) Write(record Record) {
+	record.SchemaVersion = SchemaVersion
+	record.Timestamp = l.now()
+	line, err := json.Marshal(record)
+	if err != nil {
+		klog.Errorf("Failed to encode %s audit record: %v", record.Decision, err)
+		return
+	}
+	if err := l.sink.Write(append(line, '\n')); err != nil {
+		klog.Warningf("Failed to write %s audit record: %v", record.Decision, err)
+	}
+}
+
+// ScaleUpStatusProcessor writes audit records of scale-ups. Successful and
+// failed scale-ups are always recorded, scale-ups with no options available
+// only when the set of pods which remain unschedulable changes.
+type ScaleUpStatusProcessor struct {
+	log               *Log
+	lastUnschedulable string
+}
+
+// NewScaleUpStatusProcessor creates a new ScaleUpStatusProcessor.
+func NewScaleUpStatusProcessor(log *Log) *ScaleUpStatusProcessor {
+	return &ScaleUpStatusProcessor{log: log}
+}
+
+// Process writes an audit record of the scale-up.
+func (p *ScaleUpStatusProcessor) Process(_ *context.AutoscalingContext, s *status.ScaleUpStatus) {
+	switch s.Result {
+	case status.ScaleUpSuccessful, status.ScaleUpError:
+		p.lastUnschedulable = ""
+	case status.ScaleUpNoOptionsAvailable:
+		key := unschedulableKey(s.PodsRemainUnschedulable)
+		if key == p.lastUnschedulable {
+			return
+		}
+		p.lastUnschedulable = key
+	default:
+		return
+	}
+	record := Record{
+		Decision: ScaleUpDecision,
+		Result:   scaleUpResults[s.Result],
+	}
+	if s.ScaleUpError != nil && *s.ScaleUpError != nil {
+		record.Error = (*s.ScaleUpError).Error()
+	}
+	for _, info := range s.ScaleUpInfos {
+		record.ScaleUps = append(record.ScaleUps, ScaleUp{
+			NodeGroup:   nodeGroupId(info.Group),
+			CurrentSize: info.CurrentSize,
+			TargetSize:  info.NewSize,
+		})
+	}
+	if s.Balancing != nil {
+		record.Balancing = &Balancing{
+			Zones:             s.Balancing.Zones,
+			Validated:         s.Balancing.Validated,
+			FellBack:          s.Balancing.FellBack,
+			BalancedPods:      s.Balancing.BalancedPods,
+			BestNodeGroupPods: s.Balancing.BestNodeGroupPods,
+		}
+	}
+	for _, pod := range s.PodsTriggeredScaleUp {
+		record.TriggeringPods = append(record.TriggeringPods, podName(pod))
+	}
+	for _, noScaleUp := range s.PodsRemainUnschedulable {
+		record.UnschedulablePods = append(record.UnschedulablePods, UnschedulablePod{
+			Pod:                podName(noScaleUp.Pod),
+			RejectedNodeGroups: reasons(noScaleUp.RejectedNodeGroups),
+			SkippedNodeGroups:  reasons(noScaleUp.SkippedNodeGroups),
+		})
+	}
+	p.log.Write(record)
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *ScaleUpStatusProcessor) CleanUp() {
+}
+
+// ScaleDownStatusProcessor writes audit records of scale-downs and of the
+// outcomes of node removals. A scale-down is recorded if nodes started being
+// removed, it failed, or nodes were blocked for reasons they weren't blocked
+// for at the previous scale-down.
+type ScaleDownStatusProcessor struct {
+	log *Log
+	// blockers maps unremovable nodes to what blocked them at the previous
+	// scale-down.
+	blockers map[string]string
+}
+
+// NewScaleDownStatusProcessor creates a new ScaleDownStatusProcessor.
+func NewScaleDownStatusProcessor(log *Log) *ScaleDownStatusProcessor {
+	return &ScaleDownStatusProcessor{log: log, blockers: make(map[string]string)}
+}
+
+// Process writes audit records of the scale-down and of node removals which
+// finished since the previous scale-down.
+func (p *ScaleDownStatusProcessor) Process(_ *context.AutoscalingContext, s *scaledownstatus.ScaleDownStatus) {
+	if deletions := nodeDeletions(s.NodeDeleteResults); len(deletions) > 0 {
+		p.log.Write(Record{Decision: NodeDeletionDecision, NodeDeletions: deletions})
+	}
+	switch s.Result {
+	case scaledownstatus.ScaleDownNotTried, scaledownstatus.ScaleDownInCooldown, scaledownstatus.ScaleDownInProgress:
+		// Unremovable nodes aren't known if scale-down wasn't attempted.
+		return
+	}
+	blockers := p.changedBlockers(s.UnremovableNodes)
+	if len(s.ScaledDownNodes) == 0 && len(blockers) == 0 && s.Result != scaledownstatus.ScaleDownError {
+		return
+	}
+	record := Record{
+		Decision: ScaleDownDecision,
+		Result:   scaleDownResults[s.Result],
+		Blockers: blockers,
+	}
+	for _, node := range s.ScaledDownNodes {
+		scaledDown := ScaledDownNode{Node: node.Node.Name, NodeGroup: nodeGroupId(node.NodeGroup)}
+		for _, pod := range node.EvictedPods {
+			scaledDown.PodsToEvict = append(scaledDown.PodsToEvict, podName(pod))
+		}
+		record.ScaledDownNodes = append(record.ScaledDownNodes, scaledDown)
+	}
+	p.log.Write(record)
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *ScaleDownStatusProcessor) CleanUp() {
+}
+
+// changedBlockers returns blockers of the nodes which weren't blocked the same
+// way at the previous scale-down, sorted by node name.
+func (p *ScaleDownStatusProcessor) changedBlockers(nodes []*scaledownstatus.UnremovableNode) []Blocker {
+	var changed []Blocker
+	current := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		blocker := Blocker{
+			Node:      node.Node.Name,
+			NodeGroup: nodeGroupId(node.NodeGroup),
+			Reason:    node.Reason.String(),
+		}
+		blockingPods := node.BlockingPods
+		if len(blockingPods) == 0 && node.BlockingPod != nil {
+			blockingPods = []*drain.BlockingPod{node.BlockingPod}
+		}
+		for _, blockingPod := range blockingPods {
+			if blockingPod.Pod != nil {
+				blocker.BlockingPods = append(blocker.BlockingPods, BlockingPod{
+					Pod:         podName(blockingPod.Pod),
+					Reason:      string(blockingPod.ReasonID()),
+					Rule:        blockingPod.Rule,
+					Remediation: string(blockingPod.Remediation),
+				})
+			}
+		}
+		if node.UnschedulablePod != nil && node.UnschedulablePod.Pod != nil {
+			blocker.UnschedulablePod = podName(node.UnschedulablePod.Pod)
+		}
+		key := blockerKey(blocker)
+		current[blocker.Node] = key
+		if p.blockers[blocker.Node] != key {
+			changed = append(changed, blocker)
+		}
+	}
+	p.blockers = current
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Node < changed[j].Node })
+	return changed
+}
+
+func blockerKey(blocker Blocker) string {
+	parts := []string{blocker.Reason, blocker.UnschedulablePod}
+	for _, blockingPod := range blocker.BlockingPods {
+		parts = append(parts, blockingPod.Pod+"="+blockingPod.Reason)
+	}
+	return strings.Join(parts, ",")
+}
+
+func nodeDeletions(results map[string]scaledownstatus.NodeDeleteResult) []NodeDeletion {
+	var deletions []NodeDeletion
+	for name, result := range results {
+		deletion := NodeDeletion{Node: name, Result: nodeDeleteResults[result.ResultType]}
+		if result.Err != nil {
+			deletion.Error = result.Err.Error()
+		}
+		for _, eviction := range result.PodEvictionResults {
+			if eviction.Pod == nil {
+				continue
+			}
+			podEviction := PodEviction{Pod: podName(eviction.Pod), TimedOut: eviction.TimedOut, Deleted: eviction.Deleted}
+			if eviction.Err != nil {
+				podEviction.Error = eviction.Err.Error()
+			}
+			deletion.EvictedPods = append(deletion.EvictedPods, podEviction)
+		}
+		sort.Slice(deletion.EvictedPods, func(i, j int) bool { return deletion.EvictedPods[i].Pod < deletion.EvictedPods[j].Pod })
+		deletions = append(deletions, deletion)
+	}
+	sort.Slice(deletions, func(i, j int) bool { return deletions[i].Node < deletions[j].Node })
+	return deletions
+}
+
+func unschedulableKey(noScaleUps []status.NoScaleUpInfo) string {
+	pods := make([]string, 0, len(noScaleUps))
+	for _, noScaleUp := range noScaleUps {
+		pods = append(pods, podName(noScaleUp.Pod))
+	}
+	sort.Strings(pods)
+	return strings.Join(pods, ",")
+}
+
+func reasons(nodeGroupReasons map[string]status.Reasons) map[string][]string {
+	if len(nodeGroupReasons) == 0 {
+		return nil
+	}
+	result := make(map[string][]string, len(nodeGroupReasons))
+	for nodeGroup, r := range nodeGroupReasons {
+		result[nodeGroup] = r.Reasons()
+	}
+	return result
+}
+
+func nodeGroupId(nodeGroup cloudprovider.NodeGroup) string {
+	if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+		return ""
+	}
+	return nodeGroup.Id()
+}
+
+func podName(pod *apiv1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
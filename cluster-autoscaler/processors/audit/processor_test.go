@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	apiv1 "k8s.io/api/core/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	scaledownstatus "k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+var now = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestScaleUpStatusProcessor(t *testing.T) {
+	ng := testprovider.NewTestNodeGroup("ng", 10, 0, 1, true, false, "n1-standard-2", nil, nil)
+	ng2 := testprovider.NewTestNodeGroup("ng2", 10, 0, 1, true, false, "n1-standard-2", nil, nil)
+	p1 := BuildTestPod("p1", 100, 0)
+	p2 := BuildTestPod("p2", 100, 0)
+	scaleUpErr := errors.NewAutoscalerError(errors.CloudProviderError, "quota exceeded")
+	noOptions := func(pods ...*apiv1.Pod) *status.ScaleUpStatus {
+		s := &status.ScaleUpStatus{Result: status.ScaleUpNoOptionsAvailable}
+		for _, pod := range pods {
+			s.PodsRemainUnschedulable = append(s.PodsRemainUnschedulable, status.NoScaleUpInfo{Pod: pod})
+		}
+		return s
+	}
+
+	sink := &bytes.Buffer{}
+	log := NewLog(NewWriterSink(sink))
+	log.now = func() time.Time { return now }
+	p := NewScaleUpStatusProcessor(log)
+	for _, s := range []*status.ScaleUpStatus{
+		{Result: status.ScaleUpNotNeeded},
+		{Result: status.ScaleUpSuccessful, ScaleUpInfos: []nodegroupset.ScaleUpInfo{{Group: ng, CurrentSize: 1, NewSize: 3, MaxSize: 10}}, PodsTriggeredScaleUp: []*apiv1.Pod{p1}},
+		noOptions(p1),
+		noOptions(p1),
+		noOptions(p1, p2),
+		{Result: status.ScaleUpError, ScaleUpError: &scaleUpErr},
+		{
+			Result:               status.ScaleUpSuccessful,
+			ScaleUpInfos:         []nodegroupset.ScaleUpInfo{{Group: ng, CurrentSize: 1, NewSize: 2, MaxSize: 10}, {Group: ng2, CurrentSize: 1, NewSize: 2, MaxSize: 10}},
+			PodsTriggeredScaleUp: []*apiv1.Pod{p1, p2},
+			Balancing:            &status.BalancingInfo{Zones: map[string]int{"zone-a": 1, "zone-b": 1}, Validated: true, BalancedPods: 2},
+		},
+	} {
+		p.Process(nil, s)
+	}
+
+	want := []Record{
+		{SchemaVersion: SchemaVersion, Timestamp: now, Decision: ScaleUpDecision, Result: "Successful", ScaleUps: []ScaleUp{{NodeGroup: "ng", CurrentSize: 1, TargetSize: 3}}, TriggeringPods: []string{"default/p1"}},
+		{SchemaVersion: SchemaVersion, Timestamp: now, Decision: ScaleUpDecision, Result: "NoOptionsAvailable", UnschedulablePods: []UnschedulablePod{{Pod: "default/p1"}}},
+		{SchemaVersion: SchemaVersion, Timestamp: now, Decision: ScaleUpDecision, Result: "NoOptionsAvailable", UnschedulablePods: []UnschedulablePod{{Pod: "default/p1"}, {Pod: "default/p2"}}},
+		{SchemaVersion: SchemaVersion, Timestamp: now, Decision: ScaleUpDecision, Result: "Error", Error: "quota exceeded"},
+		{
+			SchemaVersion: SchemaVersion, Timestamp: now, Decision: ScaleUpDecision, Result: "Successful",
+			ScaleUps:       []ScaleUp{{NodeGroup: "ng", CurrentSize: 1, TargetSize: 2}, {NodeGroup: "ng2", CurrentSize: 1, TargetSize: 2}},
+			Balancing:      &Balancing{Zones: map[string]int{"zone-a": 1, "zone-b": 1}, Validated: true, BalancedPods: 2},
+			TriggeringPods: []string{"default/p1", "default/p2"},
+		},
+	}
+	if diff := cmp.Diff(want, readRecords(t, sink)); diff != "" {
+		t.Errorf("Audit records diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestScaleDownStatusProcessor(t *testing.T) {
+	ng := testprovider.NewTestNodeGroup("ng", 10, 0, 3, true, false, "n1-standard-2", nil, nil)
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	p1 := BuildTestPod("p1", 100, 0)
+	p2 := BuildTestPod("p2", 100, 0)
+	blockedByPod := func(pod *apiv1.Pod) *scaledownstatus.UnremovableNode {
+		blockingPod := &drain.BlockingPod{Pod: pod, Reason: drain.NotReplicated}
+		return &scaledownstatus.UnremovableNode{Node: n2, NodeGroup: ng, Reason: simulator.BlockedByPod, BlockingPod: blockingPod}
+	}
+
+	sink := &bytes.Buffer{}
+	log := NewLog(NewWriterSink(sink))
+	log.now = func() time.Time { return now }
+	p := NewScaleDownStatusProcessor(log)
+	for _, s := range []*scaledownstatus.ScaleDownStatus{
+		{
+			Result:           scaledownstatus.ScaleDownNodeDeleteStarted,
+			ScaledDownNodes:  []*scaledownstatus.ScaleDownNode{{Node: n1, NodeGroup: ng, EvictedPods: []*apiv1.Pod{p1}}},
+			UnremovableNodes: []*scaledownstatus.UnremovableNode{blockedByPod(p2)},
+		},
+		// Blocked the same way, not recorded.
+		{Result: scaledownstatus.ScaleDownNoNodeDeleted, UnremovableNodes: []*scaledownstatus.UnremovableNode{blockedByPod(p2)}},
+		// Unremovable nodes aren't known, blockers are kept.
+		{Result: scaledownstatus.ScaleDownInCooldown},
+		{
+			Result:           scaledownstatus.ScaleDownNoNodeDeleted,
+			UnremovableNodes: []*scaledownstatus.UnremovableNode{blockedByPod(p1)},
+			NodeDeleteResults: map[string]scaledownstatus.NodeDeleteResult{
+				"n1": {
+					ResultType:         scaledownstatus.NodeDeleteErrorFailedToEvictPods,
+					Err:                fmt.Errorf("eviction failed"),
+					PodEvictionResults: map[string]scaledownstatus.PodEvictionResult{"p1": {Pod: p1, TimedOut: true, Err: fmt.Errorf("timed out")}},
+				},
+			},
+		},
+	} {
+		p.Process(nil, s)
+	}
+
+	want := []Record{
+		{
+			SchemaVersion:   SchemaVersion,
+			Timestamp:       now,
+			Decision:        ScaleDownDecision,
+			Result:          "NodeDeleteStarted",
+			ScaledDownNodes: []ScaledDownNode{{Node: "n1", NodeGroup: "ng", PodsToEvict: []string{"default/p1"}}},
+			Blockers:        []Blocker{{Node: "n2", NodeGroup: "ng", Reason: "BlockedByPod", BlockingPods: []BlockingPod{{Pod: "default/p2", Reason: "NotReplicated"}}}},
+		},
+		{
+			SchemaVersion: SchemaVersion,
+			Timestamp:     now,
+			Decision:      NodeDeletionDecision,
+			NodeDeletions: []NodeDeletion{{Node: "n1", Result: "FailedToEvictPods", Error: "eviction failed", EvictedPods: []PodEviction{{Pod: "default/p1", Error: "timed out", TimedOut: true}}}},
+		},
+		{
+			SchemaVersion: SchemaVersion,
+			Timestamp:     now,
+			Decision:      ScaleDownDecision,
+			Result:        "NoNodeDeleted",
+			Blockers:      []Blocker{{Node: "n2", NodeGroup: "ng", Reason: "BlockedByPod", BlockingPods: []BlockingPod{{Pod: "default/p1", Reason: "NotReplicated"}}}},
+		},
+	}
+	if diff := cmp.Diff(want, readRecords(t, sink)); diff != "" {
+		t.Errorf("Audit records diff (-want +got):\n%s", diff)
+	}
+}
+
+func readRecords(t *testing.T, sink *bytes.Buffer) []Record {
+	var records []Record
+	for _, line := range strings.Split(strings.TrimSuffix(sink.String(), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Failed to decode audit record %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
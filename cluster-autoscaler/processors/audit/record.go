@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import "time"
+
+// SchemaVersion is the version of the schema of audit records. Fields are only
+// added to a version of the schema, never removed or changed; incompatible
+// changes bump the version.
+const SchemaVersion = "v1"
+
+// Decision is the type of the decision described by an audit record.
+type Decision string
+
+const (
+	// ScaleUpDecision describes a scale-up attempt.
+	ScaleUpDecision Decision = "ScaleUp"
+	// ScaleDownDecision describes a scale-down attempt, i.e. which nodes
+	// started being removed and which were blocked.
+	ScaleDownDecision Decision = "ScaleDown"
+	// NodeDeletionDecision describes the outcome of removals of nodes,
+	// started by earlier scale-down decisions.
+	NodeDeletionDecision Decision = "NodeDeletion"
+)
+
+// Record is a single audit record, written as one line of JSON.
+type Record struct {
+	// SchemaVersion is the version of the schema of the record.
+	SchemaVersion string `json:"schemaVersion"`
+	// Timestamp is the time at which the decision was made.
+	Timestamp time.Time `json:"timestamp"`
+	// Decision is the type of the decision.
+	Decision Decision `json:"decision"`
+	// Result is the result of the scale-up or scale-down attempt.
+	Result string `json:"result,omitempty"`
+	// Error is the error which stopped the attempt, if any.
+	Error string `json:"error,omitempty"`
+	// ScaleUps lists the node groups scaled up.
+	ScaleUps []ScaleUp `json:"scaleUps,omitempty"`
+	// Balancing describes how the scale-up was split between similar node
+	// groups, if it was.
+	Balancing *Balancing `json:"balancing,omitempty"`
+	// TriggeringPods lists the pods which triggered the scale-up.
+	TriggeringPods []string `json:"triggeringPods,omitempty"`
+	// UnschedulablePods lists the pods which couldn't be helped by the
+	// scale-up, with the reasons for each rejected node group.
+	UnschedulablePods []UnschedulablePod `json:"unschedulablePods,omitempty"`
+	// ScaledDownNodes lists the nodes which started being removed.
+	ScaledDownNodes []ScaledDownNode `json:"scaledDownNodes,omitempty"`
+	// Blockers lists the nodes which couldn't be removed, with the reasons.
+	// Only nodes whose reason changed since the previous record are listed.
+	Blockers []Blocker `json:"blockers,omitempty"`
+	// NodeDeletions lists the outcomes of node removals.
+	NodeDeletions []NodeDeletion `json:"nodeDeletions,omitempty"`
+}
+
+// ScaleUp describes a scale-up of a node group.
+type ScaleUp struct {
+	NodeGroup   string `json:"nodeGroup"`
+	CurrentSize int    `json:"currentSize"`
+	TargetSize  int    `json:"targetSize"`
+}
+
+// Balancing describes how a scale-up was split between similar node groups.
+type Balancing struct {
+	// Zones maps zones to the number of nodes added in them. Node groups
+	// without a zone label are counted under an empty zone.
+	Zones map[string]int `json:"zones"`
+	// Validated tells if the split was validated by scheduling the triggering
+	// pods in simulation.
+	Validated bool `json:"validated"`
+	// FellBack tells if fewer pods fit the split than scaling up only the best
+	// node group, which was done instead.
+	FellBack bool `json:"fellBack,omitempty"`
+	// BalancedPods is the number of pods which fit the split in simulation.
+	BalancedPods int `json:"balancedPods,omitempty"`
+	// BestNodeGroupPods is the number of pods which fit scaling up only the
+	// best node group in simulation.
+	BestNodeGroupPods int `json:"bestNodeGroupPods,omitempty"`
+}
+
+// UnschedulablePod describes a pod which couldn't be helped by a scale-up.
+type UnschedulablePod struct {
+	Pod string `json:"pod"`
+	// RejectedNodeGroups maps node groups which couldn't fit the pod to the
+	// reasons why.
+	RejectedNodeGroups map[string][]string `json:"rejectedNodeGroups,omitempty"`
+	// SkippedNodeGroups maps node groups which weren't considered to the
+	// reasons why.
+	SkippedNodeGroups map[string][]string `json:"skippedNodeGroups,omitempty"`
+}
+
+// ScaledDownNode describes a node which started being removed.
+type ScaledDownNode struct {
+	Node      string `json:"node"`
+	NodeGroup string `json:"nodeGroup,omitempty"`
+	// PodsToEvict lists the pods evicted to drain the node. It's empty for
+	// empty nodes.
+	PodsToEvict []string `json:"podsToEvict,omitempty"`
+}
+
+// Blocker describes why a node couldn't be removed.
+type Blocker struct {
+	Node      string `json:"node"`
+	NodeGroup string `json:"nodeGroup,omitempty"`
+	Reason    string `json:"reason"`
+	// BlockingPods lists the pods blocking drain of the node.
+	BlockingPods []BlockingPod `json:"blockingPods,omitempty"`
+	// UnschedulablePod is the pod which couldn't be moved to any other node.
+	UnschedulablePod string `json:"unschedulablePod,omitempty"`
+}
+
+// BlockingPod describes a pod blocking drain of a node.
+type BlockingPod struct {
+	Pod    string `json:"pod"`
+	Reason string `json:"reason"`
+	// Rule is the name of the drainability rule which blocked the pod.
+	Rule string `json:"rule,omitempty"`
+	// Remediation is a hint on how to unblock the pod.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// NodeDeletion describes the outcome of a removal of a node.
+type NodeDeletion struct {
+	Node   string `json:"node"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// EvictedPods lists the pods evicted from the node.
+	EvictedPods []PodEviction `json:"evictedPods,omitempty"`
+}
+
+// PodEviction describes the outcome of an eviction of a pod.
+type PodEviction struct {
+	Pod      string `json:"pod"`
+	Error    string `json:"error,omitempty"`
+	TimedOut bool   `json:"timedOut,omitempty"`
+	// Deleted tells if the pod was deleted instead of evicted.
+	Deleted bool `json:"deleted,omitempty"`
+}
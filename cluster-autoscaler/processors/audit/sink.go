@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// StdoutSink is the sink specification writing records to the standard
+	// output.
+	StdoutSink = "stdout"
+	// FileSinkPrefix prefixes the path of a file to which records are
+	// appended.
+	FileSinkPrefix = "file:"
+
+	webhookTimeout = 10 * time.Second
+)
+
+// Sink receives audit records, each as a single line of JSON terminated by a
+// newline.
+type Sink interface {
+	Write(line []byte) error
+}
+
+// ParseSink creates the sink described by the specification, which is either
+// "stdout", "file:<path>", or an http:// or https:// URL of a webhook to which
+// records are posted.
+func ParseSink(spec string) (Sink, error) {
+	switch {
+	case spec == StdoutSink:
+		return NewWriterSink(os.Stdout), nil
+	case strings.HasPrefix(spec, FileSinkPrefix):
+		path := strings.TrimPrefix(spec, FileSinkPrefix)
+		if path == "" {
+			return nil, fmt.Errorf("empty path of audit log file")
+		}
+		return NewFileSink(path)
+	case strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://"):
+		return NewWebhookSink(spec, &http.Client{Timeout: webhookTimeout}), nil
+	}
+	return nil, fmt.Errorf("unknown audit log sink %q, should be %q, %q followed by a path or a webhook URL", spec, StdoutSink, FileSinkPrefix)
+}
+
+// WriterSink writes records to an io.Writer.
+type WriterSink struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterSink creates a new WriterSink.
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{writer: writer}
+}
+
+// NewFileSink creates a WriterSink appending records to the file, which is
+// created if it doesn't exist.
+func NewFileSink(path string) (*WriterSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log file: %v", err)
+	}
+	return NewWriterSink(file), nil
+}
+
+// Write writes the record.
+func (s *WriterSink) Write(line []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err := s.writer.Write(line)
+	return err
+}
+
+// WebhookSink posts each record to a webhook.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a new WebhookSink.
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	return &WebhookSink{url: url, client: client}
+}
+
+// Write posts the record. Responses other than 2xx are errors.
+func (s *WebhookSink) Write(line []byte) error {
+	response, err := s.client.Post(s.url, "application/json", bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook returned status %s", response.Status)
+	}
+	return nil
+}
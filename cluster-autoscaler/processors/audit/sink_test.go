@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSink(t *testing.T) {
+	for _, spec := range []string{"stdout", "file:" + filepath.Join(t.TempDir(), "audit.log"), "http://localhost:8080/audit", "https://audit.example.com"} {
+		if _, err := ParseSink(spec); err != nil {
+			t.Errorf("ParseSink(%q): unexpected error: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"stderr", "file:", "localhost:8080"} {
+		if _, err := ParseSink(spec); err == nil {
+			t.Errorf("ParseSink(%q): want error, got none", spec)
+		}
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for _, line := range []string{"{\"a\":1}\n", "{\"b\":2}\n"} {
+		// Records are appended to the existing file.
+		sink, err := NewFileSink(path)
+		if err != nil {
+			t.Fatalf("NewFileSink(): unexpected error: %v", err)
+		}
+		if err := sink.Write([]byte(line)); err != nil {
+			t.Fatalf("Write(): unexpected error: %v", err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read audit log: %v", err)
+	}
+	if want := "{\"a\":1}\n{\"b\":2}\n"; string(got) != want {
+		t.Errorf("Audit log: got %q, want %q", got, want)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	if err := NewWebhookSink(server.URL+"/audit", server.Client()).Write([]byte("{}\n")); err != nil {
+		t.Errorf("Write(): unexpected error: %v", err)
+	}
+	if err := NewWebhookSink(server.URL+"/fail", server.Client()).Write([]byte("{}\n")); err == nil {
+		t.Errorf("Write(): want error on status 500, got none")
+	}
+	if len(received) != 2 || received[0] != "{}\n" {
+		t.Errorf("Webhook received %q, want two records", received)
+	}
+}
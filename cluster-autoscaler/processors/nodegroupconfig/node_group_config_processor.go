@@ -17,10 +17,13 @@ limitations under the License.
 package nodegroupconfig
 
 import (
+	"reflect"
 	"time"
 
+	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/config"
+	klog "k8s.io/klog/v2"
 )
 
 // NodeGroupConfigProcessor provides config values for a particular NodeGroup.
@@ -37,6 +40,22 @@ type NodeGroupConfigProcessor interface {
 	GetMaxNodeProvisionTime(nodeGroup cloudprovider.NodeGroup) (time.Duration, error)
 	// GetIgnoreDaemonSetsUtilization returns IgnoreDaemonSetsUtilization value that should be used for a given NodeGroup.
 	GetIgnoreDaemonSetsUtilization(nodeGroup cloudprovider.NodeGroup) (bool, error)
+	// GetNodeReadinessTimeout returns NodeReadinessTimeout value that should be used for a given NodeGroup.
+	GetNodeReadinessTimeout(nodeGroup cloudprovider.NodeGroup) (time.Duration, error)
+	// GetMaxGracefulTerminationSec returns MaxGracefulTerminationSec value that should be used for a given NodeGroup.
+	GetMaxGracefulTerminationSec(nodeGroup cloudprovider.NodeGroup) (int, error)
+	// GetHeadroomNodes returns HeadroomNodes value that should be used for a given NodeGroup.
+	GetHeadroomNodes(nodeGroup cloudprovider.NodeGroup) (int, error)
+	// GetHeadroomRatio returns HeadroomRatio value that should be used for a given NodeGroup.
+	GetHeadroomRatio(nodeGroup cloudprovider.NodeGroup) (float64, error)
+	// GetScaleDownWindow returns ScaleDownWindow value that should be used for a given NodeGroup.
+	GetScaleDownWindow(nodeGroup cloudprovider.NodeGroup) (string, error)
+	// GetMaxDrainParallelism returns MaxDrainParallelism value that should be used for a given NodeGroup.
+	GetMaxDrainParallelism(nodeGroup cloudprovider.NodeGroup) (int, error)
+	// GetMaxPodEvictionsPerMinute returns MaxPodEvictionsPerMinute value that should be used for a given NodeGroup.
+	GetMaxPodEvictionsPerMinute(nodeGroup cloudprovider.NodeGroup) (int, error)
+	// GetScaleDownUtilizationMode returns ScaleDownUtilizationMode value that should be used for a given NodeGroup.
+	GetScaleDownUtilizationMode(nodeGroup cloudprovider.NodeGroup) (string, error)
 	// CleanUp cleans up processor's internal structures.
 	CleanUp()
 }
@@ -46,80 +65,191 @@ type NodeGroupConfigProcessor interface {
 // used instead.
 type DelegatingNodeGroupConfigProcessor struct {
 	nodeGroupDefaults config.NodeGroupAutoscalingOptions
+	reloader          DefaultsReloader
+}
+
+// DefaultsReloader reloads default node group options at runtime, without a restart.
+type DefaultsReloader interface {
+	// ReloadNodeGroupDefaults returns the defaults with the reloaded values applied.
+	ReloadNodeGroupDefaults(defaults config.NodeGroupAutoscalingOptions) config.NodeGroupAutoscalingOptions
+}
+
+func (p *DelegatingNodeGroupConfigProcessor) defaults() config.NodeGroupAutoscalingOptions {
+	if p.reloader == nil {
+		return p.nodeGroupDefaults
+	}
+	return p.reloader.ReloadNodeGroupDefaults(p.nodeGroupDefaults)
 }
 
 // GetScaleDownUnneededTime returns ScaleDownUnneededTime value that should be used for a given NodeGroup.
 func (p *DelegatingNodeGroupConfigProcessor) GetScaleDownUnneededTime(nodeGroup cloudprovider.NodeGroup) (time.Duration, error) {
-	ngConfig, err := nodeGroup.GetOptions(p.nodeGroupDefaults)
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
 	if err != nil && err != cloudprovider.ErrNotImplemented {
 		return time.Duration(0), err
 	}
 	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
-		return p.nodeGroupDefaults.ScaleDownUnneededTime, nil
+		return p.defaults().ScaleDownUnneededTime, nil
 	}
 	return ngConfig.ScaleDownUnneededTime, nil
 }
 
 // GetScaleDownUnreadyTime returns ScaleDownUnreadyTime value that should be used for a given NodeGroup.
 func (p *DelegatingNodeGroupConfigProcessor) GetScaleDownUnreadyTime(nodeGroup cloudprovider.NodeGroup) (time.Duration, error) {
-	ngConfig, err := nodeGroup.GetOptions(p.nodeGroupDefaults)
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
 	if err != nil && err != cloudprovider.ErrNotImplemented {
 		return time.Duration(0), err
 	}
 	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
-		return p.nodeGroupDefaults.ScaleDownUnreadyTime, nil
+		return p.defaults().ScaleDownUnreadyTime, nil
 	}
 	return ngConfig.ScaleDownUnreadyTime, nil
 }
 
 // GetScaleDownUtilizationThreshold returns ScaleDownUtilizationThreshold value that should be used for a given NodeGroup.
 func (p *DelegatingNodeGroupConfigProcessor) GetScaleDownUtilizationThreshold(nodeGroup cloudprovider.NodeGroup) (float64, error) {
-	ngConfig, err := nodeGroup.GetOptions(p.nodeGroupDefaults)
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
 	if err != nil && err != cloudprovider.ErrNotImplemented {
 		return 0.0, err
 	}
 	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
-		return p.nodeGroupDefaults.ScaleDownUtilizationThreshold, nil
+		return p.defaults().ScaleDownUtilizationThreshold, nil
 	}
 	return ngConfig.ScaleDownUtilizationThreshold, nil
 }
 
 // GetScaleDownGpuUtilizationThreshold returns ScaleDownGpuUtilizationThreshold value that should be used for a given NodeGroup.
 func (p *DelegatingNodeGroupConfigProcessor) GetScaleDownGpuUtilizationThreshold(nodeGroup cloudprovider.NodeGroup) (float64, error) {
-	ngConfig, err := nodeGroup.GetOptions(p.nodeGroupDefaults)
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
 	if err != nil && err != cloudprovider.ErrNotImplemented {
 		return 0.0, err
 	}
 	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
-		return p.nodeGroupDefaults.ScaleDownGpuUtilizationThreshold, nil
+		return p.defaults().ScaleDownGpuUtilizationThreshold, nil
 	}
 	return ngConfig.ScaleDownGpuUtilizationThreshold, nil
 }
 
 // GetMaxNodeProvisionTime returns MaxNodeProvisionTime value that should be used for a given NodeGroup.
 func (p *DelegatingNodeGroupConfigProcessor) GetMaxNodeProvisionTime(nodeGroup cloudprovider.NodeGroup) (time.Duration, error) {
-	ngConfig, err := nodeGroup.GetOptions(p.nodeGroupDefaults)
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
 	if err != nil && err != cloudprovider.ErrNotImplemented {
 		return time.Duration(0), err
 	}
 	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
-		return p.nodeGroupDefaults.MaxNodeProvisionTime, nil
+		return p.defaults().MaxNodeProvisionTime, nil
 	}
 	return ngConfig.MaxNodeProvisionTime, nil
 }
 
 // GetIgnoreDaemonSetsUtilization returns IgnoreDaemonSetsUtilization value that should be used for a given NodeGroup.
 func (p *DelegatingNodeGroupConfigProcessor) GetIgnoreDaemonSetsUtilization(nodeGroup cloudprovider.NodeGroup) (bool, error) {
-	ngConfig, err := nodeGroup.GetOptions(p.nodeGroupDefaults)
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
 	if err != nil && err != cloudprovider.ErrNotImplemented {
 		return false, err
 	}
 	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
-		return p.nodeGroupDefaults.IgnoreDaemonSetsUtilization, nil
+		return p.defaults().IgnoreDaemonSetsUtilization, nil
 	}
 	return ngConfig.IgnoreDaemonSetsUtilization, nil
 }
 
+// GetNodeReadinessTimeout returns NodeReadinessTimeout value that should be used for a given NodeGroup.
+func (p *DelegatingNodeGroupConfigProcessor) GetNodeReadinessTimeout(nodeGroup cloudprovider.NodeGroup) (time.Duration, error) {
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return time.Duration(0), err
+	}
+	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
+		return p.defaults().NodeReadinessTimeout, nil
+	}
+	return ngConfig.NodeReadinessTimeout, nil
+}
+
+// GetMaxGracefulTerminationSec returns MaxGracefulTerminationSec value that should be used for a given NodeGroup.
+// The default is used if the NodeGroup doesn't set a positive value.
+func (p *DelegatingNodeGroupConfigProcessor) GetMaxGracefulTerminationSec(nodeGroup cloudprovider.NodeGroup) (int, error) {
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return 0, err
+	}
+	if ngConfig == nil || err == cloudprovider.ErrNotImplemented || ngConfig.MaxGracefulTerminationSec <= 0 {
+		return p.defaults().MaxGracefulTerminationSec, nil
+	}
+	return ngConfig.MaxGracefulTerminationSec, nil
+}
+
+// GetHeadroomNodes returns HeadroomNodes value that should be used for a given NodeGroup.
+func (p *DelegatingNodeGroupConfigProcessor) GetHeadroomNodes(nodeGroup cloudprovider.NodeGroup) (int, error) {
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return 0, err
+	}
+	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
+		return p.defaults().HeadroomNodes, nil
+	}
+	return ngConfig.HeadroomNodes, nil
+}
+
+// GetHeadroomRatio returns HeadroomRatio value that should be used for a given NodeGroup.
+func (p *DelegatingNodeGroupConfigProcessor) GetHeadroomRatio(nodeGroup cloudprovider.NodeGroup) (float64, error) {
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return 0.0, err
+	}
+	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
+		return p.defaults().HeadroomRatio, nil
+	}
+	return ngConfig.HeadroomRatio, nil
+}
+
+// GetScaleDownWindow returns ScaleDownWindow value that should be used for a given NodeGroup.
+func (p *DelegatingNodeGroupConfigProcessor) GetScaleDownWindow(nodeGroup cloudprovider.NodeGroup) (string, error) {
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return "", err
+	}
+	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
+		return p.defaults().ScaleDownWindow, nil
+	}
+	return ngConfig.ScaleDownWindow, nil
+}
+
+// GetMaxDrainParallelism returns MaxDrainParallelism value that should be used for a given NodeGroup.
+func (p *DelegatingNodeGroupConfigProcessor) GetMaxDrainParallelism(nodeGroup cloudprovider.NodeGroup) (int, error) {
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return 0, err
+	}
+	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
+		return p.defaults().MaxDrainParallelism, nil
+	}
+	return ngConfig.MaxDrainParallelism, nil
+}
+
+// GetMaxPodEvictionsPerMinute returns MaxPodEvictionsPerMinute value that should be used for a given NodeGroup.
+func (p *DelegatingNodeGroupConfigProcessor) GetMaxPodEvictionsPerMinute(nodeGroup cloudprovider.NodeGroup) (int, error) {
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return 0, err
+	}
+	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
+		return p.defaults().MaxPodEvictionsPerMinute, nil
+	}
+	return ngConfig.MaxPodEvictionsPerMinute, nil
+}
+
+// GetScaleDownUtilizationMode returns ScaleDownUtilizationMode value that should be used for a given NodeGroup.
+func (p *DelegatingNodeGroupConfigProcessor) GetScaleDownUtilizationMode(nodeGroup cloudprovider.NodeGroup) (string, error) {
+	ngConfig, err := nodeGroup.GetOptions(p.defaults())
+	if err != nil && err != cloudprovider.ErrNotImplemented {
+		return "", err
+	}
+	if ngConfig == nil || err == cloudprovider.ErrNotImplemented {
+		return p.defaults().ScaleDownUtilizationMode, nil
+	}
+	return ngConfig.ScaleDownUtilizationMode, nil
+}
+
 // CleanUp cleans up processor's internal structures.
 func (p *DelegatingNodeGroupConfigProcessor) CleanUp() {
 }
@@ -130,3 +260,43 @@ func NewDefaultNodeGroupConfigProcessor(nodeGroupDefaults config.NodeGroupAutosc
 		nodeGroupDefaults: nodeGroupDefaults,
 	}
 }
+
+// NewReloadingNodeGroupConfigProcessor returns a NodeGroupConfigProcessor whose defaults are reloaded at runtime by
+// the reloader.
+func NewReloadingNodeGroupConfigProcessor(nodeGroupDefaults config.NodeGroupAutoscalingOptions, reloader DefaultsReloader) NodeGroupConfigProcessor {
+	return &DelegatingNodeGroupConfigProcessor{
+		nodeGroupDefaults: nodeGroupDefaults,
+		reloader:          reloader,
+	}
+}
+
+// MaxGracefulTerminationSecGetter is the part of NodeGroupConfigProcessor
+// returning MaxGracefulTerminationSec.
+type MaxGracefulTerminationSecGetter interface {
+	// GetMaxGracefulTerminationSec returns MaxGracefulTerminationSec value that should be used for a given NodeGroup.
+	GetMaxGracefulTerminationSec(nodeGroup cloudprovider.NodeGroup) (int, error)
+}
+
+// GetMaxGracefulTerminationSecForNode returns MaxGracefulTerminationSec value
+// that should be used for pods on a given node. defaultValue is returned for
+// nodes which don't belong to an autoscaled NodeGroup, or if a positive value
+// can't be determined.
+func GetMaxGracefulTerminationSecForNode(cloudProvider cloudprovider.CloudProvider, getter MaxGracefulTerminationSecGetter, node *apiv1.Node, defaultValue int) int {
+	nodeGroup, err := cloudProvider.NodeGroupForNode(node)
+	if err != nil {
+		klog.Warningf("Failed to get node group for %s, using default max graceful termination: %v", node.Name, err)
+		return defaultValue
+	}
+	if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+		return defaultValue
+	}
+	maxGracefulTerminationSec, err := getter.GetMaxGracefulTerminationSec(nodeGroup)
+	if err != nil {
+		klog.Warningf("Failed to get max graceful termination for node group %s, using default: %v", nodeGroup.Id(), err)
+		return defaultValue
+	}
+	if maxGracefulTerminationSec <= 0 {
+		return defaultValue
+	}
+	return maxGracefulTerminationSec
+}
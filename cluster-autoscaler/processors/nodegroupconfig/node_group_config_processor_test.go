@@ -48,6 +48,14 @@ func TestDelegatingNodeGroupConfigProcessor(t *testing.T) {
 		ScaleDownUtilizationThreshold:    0.5,
 		MaxNodeProvisionTime:             15 * time.Minute,
 		IgnoreDaemonSetsUtilization:      true,
+		NodeReadinessTimeout:             5 * time.Minute,
+		MaxGracefulTerminationSec:        600,
+		HeadroomNodes:                    1,
+		HeadroomRatio:                    0.1,
+		ScaleDownWindow:                  "* 0-5 * * *",
+		MaxDrainParallelism:              2,
+		MaxPodEvictionsPerMinute:         20,
+		ScaleDownUtilizationMode:         "usage",
 	}
 	ngOpts := &config.NodeGroupAutoscalingOptions{
 		ScaleDownUnneededTime:            10 * time.Minute,
@@ -56,6 +64,14 @@ func TestDelegatingNodeGroupConfigProcessor(t *testing.T) {
 		ScaleDownUtilizationThreshold:    0.75,
 		MaxNodeProvisionTime:             60 * time.Minute,
 		IgnoreDaemonSetsUtilization:      false,
+		NodeReadinessTimeout:             20 * time.Minute,
+		MaxGracefulTerminationSec:        30,
+		HeadroomNodes:                    3,
+		HeadroomRatio:                    0.25,
+		ScaleDownWindow:                  "* 22-23 * * 1-5",
+		MaxDrainParallelism:              5,
+		MaxPodEvictionsPerMinute:         50,
+		ScaleDownUtilizationMode:         "hybrid",
 	}
 
 	testUnneededTime := func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
@@ -121,6 +137,94 @@ func TestDelegatingNodeGroupConfigProcessor(t *testing.T) {
 		assert.Equal(t, res, results[w])
 	}
 
+	testNodeReadinessTimeout := func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
+		res, err := p.GetNodeReadinessTimeout(ng)
+		assert.Equal(t, err, we)
+		results := map[Want]time.Duration{
+			NIL:    time.Duration(0),
+			GLOBAL: 5 * time.Minute,
+			NG:     20 * time.Minute,
+		}
+		assert.Equal(t, res, results[w])
+	}
+
+	testMaxGracefulTerminationSec := func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
+		res, err := p.GetMaxGracefulTerminationSec(ng)
+		assert.Equal(t, err, we)
+		results := map[Want]int{
+			NIL:    0,
+			GLOBAL: 600,
+			NG:     30,
+		}
+		assert.Equal(t, res, results[w])
+	}
+
+	testHeadroomNodes := func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
+		res, err := p.GetHeadroomNodes(ng)
+		assert.Equal(t, err, we)
+		results := map[Want]int{
+			NIL:    0,
+			GLOBAL: 1,
+			NG:     3,
+		}
+		assert.Equal(t, res, results[w])
+	}
+
+	testHeadroomRatio := func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
+		res, err := p.GetHeadroomRatio(ng)
+		assert.Equal(t, err, we)
+		results := map[Want]float64{
+			NIL:    0.0,
+			GLOBAL: 0.1,
+			NG:     0.25,
+		}
+		assert.Equal(t, res, results[w])
+	}
+
+	testScaleDownWindow := func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
+		res, err := p.GetScaleDownWindow(ng)
+		assert.Equal(t, err, we)
+		results := map[Want]string{
+			NIL:    "",
+			GLOBAL: "* 0-5 * * *",
+			NG:     "* 22-23 * * 1-5",
+		}
+		assert.Equal(t, res, results[w])
+	}
+
+	testMaxDrainParallelism := func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
+		res, err := p.GetMaxDrainParallelism(ng)
+		assert.Equal(t, err, we)
+		results := map[Want]int{
+			NIL:    0,
+			GLOBAL: 2,
+			NG:     5,
+		}
+		assert.Equal(t, res, results[w])
+	}
+
+	testMaxPodEvictionsPerMinute := func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
+		res, err := p.GetMaxPodEvictionsPerMinute(ng)
+		assert.Equal(t, err, we)
+		results := map[Want]int{
+			NIL:    0,
+			GLOBAL: 20,
+			NG:     50,
+		}
+		assert.Equal(t, res, results[w])
+	}
+
+	testScaleDownUtilizationMode := func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
+		res, err := p.GetScaleDownUtilizationMode(ng)
+		assert.Equal(t, err, we)
+		results := map[Want]string{
+			NIL:    "",
+			GLOBAL: "usage",
+			NG:     "hybrid",
+		}
+		assert.Equal(t, res, results[w])
+	}
+
 	funcs := map[string]func(*testing.T, NodeGroupConfigProcessor, cloudprovider.NodeGroup, Want, error){
 		"ScaleDownUnneededTime":            testUnneededTime,
 		"ScaleDownUnreadyTime":             testUnreadyTime,
@@ -128,6 +232,14 @@ func TestDelegatingNodeGroupConfigProcessor(t *testing.T) {
 		"ScaleDownGpuUtilizationThreshold": testGpuThreshold,
 		"MaxNodeProvisionTime":             testMaxNodeProvisionTime,
 		"IgnoreDaemonSetsUtilization":      testIgnoreDSUtilization,
+		"NodeReadinessTimeout":             testNodeReadinessTimeout,
+		"MaxGracefulTerminationSec":        testMaxGracefulTerminationSec,
+		"HeadroomNodes":                    testHeadroomNodes,
+		"HeadroomRatio":                    testHeadroomRatio,
+		"ScaleDownWindow":                  testScaleDownWindow,
+		"MaxDrainParallelism":              testMaxDrainParallelism,
+		"MaxPodEvictionsPerMinute":         testMaxPodEvictionsPerMinute,
+		"ScaleDownUtilizationMode":         testScaleDownUtilizationMode,
 		"MultipleOptions": func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
 			testUnneededTime(t, p, ng, w, we)
 			testUnreadyTime(t, p, ng, w, we)
@@ -135,6 +247,14 @@ func TestDelegatingNodeGroupConfigProcessor(t *testing.T) {
 			testGpuThreshold(t, p, ng, w, we)
 			testMaxNodeProvisionTime(t, p, ng, w, we)
 			testIgnoreDSUtilization(t, p, ng, w, we)
+			testNodeReadinessTimeout(t, p, ng, w, we)
+			testMaxGracefulTerminationSec(t, p, ng, w, we)
+			testHeadroomNodes(t, p, ng, w, we)
+			testHeadroomRatio(t, p, ng, w, we)
+			testScaleDownWindow(t, p, ng, w, we)
+			testMaxDrainParallelism(t, p, ng, w, we)
+			testMaxPodEvictionsPerMinute(t, p, ng, w, we)
+			testScaleDownUtilizationMode(t, p, ng, w, we)
 		},
 		"RepeatingTheSameCallGivesConsistentResults": func(t *testing.T, p NodeGroupConfigProcessor, ng cloudprovider.NodeGroup, w Want, we error) {
 			testUnneededTime(t, p, ng, w, we)
@@ -186,3 +306,27 @@ func TestDelegatingNodeGroupConfigProcessor(t *testing.T) {
 		}
 	}
 }
+
+type thresholdReloader struct {
+	threshold float64
+}
+
+func (r thresholdReloader) ReloadNodeGroupDefaults(defaults config.NodeGroupAutoscalingOptions) config.NodeGroupAutoscalingOptions {
+	defaults.ScaleDownUtilizationThreshold = r.threshold
+	return defaults
+}
+
+func TestReloadingNodeGroupConfigProcessor(t *testing.T) {
+	globalOpts := config.NodeGroupAutoscalingOptions{ScaleDownUtilizationThreshold: 0.5, ScaleDownGpuUtilizationThreshold: 0.6}
+	reloadedOpts := config.NodeGroupAutoscalingOptions{ScaleDownUtilizationThreshold: 0.7, ScaleDownGpuUtilizationThreshold: 0.6}
+	ng := &mocks.NodeGroup{}
+	ng.On("GetOptions", reloadedOpts).Return(nil, cloudprovider.ErrNotImplemented)
+	p := NewReloadingNodeGroupConfigProcessor(globalOpts, thresholdReloader{threshold: 0.7})
+
+	threshold, err := p.GetScaleDownUtilizationThreshold(ng)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.7, threshold)
+	gpuThreshold, err := p.GetScaleDownGpuUtilizationThreshold(ng)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.6, gpuThreshold)
+}
@@ -0,0 +1,424 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodegroupresize
+
+import (
+	ctx "context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/scheduling"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+)
+
+// Kind is the kind of the custom resource recommending a machine type change.
+const Kind = "NodeGroupResizeRecommendation"
+
+// Resource is the resource of the NodeGroupResizeRecommendation custom resource definition.
+var Resource = schema.GroupVersionResource{Group: "autoscaling.x-k8s.io", Version: "v1alpha1", Resource: "nodegroupresizerecommendations"}
+
+// RecommendationProcessor recommends switching the machine type of node groups
+// whose nodes all stayed underutilized for long enough to a smaller one. The
+// smallest machine type whose nodes fit all the pods of the node group, checked
+// by replacing the nodes of the group in the cluster snapshot, is written to a
+// NodeGroupResizeRecommendation custom resource in the Cluster Autoscaler
+// namespace. Recommendations which are no longer valid are deleted.
+type RecommendationProcessor struct {
+	client          dynamic.Interface
+	simulator       *scheduling.HintingSimulator
+	configProcessor nodegroupconfig.NodeGroupConfigProcessor
+	threshold       float64
+	delay           time.Duration
+	// lowSince is when the nodes of a node group became underutilized.
+	lowSince map[string]time.Time
+	// written are the names of recommendations written in the previous loop.
+	written map[string]bool
+}
+
+// NewRecommendationProcessor returns a new RecommendationProcessor.
+func NewRecommendationProcessor(client dynamic.Interface, predicateChecker predicatechecker.PredicateChecker, configProcessor nodegroupconfig.NodeGroupConfigProcessor, threshold float64, delay time.Duration) *RecommendationProcessor {
+	return &RecommendationProcessor{
+		client:          client,
+		simulator:       scheduling.NewHintingSimulator(predicateChecker),
+		configProcessor: configProcessor,
+		threshold:       threshold,
+		delay:           delay,
+		lowSince:        make(map[string]time.Time),
+		written:         make(map[string]bool),
+	}
+}
+
+// recommendation is a machine type change recommended for a node group.
+type recommendation struct {
+	nodeGroup              string
+	currentMachineType     string
+	recommendedMachineType string
+	nodeCount              int
+	utilization            float64
+	lowUtilizationSince    time.Time
+}
+
+// Process updates the recommendations after an autoscaling iteration.
+func (p *RecommendationProcessor) Process(context *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, now time.Time) error {
+	groups, err := nodeGroupNodes(context)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	lowSince := make(map[string]time.Time)
+	written := make(map[string]bool)
+	for _, id := range ids {
+		group := groups[id]
+		maxUtilization, low := p.underutilized(context, group, now)
+		if !low {
+			continue
+		}
+		since, found := p.lowSince[id]
+		if !found {
+			since = now
+		}
+		lowSince[id] = since
+		if now.Sub(since) < p.delay {
+			continue
+		}
+		rec, err := p.recommend(context, group)
+		if err != nil {
+			klog.Warningf("Failed to compute %s for node group %s: %v", Kind, id, err)
+			continue
+		}
+		if rec == nil {
+			continue
+		}
+		rec.utilization = maxUtilization
+		rec.lowUtilizationSince = since
+		name := objectName(id)
+		if err := p.write(context.ConfigNamespace, name, rec, now); err != nil {
+			klog.Warningf("Failed to write %s %s/%s: %v", Kind, context.ConfigNamespace, name, err)
+		}
+		written[name] = true
+	}
+	for name := range p.written {
+		if written[name] {
+			continue
+		}
+		err := p.client.Resource(Resource).Namespace(context.ConfigNamespace).Delete(ctx.TODO(), name, metav1.DeleteOptions{})
+		if err != nil && !kube_errors.IsNotFound(err) {
+			klog.Warningf("Failed to delete %s %s/%s: %v", Kind, context.ConfigNamespace, name, err)
+			written[name] = true
+		}
+	}
+	p.lowSince = lowSince
+	p.written = written
+	return nil
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *RecommendationProcessor) CleanUp() {
+}
+
+type nodeGroupWithNodes struct {
+	nodeGroup cloudprovider.NodeGroup
+	nodeInfos []*schedulerframework.NodeInfo
+}
+
+// nodeGroupNodes returns registered nodes of each node group, as they are in
+// the cluster snapshot. Node groups with nodes being deleted are skipped.
+func nodeGroupNodes(context *context.AutoscalingContext) (map[string]*nodeGroupWithNodes, error) {
+	nodes, err := context.AllNodeLister().List()
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]*nodeGroupWithNodes)
+	skipped := make(map[string]bool)
+	for _, node := range nodes {
+		nodeGroup, err := context.CloudProvider.NodeGroupForNode(node)
+		if err != nil {
+			klog.Warningf("Failed to get node group for %s: %v", node.Name, err)
+			continue
+		}
+		if nodeGroup == nil || nodeGroup.Id() == "" {
+			continue
+		}
+		id := nodeGroup.Id()
+		if taints.HasToBeDeletedTaint(node) {
+			skipped[id] = true
+			continue
+		}
+		nodeInfo, err := context.ClusterSnapshot.NodeInfos().Get(node.Name)
+		if err != nil {
+			continue
+		}
+		if _, found := groups[id]; !found {
+			groups[id] = &nodeGroupWithNodes{nodeGroup: nodeGroup}
+		}
+		groups[id].nodeInfos = append(groups[id].nodeInfos, nodeInfo)
+	}
+	for id := range skipped {
+		delete(groups, id)
+	}
+	return groups, nil
+}
+
+// underutilized tells if utilization of all nodes of the node group is below
+// the threshold, along with the highest utilization.
+func (p *RecommendationProcessor) underutilized(context *context.AutoscalingContext, group *nodeGroupWithNodes, now time.Time) (float64, bool) {
+	ignoreDaemonSetsUtilization, err := p.configProcessor.GetIgnoreDaemonSetsUtilization(group.nodeGroup)
+	if err != nil {
+		klog.Warningf("Couldn't retrieve `IgnoreDaemonSetsUtilization` option for node group %v: %v", group.nodeGroup.Id(), err)
+		return 0, false
+	}
+	maxUtilization := 0.0
+	for _, nodeInfo := range group.nodeInfos {
+		gpuConfig := context.CloudProvider.GetNodeGpuConfig(nodeInfo.Node())
+		utilInfo, err := utilization.Calculate(nodeInfo, ignoreDaemonSetsUtilization, context.IgnoreMirrorPodsUtilization, gpuConfig, now, context.LongTerminatingPodThreshold)
+		if err != nil {
+			klog.Warningf("Failed to calculate utilization for %s: %v", nodeInfo.Node().Name, err)
+			return 0, false
+		}
+		if utilInfo.Utilization >= p.threshold {
+			return 0, false
+		}
+		if utilInfo.Utilization > maxUtilization {
+			maxUtilization = utilInfo.Utilization
+		}
+	}
+	return maxUtilization, true
+}
+
+type machineTypeTemplate struct {
+	machineType string
+	template    *schedulerframework.NodeInfo
+}
+
+// recommend returns the smallest machine type nodes of which are smaller than
+// the current nodes of the node group and fit all its pods, or nil if there is
+// no such machine type.
+func (p *RecommendationProcessor) recommend(context *context.AutoscalingContext, group *nodeGroupWithNodes) (*recommendation, error) {
+	machineTypes, err := context.CloudProvider.GetAvailableMachineTypes()
+	if err == cloudprovider.ErrNotImplemented {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	current := group.nodeInfos[0].Node()
+	currentMachineType := current.Labels[apiv1.LabelInstanceTypeStable]
+	nodeLabels := templateLabels(current)
+	nodeTaints := templateTaints(current)
+
+	var candidates []machineTypeTemplate
+	for _, machineType := range machineTypes {
+		if machineType == currentMachineType {
+			continue
+		}
+		nodeGroup, err := context.CloudProvider.NewNodeGroup(machineType, nodeLabels, nil, nodeTaints, nil)
+		if err != nil {
+			klog.V(4).Infof("Failed to build node group with machine type %s: %v", machineType, err)
+			continue
+		}
+		template, err := nodeGroup.TemplateNodeInfo()
+		if err != nil {
+			klog.V(4).Infof("Failed to build template node for machine type %s: %v", machineType, err)
+			continue
+		}
+		if !smaller(template.Node(), current) {
+			continue
+		}
+		candidates = append(candidates, machineTypeTemplate{machineType: machineType, template: template})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return smaller(candidates[i].template.Node(), candidates[j].template.Node())
+	})
+
+	for _, candidate := range candidates {
+		fits, err := p.fits(context.ClusterSnapshot, group, candidate.template, nodeLabels)
+		if err != nil {
+			return nil, err
+		}
+		if fits {
+			return &recommendation{
+				nodeGroup:              group.nodeGroup.Id(),
+				currentMachineType:     currentMachineType,
+				recommendedMachineType: candidate.machineType,
+				nodeCount:              len(group.nodeInfos),
+			}, nil
+		}
+		klog.V(4).Infof("Pods of node group %s don't fit on %d nodes of machine type %s", group.nodeGroup.Id(), len(group.nodeInfos), candidate.machineType)
+	}
+	return nil, nil
+}
+
+// fits tells if the pods of the node group, except DaemonSet and mirror pods,
+// fit on the same number of nodes built from the template. The check is done
+// in a fork of the cluster snapshot, in which the nodes of the node group are
+// replaced with the new ones.
+func (p *RecommendationProcessor) fits(snapshot clustersnapshot.ClusterSnapshot, group *nodeGroupWithNodes, template *schedulerframework.NodeInfo, nodeLabels map[string]string) (bool, error) {
+	fits := false
+	err, cleanupErr := clustersnapshot.WithForkedSnapshot(snapshot, func() (bool, error) {
+		var pods []*apiv1.Pod
+		for _, nodeInfo := range group.nodeInfos {
+			for _, podInfo := range nodeInfo.Pods {
+				if pod_util.IsDaemonSetPod(podInfo.Pod) || pod_util.IsMirrorPod(podInfo.Pod) {
+					continue
+				}
+				pod := podInfo.Pod.DeepCopy()
+				pod.Spec.NodeName = ""
+				pods = append(pods, pod)
+			}
+			if err := snapshot.RemoveNode(nodeInfo.Node().Name); err != nil {
+				return false, err
+			}
+		}
+		newNodes := make(map[string]bool)
+		for i := range group.nodeInfos {
+			nodeInfo := scheduler.DeepCopyTemplateNode(template, fmt.Sprintf("resize-%d", i))
+			node := nodeInfo.Node()
+			for key, value := range nodeLabels {
+				if _, found := node.Labels[key]; !found {
+					node.Labels[key] = value
+				}
+			}
+			var templatePods []*apiv1.Pod
+			for _, podInfo := range nodeInfo.Pods {
+				templatePods = append(templatePods, podInfo.Pod)
+			}
+			if err := snapshot.AddNodeWithPods(node, templatePods); err != nil {
+				return false, err
+			}
+			newNodes[node.Name] = true
+		}
+		statuses, _, err := p.simulator.TrySchedulePods(snapshot, pods, func(nodeInfo *schedulerframework.NodeInfo) bool {
+			return newNodes[nodeInfo.Node().Name]
+		}, true)
+		if err != nil {
+			return false, err
+		}
+		fits = len(statuses) == len(pods)
+		return false, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return fits, cleanupErr
+}
+
+// templateLabels returns labels of the node which should be kept on nodes of
+// a different machine type.
+func templateLabels(node *apiv1.Node) map[string]string {
+	result := make(map[string]string)
+	for key, value := range node.Labels {
+		switch key {
+		case apiv1.LabelHostname, apiv1.LabelInstanceTypeStable, apiv1.LabelInstanceType:
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// templateTaints returns taints of the node, except the ones added by Cluster
+// Autoscaler.
+func templateTaints(node *apiv1.Node) []apiv1.Taint {
+	var result []apiv1.Taint
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == taints.ToBeDeletedTaint || taint.Key == taints.DeletionCandidateTaint {
+			continue
+		}
+		result = append(result, taint)
+	}
+	return result
+}
+
+// smaller tells if the node has no more CPU and memory allocatable than the
+// other one, and less of at least one of them.
+func smaller(node, other *apiv1.Node) bool {
+	cpu, otherCpu := node.Status.Allocatable[apiv1.ResourceCPU], other.Status.Allocatable[apiv1.ResourceCPU]
+	memory, otherMemory := node.Status.Allocatable[apiv1.ResourceMemory], other.Status.Allocatable[apiv1.ResourceMemory]
+	cpuCmp, memoryCmp := cpu.Cmp(otherCpu), memory.Cmp(otherMemory)
+	return cpuCmp <= 0 && memoryCmp <= 0 && (cpuCmp < 0 || memoryCmp < 0)
+}
+
+// objectName returns the name of the recommendation for the node group, with
+// characters not allowed in object names replaced.
+func objectName(nodeGroupId string) string {
+	name := strings.ToLower(nodeGroupId)
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, name)
+	if len(name) > 253 {
+		name = name[:253]
+	}
+	return strings.Trim(name, "-.")
+}
+
+func (p *RecommendationProcessor) write(namespace, name string, rec *recommendation, now time.Time) error {
+	client := p.client.Resource(Resource).Namespace(namespace)
+	recStatus := map[string]interface{}{
+		"nodeGroup":              rec.nodeGroup,
+		"currentMachineType":     rec.currentMachineType,
+		"recommendedMachineType": rec.recommendedMachineType,
+		"nodeCount":              int64(rec.nodeCount),
+		"utilization":            rec.utilization,
+		"lowUtilizationSince":    rec.lowUtilizationSince.UTC().Format(time.RFC3339),
+		"lastUpdateTime":         now.UTC().Format(time.RFC3339),
+	}
+	obj, err := client.Get(ctx.TODO(), name, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		obj = &unstructured.Unstructured{}
+		obj.SetAPIVersion(Resource.GroupVersion().String())
+		obj.SetKind(Kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.Object["status"] = recStatus
+		_, err = client.Create(ctx.TODO(), obj, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	obj.Object["status"] = recStatus
+	_, err = client.Update(ctx.TODO(), obj, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodegroupresize
+
+import (
+	ctx "context"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendationProcessor(t *testing.T) {
+	n1 := buildNode("n1", "large", 4000, 4000)
+	n2 := buildNode("n2", "large", 4000, 4000)
+	p1 := BuildTestPod("p1", 600, 600)
+	p1.Spec.NodeName = "n1"
+	p2 := BuildTestPod("p2", 600, 600)
+	p2.Spec.NodeName = "n2"
+
+	machineTemplates := map[string]*schedulerframework.NodeInfo{
+		"large":  templateNodeInfo("large", 4000, 4000),
+		"medium": templateNodeInfo("medium", 2000, 2000),
+		"small":  templateNodeInfo("small", 500, 500),
+		"tall":   templateNodeInfo("tall", 2000, 8000),
+	}
+	provider := testprovider.NewTestAutoprovisioningCloudProvider(nil, nil, nil, nil, []string{"small", "medium", "large", "tall"}, machineTemplates)
+	provider.AddNodeGroup("ng1", 0, 10, 2)
+	provider.AddNode("ng1", n1)
+	provider.AddNode("ng1", n2)
+
+	snapshot := clustersnapshot.NewBasicClusterSnapshot()
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, snapshot, []*apiv1.Node{n1, n2}, []*apiv1.Pod{p1, p2})
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		Resource: Kind + "List",
+	})
+	autoscalingContext := &context.AutoscalingContext{
+		AutoscalingOptions: config.AutoscalingOptions{ConfigNamespace: "kube-system"},
+		AutoscalingKubeClients: context.AutoscalingKubeClients{
+			ListerRegistry: kube_util.NewListerRegistry(kube_util.NewTestNodeLister([]*apiv1.Node{n1, n2}), nil, nil, nil, nil, nil, nil, nil, nil),
+		},
+		CloudProvider:   provider,
+		ClusterSnapshot: snapshot,
+	}
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+	p := NewRecommendationProcessor(client, predicateChecker, nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{}), 0.3, time.Hour)
+
+	// Nothing is recommended until the nodes stay underutilized for long enough.
+	now := time.Now()
+	assert.NoError(t, p.Process(autoscalingContext, nil, now))
+	_, err = client.Resource(Resource).Namespace("kube-system").Get(ctx.TODO(), "ng1", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	// The smallest machine type fitting the pods is recommended.
+	assert.NoError(t, p.Process(autoscalingContext, nil, now.Add(2*time.Hour)))
+	obj, err := client.Resource(Resource).Namespace("kube-system").Get(ctx.TODO(), "ng1", metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ng1", nestedString(obj, "nodeGroup"))
+		assert.Equal(t, "large", nestedString(obj, "currentMachineType"))
+		assert.Equal(t, "medium", nestedString(obj, "recommendedMachineType"))
+		nodeCount, _, _ := unstructured.NestedInt64(obj.Object, "status", "nodeCount")
+		assert.Equal(t, int64(2), nodeCount)
+		assert.Equal(t, now.UTC().Format(time.RFC3339), nestedString(obj, "lowUtilizationSince"))
+	}
+
+	// The nodes aren't underutilized anymore, the recommendation is deleted.
+	p3 := BuildTestPod("p3", 1500, 600)
+	p3.Spec.NodeName = "n1"
+	assert.NoError(t, snapshot.AddPod(p3, "n1"))
+	assert.NoError(t, p.Process(autoscalingContext, nil, now.Add(3*time.Hour)))
+	_, err = client.Resource(Resource).Namespace("kube-system").Get(ctx.TODO(), "ng1", metav1.GetOptions{})
+	assert.Error(t, err)
+
+	// The snapshot is left intact.
+	nodeInfos, err := snapshot.NodeInfos().List()
+	assert.NoError(t, err)
+	assert.Len(t, nodeInfos, 2)
+}
+
+func TestSmaller(t *testing.T) {
+	large := BuildTestNode("large", 4000, 4000)
+	medium := BuildTestNode("medium", 2000, 2000)
+	tall := BuildTestNode("tall", 2000, 8000)
+	assert.True(t, smaller(medium, large))
+	assert.False(t, smaller(large, medium))
+	assert.False(t, smaller(large, large))
+	assert.False(t, smaller(tall, large))
+}
+
+func TestObjectName(t *testing.T) {
+	assert.Equal(t, "ng1", objectName("ng1"))
+	assert.Equal(t, "https---www.googleapis.com-compute-v1-projects-p-zones-z-instancegroups-ig", objectName("https://www.googleapis.com/compute/v1/projects/p/zones/z/instanceGroups/ig"))
+}
+
+func buildNode(name, machineType string, millicpu, mem int64) *apiv1.Node {
+	node := BuildTestNode(name, millicpu, mem)
+	node.Labels[apiv1.LabelInstanceTypeStable] = machineType
+	SetNodeReadyState(node, true, time.Time{})
+	return node
+}
+
+func templateNodeInfo(machineType string, millicpu, mem int64) *schedulerframework.NodeInfo {
+	nodeInfo := schedulerframework.NewNodeInfo()
+	nodeInfo.SetNode(buildNode("template-"+machineType, machineType, millicpu, mem))
+	return nodeInfo
+}
+
+func nestedString(obj *unstructured.Unstructured, field string) string {
+	value, _, _ := unstructured.NestedString(obj.Object, "status", field)
+	return value
+}
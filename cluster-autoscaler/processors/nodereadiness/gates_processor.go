@@ -0,0 +1,255 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodereadiness
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// ReadinessGates are checks new nodes have to pass, in addition to the Ready
+// condition, before they are counted as ready.
+type ReadinessGates struct {
+	// Taints are keys of taints which have to be removed from the node.
+	Taints []string
+	// Conditions are types of node conditions which have to be True.
+	Conditions []apiv1.NodeConditionType
+	// PodSelectors select pods, e.g. of a CNI DaemonSet, which have to be
+	// running and ready on the node. Each selector has to match at least one
+	// such pod.
+	PodSelectors []labels.Selector
+}
+
+// ParseReadinessGates parses readiness gates from flag values: taint keys,
+// node condition types and pod label selectors.
+func ParseReadinessGates(taints, conditions, podSelectors []string) (ReadinessGates, error) {
+	gates := ReadinessGates{Taints: taints}
+	for _, condition := range conditions {
+		gates.Conditions = append(gates.Conditions, apiv1.NodeConditionType(condition))
+	}
+	for _, podSelector := range podSelectors {
+		selector, err := labels.Parse(podSelector)
+		if err != nil {
+			return ReadinessGates{}, fmt.Errorf("invalid pod selector %q: %v", podSelector, err)
+		}
+		if selector.Empty() {
+			return ReadinessGates{}, fmt.Errorf("invalid pod selector %q: selector matches all pods", podSelector)
+		}
+		gates.PodSelectors = append(gates.PodSelectors, selector)
+	}
+	return gates, nil
+}
+
+// Empty tells if there are no readiness gates.
+func (g ReadinessGates) Empty() bool {
+	return len(g.Taints) == 0 && len(g.Conditions) == 0 && len(g.PodSelectors) == 0
+}
+
+// GatesNodeReadinessProcessor treats new nodes as unready until they pass
+// readiness gates. Nodes which don't pass them within the readiness timeout of
+// their node group are reported for replacement. Nodes which passed the gates
+// once, and nodes which were already older than the timeout when first seen,
+// are not checked again, so that e.g. a restart of a CNI pod doesn't make an
+// established node unready.
+type GatesNodeReadinessProcessor struct {
+	gates                    ReadinessGates
+	nodeGroupConfigProcessor nodegroupconfig.NodeGroupConfigProcessor
+	// settled contains names of nodes which aren't subject to the gates.
+	settled map[string]bool
+	// gated contains names of nodes held back by the gates.
+	gated          map[string]bool
+	nodesToReplace []*apiv1.Node
+}
+
+// NewGatesNodeReadinessProcessor returns a new GatesNodeReadinessProcessor.
+func NewGatesNodeReadinessProcessor(gates ReadinessGates, nodeGroupConfigProcessor nodegroupconfig.NodeGroupConfigProcessor) *GatesNodeReadinessProcessor {
+	return &GatesNodeReadinessProcessor{
+		gates:                    gates,
+		nodeGroupConfigProcessor: nodeGroupConfigProcessor,
+		settled:                  make(map[string]bool),
+		gated:                    make(map[string]bool),
+	}
+}
+
+// FilterOutUnreadyNodes removes new nodes that don't pass the readiness gates
+// from ready nodes list and updates their status to unready on all nodes list.
+func (p *GatesNodeReadinessProcessor) FilterOutUnreadyNodes(context *context.AutoscalingContext, allNodes, readyNodes []*apiv1.Node, currentTime time.Time) ([]*apiv1.Node, []*apiv1.Node) {
+	p.forgetDeletedNodes(allNodes)
+	p.nodesToReplace = nil
+
+	var podsByNode map[string][]*apiv1.Pod
+	newReadyNodes := make([]*apiv1.Node, 0, len(readyNodes))
+	unreadyNodes := make(map[string]*apiv1.Node)
+	for _, node := range readyNodes {
+		if p.settled[node.Name] {
+			newReadyNodes = append(newReadyNodes, node)
+			continue
+		}
+		timeout := p.readinessTimeout(context, node)
+		expired := !node.CreationTimestamp.Add(timeout).After(currentTime)
+		if timeout <= 0 || (expired && !p.gated[node.Name]) {
+			// The node isn't new, or the gates are disabled for its node group.
+			p.settled[node.Name] = true
+			newReadyNodes = append(newReadyNodes, node)
+			continue
+		}
+		if podsByNode == nil && len(p.gates.PodSelectors) > 0 {
+			podsByNode = listPodsByNode(context)
+		}
+		reason, passed := p.check(node, podsByNode[node.Name])
+		if passed {
+			p.settled[node.Name] = true
+			delete(p.gated, node.Name)
+			newReadyNodes = append(newReadyNodes, node)
+			continue
+		}
+		p.gated[node.Name] = true
+		unreadyNodes[node.Name] = kubernetes.GetUnreadyNodeCopy(node, kubernetes.ReadinessGatesUnready)
+		if expired {
+			klog.Warningf("Node %v didn't pass readiness gates within %v: %s", node.Name, timeout, reason)
+			p.nodesToReplace = append(p.nodesToReplace, node)
+		} else {
+			klog.V(3).Infof("Overriding status of node %v, which doesn't pass readiness gates yet: %s", node.Name, reason)
+		}
+	}
+
+	newAllNodes := make([]*apiv1.Node, 0, len(allNodes))
+	for _, node := range allNodes {
+		if newNode, found := unreadyNodes[node.Name]; found {
+			newAllNodes = append(newAllNodes, newNode)
+		} else {
+			newAllNodes = append(newAllNodes, node)
+		}
+	}
+	return newAllNodes, newReadyNodes
+}
+
+// NodesToReplace returns nodes which didn't pass the readiness gates within
+// the readiness timeout of their node group.
+func (p *GatesNodeReadinessProcessor) NodesToReplace() []*apiv1.Node {
+	return p.nodesToReplace
+}
+
+// CleanUp cleans up processor's internal structures.
+func (p *GatesNodeReadinessProcessor) CleanUp() {
+}
+
+func (p *GatesNodeReadinessProcessor) forgetDeletedNodes(allNodes []*apiv1.Node) {
+	present := make(map[string]bool, len(allNodes))
+	for _, node := range allNodes {
+		present[node.Name] = true
+	}
+	for name := range p.settled {
+		if !present[name] {
+			delete(p.settled, name)
+		}
+	}
+	for name := range p.gated {
+		if !present[name] {
+			delete(p.gated, name)
+		}
+	}
+}
+
+// readinessTimeout returns the readiness timeout of the node group of the
+// node, or 0 if the node doesn't belong to an autoscaled node group.
+func (p *GatesNodeReadinessProcessor) readinessTimeout(context *context.AutoscalingContext, node *apiv1.Node) time.Duration {
+	nodeGroup, err := context.CloudProvider.NodeGroupForNode(node)
+	if err != nil {
+		klog.Warningf("Failed to get node group for %s: %v", node.Name, err)
+		return 0
+	}
+	if nodeGroup == nil || reflect.ValueOf(nodeGroup).IsNil() {
+		return 0
+	}
+	timeout, err := p.nodeGroupConfigProcessor.GetNodeReadinessTimeout(nodeGroup)
+	if err != nil {
+		klog.Warningf("Failed to get node readiness timeout for node group %s: %v", nodeGroup.Id(), err)
+		return 0
+	}
+	return timeout
+}
+
+// check returns whether the node passes the readiness gates and, if it
+// doesn't, the first gate it fails.
+func (p *GatesNodeReadinessProcessor) check(node *apiv1.Node, pods []*apiv1.Pod) (reason string, passed bool) {
+	for _, taintKey := range p.gates.Taints {
+		for _, taint := range node.Spec.Taints {
+			if taint.Key == taintKey {
+				return fmt.Sprintf("taint %q is present", taintKey), false
+			}
+		}
+	}
+	for _, conditionType := range p.gates.Conditions {
+		if !hasTrueCondition(node, conditionType) {
+			return fmt.Sprintf("condition %q is not True", conditionType), false
+		}
+	}
+	for _, selector := range p.gates.PodSelectors {
+		if !hasReadyPod(pods, selector) {
+			return fmt.Sprintf("no ready pod matches %q", selector.String()), false
+		}
+	}
+	return "", true
+}
+
+func hasTrueCondition(node *apiv1.Node, conditionType apiv1.NodeConditionType) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status == apiv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func hasReadyPod(pods []*apiv1.Pod, selector labels.Selector) bool {
+	for _, pod := range pods {
+		if !selector.Matches(labels.Set(pod.Labels)) || pod.Status.Phase != apiv1.PodRunning {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == apiv1.PodReady && condition.Status == apiv1.ConditionTrue {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func listPodsByNode(context *context.AutoscalingContext) map[string][]*apiv1.Pod {
+	podsByNode := make(map[string][]*apiv1.Pod)
+	pods, err := context.AllPodLister().List()
+	if err != nil {
+		// Nodes will be treated as unready until pods can be listed.
+		klog.Warningf("Failed to list pods for node readiness gates: %v", err)
+		return podsByNode
+	}
+	for _, pod := range pods {
+		if pod.Spec.NodeName != "" {
+			podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+		}
+	}
+	return podsByNode
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodereadiness
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupconfig"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestParseReadinessGates(t *testing.T) {
+	gates, err := ParseReadinessGates([]string{"example.com/not-ready"}, []string{"NetworkReady"}, []string{"app=cni"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"example.com/not-ready"}, gates.Taints)
+	assert.Equal(t, []apiv1.NodeConditionType{"NetworkReady"}, gates.Conditions)
+	assert.Len(t, gates.PodSelectors, 1)
+	assert.False(t, gates.Empty())
+
+	gates, err = ParseReadinessGates(nil, nil, nil)
+	assert.NoError(t, err)
+	assert.True(t, gates.Empty())
+
+	_, err = ParseReadinessGates(nil, nil, []string{"app in"})
+	assert.Error(t, err)
+	_, err = ParseReadinessGates(nil, nil, []string{""})
+	assert.Error(t, err)
+}
+
+func TestGatesNodeReadinessProcessor(t *testing.T) {
+	now := time.Now()
+	timeout := 10 * time.Minute
+
+	newNode := func(name string, age time.Duration) *apiv1.Node {
+		node := BuildTestNode(name, 1000, 1000)
+		SetNodeReadyState(node, true, now.Add(-age))
+		node.CreationTimestamp = metav1.NewTime(now.Add(-age))
+		return node
+	}
+	withCondition := func(node *apiv1.Node, status apiv1.ConditionStatus) *apiv1.Node {
+		node.Status.Conditions = append(node.Status.Conditions, apiv1.NodeCondition{Type: "NetworkReady", Status: status})
+		return node
+	}
+	withTaint := func(node *apiv1.Node) *apiv1.Node {
+		node.Spec.Taints = append(node.Spec.Taints, apiv1.Taint{Key: "example.com/not-ready", Effect: apiv1.TaintEffectNoSchedule})
+		return node
+	}
+	cniPod := func(node string, ready bool) *apiv1.Pod {
+		pod := BuildTestPod("cni-"+node, 100, 0)
+		pod.Labels = map[string]string{"app": "cni"}
+		pod.Spec.NodeName = node
+		pod.Status.Phase = apiv1.PodRunning
+		status := apiv1.ConditionFalse
+		if ready {
+			status = apiv1.ConditionTrue
+		}
+		pod.Status.Conditions = []apiv1.PodCondition{{Type: apiv1.PodReady, Status: status}}
+		return pod
+	}
+
+	testCases := []struct {
+		name        string
+		node        *apiv1.Node
+		pods        []*apiv1.Pod
+		noNodeGroup bool
+		wantReady   bool
+	}{
+		{
+			name:      "passes all gates",
+			node:      withCondition(newNode("n", time.Minute), apiv1.ConditionTrue),
+			pods:      []*apiv1.Pod{cniPod("n", true)},
+			wantReady: true,
+		},
+		{
+			name: "gate taint present",
+			node: withTaint(withCondition(newNode("n", time.Minute), apiv1.ConditionTrue)),
+			pods: []*apiv1.Pod{cniPod("n", true)},
+		},
+		{
+			name: "condition not true",
+			node: withCondition(newNode("n", time.Minute), apiv1.ConditionFalse),
+			pods: []*apiv1.Pod{cniPod("n", true)},
+		},
+		{
+			name: "condition missing",
+			node: newNode("n", time.Minute),
+			pods: []*apiv1.Pod{cniPod("n", true)},
+		},
+		{
+			name: "pod not ready",
+			node: withCondition(newNode("n", time.Minute), apiv1.ConditionTrue),
+			pods: []*apiv1.Pod{cniPod("n", false)},
+		},
+		{
+			name: "pod on another node",
+			node: withCondition(newNode("n", time.Minute), apiv1.ConditionTrue),
+			pods: []*apiv1.Pod{cniPod("other", true)},
+		},
+		{
+			name:      "old node isn't gated",
+			node:      newNode("n", time.Hour),
+			wantReady: true,
+		},
+		{
+			name:        "node without node group isn't gated",
+			node:        newNode("n", time.Minute),
+			noNodeGroup: true,
+			wantReady:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := newTestContext(tc.pods, tc.node, tc.noNodeGroup)
+			p := newTestProcessor(timeout, "app=cni")
+
+			allNodes, readyNodes := p.FilterOutUnreadyNodes(ctx, []*apiv1.Node{tc.node}, []*apiv1.Node{tc.node}, now)
+			assert.Len(t, allNodes, 1)
+			if tc.wantReady {
+				assert.Equal(t, []*apiv1.Node{tc.node}, readyNodes)
+				assert.Equal(t, tc.node, allNodes[0])
+			} else {
+				assert.Empty(t, readyNodes)
+				ready, _, _ := kube_util.GetReadinessState(allNodes[0])
+				assert.False(t, ready)
+			}
+			assert.Empty(t, p.NodesToReplace())
+		})
+	}
+}
+
+func TestGatesNodeReadinessProcessorTimeout(t *testing.T) {
+	now := time.Now()
+	timeout := 10 * time.Minute
+	node := BuildTestNode("n", 1000, 1000)
+	SetNodeReadyState(node, true, now)
+	node.CreationTimestamp = metav1.NewTime(now)
+	ctx := newTestContext(nil, node, false)
+	p := newTestProcessor(timeout)
+
+	// The node doesn't pass the gates, but isn't old enough to be replaced.
+	_, readyNodes := p.FilterOutUnreadyNodes(ctx, []*apiv1.Node{node}, []*apiv1.Node{node}, now.Add(time.Minute))
+	assert.Empty(t, readyNodes)
+	assert.Empty(t, p.NodesToReplace())
+
+	// Once the timeout passes, the node is still gated and is reported for replacement.
+	_, readyNodes = p.FilterOutUnreadyNodes(ctx, []*apiv1.Node{node}, []*apiv1.Node{node}, now.Add(timeout))
+	assert.Empty(t, readyNodes)
+	assert.Equal(t, []*apiv1.Node{node}, p.NodesToReplace())
+
+	// After passing the gates the node is ready and isn't checked again.
+	passed := node.DeepCopy()
+	passed.Status.Conditions = append(passed.Status.Conditions, apiv1.NodeCondition{Type: "NetworkReady", Status: apiv1.ConditionTrue})
+	ctx = newTestContext(nil, passed, false)
+	_, readyNodes = p.FilterOutUnreadyNodes(ctx, []*apiv1.Node{passed}, []*apiv1.Node{passed}, now.Add(timeout))
+	assert.Equal(t, []*apiv1.Node{passed}, readyNodes)
+	assert.Empty(t, p.NodesToReplace())
+	_, readyNodes = p.FilterOutUnreadyNodes(ctx, []*apiv1.Node{node}, []*apiv1.Node{node}, now.Add(timeout))
+	assert.Equal(t, []*apiv1.Node{node}, readyNodes)
+
+	// A node which is deleted and recreated under the same name is gated again.
+	p.FilterOutUnreadyNodes(ctx, []*apiv1.Node{}, []*apiv1.Node{}, now.Add(timeout))
+	_, readyNodes = p.FilterOutUnreadyNodes(ctx, []*apiv1.Node{node}, []*apiv1.Node{node}, now.Add(time.Minute))
+	assert.Empty(t, readyNodes)
+}
+
+func newTestProcessor(timeout time.Duration, podSelectors ...string) *GatesNodeReadinessProcessor {
+	gates, _ := ParseReadinessGates([]string{"example.com/not-ready"}, []string{"NetworkReady"}, podSelectors)
+	return NewGatesNodeReadinessProcessor(gates, nodegroupconfig.NewDefaultNodeGroupConfigProcessor(config.NodeGroupAutoscalingOptions{NodeReadinessTimeout: timeout}))
+}
+
+func newTestContext(pods []*apiv1.Pod, node *apiv1.Node, noNodeGroup bool) *context.AutoscalingContext {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	if !noNodeGroup {
+		provider.AddNodeGroup("ng", 0, 10, 1)
+		provider.AddNode("ng", node)
+	}
+	podLister := kube_util.NewTestPodLister(pods)
+	return &context.AutoscalingContext{
+		CloudProvider: provider,
+		AutoscalingKubeClients: context.AutoscalingKubeClients{
+			ListerRegistry: kube_util.NewListerRegistry(nil, nil, podLister, nil, nil, nil, nil, nil, nil),
+		},
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodereadiness
+
+import (
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+)
+
+// NodeReadinessProcessor decides whether new nodes that are Ready according
+// to the API are ready to run workloads, e.g. because they have to pass
+// additional checks after registering.
+type NodeReadinessProcessor interface {
+	// FilterOutUnreadyNodes removes nodes that aren't ready yet from ready
+	// nodes list and updates their status to unready on all nodes list.
+	FilterOutUnreadyNodes(context *context.AutoscalingContext, allNodes, readyNodes []*apiv1.Node, currentTime time.Time) ([]*apiv1.Node, []*apiv1.Node)
+	// NodesToReplace returns nodes which didn't become ready within the
+	// readiness timeout of their node group, as of the last
+	// FilterOutUnreadyNodes call.
+	NodesToReplace() []*apiv1.Node
+	// CleanUp cleans up processor's internal structures.
+	CleanUp()
+}
+
+// NoOpNodeReadinessProcessor doesn't check anything beyond the Ready
+// condition of nodes.
+type NoOpNodeReadinessProcessor struct{}
+
+// FilterOutUnreadyNodes returns the node lists unchanged.
+func (p *NoOpNodeReadinessProcessor) FilterOutUnreadyNodes(_ *context.AutoscalingContext, allNodes, readyNodes []*apiv1.Node, _ time.Time) ([]*apiv1.Node, []*apiv1.Node) {
+	return allNodes, readyNodes
+}
+
+// NodesToReplace returns no nodes.
+func (p *NoOpNodeReadinessProcessor) NodesToReplace() []*apiv1.Node {
+	return nil
+}
+
+// CleanUp cleans up processor's internal structures.
+func (p *NoOpNodeReadinessProcessor) CleanUp() {
+}
+
+// NewDefaultNodeReadinessProcessor returns a default instance of NodeReadinessProcessor.
+func NewDefaultNodeReadinessProcessor() NodeReadinessProcessor {
+	return &NoOpNodeReadinessProcessor{}
+}
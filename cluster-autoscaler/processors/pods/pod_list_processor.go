@@ -48,3 +48,35 @@ func (p *NoOpPodListProcessor) Process(
 // CleanUp cleans up the processor's internal structures.
 func (p *NoOpPodListProcessor) CleanUp() {
 }
+
+// CombinedPodListProcessor is a list of PodListProcessors executed in order.
+type CombinedPodListProcessor struct {
+	processors []PodListProcessor
+}
+
+// NewCombinedPodListProcessor returns a new CombinedPodListProcessor.
+func NewCombinedPodListProcessor(processors []PodListProcessor) *CombinedPodListProcessor {
+	return &CombinedPodListProcessor{processors: processors}
+}
+
+// Process processes lists of unschedulable pods with each of the processors,
+// passing the result of each processor to the next one.
+func (p *CombinedPodListProcessor) Process(
+	context *context.AutoscalingContext,
+	unschedulablePods []*apiv1.Pod) ([]*apiv1.Pod, error) {
+	var err error
+	for _, processor := range p.processors {
+		unschedulablePods, err = processor.Process(context, unschedulablePods)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return unschedulablePods, nil
+}
+
+// CleanUp cleans up internal structures of each of the processors.
+func (p *CombinedPodListProcessor) CleanUp() {
+	for _, processor := range p.processors {
+		processor.CleanUp()
+	}
+}
@@ -26,9 +26,11 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodeinfos"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodeinfosprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodereadiness"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/nodes"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/pods"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/scaledowncandidates/scoring"
 	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
 )
 
@@ -65,12 +67,20 @@ type AutoscalingProcessors struct {
 	CustomResourcesProcessor customresources.CustomResourcesProcessor
 	// ActionableClusterProcessor is interface defining whether the cluster is in an actionable state
 	ActionableClusterProcessor actionablecluster.ActionableClusterProcessor
+	// NodeReadinessProcessor decides whether new nodes are ready to run workloads.
+	NodeReadinessProcessor nodereadiness.NodeReadinessProcessor
 	// ScaleDownCandidatesNotifier  is used to Update and Register new scale down candidates observer.
 	ScaleDownCandidatesNotifier *scaledowncandidates.ObserversList
+	// ScaleDownCandidatesScorer is used to order removable nodes by their scores before scale-down.
+	ScaleDownCandidatesScorer *scoring.Framework
 }
 
 // DefaultProcessors returns default set of processors.
 func DefaultProcessors(options config.AutoscalingOptions) *AutoscalingProcessors {
+	scaleDownStatusProcessor := status.NewDefaultScaleDownStatusProcessor()
+	if options.RecordScaleDownBlockingPods {
+		scaleDownStatusProcessor = status.NewEventingScaleDownStatusProcessor()
+	}
 	return &AutoscalingProcessors{
 		PodListProcessor:       pods.NewDefaultPodListProcessor(),
 		NodeGroupListProcessor: nodegroups.NewDefaultNodeGroupListProcessor(),
@@ -88,15 +98,17 @@ func DefaultProcessors(options config.AutoscalingOptions) *AutoscalingProcessors
 				nodes.NewAtomicResizeFilteringProcessor(),
 			},
 		),
-		ScaleDownStatusProcessor:    status.NewDefaultScaleDownStatusProcessor(),
+		ScaleDownStatusProcessor:    scaleDownStatusProcessor,
 		AutoscalingStatusProcessor:  status.NewDefaultAutoscalingStatusProcessor(),
 		NodeGroupManager:            nodegroups.NewDefaultNodeGroupManager(),
 		NodeInfoProcessor:           nodeinfos.NewDefaultNodeInfoProcessor(),
 		NodeGroupConfigProcessor:    nodegroupconfig.NewDefaultNodeGroupConfigProcessor(options.NodeGroupDefaults),
 		CustomResourcesProcessor:    customresources.NewDefaultCustomResourcesProcessor(),
 		ActionableClusterProcessor:  actionablecluster.NewDefaultActionableClusterProcessor(),
+		NodeReadinessProcessor:      nodereadiness.NewDefaultNodeReadinessProcessor(),
 		TemplateNodeInfoProvider:    nodeinfosprovider.NewDefaultTemplateNodeInfoProvider(nil, false),
 		ScaleDownCandidatesNotifier: scaledowncandidates.NewObserversList(),
+		ScaleDownCandidatesScorer:   scoring.NewFramework(scoring.DefaultScorers()),
 	}
 }
 
@@ -116,4 +128,5 @@ func (ap *AutoscalingProcessors) CleanUp() {
 	ap.CustomResourcesProcessor.CleanUp()
 	ap.TemplateNodeInfoProvider.CleanUp()
 	ap.ActionableClusterProcessor.CleanUp()
+	ap.NodeReadinessProcessor.CleanUp()
 }
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeselector
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// AnyTaintKey is the taint key matching taints with any key.
+const AnyTaintKey = "*"
+
+// TaintSelector matches node taints by key and, optionally, effect.
+type TaintSelector struct {
+	// Key is the key of the taint, or AnyTaintKey.
+	Key string
+	// Effect is the effect of the taint. Taints with any effect are matched
+	// if empty.
+	Effect apiv1.TaintEffect
+}
+
+// Matches tells if the taint is matched by the selector.
+func (s TaintSelector) Matches(taint apiv1.Taint) bool {
+	if s.Key != AnyTaintKey && s.Key != taint.Key {
+		return false
+	}
+	return s.Effect == "" || s.Effect == taint.Effect
+}
+
+// Selector selects nodes by taints and labels, e.g. to exclude them from
+// scale down or to scale them down first. A node is selected if any of its
+// taints matches any of the taint selectors, or if its labels match any of
+// the node selectors.
+type Selector struct {
+	Taints        []TaintSelector
+	NodeSelectors []labels.Selector
+}
+
+// Parse parses a Selector from flag values: taints as <key>[:<effect>], where
+// the key can be "*" to match taints with any key, and node label selectors.
+func Parse(taints, nodeSelectors []string) (Selector, error) {
+	selector := Selector{}
+	for _, taint := range taints {
+		taintSelector, err := parseTaintSelector(taint)
+		if err != nil {
+			return Selector{}, err
+		}
+		selector.Taints = append(selector.Taints, taintSelector)
+	}
+	for _, nodeSelector := range nodeSelectors {
+		parsed, err := labels.Parse(nodeSelector)
+		if err != nil {
+			return Selector{}, fmt.Errorf("invalid node selector %q: %v", nodeSelector, err)
+		}
+		if parsed.Empty() {
+			return Selector{}, fmt.Errorf("invalid node selector %q: selector matches all nodes", nodeSelector)
+		}
+		selector.NodeSelectors = append(selector.NodeSelectors, parsed)
+	}
+	return selector, nil
+}
+
+func parseTaintSelector(taint string) (TaintSelector, error) {
+	key, effect, _ := strings.Cut(taint, ":")
+	if key == "" {
+		return TaintSelector{}, fmt.Errorf("invalid taint %q: empty key", taint)
+	}
+	switch apiv1.TaintEffect(effect) {
+	case "", apiv1.TaintEffectNoSchedule, apiv1.TaintEffectPreferNoSchedule, apiv1.TaintEffectNoExecute:
+	default:
+		return TaintSelector{}, fmt.Errorf("invalid taint %q: unknown effect %q", taint, effect)
+	}
+	return TaintSelector{Key: key, Effect: apiv1.TaintEffect(effect)}, nil
+}
+
+// Empty tells if the selector doesn't select any nodes.
+func (s Selector) Empty() bool {
+	return len(s.Taints) == 0 && len(s.NodeSelectors) == 0
+}
+
+// Matches tells if the node is selected.
+func (s Selector) Matches(node *apiv1.Node) bool {
+	for _, taintSelector := range s.Taints {
+		for _, taint := range node.Spec.Taints {
+			if taintSelector.Matches(taint) {
+				return true
+			}
+		}
+	}
+	nodeLabels := labels.Set(node.Labels)
+	for _, nodeSelector := range s.NodeSelectors {
+		if nodeSelector.Matches(nodeLabels) {
+			return true
+		}
+	}
+	return false
+}
+
+// PreferredCandidates is a scale down candidates comparer putting nodes
+// selected by a Selector first.
+type PreferredCandidates struct {
+	selector Selector
+}
+
+// NewPreferredCandidates returns a new PreferredCandidates.
+func NewPreferredCandidates(selector Selector) *PreferredCandidates {
+	return &PreferredCandidates{
+		selector: selector,
+	}
+}
+
+// ScaleDownEarlierThan return true if node1 is selected and node2 isn't.
+func (p *PreferredCandidates) ScaleDownEarlierThan(node1, node2 *apiv1.Node) bool {
+	return p.selector.Matches(node1) && !p.selector.Matches(node2)
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeselector
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestParse(t *testing.T) {
+	selector, err := Parse([]string{"drain-me-first", "*:PreferNoSchedule"}, []string{"pool=batch"})
+	assert.NoError(t, err)
+	assert.Equal(t, []TaintSelector{{Key: "drain-me-first"}, {Key: AnyTaintKey, Effect: apiv1.TaintEffectPreferNoSchedule}}, selector.Taints)
+	assert.Len(t, selector.NodeSelectors, 1)
+	assert.False(t, selector.Empty())
+
+	selector, err = Parse(nil, nil)
+	assert.NoError(t, err)
+	assert.True(t, selector.Empty())
+
+	for _, taint := range []string{"", ":NoSchedule", "key:Sometimes"} {
+		_, err = Parse([]string{taint}, nil)
+		assert.Error(t, err, "taint %q", taint)
+	}
+	for _, nodeSelector := range []string{"", "pool in"} {
+		_, err = Parse(nil, []string{nodeSelector})
+		assert.Error(t, err, "node selector %q", nodeSelector)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	selector, err := Parse([]string{"never-drain", "*:PreferNoSchedule"}, []string{"pool=batch", "tier notin (critical),tier"})
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		taints []apiv1.Taint
+		labels map[string]string
+		want   bool
+	}{
+		{
+			name: "plain node",
+		},
+		{
+			name:   "taint key matches",
+			taints: []apiv1.Taint{{Key: "never-drain", Effect: apiv1.TaintEffectNoSchedule}},
+			want:   true,
+		},
+		{
+			name:   "taint effect matches",
+			taints: []apiv1.Taint{{Key: "other", Effect: apiv1.TaintEffectPreferNoSchedule}},
+			want:   true,
+		},
+		{
+			name:   "taint doesn't match",
+			taints: []apiv1.Taint{{Key: "other", Effect: apiv1.TaintEffectNoSchedule}},
+		},
+		{
+			name:   "label matches",
+			labels: map[string]string{"pool": "batch"},
+			want:   true,
+		},
+		{
+			name:   "set based selector matches",
+			labels: map[string]string{"tier": "spot"},
+			want:   true,
+		},
+		{
+			name:   "labels don't match",
+			labels: map[string]string{"pool": "default", "tier": "critical"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			node := BuildTestNode("n", 1000, 1000)
+			node.Spec.Taints = tc.taints
+			node.Labels = tc.labels
+			assert.Equal(t, tc.want, selector.Matches(node))
+		})
+	}
+}
+
+func TestPreferredCandidates(t *testing.T) {
+	selector, err := Parse([]string{"drain-me-first"}, nil)
+	assert.NoError(t, err)
+	p := NewPreferredCandidates(selector)
+
+	preferred := BuildTestNode("preferred", 1000, 1000)
+	preferred.Spec.Taints = []apiv1.Taint{{Key: "drain-me-first", Effect: apiv1.TaintEffectNoSchedule}}
+	other := BuildTestNode("other", 1000, 1000)
+
+	assert.True(t, p.ScaleDownEarlierThan(preferred, other))
+	assert.False(t, p.ScaleDownEarlierThan(other, preferred))
+	assert.False(t, p.ScaleDownEarlierThan(other, other))
+	assert.False(t, p.ScaleDownEarlierThan(preferred, preferred))
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scoring
+
+import (
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+)
+
+const (
+	// UtilizationScorerName is the name of the scorer preferring less utilized nodes.
+	UtilizationScorerName = "Utilization"
+	// AgeScorerName is the name of the scorer preferring older nodes.
+	AgeScorerName = "Age"
+	// PriceScorerName is the name of the scorer preferring more expensive nodes.
+	PriceScorerName = "Price"
+	// DeletionCostScorerName is the name of the scorer preferring nodes whose
+	// pods have a lower deletion cost.
+	DeletionCostScorerName = "DeletionCost"
+	// SpreadSkewScorerName is the name of the scorer preferring nodes whose
+	// removal skews topology spread of their pods less.
+	SpreadSkewScorerName = "SpreadSkew"
+	// DrainDurationScorerName is the name of the scorer preferring nodes which
+	// are expected to drain faster.
+	DrainDurationScorerName = "DrainDuration"
+)
+
+// UtilizationScorer scores less utilized nodes higher, so that they are
+// scaled down first.
+type UtilizationScorer struct{}
+
+// NewUtilizationScorer creates a new UtilizationScorer.
+func NewUtilizationScorer() *UtilizationScorer {
+	return &UtilizationScorer{}
+}
+
+// Name returns the name of the scorer.
+func (s *UtilizationScorer) Name() string {
+	return UtilizationScorerName
+}
+
+// Score returns the negated utilization of the node.
+func (s *UtilizationScorer) Score(_ *context.AutoscalingContext, candidate Candidate, _ time.Time) (float64, error) {
+	return -candidate.Utilization.Utilization, nil
+}
+
+// AgeScorer scores older nodes higher, so that they are scaled down first.
+type AgeScorer struct{}
+
+// NewAgeScorer creates a new AgeScorer.
+func NewAgeScorer() *AgeScorer {
+	return &AgeScorer{}
+}
+
+// Name returns the name of the scorer.
+func (s *AgeScorer) Name() string {
+	return AgeScorerName
+}
+
+// Score returns the age of the node in seconds.
+func (s *AgeScorer) Score(_ *context.AutoscalingContext, candidate Candidate, timestamp time.Time) (float64, error) {
+	return timestamp.Sub(candidate.Node.CreationTimestamp.Time).Seconds(), nil
+}
+
+// PriceScorer scores nodes with a higher hourly price, according to the cloud
+// provider pricing model, higher, so that they are scaled down first.
+type PriceScorer struct{}
+
+// NewPriceScorer creates a new PriceScorer.
+func NewPriceScorer() *PriceScorer {
+	return &PriceScorer{}
+}
+
+// Name returns the name of the scorer.
+func (s *PriceScorer) Name() string {
+	return PriceScorerName
+}
+
+// Score returns the price of the node for the hour following the timestamp.
+func (s *PriceScorer) Score(ctx *context.AutoscalingContext, candidate Candidate, timestamp time.Time) (float64, error) {
+	pricing, err := ctx.CloudProvider.Pricing()
+	if err != nil {
+		return 0, err
+	}
+	return pricing.NodePrice(candidate.Node, timestamp, timestamp.Add(time.Hour))
+}
+
+// DeletionCostScorer scores nodes whose pods have a lower aggregate
+// pod-deletion-cost higher, so that they are scaled down first.
+type DeletionCostScorer struct{}
+
+// NewDeletionCostScorer creates a new DeletionCostScorer.
+func NewDeletionCostScorer() *DeletionCostScorer {
+	return &DeletionCostScorer{}
+}
+
+// Name returns the name of the scorer.
+func (s *DeletionCostScorer) Name() string {
+	return DeletionCostScorerName
+}
+
+// Score returns the negated deletion cost of the node.
+func (s *DeletionCostScorer) Score(_ *context.AutoscalingContext, candidate Candidate, _ time.Time) (float64, error) {
+	return -float64(candidate.DeletionCost), nil
+}
+
+// SpreadSkewScorer scores nodes whose removal increases the topology spread
+// skew of their pods less higher, so that they are scaled down first.
+type SpreadSkewScorer struct{}
+
+// NewSpreadSkewScorer creates a new SpreadSkewScorer.
+func NewSpreadSkewScorer() *SpreadSkewScorer {
+	return &SpreadSkewScorer{}
+}
+
+// Name returns the name of the scorer.
+func (s *SpreadSkewScorer) Name() string {
+	return SpreadSkewScorerName
+}
+
+// Score returns the negated spread skew increase of the node.
+func (s *SpreadSkewScorer) Score(_ *context.AutoscalingContext, candidate Candidate, _ time.Time) (float64, error) {
+	return -float64(candidate.SpreadSkewIncrease), nil
+}
+
+// DrainDurationScorer scores nodes which are expected to drain faster higher,
+// so that they are scaled down first.
+type DrainDurationScorer struct{}
+
+// NewDrainDurationScorer creates a new DrainDurationScorer.
+func NewDrainDurationScorer() *DrainDurationScorer {
+	return &DrainDurationScorer{}
+}
+
+// Name returns the name of the scorer.
+func (s *DrainDurationScorer) Name() string {
+	return DrainDurationScorerName
+}
+
+// Score returns the negated estimated drain duration of the node in seconds.
+func (s *DrainDurationScorer) Score(_ *context.AutoscalingContext, candidate Candidate, _ time.Time) (float64, error) {
+	return -candidate.EstimatedDrainDuration.Seconds(), nil
+}
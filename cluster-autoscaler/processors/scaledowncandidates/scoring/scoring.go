@@ -0,0 +1,250 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scoring
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+	klog "k8s.io/klog/v2"
+)
+
+// MaxNodeScore is the maximum score a Scorer contributes to the total score
+// of a node, before it is multiplied by the weight of the Scorer.
+const MaxNodeScore = 100
+
+// Candidate is a removable node being scored.
+type Candidate struct {
+	simulator.NodeToBeRemoved
+	// Utilization is the utilization of the node, as computed by scale down.
+	// It is zero if unknown.
+	Utilization utilization.Info
+}
+
+// Scorer scores removable nodes, similarly to scheduler score plugins. Raw
+// scores returned by a Scorer are normalized to [0, MaxNodeScore] across all
+// candidates, so they can be of any scale. Nodes with higher raw scores are
+// scaled down earlier.
+type Scorer interface {
+	// Name returns the name of the scorer.
+	Name() string
+	// Score returns the raw score of the candidate. Candidates which can't
+	// be scored get the lowest score.
+	Score(ctx *context.AutoscalingContext, candidate Candidate, timestamp time.Time) (float64, error)
+}
+
+// WeightedScorer is a Scorer whose normalized scores are multiplied by Weight.
+type WeightedScorer struct {
+	Scorer Scorer
+	Weight int
+}
+
+// Factory creates a Scorer.
+type Factory func() Scorer
+
+var (
+	registryMutex sync.Mutex
+	registry      = map[string]Factory{
+		UtilizationScorerName:   func() Scorer { return NewUtilizationScorer() },
+		AgeScorerName:           func() Scorer { return NewAgeScorer() },
+		PriceScorerName:         func() Scorer { return NewPriceScorer() },
+		DeletionCostScorerName:  func() Scorer { return NewDeletionCostScorer() },
+		SpreadSkewScorerName:    func() Scorer { return NewSpreadSkewScorer() },
+		DrainDurationScorerName: func() Scorer { return NewDrainDurationScorer() },
+	}
+)
+
+// DefaultScorers returns the scorers used unless others are specified with
+// the --scale-down-scorer flag.
+func DefaultScorers() []WeightedScorer {
+	return []WeightedScorer{
+		{Scorer: NewUtilizationScorer(), Weight: 1},
+		{Scorer: NewAgeScorer(), Weight: 1},
+		{Scorer: NewDeletionCostScorer(), Weight: 1},
+		{Scorer: NewSpreadSkewScorer(), Weight: 1},
+		{Scorer: NewDrainDurationScorer(), Weight: 1},
+	}
+}
+
+// Register makes an out-of-tree Scorer available by name, e.g. to be enabled
+// with the --scale-down-scorer flag. It has to be called before scorers are
+// parsed, and fails if the name is already taken.
+func Register(name string, factory Factory) error {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, found := registry[name]; found {
+		return fmt.Errorf("scale down scorer %q is already registered", name)
+	}
+	registry[name] = factory
+	return nil
+}
+
+// ParseScorers creates the scorers specified as <name>[:<weight>]. The weight
+// defaults to 1.
+func ParseScorers(specs []string) ([]WeightedScorer, error) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	var scorers []WeightedScorer
+	for _, spec := range specs {
+		name, weightStr, hasWeight := strings.Cut(spec, ":")
+		factory, found := registry[name]
+		if !found {
+			return nil, fmt.Errorf("unknown scale down scorer %q in %q, expected one of: %s", name, spec, strings.Join(registeredNames(), ", "))
+		}
+		weight := 1
+		if hasWeight {
+			var err error
+			weight, err = strconv.Atoi(weightStr)
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("invalid weight of scale down scorer in %q, expected a positive integer", spec)
+			}
+		}
+		scorers = append(scorers, WeightedScorer{Scorer: factory(), Weight: weight})
+	}
+	return scorers, nil
+}
+
+func registeredNames() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Framework orders removable nodes by the weighted sum of their normalized
+// scores. A nil Framework, or one without scorers, leaves nodes in place.
+type Framework struct {
+	scorers []WeightedScorer
+}
+
+// NewFramework creates a new Framework.
+func NewFramework(scorers []WeightedScorer) *Framework {
+	return &Framework{scorers: scorers}
+}
+
+// With returns a new Framework with the scorers of f followed by scorers.
+func (f *Framework) With(scorers ...WeightedScorer) *Framework {
+	var all []WeightedScorer
+	if f != nil {
+		all = append(all, f.scorers...)
+	}
+	return NewFramework(append(all, scorers...))
+}
+
+// Has tells if the Framework has a scorer with the given name.
+func (f *Framework) Has(name string) bool {
+	if f == nil {
+		return false
+	}
+	for _, ws := range f.scorers {
+		if ws.Scorer.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TotalWeight returns the sum of weights of the scorers of the Framework.
+func (f *Framework) TotalWeight() int {
+	if f == nil {
+		return 0
+	}
+	total := 0
+	for _, ws := range f.scorers {
+		total += ws.Weight
+	}
+	return total
+}
+
+// Enabled tells if the Framework has any scorers.
+func (f *Framework) Enabled() bool {
+	return f != nil && len(f.scorers) > 0
+}
+
+// Sort sorts nodes by decreasing total score, so that the nodes which should
+// be scaled down first come first. Nodes with equal total scores keep their
+// relative order.
+func (f *Framework) Sort(ctx *context.AutoscalingContext, nodes []simulator.NodeToBeRemoved, utilizations map[string]utilization.Info, timestamp time.Time) {
+	if !f.Enabled() || len(nodes) < 2 {
+		return
+	}
+	totals := f.Scores(ctx, nodes, utilizations, timestamp)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return totals[nodes[i].Node.Name] > totals[nodes[j].Node.Name]
+	})
+}
+
+// Scores returns the total scores of the nodes by node name.
+func (f *Framework) Scores(ctx *context.AutoscalingContext, nodes []simulator.NodeToBeRemoved, utilizations map[string]utilization.Info, timestamp time.Time) map[string]float64 {
+	totals := make(map[string]float64, len(nodes))
+	if !f.Enabled() {
+		return totals
+	}
+	raw := make([]float64, len(nodes))
+	scored := make([]bool, len(nodes))
+	for _, ws := range f.scorers {
+		for i, node := range nodes {
+			score, err := ws.Scorer.Score(ctx, Candidate{NodeToBeRemoved: node, Utilization: utilizations[node.Node.Name]}, timestamp)
+			if err != nil {
+				klog.V(4).Infof("Scale down scorer %s can't score node %s: %v", ws.Scorer.Name(), node.Node.Name, err)
+			}
+			raw[i], scored[i] = score, err == nil
+		}
+		for i, score := range normalize(raw, scored) {
+			totals[nodes[i].Node.Name] += score * float64(ws.Weight)
+		}
+	}
+	return totals
+}
+
+// normalize scales the scores linearly to [0, MaxNodeScore]. Scores which
+// weren't computed map to 0, and so do all scores if they are equal.
+func normalize(raw []float64, scored []bool) []float64 {
+	normalized := make([]float64, len(raw))
+	first := true
+	var lowest, highest float64
+	for i, score := range raw {
+		if !scored[i] {
+			continue
+		}
+		if first || score < lowest {
+			lowest = score
+		}
+		if first || score > highest {
+			highest = score
+		}
+		first = false
+	}
+	if first || highest == lowest {
+		return normalized
+	}
+	for i, score := range raw {
+		if scored[i] {
+			normalized[i] = (score - lowest) / (highest - lowest) * MaxNodeScore
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scoring
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestFrameworkSort(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	utilizations := map[string]utilization.Info{
+		"n1": {Utilization: 0.5},
+		"n2": {Utilization: 0.1},
+		"n3": {Utilization: 0.3},
+	}
+	ages := map[string]time.Duration{
+		"n1": 3 * time.Hour,
+		"n2": time.Hour,
+		"n3": 2 * time.Hour,
+	}
+	for desc, tc := range map[string]struct {
+		scorers   []WeightedScorer
+		wantOrder []string
+	}{
+		"no scorers": {
+			wantOrder: []string{"n1", "n2", "n3"},
+		},
+		"utilization": {
+			scorers:   []WeightedScorer{{Scorer: NewUtilizationScorer(), Weight: 1}},
+			wantOrder: []string{"n2", "n3", "n1"},
+		},
+		"age": {
+			scorers:   []WeightedScorer{{Scorer: NewAgeScorer(), Weight: 1}},
+			wantOrder: []string{"n1", "n3", "n2"},
+		},
+		"utilization outweighs age": {
+			scorers:   []WeightedScorer{{Scorer: NewUtilizationScorer(), Weight: 2}, {Scorer: NewAgeScorer(), Weight: 1}},
+			wantOrder: []string{"n2", "n3", "n1"},
+		},
+		"age outweighs utilization": {
+			scorers:   []WeightedScorer{{Scorer: NewUtilizationScorer(), Weight: 1}, {Scorer: NewAgeScorer(), Weight: 2}},
+			wantOrder: []string{"n1", "n3", "n2"},
+		},
+		"equal scores keep order": {
+			scorers:   []WeightedScorer{{Scorer: fakeScorer{}, Weight: 1}},
+			wantOrder: []string{"n1", "n2", "n3"},
+		},
+		"nodes which can't be scored come last": {
+			scorers:   []WeightedScorer{{Scorer: fakeScorer{scores: map[string]float64{"n3": -5, "n2": -10}}, Weight: 1}},
+			wantOrder: []string{"n3", "n1", "n2"},
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			var nodes []simulator.NodeToBeRemoved
+			for _, name := range []string{"n1", "n2", "n3"} {
+				node := BuildTestNode(name, 1000, 1000)
+				node.CreationTimestamp = metav1.NewTime(now.Add(-ages[name]))
+				nodes = append(nodes, simulator.NodeToBeRemoved{Node: node})
+			}
+			NewFramework(tc.scorers).Sort(&context.AutoscalingContext{}, nodes, utilizations, now)
+			var gotOrder []string
+			for _, node := range nodes {
+				gotOrder = append(gotOrder, node.Node.Name)
+			}
+			assert.Equal(t, tc.wantOrder, gotOrder)
+		})
+	}
+}
+
+func TestFrameworkScores(t *testing.T) {
+	scorers := []WeightedScorer{
+		{Scorer: fakeScorer{scores: map[string]float64{"n1": 10, "n2": 20, "n3": 30}}, Weight: 1},
+		{Scorer: fakeScorer{scores: map[string]float64{"n1": 1, "n2": 0, "n3": 0}}, Weight: 3},
+	}
+	var nodes []simulator.NodeToBeRemoved
+	for _, name := range []string{"n1", "n2", "n3"} {
+		nodes = append(nodes, simulator.NodeToBeRemoved{Node: BuildTestNode(name, 1000, 1000)})
+	}
+	got := NewFramework(scorers).Scores(&context.AutoscalingContext{}, nodes, nil, time.Now())
+	assert.Equal(t, map[string]float64{"n1": 300, "n2": 50, "n3": 100}, got)
+
+	var framework *Framework
+	assert.False(t, framework.Enabled())
+	framework.Sort(&context.AutoscalingContext{}, nodes, nil, time.Now())
+}
+
+func TestRemovalCostScorers(t *testing.T) {
+	cheap := simulator.NodeToBeRemoved{Node: BuildTestNode("cheap", 1000, 1000), DeletionCost: -10, SpreadSkewIncrease: 3, EstimatedDrainDuration: time.Minute}
+	free := simulator.NodeToBeRemoved{Node: BuildTestNode("free", 1000, 1000), SpreadSkewIncrease: 1, EstimatedDrainDuration: 10 * time.Minute}
+	expensive := simulator.NodeToBeRemoved{Node: BuildTestNode("expensive", 1000, 1000), DeletionCost: 100, EstimatedDrainDuration: 5 * time.Minute}
+	for _, tc := range []struct {
+		scorer    Scorer
+		wantOrder []string
+	}{
+		{scorer: NewDeletionCostScorer(), wantOrder: []string{"cheap", "free", "expensive"}},
+		{scorer: NewSpreadSkewScorer(), wantOrder: []string{"expensive", "free", "cheap"}},
+		{scorer: NewDrainDurationScorer(), wantOrder: []string{"cheap", "expensive", "free"}},
+	} {
+		t.Run(tc.scorer.Name(), func(t *testing.T) {
+			nodes := []simulator.NodeToBeRemoved{expensive, free, cheap}
+			NewFramework([]WeightedScorer{{Scorer: tc.scorer, Weight: 1}}).Sort(&context.AutoscalingContext{}, nodes, nil, time.Now())
+			var gotOrder []string
+			for _, node := range nodes {
+				gotOrder = append(gotOrder, node.Node.Name)
+			}
+			assert.Equal(t, tc.wantOrder, gotOrder)
+		})
+	}
+}
+
+func TestDefaultScorers(t *testing.T) {
+	framework := NewFramework(DefaultScorers())
+	for _, name := range []string{UtilizationScorerName, AgeScorerName, DeletionCostScorerName, SpreadSkewScorerName, DrainDurationScorerName} {
+		assert.True(t, framework.Has(name), "scorer: %s", name)
+	}
+	assert.False(t, framework.Has(PriceScorerName))
+	assert.Equal(t, 5, framework.TotalWeight())
+}
+
+func TestFrameworkWith(t *testing.T) {
+	var framework *Framework
+	assert.Equal(t, 0, framework.TotalWeight())
+	withAge := framework.With(WeightedScorer{Scorer: NewAgeScorer(), Weight: 2})
+	assert.True(t, withAge.Has(AgeScorerName))
+	assert.Equal(t, 2, withAge.TotalWeight())
+
+	withPrice := withAge.With(WeightedScorer{Scorer: NewPriceScorer(), Weight: 3})
+	assert.True(t, withPrice.Has(PriceScorerName))
+	assert.Equal(t, 5, withPrice.TotalWeight())
+	assert.False(t, withAge.Has(PriceScorerName))
+}
+
+func TestPriceScorer(t *testing.T) {
+	provider := testprovider.NewTestCloudProvider(nil, nil)
+	ctx := &context.AutoscalingContext{CloudProvider: provider}
+	candidate := Candidate{NodeToBeRemoved: simulator.NodeToBeRemoved{Node: BuildTestNode("n1", 1000, 1000)}}
+
+	_, err := NewPriceScorer().Score(ctx, candidate, time.Now())
+	assert.Error(t, err)
+
+	provider.SetPricingModel(&fakePricingModel{prices: map[string]float64{"n1": 0.3}})
+	price, err := NewPriceScorer().Score(ctx, candidate, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0.3, price)
+}
+
+func TestParseScorers(t *testing.T) {
+	scorers, err := ParseScorers([]string{"Utilization", "Age:3", "Price:2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []WeightedScorer{
+		{Scorer: NewUtilizationScorer(), Weight: 1},
+		{Scorer: NewAgeScorer(), Weight: 3},
+		{Scorer: NewPriceScorer(), Weight: 2},
+	}, scorers)
+
+	for _, specs := range [][]string{{"Unknown"}, {"Age:"}, {"Age:0"}, {"Age:-1"}, {"Age:high"}} {
+		_, err := ParseScorers(specs)
+		assert.Error(t, err, "specs: %v", specs)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	assert.Error(t, Register(AgeScorerName, func() Scorer { return fakeScorer{} }))
+
+	assert.NoError(t, Register("TestCustom", func() Scorer { return fakeScorer{} }))
+	defer func() {
+		registryMutex.Lock()
+		defer registryMutex.Unlock()
+		delete(registry, "TestCustom")
+	}()
+	scorers, err := ParseScorers([]string{"TestCustom:5"})
+	assert.NoError(t, err)
+	assert.Equal(t, []WeightedScorer{{Scorer: fakeScorer{}, Weight: 5}}, scorers)
+}
+
+type fakeScorer struct {
+	scores map[string]float64
+}
+
+func (s fakeScorer) Name() string {
+	return "Fake"
+}
+
+func (s fakeScorer) Score(_ *context.AutoscalingContext, candidate Candidate, _ time.Time) (float64, error) {
+	if s.scores == nil {
+		return 0, nil
+	}
+	if score, found := s.scores[candidate.Node.Name]; found {
+		return score, nil
+	}
+	return 0, fmt.Errorf("no score for node %s", candidate.Node.Name)
+}
+
+type fakePricingModel struct {
+	prices map[string]float64
+}
+
+func (f *fakePricingModel) NodePrice(node *apiv1.Node, startTime time.Time, endTime time.Time) (float64, error) {
+	if price, found := f.prices[node.Name]; found {
+		return price, nil
+	}
+	return 0, fmt.Errorf("price for node %s not found", node.Name)
+}
+
+func (f *fakePricingModel) PodPrice(pod *apiv1.Pod, startTime time.Time, endTime time.Time) (float64, error) {
+	return 0, nil
+}
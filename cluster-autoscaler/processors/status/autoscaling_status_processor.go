@@ -45,3 +45,33 @@ func (p *NoOpAutoscalingStatusProcessor) Process(context *context.AutoscalingCon
 // CleanUp cleans up the processor's internal structures.
 func (p *NoOpAutoscalingStatusProcessor) CleanUp() {
 }
+
+// CombinedAutoscalingStatusProcessor is a list of AutoscalingStatusProcessors
+// executed in order.
+type CombinedAutoscalingStatusProcessor struct {
+	processors []AutoscalingStatusProcessor
+}
+
+// NewCombinedAutoscalingStatusProcessor returns a new CombinedAutoscalingStatusProcessor.
+func NewCombinedAutoscalingStatusProcessor(processors []AutoscalingStatusProcessor) *CombinedAutoscalingStatusProcessor {
+	return &CombinedAutoscalingStatusProcessor{processors: processors}
+}
+
+// Process processes the status of the cluster after an autoscaling iteration with each of the processors.
+// The first error is returned once all the processors ran.
+func (p *CombinedAutoscalingStatusProcessor) Process(context *context.AutoscalingContext, csr *clusterstate.ClusterStateRegistry, now time.Time) error {
+	var firstErr error
+	for _, processor := range p.processors {
+		if err := processor.Process(context, csr, now); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// CleanUp cleans up internal structures of each of the processors.
+func (p *CombinedAutoscalingStatusProcessor) CleanUp() {
+	for _, processor := range p.processors {
+		processor.CleanUp()
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	ctx "context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+)
+
+const (
+	// ScaleDownBlockedByAnnotationKey is the node annotation summarizing which pods prevent the node from being
+	// scaled down and why.
+	ScaleDownBlockedByAnnotationKey = "cluster-autoscaler.kubernetes.io/scale-down-blocked-by"
+)
+
+// EventingScaleDownStatusProcessor processes the state of the cluster after
+// a scale-down by emitting events for nodes blocked by pods, as well as for
+// the blocking pods, and by annotating the blocked nodes.
+type EventingScaleDownStatusProcessor struct {
+	// blockedNodes maps names of annotated nodes to the annotation value.
+	blockedNodes map[string]string
+}
+
+// NewEventingScaleDownStatusProcessor returns a new EventingScaleDownStatusProcessor.
+func NewEventingScaleDownStatusProcessor() *EventingScaleDownStatusProcessor {
+	return &EventingScaleDownStatusProcessor{
+		blockedNodes: make(map[string]string),
+	}
+}
+
+// Process processes the state of the cluster after a scale-down. Events are
+// only emitted when the pods blocking the node, or their reasons, change.
+func (p *EventingScaleDownStatusProcessor) Process(context *context.AutoscalingContext, status *status.ScaleDownStatus) {
+	if status.UnremovableNodes == nil {
+		// Unremovable nodes weren't computed in this loop.
+		return
+	}
+
+	stillBlocked := make(map[string]bool)
+	for _, unremovableNode := range status.UnremovableNodes {
+		node := unremovableNode.Node
+		if unremovableNode.Reason == simulator.RecentlyUnremovable {
+			// The node wasn't re-checked, so the last known blocking pod is still valid.
+			stillBlocked[node.Name] = true
+			continue
+		}
+		if unremovableNode.Reason != simulator.BlockedByPod || unremovableNode.BlockingPod == nil {
+			continue
+		}
+		stillBlocked[node.Name] = true
+
+		blockingPods := unremovableNode.BlockingPods
+		if len(blockingPods) == 0 {
+			blockingPods = []*drain.BlockingPod{unremovableNode.BlockingPod}
+		}
+		podSummaries := make([]string, 0, len(blockingPods))
+		for _, blockingPod := range blockingPods {
+			podSummaries = append(podSummaries, fmt.Sprintf("%s/%s: %v", blockingPod.Pod.Namespace, blockingPod.Pod.Name, blockingPod.ReasonID()))
+		}
+		summary := strings.Join(podSummaries, ", ")
+		if p.blockedNodes[node.Name] == summary {
+			continue
+		}
+		if len(blockingPods) == 1 {
+			pod := blockingPods[0].Pod
+			context.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDownBlocked",
+				"node cannot be removed: pod %s/%s is blocking scale down: %v%s", pod.Namespace, pod.Name, blockingPods[0].ReasonID(), blockingHint(blockingPods[0]))
+		} else {
+			context.Recorder.Eventf(node, apiv1.EventTypeNormal, "ScaleDownBlocked",
+				"node cannot be removed: %d pods are blocking scale down: %s", len(blockingPods), summary)
+		}
+		for _, blockingPod := range blockingPods {
+			context.Recorder.Eventf(blockingPod.Pod, apiv1.EventTypeNormal, "BlockingScaleDown",
+				"pod is blocking scale down of node %s: %v%s", node.Name, blockingPod.ReasonID(), blockingHint(blockingPod))
+		}
+		if err := setBlockedByAnnotation(context, node.Name, &summary); err != nil {
+			klog.Warningf("Failed to annotate node %s as blocked by pods %s: %v", node.Name, summary, err)
+			continue
+		}
+		p.blockedNodes[node.Name] = summary
+	}
+
+	for nodeName := range p.blockedNodes {
+		if stillBlocked[nodeName] {
+			continue
+		}
+		if err := setBlockedByAnnotation(context, nodeName, nil); err != nil {
+			klog.Warningf("Failed to remove %s annotation from node %s: %v", ScaleDownBlockedByAnnotationKey, nodeName, err)
+			continue
+		}
+		delete(p.blockedNodes, nodeName)
+	}
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *EventingScaleDownStatusProcessor) CleanUp() {
+}
+
+// setBlockedByAnnotation sets the annotation to the given value, or removes it if value is nil.
+func setBlockedByAnnotation(context *context.AutoscalingContext, nodeName string, value *string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{
+				ScaleDownBlockedByAnnotationKey: value,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = context.ClientSet.CoreV1().Nodes().Patch(ctx.TODO(), nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// blockingHint describes the rule which blocked the pod and how to unblock it,
+// if they are known.
+func blockingHint(blockingPod *drain.BlockingPod) string {
+	var parts []string
+	if blockingPod.Rule != "" {
+		parts = append(parts, "rule "+blockingPod.Rule)
+	}
+	if blockingPod.Remediation != "" {
+		parts = append(parts, "remediation: "+string(blockingPod.Remediation))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
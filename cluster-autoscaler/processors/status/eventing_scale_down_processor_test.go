@@ -0,0 +1,134 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	ctx "context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	kube_record "k8s.io/client-go/tools/record"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventingScaleDownStatusProcessor(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	pod := BuildTestPod("p1", 100, 100)
+	pod.Namespace = "ns"
+
+	fakeClient := fake.NewSimpleClientset(n1, n2)
+	fakeRecorder := kube_record.NewFakeRecorder(10)
+	autoscalingContext := &context.AutoscalingContext{
+		AutoscalingKubeClients: context.AutoscalingKubeClients{
+			ClientSet: fakeClient,
+			Recorder:  fakeRecorder,
+		},
+	}
+	blocked := func(reason drain.BlockingPodReason) *status.ScaleDownStatus {
+		return &status.ScaleDownStatus{
+			UnremovableNodes: []*status.UnremovableNode{
+				{Node: n1, Reason: simulator.BlockedByPod, BlockingPod: &drain.BlockingPod{Pod: pod, Reason: reason}},
+				{Node: n2, Reason: simulator.NotUnderutilized},
+			},
+		}
+	}
+	assertAnnotation := func(expected string) {
+		node, err := fakeClient.CoreV1().Nodes().Get(ctx.TODO(), "n1", metav1.GetOptions{})
+		assert.NoError(t, err)
+		value, found := node.Annotations[ScaleDownBlockedByAnnotationKey]
+		if expected == "" {
+			assert.False(t, found)
+		} else {
+			assert.Equal(t, expected, value)
+		}
+	}
+
+	p := NewEventingScaleDownStatusProcessor()
+
+	p.Process(autoscalingContext, blocked(drain.NotEnoughPdb))
+	assert.Equal(t, 2, len(fakeRecorder.Events))
+	<-fakeRecorder.Events
+	<-fakeRecorder.Events
+	assertAnnotation("ns/p1: NotEnoughPdb")
+
+	// Unchanged blocking pod doesn't emit events again.
+	p.Process(autoscalingContext, blocked(drain.NotEnoughPdb))
+	assert.Equal(t, 0, len(fakeRecorder.Events))
+
+	// Recently unremovable nodes keep their annotation.
+	p.Process(autoscalingContext, &status.ScaleDownStatus{
+		UnremovableNodes: []*status.UnremovableNode{{Node: n1, Reason: simulator.RecentlyUnremovable}},
+	})
+	assert.Equal(t, 0, len(fakeRecorder.Events))
+	assertAnnotation("ns/p1: NotEnoughPdb")
+
+	// Skipped unremovable nodes computation doesn't touch the annotation.
+	p.Process(autoscalingContext, &status.ScaleDownStatus{})
+	assertAnnotation("ns/p1: NotEnoughPdb")
+
+	// Changed reason is reported again.
+	p.Process(autoscalingContext, blocked(drain.NotReplicated))
+	assert.Equal(t, 2, len(fakeRecorder.Events))
+	<-fakeRecorder.Events
+	<-fakeRecorder.Events
+	assertAnnotation("ns/p1: NotReplicated")
+
+	// All blocking pods are reported, with a single event on the node.
+	pod2 := BuildTestPod("p2", 100, 100)
+	pod2.Namespace = "ns"
+	p.Process(autoscalingContext, &status.ScaleDownStatus{
+		UnremovableNodes: []*status.UnremovableNode{{
+			Node:         n1,
+			Reason:       simulator.BlockedByPod,
+			BlockingPod:  &drain.BlockingPod{Pod: pod, Reason: drain.NotReplicated},
+			BlockingPods: []*drain.BlockingPod{{Pod: pod, Reason: drain.NotReplicated}, {Pod: pod2, Reason: drain.LocalStorageRequested}},
+		}},
+	})
+	assert.Equal(t, 3, len(fakeRecorder.Events))
+	assert.Contains(t, <-fakeRecorder.Events, "2 pods are blocking scale down: ns/p1: NotReplicated, ns/p2: LocalStorageRequested")
+	<-fakeRecorder.Events
+	assert.Contains(t, <-fakeRecorder.Events, "LocalStorageRequested")
+	assertAnnotation("ns/p1: NotReplicated, ns/p2: LocalStorageRequested")
+
+	// The blocking rule and remediation are reported in events, but not in the annotation.
+	p.Process(autoscalingContext, &status.ScaleDownStatus{
+		UnremovableNodes: []*status.UnremovableNode{{
+			Node:        n1,
+			Reason:      simulator.BlockedByPod,
+			BlockingPod: &drain.BlockingPod{Pod: pod, Reason: drain.NotEnoughPdb, Rule: "PDB", Remediation: drain.IncreasePdbMaxUnavailable},
+		}},
+	})
+	assert.Equal(t, 2, len(fakeRecorder.Events))
+	assert.Contains(t, <-fakeRecorder.Events, "pod ns/p1 is blocking scale down: NotEnoughPdb (rule PDB, remediation: IncreasePdbMaxUnavailable)")
+	assert.Contains(t, <-fakeRecorder.Events, "NotEnoughPdb (rule PDB, remediation: IncreasePdbMaxUnavailable)")
+	assertAnnotation("ns/p1: NotEnoughPdb")
+
+	// Annotation is removed once the node isn't blocked anymore.
+	p.Process(autoscalingContext, &status.ScaleDownStatus{
+		UnremovableNodes: []*status.UnremovableNode{{Node: n1, Reason: simulator.NotUnderutilized}},
+	})
+	assertAnnotation("")
+}
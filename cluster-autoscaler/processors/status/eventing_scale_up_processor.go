@@ -26,6 +26,7 @@ import (
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
 	"k8s.io/autoscaler/cluster-autoscaler/context"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 )
 
 // EventingScaleUpStatusProcessor processes the state of the cluster after
@@ -39,6 +40,9 @@ func (p *EventingScaleUpStatusProcessor) Process(context *context.AutoscalingCon
 	consideredNodeGroupsMap := nodeGroupListToMapById(status.ConsideredNodeGroups)
 	if status.Result != ScaleUpSuccessful && status.Result != ScaleUpError {
 		for _, noScaleUpInfo := range status.PodsRemainUnschedulable {
+			if pod_util.IsHeadroomPlaceholder(noScaleUpInfo.Pod) {
+				continue
+			}
 			context.Recorder.Event(noScaleUpInfo.Pod, apiv1.EventTypeNormal, "NotTriggerScaleUp",
 				fmt.Sprintf("pod didn't trigger scale-up: %s",
 					ReasonsMessage(noScaleUpInfo, consideredNodeGroupsMap)))
@@ -49,6 +53,9 @@ func (p *EventingScaleUpStatusProcessor) Process(context *context.AutoscalingCon
 	}
 	if len(status.ScaleUpInfos) > 0 {
 		for _, pod := range status.PodsTriggeredScaleUp {
+			if pod_util.IsHeadroomPlaceholder(pod) {
+				continue
+			}
 			context.Recorder.Eventf(pod, apiv1.EventTypeNormal, "TriggeredScaleUp",
 				"pod triggered scale-up: %v", status.ScaleUpInfos)
 		}
@@ -0,0 +1,194 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	ctx "context"
+	"reflect"
+	"sort"
+	"time"
+
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+)
+
+const (
+	// ScaleDownCandidatesKind is the kind of the custom resource listing scale down candidates.
+	ScaleDownCandidatesKind = "ScaleDownCandidates"
+	// ScaleDownCandidatesName is the name of the ScaleDownCandidates object written by Cluster Autoscaler.
+	ScaleDownCandidatesName = "cluster-autoscaler"
+)
+
+// ScaleDownCandidatesResource is the resource of the ScaleDownCandidates custom resource definition.
+var ScaleDownCandidatesResource = schema.GroupVersionResource{Group: "autoscaling.x-k8s.io", Version: "v1alpha1", Resource: "scaledowncandidates"}
+
+// ScaleDownCandidatesProcessor processes the state of the cluster after a
+// scale-down by writing unneeded and unremovable nodes, along with their
+// utilization and the reasons why they can't be removed, to a
+// ScaleDownCandidates custom resource in the Cluster Autoscaler namespace.
+type ScaleDownCandidatesProcessor struct {
+	client dynamic.Interface
+}
+
+// NewScaleDownCandidatesProcessor returns a new ScaleDownCandidatesProcessor.
+func NewScaleDownCandidatesProcessor(client dynamic.Interface) *ScaleDownCandidatesProcessor {
+	return &ScaleDownCandidatesProcessor{client: client}
+}
+
+// Process processes the state of the cluster after a scale-down.
+func (p *ScaleDownCandidatesProcessor) Process(context *context.AutoscalingContext, status *status.ScaleDownStatus) {
+	if status.UnremovableNodes == nil {
+		// Unremovable nodes weren't computed in this loop.
+		return
+	}
+	if err := p.write(context.ConfigNamespace, scaleDownCandidatesStatus(status, time.Now())); err != nil {
+		klog.Warningf("Failed to write %s %s/%s: %v", ScaleDownCandidatesKind, context.ConfigNamespace, ScaleDownCandidatesName, err)
+	}
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *ScaleDownCandidatesProcessor) CleanUp() {
+}
+
+func (p *ScaleDownCandidatesProcessor) write(namespace string, candidatesStatus map[string]interface{}) error {
+	client := p.client.Resource(ScaleDownCandidatesResource).Namespace(namespace)
+	obj, err := client.Get(ctx.TODO(), ScaleDownCandidatesName, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		obj = &unstructured.Unstructured{}
+		obj.SetAPIVersion(ScaleDownCandidatesResource.GroupVersion().String())
+		obj.SetKind(ScaleDownCandidatesKind)
+		obj.SetNamespace(namespace)
+		obj.SetName(ScaleDownCandidatesName)
+		obj.Object["status"] = candidatesStatus
+		_, err = client.Create(ctx.TODO(), obj, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	obj.Object["status"] = candidatesStatus
+	_, err = client.Update(ctx.TODO(), obj, metav1.UpdateOptions{})
+	return err
+}
+
+// scaleDownCandidatesStatus returns the status of the ScaleDownCandidates
+// object, listing unneeded nodes first and then unremovable nodes, each sorted
+// by name.
+func scaleDownCandidatesStatus(status *status.ScaleDownStatus, now time.Time) map[string]interface{} {
+	unneeded := make([]interface{}, 0, len(status.UnneededNodes))
+	for _, node := range sortedUnneededNodes(status.UnneededNodes) {
+		unneeded = append(unneeded, nodeStatus(node.Node.Name, node.NodeGroup, node.UtilInfo))
+	}
+	unremovable := make([]interface{}, 0, len(status.UnremovableNodes))
+	for _, node := range sortedUnremovableNodes(status.UnremovableNodes) {
+		entry := nodeStatus(node.Node.Name, node.NodeGroup, node.UtilInfo)
+		entry["reason"] = node.Reason.String()
+		if !node.UnremovableSince.IsZero() {
+			entry["unremovableSince"] = node.UnremovableSince.UTC().Format(time.RFC3339)
+		}
+		if len(node.ReasonHistory) > 0 {
+			reasonHistory := make([]interface{}, 0, len(node.ReasonHistory))
+			for _, change := range node.ReasonHistory {
+				reasonHistory = append(reasonHistory, map[string]interface{}{
+					"reason": change.Reason.String(),
+					"since":  change.Since.UTC().Format(time.RFC3339),
+				})
+			}
+			entry["reasonHistory"] = reasonHistory
+		}
+		if node.BlockingPod != nil && node.BlockingPod.Pod != nil {
+			entry["blockingPod"] = blockingPodStatus(node.BlockingPod)
+		}
+		if len(node.BlockingPods) > 1 {
+			blockingPods := make([]interface{}, 0, len(node.BlockingPods))
+			for _, blockingPod := range node.BlockingPods {
+				if blockingPod.Pod != nil {
+					blockingPods = append(blockingPods, blockingPodStatus(blockingPod))
+				}
+			}
+			entry["blockingPods"] = blockingPods
+		}
+		if node.UnschedulablePod != nil && node.UnschedulablePod.Pod != nil {
+			entry["unschedulablePod"] = map[string]interface{}{
+				"namespace": node.UnschedulablePod.Pod.Namespace,
+				"name":      node.UnschedulablePod.Pod.Name,
+				"reason":    node.UnschedulablePod.Reason,
+			}
+		}
+		unremovable = append(unremovable, entry)
+	}
+	return map[string]interface{}{
+		"lastUpdateTime":   now.UTC().Format(time.RFC3339),
+		"unneededNodes":    unneeded,
+		"unremovableNodes": unremovable,
+	}
+}
+
+func blockingPodStatus(blockingPod *drain.BlockingPod) map[string]interface{} {
+	result := map[string]interface{}{
+		"namespace": blockingPod.Pod.Namespace,
+		"name":      blockingPod.Pod.Name,
+		"reason":    string(blockingPod.ReasonID()),
+	}
+	if len(blockingPod.Details) > 0 {
+		details := make(map[string]interface{}, len(blockingPod.Details))
+		for key, value := range blockingPod.Details {
+			details[key] = value
+		}
+		result["details"] = details
+	}
+	return result
+}
+
+func nodeStatus(name string, nodeGroup cloudprovider.NodeGroup, utilInfo *utilization.Info) map[string]interface{} {
+	entry := map[string]interface{}{"name": name}
+	if nodeGroup != nil && !reflect.ValueOf(nodeGroup).IsNil() {
+		entry["nodeGroup"] = nodeGroup.Id()
+	}
+	if utilInfo != nil {
+		entry["utilization"] = map[string]interface{}{
+			"cpu":      utilInfo.CpuUtil,
+			"memory":   utilInfo.MemUtil,
+			"gpu":      utilInfo.GpuUtil,
+			"resource": string(utilInfo.ResourceName),
+			"value":    utilInfo.Utilization,
+		}
+	}
+	return entry
+}
+
+func sortedUnneededNodes(nodes []*status.UnneededNode) []*status.UnneededNode {
+	sorted := append([]*status.UnneededNode{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Node.Name < sorted[j].Node.Name })
+	return sorted
+}
+
+func sortedUnremovableNodes(nodes []*status.UnremovableNode) []*status.UnremovableNode {
+	sorted := append([]*status.UnremovableNode{}, nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Node.Name < sorted[j].Node.Name })
+	return sorted
+}
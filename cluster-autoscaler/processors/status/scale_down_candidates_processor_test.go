@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	ctx "context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	"k8s.io/autoscaler/cluster-autoscaler/config"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleDownCandidatesProcessor(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	pod := BuildTestPod("p1", 100, 100)
+	unremovableSince := time.Date(2022, 5, 13, 15, 0, 0, 0, time.UTC)
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ScaleDownCandidatesResource: ScaleDownCandidatesKind + "List",
+	})
+	autoscalingContext := &context.AutoscalingContext{
+		AutoscalingOptions: config.AutoscalingOptions{ConfigNamespace: "kube-system"},
+	}
+	p := NewScaleDownCandidatesProcessor(client)
+
+	// Nothing is written until unremovable nodes are computed.
+	p.Process(autoscalingContext, &status.ScaleDownStatus{})
+	_, err := client.Resource(ScaleDownCandidatesResource).Namespace("kube-system").Get(ctx.TODO(), ScaleDownCandidatesName, metav1.GetOptions{})
+	assert.Error(t, err)
+
+	p.Process(autoscalingContext, &status.ScaleDownStatus{
+		UnneededNodes: []*status.UnneededNode{
+			{Node: n2, UtilInfo: &utilization.Info{CpuUtil: 0.2, MemUtil: 0.1, ResourceName: "cpu", Utilization: 0.2}},
+			{Node: n1},
+		},
+		UnremovableNodes: []*status.UnremovableNode{
+			{
+				Node:             n3,
+				Reason:           simulator.BlockedByPod,
+				BlockingPod:      &drain.BlockingPod{Pod: pod, Reason: drain.NotReplicated},
+				UnremovableSince: unremovableSince,
+				ReasonHistory: []simulator.UnremovableReasonChange{
+					{Reason: simulator.NotUnderutilized, Since: unremovableSince},
+					{Reason: simulator.BlockedByPod, Since: unremovableSince.Add(time.Hour)},
+				},
+			},
+		},
+	})
+	obj := getScaleDownCandidates(t, client)
+	unneeded, _, _ := unstructured.NestedSlice(obj.Object, "status", "unneededNodes")
+	if assert.Len(t, unneeded, 2) {
+		assert.Equal(t, "n1", unneeded[0].(map[string]interface{})["name"])
+		assert.Equal(t, "n2", unneeded[1].(map[string]interface{})["name"])
+		value, _, _ := unstructured.NestedFloat64(unneeded[1].(map[string]interface{}), "utilization", "value")
+		assert.Equal(t, 0.2, value)
+	}
+	unremovable, _, _ := unstructured.NestedSlice(obj.Object, "status", "unremovableNodes")
+	if assert.Len(t, unremovable, 1) {
+		entry := unremovable[0].(map[string]interface{})
+		assert.Equal(t, "n3", entry["name"])
+		assert.Equal(t, "BlockedByPod", entry["reason"])
+		reason, _, _ := unstructured.NestedString(entry, "blockingPod", "reason")
+		assert.Equal(t, "NotReplicated", reason)
+		assert.Equal(t, "2022-05-13T15:00:00Z", entry["unremovableSince"])
+		reasonHistory, _, _ := unstructured.NestedSlice(entry, "reasonHistory")
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"reason": "NotUnderutilized", "since": "2022-05-13T15:00:00Z"},
+			map[string]interface{}{"reason": "BlockedByPod", "since": "2022-05-13T16:00:00Z"},
+		}, reasonHistory)
+	}
+
+	// The object is updated in the following loops.
+	p.Process(autoscalingContext, &status.ScaleDownStatus{UnremovableNodes: []*status.UnremovableNode{}})
+	obj = getScaleDownCandidates(t, client)
+	unneeded, _, _ = unstructured.NestedSlice(obj.Object, "status", "unneededNodes")
+	assert.Empty(t, unneeded)
+	unremovable, _, _ = unstructured.NestedSlice(obj.Object, "status", "unremovableNodes")
+	assert.Empty(t, unremovable)
+}
+
+func getScaleDownCandidates(t *testing.T, client *dynamicfake.FakeDynamicClient) *unstructured.Unstructured {
+	obj, err := client.Resource(ScaleDownCandidatesResource).Namespace("kube-system").Get(ctx.TODO(), ScaleDownCandidatesName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	return obj
+}
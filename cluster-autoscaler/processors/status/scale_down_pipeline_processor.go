@@ -0,0 +1,281 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+)
+
+// maxNodeDeletionErrors is the number of the most recent node deletion
+// errors kept by ScaleDownPipelineProcessor.
+const maxNodeDeletionErrors = 20
+
+// ScaleDownCandidate is a node found unneeded in the last scale-down loop.
+type ScaleDownCandidate struct {
+	Node        string  `json:"node"`
+	NodeGroup   string  `json:"nodeGroup,omitempty"`
+	Utilization float64 `json:"utilization"`
+}
+
+// NodeDeletion is the progress of a deletion of a node.
+type NodeDeletion struct {
+	Node string `json:"node"`
+	// Drain tells if the node is drained before it's deleted.
+	Drain bool `json:"drain"`
+	// Phase is the phase of the drain, empty if it didn't start yet.
+	Phase         string     `json:"phase,omitempty"`
+	StartTime     *time.Time `json:"startTime,omitempty"`
+	Deadline      *time.Time `json:"deadline,omitempty"`
+	PodsToRemove  int        `json:"podsToRemove"`
+	PodsEvicted   int        `json:"podsEvicted"`
+	PodsRemaining int        `json:"podsRemaining"`
+	DelayedBy     []string   `json:"delayedBy,omitempty"`
+}
+
+// PodEvictionError is a failed eviction of a pod.
+type PodEvictionError struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Error     string `json:"error"`
+}
+
+// NodeDeletionError is a failed deletion of a node.
+type NodeDeletionError struct {
+	Node      string             `json:"node"`
+	Timestamp time.Time          `json:"timestamp"`
+	Type      string             `json:"type"`
+	Error     string             `json:"error"`
+	Pods      []PodEvictionError `json:"pods,omitempty"`
+}
+
+// ScaleDownPipelineState is the point-in-time state of the scale-down
+// pipeline.
+type ScaleDownPipelineState struct {
+	// Timestamp is the time of the last scale-down loop, which Result and
+	// Candidates are from.
+	Timestamp  time.Time            `json:"timestamp"`
+	Result     string               `json:"result"`
+	Candidates []ScaleDownCandidate `json:"candidates"`
+	// Deletions are the deletions in progress at the time of the request.
+	Deletions []NodeDeletion `json:"deletions"`
+	// Errors are the most recent node deletion errors, newest first.
+	Errors []NodeDeletionError `json:"errors"`
+}
+
+// ScaleDownPipelineProcessor keeps the state of the scale-down pipeline: the
+// candidates found unneeded in the last loop, the nodes being deleted along
+// with the progress of their drains, and the most recent node deletion
+// errors. It serves the state as JSON.
+type ScaleDownPipelineProcessor struct {
+	mutex      sync.RWMutex
+	updated    bool
+	timestamp  time.Time
+	result     status.ScaleDownResult
+	candidates []ScaleDownCandidate
+	errors     []NodeDeletionError
+	actuator   scaledown.Actuator
+	now        func() time.Time
+}
+
+// NewScaleDownPipelineProcessor returns a new ScaleDownPipelineProcessor.
+func NewScaleDownPipelineProcessor() *ScaleDownPipelineProcessor {
+	return &ScaleDownPipelineProcessor{now: time.Now}
+}
+
+// Process updates the state with the result of the scale-down loop.
+func (p *ScaleDownPipelineProcessor) Process(context *context.AutoscalingContext, status *status.ScaleDownStatus) {
+	now := p.now()
+	var errors []NodeDeletionError
+	for nodeName, result := range status.NodeDeleteResults {
+		if result.Err != nil {
+			errors = append(errors, nodeDeletionError(nodeName, result, now))
+		}
+	}
+	sort.Slice(errors, func(i, j int) bool { return errors[i].Node < errors[j].Node })
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.updated = true
+	p.timestamp = now
+	p.result = status.Result
+	p.actuator = context.ScaleDownActuator
+	if status.UnneededNodes != nil {
+		// Unneeded nodes are only computed in loops in which scale-down is tried.
+		p.candidates = make([]ScaleDownCandidate, 0, len(status.UnneededNodes))
+		for _, node := range sortedUnneededNodes(status.UnneededNodes) {
+			candidate := ScaleDownCandidate{Node: node.Node.Name}
+			if node.NodeGroup != nil && !reflect.ValueOf(node.NodeGroup).IsNil() {
+				candidate.NodeGroup = node.NodeGroup.Id()
+			}
+			if node.UtilInfo != nil {
+				candidate.Utilization = node.UtilInfo.Utilization
+			}
+			p.candidates = append(p.candidates, candidate)
+		}
+	}
+	p.errors = append(errors, p.errors...)
+	if len(p.errors) > maxNodeDeletionErrors {
+		p.errors = p.errors[:maxNodeDeletionErrors]
+	}
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *ScaleDownPipelineProcessor) CleanUp() {
+}
+
+// State returns the current state of the scale-down pipeline, or nil if no
+// scale-down loop ran yet.
+func (p *ScaleDownPipelineProcessor) State() *ScaleDownPipelineState {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if !p.updated {
+		return nil
+	}
+	state := &ScaleDownPipelineState{
+		Timestamp:  p.timestamp,
+		Result:     scaleDownResultName(p.result),
+		Candidates: append([]ScaleDownCandidate{}, p.candidates...),
+		Deletions:  []NodeDeletion{},
+		Errors:     append([]NodeDeletionError{}, p.errors...),
+	}
+	if p.actuator != nil {
+		state.Deletions = nodeDeletions(p.actuator.CheckStatus())
+	}
+	return state
+}
+
+// ServeHTTP returns the current state of the scale-down pipeline.
+func (p *ScaleDownPipelineProcessor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	state := p.State()
+	if state == nil {
+		http.Error(w, "no scale-down loop ran yet", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := json.Marshal(state)
+	if err != nil {
+		klog.Errorf("Failed to marshal scale-down pipeline state: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func nodeDeletions(actuationStatus scaledown.ActuationStatus) []NodeDeletion {
+	empty, drained := actuationStatus.DeletionsInProgress()
+	drainStatuses := actuationStatus.DrainStatuses()
+	deletions := make([]NodeDeletion, 0, len(empty)+len(drained))
+	for _, nodeName := range empty {
+		deletions = append(deletions, NodeDeletion{Node: nodeName})
+	}
+	for _, nodeName := range drained {
+		deletion := NodeDeletion{Node: nodeName, Drain: true}
+		if drainStatus, found := drainStatuses[nodeName]; found {
+			deletion.Phase = string(drainStatus.Phase)
+			deletion.StartTime = timePtr(drainStatus.StartTime)
+			deletion.Deadline = timePtr(drainStatus.Deadline)
+			deletion.PodsToRemove = drainStatus.PodsToRemove
+			deletion.PodsEvicted = drainStatus.PodsEvicted
+			deletion.PodsRemaining = drainStatus.PodsRemaining
+			deletion.DelayedBy = drainStatus.DelayedBy
+		}
+		deletions = append(deletions, deletion)
+	}
+	sort.Slice(deletions, func(i, j int) bool { return deletions[i].Node < deletions[j].Node })
+	return deletions
+}
+
+func nodeDeletionError(nodeName string, result status.NodeDeleteResult, now time.Time) NodeDeletionError {
+	deletionError := NodeDeletionError{
+		Node:      nodeName,
+		Timestamp: now,
+		Type:      nodeDeleteResultTypeName(result.ResultType),
+		Error:     result.Err.Error(),
+	}
+	for _, evictionResult := range result.PodEvictionResults {
+		if evictionResult.WasEvictionSuccessful() || evictionResult.Pod == nil {
+			continue
+		}
+		podError := PodEvictionError{Namespace: evictionResult.Pod.Namespace, Name: evictionResult.Pod.Name, Error: "timed out"}
+		if evictionResult.Err != nil {
+			podError.Error = evictionResult.Err.Error()
+		}
+		deletionError.Pods = append(deletionError.Pods, podError)
+	}
+	sort.Slice(deletionError.Pods, func(i, j int) bool {
+		return deletionError.Pods[i].Namespace+"/"+deletionError.Pods[i].Name < deletionError.Pods[j].Namespace+"/"+deletionError.Pods[j].Name
+	})
+	return deletionError
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func scaleDownResultName(result status.ScaleDownResult) string {
+	switch result {
+	case status.ScaleDownError:
+		return "Error"
+	case status.ScaleDownNoUnneeded:
+		return "NoUnneeded"
+	case status.ScaleDownNoNodeDeleted:
+		return "NoNodeDeleted"
+	case status.ScaleDownNodeDeleteStarted:
+		return "NodeDeleteStarted"
+	case status.ScaleDownNotTried:
+		return "NotTried"
+	case status.ScaleDownInCooldown:
+		return "InCooldown"
+	case status.ScaleDownInProgress:
+		return "InProgress"
+	default:
+		return fmt.Sprintf("ScaleDownResult(%d)", result)
+	}
+}
+
+func nodeDeleteResultTypeName(resultType status.NodeDeleteResultType) string {
+	switch resultType {
+	case status.NodeDeleteOk:
+		return "Ok"
+	case status.NodeDeleteErrorFailedToMarkToBeDeleted:
+		return "FailedToMarkToBeDeleted"
+	case status.NodeDeleteErrorFailedToEvictPods:
+		return "FailedToEvictPods"
+	case status.NodeDeleteErrorFailedToDelete:
+		return "FailedToDelete"
+	case status.NodeDeleteErrorInternal:
+		return "Internal"
+	default:
+		return fmt.Sprintf("NodeDeleteResultType(%d)", resultType)
+	}
+}
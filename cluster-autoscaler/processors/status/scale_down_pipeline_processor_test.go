@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	cp_test "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/deletiontracker"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/status"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+type testActuator struct {
+	scaledown.Actuator
+	tracker *deletiontracker.NodeDeletionTracker
+}
+
+func (a *testActuator) CheckStatus() scaledown.ActuationStatus {
+	return a.tracker.Snapshot()
+}
+
+func TestScaleDownPipelineProcessor(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	p := NewScaleDownPipelineProcessor()
+	p.now = func() time.Time { return testTime }
+
+	get := func() (int, *ScaleDownPipelineState) {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/scaledownz", nil))
+		if w.Code != http.StatusOK {
+			return w.Code, nil
+		}
+		state := &ScaleDownPipelineState{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), state))
+		return w.Code, state
+	}
+
+	code, _ := get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	tracker := deletiontracker.NewNodeDeletionTracker(0)
+	tracker.StartDeletion("ng1", "n1")
+	tracker.StartDeletionWithDrain("ng1", "n2")
+	tracker.RegisterDrainStatus("n2", status.NodeDrainStatus{Phase: status.NodeDrainEvicting, StartTime: testTime, Deadline: testTime.Add(time.Minute), PodsToRemove: 3, PodsEvicted: 1, PodsRemaining: 3})
+	ctx := &context.AutoscalingContext{ScaleDownActuator: &testActuator{tracker: tracker}}
+	nodeGroup := cp_test.NewTestNodeGroup("ng1", 10, 0, 3, true, false, "", nil, nil)
+	p1 := BuildTestPod("p1", 0, 0)
+	p2 := BuildTestPod("p2", 0, 0)
+	p.Process(ctx, &status.ScaleDownStatus{
+		Result: status.ScaleDownNodeDeleteStarted,
+		UnneededNodes: []*status.UnneededNode{
+			{Node: BuildTestNode("n4", 1000, 1000), NodeGroup: nodeGroup, UtilInfo: &utilization.Info{Utilization: 0.25}},
+			{Node: BuildTestNode("n3", 1000, 1000)},
+		},
+		NodeDeleteResults: map[string]status.NodeDeleteResult{
+			"n0": {ResultType: status.NodeDeleteOk},
+			"n5": {ResultType: status.NodeDeleteErrorFailedToEvictPods, Err: errors.New("failed to evict pods"), PodEvictionResults: map[string]status.PodEvictionResult{
+				"p1": {Pod: p1, TimedOut: true},
+				"p2": {Pod: p2},
+			}},
+		},
+	})
+
+	code, state := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, &ScaleDownPipelineState{
+		Timestamp: testTime,
+		Result:    "NodeDeleteStarted",
+		Candidates: []ScaleDownCandidate{
+			{Node: "n3"},
+			{Node: "n4", NodeGroup: "ng1", Utilization: 0.25},
+		},
+		Deletions: []NodeDeletion{
+			{Node: "n1"},
+			{Node: "n2", Drain: true, Phase: "Evicting", StartTime: &testTime, Deadline: timePtr(testTime.Add(time.Minute)), PodsToRemove: 3, PodsEvicted: 1, PodsRemaining: 3},
+		},
+		Errors: []NodeDeletionError{
+			{Node: "n5", Timestamp: testTime, Type: "FailedToEvictPods", Error: "failed to evict pods", Pods: []PodEvictionError{{Namespace: "default", Name: "p1", Error: "timed out"}}},
+		},
+	}, state)
+
+	// Drains progress between the loops, candidates are kept from the last loop they were computed in, and errors
+	// accumulate, newest first.
+	tracker.RegisterDrainStatus("n2", status.NodeDrainStatus{Phase: status.NodeDrainWaitingForTermination, StartTime: testTime, PodsToRemove: 3, PodsEvicted: 3, PodsRemaining: 1})
+	tracker.EndDeletion("ng1", "n1", status.NodeDeleteResult{ResultType: status.NodeDeleteOk})
+	p.now = func() time.Time { return testTime.Add(time.Minute) }
+	p.Process(ctx, &status.ScaleDownStatus{
+		Result: status.ScaleDownInCooldown,
+		NodeDeleteResults: map[string]status.NodeDeleteResult{
+			"n6": {ResultType: status.NodeDeleteErrorFailedToDelete, Err: errors.New("failed to delete node")},
+		},
+	})
+	code, state = get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "InCooldown", state.Result)
+	assert.Equal(t, []ScaleDownCandidate{{Node: "n3"}, {Node: "n4", NodeGroup: "ng1", Utilization: 0.25}}, state.Candidates)
+	assert.Equal(t, []NodeDeletion{{Node: "n2", Drain: true, Phase: "WaitingForTermination", StartTime: &testTime, PodsToRemove: 3, PodsEvicted: 3, PodsRemaining: 1}}, state.Deletions)
+	if assert.Len(t, state.Errors, 2) {
+		assert.Equal(t, "n6", state.Errors[0].Node)
+		assert.Equal(t, "n5", state.Errors[1].Node)
+	}
+}
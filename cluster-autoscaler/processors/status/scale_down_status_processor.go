@@ -42,3 +42,28 @@ func (p *NoOpScaleDownStatusProcessor) Process(context *context.AutoscalingConte
 // CleanUp cleans up the processor's internal structures.
 func (p *NoOpScaleDownStatusProcessor) CleanUp() {
 }
+
+// CombinedScaleDownStatusProcessor is a list of ScaleDownStatusProcessors
+// executed in order.
+type CombinedScaleDownStatusProcessor struct {
+	processors []ScaleDownStatusProcessor
+}
+
+// NewCombinedScaleDownStatusProcessor returns a new CombinedScaleDownStatusProcessor.
+func NewCombinedScaleDownStatusProcessor(processors []ScaleDownStatusProcessor) *CombinedScaleDownStatusProcessor {
+	return &CombinedScaleDownStatusProcessor{processors: processors}
+}
+
+// Process processes the status of the cluster after a scale-down with each of the processors.
+func (p *CombinedScaleDownStatusProcessor) Process(context *context.AutoscalingContext, status *status.ScaleDownStatus) {
+	for _, processor := range p.processors {
+		processor.Process(context, status)
+	}
+}
+
+// CleanUp cleans up internal structures of each of the processors.
+func (p *CombinedScaleDownStatusProcessor) CleanUp() {
+	for _, processor := range p.processors {
+		processor.CleanUp()
+	}
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+)
+
+const (
+	// PodQueryParam is the name of the query parameter holding the
+	// namespace/name of the pod whose explanation is returned.
+	PodQueryParam = "pod"
+
+	// PredicateFailureOutcome means that the pod doesn't fit on a new node of
+	// the node group.
+	PredicateFailureOutcome = "PredicateFailure"
+	// ResourceLimitReachedOutcome means that adding a node to the node group
+	// would exceed cluster-wide resource limits.
+	ResourceLimitReachedOutcome = "ResourceLimitReached"
+	// SkippedOutcome means that the node group wasn't considered for the
+	// scale-up, e.g. because it reached its max size or is in backoff.
+	SkippedOutcome = "Skipped"
+)
+
+// NodeGroupExplanation tells why a node group wasn't scaled up for a pod.
+type NodeGroupExplanation struct {
+	NodeGroup string `json:"nodeGroup"`
+	Outcome   string `json:"outcome"`
+	// Predicate is the scheduler predicate which failed, only set for
+	// PredicateFailureOutcome.
+	Predicate string `json:"predicate,omitempty"`
+	// Resources are the resources whose cluster-wide limits were reached,
+	// only set for ResourceLimitReachedOutcome.
+	Resources []string `json:"resources,omitempty"`
+	Reasons   []string `json:"reasons"`
+}
+
+// PodExplanation tells why a pod didn't trigger a scale-up.
+type PodExplanation struct {
+	Namespace  string                 `json:"namespace"`
+	Name       string                 `json:"name"`
+	NodeGroups []NodeGroupExplanation `json:"nodeGroups"`
+}
+
+// ScaleUpExplanation is the explanation of the last scale-up attempt.
+type ScaleUpExplanation struct {
+	Timestamp time.Time `json:"timestamp"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error,omitempty"`
+	// FailedResizeNodeGroups are node groups whose size couldn't be
+	// increased, e.g. because of a cloud provider quota.
+	FailedResizeNodeGroups []string         `json:"failedResizeNodeGroups,omitempty"`
+	Pods                   []PodExplanation `json:"pods"`
+}
+
+// ScaleUpExplanationProcessor keeps an explanation of the last scale-up
+// attempt, telling for each pod which didn't trigger a scale-up why none of
+// the node groups could be scaled up for it. It serves the explanation as
+// JSON.
+type ScaleUpExplanationProcessor struct {
+	mutex       sync.RWMutex
+	explanation *ScaleUpExplanation
+	now         func() time.Time
+}
+
+// NewScaleUpExplanationProcessor returns a new ScaleUpExplanationProcessor.
+func NewScaleUpExplanationProcessor() *ScaleUpExplanationProcessor {
+	return &ScaleUpExplanationProcessor{now: time.Now}
+}
+
+// Process updates the explanation, unless the scale-up wasn't attempted.
+func (p *ScaleUpExplanationProcessor) Process(context *context.AutoscalingContext, status *ScaleUpStatus) {
+	if status.Result == ScaleUpNotTried || status.Result == ScaleUpInCooldown {
+		return
+	}
+	explanation := &ScaleUpExplanation{
+		Timestamp:              p.now(),
+		Result:                 scaleUpResultName(status.Result),
+		FailedResizeNodeGroups: nodeGroupIds(status.FailedResizeNodeGroups),
+		Pods:                   make([]PodExplanation, 0, len(status.PodsRemainUnschedulable)),
+	}
+	if status.ScaleUpError != nil && *status.ScaleUpError != nil {
+		explanation.Error = (*status.ScaleUpError).Error()
+	}
+	for _, noScaleUpInfo := range status.PodsRemainUnschedulable {
+		explanation.Pods = append(explanation.Pods, explainPod(noScaleUpInfo))
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.explanation = explanation
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *ScaleUpExplanationProcessor) CleanUp() {
+}
+
+// ServeHTTP returns the explanation of the last scale-up attempt, limited to
+// the pod passed in PodQueryParam if it's set.
+func (p *ScaleUpExplanationProcessor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mutex.RLock()
+	explanation := p.explanation
+	p.mutex.RUnlock()
+	if explanation == nil {
+		http.Error(w, "no scale-up was attempted yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	var response interface{} = explanation
+	if podKey := r.URL.Query().Get(PodQueryParam); podKey != "" {
+		pod := findPodExplanation(explanation, podKey)
+		if pod == nil {
+			http.Error(w, fmt.Sprintf("pod %s didn't remain unschedulable in the last scale-up attempt", podKey), http.StatusNotFound)
+			return
+		}
+		response = pod
+	}
+	body, err := json.Marshal(response)
+	if err != nil {
+		klog.Errorf("Failed to marshal scale-up explanation: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func findPodExplanation(explanation *ScaleUpExplanation, podKey string) *PodExplanation {
+	for i := range explanation.Pods {
+		pod := &explanation.Pods[i]
+		if pod.Namespace+"/"+pod.Name == podKey {
+			return pod
+		}
+	}
+	return nil
+}
+
+func explainPod(noScaleUpInfo NoScaleUpInfo) PodExplanation {
+	explanation := PodExplanation{
+		Namespace: noScaleUpInfo.Pod.Namespace,
+		Name:      noScaleUpInfo.Pod.Name,
+	}
+	for nodeGroup, reasons := range noScaleUpInfo.RejectedNodeGroups {
+		explanation.NodeGroups = append(explanation.NodeGroups, explainNodeGroup(nodeGroup, reasons))
+	}
+	for nodeGroup, reasons := range noScaleUpInfo.SkippedNodeGroups {
+		explanation.NodeGroups = append(explanation.NodeGroups, explainNodeGroup(nodeGroup, reasons))
+	}
+	sort.Slice(explanation.NodeGroups, func(i, j int) bool {
+		return explanation.NodeGroups[i].NodeGroup < explanation.NodeGroups[j].NodeGroup
+	})
+	return explanation
+}
+
+func explainNodeGroup(nodeGroup string, reasons Reasons) NodeGroupExplanation {
+	explanation := NodeGroupExplanation{
+		NodeGroup: nodeGroup,
+		Outcome:   SkippedOutcome,
+		Reasons:   reasons.Reasons(),
+	}
+	switch r := reasons.(type) {
+	case interface {
+		PredicateName() string
+		Message() string
+	}:
+		explanation.Outcome = PredicateFailureOutcome
+		explanation.Predicate = r.PredicateName()
+		if len(explanation.Reasons) == 0 {
+			explanation.Reasons = []string{r.Message()}
+		}
+	case interface{ Resources() []string }:
+		explanation.Outcome = ResourceLimitReachedOutcome
+		explanation.Resources = r.Resources()
+	}
+	if explanation.Reasons == nil {
+		explanation.Reasons = []string{}
+	}
+	return explanation
+}
+
+func nodeGroupIds(nodeGroups []cloudprovider.NodeGroup) []string {
+	var ids []string
+	for _, nodeGroup := range nodeGroups {
+		ids = append(ids, nodeGroup.Id())
+	}
+	return ids
+}
+
+func scaleUpResultName(result ScaleUpResult) string {
+	switch result {
+	case ScaleUpSuccessful:
+		return "Successful"
+	case ScaleUpError:
+		return "Error"
+	case ScaleUpNoOptionsAvailable:
+		return "NoOptionsAvailable"
+	case ScaleUpNotNeeded:
+		return "NotNeeded"
+	default:
+		return fmt.Sprintf("ScaleUpResult(%d)", result)
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+	cp_test "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testResourceLimitReason struct {
+	resources []string
+}
+
+func (r *testResourceLimitReason) Reasons() []string {
+	return []string{"max cluster cpu limit reached"}
+}
+
+func (r *testResourceLimitReason) Resources() []string {
+	return r.resources
+}
+
+func TestScaleUpExplanationProcessor(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	p1 := BuildTestPod("p1", 0, 0)
+	p2 := BuildTestPod("p2", 0, 0)
+	p := NewScaleUpExplanationProcessor()
+	p.now = func() time.Time { return testTime }
+
+	get := func(url string) (int, []byte) {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w.Code, w.Body.Bytes()
+	}
+
+	code, _ := get("/scaleupz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	p.Process(nil, &ScaleUpStatus{
+		Result: ScaleUpNoOptionsAvailable,
+		PodsRemainUnschedulable: []NoScaleUpInfo{
+			{
+				Pod: p1,
+				RejectedNodeGroups: map[string]Reasons{
+					"ng2": predicatechecker.NewPredicateError(predicatechecker.NotSchedulablePredicateError, "NodeResourcesFit", "", []string{"Insufficient cpu"}, nil),
+				},
+				SkippedNodeGroups: map[string]Reasons{
+					"ng1": &testReason{"max node group size reached"},
+					"ng3": &testResourceLimitReason{resources: []string{"cpu"}},
+				},
+			},
+		},
+		FailedResizeNodeGroups: []cloudprovider.NodeGroup{cp_test.NewTestNodeGroup("ng4", 10, 0, 1, true, false, "", nil, nil)},
+	})
+	// Loops without a scale-up attempt don't override the explanation.
+	p.Process(nil, &ScaleUpStatus{Result: ScaleUpNotTried})
+
+	code, body := get("/scaleupz")
+	assert.Equal(t, http.StatusOK, code)
+	var explanation ScaleUpExplanation
+	assert.NoError(t, json.Unmarshal(body, &explanation))
+	assert.Equal(t, ScaleUpExplanation{
+		Timestamp:              testTime,
+		Result:                 "NoOptionsAvailable",
+		FailedResizeNodeGroups: []string{"ng4"},
+		Pods: []PodExplanation{
+			{
+				Namespace: p1.Namespace,
+				Name:      "p1",
+				NodeGroups: []NodeGroupExplanation{
+					{NodeGroup: "ng1", Outcome: SkippedOutcome, Reasons: []string{"max node group size reached"}},
+					{NodeGroup: "ng2", Outcome: PredicateFailureOutcome, Predicate: "NodeResourcesFit", Reasons: []string{"Insufficient cpu"}},
+					{NodeGroup: "ng3", Outcome: ResourceLimitReachedOutcome, Resources: []string{"cpu"}, Reasons: []string{"max cluster cpu limit reached"}},
+				},
+			},
+		},
+	}, explanation)
+
+	code, body = get("/scaleupz?pod=" + p1.Namespace + "/p1")
+	assert.Equal(t, http.StatusOK, code)
+	var pod PodExplanation
+	assert.NoError(t, json.Unmarshal(body, &pod))
+	assert.Equal(t, explanation.Pods[0], pod)
+
+	code, _ = get("/scaleupz?pod=" + p2.Namespace + "/p2")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -40,6 +40,24 @@ type ScaleUpStatus struct {
 	ConsideredNodeGroups     []cloudprovider.NodeGroup
 	FailedCreationNodeGroups []cloudprovider.NodeGroup
 	FailedResizeNodeGroups   []cloudprovider.NodeGroup
+	// Balancing describes how the scale-up was split between similar node groups, if it was.
+	Balancing *BalancingInfo
+}
+
+// BalancingInfo describes how a scale-up was split between similar node groups.
+type BalancingInfo struct {
+	// Zones maps the zones of the scaled up node groups to the number of nodes added in them.
+	// Node groups without a zone label are counted under an empty zone.
+	Zones map[string]int
+	// Validated tells if the split was validated by scheduling the pods triggering the scale-up in simulation.
+	Validated bool
+	// FellBack tells if fewer pods fit the split than scaling up only the best node group, which was done instead.
+	FellBack bool
+	// BalancedPods is the number of pods which fit the split in simulation.
+	BalancedPods int
+	// BestNodeGroupPods is the number of pods which fit scaling up only the best node group in simulation, if that
+	// was simulated.
+	BestNodeGroupPods int
 }
 
 // NoScaleUpInfo contains information about a pod that didn't trigger scale-up.
@@ -99,3 +117,28 @@ func (p *NoOpScaleUpStatusProcessor) Process(context *context.AutoscalingContext
 // CleanUp cleans up the processor's internal structures.
 func (p *NoOpScaleUpStatusProcessor) CleanUp() {
 }
+
+// CombinedScaleUpStatusProcessor is a list of ScaleUpStatusProcessors
+// executed in order.
+type CombinedScaleUpStatusProcessor struct {
+	processors []ScaleUpStatusProcessor
+}
+
+// NewCombinedScaleUpStatusProcessor returns a new CombinedScaleUpStatusProcessor.
+func NewCombinedScaleUpStatusProcessor(processors []ScaleUpStatusProcessor) *CombinedScaleUpStatusProcessor {
+	return &CombinedScaleUpStatusProcessor{processors: processors}
+}
+
+// Process processes the status of the cluster after a scale-up with each of the processors.
+func (p *CombinedScaleUpStatusProcessor) Process(context *context.AutoscalingContext, status *ScaleUpStatus) {
+	for _, processor := range p.processors {
+		processor.Process(context, status)
+	}
+}
+
+// CleanUp cleans up internal structures of each of the processors.
+func (p *CombinedScaleUpStatusProcessor) CleanUp() {
+	for _, processor := range p.processors {
+		processor.CleanUp()
+	}
+}
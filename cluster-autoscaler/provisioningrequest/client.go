@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioningrequest
+
+import (
+	ctx "context"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	kube_client "k8s.io/client-go/kubernetes"
+	klog "k8s.io/klog/v2"
+)
+
+// Client reads ProvisioningRequests with their PodTemplates and updates their
+// conditions.
+type Client struct {
+	dynamicClient dynamic.Interface
+	kubeClient    kube_client.Interface
+}
+
+// NewClient returns a new Client.
+func NewClient(dynamicClient dynamic.Interface, kubeClient kube_client.Interface) *Client {
+	return &Client{dynamicClient: dynamicClient, kubeClient: kubeClient}
+}
+
+// List returns all ProvisioningRequests, oldest first. Requests which can't
+// be parsed are marked as failed and skipped.
+func (c *Client) List() ([]*ProvisioningRequest, error) {
+	list, err := c.dynamicClient.Resource(Resource).Namespace(metav1.NamespaceAll).List(ctx.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var requests []*ProvisioningRequest
+	for i := range list.Items {
+		obj := &list.Items[i]
+		pr, err := fromUnstructured(obj)
+		if err != nil {
+			klog.Warningf("Invalid %s %s/%s: %v", Kind, obj.GetNamespace(), obj.GetName(), err)
+			if !hasCondition(obj, Failed) {
+				if err := c.setCondition(obj, Failed, InvalidReason, err.Error(), time.Now()); err != nil {
+					klog.Warningf("Failed to update %s %s/%s: %v", Kind, obj.GetNamespace(), obj.GetName(), err)
+				}
+			}
+			continue
+		}
+		requests = append(requests, pr)
+	}
+	sortByCreationTimestamp(requests)
+	return requests, nil
+}
+
+// Pods returns the pods of the request.
+func (c *Client) Pods(pr *ProvisioningRequest) ([]*apiv1.Pod, error) {
+	podTemplates := make(map[string]*apiv1.PodTemplate)
+	for _, podSet := range pr.PodSets {
+		if _, found := podTemplates[podSet.PodTemplateName]; found {
+			continue
+		}
+		podTemplate, err := c.kubeClient.CoreV1().PodTemplates(pr.Namespace).Get(ctx.TODO(), podSet.PodTemplateName, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		podTemplates[podSet.PodTemplateName] = podTemplate
+	}
+	return pr.Pods(podTemplates)
+}
+
+// SetCondition sets a True condition of the given type on the request.
+func (c *Client) SetCondition(namespace, name, conditionType, reason, message string, now time.Time) error {
+	obj, err := c.dynamicClient.Resource(Resource).Namespace(namespace).Get(ctx.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	return c.setCondition(obj, conditionType, reason, message, now)
+}
+
+func (c *Client) setCondition(obj *unstructured.Unstructured, conditionType, reason, message string, now time.Time) error {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	var newConditions []interface{}
+	for _, condition := range conditions {
+		if conditionMap, ok := condition.(map[string]interface{}); ok && conditionMap["type"] == conditionType {
+			continue
+		}
+		newConditions = append(newConditions, condition)
+	}
+	newConditions = append(newConditions, map[string]interface{}{
+		"type":               conditionType,
+		"status":             string(metav1.ConditionTrue),
+		"reason":             reason,
+		"message":            message,
+		"lastTransitionTime": now.UTC().Format(time.RFC3339),
+	})
+	if err := unstructured.SetNestedSlice(obj.Object, newConditions, "status", "conditions"); err != nil {
+		return err
+	}
+	_, err := c.dynamicClient.Resource(Resource).Namespace(obj.GetNamespace()).UpdateStatus(ctx.TODO(), obj, metav1.UpdateOptions{})
+	return err
+}
+
+func hasCondition(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, condition := range conditions {
+		if conditionMap, ok := condition.(map[string]interface{}); ok && conditionMap["type"] == conditionType {
+			return true
+		}
+	}
+	return false
+}
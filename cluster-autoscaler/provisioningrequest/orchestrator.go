@@ -0,0 +1,193 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioningrequest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/core/scaleup"
+	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+)
+
+// Orchestrator wraps a scale-up orchestrator. It scales up for pods of
+// ProvisioningRequests, added to unschedulable pods by PodListProcessor, one
+// request at a time and all-or-nothing, and updates the conditions of the
+// requests accordingly. Other pods are passed to the wrapped orchestrator once
+// no scale-up was triggered for a request.
+type Orchestrator struct {
+	client       *Client
+	orchestrator scaleup.Orchestrator
+}
+
+// NewOrchestrator returns a new Orchestrator.
+func NewOrchestrator(client *Client, orchestrator scaleup.Orchestrator) *Orchestrator {
+	return &Orchestrator{client: client, orchestrator: orchestrator}
+}
+
+// Initialize initializes the wrapped orchestrator.
+func (o *Orchestrator) Initialize(
+	autoscalingContext *context.AutoscalingContext,
+	processors *ca_processors.AutoscalingProcessors,
+	clusterStateRegistry *clusterstate.ClusterStateRegistry,
+	taintConfig taints.TaintConfig,
+) {
+	o.orchestrator.Initialize(autoscalingContext, processors, clusterStateRegistry, taintConfig)
+}
+
+// ScaleUp scales up for the oldest ProvisioningRequest whose pods fit in the
+// cluster after a scale-up, marking requests whose pods don't as failed. If
+// there is no such request, it scales up for other pods.
+func (o *Orchestrator) ScaleUp(
+	unschedulablePods []*apiv1.Pod,
+	nodes []*apiv1.Node,
+	daemonSets []*appsv1.DaemonSet,
+	nodeInfos map[string]*schedulerframework.NodeInfo,
+	allOrNothing bool,
+) (*status.ScaleUpStatus, errors.AutoscalerError) {
+	var keys []string
+	requestPods := make(map[string][]*apiv1.Pod)
+	var otherPods []*apiv1.Pod
+	for _, pod := range unschedulablePods {
+		key, found := requestKey(pod)
+		if !found {
+			otherPods = append(otherPods, pod)
+			continue
+		}
+		if _, found := requestPods[key]; !found {
+			keys = append(keys, key)
+		}
+		requestPods[key] = append(requestPods[key], pod)
+	}
+
+	for _, key := range keys {
+		pods := requestPods[key]
+		namespace, name := pods[0].Namespace, pods[0].Annotations[PodAnnotation]
+		scaleUpStatus, err := o.orchestrator.ScaleUp(pods, nodes, daemonSets, nodeInfos, true)
+		if err != nil {
+			// The request will be retried in the next loop.
+			return withoutRequestPods(scaleUpStatus), err
+		}
+		now := time.Now()
+		if scaleUpStatus.Result == status.ScaleUpSuccessful {
+			klog.V(1).Infof("Scaled up for %s %s/%s", Kind, namespace, name)
+			o.setCondition(namespace, name, Provisioned, ScaleUpTriggeredReason, scaleUpMessage(scaleUpStatus), now)
+			return withoutRequestPods(scaleUpStatus), nil
+		}
+		klog.V(1).Infof("Pods of %s %s/%s don't fit in the cluster after a scale-up", Kind, namespace, name)
+		o.setCondition(namespace, name, Failed, CapacityNotFoundReason, failureMessage(scaleUpStatus, len(pods)), now)
+	}
+
+	if len(otherPods) == 0 {
+		return &status.ScaleUpStatus{Result: status.ScaleUpNotNeeded}, nil
+	}
+	return o.orchestrator.ScaleUp(otherPods, nodes, daemonSets, nodeInfos, allOrNothing)
+}
+
+// ScaleUpToNodeGroupMinSize calls the wrapped orchestrator.
+func (o *Orchestrator) ScaleUpToNodeGroupMinSize(
+	nodes []*apiv1.Node,
+	nodeInfos map[string]*schedulerframework.NodeInfo,
+) (*status.ScaleUpStatus, errors.AutoscalerError) {
+	return o.orchestrator.ScaleUpToNodeGroupMinSize(nodes, nodeInfos)
+}
+
+func (o *Orchestrator) setCondition(namespace, name, conditionType, reason, message string, now time.Time) {
+	if err := o.client.SetCondition(namespace, name, conditionType, reason, message, now); err != nil {
+		klog.Warningf("Failed to update %s %s/%s: %v", Kind, namespace, name, err)
+	}
+}
+
+// withoutRequestPods removes pods of ProvisioningRequests, which don't exist
+// in the cluster, from the status, so that no events are emitted for them.
+func withoutRequestPods(scaleUpStatus *status.ScaleUpStatus) *status.ScaleUpStatus {
+	if scaleUpStatus == nil {
+		return nil
+	}
+	result := *scaleUpStatus
+	result.PodsTriggeredScaleUp = nil
+	result.PodsRemainUnschedulable = nil
+	result.PodsAwaitEvaluation = nil
+	return &result
+}
+
+func scaleUpMessage(scaleUpStatus *status.ScaleUpStatus) string {
+	var resizes []string
+	for _, info := range scaleUpStatus.ScaleUpInfos {
+		resizes = append(resizes, fmt.Sprintf("%s %d->%d", info.Group.Id(), info.CurrentSize, info.NewSize))
+	}
+	return fmt.Sprintf("Scale-up triggered: %s", strings.Join(resizes, ", "))
+}
+
+// failureMessage summarizes why the pods of a request don't fit in the
+// cluster after a scale-up.
+func failureMessage(scaleUpStatus *status.ScaleUpStatus, podCount int) string {
+	message := fmt.Sprintf("%d pods don't fit together in any node group after scale-up", podCount)
+	if len(scaleUpStatus.PodsRemainUnschedulable) == 0 {
+		return message
+	}
+	reasons := make(map[string][]string)
+	for _, noScaleUpInfo := range scaleUpStatus.PodsRemainUnschedulable {
+		for nodeGroup, nodeGroupReasons := range noScaleUpInfo.RejectedNodeGroups {
+			reasons[nodeGroup] = appendUnique(reasons[nodeGroup], nodeGroupReasons.Reasons()...)
+		}
+		for nodeGroup, nodeGroupReasons := range noScaleUpInfo.SkippedNodeGroups {
+			reasons[nodeGroup] = appendUnique(reasons[nodeGroup], nodeGroupReasons.Reasons()...)
+		}
+	}
+	var nodeGroups []string
+	for nodeGroup := range reasons {
+		nodeGroups = append(nodeGroups, nodeGroup)
+	}
+	sort.Strings(nodeGroups)
+	var details []string
+	for _, nodeGroup := range nodeGroups {
+		details = append(details, fmt.Sprintf("%s: %s", nodeGroup, strings.Join(reasons[nodeGroup], ", ")))
+	}
+	if len(details) == 0 {
+		return message
+	}
+	return fmt.Sprintf("%s; %s", message, strings.Join(details, "; "))
+}
+
+func appendUnique(values []string, newValues ...string) []string {
+	for _, newValue := range newValues {
+		found := false
+		for _, value := range values {
+			if value == newValue {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, newValue)
+		}
+	}
+	return values
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioningrequest
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+
+	testprovider "k8s.io/autoscaler/cluster-autoscaler/cloudprovider/test"
+	"k8s.io/autoscaler/cluster-autoscaler/clusterstate"
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	ca_processors "k8s.io/autoscaler/cluster-autoscaler/processors"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/nodegroupset"
+	"k8s.io/autoscaler/cluster-autoscaler/processors/status"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/taints"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testReasons []string
+
+func (r testReasons) Reasons() []string {
+	return r
+}
+
+// fakeOrchestrator scales up only if at most maxPods pods are passed.
+type fakeOrchestrator struct {
+	maxPods int
+	calls   [][]string
+}
+
+func (o *fakeOrchestrator) Initialize(*context.AutoscalingContext, *ca_processors.AutoscalingProcessors, *clusterstate.ClusterStateRegistry, taints.TaintConfig) {
+}
+
+func (o *fakeOrchestrator) ScaleUp(pods []*apiv1.Pod, _ []*apiv1.Node, _ []*appsv1.DaemonSet, _ map[string]*schedulerframework.NodeInfo, _ bool) (*status.ScaleUpStatus, errors.AutoscalerError) {
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	o.calls = append(o.calls, names)
+	if len(pods) > o.maxPods {
+		var noScaleUpInfos []status.NoScaleUpInfo
+		for _, pod := range pods {
+			noScaleUpInfos = append(noScaleUpInfos, status.NoScaleUpInfo{
+				Pod:                pod,
+				RejectedNodeGroups: map[string]status.Reasons{"ng2": testReasons{"max node group size reached"}},
+				SkippedNodeGroups:  map[string]status.Reasons{"ng1": testReasons{"Insufficient cpu"}},
+			})
+		}
+		return &status.ScaleUpStatus{Result: status.ScaleUpNoOptionsAvailable, PodsRemainUnschedulable: noScaleUpInfos}, nil
+	}
+	return &status.ScaleUpStatus{
+		Result:               status.ScaleUpSuccessful,
+		ScaleUpInfos:         []nodegroupset.ScaleUpInfo{{Group: testprovider.NewTestNodeGroup("ng1", 10, 0, 1, true, false, "", nil, nil), CurrentSize: 1, NewSize: 2}},
+		PodsTriggeredScaleUp: pods,
+	}, nil
+}
+
+func (o *fakeOrchestrator) ScaleUpToNodeGroupMinSize([]*apiv1.Node, map[string]*schedulerframework.NodeInfo) (*status.ScaleUpStatus, errors.AutoscalerError) {
+	return nil, nil
+}
+
+func TestOrchestratorScaleUp(t *testing.T) {
+	now := time.Now()
+	client := newTestClient([]runtime.Object{
+		buildTestRequest("large", now.Add(-2*time.Minute), map[string]int64{"small": 3}),
+		buildTestRequest("medium", now.Add(-time.Minute), map[string]int64{"small": 2}),
+	}, buildTestPodTemplate("small", 300, 10))
+	prLarge, err := fromUnstructured(buildTestRequest("large", now.Add(-2*time.Minute), map[string]int64{"small": 3}))
+	assert.NoError(t, err)
+	prMedium, err := fromUnstructured(buildTestRequest("medium", now.Add(-time.Minute), map[string]int64{"small": 2}))
+	assert.NoError(t, err)
+	podTemplates := map[string]*apiv1.PodTemplate{"small": buildTestPodTemplate("small", 300, 10)}
+	largePods, err := prLarge.Pods(podTemplates)
+	assert.NoError(t, err)
+	mediumPods, err := prMedium.Pods(podTemplates)
+	assert.NoError(t, err)
+	regular := BuildTestPod("regular", 100, 10)
+
+	// The large request fails, the medium one triggers a scale-up and the
+	// regular pod waits for the next loop.
+	wrapped := &fakeOrchestrator{maxPods: 2}
+	o := NewOrchestrator(client, wrapped)
+	scaleUpStatus, err := o.ScaleUp(append(append([]*apiv1.Pod{regular}, largePods...), mediumPods...), nil, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleUpSuccessful, scaleUpStatus.Result)
+	assert.Empty(t, scaleUpStatus.PodsTriggeredScaleUp)
+	assert.Equal(t, [][]string{{"large-0-0", "large-0-1", "large-0-2"}, {"medium-0-0", "medium-0-1"}}, wrapped.calls)
+
+	condition, found := getCondition(t, client, "large", Failed)
+	assert.True(t, found)
+	assert.Equal(t, CapacityNotFoundReason, condition.Reason)
+	assert.Equal(t, "3 pods don't fit together in any node group after scale-up; ng1: Insufficient cpu; ng2: max node group size reached", condition.Message)
+	condition, found = getCondition(t, client, "medium", Provisioned)
+	assert.True(t, found)
+	assert.Equal(t, ScaleUpTriggeredReason, condition.Reason)
+	assert.Equal(t, "Scale-up triggered: ng1 1->2", condition.Message)
+
+	// Without pending requests, regular pods are passed to the wrapped orchestrator.
+	wrapped.calls = nil
+	scaleUpStatus, err = o.ScaleUp([]*apiv1.Pod{regular}, nil, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, status.ScaleUpSuccessful, scaleUpStatus.Result)
+	assert.Equal(t, []*apiv1.Pod{regular}, scaleUpStatus.PodsTriggeredScaleUp)
+	assert.Equal(t, [][]string{{"regular"}}, wrapped.calls)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioningrequest
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	klog "k8s.io/klog/v2"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/scheduling"
+)
+
+// BookingTime is how long capacity provisioned for a ProvisioningRequest is
+// kept for its pods, i.e. isn't used for pods of other requests and isn't
+// scaled down.
+const BookingTime = 10 * time.Minute
+
+// PodListProcessor books capacity for ProvisioningRequests in the cluster
+// snapshot and adds pods of pending requests which don't fit in the cluster
+// to unschedulable pods, so that a scale-up is attempted for them. It should
+// run after other pod list processors, so that the pods of a request aren't
+// filtered out as schedulable one by one.
+type PodListProcessor struct {
+	client    *Client
+	simulator *scheduling.HintingSimulator
+}
+
+// NewPodListProcessor returns a new PodListProcessor.
+func NewPodListProcessor(client *Client, predicateChecker predicatechecker.PredicateChecker) *PodListProcessor {
+	return &PodListProcessor{
+		client:    client,
+		simulator: scheduling.NewHintingSimulator(predicateChecker),
+	}
+}
+
+// Process books capacity for recently provisioned requests, marks pending
+// requests which fit in the cluster as provisioned and adds pods of the
+// remaining pending requests to unschedulable pods.
+func (p *PodListProcessor) Process(context *context.AutoscalingContext, unschedulablePods []*apiv1.Pod) ([]*apiv1.Pod, error) {
+	requests, err := p.client.List()
+	if err != nil {
+		klog.Warningf("Failed to list %ss: %v", Kind, err)
+		return unschedulablePods, nil
+	}
+	now := time.Now()
+	for _, pr := range requests {
+		if !pr.Pending() && !pr.ProvisionedWithin(BookingTime, now) {
+			continue
+		}
+		pods, err := p.client.Pods(pr)
+		if err != nil {
+			klog.Warningf("Failed to get pods of %s %s/%s: %v", Kind, pr.Namespace, pr.Name, err)
+			if pr.Pending() {
+				p.setCondition(pr, Failed, InvalidReason, err.Error(), now)
+			}
+			continue
+		}
+		if !pr.Pending() {
+			// Book capacity provisioned for the request, as far as it's there.
+			if _, _, err := p.simulator.TrySchedulePods(context.ClusterSnapshot, pods, scheduling.ScheduleAnywhere, false); err != nil {
+				klog.Warningf("Failed to book capacity for %s %s/%s: %v", Kind, pr.Namespace, pr.Name, err)
+			}
+			continue
+		}
+		fits, err := p.fits(context.ClusterSnapshot, pods)
+		if err != nil {
+			klog.Warningf("Failed to check if %s %s/%s fits in the cluster: %v", Kind, pr.Namespace, pr.Name, err)
+			continue
+		}
+		if fits {
+			klog.V(1).Infof("All %d pods of %s %s/%s fit in the cluster", len(pods), Kind, pr.Namespace, pr.Name)
+			p.setCondition(pr, Provisioned, CapacityAvailableReason, fmt.Sprintf("All %d pods fit in the cluster", len(pods)), now)
+			continue
+		}
+		unschedulablePods = append(unschedulablePods, pods...)
+	}
+	return unschedulablePods, nil
+}
+
+// CleanUp cleans up the processor's internal structures.
+func (p *PodListProcessor) CleanUp() {
+}
+
+// fits tells if all the pods fit in the cluster snapshot, and if so schedules
+// them there.
+func (p *PodListProcessor) fits(snapshot clustersnapshot.ClusterSnapshot, pods []*apiv1.Pod) (bool, error) {
+	fits := false
+	err, cleanupErr := clustersnapshot.WithForkedSnapshot(snapshot, func() (bool, error) {
+		statuses, _, err := p.simulator.TrySchedulePods(snapshot, pods, scheduling.ScheduleAnywhere, true)
+		if err != nil {
+			return false, err
+		}
+		fits = len(statuses) == len(pods)
+		return fits, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return fits, cleanupErr
+}
+
+func (p *PodListProcessor) setCondition(pr *ProvisioningRequest, conditionType, reason, message string, now time.Time) {
+	if err := p.client.SetCondition(pr.Namespace, pr.Name, conditionType, reason, message, now); err != nil {
+		klog.Warningf("Failed to update %s %s/%s: %v", Kind, pr.Namespace, pr.Name, err)
+	}
+}
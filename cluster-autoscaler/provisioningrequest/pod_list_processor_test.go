@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioningrequest
+
+import (
+	ctx "context"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"k8s.io/autoscaler/cluster-autoscaler/context"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(objects []runtime.Object, podTemplates ...runtime.Object) *Client {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		Resource: Kind + "List",
+	}, objects...)
+	return NewClient(dynamicClient, fake.NewSimpleClientset(podTemplates...))
+}
+
+func getCondition(t *testing.T, client *Client, name, conditionType string) (metav1.Condition, bool) {
+	obj, err := client.dynamicClient.Resource(Resource).Namespace("default").Get(ctx.TODO(), name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	pr, err := fromUnstructured(obj)
+	assert.NoError(t, err)
+	condition, found := pr.Conditions[conditionType]
+	return condition, found
+}
+
+func TestPodListProcessor(t *testing.T) {
+	now := time.Now()
+	client := newTestClient([]runtime.Object{
+		buildTestRequest("booked", now.Add(-5*time.Minute), map[string]int64{"small": 1}, buildTestCondition(Provisioned, now.Add(-time.Minute))),
+		buildTestRequest("expired", now.Add(-4*time.Hour), map[string]int64{"small": 3}, buildTestCondition(Provisioned, now.Add(-time.Hour))),
+		buildTestRequest("fits", now.Add(-4*time.Minute), map[string]int64{"small": 2}),
+		buildTestRequest("big", now.Add(-3*time.Minute), map[string]int64{"big": 1}),
+		buildTestRequest("failed", now.Add(-2*time.Minute), map[string]int64{"big": 1}, buildTestCondition(Failed, now.Add(-time.Minute))),
+		buildTestRequest("missing", now.Add(-time.Minute), map[string]int64{"unknown": 1}),
+	}, buildTestPodTemplate("small", 300, 10), buildTestPodTemplate("big", 500, 10))
+
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+	snapshot := clustersnapshot.NewBasicClusterSnapshot()
+	n1 := BuildTestNode("n1", 1000, 1000)
+	SetNodeReadyState(n1, true, time.Time{})
+	assert.NoError(t, snapshot.AddNode(n1))
+	autoscalingContext := &context.AutoscalingContext{ClusterSnapshot: snapshot}
+
+	pending := BuildTestPod("pending", 100, 10)
+	p := NewPodListProcessor(client, predicateChecker)
+	unschedulablePods, err := p.Process(autoscalingContext, []*apiv1.Pod{pending})
+	assert.NoError(t, err)
+
+	// The booked pod and the pods of the fitting request use 900m CPU, so the
+	// big request has to trigger a scale-up.
+	var names []string
+	for _, pod := range unschedulablePods {
+		names = append(names, pod.Name)
+	}
+	assert.Equal(t, []string{"pending", "big-0-0"}, names)
+	nodeInfo, err := snapshot.NodeInfos().Get("n1")
+	assert.NoError(t, err)
+	assert.Len(t, nodeInfo.Pods, 3)
+
+	condition, found := getCondition(t, client, "fits", Provisioned)
+	assert.True(t, found)
+	assert.Equal(t, CapacityAvailableReason, condition.Reason)
+	_, found = getCondition(t, client, "big", Provisioned)
+	assert.False(t, found)
+	_, found = getCondition(t, client, "big", Failed)
+	assert.False(t, found)
+	condition, found = getCondition(t, client, "missing", Failed)
+	assert.True(t, found)
+	assert.Equal(t, InvalidReason, condition.Reason)
+}
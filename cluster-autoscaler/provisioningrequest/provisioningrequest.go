@@ -0,0 +1,215 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provisioningrequest implements ProvisioningRequests, which request
+// capacity for a group of pods, e.g. a gang-scheduled job, atomically: either
+// all the pods fit in the cluster, possibly after a scale-up, or none of them
+// does.
+package provisioningrequest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// Kind is the kind of the ProvisioningRequest custom resource.
+	Kind = "ProvisioningRequest"
+	// PodAnnotation is set on pods simulated for a ProvisioningRequest. Its
+	// value is the name of the request, in the namespace of the pod.
+	PodAnnotation = "autoscaling.x-k8s.io/provisioning-request"
+
+	// Provisioned condition is True once capacity for all the pods of the
+	// request is available or being added.
+	Provisioned = "Provisioned"
+	// Failed condition is True if the pods of the request can't all fit in
+	// the cluster even after a scale-up.
+	Failed = "Failed"
+
+	// CapacityAvailableReason means that the pods fit in the existing cluster.
+	CapacityAvailableReason = "CapacityAvailable"
+	// ScaleUpTriggeredReason means that the cluster was scaled up to fit the pods.
+	ScaleUpTriggeredReason = "ScaleUpTriggered"
+	// CapacityNotFoundReason means that no scale-up could fit all the pods.
+	CapacityNotFoundReason = "CapacityNotFound"
+	// InvalidReason means that the pods of the request couldn't be built.
+	InvalidReason = "Invalid"
+)
+
+// Resource is the resource of the ProvisioningRequest custom resource definition.
+var Resource = schema.GroupVersionResource{Group: "autoscaling.x-k8s.io", Version: "v1alpha1", Resource: "provisioningrequests"}
+
+// PodSet is a group of identical pods of a ProvisioningRequest.
+type PodSet struct {
+	// PodTemplateName is the name of the PodTemplate the pods are created from.
+	PodTemplateName string
+	// Count is the number of pods.
+	Count int32
+}
+
+// ProvisioningRequest requests capacity for all its pods at once.
+type ProvisioningRequest struct {
+	Namespace         string
+	Name              string
+	UID               types.UID
+	CreationTimestamp metav1.Time
+	PodSets           []PodSet
+	// Conditions are the conditions of the request, by type.
+	Conditions map[string]metav1.Condition
+}
+
+// fromUnstructured parses a ProvisioningRequest object.
+func fromUnstructured(obj *unstructured.Unstructured) (*ProvisioningRequest, error) {
+	pr := &ProvisioningRequest{
+		Namespace:         obj.GetNamespace(),
+		Name:              obj.GetName(),
+		UID:               obj.GetUID(),
+		CreationTimestamp: obj.GetCreationTimestamp(),
+		Conditions:        make(map[string]metav1.Condition),
+	}
+	podSets, _, err := unstructured.NestedSlice(obj.Object, "spec", "podSets")
+	if err != nil {
+		return nil, err
+	}
+	for i, podSet := range podSets {
+		podSetMap, ok := podSet.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("podSets[%d] is not an object", i)
+		}
+		name, _, err := unstructured.NestedString(podSetMap, "podTemplateRef", "name")
+		if err != nil || name == "" {
+			return nil, fmt.Errorf("podSets[%d] has no podTemplateRef name", i)
+		}
+		count, _, err := unstructured.NestedInt64(podSetMap, "count")
+		if err != nil || count < 1 {
+			return nil, fmt.Errorf("podSets[%d] has no positive count", i)
+		}
+		pr.PodSets = append(pr.PodSets, PodSet{PodTemplateName: name, Count: int32(count)})
+	}
+	if len(pr.PodSets) == 0 {
+		return nil, fmt.Errorf("no podSets")
+	}
+	conditions, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		c := metav1.Condition{}
+		c.Type, _, _ = unstructured.NestedString(conditionMap, "type")
+		status, _, _ := unstructured.NestedString(conditionMap, "status")
+		c.Status = metav1.ConditionStatus(status)
+		c.Reason, _, _ = unstructured.NestedString(conditionMap, "reason")
+		c.Message, _, _ = unstructured.NestedString(conditionMap, "message")
+		if lastTransitionTime, _, _ := unstructured.NestedString(conditionMap, "lastTransitionTime"); lastTransitionTime != "" {
+			if t, err := time.Parse(time.RFC3339, lastTransitionTime); err == nil {
+				c.LastTransitionTime = metav1.NewTime(t)
+			}
+		}
+		pr.Conditions[c.Type] = c
+	}
+	return pr, nil
+}
+
+// Pending tells if capacity for the request wasn't provisioned yet, and the
+// request didn't fail.
+func (pr *ProvisioningRequest) Pending() bool {
+	return !pr.hasTrueCondition(Provisioned) && !pr.hasTrueCondition(Failed)
+}
+
+// ProvisionedWithin tells if capacity for the request was provisioned less
+// than the given duration ago.
+func (pr *ProvisioningRequest) ProvisionedWithin(d time.Duration, now time.Time) bool {
+	if !pr.hasTrueCondition(Provisioned) {
+		return false
+	}
+	return pr.Conditions[Provisioned].LastTransitionTime.Add(d).After(now)
+}
+
+func (pr *ProvisioningRequest) hasTrueCondition(conditionType string) bool {
+	condition, found := pr.Conditions[conditionType]
+	return found && condition.Status == metav1.ConditionTrue
+}
+
+// Pods returns the pods of the request, built from the given PodTemplates by
+// name. The pods are owned by the request, so that they are treated as
+// equivalent in scale-up simulations.
+func (pr *ProvisioningRequest) Pods(podTemplates map[string]*apiv1.PodTemplate) ([]*apiv1.Pod, error) {
+	controller := true
+	ownerRef := metav1.OwnerReference{
+		APIVersion: Resource.GroupVersion().String(),
+		Kind:       Kind,
+		Name:       pr.Name,
+		UID:        pr.UID,
+		Controller: &controller,
+	}
+	var pods []*apiv1.Pod
+	for i, podSet := range pr.PodSets {
+		podTemplate, found := podTemplates[podSet.PodTemplateName]
+		if !found {
+			return nil, fmt.Errorf("PodTemplate %s/%s not found", pr.Namespace, podSet.PodTemplateName)
+		}
+		for j := 0; j < int(podSet.Count); j++ {
+			pod := &apiv1.Pod{
+				ObjectMeta: *podTemplate.Template.ObjectMeta.DeepCopy(),
+				Spec:       *podTemplate.Template.Spec.DeepCopy(),
+			}
+			pod.Namespace = pr.Namespace
+			pod.Name = fmt.Sprintf("%s-%d-%d", pr.Name, i, j)
+			pod.UID = types.UID(fmt.Sprintf("%s-%d-%d", pr.UID, i, j))
+			pod.CreationTimestamp = pr.CreationTimestamp
+			pod.OwnerReferences = []metav1.OwnerReference{ownerRef}
+			if pod.Annotations == nil {
+				pod.Annotations = make(map[string]string)
+			}
+			pod.Annotations[PodAnnotation] = pr.Name
+			pod.Spec.NodeName = ""
+			pod.Status = apiv1.PodStatus{Phase: apiv1.PodPending}
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// requestKey returns the namespace/name key of the request the pod was
+// simulated for, if any.
+func requestKey(pod *apiv1.Pod) (string, bool) {
+	name, found := pod.Annotations[PodAnnotation]
+	if !found {
+		return "", false
+	}
+	return pod.Namespace + "/" + name, true
+}
+
+// sortByCreationTimestamp sorts requests oldest first.
+func sortByCreationTimestamp(requests []*ProvisioningRequest) {
+	sort.SliceStable(requests, func(i, j int) bool {
+		if !requests[i].CreationTimestamp.Equal(&requests[j].CreationTimestamp) {
+			return requests[i].CreationTimestamp.Before(&requests[j].CreationTimestamp)
+		}
+		return requests[i].Namespace+"/"+requests[i].Name < requests[j].Namespace+"/"+requests[j].Name
+	})
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioningrequest
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTestRequest(name string, created time.Time, podSets map[string]int64, conditions ...map[string]interface{}) *unstructured.Unstructured {
+	var podSetList []interface{}
+	for _, podTemplateName := range sortedKeys(podSets) {
+		podSetList = append(podSetList, map[string]interface{}{
+			"podTemplateRef": map[string]interface{}{"name": podTemplateName},
+			"count":          podSets[podTemplateName],
+		})
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": Resource.GroupVersion().String(),
+		"kind":       Kind,
+		"spec":       map[string]interface{}{"podSets": podSetList},
+	}}
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetUID(types.UID("uid-" + name))
+	obj.SetCreationTimestamp(metav1.NewTime(created))
+	if len(conditions) > 0 {
+		var conditionList []interface{}
+		for _, condition := range conditions {
+			conditionList = append(conditionList, condition)
+		}
+		_ = unstructured.SetNestedSlice(obj.Object, conditionList, "status", "conditions")
+	}
+	return obj
+}
+
+func buildTestCondition(conditionType string, lastTransitionTime time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"type":               conditionType,
+		"status":             "True",
+		"lastTransitionTime": lastTransitionTime.UTC().Format(time.RFC3339),
+	}
+}
+
+func buildTestPodTemplate(name string, cpu, mem int64) *apiv1.PodTemplate {
+	pod := BuildTestPod(name, cpu, mem)
+	return &apiv1.PodTemplate{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Template:   apiv1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}}, Spec: pod.Spec},
+	}
+}
+
+func sortedKeys(m map[string]int64) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestFromUnstructured(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	pr, err := fromUnstructured(buildTestRequest("pr", now, map[string]int64{"a": 2, "b": 1}))
+	assert.NoError(t, err)
+	assert.Equal(t, []PodSet{{PodTemplateName: "a", Count: 2}, {PodTemplateName: "b", Count: 1}}, pr.PodSets)
+	assert.True(t, pr.Pending())
+	assert.False(t, pr.ProvisionedWithin(BookingTime, now))
+
+	pr, err = fromUnstructured(buildTestRequest("pr", now, map[string]int64{"a": 2}, buildTestCondition(Provisioned, now.Add(-time.Minute))))
+	assert.NoError(t, err)
+	assert.False(t, pr.Pending())
+	assert.True(t, pr.ProvisionedWithin(BookingTime, now))
+	assert.False(t, pr.ProvisionedWithin(BookingTime, now.Add(BookingTime)))
+
+	pr, err = fromUnstructured(buildTestRequest("pr", now, map[string]int64{"a": 2}, buildTestCondition(Failed, now)))
+	assert.NoError(t, err)
+	assert.False(t, pr.Pending())
+	assert.False(t, pr.ProvisionedWithin(BookingTime, now))
+
+	_, err = fromUnstructured(buildTestRequest("pr", now, nil))
+	assert.Error(t, err)
+	_, err = fromUnstructured(buildTestRequest("pr", now, map[string]int64{"a": 0}))
+	assert.Error(t, err)
+}
+
+func TestPods(t *testing.T) {
+	pr, err := fromUnstructured(buildTestRequest("pr", time.Now(), map[string]int64{"a": 2, "b": 1}))
+	assert.NoError(t, err)
+
+	_, err = pr.Pods(map[string]*apiv1.PodTemplate{"a": buildTestPodTemplate("a", 100, 100)})
+	assert.Error(t, err)
+
+	pods, err := pr.Pods(map[string]*apiv1.PodTemplate{
+		"a": buildTestPodTemplate("a", 100, 100),
+		"b": buildTestPodTemplate("b", 200, 200),
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, pods, 3) {
+		assert.Equal(t, []string{"pr-0-0", "pr-0-1", "pr-1-0"}, []string{pods[0].Name, pods[1].Name, pods[2].Name})
+		for _, pod := range pods {
+			assert.Equal(t, "default", pod.Namespace)
+			assert.Equal(t, apiv1.PodPending, pod.Status.Phase)
+			key, found := requestKey(pod)
+			assert.True(t, found)
+			assert.Equal(t, "default/pr", key)
+			if assert.Len(t, pod.OwnerReferences, 1) {
+				assert.Equal(t, pr.UID, pod.OwnerReferences[0].UID)
+			}
+		}
+		assert.Equal(t, "a", pods[0].Labels["app"])
+		assert.Equal(t, "b", pods[2].Labels["app"])
+		assert.NotEqual(t, pods[0].UID, pods[1].UID)
+	}
+}
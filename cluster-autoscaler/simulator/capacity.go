@@ -0,0 +1,226 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/estimator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/scheduling"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// CapacityOptions configure a Capacity.
+type CapacityOptions struct {
+	// PredicateChecker runs the scheduler filters. Required; tools running
+	// outside of a cluster can use
+	// predicatechecker.NewSchedulerBasedPredicateChecker with an informer
+	// factory of a fake clientset.
+	PredicateChecker predicatechecker.PredicateChecker
+	// Listers are used by drainability rules, e.g. to check controllers of
+	// pods. Optional, rules needing them are skipped if nil.
+	Listers kube_util.ListerRegistry
+	// DeleteOptions configure which pods block removal of their node.
+	DeleteOptions options.NodeDeleteOptions
+	// DrainabilityRules are the rules deciding how pods are handled on node
+	// drain. rules.Default(DeleteOptions) is used if nil.
+	DrainabilityRules rules.Rules
+	// MaxNodesPerEstimation is the maximum number of new nodes a single
+	// estimation adds. 0 means no limit.
+	MaxNodesPerEstimation int
+	// MaxEstimationDuration is the maximum time a single estimation takes. 0
+	// means no limit.
+	MaxEstimationDuration time.Duration
+}
+
+// Capacity answers capacity planning questions about a cluster, such as how
+// many nodes of a given type pending pods need or which nodes can be removed,
+// using the same scheduling simulation, binpacking estimator and drain
+// simulation as the autoscaler, without running it. The cluster is set with
+// SetClusterState and isn't modified by the queries. Capacity isn't safe for
+// concurrent use.
+type Capacity struct {
+	snapshot            clustersnapshot.ClusterSnapshot
+	predicateChecker    predicatechecker.PredicateChecker
+	schedulingSimulator *scheduling.HintingSimulator
+	listers             kube_util.ListerRegistry
+	deleteOptions       options.NodeDeleteOptions
+	drainabilityRules   rules.Rules
+	estimatorBuilder    estimator.EstimatorBuilder
+}
+
+// Estimate is the answer to how many nodes of a type pods need.
+type Estimate struct {
+	// NodeCount is the number of new nodes needed.
+	NodeCount int
+	// OnExistingNodes are the pods which fit the nodes of the cluster.
+	OnExistingNodes []*apiv1.Pod
+	// OnNewNodes are the pods scheduled on the new nodes.
+	OnNewNodes []*apiv1.Pod
+	// Unschedulable are the pods which fit neither the nodes of the cluster
+	// nor a new node, or didn't fit within the estimation limits.
+	Unschedulable []*apiv1.Pod
+}
+
+// NewCapacity creates a new Capacity with an empty cluster.
+func NewCapacity(opts CapacityOptions) (*Capacity, error) {
+	if opts.PredicateChecker == nil {
+		return nil, fmt.Errorf("predicate checker not set")
+	}
+	drainabilityRules := opts.DrainabilityRules
+	if drainabilityRules == nil {
+		drainabilityRules = rules.Default(opts.DeleteOptions)
+	}
+	limiter := estimator.NewThresholdBasedEstimationLimiter([]estimator.Threshold{
+		estimator.NewStaticThreshold(opts.MaxNodesPerEstimation, opts.MaxEstimationDuration),
+	})
+	estimatorBuilder, err := estimator.NewEstimatorBuilder(estimator.BinpackingEstimatorName, limiter, estimator.NewDecreasingPodOrderer(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Capacity{
+		snapshot:            clustersnapshot.NewBasicClusterSnapshot(),
+		predicateChecker:    opts.PredicateChecker,
+		schedulingSimulator: scheduling.NewHintingSimulator(opts.PredicateChecker),
+		listers:             opts.Listers,
+		deleteOptions:       opts.DeleteOptions,
+		drainabilityRules:   drainabilityRules,
+		estimatorBuilder:    estimatorBuilder,
+	}, nil
+}
+
+// SetClusterState replaces the simulated cluster with the nodes and the pods
+// scheduled on them. Pods of unknown nodes are ignored.
+func (c *Capacity) SetClusterState(nodes []*apiv1.Node, scheduledPods []*apiv1.Pod) error {
+	c.snapshot.Clear()
+	c.schedulingSimulator.DropOldHints()
+	knownNodes := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if err := c.snapshot.AddNode(node); err != nil {
+			return fmt.Errorf("can't add node %s: %v", node.Name, err)
+		}
+		knownNodes[node.Name] = true
+	}
+	for _, pod := range scheduledPods {
+		if !knownNodes[pod.Spec.NodeName] {
+			continue
+		}
+		if err := c.snapshot.AddPod(pod, pod.Spec.NodeName); err != nil {
+			return fmt.Errorf("can't add pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// NodesNeeded estimates how many nodes built from nodeTemplate the pods need,
+// after the pods fitting the nodes of the cluster are scheduled there. The
+// node template is typically the NodeInfo of a node group's template node,
+// including its DaemonSet pods.
+func (c *Capacity) NodesNeeded(pods []*apiv1.Pod, nodeTemplate *schedulerframework.NodeInfo) (Estimate, error) {
+	c.snapshot.Fork()
+	defer c.snapshot.Revert()
+
+	var estimate Estimate
+	statuses, _, err := c.schedulingSimulator.TrySchedulePods(c.snapshot, pods, scheduling.ScheduleAnywhere, false)
+	if err != nil {
+		return Estimate{}, err
+	}
+	scheduled := make(map[*apiv1.Pod]bool, len(statuses))
+	for _, status := range statuses {
+		scheduled[status.Pod] = true
+		estimate.OnExistingNodes = append(estimate.OnExistingNodes, status.Pod)
+	}
+	var remaining []*apiv1.Pod
+	for _, pod := range pods {
+		if !scheduled[pod] {
+			remaining = append(remaining, pod)
+		}
+	}
+	if len(remaining) == 0 {
+		return estimate, nil
+	}
+	e := c.estimatorBuilder(c.predicateChecker, c.snapshot, estimator.NewEstimationContext(0, nil, 0))
+	estimate.NodeCount, estimate.OnNewNodes = e.Estimate(remaining, nodeTemplate, nil)
+	onNewNodes := make(map[*apiv1.Pod]bool, len(estimate.OnNewNodes))
+	for _, pod := range estimate.OnNewNodes {
+		onNewNodes[pod] = true
+	}
+	for _, pod := range remaining {
+		if !onNewNodes[pod] {
+			estimate.Unschedulable = append(estimate.Unschedulable, pod)
+		}
+	}
+	return estimate, nil
+}
+
+// RemovableNodes finds which of the candidate nodes can be removed, with their
+// pods moved to the other nodes of the cluster within the disruption budgets.
+// All nodes are candidates if candidates is nil. Candidates are simulated as
+// removed one after another, so pods of nodes found removable are moved before
+// the following candidates are checked, use up the budgets and aren't moved to
+// other removed nodes.
+func (c *Capacity) RemovableNodes(candidates []string, pdbs []*policyv1.PodDisruptionBudget, timestamp time.Time) ([]NodeToBeRemoved, []*UnremovableNode, error) {
+	nodeInfos, err := c.snapshot.NodeInfos().List()
+	if err != nil {
+		return nil, nil, err
+	}
+	destinations := make(map[string]bool, len(nodeInfos))
+	for _, nodeInfo := range nodeInfos {
+		destinations[nodeInfo.Node().Name] = true
+	}
+	if candidates == nil {
+		for _, nodeInfo := range nodeInfos {
+			candidates = append(candidates, nodeInfo.Node().Name)
+		}
+	}
+	tracker := pdb.NewBasicRemainingPdbTracker()
+	if err := tracker.SetPdbs(pdbs); err != nil {
+		return nil, nil, err
+	}
+
+	c.snapshot.Fork()
+	defer c.snapshot.Revert()
+
+	var nodesToRemove []NodeToBeRemoved
+	var unremovableNodes []*UnremovableNode
+	r := NewRemovalSimulator(c.listers, c.snapshot, c.predicateChecker, NewUsageTracker(), c.deleteOptions, c.drainabilityRules, true)
+	for _, nodeName := range candidates {
+		rn, urn := r.SimulateNodeRemoval(nodeName, destinations, timestamp, tracker)
+		if rn == nil {
+			if urn != nil {
+				unremovableNodes = append(unremovableNodes, urn)
+			}
+			continue
+		}
+		tracker.RemovePods(rn.PodsToDisrupt(c.deleteOptions.RespectDaemonSetPdbs))
+		delete(destinations, nodeName)
+		if err := c.snapshot.RemoveNode(nodeName); err != nil {
+			return nil, nil, err
+		}
+		nodesToRemove = append(nodesToRemove, *rn)
+	}
+	return nodesToRemove, unremovableNodes, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestCapacity(t *testing.T) {
+	var nodes []*apiv1.Node
+	for _, name := range []string{"n1", "n2", "n3"} {
+		node := BuildTestNode(name, 1000, 2000000)
+		SetNodeReadyState(node, true, time.Time{})
+		nodes = append(nodes, node)
+	}
+	ownerRefs := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	p1 := BuildScheduledTestPod("p1", 600, 1000, "n1")
+	p1.OwnerReferences = ownerRefs
+	p2 := BuildScheduledTestPod("p2", 300, 1000, "n2")
+	p2.OwnerReferences = ownerRefs
+
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+	c, err := NewCapacity(CapacityOptions{PredicateChecker: predicateChecker, DeleteOptions: testDeleteOptions()})
+	assert.NoError(t, err)
+	assert.NoError(t, c.SetClusterState(nodes, []*apiv1.Pod{p1, p2}))
+
+	template := schedulerframework.NewNodeInfo()
+	template.SetNode(BuildTestNode("template", 1000, 2000000))
+	pending := []*apiv1.Pod{
+		BuildTestPod("a", 800, 1000),
+		BuildTestPod("b", 800, 1000),
+		BuildTestPod("c", 800, 1000),
+		BuildTestPod("huge", 2000, 1000),
+	}
+	// Estimations don't modify the cluster, so repeating one gives the same answer.
+	for i := 0; i < 2; i++ {
+		estimate, err := c.NodesNeeded(pending, template)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, estimate.NodeCount)
+		assert.Len(t, estimate.OnExistingNodes, 1)
+		assert.Len(t, estimate.OnNewNodes, 2)
+		assert.Equal(t, []*apiv1.Pod{pending[3]}, estimate.Unschedulable)
+	}
+
+	// n3 is empty and p2 fits n1, but p1 doesn't fit anywhere once n2 and n3
+	// are removed.
+	toRemove, unremovable, err := c.RemovableNodes([]string{"n3", "n2", "n1"}, nil, time.Now())
+	assert.NoError(t, err)
+	var removable []string
+	for _, rn := range toRemove {
+		removable = append(removable, rn.Node.Name)
+	}
+	assert.Equal(t, []string{"n3", "n2"}, removable)
+	if assert.Len(t, unremovable, 1) {
+		assert.Equal(t, "n1", unremovable[0].Node.Name)
+		assert.Equal(t, NoPlaceToMovePods, unremovable[0].Reason)
+	}
+
+	// Removals aren't persisted either, so n1 is removable when checked alone.
+	toRemove, _, err = c.RemovableNodes([]string{"n1"}, nil, time.Now())
+	assert.NoError(t, err)
+	if assert.Len(t, toRemove, 1) {
+		assert.Equal(t, "n1", toRemove[0].Node.Name)
+	}
+
+	_, err = NewCapacity(CapacityOptions{})
+	assert.Error(t, err)
+}
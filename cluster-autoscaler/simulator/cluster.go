@@ -17,21 +17,28 @@ limitations under the License.
 package simulator
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	pdbrule "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/scheduling"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/tpu"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
 
 	klog "k8s.io/klog/v2"
 )
@@ -45,6 +52,35 @@ type NodeToBeRemoved struct {
 	// PodsToReschedule contains pods on the node that should be rescheduled elsewhere.
 	PodsToReschedule []*apiv1.Pod
 	DaemonSetPods    []*apiv1.Pod
+	// MaxDrainGracePeriod is the longest grace period that will be used when
+	// evicting PodsToReschedule, including per-pod overrides.
+	MaxDrainGracePeriod time.Duration
+	// EstimatedDrainDuration is the expected time it takes to drain the node,
+	// i.e. the longest drain duration of PodsToReschedule capped at
+	// NodeDrainTimeout. Drain durations of pods are their drain grace periods,
+	// or their pre-stop durations if they are annotated with one.
+	EstimatedDrainDuration time.Duration
+	// DeletionCost is the sum of pod deletion costs of PodsToReschedule, as
+	// set by the controller.kubernetes.io/pod-deletion-cost annotation.
+	DeletionCost int64
+	// SpreadSkewIncrease is by how much removing the node and rescheduling
+	// PodsToReschedule increases the skew of the topology spread constraints
+	// of these pods, summed over the constraints.
+	SpreadSkewIncrease int
+	// EvictionGroups are PodsToReschedule grouped in the order they are
+	// evicted in when the node is drained, see
+	// DrainSimulationResult.EvictionGroups.
+	EvictionGroups []EvictionGroup
+	// Destinations are the nodes PodsToReschedule were placed on by the
+	// simulation, keyed by pod namespace/name.
+	Destinations map[string]string
+}
+
+// PodsToDisrupt returns the pods whose removal uses up disruption budgets if
+// the node is removed: PodsToReschedule, and also DaemonSetPods if
+// respectDaemonSetPdbs is set.
+func (n *NodeToBeRemoved) PodsToDisrupt(respectDaemonSetPdbs bool) []*apiv1.Pod {
+	return podsToDisrupt(n.PodsToReschedule, n.DaemonSetPods, respectDaemonSetPdbs)
 }
 
 // UnremovableNode represents a node that can't be removed by CA.
@@ -52,6 +88,36 @@ type UnremovableNode struct {
 	Node        *apiv1.Node
 	Reason      UnremovableReason
 	BlockingPod *drain.BlockingPod
+	// BlockingPods are all pods blocking drain of the node, starting with
+	// BlockingPod. It is set only if Reason is BlockedByPod.
+	BlockingPods []*drain.BlockingPod
+	// UnschedulablePod is the first pod that couldn't be moved to any other
+	// node. It is set only if Reason is NoPlaceToMovePods.
+	UnschedulablePod *UnschedulablePod
+	// UnremovableSince is the time since which the node has been found
+	// unremovable in consecutive loops. It is set once the node is tracked as
+	// unremovable.
+	UnremovableSince time.Time
+	// ReasonHistory lists the recent reasons for which the node has been
+	// unremovable since UnremovableSince, oldest first. It is set once the
+	// node is tracked as unremovable.
+	ReasonHistory []UnremovableReasonChange
+}
+
+// UnremovableReasonChange records since when a node has been unremovable for
+// a reason.
+type UnremovableReasonChange struct {
+	Reason UnremovableReason
+	Since  time.Time
+}
+
+// UnschedulablePod contains information about a pod that can't be moved to
+// any other node.
+type UnschedulablePod struct {
+	Pod *apiv1.Pod
+	// Reason describes why the pod doesn't fit any of the other nodes, in the
+	// form of aggregated scheduler predicate failures.
+	Reason string
 }
 
 // UnremovableReason represents a reason why a node can't be removed by CA.
@@ -71,6 +137,8 @@ const (
 	NotUnneededLongEnough
 	// NotUnreadyLongEnough - node can't be removed because it wasn't unready for long enough.
 	NotUnreadyLongEnough
+	// OutsideScaleDownWindow - node can't be removed because its node group's scale-down window is closed.
+	OutsideScaleDownWindow
 	// NodeGroupMinSizeReached - node can't be removed because its node group is at its minimal size already.
 	NodeGroupMinSizeReached
 	// MinimalResourceLimitExceeded - node can't be removed because it would violate cluster-wide minimal resource limits.
@@ -89,8 +157,64 @@ const (
 	BlockedByPod
 	// UnexpectedError - node can't be removed because of an unexpected error.
 	UnexpectedError
+	// ProvidesInUseResourceClaim - node can't be removed because it's the only node providing devices of an in-use DRA resource claim.
+	ProvidesInUseResourceClaim
+	// ScaleDownDisabledBySelector - node can't be removed because it has a taint or labels configured to disable scale down.
+	ScaleDownDisabledBySelector
+	// BlockedByReservePolicy - node can't be removed because the resources left in the cluster, or in its zone, would violate the resource reserve policy.
+	BlockedByReservePolicy
 )
 
+var unremovableReasonNames = map[UnremovableReason]string{
+	NoReason:                     "NoReason",
+	ScaleDownDisabledAnnotation:  "ScaleDownDisabledAnnotation",
+	ScaleDownUnreadyDisabled:     "ScaleDownUnreadyDisabled",
+	NotAutoscaled:                "NotAutoscaled",
+	NotUnneededLongEnough:        "NotUnneededLongEnough",
+	NotUnreadyLongEnough:         "NotUnreadyLongEnough",
+	OutsideScaleDownWindow:       "OutsideScaleDownWindow",
+	NodeGroupMinSizeReached:      "NodeGroupMinSizeReached",
+	MinimalResourceLimitExceeded: "MinimalResourceLimitExceeded",
+	CurrentlyBeingDeleted:        "CurrentlyBeingDeleted",
+	NotUnderutilized:             "NotUnderutilized",
+	NotUnneededOtherReason:       "NotUnneededOtherReason",
+	RecentlyUnremovable:          "RecentlyUnremovable",
+	NoPlaceToMovePods:            "NoPlaceToMovePods",
+	BlockedByPod:                 "BlockedByPod",
+	UnexpectedError:              "UnexpectedError",
+	ProvidesInUseResourceClaim:   "ProvidesInUseResourceClaim",
+	ScaleDownDisabledBySelector:  "ScaleDownDisabledBySelector",
+	BlockedByReservePolicy:       "BlockedByReservePolicy",
+}
+
+// String returns the name of the UnremovableReason.
+func (r UnremovableReason) String() string {
+	if name, found := unremovableReasonNames[r]; found {
+		return name
+	}
+	return fmt.Sprintf("UnremovableReason(%d)", int(r))
+}
+
+// NodeRemovalSimulator simulates removing nodes from the cluster. Scale down
+// depends on it rather than on RemovalSimulator, so that alternative
+// implementations can be used e.g. in tests.
+type NodeRemovalSimulator interface {
+	// PodsToMove returns the pods to move elsewhere and the DaemonSet pods to
+	// evict if the node was drained, or the pods blocking its drain.
+	PodsToMove(nodeInfo *schedulerframework.NodeInfo, timestamp time.Time, remainingPdbTracker pdb.RemainingPdbTracker) *DrainSimulationResult
+	// SimulateNodeRemoval simulates removing a node from the cluster.
+	SimulateNodeRemoval(nodeName string, destinationMap map[string]bool, timestamp time.Time, remainingPdbTracker pdb.RemainingPdbTracker) (*NodeToBeRemoved, *UnremovableNode)
+	// FindNodesToRemove finds candidates which can be removed one after another.
+	FindNodesToRemove(candidates []string, destinations []string, timestamp time.Time, remainingPdbTracker pdb.RemainingPdbTracker) ([]NodeToBeRemoved, []*UnremovableNode)
+	// FindEmptyNodesToRemove finds candidates without pods to move.
+	FindEmptyNodesToRemove(candidates []string, timestamp time.Time) []string
+	// PrecomputeDrainability evaluates drainability of the nodes in parallel
+	// ahead of SimulateNodeRemoval calls for the same timestamp.
+	PrecomputeDrainability(nodeNames []string, timestamp time.Time, remainingPdbTracker pdb.RemainingPdbTracker, parallelism int)
+	// DropOldHints drops scheduling hints which weren't used recently.
+	DropOldHints()
+}
+
 // RemovalSimulator is a helper object for simulating node removal scenarios.
 type RemovalSimulator struct {
 	listers             kube_util.ListerRegistry
@@ -99,7 +223,25 @@ type RemovalSimulator struct {
 	canPersist          bool
 	deleteOptions       options.NodeDeleteOptions
 	drainabilityRules   rules.Rules
+	predicateChecker    predicatechecker.PredicateChecker
 	schedulingSimulator *scheduling.HintingSimulator
+	// maxGracefulTerminationSec returns MaxGracefulTerminationSec of a node,
+	// if it can differ from the one in deleteOptions.
+	maxGracefulTerminationSec func(node *apiv1.Node) int
+	// podsToMove determines the pods to move from drained nodes.
+	podsToMove PodsToMoveFunc
+
+	// drainResults contains drainability of nodes precomputed for
+	// drainResultsTimestamp, see PrecomputeDrainability.
+	drainResults          map[string]drainResult
+	drainResultsTimestamp time.Time
+}
+
+// drainResult is the precomputed drain simulation of a single node.
+type drainResult struct {
+	*DrainSimulationResult
+	// budgetChecks are the sets of pods checked against disruption budgets.
+	budgetChecks [][]*apiv1.Pod
 }
 
 // NewRemovalSimulator returns a new RemovalSimulator.
@@ -112,11 +254,35 @@ func NewRemovalSimulator(listers kube_util.ListerRegistry, clusterSnapshot clust
 		canPersist:          persistSuccessfulSimulations,
 		deleteOptions:       deleteOptions,
 		drainabilityRules:   drainabilityRules,
+		predicateChecker:    predicateChecker,
 		schedulingSimulator: scheduling.NewHintingSimulator(predicateChecker),
+		podsToMove:          SimulateDrain,
 	}
 }
 
-// FindNodesToRemove finds nodes that can be removed.
+var _ NodeRemovalSimulator = &RemovalSimulator{}
+
+// SetPodsToMoveFunc makes the simulator determine pods to move from drained
+// nodes with f instead of SimulateDrain. Nil restores SimulateDrain.
+func (r *RemovalSimulator) SetPodsToMoveFunc(f PodsToMoveFunc) {
+	if f == nil {
+		f = SimulateDrain
+	}
+	r.podsToMove = f
+}
+
+// SetMaxGracefulTerminationSecGetter makes the simulator estimate drain
+// duration of nodes using the MaxGracefulTerminationSec returned by getter,
+// e.g. the one configured for the node group of the node, instead of the one
+// from delete options.
+func (r *RemovalSimulator) SetMaxGracefulTerminationSecGetter(getter func(node *apiv1.Node) int) {
+	r.maxGracefulTerminationSec = getter
+}
+
+// FindNodesToRemove finds nodes that can be removed. Nodes are simulated as
+// drained one after another, so pods of nodes found removable use up the
+// disruption budgets for the following ones. remainingPdbTracker is not
+// modified.
 func (r *RemovalSimulator) FindNodesToRemove(
 	candidates []string,
 	destinations []string,
@@ -128,9 +294,19 @@ func (r *RemovalSimulator) FindNodesToRemove(
 		destinationMap[destination] = true
 	}
 
+	if remainingPdbTracker == nil {
+		remainingPdbTracker = pdb.NewBasicRemainingPdbTracker()
+	}
+	drainCtx := &drainability.DrainContext{
+		RemainingPdbTracker: remainingPdbTracker.Clone(),
+		Listers:             r.listers,
+		Timestamp:           timestamp,
+		DeleteOptions:       r.deleteOptions,
+	}
 	for _, nodeName := range candidates {
-		rn, urn := r.SimulateNodeRemoval(nodeName, destinationMap, timestamp, remainingPdbTracker)
+		rn, urn := r.SimulateNodeRemoval(nodeName, destinationMap, drainCtx.Timestamp, drainCtx.RemainingPdbTracker)
 		if rn != nil {
+			drainCtx.MarkForEviction(rn.PodsToDisrupt(r.deleteOptions.RespectDaemonSetPdbs)...)
 			nodesToRemove = append(nodesToRemove, *rn)
 		} else if urn != nil {
 			unremovableNodes = append(unremovableNodes, urn)
@@ -160,30 +336,192 @@ func (r *RemovalSimulator) SimulateNodeRemoval(
 		return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: UnexpectedError}
 	}
 
-	podsToRemove, daemonSetPods, blockingPod, err := GetPodsToMove(nodeInfo, r.deleteOptions, r.drainabilityRules, r.listers, remainingPdbTracker, timestamp)
-	if err != nil {
+	simulation := r.PodsToMove(nodeInfo, timestamp, remainingPdbTracker)
+	if err := simulation.Err; err != nil {
 		klog.V(2).Infof("node %s cannot be removed: %v", nodeName, err)
-		if blockingPod != nil {
-			return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: BlockedByPod, BlockingPod: blockingPod}
+		if blockingPod := simulation.BlockingPod; blockingPod != nil {
+			blockingPods := simulation.BlockingPods
+			if len(blockingPods) == 0 {
+				blockingPods = []*drain.BlockingPod{blockingPod}
+			}
+			return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: BlockedByPod, BlockingPod: blockingPod, BlockingPods: blockingPods}
 		}
 		return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: UnexpectedError}
 	}
+	podsToRemove, daemonSetPods := simulation.PodsToMove, simulation.DaemonSetPods
 
+	if r.providesInUseResourceClaim(nodeInfo, podsToRemove) {
+		return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: ProvidesInUseResourceClaim}
+	}
+
+	constraints := spreadConstraints(podsToRemove)
+	skewsBefore := r.spreadSkews(constraints, "")
+	var skewsAfter []int
+	var unschedulablePod *UnschedulablePod
+	var destinations map[string]string
 	err = r.withForkedSnapshot(func() error {
-		return r.findPlaceFor(nodeName, podsToRemove, destinationMap, timestamp)
+		var err error
+		destinations, unschedulablePod, err = r.findPlaceFor(nodeName, podsToRemove, destinationMap, timestamp)
+		if err == nil {
+			skewsAfter = r.spreadSkews(constraints, nodeName)
+		}
+		return err
 	})
 	if err != nil {
 		klog.V(2).Infof("node %s is not suitable for removal: %v", nodeName, err)
-		return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: NoPlaceToMovePods}
+		return nil, &UnremovableNode{Node: nodeInfo.Node(), Reason: NoPlaceToMovePods, UnschedulablePod: unschedulablePod}
 	}
 	klog.V(2).Infof("node %s may be removed", nodeName)
+	// Options are resolved for the node the same way as in the drain simulation.
+	deleteOptions := r.deleteOptions.ForNode(nodeInfo.Node())
+	maxGracefulTerminationSec := deleteOptions.MaxGracefulTerminationSec
+	if r.maxGracefulTerminationSec != nil {
+		maxGracefulTerminationSec = r.maxGracefulTerminationSec(nodeInfo.Node())
+	}
+	maxGracefulTerminationSec = deleteOptions.MaxGracefulTerminationSecForOS(nodeInfo.Node(), maxGracefulTerminationSec)
+	gracePeriod := maxDrainGracePeriod(podsToRemove, maxGracefulTerminationSec)
 	return &NodeToBeRemoved{
-		Node:             nodeInfo.Node(),
-		PodsToReschedule: podsToRemove,
-		DaemonSetPods:    daemonSetPods,
+		Node:                   nodeInfo.Node(),
+		PodsToReschedule:       podsToRemove,
+		DaemonSetPods:          daemonSetPods,
+		MaxDrainGracePeriod:    gracePeriod,
+		EstimatedDrainDuration: estimateDrainDuration(podsToRemove, maxGracefulTerminationSec, deleteOptions.NodeDrainTimeout),
+		DeletionCost:           deletionCost(podsToRemove),
+		SpreadSkewIncrease:     spreadSkewIncrease(skewsBefore, skewsAfter),
+		EvictionGroups:         simulation.EvictionGroups,
+		Destinations:           destinations,
 	}, nil
 }
 
+// PrecomputeDrainability evaluates drainability of the given nodes using up to
+// parallelism goroutines, so that subsequent SimulateNodeRemoval calls for the
+// same timestamp don't evaluate it sequentially. Disruption budgets used up in
+// the meantime are re-checked by SimulateNodeRemoval. remainingPdbTracker is
+// not modified. Nothing is precomputed if parallelism is lower than 2.
+func (r *RemovalSimulator) PrecomputeDrainability(nodeNames []string, timestamp time.Time, remainingPdbTracker pdb.RemainingPdbTracker, parallelism int) {
+	r.drainResults = nil
+	if parallelism < 2 {
+		return
+	}
+	// Cluster snapshot isn't goroutine-safe, so NodeInfos are retrieved upfront.
+	nodeInfos := make([]*schedulerframework.NodeInfo, 0, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		nodeInfo, err := r.clusterSnapshot.NodeInfos().Get(nodeName)
+		if err != nil {
+			// SimulateNodeRemoval will report the error.
+			continue
+		}
+		nodeInfos = append(nodeInfos, nodeInfo)
+	}
+	if remainingPdbTracker == nil {
+		remainingPdbTracker = pdb.NewBasicRemainingPdbTracker()
+	}
+	tracker := pdb.NewThreadSafeRemainingPdbTracker(remainingPdbTracker.Clone())
+	results := make([]drainResult, len(nodeInfos))
+	workqueue.ParallelizeUntil(context.Background(), parallelism, len(nodeInfos), func(i int) {
+		recorder := &budgetCheckRecorder{RemainingPdbTracker: tracker}
+		results[i] = drainResult{
+			DrainSimulationResult: r.podsToMove(nodeInfos[i], r.deleteOptions, r.drainabilityRules, r.listers, recorder, timestamp),
+			budgetChecks:          recorder.checks,
+		}
+	})
+	r.drainResults = make(map[string]drainResult, len(nodeInfos))
+	r.drainResultsTimestamp = timestamp
+	for i, nodeInfo := range nodeInfos {
+		r.drainResults[nodeInfo.Node().Name] = results[i]
+	}
+}
+
+// PodsToMove returns the pods to move elsewhere and the DaemonSet pods to
+// evict if a given node was drained, or the pods blocking its drain. The result
+// precomputed by PrecomputeDrainability is used if there is one.
+func (r *RemovalSimulator) PodsToMove(nodeInfo *schedulerframework.NodeInfo, timestamp time.Time, remainingPdbTracker pdb.RemainingPdbTracker) *DrainSimulationResult {
+	nodeName := nodeInfo.Node().Name
+	result, found := r.drainResults[nodeName]
+	if !found || !r.drainResultsTimestamp.Equal(timestamp) {
+		return r.podsToMove(nodeInfo, r.deleteOptions, r.drainabilityRules, r.listers, remainingPdbTracker, timestamp)
+	}
+	delete(r.drainResults, nodeName)
+	if result.Err == nil && remainingPdbTracker != nil {
+		// Budgets could have been used up by nodes simulated after the result was precomputed.
+		for _, pods := range result.budgetChecks {
+			if canRemove, _, blockingPod := remainingPdbTracker.CanRemovePods(pods); !canRemove {
+				blockingPod.Rule = pdbrule.New().Name()
+				blockingPod.Remediation = blockingPod.Reason.Remediation()
+				return &DrainSimulationResult{
+					BlockingPod:  blockingPod,
+					BlockingPods: []*drain.BlockingPod{blockingPod},
+					Verdicts:     result.Verdicts,
+					Err:          fmt.Errorf("not enough pod disruption budget to move %s/%s", blockingPod.Pod.Namespace, blockingPod.Pod.Name),
+				}
+			}
+		}
+	}
+	return result.DrainSimulationResult
+}
+
+// budgetCheckRecorder is a RemainingPdbTracker recording the pods checked
+// against disruption budgets.
+type budgetCheckRecorder struct {
+	pdb.RemainingPdbTracker
+	checks [][]*apiv1.Pod
+}
+
+// CanRemovePods checks if the set of pods can be removed and records the check.
+func (r *budgetCheckRecorder) CanRemovePods(pods []*apiv1.Pod) (canRemove, inParallel bool, blockingPod *drain.BlockingPod) {
+	r.checks = append(r.checks, pods)
+	return r.RemainingPdbTracker.CanRemovePods(pods)
+}
+
+func maxDrainGracePeriod(pods []*apiv1.Pod, maxGracefulTerminationSec int) time.Duration {
+	var result time.Duration
+	for _, pod := range pods {
+		if gracePeriod := time.Duration(drain.GetPodDrainGracePeriod(pod, maxGracefulTerminationSec)) * time.Second; gracePeriod > result {
+			result = gracePeriod
+		}
+	}
+	return result
+}
+
+// estimateDrainDuration estimates how long it takes to drain a node running the
+// given pods. Pods are evicted in parallel, so it is the longest drain duration
+// of the pods. Pods remaining after nodeDrainTimeout are force deleted, so it
+// bounds the drain duration.
+func estimateDrainDuration(pods []*apiv1.Pod, maxGracefulTerminationSec int, nodeDrainTimeout time.Duration) time.Duration {
+	var result time.Duration
+	for _, pod := range pods {
+		if duration := time.Duration(drain.GetPodDrainDuration(pod, maxGracefulTerminationSec)) * time.Second; duration > result {
+			result = duration
+		}
+	}
+	if nodeDrainTimeout > 0 && nodeDrainTimeout < result {
+		return nodeDrainTimeout
+	}
+	return result
+}
+
+func deletionCost(pods []*apiv1.Pod) int64 {
+	var cost int64
+	for _, pod := range pods {
+		cost += int64(pod_util.DeletionCost(pod))
+	}
+	return cost
+}
+
+// spreadSkews returns the skews of the constraints in the cluster snapshot,
+// ignoring excludedNode.
+func (r *RemovalSimulator) spreadSkews(constraints []spreadConstraint, excludedNode string) []int {
+	if len(constraints) == 0 {
+		return nil
+	}
+	nodeInfos, err := r.clusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Can't list nodes from snapshot to compute topology spread, err: %v", err)
+		return nil
+	}
+	return spreadSkews(nodeInfos, constraints, excludedNode)
+}
+
 // FindEmptyNodesToRemove finds empty nodes that can be removed.
 func (r *RemovalSimulator) FindEmptyNodesToRemove(candidates []string, timestamp time.Time) []string {
 	result := make([]string, 0)
@@ -194,14 +532,42 @@ func (r *RemovalSimulator) FindEmptyNodesToRemove(candidates []string, timestamp
 			continue
 		}
 		// Should block on all pods
-		podsToRemove, _, _, err := GetPodsToMove(nodeInfo, r.deleteOptions, r.drainabilityRules, nil, nil, timestamp)
-		if err == nil && len(podsToRemove) == 0 {
+		simulation := r.podsToMove(nodeInfo, r.deleteOptions, r.drainabilityRules, nil, nil, timestamp)
+		if simulation.Err == nil && len(simulation.PodsToMove) == 0 && !r.providesInUseResourceClaim(nodeInfo, nil) {
 			result = append(result, node)
 		}
 	}
 	return result
 }
 
+// providesInUseResourceClaim tells if removing the node would make devices of
+// an in-use DRA resource claim unavailable. Errors are treated as in use.
+func (r *RemovalSimulator) providesInUseResourceClaim(nodeInfo *schedulerframework.NodeInfo, podsToRemove []*apiv1.Pod) bool {
+	claims := r.clusterSnapshot.ResourceClaims()
+	if claims.Len() == 0 {
+		return false
+	}
+	nodeInfos, err := r.clusterSnapshot.NodeInfos().List()
+	if err != nil {
+		klog.Errorf("Failed to list nodes from snapshot: %v", err)
+		return true
+	}
+	nodes := make([]*apiv1.Node, 0, len(nodeInfos))
+	for _, ni := range nodeInfos {
+		nodes = append(nodes, ni.Node())
+	}
+	claim, err := claims.InUseClaimProvidedBy(nodeInfo.Node(), nodes, podsToRemove)
+	if err != nil {
+		klog.Warningf("Failed to check resource claims provided by node %s: %v", nodeInfo.Node().Name, err)
+		return true
+	}
+	if claim != nil {
+		klog.V(2).Infof("node %s cannot be removed: it provides devices of in-use resource claim %s/%s", nodeInfo.Node().Name, claim.Namespace, claim.Name)
+		return true
+	}
+	return false
+}
+
 func (r *RemovalSimulator) withForkedSnapshot(f func() error) (err error) {
 	r.clusterSnapshot.Fork()
 	defer func() {
@@ -218,7 +584,10 @@ func (r *RemovalSimulator) withForkedSnapshot(f func() error) (err error) {
 	return err
 }
 
-func (r *RemovalSimulator) findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes map[string]bool, timestamp time.Time) error {
+// findPlaceFor simulates moving pods from removedNode to other nodes and
+// returns the nodes the pods were moved to, keyed by pod namespace/name. If
+// some pod can't be moved, it is returned along with the reason why.
+func (r *RemovalSimulator) findPlaceFor(removedNode string, pods []*apiv1.Pod, nodes map[string]bool, timestamp time.Time) (map[string]string, *UnschedulablePod, error) {
 	isCandidateNode := func(nodeInfo *schedulerframework.NodeInfo) bool {
 		return nodeInfo.Node().Name != removedNode && nodes[nodeInfo.Node().Name]
 	}
@@ -242,16 +611,81 @@ func (r *RemovalSimulator) findPlaceFor(removedNode string, pods []*apiv1.Pod, n
 
 	statuses, _, err := r.schedulingSimulator.TrySchedulePods(r.clusterSnapshot, newpods, isCandidateNode, true)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	if len(statuses) != len(newpods) {
-		return fmt.Errorf("can reschedule only %d out of %d pods", len(statuses), len(newpods))
+		// Scheduling stops at the first pod that doesn't fit, so the snapshot
+		// still reflects the state in which it was evaluated.
+		failedPod := newpods[len(statuses)]
+		unschedulablePod := &UnschedulablePod{Pod: pods[len(statuses)], Reason: r.unschedulableReason(failedPod, isCandidateNode)}
+		return nil, unschedulablePod, fmt.Errorf("can reschedule only %d out of %d pods, pod %s/%s doesn't fit: %s", len(statuses), len(newpods), failedPod.Namespace, failedPod.Name, unschedulablePod.Reason)
+	}
+	failedPod, reason, err := r.checkHardSpreadConstraints(statuses)
+	if err != nil {
+		return nil, nil, err
+	}
+	if failedPod != nil {
+		unschedulablePod := &UnschedulablePod{Pod: failedPod, Reason: reason}
+		for _, pod := range pods {
+			if pod.Namespace == failedPod.Namespace && pod.Name == failedPod.Name {
+				unschedulablePod.Pod = pod
+			}
+		}
+		return nil, unschedulablePod, fmt.Errorf("pod %s/%s would violate its topology spread constraints: %s", failedPod.Namespace, failedPod.Name, reason)
 	}
 
+	var destinations map[string]string
 	for _, status := range statuses {
 		r.usageTracker.RegisterUsage(removedNode, status.NodeName, timestamp)
+		if destinations == nil {
+			destinations = make(map[string]string, len(statuses))
+		}
+		destinations[status.Pod.Namespace+"/"+status.Pod.Name] = status.NodeName
+	}
+	return destinations, nil, nil
+}
+
+// unschedulableReason aggregates the reasons why the pod doesn't fit any of
+// the candidate nodes, similarly to scheduler FailedScheduling events.
+func (r *RemovalSimulator) unschedulableReason(pod *apiv1.Pod, isCandidateNode func(*schedulerframework.NodeInfo) bool) string {
+	nodeInfos, err := r.clusterSnapshot.NodeInfos().List()
+	if err != nil {
+		return fmt.Sprintf("can't list nodes: %v", err)
+	}
+	checked := 0
+	counts := make(map[string]int)
+	for _, nodeInfo := range nodeInfos {
+		if !isCandidateNode(nodeInfo) {
+			continue
+		}
+		checked++
+		if nodeInfo.Node().Spec.Unschedulable {
+			counts["node(s) were unschedulable"]++
+			continue
+		}
+		predicateErr := r.predicateChecker.CheckPredicates(r.clusterSnapshot, pod, nodeInfo.Node().Name)
+		if predicateErr == nil {
+			// Shouldn't happen, as the pod was just found not to fit.
+			counts["node(s) didn't fit for unknown reason"]++
+			continue
+		}
+		reasons := predicateErr.Reasons()
+		if len(reasons) == 0 {
+			reasons = []string{predicateErr.Message()}
+		}
+		for _, reason := range reasons {
+			counts[reason]++
+		}
+	}
+	if checked == 0 {
+		return "no other nodes are available"
+	}
+	reasons := make([]string, 0, len(counts))
+	for reason, count := range counts {
+		reasons = append(reasons, fmt.Sprintf("%d %s", count, reason))
 	}
-	return nil
+	sort.Strings(reasons)
+	return fmt.Sprintf("0/%d nodes are available: %s", checked, strings.Join(reasons, ", "))
 }
 
 // DropOldHints drops old scheduling hints.
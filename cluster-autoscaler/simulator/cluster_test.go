@@ -22,16 +22,21 @@ import (
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/dynamicresources"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	resourcev1alpha2 "k8s.io/api/resource/v1alpha2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
@@ -124,6 +129,7 @@ func TestFindNodesToRemove(t *testing.T) {
 
 	pod2 := BuildTestPod("p2", 100, 100000)
 	pod2.OwnerReferences = ownerRefs
+	pod2.Annotations = map[string]string{drain.PodDrainGracePeriodKey: "120"}
 	pod2.Spec.NodeName = "n2"
 	drainableNodeInfo.AddPod(pod2)
 
@@ -139,8 +145,11 @@ func TestFindNodesToRemove(t *testing.T) {
 		Node: emptyNode,
 	}
 	drainableNodeToRemove := NodeToBeRemoved{
-		Node:             drainableNode,
-		PodsToReschedule: []*apiv1.Pod{pod1, pod2},
+		Node:                   drainableNode,
+		PodsToReschedule:       []*apiv1.Pod{pod1, pod2},
+		MaxDrainGracePeriod:    120 * time.Second,
+		EstimatedDrainDuration: 120 * time.Second,
+		EvictionGroups:         []EvictionGroup{{Pods: []*apiv1.Pod{pod1, pod2}}},
 	}
 
 	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
@@ -160,7 +169,7 @@ func TestFindNodesToRemove(t *testing.T) {
 			pods:        []*apiv1.Pod{pod1, pod2},
 			candidates:  []string{drainableNode.Name},
 			allNodes:    []*apiv1.Node{drainableNode},
-			unremovable: []*UnremovableNode{{Node: drainableNode, Reason: NoPlaceToMovePods}},
+			unremovable: []*UnremovableNode{{Node: drainableNode, Reason: NoPlaceToMovePods, UnschedulablePod: &UnschedulablePod{Pod: pod1, Reason: "no other nodes are available"}}},
 		},
 		{
 			name:        "drainable node, and a mostly empty node that can take its pods",
@@ -168,14 +177,14 @@ func TestFindNodesToRemove(t *testing.T) {
 			candidates:  []string{drainableNode.Name, nonDrainableNode.Name},
 			allNodes:    []*apiv1.Node{drainableNode, nonDrainableNode},
 			toRemove:    []NodeToBeRemoved{drainableNodeToRemove},
-			unremovable: []*UnremovableNode{{Node: nonDrainableNode, Reason: BlockedByPod, BlockingPod: &drain.BlockingPod{Pod: pod3, Reason: drain.NotReplicated}}},
+			unremovable: []*UnremovableNode{{Node: nonDrainableNode, Reason: BlockedByPod, BlockingPod: &drain.BlockingPod{Pod: pod3, Reason: drain.NotReplicated, Rule: "Replicated", Remediation: drain.AddSafeToEvictAnnotation}, BlockingPods: []*drain.BlockingPod{{Pod: pod3, Reason: drain.NotReplicated, Rule: "Replicated", Remediation: drain.AddSafeToEvictAnnotation}}}},
 		},
 		{
 			name:        "drainable node, and a full node that cannot fit anymore pods",
 			pods:        []*apiv1.Pod{pod1, pod2, pod4},
 			candidates:  []string{drainableNode.Name},
 			allNodes:    []*apiv1.Node{drainableNode, fullNode},
-			unremovable: []*UnremovableNode{{Node: drainableNode, Reason: NoPlaceToMovePods}},
+			unremovable: []*UnremovableNode{{Node: drainableNode, Reason: NoPlaceToMovePods, UnschedulablePod: &UnschedulablePod{Pod: pod1, Reason: "0/1 nodes are available: 1 Insufficient cpu"}}},
 		},
 		{
 			name:       "4 nodes, 1 empty, 1 drainable",
@@ -196,12 +205,429 @@ func TestFindNodesToRemove(t *testing.T) {
 			r := NewRemovalSimulator(registry, clusterSnapshot, predicateChecker, tracker, testDeleteOptions(), nil, false)
 			toRemove, unremovable := r.FindNodesToRemove(test.candidates, destinations, time.Now(), nil)
 			fmt.Printf("Test scenario: %s, found len(toRemove)=%v, expected len(test.toRemove)=%v\n", test.name, len(toRemove), len(test.toRemove))
+			// Destinations depend on the order in which nodes are tried, only check they're valid.
+			for i := range toRemove {
+				assert.Len(t, toRemove[i].Destinations, len(toRemove[i].PodsToReschedule))
+				for _, pod := range toRemove[i].PodsToReschedule {
+					assert.NotEqual(t, toRemove[i].Node.Name, toRemove[i].Destinations[pod.Namespace+"/"+pod.Name])
+				}
+				toRemove[i].Destinations = nil
+			}
 			assert.Equal(t, test.toRemove, toRemove)
 			assert.Equal(t, test.unremovable, unremovable)
 		})
 	}
 }
 
+func TestFindNodesToRemoveSharedDisruptionBudget(t *testing.T) {
+	replicas := int32(5)
+	rsLister, err := kube_util.NewTestReplicaSetLister([]*appsv1.ReplicaSet{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default"},
+			Spec:       appsv1.ReplicaSetSpec{Replicas: &replicas},
+		},
+	})
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, rsLister, nil)
+
+	var nodes []*apiv1.Node
+	var pods []*apiv1.Pod
+	for _, name := range []string{"n1", "n2", "n3"} {
+		node := BuildTestNode(name, 1000, 2000000)
+		SetNodeReadyState(node, true, time.Time{})
+		nodes = append(nodes, node)
+		if name == "n3" {
+			continue
+		}
+		pod := BuildTestPod("p-"+name, 100, 100000)
+		pod.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+		pod.Labels = map[string]string{"app": "a"}
+		pod.Spec.NodeName = name
+		pods = append(pods, pod)
+	}
+	budget := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "pdb", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+	}
+	remainingPdbTracker := pdb.NewBasicRemainingPdbTracker()
+	assert.NoError(t, remainingPdbTracker.SetPdbs([]*policyv1.PodDisruptionBudget{budget}))
+
+	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, clusterSnapshot, nodes, pods)
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+	r := NewRemovalSimulator(registry, clusterSnapshot, predicateChecker, NewUsageTracker(), testDeleteOptions(), nil, false)
+
+	toRemove, unremovable := r.FindNodesToRemove([]string{"n1", "n2"}, []string{"n1", "n2", "n3"}, time.Now(), remainingPdbTracker)
+	if assert.Len(t, toRemove, 1) {
+		assert.Equal(t, "n1", toRemove[0].Node.Name)
+	}
+	if assert.Len(t, unremovable, 1) {
+		assert.Equal(t, "n2", unremovable[0].Node.Name)
+		assert.Equal(t, BlockedByPod, unremovable[0].Reason)
+		assert.Equal(t, drain.NotEnoughPdb, unremovable[0].BlockingPod.Reason)
+	}
+	// The budget used up by the simulation isn't reflected in the tracker passed in.
+	assert.Equal(t, int32(1), remainingPdbTracker.GetPdbs()[0].Status.DisruptionsAllowed)
+}
+
+func TestFindNodesToRemoveDaemonSetDisruptionBudget(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		respectPdbs     bool
+		wantToRemove    []string
+		wantUnremovable []string
+	}{
+		{
+			name:            "budgets of daemon set pods respected",
+			respectPdbs:     true,
+			wantToRemove:    []string{"n1"},
+			wantUnremovable: []string{"n2"},
+		},
+		{
+			name:         "budgets of daemon set pods not respected",
+			wantToRemove: []string{"n1", "n2"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var nodes []*apiv1.Node
+			var pods []*apiv1.Pod
+			for _, name := range []string{"n1", "n2", "n3"} {
+				node := BuildTestNode(name, 1000, 2000000)
+				SetNodeReadyState(node, true, time.Time{})
+				nodes = append(nodes, node)
+				pod := BuildScheduledTestPod("agent-"+name, 100, 100000, name)
+				pod.Labels = map[string]string{"app": "agent"}
+				pod.OwnerReferences = GenerateOwnerReferences("agent", "DaemonSet", "apps/v1", "")
+				pods = append(pods, pod)
+			}
+			remainingPdbTracker := pdb.NewBasicRemainingPdbTracker()
+			assert.NoError(t, remainingPdbTracker.SetPdbs([]*policyv1.PodDisruptionBudget{{
+				ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "agent"}}},
+				Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+			}}))
+
+			clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+			clustersnapshot.InitializeClusterSnapshotOrDie(t, clusterSnapshot, nodes, pods)
+			predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+			assert.NoError(t, err)
+			deleteOptions := testDeleteOptions()
+			deleteOptions.RespectDaemonSetPdbs = tc.respectPdbs
+			r := NewRemovalSimulator(nil, clusterSnapshot, predicateChecker, NewUsageTracker(), deleteOptions, nil, false)
+
+			toRemove, unremovable := r.FindNodesToRemove([]string{"n1", "n2"}, []string{"n1", "n2", "n3"}, time.Now(), remainingPdbTracker)
+			var gotToRemove, gotUnremovable []string
+			for _, rn := range toRemove {
+				gotToRemove = append(gotToRemove, rn.Node.Name)
+			}
+			for _, urn := range unremovable {
+				gotUnremovable = append(gotUnremovable, urn.Node.Name)
+				assert.Equal(t, BlockedByPod, urn.Reason)
+				if assert.NotNil(t, urn.BlockingPod) {
+					assert.Equal(t, drain.NotEnoughPdb, urn.BlockingPod.Reason)
+				}
+			}
+			assert.Equal(t, tc.wantToRemove, gotToRemove)
+			assert.Equal(t, tc.wantUnremovable, gotUnremovable)
+		})
+	}
+}
+
+func TestPrecomputeDrainability(t *testing.T) {
+	replicas := int32(5)
+	rsLister, err := kube_util.NewTestReplicaSetLister([]*appsv1.ReplicaSet{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default"},
+			Spec:       appsv1.ReplicaSetSpec{Replicas: &replicas},
+		},
+	})
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, rsLister, nil)
+	ownerRefs := GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+
+	var nodes []*apiv1.Node
+	var pods []*apiv1.Pod
+	for i := 0; i < 4; i++ {
+		node := BuildTestNode(fmt.Sprintf("n%d", i), 1000, 2000000)
+		SetNodeReadyState(node, true, time.Time{})
+		nodes = append(nodes, node)
+		if i == 0 {
+			// Destination for the pods.
+			continue
+		}
+		pod := BuildTestPod(fmt.Sprintf("p%d", i), 100, 100000)
+		pod.Spec.NodeName = node.Name
+		if i < 3 {
+			pod.OwnerReferences = ownerRefs
+			pod.Labels = map[string]string{"app": "test"}
+		}
+		pods = append(pods, pod)
+	}
+	budget := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "pdb", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+	}
+	candidates := []string{"n1", "n2", "n3"}
+	destinations := map[string]bool{"n0": true, "n1": true, "n2": true, "n3": true}
+
+	simulate := func(parallelism int) ([]*NodeToBeRemoved, []*UnremovableNode) {
+		clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+		clustersnapshot.InitializeClusterSnapshotOrDie(t, clusterSnapshot, nodes, pods)
+		predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+		assert.NoError(t, err)
+		pdbTracker := pdb.NewBasicRemainingPdbTracker()
+		assert.NoError(t, pdbTracker.SetPdbs([]*policyv1.PodDisruptionBudget{budget}))
+		timestamp := time.Now()
+
+		r := NewRemovalSimulator(registry, clusterSnapshot, predicateChecker, NewUsageTracker(), testDeleteOptions(), nil, false)
+		r.PrecomputeDrainability(candidates, timestamp, pdbTracker, parallelism)
+		var removable []*NodeToBeRemoved
+		var unremovable []*UnremovableNode
+		for _, candidate := range candidates {
+			rn, urn := r.SimulateNodeRemoval(candidate, destinations, timestamp, pdbTracker)
+			if rn != nil {
+				pdbTracker.RemovePods(rn.PodsToReschedule)
+				removable = append(removable, rn)
+			}
+			if urn != nil {
+				unremovable = append(unremovable, urn)
+			}
+		}
+		// Destinations depend on the order in which nodes are tried.
+		for _, rn := range removable {
+			rn.Destinations = nil
+		}
+		return removable, unremovable
+	}
+
+	wantRemovable, wantUnremovable := simulate(1)
+	assert.Equal(t, 1, len(wantRemovable))
+	assert.Equal(t, 2, len(wantUnremovable))
+	gotRemovable, gotUnremovable := simulate(4)
+	assert.Equal(t, wantRemovable, gotRemovable)
+	assert.Equal(t, wantUnremovable, gotUnremovable)
+	assert.Equal(t, drain.NotEnoughPdb, gotUnremovable[0].BlockingPod.Reason)
+	assert.Equal(t, drain.NotReplicated, gotUnremovable[1].BlockingPod.Reason)
+}
+
+func TestSimulateNodeRemovalMaxGracefulTerminationSecGetter(t *testing.T) {
+	rsLister, err := kube_util.NewTestReplicaSetLister([]*appsv1.ReplicaSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default"}},
+	})
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, rsLister, nil)
+
+	n0 := BuildTestNode("n0", 1000, 2000000)
+	n1 := BuildTestNode("n1", 1000, 2000000)
+	SetNodeReadyState(n0, true, time.Time{})
+	SetNodeReadyState(n1, true, time.Time{})
+	pod := BuildTestPod("p1", 100, 100000)
+	pod.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	pod.Spec.NodeName = "n1"
+	terminationGracePeriod := int64(300)
+	pod.Spec.TerminationGracePeriodSeconds = &terminationGracePeriod
+
+	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, clusterSnapshot, []*apiv1.Node{n0, n1}, []*apiv1.Pod{pod})
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+	deleteOptions := testDeleteOptions()
+	deleteOptions.MaxGracefulTerminationSec = 600
+
+	r := NewRemovalSimulator(registry, clusterSnapshot, predicateChecker, NewUsageTracker(), deleteOptions, nil, false)
+	destinations := map[string]bool{"n0": true, "n1": true}
+	rn, _ := r.SimulateNodeRemoval("n1", destinations, time.Now(), nil)
+	if assert.NotNil(t, rn) {
+		assert.Equal(t, 300*time.Second, rn.EstimatedDrainDuration)
+		assert.Equal(t, []EvictionGroup{{Pods: []*apiv1.Pod{pod}}}, rn.EvictionGroups)
+	}
+
+	r.SetMaxGracefulTerminationSecGetter(func(node *apiv1.Node) int {
+		assert.Equal(t, "n1", node.Name)
+		return 30
+	})
+	rn, _ = r.SimulateNodeRemoval("n1", destinations, time.Now(), nil)
+	if assert.NotNil(t, rn) {
+		assert.Equal(t, 30*time.Second, rn.MaxDrainGracePeriod)
+		assert.Equal(t, 30*time.Second, rn.EstimatedDrainDuration)
+	}
+
+	// Reloaded options apply to the estimate, like in the drain simulation.
+	deleteOptions.Reloader = drainTimeoutReloader{timeout: 10 * time.Second}
+	r = NewRemovalSimulator(registry, clusterSnapshot, predicateChecker, NewUsageTracker(), deleteOptions, nil, false)
+	rn, _ = r.SimulateNodeRemoval("n1", destinations, time.Now(), nil)
+	if assert.NotNil(t, rn) {
+		assert.Equal(t, 10*time.Second, rn.EstimatedDrainDuration)
+	}
+}
+
+// drainTimeoutReloader overrides NodeDrainTimeout of reloaded options.
+type drainTimeoutReloader struct {
+	timeout time.Duration
+}
+
+func (r drainTimeoutReloader) Reload(o options.NodeDeleteOptions) options.NodeDeleteOptions {
+	o.NodeDrainTimeout = r.timeout
+	return o
+}
+
+func (r drainTimeoutReloader) Refresh() {}
+
+func TestSimulateNodeRemovalPodsToMoveFunc(t *testing.T) {
+	n0 := BuildTestNode("n0", 1000, 2000000)
+	n1 := BuildTestNode("n1", 1000, 2000000)
+	SetNodeReadyState(n0, true, time.Time{})
+	SetNodeReadyState(n1, true, time.Time{})
+	// Unreplicated pods block drain with the default rules.
+	pod := BuildTestPod("p1", 100, 100000)
+	pod.Spec.NodeName = "n1"
+
+	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, clusterSnapshot, []*apiv1.Node{n0, n1}, []*apiv1.Pod{pod})
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	r := NewRemovalSimulator(registry, clusterSnapshot, predicateChecker, NewUsageTracker(), testDeleteOptions(), nil, false)
+	destinations := map[string]bool{"n0": true, "n1": true}
+	rn, urn := r.SimulateNodeRemoval("n1", destinations, time.Now(), nil)
+	assert.Nil(t, rn)
+	if assert.NotNil(t, urn) {
+		assert.Equal(t, BlockedByPod, urn.Reason)
+	}
+
+	r.SetPodsToMoveFunc(IgnoreAllPodsToMove)
+	rn, _ = r.SimulateNodeRemoval("n1", destinations, time.Now(), nil)
+	if assert.NotNil(t, rn) {
+		assert.Equal(t, []*apiv1.Pod{pod}, rn.PodsToReschedule)
+	}
+
+	r.SetPodsToMoveFunc(nil)
+	rn, _ = r.SimulateNodeRemoval("n1", destinations, time.Now(), nil)
+	assert.Nil(t, rn)
+}
+
+func TestSimulateNodeRemovalResourceClaims(t *testing.T) {
+	rsLister, err := kube_util.NewTestReplicaSetLister([]*appsv1.ReplicaSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default"}},
+	})
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, rsLister, nil)
+
+	var nodes []*apiv1.Node
+	for _, device := range []string{"a", "b", "a"} {
+		node := BuildTestNode(fmt.Sprintf("n%d", len(nodes)), 1000, 2000000)
+		node.Labels["device"] = device
+		node.Labels[apiv1.LabelHostname] = node.Name
+		SetNodeReadyState(node, true, time.Time{})
+		nodes = append(nodes, node)
+	}
+	pod := BuildTestPod("p1", 100, 100000)
+	pod.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	pod.Spec.NodeName = "n0"
+	claimName := "gpu"
+	pod.Spec.ResourceClaims = []apiv1.PodResourceClaim{{Name: "gpu", Source: apiv1.ClaimSource{ResourceClaimName: &claimName}}}
+
+	// The pod's claim is available on all nodes with device a, the pending
+	// pod's claim only on n1.
+	claims := dynamicresources.NewClaims([]*resourcev1alpha2.ResourceClaim{
+		testResourceClaim("gpu", "device", "a", pod.UID),
+		testResourceClaim("pending", apiv1.LabelHostname, "n1", "pending-uid"),
+	}, nil, nil)
+
+	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, clusterSnapshot, nodes, []*apiv1.Pod{pod})
+	clusterSnapshot.SetResourceClaims(claims)
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	r := NewRemovalSimulator(registry, clusterSnapshot, predicateChecker, NewUsageTracker(), testDeleteOptions(), nil, false)
+
+	// The pod can only be moved to the other node with device a.
+	rn, urn := r.SimulateNodeRemoval("n0", map[string]bool{"n0": true, "n1": true, "n2": true}, testTime, nil)
+	assert.Nil(t, urn)
+	assert.NotNil(t, rn)
+	rn, urn = r.SimulateNodeRemoval("n0", map[string]bool{"n0": true, "n1": true}, testTime, nil)
+	assert.Nil(t, rn)
+	if assert.NotNil(t, urn) {
+		assert.Equal(t, NoPlaceToMovePods, urn.Reason)
+	}
+
+	// n1 is empty, but provides the claim of the pending pod.
+	rn, urn = r.SimulateNodeRemoval("n1", map[string]bool{"n0": true, "n1": true, "n2": true}, testTime, nil)
+	assert.Nil(t, rn)
+	if assert.NotNil(t, urn) {
+		assert.Equal(t, ProvidesInUseResourceClaim, urn.Reason)
+	}
+	assert.Equal(t, []string{"n2"}, r.FindEmptyNodesToRemove([]string{"n1", "n2"}, testTime))
+}
+
+func testResourceClaim(name, labelKey, labelValue string, reservedFor k8stypes.UID) *resourcev1alpha2.ResourceClaim {
+	return &resourcev1alpha2.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status: resourcev1alpha2.ResourceClaimStatus{
+			Allocation: &resourcev1alpha2.AllocationResult{
+				AvailableOnNodes: &apiv1.NodeSelector{NodeSelectorTerms: []apiv1.NodeSelectorTerm{{
+					MatchExpressions: []apiv1.NodeSelectorRequirement{{Key: labelKey, Operator: apiv1.NodeSelectorOpIn, Values: []string{labelValue}}},
+				}}},
+			},
+			ReservedFor: []resourcev1alpha2.ResourceClaimConsumerReference{{Resource: "pods", Name: name, UID: reservedFor}},
+		},
+	}
+}
+
+func TestEstimateDrainDuration(t *testing.T) {
+	shortPod := BuildTestPod("short", 100, 100000)
+	shortGracePeriod := int64(10)
+	shortPod.Spec.TerminationGracePeriodSeconds = &shortGracePeriod
+	longPod := BuildTestPod("long", 100, 100000)
+	longGracePeriod := int64(300)
+	longPod.Spec.TerminationGracePeriodSeconds = &longGracePeriod
+	veryLongPod := BuildTestPod("very-long", 100, 100000)
+	veryLongGracePeriod := int64(900)
+	veryLongPod.Spec.TerminationGracePeriodSeconds = &veryLongGracePeriod
+	preStopPod := longPod.DeepCopy()
+	preStopPod.Annotations = map[string]string{drain.PodPreStopDurationKey: "20"}
+
+	for desc, tc := range map[string]struct {
+		pods             []*apiv1.Pod
+		nodeDrainTimeout time.Duration
+		want             time.Duration
+	}{
+		"no pods": {
+			want: 0,
+		},
+		"longest grace period": {
+			pods: []*apiv1.Pod{shortPod, longPod},
+			want: 300 * time.Second,
+		},
+		"grace period capped at max graceful termination": {
+			pods: []*apiv1.Pod{shortPod, veryLongPod},
+			want: 600 * time.Second,
+		},
+		"pre-stop duration": {
+			pods: []*apiv1.Pod{shortPod, preStopPod},
+			want: 20 * time.Second,
+		},
+		"capped at node drain timeout": {
+			pods:             []*apiv1.Pod{shortPod, longPod},
+			nodeDrainTimeout: time.Minute,
+			want:             time.Minute,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			assert.Equal(t, tc.want, estimateDrainDuration(tc.pods, 600, tc.nodeDrainTimeout))
+		})
+	}
+}
+
 func testDeleteOptions() options.NodeDeleteOptions {
 	return options.NodeDeleteOptions{
 		SkipNodesWithSystemPods:           true,
@@ -20,17 +20,23 @@ import (
 	"fmt"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/dynamicresources"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 // BasicClusterSnapshot is simple, reference implementation of ClusterSnapshot.
-// It is inefficient. But hopefully bug-free and good for initial testing.
+// Forks share node infos with the forked state and clone them on first modification,
+// but copy the whole node info map, so forking is still linear in the number of nodes.
 type BasicClusterSnapshot struct {
-	data []*internalBasicSnapshotData
+	data           []*internalBasicSnapshotData
+	resourceClaims *dynamicresources.Claims
 }
 
 type internalBasicSnapshotData struct {
-	nodeInfoMap        map[string]*schedulerframework.NodeInfo
+	nodeInfoMap map[string]*schedulerframework.NodeInfo
+	// ownedNodeInfos are the node infos created or cloned in this data. The others
+	// are shared with the forked data and mustn't be modified in place.
+	ownedNodeInfos     map[string]bool
 	pvcNamespacePodMap map[string]map[string]bool
 }
 
@@ -119,14 +125,16 @@ func (data *internalBasicSnapshotData) removePvcUsedByPod(pod *apiv1.Pod) {
 func newInternalBasicSnapshotData() *internalBasicSnapshotData {
 	return &internalBasicSnapshotData{
 		nodeInfoMap:        make(map[string]*schedulerframework.NodeInfo),
+		ownedNodeInfos:     make(map[string]bool),
 		pvcNamespacePodMap: make(map[string]map[string]bool),
 	}
 }
 
+// clone returns a copy of the data sharing all node infos with it.
 func (data *internalBasicSnapshotData) clone() *internalBasicSnapshotData {
-	clonedNodeInfoMap := make(map[string]*schedulerframework.NodeInfo)
+	clonedNodeInfoMap := make(map[string]*schedulerframework.NodeInfo, len(data.nodeInfoMap))
 	for k, v := range data.nodeInfoMap {
-		clonedNodeInfoMap[k] = v.Clone()
+		clonedNodeInfoMap[k] = v
 	}
 	clonedPvcNamespaceNodeMap := make(map[string]map[string]bool)
 	for k, v := range data.pvcNamespacePodMap {
@@ -137,10 +145,25 @@ func (data *internalBasicSnapshotData) clone() *internalBasicSnapshotData {
 	}
 	return &internalBasicSnapshotData{
 		nodeInfoMap:        clonedNodeInfoMap,
+		ownedNodeInfos:     make(map[string]bool),
 		pvcNamespacePodMap: clonedPvcNamespaceNodeMap,
 	}
 }
 
+// nodeInfoToModify returns the node info which can be modified in place, cloning it if it's shared.
+func (data *internalBasicSnapshotData) nodeInfoToModify(nodeName string) (*schedulerframework.NodeInfo, bool) {
+	nodeInfo, found := data.nodeInfoMap[nodeName]
+	if !found {
+		return nil, false
+	}
+	if !data.ownedNodeInfos[nodeName] {
+		nodeInfo = nodeInfo.Clone()
+		data.nodeInfoMap[nodeName] = nodeInfo
+		data.ownedNodeInfos[nodeName] = true
+	}
+	return nodeInfo, true
+}
+
 func (data *internalBasicSnapshotData) addNode(node *apiv1.Node) error {
 	if _, found := data.nodeInfoMap[node.Name]; found {
 		return fmt.Errorf("node %s already in snapshot", node.Name)
@@ -148,6 +171,7 @@ func (data *internalBasicSnapshotData) addNode(node *apiv1.Node) error {
 	nodeInfo := schedulerframework.NewNodeInfo()
 	nodeInfo.SetNode(node)
 	data.nodeInfoMap[node.Name] = nodeInfo
+	data.ownedNodeInfos[node.Name] = true
 	return nil
 }
 
@@ -168,20 +192,22 @@ func (data *internalBasicSnapshotData) removeNode(nodeName string) error {
 		data.removePvcUsedByPod(pod.Pod)
 	}
 	delete(data.nodeInfoMap, nodeName)
+	delete(data.ownedNodeInfos, nodeName)
 	return nil
 }
 
 func (data *internalBasicSnapshotData) addPod(pod *apiv1.Pod, nodeName string) error {
-	if _, found := data.nodeInfoMap[nodeName]; !found {
+	nodeInfo, found := data.nodeInfoToModify(nodeName)
+	if !found {
 		return ErrNodeNotFound
 	}
-	data.nodeInfoMap[nodeName].AddPod(pod)
+	nodeInfo.AddPod(pod)
 	data.addPvcUsedByPod(pod)
 	return nil
 }
 
 func (data *internalBasicSnapshotData) removePod(namespace, podName, nodeName string) error {
-	nodeInfo, found := data.nodeInfoMap[nodeName]
+	nodeInfo, found := data.nodeInfoToModify(nodeName)
 	if !found {
 		return ErrNodeNotFound
 	}
@@ -253,7 +279,18 @@ func (snapshot *BasicClusterSnapshot) IsPVCUsedByPods(key string) bool {
 	return snapshot.getInternalData().isPVCUsedByPods(key)
 }
 
+// SetResourceClaims sets the DRA resource claims taken into account when scheduling pods.
+func (snapshot *BasicClusterSnapshot) SetResourceClaims(claims *dynamicresources.Claims) {
+	snapshot.resourceClaims = claims
+}
+
+// ResourceClaims returns the DRA resource claims set on the snapshot.
+func (snapshot *BasicClusterSnapshot) ResourceClaims() *dynamicresources.Claims {
+	return snapshot.resourceClaims
+}
+
 // Fork creates a fork of snapshot state. All modifications can later be reverted to moment of forking via Revert()
+// Time: O(n), where n = number of nodes. Node infos are cloned only when first modified after forking.
 func (snapshot *BasicClusterSnapshot) Fork() {
 	forkData := snapshot.getInternalData().clone()
 	snapshot.data = append(snapshot.data, forkData)
@@ -281,6 +318,7 @@ func (snapshot *BasicClusterSnapshot) Commit() error {
 func (snapshot *BasicClusterSnapshot) Clear() {
 	baseData := newInternalBasicSnapshotData()
 	snapshot.data = []*internalBasicSnapshotData{baseData}
+	snapshot.resourceClaims = nil
 }
 
 // implementation of SharedLister interface
@@ -20,6 +20,7 @@ import (
 	"errors"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/dynamicresources"
 	"k8s.io/klog/v2"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
@@ -42,6 +43,11 @@ type ClusterSnapshot interface {
 	AddNodeWithPods(node *apiv1.Node, pods []*apiv1.Pod) error
 	// IsPVCUsedByPods returns if the pvc is used by any pod, key = <namespace>/<pvc_name>
 	IsPVCUsedByPods(key string) bool
+	// SetResourceClaims sets the DRA resource claims taken into account when scheduling pods. The claims aren't
+	// affected by Fork(), Revert() and Commit(). Nil means DRA isn't taken into account.
+	SetResourceClaims(claims *dynamicresources.Claims)
+	// ResourceClaims returns the DRA resource claims set on the snapshot, nil if there are none.
+	ResourceClaims() *dynamicresources.Claims
 
 	// Fork creates a fork of snapshot state. All modifications can later be reverted to moment of forking via Revert().
 	// Use WithForkedSnapshot() helper function instead if possible.
@@ -278,6 +278,44 @@ func TestForking(t *testing.T) {
 	}
 }
 
+func TestForkDoesNotModifyForkedNodeInfos(t *testing.T) {
+	nodeA := BuildTestNode("A", 10, 100)
+	nodeB := BuildTestNode("B", 10, 100)
+	pod := BuildTestPod("p", 1, 1)
+	pod.Spec.NodeName = "A"
+
+	for name, snapshotFactory := range snapshots {
+		t.Run(name, func(t *testing.T) {
+			snapshot := startSnapshot(t, snapshotFactory, snapshotState{nodes: []*apiv1.Node{nodeA, nodeB}, pods: []*apiv1.Pod{pod}})
+			baseA, err := snapshot.NodeInfos().Get("A")
+			assert.NoError(t, err)
+			baseB, err := snapshot.NodeInfos().Get("B")
+			assert.NoError(t, err)
+
+			// What if A was removed and its pod moved to B?
+			snapshot.Fork()
+			assert.NoError(t, snapshot.RemovePod(pod.Namespace, pod.Name, "A"))
+			assert.NoError(t, snapshot.AddPod(pod, "B"))
+			assert.NoError(t, snapshot.RemoveNode("A"))
+			forkedB, err := snapshot.NodeInfos().Get("B")
+			assert.NoError(t, err)
+			assert.Len(t, forkedB.Pods, 1)
+
+			// Node infos obtained before forking are unaffected.
+			assert.Len(t, baseA.Pods, 1)
+			assert.Len(t, baseB.Pods, 0)
+
+			snapshot.Revert()
+			revertedA, err := snapshot.NodeInfos().Get("A")
+			assert.NoError(t, err)
+			assert.Len(t, revertedA.Pods, 1)
+			revertedB, err := snapshot.NodeInfos().Get("B")
+			assert.NoError(t, err)
+			assert.Len(t, revertedB.Pods, 0)
+		})
+	}
+}
+
 func TestClear(t *testing.T) {
 	// Run with -count=1 to avoid caching.
 	localRand := rand.New(rand.NewSource(time.Now().Unix()))
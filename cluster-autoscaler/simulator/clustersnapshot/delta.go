@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/dynamicresources"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
@@ -41,7 +42,8 @@ import (
 //	pod affinity - causes scheduler framework to list pods with non-empty selector,
 //		so basic caching doesn't help.
 type DeltaClusterSnapshot struct {
-	data *internalDeltaSnapshotData
+	data           *internalDeltaSnapshotData
+	resourceClaims *dynamicresources.Claims
 }
 
 type deltaSnapshotNodeLister DeltaClusterSnapshot
@@ -443,6 +445,16 @@ func (snapshot *DeltaClusterSnapshot) IsPVCUsedByPods(key string) bool {
 	return snapshot.data.isPVCUsedByPods(key)
 }
 
+// SetResourceClaims sets the DRA resource claims taken into account when scheduling pods.
+func (snapshot *DeltaClusterSnapshot) SetResourceClaims(claims *dynamicresources.Claims) {
+	snapshot.resourceClaims = claims
+}
+
+// ResourceClaims returns the DRA resource claims set on the snapshot.
+func (snapshot *DeltaClusterSnapshot) ResourceClaims() *dynamicresources.Claims {
+	return snapshot.resourceClaims
+}
+
 // Fork creates a fork of snapshot state. All modifications can later be reverted to moment of forking via Revert()
 // Time: O(1)
 func (snapshot *DeltaClusterSnapshot) Fork() {
@@ -472,4 +484,5 @@ func (snapshot *DeltaClusterSnapshot) Commit() error {
 // Time: O(1)
 func (snapshot *DeltaClusterSnapshot) Clear() {
 	snapshot.data = newInternalDeltaSnapshotData()
+	snapshot.resourceClaims = nil
 }
@@ -17,55 +17,195 @@ limitations under the License.
 package simulator
 
 import (
+	"fmt"
 	"time"
 
 	apiv1 "k8s.io/api/core/v1"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	drainabilitymetrics "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
-	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+	v1appslister "k8s.io/client-go/listers/apps/v1"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
-// GetPodsToMove returns a list of pods that should be moved elsewhere and a
-// list of DaemonSet pods that should be evicted if the node is drained.
-// Raises error if there is an unreplicated pod.
+// PodsToMoveFunc determines the pods to move elsewhere and the DaemonSet pods
+// to evict when draining a node, or the pods blocking the drain. SimulateDrain
+// is the default implementation.
+type PodsToMoveFunc func(nodeInfo *schedulerframework.NodeInfo, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, listers kube_util.ListerRegistry, remainingPdbTracker pdb.RemainingPdbTracker, timestamp time.Time) *DrainSimulationResult
+
+// DrainSimulationResult is the result of simulating drain of a node.
+type DrainSimulationResult struct {
+	// PodsToMove are the pods that should be moved elsewhere.
+	PodsToMove []*apiv1.Pod
+	// DaemonSetPods are the DaemonSet pods that should be evicted. DaemonSet
+	// pods disabling eviction with an annotation of the pod or of its
+	// DaemonSet are not included.
+	DaemonSetPods []*apiv1.Pod
+	// BlockingPod is the first pod blocking the drain, if any. PodsToMove
+	// and DaemonSetPods are empty then.
+	BlockingPod *drain.BlockingPod
+	// BlockingPods are all pods blocking the drain, starting with
+	// BlockingPod, so that all of them can be fixed at once.
+	BlockingPods []*drain.BlockingPod
+	// Verdicts are the drainability statuses of the evaluated pods, in the
+	// order in which they were evaluated.
+	Verdicts []PodVerdict
+	// EvictionGroups are PodsToMove grouped in the order they are evicted in
+	// when the node is drained, preserving the order of StatefulSet pods if
+	// StatefulSetOrdinalOrder of the delete options for the node is set.
+	EvictionGroups []EvictionGroup
+	// EstimatedDrainDuration is the expected time it takes to drain the
+	// node, using MaxGracefulTerminationSec and NodeDrainTimeout of the
+	// delete options for the node.
+	EstimatedDrainDuration time.Duration
+	// Err is the error of the first blocking pod, if any.
+	Err error
+}
+
+// PodVerdict is the drainability status of a pod on a drained node.
+type PodVerdict struct {
+	Pod    *apiv1.Pod
+	Status drainability.Status
+}
+
+// SimulateDrain evaluates the drainability rules for all pods of the node and
+// returns the pods that should be moved elsewhere and the DaemonSet pods that
+// should be evicted if the node is drained, or the pods blocking the drain.
 // Based on kubectl drain code. If listers is nil it makes an assumption that
 // RC, DS, Jobs and RS were deleted along with their pods (no abandoned pods
 // with dangling created-by annotation).
 // If listers is not nil it checks whether RC, DS, Jobs and RS that created
 // these pods still exist.
+func SimulateDrain(nodeInfo *schedulerframework.NodeInfo, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, listers kube_util.ListerRegistry, remainingPdbTracker pdb.RemainingPdbTracker, timestamp time.Time) *DrainSimulationResult {
+	return simulateDrain(nodeInfo, deleteOptions, drainabilityRules, listers, remainingPdbTracker, timestamp, false)
+}
+
+// GetPodsToMove returns a list of pods that should be moved elsewhere and a
+// list of DaemonSet pods that should be evicted if the node is drained, or the
+// first pod blocking the drain along with an error. It returns the fields of
+// the result of SimulateDrain, for callers not interested in the others.
 func GetPodsToMove(nodeInfo *schedulerframework.NodeInfo, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, listers kube_util.ListerRegistry, remainingPdbTracker pdb.RemainingPdbTracker, timestamp time.Time) (pods []*apiv1.Pod, daemonSetPods []*apiv1.Pod, blockingPod *drain.BlockingPod, err error) {
+	return SimulateDrain(nodeInfo, deleteOptions, drainabilityRules, listers, remainingPdbTracker, timestamp).podsToMove()
+}
+
+// StrictPodsToMove is a PodsToMoveFunc which works like GetPodsToMove, except
+// that drain is also blocked by pods no drainability rule decided about, e.g.
+// replicated pods which aren't annotated as safe to evict. Only pods which are
+// explicitly allowed to be drained or skipped by the rules don't block it.
+func StrictPodsToMove(nodeInfo *schedulerframework.NodeInfo, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, listers kube_util.ListerRegistry, remainingPdbTracker pdb.RemainingPdbTracker, timestamp time.Time) *DrainSimulationResult {
+	return simulateDrain(nodeInfo, deleteOptions, drainabilityRules, listers, remainingPdbTracker, timestamp, true)
+}
+
+// PodsToDisrupt returns the pods whose removal uses up disruption budgets if
+// the node is drained: PodsToMove, and also DaemonSetPods if
+// respectDaemonSetPdbs is set.
+func (r *DrainSimulationResult) PodsToDisrupt(respectDaemonSetPdbs bool) []*apiv1.Pod {
+	return podsToDisrupt(r.PodsToMove, r.DaemonSetPods, respectDaemonSetPdbs)
+}
+
+func podsToDisrupt(pods, daemonSetPods []*apiv1.Pod, respectDaemonSetPdbs bool) []*apiv1.Pod {
+	if !respectDaemonSetPdbs || len(daemonSetPods) == 0 {
+		return pods
+	}
+	result := make([]*apiv1.Pod, 0, len(pods)+len(daemonSetPods))
+	result = append(result, pods...)
+	return append(result, daemonSetPods...)
+}
+
+func (r *DrainSimulationResult) podsToMove() (pods []*apiv1.Pod, daemonSetPods []*apiv1.Pod, blockingPod *drain.BlockingPod, err error) {
+	return r.PodsToMove, r.DaemonSetPods, r.BlockingPod, r.Err
+}
+
+// IgnoreAllPodsToMove is a PodsToMoveFunc ignoring drainability rules and
+// disruption budgets. All pods except mirror pods are moved, so drain is never
+// blocked. It's meant for environments draining nodes on their own.
+func IgnoreAllPodsToMove(nodeInfo *schedulerframework.NodeInfo, _ options.NodeDeleteOptions, _ rules.Rules, _ kube_util.ListerRegistry, _ pdb.RemainingPdbTracker, _ time.Time) *DrainSimulationResult {
+	result := &DrainSimulationResult{}
+	for _, podInfo := range nodeInfo.Pods {
+		pod := podInfo.Pod
+		switch {
+		case pod_util.IsMirrorPod(pod):
+		case pod_util.IsDaemonSetPod(pod):
+			result.DaemonSetPods = append(result.DaemonSetPods, pod)
+		default:
+			result.PodsToMove = append(result.PodsToMove, pod)
+		}
+	}
+	return result
+}
+
+func simulateDrain(nodeInfo *schedulerframework.NodeInfo, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, listers kube_util.ListerRegistry, remainingPdbTracker pdb.RemainingPdbTracker, timestamp time.Time, strict bool) *DrainSimulationResult {
 	if drainabilityRules == nil {
 		drainabilityRules = rules.Default(deleteOptions)
 	}
 	if remainingPdbTracker == nil {
 		remainingPdbTracker = pdb.NewBasicRemainingPdbTracker()
 	}
+	defer func(start time.Time) {
+		drainabilitymetrics.AddEvaluationDuration(time.Since(start))
+	}(time.Now())
+	deleteOptions = deleteOptions.ForNode(nodeInfo.Node())
 	drainCtx := &drainability.DrainContext{
 		RemainingPdbTracker: remainingPdbTracker,
 		Listers:             listers,
 		Timestamp:           timestamp,
+		DeleteOptions:       deleteOptions,
+	}
+	var dsLister v1appslister.DaemonSetLister
+	var ssLister v1appslister.StatefulSetLister
+	if listers != nil {
+		dsLister = listers.DaemonSetLister()
+		ssLister = listers.StatefulSetLister()
+	}
+	result := &DrainSimulationResult{
+		Verdicts: make([]PodVerdict, 0, len(nodeInfo.Pods)),
 	}
 	for _, podInfo := range nodeInfo.Pods {
 		pod := podInfo.Pod
-		status := drainabilityRules.Drainable(drainCtx, pod)
+		status := drainabilityRules.Drainable(drainCtx, pod, nodeInfo)
+		if strict && status.Outcome == drainability.UndefinedOutcome {
+			status = drainability.NewBlockedStatus(drain.NotSafeToEvictAnnotation, fmt.Errorf("pod %s/%s is not explicitly allowed to be drained", pod.Namespace, pod.Name))
+		}
+		result.Verdicts = append(result.Verdicts, PodVerdict{Pod: pod, Status: status})
 		switch status.Outcome {
 		case drainability.UndefinedOutcome, drainability.DrainOk:
 			if pod_util.IsDaemonSetPod(pod) {
-				daemonSetPods = append(daemonSetPods, pod)
+				// Pods which explicitly opted out of eviction are left on the node.
+				if evict, found := daemonset.EvictionAnnotation(pod, dsLister); found && !evict {
+					continue
+				}
+				result.DaemonSetPods = append(result.DaemonSetPods, pod)
 			} else {
-				pods = append(pods, pod)
+				result.PodsToMove = append(result.PodsToMove, pod)
 			}
 		case drainability.BlockDrain:
-			return nil, nil, &drain.BlockingPod{
-				Pod:    pod,
-				Reason: status.BlockingReason,
-			}, status.Error
+			drainabilitymetrics.RegisterBlockingPod(status.BlockingReason)
+			blockingPod := &drain.BlockingPod{
+				Pod:          pod,
+				Reason:       status.BlockingReason,
+				CustomReason: status.CustomBlockingReason,
+				Details:      status.BlockingDetails,
+				Rule:         status.Rule,
+				Remediation:  status.Remediation,
+			}
+			if result.BlockingPod == nil {
+				result.BlockingPod = blockingPod
+				result.Err = status.Error
+			}
+			result.BlockingPods = append(result.BlockingPods, blockingPod)
 		}
 	}
-	return pods, daemonSetPods, nil, nil
+	if result.BlockingPod != nil {
+		result.PodsToMove, result.DaemonSetPods = nil, nil
+		return result
+	}
+	result.EstimatedDrainDuration = estimateDrainDuration(result.PodsToMove, deleteOptions.MaxGracefulTerminationSec, deleteOptions.NodeDrainTimeout)
+	result.EvictionGroups = evictionGroups(result.PodsToMove, deleteOptions.StatefulSetOrdinalOrder, ssLister)
+	return result
 }
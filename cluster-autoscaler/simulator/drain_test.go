@@ -27,11 +27,12 @@ import (
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
@@ -231,6 +232,38 @@ func TestGetPodsToMove(t *testing.T) {
 				NodeName: "node",
 			},
 		}
+		keepDs = appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "keep-ds",
+				Namespace: "default",
+				Annotations: map[string]string{
+					daemonset.EvictDaemonSetPodKey: "false",
+				},
+			},
+		}
+		keepDsPod = &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "keep",
+				Namespace:       "default",
+				OwnerReferences: GenerateOwnerReferences(keepDs.Name, "DaemonSet", "apps/v1", ""),
+			},
+			Spec: apiv1.PodSpec{
+				NodeName: "node",
+			},
+		}
+		keepAnnotatedDsPod = &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "keep-annotated",
+				Namespace:       "default",
+				OwnerReferences: GenerateOwnerReferences(ds.Name, "DaemonSet", "apps/v1", ""),
+				Annotations: map[string]string{
+					daemonset.EnableDsEvictionKey: "false",
+				},
+			},
+			Spec: apiv1.PodSpec{
+				NodeName: "node",
+			},
+		}
 		cdsPod = &apiv1.Pod{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:            "bar",
@@ -513,8 +546,10 @@ func TestGetPodsToMove(t *testing.T) {
 			pods:    []*apiv1.Pod{unreplicatedPod},
 			wantErr: true,
 			wantBlocking: &drain.BlockingPod{
-				Pod:    unreplicatedPod,
-				Reason: drain.NotReplicated,
+				Pod:         unreplicatedPod,
+				Reason:      drain.NotReplicated,
+				Rule:        "Replicated",
+				Remediation: drain.AddSafeToEvictAnnotation,
 			},
 		},
 		{
@@ -537,8 +572,10 @@ func TestGetPodsToMove(t *testing.T) {
 			pods:    []*apiv1.Pod{systemPod},
 			wantErr: true,
 			wantBlocking: &drain.BlockingPod{
-				Pod:    systemPod,
-				Reason: drain.UnmovableKubeSystemPod,
+				Pod:         systemPod,
+				Reason:      drain.UnmovableKubeSystemPod,
+				Rule:        "System",
+				Remediation: drain.AddPodDisruptionBudget,
 			},
 		},
 		{
@@ -546,8 +583,10 @@ func TestGetPodsToMove(t *testing.T) {
 			pods:    []*apiv1.Pod{localStoragePod},
 			wantErr: true,
 			wantBlocking: &drain.BlockingPod{
-				Pod:    localStoragePod,
-				Reason: drain.LocalStorageRequested,
+				Pod:         localStoragePod,
+				Reason:      drain.LocalStorageRequested,
+				Rule:        "LocalStorage",
+				Remediation: drain.AddSafeToEvictAnnotation,
 			},
 		},
 		{
@@ -561,8 +600,10 @@ func TestGetPodsToMove(t *testing.T) {
 			pdbs:    []*policyv1.PodDisruptionBudget{restrictivePdb},
 			wantErr: true,
 			wantBlocking: &drain.BlockingPod{
-				Pod:    pdbPod,
-				Reason: drain.NotEnoughPdb,
+				Pod:         pdbPod,
+				Reason:      drain.NotEnoughPdb,
+				Rule:        "PDB",
+				Remediation: drain.IncreasePdbMaxUnavailable,
 			},
 		},
 		{
@@ -596,6 +637,7 @@ func TestGetPodsToMove(t *testing.T) {
 			wantBlocking: &drain.BlockingPod{
 				Pod:    rsPod,
 				Reason: drain.UnexpectedError,
+				Rule:   "NeverDrain",
 			},
 		},
 		{
@@ -606,6 +648,20 @@ func TestGetPodsToMove(t *testing.T) {
 			wantBlocking: &drain.BlockingPod{
 				Pod:    rsPod,
 				Reason: drain.UnexpectedError,
+				Rule:   "NeverDrain",
+			},
+		},
+		{
+			desc:    "Rule blocks with custom reason",
+			pods:    []*apiv1.Pod{rsPod},
+			rules:   []rules.Rule{customBlock{}},
+			wantErr: true,
+			wantBlocking: &drain.BlockingPod{
+				Pod:          rsPod,
+				Reason:       drain.CustomRuleReason,
+				CustomReason: "example.com/MigrationPending",
+				Details:      map[string]string{"volume": "data"},
+				Rule:         "CustomBlock",
 			},
 		},
 		{
@@ -614,8 +670,10 @@ func TestGetPodsToMove(t *testing.T) {
 			rules:   []rules.Rule{cantDecide{}},
 			wantErr: true,
 			wantBlocking: &drain.BlockingPod{
-				Pod:    unreplicatedPod,
-				Reason: drain.NotReplicated,
+				Pod:         unreplicatedPod,
+				Reason:      drain.NotReplicated,
+				Rule:        "Replicated",
+				Remediation: drain.AddSafeToEvictAnnotation,
 			},
 		},
 		{
@@ -636,6 +694,18 @@ func TestGetPodsToMove(t *testing.T) {
 			pods:   []*apiv1.Pod{dsPod},
 			wantDs: []*apiv1.Pod{dsPod},
 		},
+		{
+			desc: "DS-managed pod with eviction disabled by the DaemonSet",
+			pods: []*apiv1.Pod{keepDsPod, dsPod},
+			// Non-nil to build listers.
+			rcs:    []*apiv1.ReplicationController{},
+			wantDs: []*apiv1.Pod{dsPod},
+		},
+		{
+			desc:   "DS-managed pod with eviction disabled by the pod",
+			pods:   []*apiv1.Pod{keepAnnotatedDsPod, dsPod},
+			wantDs: []*apiv1.Pod{dsPod},
+		},
 		{
 			desc:   "DS-managed pod by a custom Daemonset",
 			pods:   []*apiv1.Pod{cdsPod},
@@ -671,9 +741,8 @@ func TestGetPodsToMove(t *testing.T) {
 			wantPods: []*apiv1.Pod{emptyDirSafeToEvictLocalVolumeMultiValAllMatching},
 		},
 		{
-			desc:     "failed pod",
-			pods:     []*apiv1.Pod{failedPod},
-			wantPods: []*apiv1.Pod{failedPod},
+			desc: "failed pod",
+			pods: []*apiv1.Pod{failedPod},
 		},
 		{
 			desc: "long terminating pod with 0 grace period",
@@ -687,14 +756,12 @@ func TestGetPodsToMove(t *testing.T) {
 			wantPods: []*apiv1.Pod{longTerminatingPodWithExtendedGracePeriod},
 		},
 		{
-			desc:     "evicted pod",
-			pods:     []*apiv1.Pod{evictedPod},
-			wantPods: []*apiv1.Pod{evictedPod},
+			desc: "evicted pod",
+			pods: []*apiv1.Pod{evictedPod},
 		},
 		{
-			desc:     "pod in terminal state",
-			pods:     []*apiv1.Pod{terminalPod},
-			wantPods: []*apiv1.Pod{terminalPod},
+			desc: "pod in terminal state",
+			pods: []*apiv1.Pod{terminalPod},
 		},
 		{
 			desc:     "pod with PodSafeToEvict annotation",
@@ -731,7 +798,7 @@ func TestGetPodsToMove(t *testing.T) {
 			pdbs:         []*policyv1.PodDisruptionBudget{kubeSystemFakePDB},
 			rcs:          []*apiv1.ReplicationController{&kubeSystemRc},
 			wantErr:      true,
-			wantBlocking: &drain.BlockingPod{Pod: kubeSystemRcPod, Reason: drain.UnmovableKubeSystemPod},
+			wantBlocking: &drain.BlockingPod{Pod: kubeSystemRcPod, Reason: drain.UnmovableKubeSystemPod, Rule: "System", Remediation: drain.AddPodDisruptionBudget},
 		},
 		{
 			desc:     "kube-system PDB with default namespace pod",
@@ -746,7 +813,7 @@ func TestGetPodsToMove(t *testing.T) {
 			pdbs:         []*policyv1.PodDisruptionBudget{defaultNamespacePDB},
 			rcs:          []*apiv1.ReplicationController{&kubeSystemRc},
 			wantErr:      true,
-			wantBlocking: &drain.BlockingPod{Pod: kubeSystemRcPod, Reason: drain.UnmovableKubeSystemPod},
+			wantBlocking: &drain.BlockingPod{Pod: kubeSystemRcPod, Reason: drain.UnmovableKubeSystemPod, Rule: "System", Remediation: drain.AddPodDisruptionBudget},
 		},
 	}
 	for _, tc := range testCases {
@@ -757,7 +824,7 @@ func TestGetPodsToMove(t *testing.T) {
 				assert.NoError(t, err)
 				rsLister, err := kube_util.NewTestReplicaSetLister(tc.replicaSets)
 				assert.NoError(t, err)
-				dsLister, err := kube_util.NewTestDaemonSetLister([]*appsv1.DaemonSet{&ds})
+				dsLister, err := kube_util.NewTestDaemonSetLister([]*appsv1.DaemonSet{&ds, &keepDs})
 				assert.NoError(t, err)
 				jobLister, err := kube_util.NewTestJobLister([]*batchv1.Job{&job})
 				assert.NoError(t, err)
@@ -794,7 +861,7 @@ func (a alwaysDrain) Name() string {
 	return "AlwaysDrain"
 }
 
-func (a alwaysDrain) Drainable(*drainability.DrainContext, *apiv1.Pod) drainability.Status {
+func (a alwaysDrain) Drainable(*drainability.DrainContext, *apiv1.Pod, *schedulerframework.NodeInfo) drainability.Status {
 	return drainability.NewDrainableStatus()
 }
 
@@ -804,16 +871,155 @@ func (n neverDrain) Name() string {
 	return "NeverDrain"
 }
 
-func (n neverDrain) Drainable(*drainability.DrainContext, *apiv1.Pod) drainability.Status {
+func (n neverDrain) Drainable(*drainability.DrainContext, *apiv1.Pod, *schedulerframework.NodeInfo) drainability.Status {
 	return drainability.NewBlockedStatus(drain.UnexpectedError, fmt.Errorf("nope"))
 }
 
+type customBlock struct{}
+
+func (c customBlock) Name() string {
+	return "CustomBlock"
+}
+
+func (c customBlock) Drainable(*drainability.DrainContext, *apiv1.Pod, *schedulerframework.NodeInfo) drainability.Status {
+	return drainability.NewCustomBlockedStatus("example.com/MigrationPending", map[string]string{"volume": "data"}, fmt.Errorf("nope"))
+}
+
 type cantDecide struct{}
 
 func (c cantDecide) Name() string {
 	return "CantDecide"
 }
 
-func (c cantDecide) Drainable(*drainability.DrainContext, *apiv1.Pod) drainability.Status {
+func (c cantDecide) Drainable(*drainability.DrainContext, *apiv1.Pod, *schedulerframework.NodeInfo) drainability.Status {
+	return drainability.NewUndefinedStatus()
+}
+
+func TestGetPodsToMovePopulatesDrainContext(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	deleteOptions := options.NodeDeleteOptions{
+		SkipNodesWithSystemPods: true,
+		MinReplicaCount:         3,
+	}
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	tracker := pdb.NewBasicRemainingPdbTracker()
+	pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+
+	rule := &contextRecorder{}
+	nodeInfo := schedulerframework.NewNodeInfo(pod)
+	_, _, _, err := GetPodsToMove(nodeInfo, deleteOptions, rules.Rules{rule}, registry, tracker, testTime)
+	assert.NoError(t, err)
+	assert.Same(t, nodeInfo, rule.nodeInfo)
+	if assert.NotNil(t, rule.drainCtx) {
+		assert.Equal(t, testTime, rule.drainCtx.Timestamp)
+		assert.Equal(t, deleteOptions, rule.drainCtx.DeleteOptions)
+		assert.Equal(t, registry, rule.drainCtx.Listers)
+		assert.Equal(t, tracker, rule.drainCtx.RemainingPdbTracker)
+	}
+}
+
+func TestStrictPodsToMove(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	pod := BuildTestPod("pod", 100, 0)
+	nodeInfo := schedulerframework.NewNodeInfo(pod)
+
+	p, _, b, err := GetPodsToMove(nodeInfo, options.NodeDeleteOptions{}, rules.Rules{cantDecide{}}, nil, nil, testTime)
+	assert.NoError(t, err)
+	assert.Nil(t, b)
+	assert.Equal(t, []*apiv1.Pod{pod}, p)
+
+	result := StrictPodsToMove(nodeInfo, options.NodeDeleteOptions{}, rules.Rules{cantDecide{}}, nil, nil, testTime)
+	assert.Error(t, result.Err)
+	assert.Empty(t, result.PodsToMove)
+	assert.Equal(t, &drain.BlockingPod{Pod: pod, Reason: drain.NotSafeToEvictAnnotation}, result.BlockingPod)
+
+	result = StrictPodsToMove(nodeInfo, options.NodeDeleteOptions{}, rules.Rules{alwaysDrain{}}, nil, nil, testTime)
+	assert.NoError(t, result.Err)
+	assert.Nil(t, result.BlockingPod)
+	assert.Equal(t, []*apiv1.Pod{pod}, result.PodsToMove)
+}
+
+func TestSimulateDrain(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	deleteOptions := options.NodeDeleteOptions{MaxGracefulTerminationSec: 60}
+	fastGracePeriod, slowGracePeriod := int64(10), int64(600)
+	fastPod := BuildTestPod("fast", 100, 0)
+	fastPod.Spec.TerminationGracePeriodSeconds = &fastGracePeriod
+	slowPod := BuildTestPod("slow", 100, 0)
+	slowPod.Spec.TerminationGracePeriodSeconds = &slowGracePeriod
+	nodeInfo := schedulerframework.NewNodeInfo(fastPod, slowPod)
+
+	result := SimulateDrain(nodeInfo, deleteOptions, rules.Rules{alwaysDrain{}}, nil, nil, testTime)
+	assert.NoError(t, result.Err)
+	assert.Nil(t, result.BlockingPod)
+	assert.Equal(t, []*apiv1.Pod{fastPod, slowPod}, result.PodsToMove)
+	assert.Equal(t, []PodVerdict{
+		{Pod: fastPod, Status: drainability.NewDrainableStatus()},
+		{Pod: slowPod, Status: drainability.NewDrainableStatus()},
+	}, result.Verdicts)
+	// The grace period of the slow pod is capped at MaxGracefulTerminationSec.
+	assert.Equal(t, 60*time.Second, result.EstimatedDrainDuration)
+
+	result = SimulateDrain(nodeInfo, deleteOptions, rules.Rules{neverDrain{}}, nil, nil, testTime)
+	assert.Error(t, result.Err)
+	assert.Empty(t, result.PodsToMove)
+	assert.Equal(t, &drain.BlockingPod{Pod: fastPod, Reason: drain.UnexpectedError, Rule: "NeverDrain"}, result.BlockingPod)
+	// All pods blocking the drain are reported.
+	assert.Equal(t, []*drain.BlockingPod{
+		{Pod: fastPod, Reason: drain.UnexpectedError, Rule: "NeverDrain"},
+		{Pod: slowPod, Reason: drain.UnexpectedError, Rule: "NeverDrain"},
+	}, result.BlockingPods)
+	assert.Len(t, result.Verdicts, 2)
+
+	pods, _, blockingPod, err := GetPodsToMove(nodeInfo, deleteOptions, rules.Rules{alwaysDrain{}}, nil, nil, testTime)
+	assert.NoError(t, err)
+	assert.Nil(t, blockingPod)
+	assert.Equal(t, []*apiv1.Pod{fastPod, slowPod}, pods)
+}
+
+func TestSimulateDrainEvictionGroups(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	web0 := BuildTestPod("web-0", 100, 0)
+	web0.OwnerReferences = GenerateOwnerReferences("web", "StatefulSet", "apps/v1", "web-uid")
+	web1 := BuildTestPod("web-1", 100, 0)
+	web1.OwnerReferences = GenerateOwnerReferences("web", "StatefulSet", "apps/v1", "web-uid")
+	nodeInfo := schedulerframework.NewNodeInfo(web0, web1)
+
+	result := SimulateDrain(nodeInfo, options.NodeDeleteOptions{}, rules.Rules{alwaysDrain{}}, nil, nil, testTime)
+	assert.Equal(t, []EvictionGroup{{Pods: []*apiv1.Pod{web0, web1}}}, result.EvictionGroups)
+
+	result = SimulateDrain(nodeInfo, options.NodeDeleteOptions{StatefulSetOrdinalOrder: true}, rules.Rules{alwaysDrain{}}, nil, nil, testTime)
+	assert.Equal(t, []EvictionGroup{
+		{Pods: []*apiv1.Pod{web1}},
+		{Pods: []*apiv1.Pod{web0}, AwaitReady: []*apiv1.Pod{web1}},
+	}, result.EvictionGroups)
+}
+
+func TestIgnoreAllPodsToMove(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	pod := BuildTestPod("pod", 100, 0)
+	mirrorPod := SetMirrorPodSpec(BuildTestPod("mirror", 100, 0))
+	dsPod := BuildTestPod("ds", 100, 0)
+	dsPod.OwnerReferences = GenerateOwnerReferences("ds", "DaemonSet", "apps/v1", "")
+
+	result := IgnoreAllPodsToMove(schedulerframework.NewNodeInfo(pod, mirrorPod, dsPod), options.NodeDeleteOptions{}, rules.Rules{neverDrain{}}, nil, nil, testTime)
+	assert.NoError(t, result.Err)
+	assert.Nil(t, result.BlockingPod)
+	assert.Equal(t, []*apiv1.Pod{pod}, result.PodsToMove)
+	assert.Equal(t, []*apiv1.Pod{dsPod}, result.DaemonSetPods)
+}
+
+type contextRecorder struct {
+	drainCtx *drainability.DrainContext
+	nodeInfo *schedulerframework.NodeInfo
+}
+
+func (c *contextRecorder) Name() string {
+	return "ContextRecorder"
+}
+
+func (c *contextRecorder) Drainable(drainCtx *drainability.DrainContext, _ *apiv1.Pod, nodeInfo *schedulerframework.NodeInfo) drainability.Status {
+	c.drainCtx = drainCtx
+	c.nodeInfo = nodeInfo
 	return drainability.NewUndefinedStatus()
 }
@@ -19,13 +19,29 @@ package drainability
 import (
 	"time"
 
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
-	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
 )
 
-// DrainContext contains parameters for drainability rules.
+// DrainContext contains parameters for drainability rules. Rules don't modify
+// DrainContext, so it is safe to evaluate rules concurrently as long as the
+// RemainingPdbTracker is goroutine-safe.
 type DrainContext struct {
 	RemainingPdbTracker pdb.RemainingPdbTracker
-	Listers             kube_util.ListerRegistry
+	Listers             Listers
 	Timestamp           time.Time
+	DeleteOptions       options.NodeDeleteOptions
+}
+
+// MarkForEviction records that the pods will be evicted by the simulated
+// drain, decrementing DisruptionsAllowed of the disruption budgets matching
+// them in RemainingPdbTracker. Rules evaluated against the same DrainContext
+// afterwards, e.g. for pods of the next node drained in the same loop, see the
+// budgets used up. It is meant to be called by simulations, not by rules.
+func (c *DrainContext) MarkForEviction(pods ...*apiv1.Pod) {
+	if c.RemainingPdbTracker == nil {
+		return
+	}
+	c.RemainingPdbTracker.RemovePods(pods)
 }
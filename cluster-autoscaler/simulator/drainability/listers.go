@@ -0,0 +1,50 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drainability
+
+import (
+	apiv1 "k8s.io/api/core/v1"
+	v1appslister "k8s.io/client-go/listers/apps/v1"
+	v1batchlister "k8s.io/client-go/listers/batch/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+)
+
+// Listers provides the listers of cluster objects used by drainability rules,
+// e.g. to find the controllers of pods. The ListerRegistry of Cluster
+// Autoscaler implements it.
+type Listers interface {
+	AllNodeLister() NodeLister
+	AllPodLister() PodLister
+	DaemonSetLister() v1appslister.DaemonSetLister
+	ReplicationControllerLister() v1lister.ReplicationControllerLister
+	JobLister() v1batchlister.JobLister
+	ReplicaSetLister() v1appslister.ReplicaSetLister
+	StatefulSetLister() v1appslister.StatefulSetLister
+	NamespaceLister() v1lister.NamespaceLister
+	DeploymentLister() v1appslister.DeploymentLister
+}
+
+// PodLister lists all pods.
+type PodLister interface {
+	List() ([]*apiv1.Pod, error)
+}
+
+// NodeLister lists nodes.
+type NodeLister interface {
+	List() ([]*apiv1.Node, error)
+	Get(name string) (*apiv1.Node, error)
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	caNamespace = "cluster_autoscaler"
+)
+
+// Names of caches used when evaluating drainability, as reported in metrics.
+const (
+	// WebhookCache caches drainability webhook responses.
+	WebhookCache = "webhook"
+	// ScaleResourcesCache caches discovery of resources implementing the scale subresource.
+	ScaleResourcesCache = "scale_resources"
+	// ScaleReplicasCache caches replica counts read from the scale subresource.
+	ScaleReplicasCache = "scale_replicas"
+	// PdbSelectorsCache caches label selectors parsed from PDBs.
+	PdbSelectorsCache = "pdb_selectors"
+	// PinnedNodesCache caches nodes matching required node affinities of pods.
+	PinnedNodesCache = "pinned_nodes"
+)
+
+var (
+	ruleBlockedPodsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "drainability_rule_blocked_pods_total",
+			Help:      "Number of times a pod was found to block node drain, by the drainability rule that decided it.",
+		}, []string{"rule"},
+	)
+
+	shadowRuleVerdictsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "drainability_shadow_rule_verdicts_total",
+			Help:      "Number of times a drainability rule in shadow mode would have changed the outcome for a pod, by rule and the outcome it would have decided.",
+		}, []string{"rule", "outcome"},
+	)
+
+	blockingPodsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "drainability_blocking_pods_total",
+			Help:      "Number of times a node drain was blocked by a pod, by blocking reason.",
+		}, []string{"reason"},
+	)
+
+	evaluationDuration = k8smetrics.NewHistogram(
+		&k8smetrics.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "drainability_evaluation_duration_seconds",
+			Help:      "Cumulative time spent evaluating drainability of pods in a single CA loop.",
+			Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1.0, 2.5, 5.0, 10.0, 30.0},
+		},
+	)
+
+	ruleEvaluationDuration = k8smetrics.NewHistogramVec(
+		&k8smetrics.HistogramOpts{
+			Namespace: caNamespace,
+			Name:      "drainability_rule_evaluation_duration_seconds",
+			Help:      "Time spent by a drainability rule evaluating a single pod.",
+			Buckets:   k8smetrics.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"rule"},
+	)
+
+	cacheLookupsCount = k8smetrics.NewCounterVec(
+		&k8smetrics.CounterOpts{
+			Namespace: caNamespace,
+			Name:      "drainability_cache_lookups_total",
+			Help:      "Number of lookups in caches used by drainability rules, by cache and result (hit or miss).",
+		}, []string{"cache", "result"},
+	)
+
+	// loopEvaluationDuration accumulates evaluation time, in nanoseconds,
+	// since the last ObserveLoopEvaluationDuration call.
+	loopEvaluationDuration atomic.Int64
+)
+
+// Register registers drainability metrics.
+func Register() {
+	legacyregistry.MustRegister(ruleBlockedPodsCount)
+	legacyregistry.MustRegister(shadowRuleVerdictsCount)
+	legacyregistry.MustRegister(blockingPodsCount)
+	legacyregistry.MustRegister(evaluationDuration)
+	legacyregistry.MustRegister(ruleEvaluationDuration)
+	legacyregistry.MustRegister(cacheLookupsCount)
+}
+
+// RegisterRuleBlockedPod records that a given rule decided a pod blocks node drain.
+func RegisterRuleBlockedPod(rule string) {
+	ruleBlockedPodsCount.WithLabelValues(rule).Inc()
+}
+
+// RegisterShadowRuleVerdict records that a given rule in shadow mode would
+// have changed the outcome for a pod to the given one.
+func RegisterShadowRuleVerdict(rule, outcome string) {
+	shadowRuleVerdictsCount.WithLabelValues(rule, outcome).Inc()
+}
+
+// RegisterBlockingPod records that node drain was blocked by a pod for a given reason.
+func RegisterBlockingPod(reason drain.BlockingPodReason) {
+	blockingPodsCount.WithLabelValues(reason.String()).Inc()
+}
+
+// ObserveRuleEvaluationDuration records time spent by a given rule evaluating a pod.
+func ObserveRuleEvaluationDuration(rule string, duration time.Duration) {
+	ruleEvaluationDuration.WithLabelValues(rule).Observe(duration.Seconds())
+}
+
+// RegisterCacheLookup records a hit or a miss in a given cache.
+func RegisterCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookupsCount.WithLabelValues(cache, result).Inc()
+}
+
+// AddEvaluationDuration records time spent evaluating drainability. It is
+// safe to call concurrently.
+func AddEvaluationDuration(duration time.Duration) {
+	loopEvaluationDuration.Add(int64(duration))
+}
+
+// ObserveLoopEvaluationDuration records the time spent evaluating
+// drainability since the previous call. It should be called once per CA loop.
+func ObserveLoopEvaluationDuration() {
+	evaluationDuration.Observe(time.Duration(loopEvaluationDuration.Swap(0)).Seconds())
+}
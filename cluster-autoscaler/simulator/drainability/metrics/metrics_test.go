@@ -0,0 +1,77 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	k8smetrics "k8s.io/component-base/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	// Using a separate registry, as registering metrics in the legacy registry multiple times panics.
+	registry := k8smetrics.NewKubeRegistry()
+	registry.MustRegister(ruleBlockedPodsCount, shadowRuleVerdictsCount, blockingPodsCount, evaluationDuration, ruleEvaluationDuration, cacheLookupsCount)
+
+	RegisterRuleBlockedPod("PDB")
+	RegisterRuleBlockedPod("PDB")
+	RegisterBlockingPod(drain.NotEnoughPdb)
+	assert.Equal(t, 2, int(testutil.ToFloat64(ruleBlockedPodsCount.CounterVec.WithLabelValues("PDB"))))
+	assert.Equal(t, 1, int(testutil.ToFloat64(blockingPodsCount.CounterVec.WithLabelValues("NotEnoughPdb"))))
+	RegisterShadowRuleVerdict("LocalPersistentVolume", "BlockDrain")
+	assert.Equal(t, 1, int(testutil.ToFloat64(shadowRuleVerdictsCount.CounterVec.WithLabelValues("LocalPersistentVolume", "BlockDrain"))))
+
+	AddEvaluationDuration(time.Second)
+	AddEvaluationDuration(time.Second)
+	ObserveLoopEvaluationDuration()
+	assert.Equal(t, int64(0), loopEvaluationDuration.Load())
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	found := false
+	for _, family := range families {
+		if family.GetName() == "cluster_autoscaler_drainability_evaluation_duration_seconds" {
+			found = true
+			histogram := family.GetMetric()[0].GetHistogram()
+			assert.Equal(t, uint64(1), histogram.GetSampleCount())
+			assert.Equal(t, 2.0, histogram.GetSampleSum())
+		}
+	}
+	assert.True(t, found)
+
+	ObserveRuleEvaluationDuration("PDB", time.Millisecond)
+	RegisterCacheLookup(WebhookCache, true)
+	RegisterCacheLookup(WebhookCache, true)
+	RegisterCacheLookup(WebhookCache, false)
+	assert.Equal(t, 2, int(testutil.ToFloat64(cacheLookupsCount.CounterVec.WithLabelValues("webhook", "hit"))))
+	assert.Equal(t, 1, int(testutil.ToFloat64(cacheLookupsCount.CounterVec.WithLabelValues("webhook", "miss"))))
+	families, err = registry.Gather()
+	assert.NoError(t, err)
+	found = false
+	for _, family := range families {
+		if family.GetName() == "cluster_autoscaler_drainability_rule_evaluation_duration_seconds" {
+			found = true
+			metric := family.GetMetric()[0]
+			assert.Equal(t, "PDB", metric.GetLabel()[0].GetValue())
+			assert.Equal(t, uint64(1), metric.GetHistogram().GetSampleCount())
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,242 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// SkipNodesWithSystemPodsLabelKey is the node label overriding SkipNodesWithSystemPods for a given node.
+	SkipNodesWithSystemPodsLabelKey = "cluster-autoscaler.kubernetes.io/skip-nodes-with-system-pods"
+	// SkipNodesWithLocalStorageLabelKey is the node label overriding SkipNodesWithLocalStorage for a given node.
+	SkipNodesWithLocalStorageLabelKey = "cluster-autoscaler.kubernetes.io/skip-nodes-with-local-storage"
+	// MinReplicaCountLabelKey is the node label overriding MinReplicaCount for a given node.
+	MinReplicaCountLabelKey = "cluster-autoscaler.kubernetes.io/min-replica-count"
+	// WindowsOS is the value of the kubernetes.io/os label on Windows nodes.
+	WindowsOS = "windows"
+)
+
+// DrainMode determines how pods are removed from nodes during scale down.
+type DrainMode string
+
+const (
+	// EvictDrainMode removes pods with the eviction subresource only.
+	EvictDrainMode DrainMode = "Evict"
+	// EvictOrDeleteDrainMode removes pods with the eviction subresource, but
+	// falls back to deleting them if evictions are persistently denied by
+	// admission webhooks, e.g. a misbehaving one. Disruption budgets are still respected by scale down
+	// simulation, but not enforced by the API server for deleted pods.
+	EvictOrDeleteDrainMode DrainMode = "EvictOrDelete"
+)
+
+// ParseDrainMode parses a DrainMode from its name.
+func ParseDrainMode(name string) (DrainMode, error) {
+	switch mode := DrainMode(name); mode {
+	case EvictDrainMode, EvictOrDeleteDrainMode:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown drain mode %q, expected one of: %s, %s", name, EvictDrainMode, EvictOrDeleteDrainMode)
+}
+
+// WebhookDenialPolicy determines how evictions denied by validating admission
+// webhooks are handled during scale down.
+type WebhookDenialPolicy string
+
+const (
+	// RetryWebhookDenialPolicy retries denied evictions like other failed
+	// evictions, until the pod eviction time runs out.
+	RetryWebhookDenialPolicy WebhookDenialPolicy = "Retry"
+	// SkipNodeWebhookDenialPolicy aborts drain of the node on the first
+	// denied eviction, so that the node isn't left half-drained while the
+	// eviction is retried. Pods whose evictions haven't started yet aren't
+	// evicted and the drain fails.
+	SkipNodeWebhookDenialPolicy WebhookDenialPolicy = "SkipNode"
+	// ForceDeleteWebhookDenialPolicy deletes pods whose evictions keep being
+	// denied for WebhookDenialTimeout. Disruption budgets are still respected
+	// by scale down simulation, but not enforced by the API server for
+	// deleted pods.
+	ForceDeleteWebhookDenialPolicy WebhookDenialPolicy = "ForceDelete"
+)
+
+// ParseWebhookDenialPolicy parses a WebhookDenialPolicy from its name.
+func ParseWebhookDenialPolicy(name string) (WebhookDenialPolicy, error) {
+	switch policy := WebhookDenialPolicy(name); policy {
+	case RetryWebhookDenialPolicy, SkipNodeWebhookDenialPolicy, ForceDeleteWebhookDenialPolicy:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown webhook denial policy %q, expected one of: %s, %s, %s", name, RetryWebhookDenialPolicy, SkipNodeWebhookDenialPolicy, ForceDeleteWebhookDenialPolicy)
+}
+
+// NodeDeleteOptions contains various options to customize how draining will behave
+type NodeDeleteOptions struct {
+	// SkipNodesWithSystemPods is true if nodes with kube-system pods should be
+	// deleted (except for DaemonSet or mirror pods).
+	SkipNodesWithSystemPods bool
+	// SystemPodNamespaces are namespaces whose pods are treated like
+	// kube-system pods, in addition to kube-system itself.
+	SystemPodNamespaces []string
+	// SkipNodesWithLocalStorage is true if nodes with pods using local storage
+	// (e.g. EmptyDir or HostPath) should be deleted.
+	SkipNodesWithLocalStorage bool
+	// SkipNodesWithCustomControllerPods is true if nodes with
+	// custom-controller-owned pods should be skipped.
+	SkipNodesWithCustomControllerPods bool
+	// SkipNodesWithStaticPods is true if nodes with kubelet static pods
+	// should be skipped, unless the static pods are annotated as drainable.
+	SkipNodesWithStaticPods bool
+	// MinReplicaCount determines the minimum number of replicas that a replica
+	// set or replication controller should have to allow pod deletion during
+	// scale down.
+	MinReplicaCount int
+	// MaxGracefulTerminationSec is the maximum number of seconds scale down
+	// waits for pods to terminate, unless overridden per pod.
+	MaxGracefulTerminationSec int
+	// WindowsMaxGracefulTerminationSec replaces MaxGracefulTerminationSec on
+	// Windows nodes, whose containers usually take longer to stop. If 0,
+	// MaxGracefulTerminationSec is used on all nodes.
+	WindowsMaxGracefulTerminationSec int
+	// LongTerminatingPodThreshold is the time after which a pod that has run
+	// over its termination grace period is ignored during scale down.
+	LongTerminatingPodThreshold time.Duration
+	// DrainMode determines how pods are removed from nodes. Empty DrainMode
+	// is equivalent to EvictDrainMode.
+	DrainMode DrainMode
+	// NodeDrainTimeout is the maximum time to wait for evicted pods to
+	// terminate. Pods remaining afterwards are force deleted. If 0, pods
+	// are awaited for their drain grace period plus headroom and the drain
+	// fails if any of them remain.
+	NodeDrainTimeout time.Duration
+	// WebhookDenialPolicy determines how evictions denied by validating
+	// admission webhooks are handled. Empty WebhookDenialPolicy is equivalent
+	// to RetryWebhookDenialPolicy.
+	WebhookDenialPolicy WebhookDenialPolicy
+	// WebhookDenialTimeout is how long evictions of a pod have to be denied
+	// by admission webhooks before the pod is deleted, if
+	// ForceDeleteWebhookDenialPolicy is used.
+	WebhookDenialTimeout time.Duration
+	// WaitForReschedulingBeforeCriticalPods tells if evictions of pods with
+	// at least CriticalPodPriority should start only once the lower-priority
+	// pods evicted from the node before them have been rescheduled.
+	WaitForReschedulingBeforeCriticalPods bool
+	// CriticalPodPriority is the lowest priority of pods which are evicted
+	// only once lower-priority pods have been rescheduled, if
+	// WaitForReschedulingBeforeCriticalPods is set.
+	CriticalPodPriority int32
+	// OneOffPodMaxLifetime is how long unreplicated pods with restartPolicy
+	// Never or OnFailure, which are expected to finish on their own, block
+	// node drain. Afterwards they are removed like replicated pods. If 0,
+	// they block node drain like other unreplicated pods.
+	OneOffPodMaxLifetime time.Duration
+	// StatefulSetOrdinalOrder tells if pods of the same StatefulSet should
+	// be evicted one at a time in reverse ordinal order, waiting for
+	// replacements to be ready in between for OrderedReady StatefulSets.
+	StatefulSetOrdinalOrder bool
+	// RespectDaemonSetPdbs tells if DaemonSet pods should be subject to the
+	// disruption budgets matching them, like other pods. Drain is blocked by
+	// DaemonSet pods whose budgets are exhausted, their removal uses up the
+	// budgets in scale down simulation, and their evictions are ordered
+	// across nodes drained in parallel.
+	RespectDaemonSetPdbs bool
+	// Reloader, if set, reloads some of the options at runtime. The reloaded
+	// values are applied by ForNode, before node label overrides.
+	Reloader Reloader `json:"-"`
+}
+
+// Reloader reloads node delete options at runtime, without a restart.
+type Reloader interface {
+	// Reload returns the options with the reloaded values applied.
+	Reload(o NodeDeleteOptions) NodeDeleteOptions
+	// Refresh reads the reloaded values again. It's called once per
+	// autoscaler loop, so that Reload stays cheap.
+	Refresh()
+}
+
+// IsSystemNamespace tells if pods in the namespace are system pods, i.e. if
+// the namespace is kube-system or one of SystemPodNamespaces.
+func (o NodeDeleteOptions) IsSystemNamespace(namespace string) bool {
+	if namespace == metav1.NamespaceSystem {
+		return true
+	}
+	for _, ns := range o.SystemPodNamespaces {
+		if ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// ForNode returns node delete options that should be used for a given node.
+// SkipNodesWithSystemPods, SkipNodesWithLocalStorage and MinReplicaCount can
+// be overridden with node labels, which allows node groups to carry their own
+// settings, e.g. via node group labels. Invalid label values are ignored.
+// Values reloaded by the Reloader, if any, replace the configured ones first.
+// MaxGracefulTerminationSec depends on the operating system of the node, see
+// MaxGracefulTerminationSecForOS.
+func (o NodeDeleteOptions) ForNode(node *apiv1.Node) NodeDeleteOptions {
+	if o.Reloader != nil {
+		o = o.Reloader.Reload(o)
+	}
+	if node == nil {
+		return o
+	}
+	o.MaxGracefulTerminationSec = o.MaxGracefulTerminationSecForOS(node, o.MaxGracefulTerminationSec)
+	if value, found := node.Labels[SkipNodesWithSystemPodsLabelKey]; found {
+		if skip, err := strconv.ParseBool(value); err == nil {
+			o.SkipNodesWithSystemPods = skip
+		} else {
+			klog.Warningf("Ignoring invalid %s label value %q on node %s: %v", SkipNodesWithSystemPodsLabelKey, value, node.Name, err)
+		}
+	}
+	if value, found := node.Labels[SkipNodesWithLocalStorageLabelKey]; found {
+		if skip, err := strconv.ParseBool(value); err == nil {
+			o.SkipNodesWithLocalStorage = skip
+		} else {
+			klog.Warningf("Ignoring invalid %s label value %q on node %s: %v", SkipNodesWithLocalStorageLabelKey, value, node.Name, err)
+		}
+	}
+	if value, found := node.Labels[MinReplicaCountLabelKey]; found {
+		if count, err := strconv.Atoi(value); err == nil && count >= 0 {
+			o.MinReplicaCount = count
+		} else {
+			klog.Warningf("Ignoring invalid %s label value %q on node %s", MinReplicaCountLabelKey, value, node.Name)
+		}
+	}
+	return o
+}
+
+// MaxGracefulTerminationSecForOS returns WindowsMaxGracefulTerminationSec for
+// Windows nodes if it's set, or defaultValue otherwise. The Windows value takes
+// precedence over MaxGracefulTerminationSec configured for node groups.
+func (o NodeDeleteOptions) MaxGracefulTerminationSecForOS(node *apiv1.Node, defaultValue int) int {
+	if IsWindowsNode(node) && o.WindowsMaxGracefulTerminationSec > 0 {
+		return o.WindowsMaxGracefulTerminationSec
+	}
+	return defaultValue
+}
+
+// IsWindowsNode tells if the node runs Windows, according to its
+// kubernetes.io/os label.
+func IsWindowsNode(node *apiv1.Node) bool {
+	return node != nil && node.Labels[apiv1.LabelOSStable] == WindowsOS
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package options
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestForNode(t *testing.T) {
+	defaults := NodeDeleteOptions{
+		SkipNodesWithSystemPods:   true,
+		SkipNodesWithLocalStorage: true,
+		MinReplicaCount:           0,
+		MaxGracefulTerminationSec: 600,
+	}
+
+	for desc, tc := range map[string]struct {
+		node *apiv1.Node
+		want NodeDeleteOptions
+	}{
+		"nil node": {
+			want: defaults,
+		},
+		"no labels": {
+			node: &apiv1.Node{},
+			want: defaults,
+		},
+		"all overridden": {
+			node: &apiv1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						SkipNodesWithSystemPodsLabelKey:   "false",
+						SkipNodesWithLocalStorageLabelKey: "false",
+						MinReplicaCountLabelKey:           "3",
+					},
+				},
+			},
+			want: NodeDeleteOptions{
+				SkipNodesWithSystemPods:   false,
+				SkipNodesWithLocalStorage: false,
+				MinReplicaCount:           3,
+				MaxGracefulTerminationSec: 600,
+			},
+		},
+		"windows node without windows limit": {
+			node: &apiv1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{apiv1.LabelOSStable: WindowsOS},
+				},
+			},
+			want: defaults,
+		},
+		"invalid values ignored": {
+			node: &apiv1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						SkipNodesWithSystemPodsLabelKey:   "maybe",
+						SkipNodesWithLocalStorageLabelKey: "",
+						MinReplicaCountLabelKey:           "-1",
+					},
+				},
+			},
+			want: defaults,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			got := defaults.ForNode(tc.node)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ForNode(): got options diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMaxGracefulTerminationSecForOS(t *testing.T) {
+	options := NodeDeleteOptions{WindowsMaxGracefulTerminationSec: 1800}
+	linuxNode := &apiv1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{apiv1.LabelOSStable: "linux"}}}
+	windowsNode := &apiv1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{apiv1.LabelOSStable: WindowsOS}}}
+
+	if got := options.MaxGracefulTerminationSecForOS(linuxNode, 600); got != 600 {
+		t.Errorf("MaxGracefulTerminationSecForOS(linux node) = %d, want 600", got)
+	}
+	if got := options.MaxGracefulTerminationSecForOS(&apiv1.Node{}, 600); got != 600 {
+		t.Errorf("MaxGracefulTerminationSecForOS(node without OS label) = %d, want 600", got)
+	}
+	if got := options.MaxGracefulTerminationSecForOS(windowsNode, 600); got != 1800 {
+		t.Errorf("MaxGracefulTerminationSecForOS(windows node) = %d, want 1800", got)
+	}
+	if got := (NodeDeleteOptions{}).MaxGracefulTerminationSecForOS(windowsNode, 600); got != 600 {
+		t.Errorf("MaxGracefulTerminationSecForOS(windows node) without windows limit = %d, want 600", got)
+	}
+
+	options.MaxGracefulTerminationSec = 600
+	if got := options.ForNode(windowsNode).MaxGracefulTerminationSec; got != 1800 {
+		t.Errorf("ForNode(windows node).MaxGracefulTerminationSec = %d, want 1800", got)
+	}
+}
+
+func TestIsSystemNamespace(t *testing.T) {
+	opts := NodeDeleteOptions{SystemPodNamespaces: []string{"monitoring", "istio-system"}}
+	for namespace, want := range map[string]bool{
+		"kube-system":  true,
+		"monitoring":   true,
+		"istio-system": true,
+		"default":      false,
+		"":             false,
+	} {
+		if got := opts.IsSystemNamespace(namespace); got != want {
+			t.Errorf("IsSystemNamespace(%q): got %v, want %v", namespace, got, want)
+		}
+	}
+	if !(NodeDeleteOptions{}).IsSystemNamespace("kube-system") {
+		t.Errorf("IsSystemNamespace(%q) without SystemPodNamespaces: got false, want true", "kube-system")
+	}
+}
+
+type testReloader struct {
+	skipNodesWithSystemPods bool
+}
+
+func (r testReloader) Reload(o NodeDeleteOptions) NodeDeleteOptions {
+	o.SkipNodesWithSystemPods = r.skipNodesWithSystemPods
+	return o
+}
+
+func (r testReloader) Refresh() {}
+
+func TestForNodeReloaded(t *testing.T) {
+	o := NodeDeleteOptions{SkipNodesWithSystemPods: true, SkipNodesWithLocalStorage: true, Reloader: testReloader{skipNodesWithSystemPods: false}}
+
+	if got := o.ForNode(&apiv1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}); got.SkipNodesWithSystemPods || !got.SkipNodesWithLocalStorage {
+		t.Errorf("ForNode(): got %+v, want the reloaded SkipNodesWithSystemPods", got)
+	}
+	labeled := &apiv1.Node{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Labels: map[string]string{SkipNodesWithSystemPodsLabelKey: "true"}}}
+	if got := o.ForNode(labeled); !got.SkipNodesWithSystemPods {
+		t.Errorf("ForNode(): got %+v, want the node label to override the reloaded SkipNodesWithSystemPods", got)
+	}
+}
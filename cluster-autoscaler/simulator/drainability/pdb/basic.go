@@ -19,9 +19,8 @@ package pdb
 import (
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 )
 
 type pdbInfo struct {
@@ -31,19 +30,20 @@ type pdbInfo struct {
 
 // basicRemainingPdbTracker is the basic implementation of RemainingPdbTracker
 type basicRemainingPdbTracker struct {
-	pdbInfos []*pdbInfo
+	pdbInfos  []*pdbInfo
+	selectors *SelectorCache
 }
 
 // NewBasicRemainingPdbTracker returns a new instance of basicRemainingPdbTracker
 func NewBasicRemainingPdbTracker() *basicRemainingPdbTracker {
-	return &basicRemainingPdbTracker{}
+	return &basicRemainingPdbTracker{selectors: defaultSelectorCache}
 }
 
 func (t *basicRemainingPdbTracker) SetPdbs(pdbs []*policyv1.PodDisruptionBudget) error {
 	t.Clear()
 	for _, pdb := range pdbs {
 		pdbCopy := pdb.DeepCopy()
-		selector, err := metav1.LabelSelectorAsSelector(pdbCopy.Spec.Selector)
+		selector, err := t.selectors.Selector(pdbCopy)
 		if err != nil {
 			return err
 		}
@@ -106,3 +106,15 @@ func (t *basicRemainingPdbTracker) RemovePods(pods []*apiv1.Pod) {
 func (t *basicRemainingPdbTracker) Clear() {
 	t.pdbInfos = nil
 }
+
+func (t *basicRemainingPdbTracker) Clone() RemainingPdbTracker {
+	clone := &basicRemainingPdbTracker{selectors: t.selectors}
+	for _, info := range t.pdbInfos {
+		// Selectors are immutable, so they can be shared between the copies.
+		clone.pdbInfos = append(clone.pdbInfos, &pdbInfo{
+			pdb:      info.pdb.DeepCopy(),
+			selector: info.selector,
+		})
+	}
+	return clone
+}
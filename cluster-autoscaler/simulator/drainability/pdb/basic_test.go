@@ -26,7 +26,7 @@ import (
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	. "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 )
 
 var (
@@ -19,7 +19,7 @@ package pdb
 import (
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 )
 
 // RemainingPdbTracker is responsible for tracking the remaining PDBs
@@ -40,4 +40,7 @@ type RemainingPdbTracker interface {
 
 	// Clear resets the remaining PDB tracker to empty state.
 	Clear()
+	// Clone returns an independent copy of the remaining PDB tracker. Changes
+	// made to the copy are not reflected in the original and vice versa.
+	Clone() RemainingPdbTracker
 }
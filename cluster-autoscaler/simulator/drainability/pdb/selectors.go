@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdb
+
+import (
+	"sync"
+	"time"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/metrics"
+)
+
+// selectorCacheTTL is the time after which selectors of PDBs that weren't
+// looked up are dropped from the cache.
+const selectorCacheTTL = 10 * time.Minute
+
+// defaultSelectorCache is shared by all basic trackers, so that trackers
+// created for a single drain don't parse selectors again.
+var defaultSelectorCache = NewSelectorCache()
+
+// SelectorCache caches parsed PDB selectors keyed on PDB UID and generation.
+// It is safe for concurrent use.
+type SelectorCache struct {
+	now func() time.Time
+
+	mutex     sync.Mutex
+	entries   map[types.UID]*selectorEntry
+	lastPurge time.Time
+}
+
+type selectorEntry struct {
+	generation int64
+	selector   labels.Selector
+	lastUsed   time.Time
+}
+
+// NewSelectorCache creates a new SelectorCache.
+func NewSelectorCache() *SelectorCache {
+	return &SelectorCache{
+		now:     time.Now,
+		entries: make(map[types.UID]*selectorEntry),
+	}
+}
+
+// Selector returns the parsed selector of the PDB. PDBs without UID, e.g.
+// created in simulations, are parsed each time.
+func (c *SelectorCache) Selector(pdb *policyv1.PodDisruptionBudget) (labels.Selector, error) {
+	if pdb.UID == "" {
+		return metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	c.purge(now)
+	entry, found := c.entries[pdb.UID]
+	hit := found && entry.generation == pdb.Generation
+	metrics.RegisterCacheLookup(metrics.PdbSelectorsCache, hit)
+	if hit {
+		entry.lastUsed = now
+		return entry.selector, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		delete(c.entries, pdb.UID)
+		return nil, err
+	}
+	c.entries[pdb.UID] = &selectorEntry{generation: pdb.Generation, selector: selector, lastUsed: now}
+	return selector, nil
+}
+
+// purge drops entries unused for the TTL at most once per TTL, so that
+// selectors of deleted PDBs don't accumulate.
+func (c *SelectorCache) purge(now time.Time) {
+	if now.Sub(c.lastPurge) < selectorCacheTTL {
+		return
+	}
+	for uid, entry := range c.entries {
+		if now.Sub(entry.lastUsed) >= selectorCacheTTL {
+			delete(c.entries, uid)
+		}
+	}
+	c.lastPurge = now
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSelectorCache(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	cache := NewSelectorCache()
+	cache.now = func() time.Time { return now }
+
+	budget := testSelectorPdb("uid-1", 1, "a")
+	selector, err := cache.Selector(budget)
+	assert.NoError(t, err)
+	assert.True(t, selector.Matches(labels.Set{"app": "a"}))
+
+	// The cached selector is returned as long as the generation doesn't change.
+	cached, err := cache.Selector(testSelectorPdb("uid-1", 1, "b"))
+	assert.NoError(t, err)
+	assert.True(t, cached.Matches(labels.Set{"app": "a"}))
+
+	updated, err := cache.Selector(testSelectorPdb("uid-1", 2, "b"))
+	assert.NoError(t, err)
+	assert.True(t, updated.Matches(labels.Set{"app": "b"}))
+	assert.False(t, updated.Matches(labels.Set{"app": "a"}))
+
+	// PDBs without UID aren't cached.
+	noUID, err := cache.Selector(testSelectorPdb("", 1, "c"))
+	assert.NoError(t, err)
+	assert.True(t, noUID.Matches(labels.Set{"app": "c"}))
+	assert.Len(t, cache.entries, 1)
+
+	invalid := testSelectorPdb("uid-2", 1, "a")
+	invalid.Spec.Selector.MatchExpressions = []metav1.LabelSelectorRequirement{{Key: "app", Operator: "invalid"}}
+	_, err = cache.Selector(invalid)
+	assert.Error(t, err)
+	assert.Len(t, cache.entries, 1)
+
+	// Selectors unused for the TTL are dropped.
+	now = now.Add(selectorCacheTTL)
+	_, err = cache.Selector(testSelectorPdb("uid-3", 1, "a"))
+	assert.NoError(t, err)
+	_, found := cache.entries["uid-1"]
+	assert.False(t, found)
+	_, found = cache.entries["uid-3"]
+	assert.True(t, found)
+}
+
+func testSelectorPdb(uid string, generation int64, app string) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "pdb",
+			Namespace:  "ns",
+			UID:        types.UID(uid),
+			Generation: generation,
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+		},
+	}
+}
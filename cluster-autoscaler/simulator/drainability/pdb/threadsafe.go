@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdb
+
+import (
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+)
+
+// threadSafeRemainingPdbTracker is a RemainingPdbTracker wrapper that
+// serializes access to the underlying tracker, so it can be shared between
+// concurrently simulated drains.
+type threadSafeRemainingPdbTracker struct {
+	mutex   sync.RWMutex
+	tracker RemainingPdbTracker
+}
+
+// NewThreadSafeRemainingPdbTracker returns a new RemainingPdbTracker that is
+// safe for concurrent use and delegates to the provided tracker.
+func NewThreadSafeRemainingPdbTracker(tracker RemainingPdbTracker) *threadSafeRemainingPdbTracker {
+	return &threadSafeRemainingPdbTracker{tracker: tracker}
+}
+
+func (t *threadSafeRemainingPdbTracker) SetPdbs(pdbs []*policyv1.PodDisruptionBudget) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.tracker.SetPdbs(pdbs)
+}
+
+func (t *threadSafeRemainingPdbTracker) GetPdbs() []*policyv1.PodDisruptionBudget {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	var pdbs []*policyv1.PodDisruptionBudget
+	for _, pdb := range t.tracker.GetPdbs() {
+		pdbs = append(pdbs, pdb.DeepCopy())
+	}
+	return pdbs
+}
+
+func (t *threadSafeRemainingPdbTracker) MatchingPdbs(pod *apiv1.Pod) []*policyv1.PodDisruptionBudget {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	var pdbs []*policyv1.PodDisruptionBudget
+	for _, pdb := range t.tracker.MatchingPdbs(pod) {
+		pdbs = append(pdbs, pdb.DeepCopy())
+	}
+	return pdbs
+}
+
+func (t *threadSafeRemainingPdbTracker) CanRemovePods(pods []*apiv1.Pod) (canRemove, inParallel bool, blockingPod *drain.BlockingPod) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.tracker.CanRemovePods(pods)
+}
+
+func (t *threadSafeRemainingPdbTracker) RemovePods(pods []*apiv1.Pod) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.tracker.RemovePods(pods)
+}
+
+// TryRemovePods atomically checks if the set of pods can be removed and, if
+// so, updates the remaining PDBs. It returns the result of CanRemovePods.
+func (t *threadSafeRemainingPdbTracker) TryRemovePods(pods []*apiv1.Pod) (canRemove, inParallel bool, blockingPod *drain.BlockingPod) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	canRemove, inParallel, blockingPod = t.tracker.CanRemovePods(pods)
+	if canRemove {
+		t.tracker.RemovePods(pods)
+	}
+	return canRemove, inParallel, blockingPod
+}
+
+func (t *threadSafeRemainingPdbTracker) Clear() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.tracker.Clear()
+}
+
+func (t *threadSafeRemainingPdbTracker) Clone() RemainingPdbTracker {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return NewThreadSafeRemainingPdbTracker(t.tracker.Clone())
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pdb
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+)
+
+func TestThreadSafeTryRemovePods(t *testing.T) {
+	pdb1.Status.DisruptionsAllowed = 5
+	tracker := NewThreadSafeRemainingPdbTracker(NewBasicRemainingPdbTracker())
+	assert.NoError(t, tracker.SetPdbs([]*policyv1.PodDisruptionBudget{pdb1}))
+
+	pods := makePodsWithLabel(label1, 20)
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	removed := 0
+	for _, pod := range pods {
+		wg.Add(1)
+		go func(pod *apiv1.Pod) {
+			defer wg.Done()
+			if canRemove, _, _ := tracker.TryRemovePods([]*apiv1.Pod{pod}); canRemove {
+				mutex.Lock()
+				removed++
+				mutex.Unlock()
+			}
+		}(pod)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, removed)
+	assert.Equal(t, int32(0), tracker.GetPdbs()[0].Status.DisruptionsAllowed)
+}
+
+func TestClone(t *testing.T) {
+	for desc, tracker := range map[string]RemainingPdbTracker{
+		"basic":       NewBasicRemainingPdbTracker(),
+		"thread safe": NewThreadSafeRemainingPdbTracker(NewBasicRemainingPdbTracker()),
+	} {
+		t.Run(desc, func(t *testing.T) {
+			pdb1.Status.DisruptionsAllowed = 1
+			assert.NoError(t, tracker.SetPdbs([]*policyv1.PodDisruptionBudget{pdb1}))
+			pods := makePodsWithLabel(label1, 1)
+
+			clone := tracker.Clone()
+			clone.RemovePods(pods)
+
+			assert.Equal(t, int32(0), clone.GetPdbs()[0].Status.DisruptionsAllowed)
+			assert.Equal(t, int32(1), tracker.GetPdbs()[0].Status.DisruptionsAllowed)
+			canRemove, _, _ := tracker.CanRemovePods(pods)
+			assert.True(t, canRemove)
+			canRemove, _, _ = clone.CanRemovePods(pods)
+			assert.False(t, canRemove)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Chain is a chain of drainability rules configured by an
+// AutoscalerDrainPolicy. The policy is reconciled live, its changes take
+// effect the next time the rules are evaluated. While the policy doesn't
+// exist, the base rules are evaluated as they are, and while it's invalid,
+// the last valid configuration is kept.
+type Chain struct {
+	lister     cache.GenericLister
+	policyName string
+	base       rules.Rules
+	factories  map[string]RuleFactory
+
+	mutex           sync.Mutex
+	resourceVersion string
+	current         rules.Rules
+}
+
+// NewChain creates a new Chain of the base rules, configured by the
+// AutoscalerDrainPolicy of the given name. Parameters of rules set by the
+// policy are passed to the factories of the rules.
+func NewChain(lister cache.GenericLister, policyName string, base rules.Rules, factories map[string]RuleFactory) *Chain {
+	return &Chain{
+		lister:     lister,
+		policyName: policyName,
+		base:       base,
+		factories:  factories,
+		current:    base,
+	}
+}
+
+// NewLister returns a lister of AutoscalerDrainPolicies, backed by an
+// informer running until stopChannel is closed.
+func NewLister(client dynamic.Interface, stopChannel <-chan struct{}) cache.GenericLister {
+	informer := dynamicinformer.NewFilteredDynamicInformer(client, Resource, metav1.NamespaceAll, time.Hour, cache.Indexers{}, nil)
+	go informer.Informer().Run(stopChannel)
+	return informer.Lister()
+}
+
+// Name returns the name of the chain.
+func (c *Chain) Name() string {
+	return "DrainPolicy"
+}
+
+// Drainable evaluates the current rules of the chain. As part of other
+// rules, the current rules are evaluated in place of the chain instead.
+func (c *Chain) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	return c.Rules().Drainable(drainCtx, pod, nodeInfo)
+}
+
+// Rules returns the base rules configured by the current version of the
+// policy.
+func (c *Chain) Rules() rules.Rules {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	obj, err := c.lister.Get(c.policyName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Warningf("Failed to get %s %s, keeping the current drainability rules: %v", Kind, c.policyName, err)
+			return c.current
+		}
+		if c.resourceVersion != "" {
+			klog.V(1).Infof("%s %s removed, evaluating drainability rules as configured by flags", Kind, c.policyName)
+			c.resourceVersion = ""
+			c.current = c.base
+		}
+		return c.current
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok || u.GetResourceVersion() == c.resourceVersion {
+		return c.current
+	}
+	// Versions are applied at most once, so that invalid ones aren't
+	// reported on each evaluation.
+	c.resourceVersion = u.GetResourceVersion()
+	policy, err := FromUnstructured(u)
+	if err != nil {
+		klog.Errorf("Invalid %s %s, keeping the current drainability rules: %v", Kind, c.policyName, err)
+		return c.current
+	}
+	configured, err := Apply(c.base, policy, c.factories)
+	if err != nil {
+		klog.Errorf("Invalid %s %s, keeping the current drainability rules: %v", Kind, c.policyName, err)
+		return c.current
+	}
+	klog.V(1).Infof("Applied %s %s version %s to drainability rules", Kind, c.policyName, c.resourceVersion)
+	c.current = configured
+	return c.current
+}
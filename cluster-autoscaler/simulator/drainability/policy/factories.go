@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/debugcontainer"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/expendable"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/longterminating"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/rollout"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/singleton"
+	schedulinglisters "k8s.io/client-go/listers/scheduling/v1"
+)
+
+// DefaultFactories returns factories of the rules accepting parameters:
+//   - LongTerminating: threshold, the time after which pods running over their
+//     termination grace period are ignored.
+//   - DebugContainer: maxAge, the time after which running ephemeral containers
+//     are considered abandoned and don't block drain anymore.
+//   - OwnerRollout: maxDelay, the maximum time pods are delayed by a rollout
+//     of their Deployment.
+//   - Expendable: priorityCutoff, the priority below which pods are expendable.
+//   - SingletonInfraPod: selector, the label selector of singleton
+//     infrastructure pods.
+func DefaultFactories(priorityClassLister schedulinglisters.PriorityClassLister) map[string]RuleFactory {
+	return map[string]RuleFactory{
+		"LongTerminating": func(parameters map[string]string) (rules.Rule, error) {
+			threshold, err := durationParameter(parameters, "threshold")
+			if err != nil {
+				return nil, err
+			}
+			return longterminating.New(threshold), nil
+		},
+		"DebugContainer": func(parameters map[string]string) (rules.Rule, error) {
+			maxAge, err := durationParameter(parameters, "maxAge")
+			if err != nil {
+				return nil, err
+			}
+			return debugcontainer.New(maxAge), nil
+		},
+		"OwnerRollout": func(parameters map[string]string) (rules.Rule, error) {
+			maxDelay, err := durationParameter(parameters, "maxDelay")
+			if err != nil {
+				return nil, err
+			}
+			return rollout.New(maxDelay), nil
+		},
+		"Expendable": func(parameters map[string]string) (rules.Rule, error) {
+			if err := onlyParameters(parameters, "priorityCutoff"); err != nil {
+				return nil, err
+			}
+			cutoff, err := strconv.Atoi(parameters["priorityCutoff"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid priorityCutoff: %v", err)
+			}
+			return expendable.New(cutoff, priorityClassLister), nil
+		},
+		"SingletonInfraPod": func(parameters map[string]string) (rules.Rule, error) {
+			if err := onlyParameters(parameters, "selector"); err != nil {
+				return nil, err
+			}
+			selector, err := labels.Parse(parameters["selector"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector: %v", err)
+			}
+			return singleton.New([]labels.Selector{selector}), nil
+		},
+	}
+}
+
+func durationParameter(parameters map[string]string, name string) (time.Duration, error) {
+	if err := onlyParameters(parameters, name); err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(parameters[name])
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return d, nil
+}
+
+// onlyParameters checks that the parameters consist of exactly the named one.
+func onlyParameters(parameters map[string]string, name string) error {
+	for key := range parameters {
+		if key != name {
+			return fmt.Errorf("unknown parameter %s, expected %s", key, name)
+		}
+	}
+	if _, found := parameters[name]; !found {
+		return fmt.Errorf("parameter %s not set", name)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Kind is the kind of the custom resource configuring drainability rules.
+const Kind = "AutoscalerDrainPolicy"
+
+// Resource is the resource of the AutoscalerDrainPolicy custom resource definition.
+var Resource = schema.GroupVersionResource{Group: "autoscaling.x-k8s.io", Version: "v1alpha1", Resource: "autoscalerdrainpolicies"}
+
+// Mode determines how a drainability rule is evaluated.
+type Mode string
+
+const (
+	// Enforce evaluates the rule and enforces its outcomes.
+	Enforce Mode = "Enforce"
+	// Shadow evaluates the rule in shadow mode, only reporting its outcomes.
+	Shadow Mode = "Shadow"
+	// Disabled doesn't evaluate the rule at all.
+	Disabled Mode = "Disabled"
+)
+
+// Policy is the spec of an AutoscalerDrainPolicy.
+type Policy struct {
+	// Rules configures drainability rules, in the order of evaluation of
+	// rules with equal priority. Rules not listed are evaluated as configured
+	// by flags, after the listed ones of equal priority.
+	Rules []RulePolicy `json:"rules,omitempty"`
+}
+
+// RulePolicy configures a single drainability rule.
+type RulePolicy struct {
+	// Name is the name of the rule, as used in metrics and traces.
+	Name string `json:"name"`
+	// Mode determines how the rule is evaluated. Empty Mode is equivalent to
+	// Enforce.
+	Mode Mode `json:"mode,omitempty"`
+	// Priority overrides the priority of the rule, if set.
+	Priority *int `json:"priority,omitempty"`
+	// Namespaces restricts the rule to pods in the namespaces, if not empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// ExcludedNamespaces exempts pods in the namespaces from the rule.
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+	// Parameters are rule specific parameters, e.g. thresholds. Only rules
+	// with a RuleFactory accept parameters.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// RuleFactory creates a drainability rule from its parameters.
+type RuleFactory func(parameters map[string]string) (rules.Rule, error)
+
+// FromUnstructured returns the Policy declared by an AutoscalerDrainPolicy.
+func FromUnstructured(u *unstructured.Unstructured) (*Policy, error) {
+	spec, found, err := unstructured.NestedMap(u.Object, "spec")
+	if err != nil {
+		return nil, err
+	}
+	policy := &Policy{}
+	if !found {
+		return policy, nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Apply returns the base rules configured by the policy. Rules listed by the
+// policy come first in its order, so they are evaluated before unlisted rules
+// of equal priority. Rules are created with the factories if the policy sets
+// their parameters. It returns an error if the policy configures rules missing
+// from the base rules, or is invalid otherwise.
+func Apply(base rules.Rules, policy *Policy, factories map[string]RuleFactory) (rules.Rules, error) {
+	byName := make(map[string]rules.Rule, len(base))
+	for _, r := range base {
+		byName[r.Name()] = r
+	}
+	index := make(map[string]int, len(policy.Rules))
+	var result rules.Rules
+	for i, rp := range policy.Rules {
+		if _, found := index[rp.Name]; found {
+			return nil, fmt.Errorf("drainability rule %s configured more than once", rp.Name)
+		}
+		index[rp.Name] = i
+		r, found := byName[rp.Name]
+		if !found {
+			return nil, fmt.Errorf("no drainability rule named %s", rp.Name)
+		}
+		configured, err := configure(r, rp, factories)
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration of drainability rule %s: %v", rp.Name, err)
+		}
+		if configured != nil {
+			result = append(result, configured)
+		}
+	}
+	for _, r := range base {
+		if _, found := index[r.Name()]; !found {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+// configure returns the rule configured by the policy, or nil if the rule is
+// disabled.
+func configure(r rules.Rule, rp RulePolicy, factories map[string]RuleFactory) (rules.Rule, error) {
+	shadow := false
+	if s, ok := r.(rules.ShadowRule); ok {
+		// The policy decides the mode, regardless of flags.
+		r = s.Shadowed()
+		shadow = true
+	}
+	switch rp.Mode {
+	case "", Enforce:
+		shadow = false
+	case Shadow:
+		shadow = true
+	case Disabled:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown mode %q, expected one of: %s, %s, %s", rp.Mode, Enforce, Shadow, Disabled)
+	}
+	if len(rp.Namespaces) > 0 && len(rp.ExcludedNamespaces) > 0 {
+		return nil, fmt.Errorf("namespaces and excludedNamespaces are mutually exclusive")
+	}
+
+	priority := rules.PriorityOf(r)
+	if rp.Priority != nil {
+		priority = rules.Priority(*rp.Priority)
+	}
+	if len(rp.Parameters) > 0 {
+		factory, found := factories[rp.Name]
+		if !found {
+			return nil, fmt.Errorf("the rule doesn't accept parameters")
+		}
+		created, err := factory(rp.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		r = created
+	}
+	if len(rp.Namespaces) > 0 || len(rp.ExcludedNamespaces) > 0 {
+		r = newNamespacedRule(r, rp.Namespaces, rp.ExcludedNamespaces)
+	}
+	configured := rules.WithPriority(r, priority)
+	if shadow {
+		return rules.Shadow(configured), nil
+	}
+	return configured, nil
+}
+
+// namespacedRule is a rule applied only to pods in some namespaces.
+type namespacedRule struct {
+	rule     rules.Rule
+	included map[string]bool
+	excluded map[string]bool
+}
+
+func newNamespacedRule(rule rules.Rule, included, excluded []string) *namespacedRule {
+	return &namespacedRule{
+		rule:     rule,
+		included: toSet(included),
+		excluded: toSet(excluded),
+	}
+}
+
+// Name returns the name of the rule.
+func (r *namespacedRule) Name() string {
+	return r.rule.Name()
+}
+
+// Drainable evaluates the rule for pods in its namespaces, and returns an
+// undefined status for other pods.
+func (r *namespacedRule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if r.excluded[pod.Namespace] || (len(r.included) > 0 && !r.included[pod.Namespace]) {
+		return drainability.NewUndefinedStatus()
+	}
+	return r.rule.Drainable(drainCtx, pod, nodeInfo)
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
@@ -0,0 +1,304 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestApply(t *testing.T) {
+	priority := int(rules.SkipPriority)
+	base := rules.Rules{
+		rules.WithPriority(blockingRule("LocalStorage"), rules.BlockingPriority),
+		rules.WithPriority(blockingRule("System"), rules.BlockingPriority),
+		rules.Shadow(rules.WithPriority(blockingRule("Replicated"), rules.BlockingPriority)),
+		rules.WithPriority(blockingRule("LongTerminating"), rules.SkipPriority),
+	}
+	factories := map[string]RuleFactory{
+		"LongTerminating": func(parameters map[string]string) (rules.Rule, error) {
+			if parameters["threshold"] == "" {
+				return nil, fmt.Errorf("parameter threshold not set")
+			}
+			return testRule{name: "LongTerminating", status: drainability.NewSkipStatus()}, nil
+		},
+	}
+
+	for desc, tc := range map[string]struct {
+		policy       *Policy
+		wantRules    []string
+		wantShadow   []string
+		wantOutcomes map[string]drainability.OutcomeType
+		wantErr      bool
+	}{
+		"empty policy": {
+			policy:     &Policy{},
+			wantRules:  []string{"LongTerminating", "LocalStorage", "System", "Replicated"},
+			wantShadow: []string{"Replicated"},
+		},
+		"listed rules first": {
+			policy:     &Policy{Rules: []RulePolicy{{Name: "System"}}},
+			wantRules:  []string{"LongTerminating", "System", "LocalStorage", "Replicated"},
+			wantShadow: []string{"Replicated"},
+		},
+		"modes": {
+			policy: &Policy{Rules: []RulePolicy{
+				{Name: "LocalStorage", Mode: Shadow},
+				{Name: "System", Mode: Disabled},
+				{Name: "Replicated", Mode: Enforce},
+			}},
+			wantRules:  []string{"LongTerminating", "LocalStorage", "Replicated"},
+			wantShadow: []string{"LocalStorage"},
+		},
+		"priority": {
+			policy:     &Policy{Rules: []RulePolicy{{Name: "System", Priority: &priority}}},
+			wantRules:  []string{"System", "LongTerminating", "LocalStorage", "Replicated"},
+			wantShadow: []string{"Replicated"},
+		},
+		"namespaces": {
+			policy: &Policy{Rules: []RulePolicy{
+				{Name: "LocalStorage", Namespaces: []string{"batch"}},
+				{Name: "System", ExcludedNamespaces: []string{"batch"}},
+			}},
+			wantRules:  []string{"LongTerminating", "LocalStorage", "System", "Replicated"},
+			wantShadow: []string{"Replicated"},
+			wantOutcomes: map[string]drainability.OutcomeType{
+				"LocalStorage/batch":   drainability.BlockDrain,
+				"LocalStorage/default": drainability.UndefinedOutcome,
+				"System/batch":         drainability.UndefinedOutcome,
+				"System/default":       drainability.BlockDrain,
+			},
+		},
+		"parameters": {
+			policy:     &Policy{Rules: []RulePolicy{{Name: "LongTerminating", Parameters: map[string]string{"threshold": "10m"}}}},
+			wantRules:  []string{"LongTerminating", "LocalStorage", "System", "Replicated"},
+			wantShadow: []string{"Replicated"},
+			wantOutcomes: map[string]drainability.OutcomeType{
+				"LongTerminating/default": drainability.SkipDrain,
+			},
+		},
+		"invalid parameters": {
+			policy:  &Policy{Rules: []RulePolicy{{Name: "LongTerminating", Parameters: map[string]string{"maxAge": "10m"}}}},
+			wantErr: true,
+		},
+		"parameters of rule without factory": {
+			policy:  &Policy{Rules: []RulePolicy{{Name: "System", Parameters: map[string]string{"threshold": "10m"}}}},
+			wantErr: true,
+		},
+		"unknown rule": {
+			policy:  &Policy{Rules: []RulePolicy{{Name: "Webhook"}}},
+			wantErr: true,
+		},
+		"rule listed twice": {
+			policy:  &Policy{Rules: []RulePolicy{{Name: "System"}, {Name: "System", Mode: Shadow}}},
+			wantErr: true,
+		},
+		"unknown mode": {
+			policy:  &Policy{Rules: []RulePolicy{{Name: "System", Mode: "Warn"}}},
+			wantErr: true,
+		},
+		"both namespaces and excluded namespaces": {
+			policy:  &Policy{Rules: []RulePolicy{{Name: "System", Namespaces: []string{"a"}, ExcludedNamespaces: []string{"b"}}}},
+			wantErr: true,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			configured, err := Apply(base, tc.policy, factories)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var gotRules, gotShadow []string
+			for _, r := range configured.Sorted() {
+				gotRules = append(gotRules, r.Name())
+				if _, isShadow := r.(rules.ShadowRule); isShadow {
+					gotShadow = append(gotShadow, r.Name())
+				}
+			}
+			assert.Equal(t, tc.wantRules, gotRules)
+			assert.Equal(t, tc.wantShadow, gotShadow)
+			for key, want := range tc.wantOutcomes {
+				name, namespace, _ := strings.Cut(key, "/")
+				for _, r := range configured {
+					if r.Name() == name {
+						got := r.Drainable(&drainability.DrainContext{}, testPod(namespace), nil)
+						assert.Equal(t, want, got.Outcome, "outcome of rule %s for pod in namespace %s", name, namespace)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFromUnstructured(t *testing.T) {
+	u := testPolicy("default", "1", []interface{}{
+		map[string]interface{}{
+			"name":               "LocalStorage",
+			"mode":               "Shadow",
+			"priority":           int64(150),
+			"excludedNamespaces": []interface{}{"batch"},
+		},
+		map[string]interface{}{
+			"name":       "LongTerminating",
+			"parameters": map[string]interface{}{"threshold": "10m"},
+		},
+	})
+	policy, err := FromUnstructured(u)
+	assert.NoError(t, err)
+	priority := 150
+	assert.Equal(t, &Policy{Rules: []RulePolicy{
+		{Name: "LocalStorage", Mode: Shadow, Priority: &priority, ExcludedNamespaces: []string{"batch"}},
+		{Name: "LongTerminating", Parameters: map[string]string{"threshold": "10m"}},
+	}}, policy)
+
+	u = testPolicy("default", "1", nil)
+	unstructured.RemoveNestedField(u.Object, "spec")
+	policy, err = FromUnstructured(u)
+	assert.NoError(t, err)
+	assert.Equal(t, &Policy{}, policy)
+
+	u = testPolicy("default", "1", []interface{}{map[string]interface{}{"name": int64(1)}})
+	_, err = FromUnstructured(u)
+	assert.Error(t, err)
+}
+
+func TestChain(t *testing.T) {
+	base := rules.Rules{
+		rules.WithPriority(blockingRule("LocalStorage"), rules.BlockingPriority),
+		rules.WithPriority(blockingRule("System"), rules.BlockingPriority),
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	lister := cache.NewGenericLister(indexer, Resource.GroupResource())
+	chain := NewChain(lister, "default", base, nil)
+	chained := rules.Rules{chain}
+	pod := testPod("default")
+
+	// Without the policy, the base rules are evaluated.
+	assert.Equal(t, base, chain.Rules())
+	assert.Equal(t, drainability.BlockDrain, chained.Drainable(nil, pod, nil).Outcome)
+
+	// Policies of other names are ignored.
+	assert.NoError(t, indexer.Add(testPolicy("other", "1", []interface{}{
+		map[string]interface{}{"name": "LocalStorage", "mode": "Disabled"},
+		map[string]interface{}{"name": "System", "mode": "Disabled"},
+	})))
+	assert.Equal(t, base, chain.Rules())
+
+	// Changes of the policy take effect on the next evaluation.
+	assert.NoError(t, indexer.Add(testPolicy("default", "1", []interface{}{
+		map[string]interface{}{"name": "LocalStorage", "mode": "Disabled"},
+		map[string]interface{}{"name": "System", "mode": "Shadow"},
+	})))
+	assert.Equal(t, drainability.UndefinedOutcome, chained.Drainable(nil, pod, nil).Outcome)
+	assert.Len(t, chain.Rules(), 1)
+
+	// Invalid versions are ignored, the last valid one is kept.
+	assert.NoError(t, indexer.Update(testPolicy("default", "2", []interface{}{
+		map[string]interface{}{"name": "Webhook", "mode": "Disabled"},
+	})))
+	assert.Equal(t, drainability.UndefinedOutcome, chained.Drainable(nil, pod, nil).Outcome)
+	assert.Len(t, chain.Rules(), 1)
+
+	assert.NoError(t, indexer.Update(testPolicy("default", "3", []interface{}{
+		map[string]interface{}{"name": "System", "namespaces": []interface{}{"kube-system"}},
+	})))
+	assert.Equal(t, drainability.BlockDrain, chained.Drainable(nil, pod, nil).Outcome)
+	assert.Len(t, chain.Rules(), 2)
+
+	// Removing the policy restores the base rules.
+	assert.NoError(t, indexer.Delete(testPolicy("default", "3", nil)))
+	assert.Equal(t, base, chain.Rules())
+}
+
+type testRule struct {
+	name   string
+	status drainability.Status
+}
+
+func (r testRule) Name() string {
+	return r.name
+}
+
+func (r testRule) Drainable(*drainability.DrainContext, *apiv1.Pod, *framework.NodeInfo) drainability.Status {
+	return r.status
+}
+
+func blockingRule(name string) testRule {
+	return testRule{name: name, status: drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("blocked by %s", name))}
+}
+
+func testPolicy(name, resourceVersion string, ruleList []interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"rules": ruleList},
+	}}
+	u.SetAPIVersion(Resource.GroupVersion().String())
+	u.SetKind(Kind)
+	u.SetName(name)
+	u.SetResourceVersion(resourceVersion)
+	return u
+}
+
+func testPod(namespace string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: namespace,
+		},
+	}
+}
+
+func TestDefaultFactories(t *testing.T) {
+	factories := DefaultFactories(nil)
+	for desc, tc := range map[string]struct {
+		rule       string
+		parameters map[string]string
+		wantErr    bool
+	}{
+		"long terminating threshold":         {rule: "LongTerminating", parameters: map[string]string{"threshold": "10m"}},
+		"invalid threshold":                  {rule: "LongTerminating", parameters: map[string]string{"threshold": "soon"}, wantErr: true},
+		"unknown parameter":                  {rule: "LongTerminating", parameters: map[string]string{"threshold": "10m", "maxAge": "1h"}, wantErr: true},
+		"debug container max age":            {rule: "DebugContainer", parameters: map[string]string{"maxAge": "1h"}},
+		"missing parameter":                  {rule: "DebugContainer", parameters: map[string]string{"threshold": "1h"}, wantErr: true},
+		"owner rollout max delay":            {rule: "OwnerRollout", parameters: map[string]string{"maxDelay": "15m"}},
+		"expendable priority cutoff":         {rule: "Expendable", parameters: map[string]string{"priorityCutoff": "-100"}},
+		"invalid expendable priority cutoff": {rule: "Expendable", parameters: map[string]string{"priorityCutoff": "low"}, wantErr: true},
+		"singleton selector":                 {rule: "SingletonInfraPod", parameters: map[string]string{"selector": "app=csi-controller"}},
+		"invalid singleton selector":         {rule: "SingletonInfraPod", parameters: map[string]string{"selector": "app in csi"}, wantErr: true},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			rule, err := factories[tc.rule](tc.parameters)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.rule, rule.Name())
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// AllOf returns a Rule matching pods matched by all the given Rules, where a
+// Rule matches a pod if it returns an outcome other than UndefinedOutcome. The
+// status of a matched pod is the status returned by the first Rule which
+// isn't negated, e.g. AllOf(replicated.New(false), Not(safetoevict.New()))
+// blocks drain of pods which aren't replicated, unless they are annotated as
+// safe to evict. Rules are evaluated in order, until one doesn't match.
+func AllOf(rules ...Rule) Rule {
+	return &allOf{rules: rules}
+}
+
+// AnyOf returns a Rule matching pods matched by any of the given Rules. The
+// status of a matched pod is the status returned by the first Rule matching
+// it. Rules are evaluated in order, until one matches.
+func AnyOf(rules ...Rule) Rule {
+	return &anyOf{rules: rules}
+}
+
+// Not returns a Rule matching pods not matched by the given Rule. Negated
+// Rules match pods without deciding on them, so on their own, like
+// compositions of negated Rules only, they return an undefined status.
+func Not(rule Rule) Rule {
+	return &not{rule: rule}
+}
+
+// matcher is implemented by combinators, which can match pods without
+// deciding on them.
+type matcher interface {
+	match(*drainability.DrainContext, *apiv1.Pod, *framework.NodeInfo) (drainability.Status, bool)
+}
+
+func match(rule Rule, drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) (drainability.Status, bool) {
+	if m, ok := rule.(matcher); ok {
+		return m.match(drainCtx, pod, nodeInfo)
+	}
+	status := rule.Drainable(drainCtx, pod, nodeInfo)
+	return status, status.Outcome != drainability.UndefinedOutcome
+}
+
+// drainable returns the status of a matched pod, or an undefined status.
+func drainable(m matcher, drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if status, matched := m.match(drainCtx, pod, nodeInfo); matched {
+		return status
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+type allOf struct {
+	rules []Rule
+}
+
+// Name returns the name of the rule.
+func (r *allOf) Name() string {
+	return combinatorName("AllOf", r.rules)
+}
+
+// Drainable returns the status of pods matched by all the rules.
+func (r *allOf) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	return drainable(r, drainCtx, pod, nodeInfo)
+}
+
+func (r *allOf) match(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) (drainability.Status, bool) {
+	result := drainability.NewUndefinedStatus()
+	for _, rule := range r.rules {
+		status, matched := match(rule, drainCtx, pod, nodeInfo)
+		if !matched {
+			return drainability.NewUndefinedStatus(), false
+		}
+		if result.Outcome == drainability.UndefinedOutcome {
+			result = status
+		}
+	}
+	return result, len(r.rules) > 0
+}
+
+type anyOf struct {
+	rules []Rule
+}
+
+// Name returns the name of the rule.
+func (r *anyOf) Name() string {
+	return combinatorName("AnyOf", r.rules)
+}
+
+// Drainable returns the status of pods matched by any of the rules.
+func (r *anyOf) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	return drainable(r, drainCtx, pod, nodeInfo)
+}
+
+func (r *anyOf) match(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) (drainability.Status, bool) {
+	for _, rule := range r.rules {
+		if status, matched := match(rule, drainCtx, pod, nodeInfo); matched {
+			return status, true
+		}
+	}
+	return drainability.NewUndefinedStatus(), false
+}
+
+type not struct {
+	rule Rule
+}
+
+// Name returns the name of the rule.
+func (r *not) Name() string {
+	return fmt.Sprintf("Not(%s)", r.rule.Name())
+}
+
+// Drainable returns an undefined status, negated rules don't decide on pods.
+func (r *not) Drainable(*drainability.DrainContext, *apiv1.Pod, *framework.NodeInfo) drainability.Status {
+	return drainability.NewUndefinedStatus()
+}
+
+func (r *not) match(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) (drainability.Status, bool) {
+	_, matched := match(r.rule, drainCtx, pod, nodeInfo)
+	return drainability.NewUndefinedStatus(), !matched
+}
+
+func combinatorName(combinator string, rules []Rule) string {
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name())
+	}
+	return fmt.Sprintf("%s(%s)", combinator, strings.Join(names, ","))
+}
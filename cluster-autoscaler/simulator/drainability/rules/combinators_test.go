@@ -0,0 +1,163 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/replicated"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/safetoevict"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+type countingRule struct {
+	fakeRule
+	name        string
+	evaluations *int
+}
+
+func (r countingRule) Name() string {
+	return r.name
+}
+
+func (r countingRule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	*r.evaluations++
+	return r.fakeRule.Drainable(drainCtx, pod, nodeInfo)
+}
+
+func TestCombinators(t *testing.T) {
+	blocked := drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("not replicated"))
+	ok := drainability.NewDrainableStatus()
+	skip := drainability.NewSkipStatus()
+	undefined := drainability.NewUndefinedStatus()
+
+	var evaluations int
+	rule := func(name string, status drainability.Status) Rule {
+		return countingRule{fakeRule: fakeRule{status}, name: name, evaluations: &evaluations}
+	}
+
+	for desc, tc := range map[string]struct {
+		rule            Rule
+		wantName        string
+		wantStatus      drainability.Status
+		wantEvaluations int
+	}{
+		"all of, all match": {
+			rule:            AllOf(rule("A", blocked), rule("B", ok)),
+			wantName:        "AllOf(A,B)",
+			wantStatus:      blocked,
+			wantEvaluations: 2,
+		},
+		"all of, one doesn't match": {
+			rule:            AllOf(rule("A", undefined), rule("B", blocked)),
+			wantName:        "AllOf(A,B)",
+			wantStatus:      undefined,
+			wantEvaluations: 1,
+		},
+		"all of, status of the first rule which isn't negated": {
+			rule:            AllOf(Not(rule("A", undefined)), rule("B", skip), rule("C", blocked)),
+			wantName:        "AllOf(Not(A),B,C)",
+			wantStatus:      skip,
+			wantEvaluations: 3,
+		},
+		"all of, negated rule matches": {
+			rule:            AllOf(rule("A", blocked), Not(rule("B", ok))),
+			wantName:        "AllOf(A,Not(B))",
+			wantStatus:      undefined,
+			wantEvaluations: 2,
+		},
+		"all of, negated rules only": {
+			rule:            AllOf(Not(rule("A", undefined)), Not(rule("B", undefined))),
+			wantName:        "AllOf(Not(A),Not(B))",
+			wantStatus:      undefined,
+			wantEvaluations: 2,
+		},
+		"all of, no rules": {
+			rule:       AllOf(),
+			wantName:   "AllOf()",
+			wantStatus: undefined,
+		},
+		"any of, first matching rule": {
+			rule:            AnyOf(rule("A", undefined), rule("B", ok), rule("C", blocked)),
+			wantName:        "AnyOf(A,B,C)",
+			wantStatus:      ok,
+			wantEvaluations: 2,
+		},
+		"any of, none matches": {
+			rule:            AnyOf(rule("A", undefined), rule("B", undefined)),
+			wantName:        "AnyOf(A,B)",
+			wantStatus:      undefined,
+			wantEvaluations: 2,
+		},
+		"not on its own": {
+			rule:            Not(rule("A", undefined)),
+			wantName:        "Not(A)",
+			wantStatus:      undefined,
+			wantEvaluations: 0,
+		},
+		"nested": {
+			rule:            AllOf(AnyOf(rule("A", undefined), rule("B", blocked)), Not(AllOf(rule("C", ok), rule("D", undefined)))),
+			wantName:        "AllOf(AnyOf(A,B),Not(AllOf(C,D)))",
+			wantStatus:      blocked,
+			wantEvaluations: 4,
+		},
+		"double negation": {
+			rule:            AllOf(rule("A", blocked), Not(Not(rule("B", ok)))),
+			wantName:        "AllOf(A,Not(Not(B)))",
+			wantStatus:      blocked,
+			wantEvaluations: 2,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			evaluations = 0
+			if got := tc.rule.Name(); got != tc.wantName {
+				t.Errorf("Name() = %q, want %q", got, tc.wantName)
+			}
+			got := tc.rule.Drainable(&drainability.DrainContext{}, &apiv1.Pod{}, nil)
+			if diff := cmp.Diff(tc.wantStatus, got, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Drainable(): diff (-want +got):\n%s", diff)
+			}
+			if evaluations != tc.wantEvaluations {
+				t.Errorf("Drainable() evaluated %d rules, want %d", evaluations, tc.wantEvaluations)
+			}
+		})
+	}
+}
+
+func TestCombinatorsInRules(t *testing.T) {
+	// Block drain of pods which aren't replicated, unless they are annotated as safe to evict.
+	rules := Rules{AllOf(replicated.New(false), Not(safetoevict.New()))}
+	notReplicated := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+	safeToEvict := notReplicated.DeepCopy()
+	safeToEvict.Annotations = map[string]string{drain.PodSafeToEvictKey: "true"}
+
+	got := rules.Drainable(&drainability.DrainContext{}, notReplicated, nil)
+	if got.Outcome != drainability.BlockDrain || got.BlockingReason != drain.NotReplicated || got.Rule != "AllOf(Replicated,Not(SafeToEvict))" {
+		t.Errorf("Drainable(not replicated) = %+v, want blocked as not replicated by the composed rule", got)
+	}
+	got = rules.Drainable(&drainability.DrainContext{}, safeToEvict, nil)
+	if got.Outcome != drainability.UndefinedOutcome {
+		t.Errorf("Drainable(safe to evict) = %+v, want undefined outcome", got)
+	}
+}
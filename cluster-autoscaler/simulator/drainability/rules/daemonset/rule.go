@@ -17,9 +17,12 @@ limitations under the License.
 package daemonset
 
 import (
+	"fmt"
+
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 // Rule is a drainability rule on how to handle daemon set pods.
@@ -35,10 +38,17 @@ func (r *Rule) Name() string {
 	return "DaemonSet"
 }
 
-// Drainable decides what to do with daemon set pods on node drain.
-func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
-	if pod_util.IsDaemonSetPod(pod) {
-		return drainability.NewDrainableStatus()
+// Drainable decides what to do with daemon set pods on node drain. Daemon set
+// pods are drainable, unless the delete options respect their pod disruption
+// budgets and one of the budgets doesn't allow a disruption.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if !pod_util.IsDaemonSetPod(pod) {
+		return drainability.NewUndefinedStatus()
+	}
+	if drainCtx != nil && drainCtx.DeleteOptions.RespectDaemonSetPdbs && drainCtx.RemainingPdbTracker != nil {
+		if canRemove, _, blockingPod := drainCtx.RemainingPdbTracker.CanRemovePods([]*apiv1.Pod{pod}); !canRemove {
+			return drainability.NewBlockedStatus(blockingPod.Reason, fmt.Errorf("not enough pod disruption budget to remove daemon set pod %s/%s", pod.Namespace, pod.Name))
+		}
 	}
-	return drainability.NewUndefinedStatus()
+	return drainability.NewDrainableStatus()
 }
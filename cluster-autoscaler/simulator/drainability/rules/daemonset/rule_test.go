@@ -21,9 +21,13 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 )
 
 func TestDrainable(t *testing.T) {
@@ -52,10 +56,69 @@ func TestDrainable(t *testing.T) {
 		},
 	} {
 		t.Run(desc, func(t *testing.T) {
-			got := New().Drainable(nil, tc.pod)
+			got := New().Drainable(nil, tc.pod, nil)
 			if diff := cmp.Diff(tc.want, got); diff != "" {
 				t.Errorf("Rule.Drainable(%v): got status diff (-want +got):\n%s", tc.pod.Name, diff)
 			}
 		})
 	}
 }
+
+func TestDrainableWithPdbs(t *testing.T) {
+	labels := map[string]string{"app": "agent"}
+	dsPod := func(name string) *apiv1.Pod {
+		return &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       "ns",
+				Labels:          labels,
+				OwnerReferences: test.GenerateOwnerReferences("ds", "DaemonSet", "apps/v1", ""),
+			},
+		}
+	}
+	budget := func(disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "ns"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+		}
+	}
+
+	for desc, tc := range map[string]struct {
+		respectPdbs        bool
+		disruptionsAllowed int32
+		wantOutcome        drainability.OutcomeType
+		wantReason         drain.BlockingPodReason
+	}{
+		"budget allows disruption": {
+			respectPdbs:        true,
+			disruptionsAllowed: 1,
+			wantOutcome:        drainability.DrainOk,
+		},
+		"budget exhausted": {
+			respectPdbs:        true,
+			disruptionsAllowed: 0,
+			wantOutcome:        drainability.BlockDrain,
+			wantReason:         drain.NotEnoughPdb,
+		},
+		"budget exhausted, budgets not respected": {
+			disruptionsAllowed: 0,
+			wantOutcome:        drainability.DrainOk,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			tracker := pdb.NewBasicRemainingPdbTracker()
+			if err := tracker.SetPdbs([]*policyv1.PodDisruptionBudget{budget(tc.disruptionsAllowed)}); err != nil {
+				t.Fatalf("SetPdbs: %v", err)
+			}
+			drainCtx := &drainability.DrainContext{
+				RemainingPdbTracker: tracker,
+				DeleteOptions:       options.NodeDeleteOptions{RespectDaemonSetPdbs: tc.respectPdbs},
+			}
+			got := New().Drainable(drainCtx, dsPod("pod"), nil)
+			if got.Outcome != tc.wantOutcome || got.BlockingReason != tc.wantReason {
+				t.Errorf("Rule.Drainable(): got outcome %v, reason %v, want outcome %v, reason %v", got.Outcome, got.BlockingReason, tc.wantOutcome, tc.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugcontainer
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Rule is a drainability rule on how to handle pods with running ephemeral
+// containers, e.g. debug sessions started with kubectl debug.
+type Rule struct {
+	maxAge time.Duration
+}
+
+// New creates a new Rule. Ephemeral containers running for longer than maxAge
+// are considered abandoned and don't block drain anymore.
+func New(maxAge time.Duration) *Rule {
+	return &Rule{
+		maxAge: maxAge,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "DebugContainer"
+}
+
+// Drainable decides what to do with pods with running ephemeral containers on
+// node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	for _, status := range pod.Status.EphemeralContainerStatuses {
+		running := status.State.Running
+		if running == nil {
+			continue
+		}
+		// Containers without a start time have only just started.
+		if running.StartedAt.IsZero() {
+			return drainability.NewBlockedStatus(drain.DebugContainerRunning, fmt.Errorf("pod %s/%s has a running ephemeral container %s", pod.Namespace, pod.Name, status.Name))
+		}
+		if age := drainCtx.Timestamp.Sub(running.StartedAt.Time); age < r.maxAge {
+			return drainability.NewBlockedStatus(drain.DebugContainerRunning, fmt.Errorf("pod %s/%s has an ephemeral container %s running for %v", pod.Namespace, pod.Name, status.Name, age.Round(time.Second)))
+		}
+	}
+	return drainability.NewUndefinedStatus()
+}
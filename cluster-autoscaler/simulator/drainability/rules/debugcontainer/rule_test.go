@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debugcontainer
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainable(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+
+	for desc, tc := range map[string]struct {
+		statuses []apiv1.ContainerStatus
+		maxAge   time.Duration
+
+		wantOutcome drainability.OutcomeType
+		wantReason  drain.BlockingPodReason
+	}{
+		"no ephemeral containers": {
+			maxAge:      time.Hour,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"running ephemeral container": {
+			statuses:    []apiv1.ContainerStatus{running("debugger", testTime.Add(-10*time.Minute))},
+			maxAge:      time.Hour,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.DebugContainerRunning,
+		},
+		"ephemeral container running for longer than max age": {
+			statuses:    []apiv1.ContainerStatus{running("debugger", testTime.Add(-2*time.Hour))},
+			maxAge:      time.Hour,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"ephemeral container without start time": {
+			statuses:    []apiv1.ContainerStatus{running("debugger", time.Time{})},
+			maxAge:      time.Hour,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.DebugContainerRunning,
+		},
+		"terminated ephemeral container": {
+			statuses: []apiv1.ContainerStatus{{
+				Name:  "debugger",
+				State: apiv1.ContainerState{Terminated: &apiv1.ContainerStateTerminated{}},
+			}},
+			maxAge:      time.Hour,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"one of many ephemeral containers running": {
+			statuses: []apiv1.ContainerStatus{
+				running("old-debugger", testTime.Add(-2*time.Hour)),
+				running("debugger", testTime.Add(-time.Minute)),
+			},
+			maxAge:      time.Hour,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.DebugContainerRunning,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			pod := &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod",
+					Namespace: "default",
+				},
+				Status: apiv1.PodStatus{
+					EphemeralContainerStatuses: tc.statuses,
+				},
+			}
+			status := New(tc.maxAge).Drainable(&drainability.DrainContext{Timestamp: testTime}, pod, nil)
+			assert.Equal(t, tc.wantOutcome, status.Outcome)
+			assert.Equal(t, tc.wantReason, status.BlockingReason)
+		})
+	}
+}
+
+func running(name string, startedAt time.Time) apiv1.ContainerStatus {
+	return apiv1.ContainerStatus{
+		Name: name,
+		State: apiv1.ContainerState{
+			Running: &apiv1.ContainerStateRunning{StartedAt: metav1.Time{Time: startedAt}},
+		},
+	}
+}
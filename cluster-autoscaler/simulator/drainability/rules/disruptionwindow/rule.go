@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disruptionwindow
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/timewindow"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// DisruptionWindowAnnotationKey is the annotation on a pod restricting its
+// eviction on node drain to the time windows described by cron-like
+// expressions, see the timewindow package.
+const DisruptionWindowAnnotationKey = "cluster-autoscaler.kubernetes.io/disruption-window"
+
+// Rule is a drainability rule blocking drain of pods outside of their
+// disruption windows.
+type Rule struct{}
+
+// New creates a new Rule.
+func New() *Rule {
+	return &Rule{}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "DisruptionWindow"
+}
+
+// Drainable decides what to do with pods annotated with disruption windows on
+// node drain. Pods with invalid windows are blocked, rather than disrupted at
+// any time.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	spec, found := pod.Annotations[DisruptionWindowAnnotationKey]
+	if !found {
+		return drainability.NewUndefinedStatus()
+	}
+	window, err := timewindow.Parse(spec)
+	if err != nil {
+		return drainability.NewBlockedStatus(drain.OutsideDisruptionWindow, fmt.Errorf("pod %s/%s has invalid %s annotation: %v", pod.Namespace, pod.Name, DisruptionWindowAnnotationKey, err))
+	}
+	if !window.Contains(drainCtx.Timestamp) {
+		return drainability.NewBlockedStatus(drain.OutsideDisruptionWindow, fmt.Errorf("pod %s/%s is outside of its disruption window %q", pod.Namespace, pod.Name, spec))
+	}
+	return drainability.NewUndefinedStatus()
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package disruptionwindow
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+)
+
+func TestDrainable(t *testing.T) {
+	// 2023-10-02 is a Monday.
+	now := time.Date(2023, time.October, 2, 23, 30, 0, 0, time.UTC)
+	test.RunDrainableTests(t, New(), []test.TestCase{
+		{
+			Name: "pod without annotation",
+			Pod:  testPod(nil),
+			Want: drainability.NewUndefinedStatus(),
+		},
+		{
+			Name:    "pod inside disruption window",
+			Pod:     testPod(map[string]string{DisruptionWindowAnnotationKey: "* 22-23,0-5 * * 1-5"}),
+			Options: []test.DrainContextOption{test.WithTimestamp(now)},
+			Want:    drainability.NewUndefinedStatus(),
+		},
+		{
+			Name:    "pod outside disruption window",
+			Pod:     testPod(map[string]string{DisruptionWindowAnnotationKey: "* 0-5 * * *"}),
+			Options: []test.DrainContextOption{test.WithTimestamp(now)},
+			Want:    drainability.NewBlockedStatus(drain.OutsideDisruptionWindow, nil),
+		},
+		{
+			Name:    "pod with invalid disruption window",
+			Pod:     testPod(map[string]string{DisruptionWindowAnnotationKey: "at night"}),
+			Options: []test.DrainContextOption{test.WithTimestamp(now)},
+			Want:    drainability.NewBlockedStatus(drain.OutsideDisruptionWindow, nil),
+		},
+	})
+}
+
+func testPod(annotations map[string]string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   "ns",
+			Annotations: annotations,
+		},
+	}
+}
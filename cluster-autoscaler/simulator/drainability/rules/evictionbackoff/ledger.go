@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evictionbackoff
+
+import (
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+)
+
+// Ledger records failed evictions of pods, e.g. rejected by admission webhooks
+// or disruption budgets, and backs off from evicting them again exponentially.
+// It is safe for concurrent use.
+type Ledger struct {
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	mutex     sync.Mutex
+	entries   map[string]*entry
+	lastPurge time.Time
+}
+
+type entry struct {
+	failures      int
+	lastError     string
+	webhookDenied bool
+	backoffUntil  time.Time
+}
+
+// Failure describes the failed evictions of a pod.
+type Failure struct {
+	// Failures is the number of consecutive failed evictions.
+	Failures int
+	// LastError is the error of the last failed eviction.
+	LastError string
+	// WebhookDenied tells if the last failed eviction was denied by a
+	// validating admission webhook.
+	WebhookDenied bool
+	// BackoffUntil is the time until which the pod shouldn't be evicted again.
+	BackoffUntil time.Time
+}
+
+// NewLedger creates a new Ledger. The pod is backed off for initialBackoff
+// after the first failed eviction, and the backoff doubles with each
+// consecutive failure up to maxBackoff. Failures are forgotten once the pod
+// wasn't backed off for maxBackoff.
+func NewLedger(initialBackoff, maxBackoff time.Duration) *Ledger {
+	return &Ledger{
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+		entries:        make(map[string]*entry),
+	}
+}
+
+// RegisterFailure records a failed eviction of the pod.
+func (l *Ledger) RegisterFailure(pod *apiv1.Pod, err error, timestamp time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.purge(timestamp)
+	key := podKey(pod)
+	e, found := l.entries[key]
+	if !found || l.expired(e, timestamp) {
+		e = &entry{}
+		l.entries[key] = e
+	}
+	e.failures++
+	if err != nil {
+		e.lastError = err.Error()
+		e.webhookDenied = drain.IsAdmissionWebhookDenial(err)
+	}
+	e.backoffUntil = timestamp.Add(l.backoff(e.failures))
+}
+
+// RegisterSuccess forgets failed evictions of the pod.
+func (l *Ledger) RegisterSuccess(pod *apiv1.Pod) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.entries, podKey(pod))
+}
+
+// Failure returns the failed evictions of the pod, if there were any recently.
+func (l *Ledger) Failure(pod *apiv1.Pod, timestamp time.Time) (Failure, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	e, found := l.entries[podKey(pod)]
+	if !found || l.expired(e, timestamp) {
+		return Failure{}, false
+	}
+	return Failure{Failures: e.failures, LastError: e.lastError, WebhookDenied: e.webhookDenied, BackoffUntil: e.backoffUntil}, true
+}
+
+// IsBackedOff tells if the pod shouldn't be evicted at the timestamp.
+func (l *Ledger) IsBackedOff(pod *apiv1.Pod, timestamp time.Time) bool {
+	failure, found := l.Failure(pod, timestamp)
+	return found && timestamp.Before(failure.BackoffUntil)
+}
+
+func (l *Ledger) backoff(failures int) time.Duration {
+	backoff := l.initialBackoff
+	for i := 1; i < failures && backoff < l.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > l.maxBackoff {
+		return l.maxBackoff
+	}
+	return backoff
+}
+
+func (l *Ledger) expired(e *entry, timestamp time.Time) bool {
+	return !timestamp.Before(e.backoffUntil.Add(l.maxBackoff))
+}
+
+// purge drops expired entries at most once per maxBackoff, so that entries of
+// deleted pods don't accumulate.
+func (l *Ledger) purge(timestamp time.Time) {
+	if timestamp.Sub(l.lastPurge) < l.maxBackoff {
+		return
+	}
+	for key, e := range l.entries {
+		if l.expired(e, timestamp) {
+			delete(l.entries, key)
+		}
+	}
+	l.lastPurge = timestamp
+}
+
+// podKey identifies the pod by its UID, so that failures of a pod don't carry
+// over to a pod recreated with the same name.
+func podKey(pod *apiv1.Pod) string {
+	if pod.UID != "" {
+		return string(pod.UID)
+	}
+	return pod.Namespace + "/" + pod.Name
+}
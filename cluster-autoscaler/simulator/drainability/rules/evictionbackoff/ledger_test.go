@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evictionbackoff
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLedger(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	ledger := NewLedger(time.Minute, 5*time.Minute)
+	pod := testPod("uid-1")
+	other := testPod("uid-2")
+
+	_, found := ledger.Failure(pod, now)
+	assert.False(t, found)
+	assert.False(t, ledger.IsBackedOff(pod, now))
+
+	ledger.RegisterFailure(pod, fmt.Errorf("denied by webhook"), now)
+	failure, found := ledger.Failure(pod, now)
+	assert.True(t, found)
+	assert.Equal(t, Failure{Failures: 1, LastError: "denied by webhook", BackoffUntil: now.Add(time.Minute)}, failure)
+	assert.True(t, ledger.IsBackedOff(pod, now.Add(59*time.Second)))
+	assert.False(t, ledger.IsBackedOff(pod, now.Add(time.Minute)))
+	assert.False(t, ledger.IsBackedOff(other, now))
+
+	// The backoff doubles with each consecutive failure, up to the maximum.
+	for i, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		ledger.RegisterFailure(pod, nil, now)
+		failure, _ := ledger.Failure(pod, now)
+		assert.Equal(t, i+2, failure.Failures)
+		assert.Equal(t, now.Add(want), failure.BackoffUntil)
+		assert.Equal(t, "denied by webhook", failure.LastError)
+	}
+
+	// Failures are forgotten once the pod wasn't backed off for the maximum backoff.
+	_, found = ledger.Failure(pod, now.Add(10*time.Minute-time.Second))
+	assert.True(t, found)
+	_, found = ledger.Failure(pod, now.Add(10*time.Minute))
+	assert.False(t, found)
+	ledger.RegisterFailure(pod, nil, now.Add(10*time.Minute))
+	failure, _ = ledger.Failure(pod, now.Add(10*time.Minute))
+	assert.Equal(t, 1, failure.Failures)
+
+	// Successful evictions reset failures.
+	ledger.RegisterSuccess(pod)
+	_, found = ledger.Failure(pod, now.Add(10*time.Minute))
+	assert.False(t, found)
+}
+
+func TestLedgerPurge(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	ledger := NewLedger(time.Minute, 5*time.Minute)
+	ledger.RegisterFailure(testPod("uid-1"), nil, now)
+	ledger.RegisterFailure(testPod("uid-2"), nil, now.Add(5*time.Minute))
+	assert.Len(t, ledger.entries, 2)
+	// Entries are purged at most once per maximum backoff.
+	ledger.RegisterFailure(testPod("uid-3"), nil, now.Add(9*time.Minute))
+	assert.Len(t, ledger.entries, 3)
+	ledger.RegisterFailure(testPod("uid-4"), nil, now.Add(10*time.Minute))
+	assert.Len(t, ledger.entries, 3)
+	_, found := ledger.entries["uid-1"]
+	assert.False(t, found)
+}
+
+func testPod(uid string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-" + uid,
+			Namespace: "ns",
+			UID:       types.UID(uid),
+		},
+	}
+}
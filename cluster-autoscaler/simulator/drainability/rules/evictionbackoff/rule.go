@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evictionbackoff
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Rule is a drainability rule blocking drain of pods whose evictions recently
+// failed, until their backoff passes.
+type Rule struct {
+	ledger *Ledger
+}
+
+// New creates a new Rule.
+func New(ledger *Ledger) *Rule {
+	return &Rule{
+		ledger: ledger,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "EvictionBackoff"
+}
+
+// Drainable decides what to do with pods whose evictions recently failed on
+// node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	failure, found := r.ledger.Failure(pod, drainCtx.Timestamp)
+	if !found || !drainCtx.Timestamp.Before(failure.BackoffUntil) {
+		return drainability.NewUndefinedStatus()
+	}
+	reason := drain.EvictionBackoff
+	if failure.WebhookDenied {
+		reason = drain.DeniedByAdmissionWebhook
+	}
+	return drainability.NewBlockedStatus(reason, fmt.Errorf("%d consecutive evictions of pod %s/%s failed, backing off until %v (last error: %s)",
+		failure.Failures, pod.Namespace, pod.Name, failure.BackoffUntil, failure.LastError))
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evictionbackoff
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainable(t *testing.T) {
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	ledger := NewLedger(time.Minute, 5*time.Minute)
+	failed := testPod("failed")
+	ledger.RegisterFailure(failed, fmt.Errorf("connection refused"), now)
+	denied := testPod("denied")
+	ledger.RegisterFailure(denied, &kube_errors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusForbidden,
+		Message: `admission webhook "deny.example.com" denied the request: pod is protected`,
+	}}, now)
+
+	for desc, tc := range map[string]struct {
+		pod         *apiv1.Pod
+		timestamp   time.Time
+		wantOutcome drainability.OutcomeType
+		wantReason  drain.BlockingPodReason
+		wantError   string
+	}{
+		"during backoff": {
+			pod:         failed,
+			timestamp:   now.Add(30 * time.Second),
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.EvictionBackoff,
+			wantError:   "connection refused",
+		},
+		"after backoff": {
+			pod:         failed,
+			timestamp:   now.Add(time.Minute),
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"denied by admission webhook": {
+			pod:         denied,
+			timestamp:   now.Add(30 * time.Second),
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.DeniedByAdmissionWebhook,
+			wantError:   "pod is protected",
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			rule := New(ledger)
+			drainCtx := &drainability.DrainContext{Timestamp: tc.timestamp}
+			status := rule.Drainable(drainCtx, tc.pod, nil)
+			assert.Equal(t, tc.wantOutcome, status.Outcome)
+			if tc.wantOutcome == drainability.BlockDrain {
+				assert.Equal(t, tc.wantReason, status.BlockingReason)
+				assert.ErrorContains(t, status.Error, tc.wantError)
+			}
+			assert.Equal(t, drainability.UndefinedOutcome, rule.Drainable(drainCtx, testPod("other"), nil).Outcome)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expendable
+
+import (
+	"strconv"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	schedulinglisters "k8s.io/client-go/listers/scheduling/v1"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// ExpendableAnnotationKey is the annotation on a PriorityClass overriding
+// whether its pods are expendable, regardless of the priority cutoff. The
+// value is either "true" or "false".
+const ExpendableAnnotationKey = "cluster-autoscaler.kubernetes.io/expendable"
+
+// Rule is a drainability rule on how to handle expendable pods. Expendable
+// pods are ignored on node drain, they don't cause scale-up and aren't taken
+// into account when simulating whether other pods fit.
+type Rule struct {
+	priorityCutoff      int
+	priorityClassLister schedulinglisters.PriorityClassLister
+}
+
+// New creates a new Rule. Pods with priority below the cutoff are expendable,
+// unless their PriorityClass is annotated otherwise. The lister can be nil, in
+// which case only the cutoff is used.
+func New(priorityCutoff int, priorityClassLister schedulinglisters.PriorityClassLister) *Rule {
+	return &Rule{
+		priorityCutoff:      priorityCutoff,
+		priorityClassLister: priorityClassLister,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "Expendable"
+}
+
+// Drainable decides what to do with expendable pods on node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if r.IsExpendable(pod) {
+		return drainability.NewSkipStatus()
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+// IsExpendable tests if the pod is expendable. The annotation on the pod's
+// PriorityClass takes precedence over the priority cutoff. Pods with null
+// priority are expendable only if their PriorityClass says so.
+func (r *Rule) IsExpendable(pod *apiv1.Pod) bool {
+	if expendable, found := r.priorityClassOverride(pod); found {
+		return expendable
+	}
+	return pod.Spec.Priority != nil && int(*pod.Spec.Priority) < r.priorityCutoff
+}
+
+func (r *Rule) priorityClassOverride(pod *apiv1.Pod) (expendable bool, found bool) {
+	if r.priorityClassLister == nil || pod.Spec.PriorityClassName == "" {
+		return false, false
+	}
+	priorityClass, err := r.priorityClassLister.Get(pod.Spec.PriorityClassName)
+	if err != nil {
+		return false, false
+	}
+	value, found := priorityClass.Annotations[ExpendableAnnotationKey]
+	if !found {
+		return false, false
+	}
+	expendable, err = strconv.ParseBool(value)
+	if err != nil {
+		klog.Warningf("Invalid value %q of annotation %s on PriorityClass %s, using priority cutoff: %v", value, ExpendableAnnotationKey, priorityClass.Name, err)
+		return false, false
+	}
+	return expendable, true
+}
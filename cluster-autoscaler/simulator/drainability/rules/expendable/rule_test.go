@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expendable
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apiv1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	schedulinglisters "k8s.io/client-go/listers/scheduling/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestDrainable(t *testing.T) {
+	priorityClasses := []*schedulingv1.PriorityClass{
+		testPriorityClass("expendable", "true"),
+		testPriorityClass("not-expendable", "false"),
+		testPriorityClass("invalid", "maybe"),
+		testPriorityClass("no-annotation", ""),
+	}
+
+	for desc, tc := range map[string]struct {
+		pod           *apiv1.Pod
+		withoutLister bool
+		want          drainability.Status
+	}{
+		"pod with null priority": {
+			pod:  testPod(nil, ""),
+			want: drainability.NewUndefinedStatus(),
+		},
+		"pod with priority above cutoff": {
+			pod:  testPod(int32Ptr(0), ""),
+			want: drainability.NewUndefinedStatus(),
+		},
+		"pod with priority equal to cutoff": {
+			pod:  testPod(int32Ptr(-10), ""),
+			want: drainability.NewUndefinedStatus(),
+		},
+		"pod with priority below cutoff": {
+			pod:  testPod(int32Ptr(-11), ""),
+			want: drainability.NewSkipStatus(),
+		},
+		"priority class annotated expendable": {
+			pod:  testPod(int32Ptr(1000), "expendable"),
+			want: drainability.NewSkipStatus(),
+		},
+		"priority class annotated expendable, null priority": {
+			pod:  testPod(nil, "expendable"),
+			want: drainability.NewSkipStatus(),
+		},
+		"priority class annotated not expendable": {
+			pod:  testPod(int32Ptr(-100), "not-expendable"),
+			want: drainability.NewUndefinedStatus(),
+		},
+		"priority class with invalid annotation": {
+			pod:  testPod(int32Ptr(-100), "invalid"),
+			want: drainability.NewSkipStatus(),
+		},
+		"priority class without annotation": {
+			pod:  testPod(int32Ptr(1000), "no-annotation"),
+			want: drainability.NewUndefinedStatus(),
+		},
+		"priority class not found": {
+			pod:  testPod(int32Ptr(-100), "missing"),
+			want: drainability.NewSkipStatus(),
+		},
+		"priority class annotated expendable, no lister": {
+			pod:           testPod(int32Ptr(1000), "expendable"),
+			withoutLister: true,
+			want:          drainability.NewUndefinedStatus(),
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			var lister schedulinglisters.PriorityClassLister
+			if !tc.withoutLister {
+				lister = testLister(t, priorityClasses...)
+			}
+			got := New(-10, lister).Drainable(nil, tc.pod, nil)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Rule.Drainable(%v): got status diff (-want +got):\n%s", tc.pod.Name, diff)
+			}
+		})
+	}
+}
+
+func testLister(t *testing.T, priorityClasses ...*schedulingv1.PriorityClass) schedulinglisters.PriorityClassLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, priorityClass := range priorityClasses {
+		if err := indexer.Add(priorityClass); err != nil {
+			t.Fatalf("Failed to add priority class: %v", err)
+		}
+	}
+	return schedulinglisters.NewPriorityClassLister(indexer)
+}
+
+func testPriorityClass(name, expendable string) *schedulingv1.PriorityClass {
+	priorityClass := &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if expendable != "" {
+		priorityClass.Annotations = map[string]string{ExpendableAnnotationKey: expendable}
+	}
+	return priorityClass
+}
+
+func testPod(priority *int32, priorityClassName string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: "ns",
+		},
+		Spec: apiv1.PodSpec{
+			Priority:          priority,
+			PriorityClassName: priorityClassName,
+		},
+	}
+}
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headroom
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Rule is a drainability rule on how to handle headroom placeholder pods.
+// Placeholders don't exist in the cluster, they reserve spare capacity of
+// their node group, so the nodes they are simulated on aren't removed.
+type Rule struct{}
+
+// New creates a new Rule.
+func New() *Rule {
+	return &Rule{}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "Headroom"
+}
+
+// Drainable decides what to do with headroom placeholder pods on node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if pod_util.IsHeadroomPlaceholder(pod) {
+		return drainability.NewBlockedStatus(drain.HeadroomReserved, fmt.Errorf("pod %s/%s reserves spare capacity of node group %s", pod.Namespace, pod.Name, pod.Annotations[pod_util.HeadroomPlaceholderAnnotationKey]))
+	}
+	return drainability.NewUndefinedStatus()
+}
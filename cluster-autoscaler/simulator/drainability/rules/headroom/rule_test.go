@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package headroom
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+)
+
+func TestDrainable(t *testing.T) {
+	for desc, tc := range map[string]struct {
+		pod         *apiv1.Pod
+		wantOutcome drainability.OutcomeType
+		wantReason  drain.BlockingPodReason
+	}{
+		"regular pod": {
+			pod: &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "regularPod",
+					Namespace: "ns",
+				},
+			},
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"headroom placeholder": {
+			pod: &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "placeholder",
+					Namespace: "kube-system",
+					Annotations: map[string]string{
+						pod_util.HeadroomPlaceholderAnnotationKey: "ng1",
+					},
+				},
+			},
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.HeadroomReserved,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			got := New().Drainable(nil, tc.pod, nil)
+			if got.Outcome != tc.wantOutcome || got.BlockingReason != tc.wantReason {
+				t.Errorf("Rule.Drainable(%v) = (outcome: %v, reason: %v), want (outcome: %v, reason: %v)", tc.pod.Name, got.Outcome, got.BlockingReason, tc.wantOutcome, tc.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostprocess
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Rule is a drainability rule on how to handle Windows HostProcess pods.
+// HostProcess containers run directly on the host, typically to manage it, so
+// they can't be moved to other nodes.
+type Rule struct{}
+
+// New creates a new Rule.
+func New() *Rule {
+	return &Rule{}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "HostProcess"
+}
+
+// Drainable decides what to do with HostProcess pods on node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if IsHostProcessPod(pod) {
+		return drainability.NewBlockedStatus(drain.HostProcessPod, fmt.Errorf("pod %s/%s runs Windows HostProcess containers", pod.Namespace, pod.Name))
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+// IsHostProcessPod tells if the pod runs Windows HostProcess containers,
+// according to the pod's or any of its containers' security context. The API
+// server requires either all or none of the containers of a pod to be
+// HostProcess containers.
+func IsHostProcessPod(pod *apiv1.Pod) bool {
+	if sc := pod.Spec.SecurityContext; sc != nil && isHostProcess(sc.WindowsOptions) {
+		return true
+	}
+	for _, containers := range [][]apiv1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			if sc := container.SecurityContext; sc != nil && isHostProcess(sc.WindowsOptions) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isHostProcess(options *apiv1.WindowsSecurityContextOptions) bool {
+	return options != nil && options.HostProcess != nil && *options.HostProcess
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hostprocess
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainable(t *testing.T) {
+	for desc, tc := range map[string]struct {
+		podContext       *apiv1.WindowsSecurityContextOptions
+		containerContext *apiv1.WindowsSecurityContextOptions
+		initContainer    bool
+
+		wantOutcome drainability.OutcomeType
+		wantReason  drain.BlockingPodReason
+	}{
+		"regular pod": {
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"windows pod without host process": {
+			podContext:  &apiv1.WindowsSecurityContextOptions{RunAsUserName: stringPtr("ContainerUser")},
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"host process disabled": {
+			podContext:  &apiv1.WindowsSecurityContextOptions{HostProcess: boolPtr(false)},
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"host process pod": {
+			podContext:  &apiv1.WindowsSecurityContextOptions{HostProcess: boolPtr(true)},
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.HostProcessPod,
+		},
+		"host process container": {
+			containerContext: &apiv1.WindowsSecurityContextOptions{HostProcess: boolPtr(true)},
+			wantOutcome:      drainability.BlockDrain,
+			wantReason:       drain.HostProcessPod,
+		},
+		"host process init container": {
+			containerContext: &apiv1.WindowsSecurityContextOptions{HostProcess: boolPtr(true)},
+			initContainer:    true,
+			wantOutcome:      drainability.BlockDrain,
+			wantReason:       drain.HostProcessPod,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			pod := &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod",
+					Namespace: "ns",
+				},
+			}
+			if tc.podContext != nil {
+				pod.Spec.SecurityContext = &apiv1.PodSecurityContext{WindowsOptions: tc.podContext}
+			}
+			container := apiv1.Container{Name: "container"}
+			if tc.containerContext != nil {
+				container.SecurityContext = &apiv1.SecurityContext{WindowsOptions: tc.containerContext}
+			}
+			if tc.initContainer {
+				pod.Spec.InitContainers = []apiv1.Container{container}
+				pod.Spec.Containers = []apiv1.Container{{Name: "main"}}
+			} else {
+				pod.Spec.Containers = []apiv1.Container{container}
+			}
+
+			got := New().Drainable(nil, pod, nil)
+			assert.Equal(t, tc.wantOutcome, got.Outcome)
+			assert.Equal(t, tc.wantReason, got.BlockingReason)
+		})
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localpv
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Policy defines how the Rule handles pods using node-local persistent
+// volumes.
+type Policy string
+
+const (
+	// Ignore means that node-local persistent volumes don't affect drain.
+	Ignore Policy = "Ignore"
+	// Warn means that pods using node-local persistent volumes are logged,
+	// but don't block drain.
+	Warn Policy = "Warn"
+	// Block means that pods using node-local persistent volumes block drain.
+	Block Policy = "Block"
+)
+
+// ParsePolicy parses a Policy from its name.
+func ParsePolicy(name string) (Policy, error) {
+	switch policy := Policy(name); policy {
+	case Ignore, Warn, Block:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown local persistent volume drain policy %q, expected one of: %s, %s, %s", name, Ignore, Warn, Block)
+}
+
+// Rule is a drainability rule on how to handle pods using persistent volumes
+// bound to their node, e.g. local persistent volumes or volumes with node
+// affinity pinning them to a single node. Such pods can't run anywhere else
+// once their node is removed.
+type Rule struct {
+	pvcLister v1lister.PersistentVolumeClaimLister
+	pvLister  v1lister.PersistentVolumeLister
+	policy    Policy
+}
+
+// New creates a new Rule.
+func New(pvcLister v1lister.PersistentVolumeClaimLister, pvLister v1lister.PersistentVolumeLister, policy Policy) *Rule {
+	return &Rule{
+		pvcLister: pvcLister,
+		pvLister:  pvLister,
+		policy:    policy,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "LocalPersistentVolume"
+}
+
+// Drainable decides what to do with pods using node-local persistent volumes
+// on node drain. Volumes listed in the SafeToEvictLocalVolumesKey annotation
+// are ignored.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if r.policy == Ignore {
+		return drainability.NewUndefinedStatus()
+	}
+	volumes := r.nodeLocalVolumes(pod)
+	if len(volumes) == 0 {
+		return drainability.NewUndefinedStatus()
+	}
+	err := fmt.Errorf("pod with node-local persistent volumes present: %s/%s (volumes not listed in %s annotation: %s)", pod.Namespace, pod.Name, drain.SafeToEvictLocalVolumesKey, strings.Join(volumes, ","))
+	if r.policy == Block {
+		return drainability.NewBlockedStatus(drain.LocalStorageRequested, err)
+	}
+	klog.Warningf("%v, allowing drain", err)
+	return drainability.NewUndefinedStatus()
+}
+
+func (r *Rule) nodeLocalVolumes(pod *apiv1.Pod) []string {
+	var volumes []string
+	isNonBlocking := drain.NonBlockingVolumes(pod)
+	for _, volume := range pod.Spec.Volumes {
+		if isNonBlocking[volume.Name] {
+			continue
+		}
+		claimName := ""
+		if volume.PersistentVolumeClaim != nil {
+			claimName = volume.PersistentVolumeClaim.ClaimName
+		} else if volume.Ephemeral != nil {
+			// Generic ephemeral volumes are backed by a PVC named after the
+			// pod and the volume.
+			claimName = pod.Name + "-" + volume.Name
+		} else {
+			continue
+		}
+		if r.isNodeLocal(pod.Namespace, claimName) {
+			volumes = append(volumes, volume.Name)
+		}
+	}
+	return volumes
+}
+
+func (r *Rule) isNodeLocal(namespace, claimName string) bool {
+	pvc, err := r.pvcLister.PersistentVolumeClaims(namespace).Get(claimName)
+	if err != nil || pvc.Spec.VolumeName == "" {
+		// Unbound claims don't pin the pod to the node.
+		return false
+	}
+	pv, err := r.pvLister.Get(pvc.Spec.VolumeName)
+	if err != nil {
+		klog.V(4).Infof("Persistent volume %s for claim %s/%s not found: %v", pvc.Spec.VolumeName, namespace, claimName, err)
+		return false
+	}
+	return IsNodeLocal(pv)
+}
+
+// IsNodeLocal tells if the persistent volume is only accessible from a single
+// node: it is a local or host path volume, or its node affinity requires a
+// specific hostname.
+func IsNodeLocal(pv *apiv1.PersistentVolume) bool {
+	if pv.Spec.Local != nil || pv.Spec.HostPath != nil {
+		return true
+	}
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil || len(pv.Spec.NodeAffinity.Required.NodeSelectorTerms) == 0 {
+		return false
+	}
+	// Node selector terms are ORed, so the volume is node-local only if
+	// every term pins it to particular hostnames.
+	for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+		if !requiresHostname(term) {
+			return false
+		}
+	}
+	return true
+}
+
+func requiresHostname(term apiv1.NodeSelectorTerm) bool {
+	for _, requirement := range term.MatchExpressions {
+		if requirement.Key == apiv1.LabelHostname && requirement.Operator == apiv1.NodeSelectorOpIn {
+			return true
+		}
+	}
+	for _, requirement := range term.MatchFields {
+		if requirement.Key == "metadata.name" && requirement.Operator == apiv1.NodeSelectorOpIn {
+			return true
+		}
+	}
+	return false
+}
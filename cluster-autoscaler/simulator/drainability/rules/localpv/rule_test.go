@@ -0,0 +1,206 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package localpv
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainable(t *testing.T) {
+	var (
+		localPV = &apiv1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "local-pv"},
+			Spec: apiv1.PersistentVolumeSpec{
+				PersistentVolumeSource: apiv1.PersistentVolumeSource{Local: &apiv1.LocalVolumeSource{Path: "/mnt/disks/ssd1"}},
+			},
+		}
+		hostnamePV = &apiv1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "hostname-pv"},
+			Spec: apiv1.PersistentVolumeSpec{
+				PersistentVolumeSource: apiv1.PersistentVolumeSource{CSI: &apiv1.CSIPersistentVolumeSource{Driver: "local.csi.k8s.io"}},
+				NodeAffinity:           nodeAffinity(apiv1.LabelHostname, "n1"),
+			},
+		}
+		zonalPV = &apiv1.PersistentVolume{
+			ObjectMeta: metav1.ObjectMeta{Name: "zonal-pv"},
+			Spec: apiv1.PersistentVolumeSpec{
+				PersistentVolumeSource: apiv1.PersistentVolumeSource{CSI: &apiv1.CSIPersistentVolumeSource{Driver: "pd.csi.storage.gke.io"}},
+				NodeAffinity:           nodeAffinity(apiv1.LabelTopologyZone, "us-central1-b"),
+			},
+		}
+		pvcs = []*apiv1.PersistentVolumeClaim{
+			testPVC("local", localPV.Name),
+			testPVC("hostname", hostnamePV.Name),
+			testPVC("zonal", zonalPV.Name),
+			testPVC("unbound", ""),
+			testPVC("pod-ephemeral", localPV.Name),
+		}
+	)
+
+	for desc, test := range map[string]struct {
+		pod    *apiv1.Pod
+		policy Policy
+
+		wantOutcome drainability.OutcomeType
+		wantReason  drain.BlockingPodReason
+		wantError   bool
+	}{
+		"pod without volumes": {
+			pod:         testPod(),
+			policy:      Block,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"local persistent volume": {
+			pod:         testPod(pvcVolume("data", "local")),
+			policy:      Block,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.LocalStorageRequested,
+			wantError:   true,
+		},
+		"volume pinned to hostname": {
+			pod:         testPod(pvcVolume("data", "hostname")),
+			policy:      Block,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.LocalStorageRequested,
+			wantError:   true,
+		},
+		"generic ephemeral volume": {
+			pod:         testPod(apiv1.Volume{Name: "ephemeral", VolumeSource: apiv1.VolumeSource{Ephemeral: &apiv1.EphemeralVolumeSource{}}}),
+			policy:      Block,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.LocalStorageRequested,
+			wantError:   true,
+		},
+		"zonal volume": {
+			pod:         testPod(pvcVolume("data", "zonal")),
+			policy:      Block,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"unbound claim": {
+			pod:         testPod(pvcVolume("data", "unbound")),
+			policy:      Block,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"missing claim": {
+			pod:         testPod(pvcVolume("data", "missing")),
+			policy:      Block,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"local persistent volume safe to evict": {
+			pod: func() *apiv1.Pod {
+				pod := testPod(pvcVolume("data", "local"))
+				pod.Annotations = map[string]string{drain.SafeToEvictLocalVolumesKey: "data"}
+				return pod
+			}(),
+			policy:      Block,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"local persistent volume with warn policy": {
+			pod:         testPod(pvcVolume("data", "local")),
+			policy:      Warn,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"local persistent volume with ignore policy": {
+			pod:         testPod(pvcVolume("data", "local")),
+			policy:      Ignore,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			pvcIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, pvc := range pvcs {
+				assert.NoError(t, pvcIndexer.Add(pvc))
+			}
+			pvIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			for _, pv := range []*apiv1.PersistentVolume{localPV, hostnamePV, zonalPV} {
+				assert.NoError(t, pvIndexer.Add(pv))
+			}
+
+			rule := New(v1lister.NewPersistentVolumeClaimLister(pvcIndexer), v1lister.NewPersistentVolumeLister(pvIndexer), test.policy)
+			status := rule.Drainable(&drainability.DrainContext{}, test.pod, nil)
+			assert.Equal(t, test.wantOutcome, status.Outcome)
+			assert.Equal(t, test.wantReason, status.BlockingReason)
+			assert.Equal(t, test.wantError, status.Error != nil)
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, name := range []string{"Ignore", "Warn", "Block"} {
+		policy, err := ParsePolicy(name)
+		assert.NoError(t, err)
+		assert.Equal(t, Policy(name), policy)
+	}
+	_, err := ParsePolicy("block")
+	assert.Error(t, err)
+}
+
+func testPod(volumes ...apiv1.Volume) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: "default",
+		},
+		Spec: apiv1.PodSpec{
+			Volumes: volumes,
+		},
+	}
+}
+
+func pvcVolume(name, claimName string) apiv1.Volume {
+	return apiv1.Volume{
+		Name: name,
+		VolumeSource: apiv1.VolumeSource{
+			PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+		},
+	}
+}
+
+func testPVC(name, volumeName string) *apiv1.PersistentVolumeClaim {
+	return &apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: apiv1.PersistentVolumeClaimSpec{
+			VolumeName: volumeName,
+		},
+	}
+}
+
+func nodeAffinity(key, value string) *apiv1.VolumeNodeAffinity {
+	return &apiv1.VolumeNodeAffinity{
+		Required: &apiv1.NodeSelector{
+			NodeSelectorTerms: []apiv1.NodeSelectorTerm{
+				{
+					MatchExpressions: []apiv1.NodeSelectorRequirement{
+						{Key: key, Operator: apiv1.NodeSelectorOpIn, Values: []string{value}},
+					},
+				},
+			},
+		},
+	}
+}
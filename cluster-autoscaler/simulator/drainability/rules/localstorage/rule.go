@@ -18,18 +18,26 @@ package localstorage
 
 import (
 	"fmt"
+	"strings"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 // Rule is a drainability rule on how to handle local storage pods.
-type Rule struct{}
+type Rule struct {
+	deleteOptions options.NodeDeleteOptions
+}
 
-// New creates a new Rule.
-func New() *Rule {
-	return &Rule{}
+// New creates a new Rule. The delete options are adjusted for the node pods
+// are drained from, see NodeDeleteOptions.ForNode.
+func New(deleteOptions options.NodeDeleteOptions) *Rule {
+	return &Rule{
+		deleteOptions: deleteOptions,
+	}
 }
 
 // Name returns the name of the rule.
@@ -37,10 +45,14 @@ func (r *Rule) Name() string {
 	return "LocalStorage"
 }
 
-// Drainable decides what to do with local storage pods on node drain.
-func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
-	if drain.HasBlockingLocalStorage(pod) {
-		return drainability.NewBlockedStatus(drain.LocalStorageRequested, fmt.Errorf("pod with local storage present: %s", pod.Name))
+// Drainable decides what to do with local storage pods on node drain. Local
+// storage pods only block drain if SkipNodesWithLocalStorage is set.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if !r.deleteOptions.ForNode(nodeInfo.Node()).SkipNodesWithLocalStorage {
+		return drainability.NewUndefinedStatus()
+	}
+	if volumes := drain.BlockingLocalVolumes(pod); len(volumes) > 0 {
+		return drainability.NewBlockedStatus(drain.LocalStorageRequested, fmt.Errorf("pod with local storage present: %s (volumes not listed in %s annotation: %s)", pod.Name, drain.SafeToEvictLocalVolumesKey, strings.Join(volumes, ",")))
 	}
 	return drainability.NewUndefinedStatus()
 }
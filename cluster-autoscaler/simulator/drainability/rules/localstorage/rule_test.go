@@ -24,8 +24,9 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -176,6 +177,36 @@ func TestDrainable(t *testing.T) {
 			},
 			rcs: []*apiv1.ReplicationController{&rc},
 		},
+		"pod with EmptyDir and SafeToEvictLocalVolumesKey annotation with whitespace around matching values": {
+			pod: &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "bar",
+					Namespace:       "default",
+					OwnerReferences: test.GenerateOwnerReferences(rc.Name, "ReplicationController", "core/v1", ""),
+					Annotations: map[string]string{
+						drain.SafeToEvictLocalVolumesKey: "scratch-1, scratch-2 ,scratch-3",
+					},
+				},
+				Spec: apiv1.PodSpec{
+					NodeName: "node",
+					Volumes: []apiv1.Volume{
+						{
+							Name:         "scratch-1",
+							VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{Medium: ""}},
+						},
+						{
+							Name:         "scratch-2",
+							VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{Medium: ""}},
+						},
+						{
+							Name:         "scratch-3",
+							VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{Medium: ""}},
+						},
+					},
+				},
+			},
+			rcs: []*apiv1.ReplicationController{&rc},
+		},
 		"pod with EmptyDir and SafeToEvictLocalVolumesKey annotation with non-matching values": {
 			pod: &apiv1.Pod{
 				ObjectMeta: metav1.ObjectMeta{
@@ -277,7 +308,7 @@ func TestDrainable(t *testing.T) {
 			drainCtx := &drainability.DrainContext{
 				Timestamp: testTime,
 			}
-			status := New().Drainable(drainCtx, test.pod)
+			status := New(options.NodeDeleteOptions{SkipNodesWithLocalStorage: true}).Drainable(drainCtx, test.pod, nil)
 			assert.Equal(t, test.wantReason, status.BlockingReason)
 			assert.Equal(t, test.wantError, status.Error != nil)
 		})
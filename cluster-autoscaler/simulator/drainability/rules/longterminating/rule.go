@@ -17,17 +17,25 @@ limitations under the License.
 package longterminating
 
 import (
+	"time"
+
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 // Rule is a drainability rule on how to handle long terminating pods.
-type Rule struct{}
+type Rule struct {
+	extraThreshold time.Duration
+}
 
-// New creates a new Rule.
-func New() *Rule {
-	return &Rule{}
+// New creates a new Rule. Pods terminating for longer than their termination
+// grace period plus extraThreshold are skipped.
+func New(extraThreshold time.Duration) *Rule {
+	return &Rule{
+		extraThreshold: extraThreshold,
+	}
 }
 
 // Name returns the name of the rule.
@@ -36,8 +44,8 @@ func (r *Rule) Name() string {
 }
 
 // Drainable decides what to do with long terminating pods on node drain.
-func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
-	if drain.IsPodLongTerminating(pod, drainCtx.Timestamp) {
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if drain.IsPodLongTerminatingWithThreshold(pod, drainCtx.Timestamp, r.extraThreshold) {
 		return drainability.NewSkipStatus()
 	}
 	return drainability.NewUndefinedStatus()
@@ -24,7 +24,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 )
 
 func TestDrainable(t *testing.T) {
@@ -32,11 +32,14 @@ func TestDrainable(t *testing.T) {
 		testTime            = time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
 		zeroGracePeriod     = int64(0)
 		extendedGracePeriod = int64(6 * 60) // 6 minutes
+		customThreshold     = time.Minute
+		longThreshold       = time.Hour
 	)
 
 	for desc, tc := range map[string]struct {
-		pod  *apiv1.Pod
-		want drainability.Status
+		pod            *apiv1.Pod
+		extraThreshold *time.Duration
+		want           drainability.Status
 	}{
 		"regular pod": {
 			pod: &apiv1.Pod{
@@ -115,12 +118,52 @@ func TestDrainable(t *testing.T) {
 			},
 			want: drainability.NewSkipStatus(),
 		},
+		"long terminating pod with 0 grace period past custom threshold": {
+			pod: &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "bar",
+					Namespace:         "default",
+					DeletionTimestamp: &metav1.Time{Time: testTime.Add(-2 * time.Minute)},
+				},
+				Spec: apiv1.PodSpec{
+					RestartPolicy:                 apiv1.RestartPolicyOnFailure,
+					TerminationGracePeriodSeconds: &zeroGracePeriod,
+				},
+				Status: apiv1.PodStatus{
+					Phase: apiv1.PodUnknown,
+				},
+			},
+			extraThreshold: &customThreshold,
+			want:           drainability.NewSkipStatus(),
+		},
+		"long terminating pod with 0 grace period below custom threshold": {
+			pod: &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "bar",
+					Namespace:         "default",
+					DeletionTimestamp: &metav1.Time{Time: testTime.Add(-2 * time.Minute)},
+				},
+				Spec: apiv1.PodSpec{
+					RestartPolicy:                 apiv1.RestartPolicyOnFailure,
+					TerminationGracePeriodSeconds: &zeroGracePeriod,
+				},
+				Status: apiv1.PodStatus{
+					Phase: apiv1.PodUnknown,
+				},
+			},
+			extraThreshold: &longThreshold,
+			want:           drainability.NewUndefinedStatus(),
+		},
 	} {
 		t.Run(desc, func(t *testing.T) {
 			drainCtx := &drainability.DrainContext{
 				Timestamp: testTime,
 			}
-			got := New().Drainable(drainCtx, tc.pod)
+			extraThreshold := drain.PodLongTerminatingExtraThreshold
+			if tc.extraThreshold != nil {
+				extraThreshold = *tc.extraThreshold
+			}
+			got := New(extraThreshold).Drainable(drainCtx, tc.pod, nil)
 			if diff := cmp.Diff(tc.want, got); diff != "" {
 				t.Errorf("Rule.Drainable(%v): got status diff (-want +got):\n%s", tc.pod.Name, diff)
 			}
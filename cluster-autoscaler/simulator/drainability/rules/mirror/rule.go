@@ -17,11 +17,21 @@ limitations under the License.
 package mirror
 
 import (
+	"fmt"
+
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
+// DrainableAnnotationKey is the annotation on a static pod, set in its
+// manifest and copied to its mirror pod, telling that the static pod doesn't
+// block node removal even if DeleteOptions.SkipNodesWithStaticPods is set. The
+// value has to be "true".
+const DrainableAnnotationKey = "cluster-autoscaler.kubernetes.io/static-pod-drainable"
+
 // Rule is a drainability rule on how to handle mirror pods.
 type Rule struct{}
 
@@ -35,10 +45,22 @@ func (r *Rule) Name() string {
 	return "Mirror"
 }
 
-// Drainable decides what to do with mirror pods on node drain.
-func (Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
-	if pod_util.IsMirrorPod(pod) {
+// Drainable decides what to do with mirror pods on node drain. Mirror pods
+// can't be evicted and go away with their node, so they are skipped. If
+// DeleteOptions.SkipNodesWithStaticPods is set, mirror pods of kubelet static
+// pods, i.e. pods with a kubelet config source other than the API server,
+// block drain instead, unless annotated with DrainableAnnotationKey. Mirror
+// pods without such a config source aren't backed by a static pod on the node
+// and are always skipped.
+func (Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if !pod_util.IsMirrorPod(pod) {
+		return drainability.NewUndefinedStatus()
+	}
+	if !pod_util.IsStaticPod(pod) || pod.Annotations[DrainableAnnotationKey] == "true" {
 		return drainability.NewSkipStatus()
 	}
-	return drainability.NewUndefinedStatus()
+	if drainCtx != nil && drainCtx.DeleteOptions.SkipNodesWithStaticPods {
+		return drainability.NewBlockedStatus(drain.StaticPod, fmt.Errorf("static pod %s/%s present", pod.Namespace, pod.Name))
+	}
+	return drainability.NewSkipStatus()
 }
@@ -17,19 +17,24 @@ limitations under the License.
 package mirror
 
 import (
+	"fmt"
 	"testing"
 
-	"github.com/google/go-cmp/cmp"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	drainabilitytest "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 )
 
 func TestDrainable(t *testing.T) {
 	for desc, tc := range map[string]struct {
-		pod  *apiv1.Pod
-		want drainability.Status
+		pod             *apiv1.Pod
+		skipStaticPods  bool
+		withoutDrainCtx bool
+		want            drainability.Status
 	}{
 		"regular pod": {
 			pod: &apiv1.Pod{
@@ -52,12 +57,65 @@ func TestDrainable(t *testing.T) {
 			},
 			want: drainability.NewSkipStatus(),
 		},
+		"static pod": {
+			pod:  testStaticPod(types.FileSource, ""),
+			want: drainability.NewSkipStatus(),
+		},
+		"static pod, skipping nodes with static pods": {
+			pod:            testStaticPod(types.FileSource, ""),
+			skipStaticPods: true,
+			want:           drainability.NewBlockedStatus(drain.StaticPod, fmt.Errorf("static pod kube-system/staticPod present")),
+		},
+		"static pod from http source, skipping nodes with static pods": {
+			pod:            testStaticPod(types.HTTPSource, ""),
+			skipStaticPods: true,
+			want:           drainability.NewBlockedStatus(drain.StaticPod, fmt.Errorf("static pod kube-system/staticPod present")),
+		},
+		"static pod annotated drainable, skipping nodes with static pods": {
+			pod:            testStaticPod(types.FileSource, "true"),
+			skipStaticPods: true,
+			want:           drainability.NewSkipStatus(),
+		},
+		"static pod with invalid drainable annotation, skipping nodes with static pods": {
+			pod:            testStaticPod(types.FileSource, "yes"),
+			skipStaticPods: true,
+			want:           drainability.NewBlockedStatus(drain.StaticPod, fmt.Errorf("static pod kube-system/staticPod present")),
+		},
+		"mirror pod from api server source, skipping nodes with static pods": {
+			pod:            testStaticPod(types.ApiserverSource, ""),
+			skipStaticPods: true,
+			want:           drainability.NewSkipStatus(),
+		},
+		"static pod, no drain context": {
+			pod:             testStaticPod(types.FileSource, ""),
+			withoutDrainCtx: true,
+			want:            drainability.NewSkipStatus(),
+		},
 	} {
 		t.Run(desc, func(t *testing.T) {
-			got := New().Drainable(nil, tc.pod)
-			if diff := cmp.Diff(tc.want, got); diff != "" {
-				t.Errorf("Rule.Drainable(%v): got status diff (-want +got):\n%s", tc.pod.Name, diff)
+			drainCtx := &drainability.DrainContext{DeleteOptions: options.NodeDeleteOptions{SkipNodesWithStaticPods: tc.skipStaticPods}}
+			if tc.withoutDrainCtx {
+				drainCtx = nil
 			}
+			got := New().Drainable(drainCtx, tc.pod, nil)
+			drainabilitytest.AssertStatus(t, got, tc.want)
 		})
 	}
 }
+
+func testStaticPod(source, drainable string) *apiv1.Pod {
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "staticPod",
+			Namespace: "kube-system",
+			Annotations: map[string]string{
+				types.ConfigMirrorAnnotationKey: "mirror",
+				types.ConfigSourceAnnotationKey: source,
+			},
+		},
+	}
+	if drainable != "" {
+		pod.Annotations[DrainableAnnotationKey] = drainable
+	}
+	return pod
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespace
+
+import (
+	"fmt"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// ConfigMapKey defines the key used in the ConfigMap to configure
+	// namespace drainability overrides.
+	ConfigMapKey = "namespaces"
+)
+
+// Config lists namespaces with overridden drainability.
+type Config struct {
+	// AlwaysDrainable contains namespaces whose pods never block node drain.
+	AlwaysDrainable []string `yaml:"alwaysDrainable"`
+	// NeverDrainable contains namespaces whose pods always block node drain.
+	NeverDrainable []string `yaml:"neverDrainable"`
+}
+
+// Namespaces reads the namespaces with overridden drainability from a
+// ConfigMap, shared by Rule and DrainableRule. It is safe for concurrent use.
+type Namespaces struct {
+	configMapLister v1lister.ConfigMapNamespaceLister
+	configMapName   string
+
+	mutex           sync.Mutex
+	resourceVersion string
+	alwaysDrainable map[string]bool
+	neverDrainable  map[string]bool
+}
+
+// NewNamespaces creates a new Namespaces. The configuration is read from the
+// ConfigMap with the given name on every call, so changes are picked up
+// without a restart.
+func NewNamespaces(configMapLister v1lister.ConfigMapNamespaceLister, configMapName string) *Namespaces {
+	return &Namespaces{
+		configMapLister: configMapLister,
+		configMapName:   configMapName,
+	}
+}
+
+// Rule is a drainability rule blocking drain of pods from never drainable
+// namespaces. It is meant to be evaluated above the safe-to-evict annotation,
+// which doesn't override it.
+type Rule struct {
+	namespaces *Namespaces
+}
+
+// New creates a new Rule.
+func New(namespaces *Namespaces) *Rule {
+	return &Rule{
+		namespaces: namespaces,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "Namespace"
+}
+
+// Drainable decides what to do with pods from never drainable namespaces on
+// node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	_, neverDrainable := r.namespaces.get()
+	if neverDrainable[pod.Namespace] {
+		return drainability.NewBlockedStatus(drain.NonDrainableNamespace, fmt.Errorf("pod from never drainable namespace present: %s/%s", pod.Namespace, pod.Name))
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+// DrainableRule is a drainability rule allowing drain of pods from always
+// drainable namespaces. It makes pods drainable the same way the
+// safe-to-evict annotation does, so it's meant to be evaluated below
+// disruption budgets.
+type DrainableRule struct {
+	namespaces *Namespaces
+}
+
+// NewDrainable creates a new DrainableRule.
+func NewDrainable(namespaces *Namespaces) *DrainableRule {
+	return &DrainableRule{
+		namespaces: namespaces,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *DrainableRule) Name() string {
+	return "DrainableNamespace"
+}
+
+// Drainable decides what to do with pods from always drainable namespaces on
+// node drain.
+func (r *DrainableRule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	alwaysDrainable, _ := r.namespaces.get()
+	if alwaysDrainable[pod.Namespace] {
+		return drainability.NewDrainableStatus()
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+func (n *Namespaces) get() (alwaysDrainable, neverDrainable map[string]bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	cm, err := n.configMapLister.Get(n.configMapName)
+	if err != nil {
+		klog.V(4).Infof("Namespace drainability config map %s not found: %v", n.configMapName, err)
+		n.resourceVersion, n.alwaysDrainable, n.neverDrainable = "", nil, nil
+		return nil, nil
+	}
+	if cm.ResourceVersion != "" && cm.ResourceVersion == n.resourceVersion {
+		return n.alwaysDrainable, n.neverDrainable
+	}
+
+	config, err := parseConfig(cm)
+	if err != nil {
+		// Keep the last valid configuration.
+		klog.Warningf("Wrong configuration for namespace drainability: %v. Ignoring update.", err)
+		return n.alwaysDrainable, n.neverDrainable
+	}
+
+	n.resourceVersion = cm.ResourceVersion
+	n.alwaysDrainable = toSet(config.AlwaysDrainable)
+	n.neverDrainable = toSet(config.NeverDrainable)
+	klog.V(4).Infof("Loaded namespace drainability configuration from config map %s", n.configMapName)
+	return n.alwaysDrainable, n.neverDrainable
+}
+
+func parseConfig(cm *apiv1.ConfigMap) (*Config, error) {
+	configString, found := cm.Data[ConfigMapKey]
+	if !found {
+		return nil, fmt.Errorf("config map %s doesn't contain %s key", cm.Name, ConfigMapKey)
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict([]byte(configString), config); err != nil {
+		return nil, fmt.Errorf("can't parse YAML with namespaces in config map %s: %v", cm.Name, err)
+	}
+	for _, ns := range config.NeverDrainable {
+		for _, other := range config.AlwaysDrainable {
+			if ns == other {
+				return nil, fmt.Errorf("namespace %s is configured as both always and never drainable", ns)
+			}
+		}
+	}
+	return config, nil
+}
+
+func toSet(namespaces []string) map[string]bool {
+	set := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		set[ns] = true
+	}
+	return set
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespace
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
+	"k8s.io/kubernetes/pkg/kubelet/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testConfigMapName = "drainability-namespaces"
+	testNamespace     = "kube-system"
+)
+
+func TestDrainable(t *testing.T) {
+	var (
+		testTime = time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+
+		validConfig = `
+alwaysDrainable:
+  - batch
+neverDrainable:
+  - payments
+`
+	)
+
+	for desc, tc := range map[string]struct {
+		pod    *apiv1.Pod
+		config *string
+
+		wantOutcome drainability.OutcomeType
+		wantReason  drain.BlockingPodReason
+		wantError   bool
+	}{
+		"no config map": {
+			pod:         testPod("payments"),
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"pod from always drainable namespace": {
+			pod:         testPod("batch"),
+			config:      &validConfig,
+			wantOutcome: drainability.DrainOk,
+		},
+		"pod from never drainable namespace": {
+			pod:         testPod("payments"),
+			config:      &validConfig,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.NonDrainableNamespace,
+			wantError:   true,
+		},
+		"pod from other namespace": {
+			pod:         testPod("default"),
+			config:      &validConfig,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"invalid config": {
+			pod:         testPod("payments"),
+			config:      stringPtr("neverDrainable: payments"),
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"namespace both always and never drainable": {
+			pod:         testPod("payments"),
+			config:      stringPtr("alwaysDrainable: [payments]\nneverDrainable: [payments]"),
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			var cms []*apiv1.ConfigMap
+			if tc.config != nil {
+				cms = append(cms, testConfigMap("1", *tc.config))
+			}
+			lister, err := test.NewTestConfigMapLister(cms)
+			assert.NoError(t, err)
+
+			drainCtx := &drainability.DrainContext{
+				Timestamp: testTime,
+			}
+			namespaces := NewNamespaces(lister.ConfigMaps(testNamespace), testConfigMapName)
+			status := New(namespaces).Drainable(drainCtx, tc.pod, nil)
+			if status.Outcome == drainability.UndefinedOutcome {
+				status = NewDrainable(namespaces).Drainable(drainCtx, tc.pod, nil)
+			}
+			assert.Equal(t, tc.wantOutcome, status.Outcome)
+			assert.Equal(t, tc.wantReason, status.BlockingReason)
+			assert.Equal(t, tc.wantError, status.Error != nil)
+		})
+	}
+}
+
+func TestDrainableConfigUpdates(t *testing.T) {
+	cms := []*apiv1.ConfigMap{testConfigMap("1", "neverDrainable: [payments]")}
+	lister, err := test.NewTestConfigMapLister(cms)
+	assert.NoError(t, err)
+	namespaces := NewNamespaces(lister.ConfigMaps(testNamespace), testConfigMapName)
+	rule, drainableRule := New(namespaces), NewDrainable(namespaces)
+	drainCtx := &drainability.DrainContext{}
+
+	assert.Equal(t, drainability.BlockDrain, rule.Drainable(drainCtx, testPod("payments"), nil).Outcome)
+
+	// An invalid update keeps the last valid configuration.
+	cms[0].ResourceVersion = "2"
+	cms[0].Data[ConfigMapKey] = "neverDrainable: payments"
+	assert.Equal(t, drainability.BlockDrain, rule.Drainable(drainCtx, testPod("payments"), nil).Outcome)
+
+	cms[0].ResourceVersion = "3"
+	cms[0].Data[ConfigMapKey] = "alwaysDrainable: [payments]"
+	assert.Equal(t, drainability.UndefinedOutcome, rule.Drainable(drainCtx, testPod("payments"), nil).Outcome)
+	assert.Equal(t, drainability.DrainOk, drainableRule.Drainable(drainCtx, testPod("payments"), nil).Outcome)
+}
+
+func TestDrainableWithDefaultRules(t *testing.T) {
+	cms := []*apiv1.ConfigMap{testConfigMap("1", "alwaysDrainable: [batch]\nneverDrainable: [payments]")}
+	lister, err := test.NewTestConfigMapLister(cms)
+	assert.NoError(t, err)
+	namespaces := NewNamespaces(lister.ConfigMaps(testNamespace), testConfigMapName)
+	drainabilityRules := append(rules.Default(options.NodeDeleteOptions{}),
+		rules.WithPriority(New(namespaces), rules.BudgetPriority),
+		rules.WithPriority(NewDrainable(namespaces), rules.NonBlockingPriority))
+
+	mirrorPod := testPod("payments")
+	mirrorPod.Annotations = map[string]string{types.ConfigMirrorAnnotationKey: "something"}
+	budgetedPod := testPod("batch")
+	budgetedPod.Labels = map[string]string{"app": "job"}
+	zeroBudget := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "batch"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "job"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 0},
+	}
+
+	for desc, tc := range map[string]struct {
+		pod         *apiv1.Pod
+		pdbs        []*policyv1.PodDisruptionBudget
+		wantOutcome drainability.OutcomeType
+		wantReason  drain.BlockingPodReason
+	}{
+		"pod from always drainable namespace": {
+			pod:         testPod("batch"),
+			wantOutcome: drainability.DrainOk,
+		},
+		"pod from always drainable namespace without disruptions allowed": {
+			pod:         budgetedPod,
+			pdbs:        []*policyv1.PodDisruptionBudget{zeroBudget},
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.NotEnoughPdb,
+		},
+		"pod from never drainable namespace": {
+			pod:         testPod("payments"),
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.NonDrainableNamespace,
+		},
+		"mirror pod from never drainable namespace": {
+			pod:         mirrorPod,
+			wantOutcome: drainability.SkipDrain,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			tracker := pdb.NewBasicRemainingPdbTracker()
+			assert.NoError(t, tracker.SetPdbs(tc.pdbs))
+			drainCtx := &drainability.DrainContext{RemainingPdbTracker: tracker}
+			status := drainabilityRules.Drainable(drainCtx, tc.pod, nil)
+			assert.Equal(t, tc.wantOutcome, status.Outcome)
+			assert.Equal(t, tc.wantReason, status.BlockingReason)
+		})
+	}
+}
+
+func testPod(namespace string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bar",
+			Namespace: namespace,
+		},
+	}
+}
+
+func testConfigMap(resourceVersion, config string) *apiv1.ConfigMap {
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            testConfigMapName,
+			Namespace:       testNamespace,
+			ResourceVersion: resourceVersion,
+		},
+		Data: map[string]string{
+			ConfigMapKey: config,
+		},
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -21,10 +21,14 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/safetoevict"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
-// Rule is a drainability rule on how to handle not safe to evict pods.
+// Rule is a drainability rule on how to handle not safe to evict pods. The
+// annotation can be set on the pod, or inherited from its owning workload or
+// namespace.
 type Rule struct{}
 
 // New creates a new Rule.
@@ -38,9 +42,13 @@ func (r *Rule) Name() string {
 }
 
 // Drainable decides what to do with not safe to evict pods on node drain.
-func (Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
+func (Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	value, source, _ := safetoevict.Annotation(drainCtx, pod)
+	if value != "false" {
+		return drainability.NewUndefinedStatus()
+	}
 	if drain.HasNotSafeToEvictAnnotation(pod) {
 		return drainability.NewBlockedStatus(drain.NotSafeToEvictAnnotation, fmt.Errorf("pod annotated as not safe to evict present: %s", pod.Name))
 	}
-	return drainability.NewUndefinedStatus()
+	return drainability.NewBlockedStatus(drain.NotSafeToEvictAnnotation, fmt.Errorf("pod %s inherits not safe to evict annotation from %s", pod.Name, source))
 }
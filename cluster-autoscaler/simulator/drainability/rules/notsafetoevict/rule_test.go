@@ -17,6 +17,7 @@ limitations under the License.
 package notsafetoevict
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -25,8 +26,9 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	drainabilitytest "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -138,9 +140,37 @@ func TestDrainable(t *testing.T) {
 			drainCtx := &drainability.DrainContext{
 				Timestamp: testTime,
 			}
-			status := New().Drainable(drainCtx, test.pod)
+			status := New().Drainable(drainCtx, test.pod, nil)
 			assert.Equal(t, test.wantReason, status.BlockingReason)
 			assert.Equal(t, test.wantError, status.Error != nil)
 		})
 	}
 }
+
+func TestDrainableInherited(t *testing.T) {
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Annotations: map[string]string{drain.PodSafeToEvictKey: "false"}}}
+	ss := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: "ns", Annotations: map[string]string{drain.PodSafeToEvictKey: "true"}}}
+	drainabilitytest.RunDrainableTests(t, New(), []drainabilitytest.TestCase{
+		{
+			Name:    "inherited from namespace",
+			Pod:     &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}},
+			Options: []drainabilitytest.DrainContextOption{drainabilitytest.WithOwners(namespace)},
+			Want:    drainability.NewBlockedStatus(drain.NotSafeToEvictAnnotation, fmt.Errorf("pod pod inherits not safe to evict annotation from namespace ns")),
+		},
+		{
+			Name: "statefulset annotation overrides namespace",
+			Pod: &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Name:            "pod",
+				Namespace:       "ns",
+				OwnerReferences: test.GenerateOwnerReferences(ss.Name, "StatefulSet", "apps/v1", ""),
+			}},
+			Options: []drainabilitytest.DrainContextOption{drainabilitytest.WithOwners(namespace, ss)},
+			Want:    drainability.NewUndefinedStatus(),
+		},
+		{
+			Name: "no listers",
+			Pod:  &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}},
+			Want: drainability.NewUndefinedStatus(),
+		},
+	})
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package override
+
+import (
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Kind is the kind of the custom resource declaring drainable pods.
+const Kind = "DrainabilityOverride"
+
+// Resource is the resource of the DrainabilityOverride custom resource definition.
+var Resource = schema.GroupVersionResource{Group: "autoscaling.x-k8s.io", Version: "v1alpha1", Resource: "drainabilityoverrides"}
+
+// Rule is a drainability rule on how to handle pods selected by
+// DrainabilityOverrides. An override only applies to pods in its own
+// namespace, so who can declare pods drainable is controlled by RBAC
+// permissions to create the overrides, and only if the namespace is in the
+// allowlist of the rule.
+type Rule struct {
+	lister            cache.GenericLister
+	allowedNamespaces map[string]bool
+}
+
+// New creates a new Rule honoring overrides in the allowed namespaces.
+func New(lister cache.GenericLister, allowedNamespaces []string) *Rule {
+	allowed := make(map[string]bool, len(allowedNamespaces))
+	for _, ns := range allowedNamespaces {
+		allowed[ns] = true
+	}
+	return &Rule{
+		lister:            lister,
+		allowedNamespaces: allowed,
+	}
+}
+
+// NewLister returns a lister of DrainabilityOverrides in all namespaces,
+// backed by an informer running until stopChannel is closed.
+func NewLister(client dynamic.Interface, stopChannel <-chan struct{}) cache.GenericLister {
+	informer := dynamicinformer.NewFilteredDynamicInformer(client, Resource, metav1.NamespaceAll, time.Hour, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, nil)
+	go informer.Informer().Run(stopChannel)
+	return informer.Lister()
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "DrainabilityOverride"
+}
+
+// Drainable decides what to do with pods selected by DrainabilityOverrides on
+// node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if !r.allowedNamespaces[pod.Namespace] {
+		return drainability.NewUndefinedStatus()
+	}
+
+	objs, err := r.lister.ByNamespace(pod.Namespace).List(labels.Everything())
+	if err != nil {
+		klog.Warningf("Failed to list %ss in namespace %s: %v", Kind, pod.Namespace, err)
+		return drainability.NewUndefinedStatus()
+	}
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		selector, err := podSelector(u)
+		if err != nil {
+			klog.Warningf("Invalid %s %s/%s: %v", Kind, u.GetNamespace(), u.GetName(), err)
+			continue
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			klog.V(4).Infof("Pod %s/%s is drainable according to %s %s", pod.Namespace, pod.Name, Kind, u.GetName())
+			return drainability.NewDrainableStatus()
+		}
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+// podSelector returns the selector of pods declared drainable by the override.
+// An empty selector selects all pods in the namespace.
+func podSelector(u *unstructured.Unstructured) (labels.Selector, error) {
+	selectorMap, found, err := unstructured.NestedMap(u.Object, "spec", "podSelector")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("spec.podSelector not set")
+	}
+	labelSelector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selectorMap, labelSelector); err != nil {
+		return nil, err
+	}
+	return metav1.LabelSelectorAsSelector(labelSelector)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package override
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/kubernetes/pkg/kubelet/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainable(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	overrides := []*unstructured.Unstructured{
+		testOverride("kube-system", "metrics", map[string]interface{}{
+			"matchLabels": map[string]interface{}{"app": "metrics"},
+		}),
+		testOverride("kube-system", "invalid", map[string]interface{}{
+			"matchExpressions": []interface{}{map[string]interface{}{"key": "app", "operator": "Bogus"}},
+		}),
+		testOverride("batch", "all", map[string]interface{}{}),
+		testOverride("default", "all", map[string]interface{}{}),
+	}
+
+	for desc, test := range map[string]struct {
+		pod         *apiv1.Pod
+		wantOutcome drainability.OutcomeType
+	}{
+		"pod selected by override": {
+			pod:         testPod("kube-system", map[string]string{"app": "metrics"}),
+			wantOutcome: drainability.DrainOk,
+		},
+		"pod not selected by override": {
+			pod:         testPod("kube-system", map[string]string{"app": "dns"}),
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"override with empty selector": {
+			pod:         testPod("batch", nil),
+			wantOutcome: drainability.DrainOk,
+		},
+		"override in namespace not in allowlist": {
+			pod:         testPod("default", nil),
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"no overrides in namespace": {
+			pod:         testPod("payments", nil),
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			rule := New(testLister(t, overrides...), []string{"kube-system", "batch", "payments"})
+			status := rule.Drainable(&drainability.DrainContext{Timestamp: testTime}, test.pod, nil)
+			assert.Equal(t, test.wantOutcome, status.Outcome)
+		})
+	}
+}
+
+func TestDrainableOverridesSystemRule(t *testing.T) {
+	pod := testPod("kube-system", map[string]string{"app": "metrics"})
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "metrics", Controller: boolPtr(true)}}
+	drainCtx := &drainability.DrainContext{DeleteOptions: options.NodeDeleteOptions{SkipNodesWithSystemPods: true}}
+	defaultRules := rules.Default(drainCtx.DeleteOptions)
+
+	status := defaultRules.Drainable(drainCtx, pod, nil)
+	assert.Equal(t, drainability.BlockDrain, status.Outcome)
+	assert.Equal(t, drain.UnmovableKubeSystemPod, status.BlockingReason)
+
+	rule := New(testLister(t, testOverride("kube-system", "metrics", map[string]interface{}{
+		"matchLabels": map[string]interface{}{"app": "metrics"},
+	})), []string{"kube-system"})
+	withOverride := append(rules.Rules{rules.WithPriority(rule, rules.NonBlockingPriority)}, defaultRules...)
+	status = withOverride.Drainable(drainCtx, pod, nil)
+	assert.Equal(t, drainability.DrainOk, status.Outcome)
+
+	// Mirror pods are skipped by the default rules before overrides are evaluated.
+	mirrorPod := pod.DeepCopy()
+	mirrorPod.Annotations = map[string]string{types.ConfigMirrorAnnotationKey: "something"}
+	status = withOverride.Drainable(drainCtx, mirrorPod, nil)
+	assert.Equal(t, drainability.SkipDrain, status.Outcome)
+}
+
+func testLister(t *testing.T, overrides ...*unstructured.Unstructured) cache.GenericLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, override := range overrides {
+		assert.NoError(t, indexer.Add(override))
+	}
+	return cache.NewGenericLister(indexer, Resource.GroupResource())
+}
+
+func testOverride(namespace, name string, podSelector map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"podSelector": podSelector},
+	}}
+	u.SetAPIVersion(Resource.GroupVersion().String())
+	u.SetKind(Kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func testPod(namespace string, labels map[string]string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "bar",
+			Namespace: namespace,
+			Labels:    labels,
+		},
+	}
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -21,7 +21,7 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 // Rule is a drainability rule on how to handle pods with pdbs.
@@ -38,11 +38,9 @@ func (r *Rule) Name() string {
 }
 
 // Drainable decides how to handle pods with pdbs on node drain.
-func (Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
-	for _, pdb := range drainCtx.RemainingPdbTracker.MatchingPdbs(pod) {
-		if pdb.Status.DisruptionsAllowed < 1 {
-			return drainability.NewBlockedStatus(drain.NotEnoughPdb, fmt.Errorf("not enough pod disruption budget to move %s/%s", pod.Namespace, pod.Name))
-		}
+func (Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if canRemove, _, blockingPod := drainCtx.RemainingPdbTracker.CanRemovePods([]*apiv1.Pod{pod}); !canRemove {
+		return drainability.NewBlockedStatus(blockingPod.Reason, fmt.Errorf("not enough pod disruption budget to move %s/%s", pod.Namespace, pod.Name))
 	}
 	return drainability.NewUndefinedStatus()
 }
@@ -23,9 +23,9 @@ import (
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -143,9 +143,45 @@ func TestDrainable(t *testing.T) {
 				RemainingPdbTracker: tracker,
 			}
 
-			got := New().Drainable(drainCtx, tc.pod)
+			got := New().Drainable(drainCtx, tc.pod, nil)
 			assert.Equal(t, tc.wantReason, got.BlockingReason)
 			assert.Equal(t, tc.wantOutcome, got.Outcome)
 		})
 	}
 }
+
+func TestDrainableSharedBudget(t *testing.T) {
+	one := intstr.FromInt(1)
+	labels := map[string]string{"label": "true"}
+	tracker := pdb.NewBasicRemainingPdbTracker()
+	tracker.SetPdbs([]*policyv1.PodDisruptionBudget{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "good",
+			},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &one,
+				Selector: &metav1.LabelSelector{
+					MatchLabels: labels,
+				},
+			},
+			Status: policyv1.PodDisruptionBudgetStatus{
+				DisruptionsAllowed: 1,
+			},
+		},
+	})
+	drainCtx := &drainability.DrainContext{
+		RemainingPdbTracker: tracker,
+	}
+	first := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "good", Labels: labels}}
+	second := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "good", Labels: labels}}
+
+	assert.Equal(t, drainability.UndefinedOutcome, New().Drainable(drainCtx, first, nil).Outcome)
+
+	// Simulate the first pod being drained from another node.
+	tracker.RemovePods([]*apiv1.Pod{first})
+
+	got := New().Drainable(drainCtx, second, nil)
+	assert.Equal(t, drainability.BlockDrain, got.Outcome)
+	assert.Equal(t, drain.NotEnoughPdb, got.BlockingReason)
+}
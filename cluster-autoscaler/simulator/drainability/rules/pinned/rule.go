@@ -0,0 +1,200 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pinned
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Policy defines how the Rule handles pods pinned to their node.
+type Policy string
+
+const (
+	// Ignore means that pinned pods are simulated like any other pods, so
+	// they make their node unremovable because they don't fit elsewhere.
+	Ignore Policy = "Ignore"
+	// Block means that pinned pods block drain with the PinnedToNode reason.
+	Block Policy = "Block"
+)
+
+// ParsePolicy parses a Policy from its name.
+func ParsePolicy(name string) (Policy, error) {
+	switch policy := Policy(name); policy {
+	case Ignore, Block:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown pinned pod drain policy %q, expected one of: %s, %s", name, Ignore, Block)
+}
+
+// Rule is a drainability rule on how to handle pods pinned to their node,
+// i.e. pods whose node selector or required node affinity, e.g. on the
+// kubernetes.io/hostname label, doesn't match any other node in the cluster.
+// Such pods can't run anywhere else once their node is removed. Pinned pods
+// matching the sacrificable selector are evicted with their node without
+// being rescheduled.
+type Rule struct {
+	policy       Policy
+	sacrificable labels.Selector
+
+	// Nodes matching the node selector and required node affinity of pods
+	// are cached for DrainContext.Timestamp, so that the nodes are listed
+	// once per distinct affinity in each simulation instead of once per pod.
+	mutex     sync.Mutex
+	timestamp time.Time
+	matches   map[string][]string
+}
+
+// New creates a new Rule. The sacrificable selector can be nil if no pinned
+// pods may be sacrificed.
+func New(policy Policy, sacrificable labels.Selector) *Rule {
+	return &Rule{
+		policy:       policy,
+		sacrificable: sacrificable,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "PinnedToNode"
+}
+
+// Drainable decides what to do with pods pinned to their node on node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if !hasRequiredNodeAffinity(pod) || nodeInfo == nil || nodeInfo.Node() == nil || drainCtx.Listers == nil {
+		return drainability.NewUndefinedStatus()
+	}
+	if r.policy == Ignore && r.sacrificable == nil {
+		return drainability.NewUndefinedStatus()
+	}
+	pinned, err := r.isPinned(drainCtx, pod, nodeInfo.Node())
+	if err != nil {
+		klog.Warningf("Can't check if pod %s/%s is pinned to node %s: %v", pod.Namespace, pod.Name, nodeInfo.Node().Name, err)
+		return drainability.NewUndefinedStatus()
+	}
+	if !pinned {
+		return drainability.NewUndefinedStatus()
+	}
+	if r.sacrificable != nil && r.sacrificable.Matches(labels.Set(pod.Labels)) {
+		klog.V(4).Infof("Pod %s/%s is pinned to node %s, sacrificing it on drain", pod.Namespace, pod.Name, nodeInfo.Node().Name)
+		return drainability.NewSkipStatus()
+	}
+	if r.policy == Block {
+		return drainability.NewBlockedStatus(drain.PinnedToNode, fmt.Errorf("pod %s/%s is pinned to node %s: its node selector or affinity doesn't match any other node", pod.Namespace, pod.Name, nodeInfo.Node().Name))
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+func hasRequiredNodeAffinity(pod *apiv1.Pod) bool {
+	if len(pod.Spec.NodeSelector) > 0 {
+		return true
+	}
+	affinity := pod.Spec.Affinity
+	return affinity != nil && affinity.NodeAffinity != nil && affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil
+}
+
+// isPinned tells if the node is the only node in the cluster matching the
+// pod's node selector and required node affinity.
+func (r *Rule) isPinned(drainCtx *drainability.DrainContext, pod *apiv1.Pod, node *apiv1.Node) (bool, error) {
+	required := nodeaffinity.GetRequiredNodeAffinity(pod)
+	if match, err := required.Match(node); err != nil || !match {
+		return false, err
+	}
+	// Hostnames are unique, so there is no need to look for other nodes.
+	if _, found := pod.Spec.NodeSelector[apiv1.LabelHostname]; found {
+		return true, nil
+	}
+	matches, err := r.matchingNodes(drainCtx, pod, required)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range matches {
+		if name != node.Name {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchingNodes returns the names of up to two nodes matching the required
+// node affinity of the pod, which is enough to tell if the pod is pinned to
+// one of them. Results are cached for DrainContext.Timestamp, unless it's
+// zero.
+func (r *Rule) matchingNodes(drainCtx *drainability.DrainContext, pod *apiv1.Pod, required nodeaffinity.RequiredNodeAffinity) ([]string, error) {
+	var key string
+	if !drainCtx.Timestamp.IsZero() {
+		key = affinityKey(pod)
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if !r.timestamp.Equal(drainCtx.Timestamp) {
+			r.timestamp = drainCtx.Timestamp
+			r.matches = make(map[string][]string)
+		}
+		matches, found := r.matches[key]
+		metrics.RegisterCacheLookup(metrics.PinnedNodesCache, found)
+		if found {
+			return matches, nil
+		}
+	}
+	nodes, err := drainCtx.Listers.AllNodeLister().List()
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, node := range nodes {
+		if match, err := required.Match(node); err == nil && match {
+			matches = append(matches, node.Name)
+			if len(matches) == 2 {
+				break
+			}
+		}
+	}
+	if !drainCtx.Timestamp.IsZero() {
+		r.matches[key] = matches
+	}
+	return matches, nil
+}
+
+// affinityKey identifies the node selector and required node affinity of the
+// pod, so that pods with the same ones share the cached matching nodes.
+func affinityKey(pod *apiv1.Pod) string {
+	keys := make([]string, 0, len(pod.Spec.NodeSelector))
+	for key := range pod.Spec.NodeSelector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s=%s,", key, pod.Spec.NodeSelector[key])
+	}
+	if affinity := pod.Spec.Affinity; affinity != nil && affinity.NodeAffinity != nil && affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		b.WriteString(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pinned
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	drainabilitytest "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	. "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainable(t *testing.T) {
+	n1 := testNode("n1", "pool-a")
+	n2 := testNode("n2", "pool-a")
+	n3 := testNode("n3", "pool-b")
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(n1)
+	listers := &drainabilitytest.Listers{Nodes: NewTestNodeLister([]*apiv1.Node{n1, n2, n3})}
+	sacrificable := labels.SelectorFromSet(labels.Set{"sacrificable": "true"})
+
+	plainPod := BuildTestPod("plain", 100, 0)
+	poolPod := BuildTestPod("pool", 100, 0)
+	poolPod.Spec.NodeSelector = map[string]string{"pool": "pool-a"}
+	hostnamePod := BuildTestPod("hostname", 100, 0)
+	hostnamePod.Spec.NodeSelector = map[string]string{apiv1.LabelHostname: "n1"}
+	fieldPod := BuildTestPod("field", 100, 0)
+	fieldPod.Spec.Affinity = &apiv1.Affinity{NodeAffinity: &apiv1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &apiv1.NodeSelector{NodeSelectorTerms: []apiv1.NodeSelectorTerm{{
+			MatchFields: []apiv1.NodeSelectorRequirement{{Key: "metadata.name", Operator: apiv1.NodeSelectorOpIn, Values: []string{"n1"}}},
+		}}},
+	}}
+	sacrificablePod := hostnamePod.DeepCopy()
+	sacrificablePod.Labels = map[string]string{"sacrificable": "true"}
+
+	for desc, tc := range map[string]struct {
+		pod          *apiv1.Pod
+		policy       Policy
+		sacrificable labels.Selector
+		noListers    bool
+		wantReason   drain.BlockingPodReason
+		wantOutcome  drainability.OutcomeType
+	}{
+		"pod without node affinity": {
+			pod:         plainPod,
+			policy:      Block,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"pod matching other nodes": {
+			pod:         poolPod,
+			policy:      Block,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"pod pinned by node selector": {
+			pod:         hostnamePod,
+			policy:      Block,
+			wantReason:  drain.PinnedToNode,
+			wantOutcome: drainability.BlockDrain,
+		},
+		"pod pinned by node affinity": {
+			pod:         fieldPod,
+			policy:      Block,
+			wantReason:  drain.PinnedToNode,
+			wantOutcome: drainability.BlockDrain,
+		},
+		"pinned pod with ignore policy": {
+			pod:         hostnamePod,
+			policy:      Ignore,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"sacrificable pinned pod": {
+			pod:          sacrificablePod,
+			policy:       Block,
+			sacrificable: sacrificable,
+			wantOutcome:  drainability.SkipDrain,
+		},
+		"sacrificable pinned pod with ignore policy": {
+			pod:          sacrificablePod,
+			policy:       Ignore,
+			sacrificable: sacrificable,
+			wantOutcome:  drainability.SkipDrain,
+		},
+		"pinned pod not matching sacrificable selector": {
+			pod:          hostnamePod,
+			policy:       Block,
+			sacrificable: sacrificable,
+			wantReason:   drain.PinnedToNode,
+			wantOutcome:  drainability.BlockDrain,
+		},
+		"no listers": {
+			pod:         hostnamePod,
+			policy:      Block,
+			noListers:   true,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			drainCtx := &drainability.DrainContext{Listers: listers}
+			if tc.noListers {
+				drainCtx.Listers = nil
+			}
+			got := New(tc.policy, tc.sacrificable).Drainable(drainCtx, tc.pod, nodeInfo)
+			assert.Equal(t, tc.wantOutcome, got.Outcome)
+			assert.Equal(t, tc.wantReason, got.BlockingReason)
+		})
+	}
+}
+
+// countingNodeLister counts calls to List.
+type countingNodeLister struct {
+	drainability.NodeLister
+	lists int
+}
+
+func (l *countingNodeLister) List() ([]*apiv1.Node, error) {
+	l.lists++
+	return l.NodeLister.List()
+}
+
+func TestDrainableCachesMatchingNodes(t *testing.T) {
+	n1 := testNode("n1", "pool-a")
+	n2 := testNode("n2", "pool-a")
+	nodeLister := &countingNodeLister{NodeLister: NewTestNodeLister([]*apiv1.Node{n1, n2})}
+	listers := &drainabilitytest.Listers{Nodes: nodeLister}
+	poolPods := []*apiv1.Pod{BuildTestPod("p1", 100, 0), BuildTestPod("p2", 100, 0)}
+	for _, pod := range poolPods {
+		pod.Spec.NodeSelector = map[string]string{"pool": "pool-a"}
+	}
+	fieldPod := BuildTestPod("field", 100, 0)
+	fieldPod.Spec.Affinity = &apiv1.Affinity{NodeAffinity: &apiv1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &apiv1.NodeSelector{NodeSelectorTerms: []apiv1.NodeSelectorTerm{{
+			MatchFields: []apiv1.NodeSelectorRequirement{{Key: "metadata.name", Operator: apiv1.NodeSelectorOpIn, Values: []string{"n1"}}},
+		}}},
+	}}
+	hostnamePod := BuildTestPod("hostname", 100, 0)
+	hostnamePod.Spec.NodeSelector = map[string]string{apiv1.LabelHostname: "n1"}
+	rule := New(Block, nil)
+	timestamp := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, node := range []*apiv1.Node{n1, n2} {
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		for _, pod := range poolPods {
+			got := rule.Drainable(&drainability.DrainContext{Listers: listers, Timestamp: timestamp}, pod, nodeInfo)
+			assert.Equal(t, drainability.UndefinedOutcome, got.Outcome)
+		}
+	}
+	assert.Equal(t, 1, nodeLister.lists)
+
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(n1)
+	got := rule.Drainable(&drainability.DrainContext{Listers: listers, Timestamp: timestamp}, fieldPod, nodeInfo)
+	assert.Equal(t, drainability.BlockDrain, got.Outcome)
+	assert.Equal(t, 2, nodeLister.lists)
+
+	// Pods selecting the hostname of their node are pinned without listing nodes.
+	got = rule.Drainable(&drainability.DrainContext{Listers: listers, Timestamp: timestamp}, hostnamePod, nodeInfo)
+	assert.Equal(t, drainability.BlockDrain, got.Outcome)
+	assert.Equal(t, 2, nodeLister.lists)
+
+	// Nodes are listed again for another simulation, as they may have changed.
+	got = rule.Drainable(&drainability.DrainContext{Listers: listers, Timestamp: timestamp.Add(time.Second)}, poolPods[0], nodeInfo)
+	assert.Equal(t, drainability.UndefinedOutcome, got.Outcome)
+	assert.Equal(t, 3, nodeLister.lists)
+}
+
+func BenchmarkDrainable(b *testing.B) {
+	// Nodes of each pool are listed next to each other, so pods of pools
+	// listed last match no other node until most of the nodes are checked.
+	const nodeCount, podsPerNode, poolSize = 1000, 20, 10
+	var nodes []*apiv1.Node
+	var nodeInfos []*framework.NodeInfo
+	var pods [][]*apiv1.Pod
+	for i := 0; i < nodeCount; i++ {
+		node := testNode(fmt.Sprintf("n%d", i), fmt.Sprintf("pool-%d", i/poolSize))
+		nodeInfo := framework.NewNodeInfo()
+		nodeInfo.SetNode(node)
+		nodes = append(nodes, node)
+		nodeInfos = append(nodeInfos, nodeInfo)
+		var nodePods []*apiv1.Pod
+		for j := 0; j < podsPerNode; j++ {
+			pod := BuildTestPod(fmt.Sprintf("p%d-%d", i, j), 100, 0)
+			pod.Spec.NodeSelector = map[string]string{"pool": node.Labels["pool"]}
+			nodePods = append(nodePods, pod)
+		}
+		pinnedPod := BuildTestPod(fmt.Sprintf("pinned%d", i), 100, 0)
+		pinnedPod.Spec.NodeSelector = map[string]string{apiv1.LabelHostname: node.Name}
+		nodePods = append(nodePods, pinnedPod)
+		pods = append(pods, nodePods)
+	}
+	listers := &drainabilitytest.Listers{Nodes: NewTestNodeLister(nodes)}
+	rule := New(Block, nil)
+	timestamp := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		// Each iteration is a separate simulation, like a scale down loop.
+		drainCtx := &drainability.DrainContext{Listers: listers, Timestamp: timestamp.Add(time.Duration(n) * time.Second)}
+		for i, nodeInfo := range nodeInfos {
+			for _, pod := range pods[i] {
+				rule.Drainable(drainCtx, pod, nodeInfo)
+			}
+		}
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, policy := range []Policy{Ignore, Block} {
+		got, err := ParsePolicy(string(policy))
+		assert.NoError(t, err)
+		assert.Equal(t, policy, got)
+	}
+	_, err := ParsePolicy("Warn")
+	assert.Error(t, err)
+}
+
+func testNode(name, pool string) *apiv1.Node {
+	node := BuildTestNode(name, 1000, 1000)
+	node.Labels = map[string]string{apiv1.LabelHostname: name, "pool": pool}
+	return node
+}
@@ -22,19 +22,23 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
-	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
-// Rule is a drainability rule on how to handle replicated pods.
+// Rule is a drainability rule on how to handle replicated pods. The minimum
+// replica count is taken from MinReplicaCount of the delete options.
 type Rule struct {
-	minReplicaCount int
+	deleteOptions options.NodeDeleteOptions
 }
 
-// New creates a new Rule.
-func New(minReplicaCount int) *Rule {
+// New creates a new Rule. The delete options are adjusted for the node pods
+// are drained from, see NodeDeleteOptions.ForNode.
+func New(deleteOptions options.NodeDeleteOptions) *Rule {
 	return &Rule{
-		minReplicaCount: minReplicaCount,
+		deleteOptions: deleteOptions,
 	}
 }
 
@@ -44,7 +48,7 @@ func (r *Rule) Name() string {
 }
 
 // Drainable decides what to do with replicated pods on node drain.
-func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
 	if drainCtx.Listers == nil {
 		return drainability.NewUndefinedStatus()
 	}
@@ -58,6 +62,7 @@ func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) dr
 		return drainability.NewUndefinedStatus()
 	}
 	refKind := controllerRef.Kind
+	minReplicaCount := r.deleteOptions.ForNode(nodeInfo.Node()).MinReplicaCount
 
 	if refKind == "ReplicationController" {
 		rc, err := drainCtx.Listers.ReplicationControllerLister().ReplicationControllers(controllerNamespace).Get(controllerRef.Name)
@@ -67,8 +72,8 @@ func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) dr
 		}
 
 		// TODO: Replace the minReplica check with PDB.
-		if rc.Spec.Replicas != nil && int(*rc.Spec.Replicas) < r.minReplicaCount {
-			return drainability.NewBlockedStatus(drain.MinReplicasReached, fmt.Errorf("replication controller for %s/%s has too few replicas spec: %d min: %d", pod.Namespace, pod.Name, rc.Spec.Replicas, r.minReplicaCount))
+		if rc.Spec.Replicas != nil && int(*rc.Spec.Replicas) < minReplicaCount {
+			return drainability.NewBlockedStatus(drain.MinReplicasReached, fmt.Errorf("replication controller for %s/%s has too few replicas spec: %d min: %d", pod.Namespace, pod.Name, rc.Spec.Replicas, minReplicaCount))
 		}
 	} else if pod_util.IsDaemonSetPod(pod) {
 		if refKind != "DaemonSet" {
@@ -96,8 +101,8 @@ func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) dr
 
 		if err == nil && rs != nil {
 			// Assume the only reason for an error is because the RS is gone/missing.
-			if rs.Spec.Replicas != nil && int(*rs.Spec.Replicas) < r.minReplicaCount {
-				return drainability.NewBlockedStatus(drain.MinReplicasReached, fmt.Errorf("replication controller for %s/%s has too few replicas spec: %d min: %d", pod.Namespace, pod.Name, rs.Spec.Replicas, r.minReplicaCount))
+			if rs.Spec.Replicas != nil && int(*rs.Spec.Replicas) < minReplicaCount {
+				return drainability.NewBlockedStatus(drain.MinReplicasReached, fmt.Errorf("replication controller for %s/%s has too few replicas spec: %d min: %d", pod.Namespace, pod.Name, rs.Spec.Replicas, minReplicaCount))
 			}
 		} else {
 			return drainability.NewBlockedStatus(drain.ControllerNotFound, fmt.Errorf("replication controller for %s/%s is not available, err: %v", pod.Namespace, pod.Name, err))
@@ -25,9 +25,10 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
-	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	drainabilitytest "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 	v1appslister "k8s.io/client-go/listers/apps/v1"
 	v1lister "k8s.io/client-go/listers/core/v1"
 
@@ -82,7 +83,7 @@ func TestDrainable(t *testing.T) {
 		}
 	)
 
-	for desc, test := range map[string]struct {
+	for desc, tc := range map[string]struct {
 		desc string
 		pod  *apiv1.Pod
 		rcs  []*apiv1.ReplicationController
@@ -265,31 +266,37 @@ func TestDrainable(t *testing.T) {
 		t.Run(desc, func(t *testing.T) {
 			var err error
 			var rcLister v1lister.ReplicationControllerLister
-			if len(test.rcs) > 0 {
-				rcLister, err = kube_util.NewTestReplicationControllerLister(test.rcs)
+			if len(tc.rcs) > 0 {
+				rcLister, err = test.NewTestReplicationControllerLister(tc.rcs)
 				assert.NoError(t, err)
 			}
 			var rsLister v1appslister.ReplicaSetLister
-			if len(test.rss) > 0 {
-				rsLister, err = kube_util.NewTestReplicaSetLister(test.rss)
+			if len(tc.rss) > 0 {
+				rsLister, err = test.NewTestReplicaSetLister(tc.rss)
 				assert.NoError(t, err)
 			}
-			dsLister, err := kube_util.NewTestDaemonSetLister([]*appsv1.DaemonSet{&ds})
+			dsLister, err := test.NewTestDaemonSetLister([]*appsv1.DaemonSet{&ds})
 			assert.NoError(t, err)
-			jobLister, err := kube_util.NewTestJobLister([]*batchv1.Job{&job})
+			jobLister, err := test.NewTestJobLister([]*batchv1.Job{&job})
 			assert.NoError(t, err)
-			ssLister, err := kube_util.NewTestStatefulSetLister([]*appsv1.StatefulSet{&statefulset})
+			ssLister, err := test.NewTestStatefulSetLister([]*appsv1.StatefulSet{&statefulset})
 			assert.NoError(t, err)
 
-			registry := kube_util.NewListerRegistry(nil, nil, nil, nil, dsLister, rcLister, jobLister, rsLister, ssLister)
+			listers := &drainabilitytest.Listers{
+				DaemonSets:             dsLister,
+				ReplicationControllers: rcLister,
+				Jobs:                   jobLister,
+				ReplicaSets:            rsLister,
+				StatefulSets:           ssLister,
+			}
 
 			drainCtx := &drainability.DrainContext{
-				Listers:   registry,
+				Listers:   listers,
 				Timestamp: testTime,
 			}
-			status := New(0).Drainable(drainCtx, test.pod)
-			assert.Equal(t, test.wantReason, status.BlockingReason)
-			assert.Equal(t, test.wantError, status.Error != nil)
+			status := New(options.NodeDeleteOptions{}).Drainable(drainCtx, tc.pod, nil)
+			assert.Equal(t, tc.wantReason, status.BlockingReason)
+			assert.Equal(t, tc.wantError, status.Error != nil)
 		})
 	}
 }
@@ -20,13 +20,16 @@ import (
 	"fmt"
 
 	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 // Rule is a drainability rule on how to handle replicated pods.
 type Rule struct {
 	skipNodesWithCustomControllerPods bool
+	scaleLookup                       ScaleLookup
 }
 
 // New creates a new Rule.
@@ -36,27 +39,84 @@ func New(skipNodesWithCustomControllerPods bool) *Rule {
 	}
 }
 
+// NewWithScaleLookup creates a new Rule treating pods owned by custom
+// controllers as replicated if the controller implements the scale
+// subresource and has more than one replica, even if
+// skipNodesWithCustomControllerPods is set.
+func NewWithScaleLookup(skipNodesWithCustomControllerPods bool, scaleLookup ScaleLookup) *Rule {
+	return &Rule{
+		skipNodesWithCustomControllerPods: skipNodesWithCustomControllerPods,
+		scaleLookup:                       scaleLookup,
+	}
+}
+
 // Name returns the name of the rule.
 func (r *Rule) Name() string {
 	return "Replicated"
 }
 
 // Drainable decides what to do with replicated pods on node drain.
-func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
 	controllerRef := drain.ControllerRef(pod)
 	replicated := controllerRef != nil
 
-	if r.skipNodesWithCustomControllerPods {
+	if r.skipNodesWithCustomControllerPods && replicated && !replicatedKind[controllerRef.Kind] {
 		// TODO(vadasambar): remove this when we get rid of skipNodesWithCustomControllerPods
-		replicated = replicated && replicatedKind[controllerRef.Kind]
+		if r.scaleLookup != nil {
+			return r.checkScale(pod, controllerRef)
+		}
+		replicated = false
 	}
 
+	if controllerRef == nil && isOneOff(pod) {
+		return checkOneOffLifetime(drainCtx, pod)
+	}
 	if !replicated {
 		return drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("%s/%s is not replicated", pod.Namespace, pod.Name))
 	}
 	return drainability.NewUndefinedStatus()
 }
 
+// isOneOff returns true if the pod isn't restarted once its containers exit
+// successfully, i.e. it's expected to finish on its own.
+func isOneOff(pod *apiv1.Pod) bool {
+	return pod.Spec.RestartPolicy == apiv1.RestartPolicyNever || pod.Spec.RestartPolicy == apiv1.RestartPolicyOnFailure
+}
+
+// checkOneOffLifetime blocks drain of an unreplicated one-off pod, unless it
+// has been running for at least OneOffPodMaxLifetime. Such pods are expected
+// to finish soon, so they block drain for a limited time only.
+func checkOneOffLifetime(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
+	maxLifetime := drainCtx.DeleteOptions.OneOffPodMaxLifetime
+	if maxLifetime <= 0 {
+		return drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("%s/%s is not replicated", pod.Namespace, pod.Name))
+	}
+	startTime := pod.CreationTimestamp.Time
+	if pod.Status.StartTime != nil {
+		startTime = pod.Status.StartTime.Time
+	}
+	if lifetime := drainCtx.Timestamp.Sub(startTime); lifetime < maxLifetime {
+		return drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("%s/%s is not replicated and has been running for %v, less than the max lifetime of one-off pods %v", pod.Namespace, pod.Name, lifetime, maxLifetime))
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+// checkScale blocks drain of the pod unless its custom controller implements
+// the scale subresource and has more than one replica.
+func (r *Rule) checkScale(pod *apiv1.Pod, controllerRef *metav1.OwnerReference) drainability.Status {
+	replicas, found, err := r.scaleLookup.Replicas(pod.Namespace, controllerRef)
+	if err != nil {
+		return drainability.NewBlockedStatus(drain.ControllerNotFound, fmt.Errorf("scale of %s %s for %s/%s is not available, err: %v", controllerRef.Kind, controllerRef.Name, pod.Namespace, pod.Name, err))
+	}
+	if !found {
+		return drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("%s/%s is not replicated, %s doesn't implement the scale subresource", pod.Namespace, pod.Name, controllerRef.Kind))
+	}
+	if replicas <= 1 {
+		return drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("%s/%s is not replicated, %s %s has %d replicas", pod.Namespace, pod.Name, controllerRef.Kind, controllerRef.Name, replicas))
+	}
+	return drainability.NewUndefinedStatus()
+}
+
 // replicatedKind returns true if this kind has replicates pods.
 var replicatedKind = map[string]bool{
 	"ReplicationController": true,
@@ -26,9 +26,10 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
-	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	drainabilitytest "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 	v1appslister "k8s.io/client-go/listers/apps/v1"
 	v1lister "k8s.io/client-go/listers/core/v1"
 
@@ -205,35 +206,183 @@ func TestDrainable(t *testing.T) {
 		wantError:                         true,
 	}
 
-	for desc, test := range tests {
+	for desc, tc := range tests {
 		t.Run(desc, func(t *testing.T) {
 			var err error
 			var rcLister v1lister.ReplicationControllerLister
-			if len(test.rcs) > 0 {
-				rcLister, err = kube_util.NewTestReplicationControllerLister(test.rcs)
+			if len(tc.rcs) > 0 {
+				rcLister, err = test.NewTestReplicationControllerLister(tc.rcs)
 				assert.NoError(t, err)
 			}
 			var rsLister v1appslister.ReplicaSetLister
-			if len(test.rss) > 0 {
-				rsLister, err = kube_util.NewTestReplicaSetLister(test.rss)
+			if len(tc.rss) > 0 {
+				rsLister, err = test.NewTestReplicaSetLister(tc.rss)
 				assert.NoError(t, err)
 			}
-			dsLister, err := kube_util.NewTestDaemonSetLister([]*appsv1.DaemonSet{&ds})
+			dsLister, err := test.NewTestDaemonSetLister([]*appsv1.DaemonSet{&ds})
 			assert.NoError(t, err)
-			jobLister, err := kube_util.NewTestJobLister([]*batchv1.Job{&job})
+			jobLister, err := test.NewTestJobLister([]*batchv1.Job{&job})
 			assert.NoError(t, err)
-			ssLister, err := kube_util.NewTestStatefulSetLister([]*appsv1.StatefulSet{&statefulset})
+			ssLister, err := test.NewTestStatefulSetLister([]*appsv1.StatefulSet{&statefulset})
 			assert.NoError(t, err)
 
-			registry := kube_util.NewListerRegistry(nil, nil, nil, nil, dsLister, rcLister, jobLister, rsLister, ssLister)
+			listers := &drainabilitytest.Listers{
+				DaemonSets:             dsLister,
+				ReplicationControllers: rcLister,
+				Jobs:                   jobLister,
+				ReplicaSets:            rsLister,
+				StatefulSets:           ssLister,
+			}
 
 			drainCtx := &drainability.DrainContext{
-				Listers:   registry,
+				Listers:   listers,
 				Timestamp: testTime,
 			}
-			status := New(test.skipNodesWithCustomControllerPods).Drainable(drainCtx, test.pod)
-			assert.Equal(t, test.wantReason, status.BlockingReason)
-			assert.Equal(t, test.wantError, status.Error != nil)
+			status := New(tc.skipNodesWithCustomControllerPods).Drainable(drainCtx, tc.pod, nil)
+			assert.Equal(t, tc.wantReason, status.BlockingReason)
+			assert.Equal(t, tc.wantError, status.Error != nil)
 		})
 	}
 }
+
+func TestDrainableOneOffPods(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	nakedPod := func(restartPolicy apiv1.RestartPolicy, startedAgo time.Duration) *apiv1.Pod {
+		return &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "bar",
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(testTime.Add(-startedAgo - time.Minute)),
+			},
+			Spec: apiv1.PodSpec{
+				RestartPolicy: restartPolicy,
+			},
+			Status: apiv1.PodStatus{
+				StartTime: &metav1.Time{Time: testTime.Add(-startedAgo)},
+			},
+		}
+	}
+
+	for desc, tc := range map[string]struct {
+		pod         *apiv1.Pod
+		maxLifetime time.Duration
+		wantBlocked bool
+	}{
+		"one-off pod, max lifetime not set": {
+			pod:         nakedPod(apiv1.RestartPolicyNever, 10*time.Hour),
+			wantBlocked: true,
+		},
+		"one-off pod younger than max lifetime": {
+			pod:         nakedPod(apiv1.RestartPolicyNever, 59*time.Minute),
+			maxLifetime: time.Hour,
+			wantBlocked: true,
+		},
+		"one-off pod running for max lifetime": {
+			pod:         nakedPod(apiv1.RestartPolicyNever, time.Hour),
+			maxLifetime: time.Hour,
+		},
+		"restarted on failure pod older than max lifetime": {
+			pod:         nakedPod(apiv1.RestartPolicyOnFailure, 2*time.Hour),
+			maxLifetime: time.Hour,
+		},
+		"not started one-off pod created before max lifetime": {
+			pod: &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "bar",
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(testTime.Add(-2 * time.Hour)),
+				},
+				Spec: apiv1.PodSpec{
+					RestartPolicy: apiv1.RestartPolicyNever,
+				},
+			},
+			maxLifetime: time.Hour,
+		},
+		"long-running pod older than max lifetime": {
+			pod:         nakedPod(apiv1.RestartPolicyAlways, 2*time.Hour),
+			maxLifetime: time.Hour,
+			wantBlocked: true,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			drainCtx := &drainability.DrainContext{
+				Timestamp:     testTime,
+				DeleteOptions: options.NodeDeleteOptions{OneOffPodMaxLifetime: tc.maxLifetime},
+			}
+			status := New(false).Drainable(drainCtx, tc.pod, nil)
+			if tc.wantBlocked {
+				assert.Equal(t, drainability.BlockDrain, status.Outcome)
+				assert.Equal(t, drain.NotReplicated, status.BlockingReason)
+			} else {
+				assert.Equal(t, drainability.NewUndefinedStatus(), status)
+			}
+		})
+	}
+}
+
+func TestDrainableWithScaleLookup(t *testing.T) {
+	customControllerPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "bar",
+			Namespace:       "default",
+			OwnerReferences: test.GenerateOwnerReferences("foo", "FooSet", "example.com/v1", ""),
+		},
+	}
+	rsPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "bar",
+			Namespace:       "default",
+			OwnerReferences: test.GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", ""),
+		},
+	}
+
+	for desc, tc := range map[string]struct {
+		pod        *apiv1.Pod
+		lookup     fakeScaleLookup
+		wantReason drain.BlockingPodReason
+		wantError  bool
+	}{
+		"controller with multiple replicas": {
+			pod:    customControllerPod,
+			lookup: fakeScaleLookup{replicas: 3, found: true},
+		},
+		"controller with a single replica": {
+			pod:        customControllerPod,
+			lookup:     fakeScaleLookup{replicas: 1, found: true},
+			wantReason: drain.NotReplicated,
+			wantError:  true,
+		},
+		"controller without scale subresource": {
+			pod:        customControllerPod,
+			lookup:     fakeScaleLookup{},
+			wantReason: drain.NotReplicated,
+			wantError:  true,
+		},
+		"lookup error": {
+			pod:        customControllerPod,
+			lookup:     fakeScaleLookup{err: fmt.Errorf("forbidden")},
+			wantReason: drain.ControllerNotFound,
+			wantError:  true,
+		},
+		"known kind isn't looked up": {
+			pod:    rsPod,
+			lookup: fakeScaleLookup{err: fmt.Errorf("unexpected lookup")},
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			status := NewWithScaleLookup(true, tc.lookup).Drainable(&drainability.DrainContext{}, tc.pod, nil)
+			assert.Equal(t, tc.wantReason, status.BlockingReason)
+			assert.Equal(t, tc.wantError, status.Error != nil)
+		})
+	}
+}
+
+type fakeScaleLookup struct {
+	replicas int32
+	found    bool
+	err      error
+}
+
+func (l fakeScaleLookup) Replicas(namespace string, controllerRef *metav1.OwnerReference) (int32, bool, error) {
+	return l.replicas, l.found, l.err
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicated
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/metrics"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+)
+
+// DefaultScaleLookupCacheTTL is the time for which scale subresource discovery
+// and replica counts are cached by the ScaleLookup.
+const DefaultScaleLookupCacheTTL = time.Minute
+
+// ScaleLookup finds replica counts of custom controllers.
+type ScaleLookup interface {
+	// Replicas returns the desired number of replicas of the controller in
+	// the namespace. found is false if the controller doesn't implement the
+	// scale subresource.
+	Replicas(namespace string, controllerRef *metav1.OwnerReference) (replicas int32, found bool, err error)
+}
+
+// DiscoveryScaleLookup is a ScaleLookup using API discovery to check whether
+// controllers implement the scale subresource, and reading their replica
+// counts from it.
+type DiscoveryScaleLookup struct {
+	discovery discovery.ServerResourcesInterface
+	client    dynamic.Interface
+	cacheTTL  time.Duration
+	now       func() time.Time
+
+	mutex     sync.Mutex
+	resources map[string]resourcesEntry
+	replicas  map[string]replicasEntry
+	lastPurge time.Time
+}
+
+type resourcesEntry struct {
+	// scalable maps kinds implementing the scale subresource to their resources.
+	scalable map[string]string
+	expires  time.Time
+}
+
+type replicasEntry struct {
+	replicas int32
+	expires  time.Time
+}
+
+// NewDiscoveryScaleLookup creates a new DiscoveryScaleLookup. Discovery
+// results and replica counts are cached for cacheTTL.
+func NewDiscoveryScaleLookup(discovery discovery.ServerResourcesInterface, client dynamic.Interface, cacheTTL time.Duration) *DiscoveryScaleLookup {
+	return &DiscoveryScaleLookup{
+		discovery: discovery,
+		client:    client,
+		cacheTTL:  cacheTTL,
+		now:       time.Now,
+		resources: make(map[string]resourcesEntry),
+		replicas:  make(map[string]replicasEntry),
+	}
+}
+
+// Replicas returns the desired number of replicas of the controller in the
+// namespace, read from its scale subresource.
+func (l *DiscoveryScaleLookup) Replicas(namespace string, controllerRef *metav1.OwnerReference) (int32, bool, error) {
+	gv, err := schema.ParseGroupVersion(controllerRef.APIVersion)
+	if err != nil {
+		return 0, false, err
+	}
+	scalable, err := l.scalableResources(gv)
+	if err != nil {
+		return 0, false, err
+	}
+	resource, found := scalable[controllerRef.Kind]
+	if !found {
+		return 0, false, nil
+	}
+
+	key := fmt.Sprintf("%s/%s/%s/%s", gv.String(), resource, namespace, controllerRef.Name)
+	l.mutex.Lock()
+	entry, found := l.replicas[key]
+	l.mutex.Unlock()
+	hit := found && l.now().Before(entry.expires)
+	metrics.RegisterCacheLookup(metrics.ScaleReplicasCache, hit)
+	if hit {
+		return entry.replicas, true, nil
+	}
+
+	scale, err := l.client.Resource(gv.WithResource(resource)).Namespace(namespace).Get(context.TODO(), controllerRef.Name, metav1.GetOptions{}, "scale")
+	if err != nil {
+		return 0, false, err
+	}
+	replicas, _, err := unstructured.NestedInt64(scale.Object, "spec", "replicas")
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid scale of %s %s/%s: %v", controllerRef.Kind, namespace, controllerRef.Name, err)
+	}
+	l.mutex.Lock()
+	now := l.now()
+	// Drop expired entries at most once per TTL, so that replicas of deleted
+	// controllers don't accumulate.
+	if now.Sub(l.lastPurge) >= l.cacheTTL {
+		for k, entry := range l.replicas {
+			if !now.Before(entry.expires) {
+				delete(l.replicas, k)
+			}
+		}
+		l.lastPurge = now
+	}
+	l.replicas[key] = replicasEntry{replicas: int32(replicas), expires: now.Add(l.cacheTTL)}
+	l.mutex.Unlock()
+	return int32(replicas), true, nil
+}
+
+// scalableResources returns kinds in the group version implementing the scale
+// subresource, mapped to their resources.
+func (l *DiscoveryScaleLookup) scalableResources(gv schema.GroupVersion) (map[string]string, error) {
+	l.mutex.Lock()
+	entry, found := l.resources[gv.String()]
+	l.mutex.Unlock()
+	hit := found && l.now().Before(entry.expires)
+	metrics.RegisterCacheLookup(metrics.ScaleResourcesCache, hit)
+	if hit {
+		return entry.scalable, nil
+	}
+
+	resourceList, err := l.discovery.ServerResourcesForGroupVersion(gv.String())
+	if err != nil {
+		return nil, err
+	}
+	kinds := make(map[string]string)
+	subresources := make(map[string]bool)
+	for _, resource := range resourceList.APIResources {
+		if strings.Contains(resource.Name, "/") {
+			subresources[resource.Name] = true
+		} else {
+			kinds[resource.Name] = resource.Kind
+		}
+	}
+	scalable := make(map[string]string)
+	for resource, kind := range kinds {
+		if subresources[resource+"/scale"] {
+			scalable[kind] = resource
+		}
+	}
+	l.mutex.Lock()
+	l.resources[gv.String()] = resourcesEntry{scalable: scalable, expires: l.now().Add(l.cacheTTL)}
+	l.mutex.Unlock()
+	return scalable, nil
+}
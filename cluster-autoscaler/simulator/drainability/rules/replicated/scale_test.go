@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replicated
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoveryScaleLookup(t *testing.T) {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "foosets", Kind: "FooSet", Namespaced: true},
+				{Name: "foosets/scale", Kind: "Scale", Namespaced: true},
+				{Name: "bars", Kind: "Bar", Namespaced: true},
+				{Name: "bars/status", Kind: "Bar", Namespaced: true},
+			},
+		},
+	}}}
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	scaleGets := 0
+	client.PrependReactor("get", "foosets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" || action.GetNamespace() != "default" {
+			return false, nil, nil
+		}
+		scaleGets++
+		return true, &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "autoscaling/v1",
+			"kind":       "Scale",
+			"spec":       map[string]interface{}{"replicas": int64(3)},
+		}}, nil
+	})
+
+	now := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	lookup := NewDiscoveryScaleLookup(discovery, client, time.Minute)
+	lookup.now = func() time.Time { return now }
+
+	replicas, found, err := lookup.Replicas("default", &metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "FooSet", Name: "foo"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, int32(3), replicas)
+
+	_, found, err = lookup.Replicas("default", &metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "Bar", Name: "bar"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	_, _, err = lookup.Replicas("default", &metav1.OwnerReference{APIVersion: "other.com/v1", Kind: "Baz", Name: "baz"})
+	assert.Error(t, err)
+
+	// Replicas are cached for the TTL.
+	_, _, err = lookup.Replicas("default", &metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "FooSet", Name: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, scaleGets)
+	now = now.Add(2 * time.Minute)
+	_, _, err = lookup.Replicas("default", &metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "FooSet", Name: "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, scaleGets)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// RevisionAnnotationKey is the annotation the Deployment controller sets on
+// its ReplicaSets, holding the revision of the Deployment they implement.
+const RevisionAnnotationKey = "deployment.kubernetes.io/revision"
+
+// Rule is a drainability rule delaying drain of pods whose ReplicaSet is being
+// replaced by a newer ReplicaSet of the same Deployment, until the replacement
+// has all of its replicas available. Evicting the old pods while the rollout
+// is scaling the new ones up would dip the availability of the Deployment
+// further than the rollout itself does.
+type Rule struct {
+	maxDelay time.Duration
+}
+
+// New creates a new Rule. Pods aren't delayed for longer than maxDelay after
+// the replacement ReplicaSet was created, so that a stuck rollout doesn't
+// block scale down indefinitely.
+func New(maxDelay time.Duration) *Rule {
+	return &Rule{
+		maxDelay: maxDelay,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "OwnerRollout"
+}
+
+// Drainable decides what to do with pods of ReplicaSets being replaced on node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if drainCtx == nil || drainCtx.Listers == nil {
+		return drainability.NewUndefinedStatus()
+	}
+	controllerRef := drain.ControllerRef(pod)
+	if controllerRef == nil || controllerRef.Kind != "ReplicaSet" {
+		return drainability.NewUndefinedStatus()
+	}
+	rsLister := drainCtx.Listers.ReplicaSetLister().ReplicaSets(pod.Namespace)
+	rs, err := rsLister.Get(controllerRef.Name)
+	if err != nil {
+		// Missing controllers are handled by the replicated rule.
+		return drainability.NewUndefinedStatus()
+	}
+	deploymentRef := metav1.GetControllerOf(rs)
+	if deploymentRef == nil || deploymentRef.Kind != "Deployment" {
+		return drainability.NewUndefinedStatus()
+	}
+	siblings, err := rsLister.List(labels.Everything())
+	if err != nil {
+		return drainability.NewBlockedStatus(drain.UnexpectedError, fmt.Errorf("can't list ReplicaSets of Deployment %s/%s: %v", pod.Namespace, deploymentRef.Name, err))
+	}
+	replacement := newestReplicaSet(siblings, deploymentRef.UID)
+	if replacement == nil || replacement.UID == rs.UID {
+		return drainability.NewUndefinedStatus()
+	}
+	desired := int32(1)
+	if replacement.Spec.Replicas != nil {
+		desired = *replacement.Spec.Replicas
+	}
+	if replacement.Status.AvailableReplicas >= desired {
+		return drainability.NewUndefinedStatus()
+	}
+	if !drainCtx.Timestamp.Before(replacement.CreationTimestamp.Add(r.maxDelay)) {
+		return drainability.NewUndefinedStatus()
+	}
+	return drainability.NewBlockedStatus(drain.OwnerRolloutInProgress, fmt.Errorf("ReplicaSet %s of pod %s/%s is being replaced by ReplicaSet %s of Deployment %s, which has %d of %d replicas available",
+		rs.Name, pod.Namespace, pod.Name, replacement.Name, deploymentRef.Name, replacement.Status.AvailableReplicas, desired))
+}
+
+// newestReplicaSet returns the ReplicaSet of the Deployment with the highest
+// revision, or nil if none of them has a valid revision.
+func newestReplicaSet(replicaSets []*appsv1.ReplicaSet, deploymentUID types.UID) *appsv1.ReplicaSet {
+	var newest *appsv1.ReplicaSet
+	var newestRevision int64
+	for _, rs := range replicaSets {
+		ref := metav1.GetControllerOf(rs)
+		if ref == nil || ref.UID != deploymentUID {
+			continue
+		}
+		revision, err := strconv.ParseInt(rs.Annotations[RevisionAnnotationKey], 10, 64)
+		if err != nil {
+			continue
+		}
+		if newest == nil || revision > newestRevision {
+			newest, newestRevision = rs, revision
+		}
+	}
+	return newest
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rollout
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	drainabilitytest "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	. "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainable(t *testing.T) {
+	now := time.Now()
+	old := testReplicaSet("old", "web", "1", 0, 0, now.Add(-time.Hour))
+	rolling := testReplicaSet("rolling", "web", "2", 3, 1, now.Add(-5*time.Minute))
+	rolledOut := testReplicaSet("rolled-out", "web", "2", 3, 3, now.Add(-5*time.Minute))
+	stuck := testReplicaSet("stuck", "web", "2", 3, 1, now.Add(-time.Hour))
+	otherDeployment := testReplicaSet("other-deployment", "api", "2", 3, 0, now.Add(-5*time.Minute))
+	noRevision := testReplicaSet("no-revision", "web", "", 3, 0, now.Add(-5*time.Minute))
+	standalone := testReplicaSet("standalone", "", "", 3, 3, now.Add(-time.Hour))
+
+	for desc, tc := range map[string]struct {
+		pod         *apiv1.Pod
+		replicaSets []*appsv1.ReplicaSet
+		noListers   bool
+		wantReason  drain.BlockingPodReason
+	}{
+		"pod of replica set being replaced": {
+			pod:         testPod("old"),
+			replicaSets: []*appsv1.ReplicaSet{old, rolling},
+			wantReason:  drain.OwnerRolloutInProgress,
+		},
+		"pod of replica set replaced": {
+			pod:         testPod("old"),
+			replicaSets: []*appsv1.ReplicaSet{old, rolledOut},
+		},
+		"pod of replica set replaced for longer than max delay": {
+			pod:         testPod("old"),
+			replicaSets: []*appsv1.ReplicaSet{old, stuck},
+		},
+		"pod of replica set replacing another": {
+			pod:         testPod("rolling"),
+			replicaSets: []*appsv1.ReplicaSet{old, rolling},
+		},
+		"newer replica set of another deployment": {
+			pod:         testPod("old"),
+			replicaSets: []*appsv1.ReplicaSet{old, otherDeployment},
+		},
+		"newer replica set without revision": {
+			pod:         testPod("old"),
+			replicaSets: []*appsv1.ReplicaSet{old, noRevision},
+		},
+		"pod of replica set without deployment": {
+			pod:         testPod("standalone"),
+			replicaSets: []*appsv1.ReplicaSet{standalone, rolling},
+		},
+		"pod of missing replica set": {
+			pod:         testPod("missing"),
+			replicaSets: []*appsv1.ReplicaSet{rolling},
+		},
+		"pod without controller": {
+			pod:         BuildScheduledTestPod("unowned", 100, 0, "n"),
+			replicaSets: []*appsv1.ReplicaSet{old, rolling},
+		},
+		"no listers": {
+			pod:         testPod("old"),
+			replicaSets: []*appsv1.ReplicaSet{old, rolling},
+			noListers:   true,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			drainCtx := &drainability.DrainContext{Timestamp: now}
+			if !tc.noListers {
+				rsLister, err := NewTestReplicaSetLister(tc.replicaSets)
+				assert.NoError(t, err)
+				drainCtx.Listers = &drainabilitytest.Listers{ReplicaSets: rsLister}
+			}
+			got := New(15*time.Minute).Drainable(drainCtx, tc.pod, nil)
+			if tc.wantReason == drain.NoReason {
+				assert.Equal(t, drainability.UndefinedOutcome, got.Outcome)
+				return
+			}
+			assert.Equal(t, drainability.BlockDrain, got.Outcome)
+			assert.Equal(t, tc.wantReason, got.BlockingReason)
+		})
+	}
+}
+
+func testReplicaSet(name, deployment, revision string, replicas, available int32, created time.Time) *appsv1.ReplicaSet {
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			UID:               types.UID(name),
+			CreationTimestamp: metav1.Time{Time: created},
+		},
+		Spec:   appsv1.ReplicaSetSpec{Replicas: &replicas},
+		Status: appsv1.ReplicaSetStatus{AvailableReplicas: available},
+	}
+	if deployment != "" {
+		rs.OwnerReferences = GenerateOwnerReferences(deployment, "Deployment", "apps/v1", types.UID(deployment))
+	}
+	if revision != "" {
+		rs.Annotations = map[string]string{RevisionAnnotationKey: revision}
+	}
+	return rs
+}
+
+func testPod(replicaSet string) *apiv1.Pod {
+	pod := BuildScheduledTestPod("pod", 100, 0, "n")
+	pod.OwnerReferences = GenerateOwnerReferences(replicaSet, "ReplicaSet", "apps/v1", types.UID(replicaSet))
+	return pod
+}
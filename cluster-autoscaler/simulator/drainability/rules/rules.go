@@ -17,10 +17,20 @@ limitations under the License.
 package rules
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
 	apiv1 "k8s.io/api/core/v1"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/daemonset"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/disruptionwindow"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/headroom"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/hostprocess"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/localstorage"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/longterminating"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/mirror"
@@ -31,46 +41,177 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/safetoevict"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/system"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules/terminal"
-	"k8s.io/autoscaler/cluster-autoscaler/simulator/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/trace"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 // Rule determines whether a given pod can be drained or not.
 type Rule interface {
 	// The name of the rule.
 	Name() string
-	// Drainable determines whether a given pod is drainable according to
-	// the specific Rule.
+	// Drainable determines whether a given pod on a given node is drainable
+	// according to the specific Rule.
 	//
-	// DrainContext cannot be nil.
-	Drainable(*drainability.DrainContext, *apiv1.Pod) drainability.Status
+	// DrainContext cannot be nil. NodeInfo is the node the pod is drained
+	// from, and can be nil if the caller doesn't know it.
+	Drainable(*drainability.DrainContext, *apiv1.Pod, *framework.NodeInfo) drainability.Status
+}
+
+// Priority determines the order in which Rules are evaluated. Rules with
+// higher Priority are evaluated first, so their outcomes (and Overrides) win
+// over outcomes of Rules with lower Priority. Rules with equal Priority are
+// evaluated in the order they were specified in.
+type Priority int
+
+const (
+	// BlockingPriority is the priority of Rules blocking drain of pods.
+	BlockingPriority Priority = 100
+	// NonBlockingPriority is the priority of Rules allowing drain of pods
+	// that would otherwise be blocked by a BlockingPriority Rule.
+	NonBlockingPriority Priority = 200
+	// BudgetPriority is the priority of Rules checking disruption budgets.
+	// They cannot be overridden by NonBlockingPriority Rules.
+	BudgetPriority Priority = 300
+	// InterruptingPriority is the priority of Rules deciding drainability of
+	// pods which are never subject to disruption budgets.
+	InterruptingPriority Priority = 400
+	// SkipPriority is the priority of Rules skipping pods which are not
+	// subject to any other checks.
+	SkipPriority Priority = 500
+	// DefaultPriority is the priority of Rules that don't specify one. Such
+	// Rules are evaluated before all the default Rules.
+	DefaultPriority Priority = 600
+)
+
+// PrioritizedRule is a Rule evaluated according to its Priority.
+type PrioritizedRule interface {
+	Rule
+	// Priority returns the priority of the rule.
+	Priority() Priority
+}
+
+// WithPriority returns a Rule evaluated with the given Priority.
+func WithPriority(rule Rule, priority Priority) PrioritizedRule {
+	return &prioritizedRule{Rule: rule, priority: priority}
+}
+
+type prioritizedRule struct {
+	Rule
+	priority Priority
+}
+
+// Priority returns the priority of the rule.
+func (r *prioritizedRule) Priority() Priority {
+	return r.priority
+}
+
+// PriorityOf returns the Priority of a given Rule, or DefaultPriority if the
+// Rule doesn't specify one.
+func PriorityOf(rule Rule) Priority {
+	if r, ok := rule.(PrioritizedRule); ok {
+		return r.Priority()
+	}
+	return DefaultPriority
+}
+
+// ShadowRule is a Rule evaluated in shadow mode: its outcomes are reported
+// by metrics, logs and traces whenever they would change the outcome of the
+// evaluation, but they are never enforced.
+type ShadowRule interface {
+	PrioritizedRule
+	// Shadowed returns the rule evaluated in shadow mode.
+	Shadowed() Rule
+}
+
+// Shadow returns a Rule evaluating the given Rule in shadow mode, with the
+// same name and Priority. Evaluated on its own, the returned Rule always
+// returns an undefined status.
+func Shadow(rule Rule) ShadowRule {
+	return &shadowRule{rule: rule}
+}
+
+type shadowRule struct {
+	rule Rule
+}
+
+// Name returns the name of the shadowed rule.
+func (r *shadowRule) Name() string {
+	return r.rule.Name()
+}
+
+// Drainable returns an undefined status, outcomes of shadowed rules are
+// never enforced.
+func (r *shadowRule) Drainable(*drainability.DrainContext, *apiv1.Pod, *framework.NodeInfo) drainability.Status {
+	return drainability.NewUndefinedStatus()
+}
+
+// Priority returns the priority of the shadowed rule.
+func (r *shadowRule) Priority() Priority {
+	return PriorityOf(r.rule)
+}
+
+// Shadowed returns the shadowed rule.
+func (r *shadowRule) Shadowed() Rule {
+	return r.rule
+}
+
+// Chain is a Rule standing for a list of Rules which may change over time,
+// e.g. when their configuration is reconciled from a custom resource. When
+// evaluated as part of Rules, the current Rules of the chain are evaluated in
+// its place, as if they were part of the list.
+type Chain interface {
+	Rule
+	// Rules returns the current Rules of the chain.
+	Rules() Rules
 }
 
 // Default returns the default list of Rules.
 func Default(deleteOptions options.NodeDeleteOptions) Rules {
+	return defaultRules(deleteOptions, replicated.New(deleteOptions.SkipNodesWithCustomControllerPods))
+}
+
+// DefaultWithScaleLookup returns the default list of Rules, except that pods
+// owned by custom controllers are treated as replicated if scaleLookup finds
+// that the controller implements the scale subresource and has more than one
+// replica.
+func DefaultWithScaleLookup(deleteOptions options.NodeDeleteOptions, scaleLookup replicated.ScaleLookup) Rules {
+	return defaultRules(deleteOptions, replicated.NewWithScaleLookup(deleteOptions.SkipNodesWithCustomControllerPods, scaleLookup))
+}
+
+func defaultRules(deleteOptions options.NodeDeleteOptions, replicatedRule Rule) Rules {
 	var rules Rules
 	for _, r := range []struct {
-		rule Rule
-		skip bool
+		rule     Rule
+		priority Priority
+		skip     bool
 	}{
-		{rule: mirror.New()},
-		{rule: longterminating.New()},
-		{rule: replicacount.New(deleteOptions.MinReplicaCount), skip: !deleteOptions.SkipNodesWithCustomControllerPods},
+		{rule: mirror.New(), priority: SkipPriority},
+		{rule: longterminating.New(deleteOptions.LongTerminatingPodThreshold), priority: SkipPriority},
+		{rule: replicacount.New(deleteOptions), priority: SkipPriority, skip: !deleteOptions.SkipNodesWithCustomControllerPods},
+		{rule: terminal.New(), priority: SkipPriority},
 
 		// Interrupting checks
-		{rule: daemonset.New()},
-		{rule: safetoevict.New()},
-		{rule: terminal.New()},
+		{rule: headroom.New(), priority: InterruptingPriority},
+		{rule: daemonset.New(), priority: InterruptingPriority},
+
+		// Budget checks
+		{rule: pdbrule.New(), priority: BudgetPriority},
+		{rule: disruptionwindow.New(), priority: BudgetPriority},
+
+		// Non-blocking checks
+		{rule: safetoevict.New(), priority: NonBlockingPriority},
 
 		// Blocking checks
-		{rule: replicated.New(deleteOptions.SkipNodesWithCustomControllerPods)},
-		{rule: system.New(), skip: !deleteOptions.SkipNodesWithSystemPods},
-		{rule: notsafetoevict.New()},
-		{rule: localstorage.New(), skip: !deleteOptions.SkipNodesWithLocalStorage},
-		{rule: pdbrule.New()},
+		{rule: replicatedRule, priority: BlockingPriority},
+		{rule: system.New(deleteOptions), priority: BlockingPriority},
+		{rule: notsafetoevict.New(), priority: BlockingPriority},
+		{rule: localstorage.New(deleteOptions), priority: BlockingPriority},
+		{rule: hostprocess.New(), priority: BlockingPriority},
 	} {
 		if !r.skip {
-			rules = append(rules, r.rule)
+			rules = append(rules, WithPriority(r.rule, r.priority))
 		}
 	}
 	return rules
@@ -79,9 +220,87 @@ func Default(deleteOptions options.NodeDeleteOptions) Rules {
 // Rules defines operations on a collections of rules.
 type Rules []Rule
 
+// Sorted returns the rules in the order of evaluation, i.e. by decreasing
+// Priority, with Chains replaced by their current Rules. The relative order of
+// rules with equal Priority is preserved.
+func (rs Rules) Sorted() Rules {
+	rs = rs.expanded()
+	byPriority := func(i, j int) bool {
+		return PriorityOf(rs[i]) > PriorityOf(rs[j])
+	}
+	if sort.SliceIsSorted(rs, byPriority) {
+		return rs
+	}
+	sorted := make(Rules, len(rs))
+	copy(sorted, rs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return PriorityOf(sorted[i]) > PriorityOf(sorted[j])
+	})
+	return sorted
+}
+
+// expanded returns the rules with Chains replaced by their current Rules.
+func (rs Rules) expanded() Rules {
+	hasChain := false
+	for _, r := range rs {
+		if _, ok := r.(Chain); ok {
+			hasChain = true
+			break
+		}
+	}
+	if !hasChain {
+		return rs
+	}
+	expanded := make(Rules, 0, len(rs))
+	for _, r := range rs {
+		if chain, ok := r.(Chain); ok {
+			expanded = append(expanded, chain.Rules().expanded()...)
+		} else {
+			expanded = append(expanded, r)
+		}
+	}
+	return expanded
+}
+
+// WithShadowRules returns the rules with the ones of the given names
+// evaluated in shadow mode. It returns an error if there are no rules with
+// some of the names.
+func (rs Rules) WithShadowRules(names []string) (Rules, error) {
+	shadowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		shadowed[name] = false
+	}
+	result := make(Rules, 0, len(rs))
+	for _, r := range rs {
+		if _, found := shadowed[r.Name()]; found {
+			if _, isShadow := r.(ShadowRule); !isShadow {
+				r = Shadow(r)
+			}
+			shadowed[r.Name()] = true
+		}
+		result = append(result, r)
+	}
+	var missing []string
+	for name, found := range shadowed {
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("no drainability rules to evaluate in shadow mode named: %s", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
+
 // Drainable determines whether a given pod is drainable according to the
-// specified set of rules.
-func (rs Rules) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
+// specified set of rules. Rules are evaluated by decreasing Priority and the
+// first non-undefined outcome is returned, unless it is overridden by a
+// Status of a previously evaluated rule. Outcomes of shadow rules are only
+// reported, if they would have changed the returned outcome. If tracing is
+// enabled, all evaluated rules and their statuses are recorded in the trace of
+// the current loop.
+func (rs Rules) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
 	if drainCtx == nil {
 		drainCtx = &drainability.DrainContext{}
 	}
@@ -89,30 +308,133 @@ func (rs Rules) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) d
 		drainCtx.RemainingPdbTracker = pdb.NewBasicRemainingPdbTracker()
 	}
 
-	var candidates []overrideCandidate
+	podTrace := trace.NewPodTrace(pod)
+	decidedBy, status := rs.evaluate(drainCtx, pod, nodeInfo, podTrace)
+	status = withDeletionCost(status, pod)
+	status = withBlockingRule(status, decidedBy)
+	podTrace.Finish(pod, nodeInfo, decidedBy, status)
+	return status
+}
 
-	for _, r := range rs {
-		status := r.Drainable(drainCtx, pod)
-		if len(status.Overrides) > 0 {
-			candidates = append(candidates, overrideCandidate{r.Name(), status})
-			continue
+// evaluate returns the status of the pod and the name of the rule that
+// decided it, or an empty name if no rule did.
+//
+// Statuses of all rules are fed to the enforced evaluation, and additionally
+// with statuses of shadow rules to the shadow one. The shadow evaluation
+// always decides no later than the enforced one, so no rules are evaluated
+// only because of shadow rules.
+func (rs Rules) evaluate(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo, podTrace *trace.PodTrace) (string, drainability.Status) {
+	var enforced, shadow evaluation
+	shadowRules := make(map[string]bool)
+
+	for _, r := range rs.Sorted() {
+		rule := r
+		shadowRule, isShadow := r.(ShadowRule)
+		if isShadow {
+			rule = shadowRule.Shadowed()
+			shadowRules[r.Name()] = true
 		}
-		for _, candidate := range candidates {
-			for _, override := range candidate.status.Overrides {
-				if status.Outcome == override {
-					klog.V(5).Info("Overriding pod %s/%s drainability rule %s with rule %s, outcome %v", pod.GetNamespace(), pod.GetName(), r.Name(), candidate.name, candidate.status.Outcome)
-					return candidate.status
-				}
-			}
+		start := time.Now()
+		status := rule.Drainable(drainCtx, pod, nodeInfo)
+		metrics.ObserveRuleEvaluationDuration(r.Name(), time.Since(start))
+		if isShadow {
+			podTrace.AddShadowRule(r.Name(), status)
+		} else {
+			podTrace.AddRule(r.Name(), status)
 		}
-		if status.Outcome != drainability.UndefinedOutcome {
-			return status
+		shadow.add(r.Name(), status, pod)
+		if !isShadow && enforced.add(r.Name(), status, pod) {
+			break
 		}
 	}
-	return drainability.NewUndefinedStatus()
+	if shadow.status.Outcome != enforced.status.Outcome {
+		// The outcome is changed either by a shadow rule deciding it, or by a
+		// shadow rule whose status was overridden by another rule.
+		rule := shadow.decidedBy
+		if !shadowRules[rule] {
+			rule = shadow.overridden
+		}
+		klog.V(4).Infof("Drainability rule %s in shadow mode would change outcome of pod %s/%s from %v to %v", rule, pod.GetNamespace(), pod.GetName(), enforced.status.Outcome, shadow.status.Outcome)
+		metrics.RegisterShadowRuleVerdict(rule, shadow.status.Outcome.String())
+		podTrace.SetShadowOutcome(rule, shadow.status)
+	}
+	recordOutcome(enforced.decidedBy, enforced.status)
+	return enforced.decidedBy, enforced.status
+}
+
+// evaluation is the state of an evaluation of rules: statuses of rules are
+// added in the order of evaluation until one of them decides the outcome.
+type evaluation struct {
+	candidates []overrideCandidate
+	decided    bool
+	decidedBy  string
+	// overridden is the rule whose status was overridden by the deciding
+	// rule, if any.
+	overridden string
+	status     drainability.Status
+}
+
+// add adds the status of a rule to the evaluation, and tells if the outcome
+// is decided.
+func (e *evaluation) add(rule string, status drainability.Status, pod *apiv1.Pod) bool {
+	if e.decided {
+		return true
+	}
+	if len(status.Overrides) > 0 {
+		e.candidates = append(e.candidates, overrideCandidate{rule, status})
+		return false
+	}
+	for _, candidate := range e.candidates {
+		for _, override := range candidate.status.Overrides {
+			if status.Outcome == override {
+				klog.V(5).Info("Overriding pod %s/%s drainability rule %s with rule %s, outcome %v", pod.GetNamespace(), pod.GetName(), rule, candidate.name, candidate.status.Outcome)
+				e.decide(candidate.name, candidate.status)
+				e.overridden = rule
+				return true
+			}
+		}
+	}
+	if status.Outcome != drainability.UndefinedOutcome {
+		e.decide(rule, status)
+		return true
+	}
+	return false
+}
+
+func (e *evaluation) decide(rule string, status drainability.Status) {
+	e.decided = true
+	e.decidedBy = rule
+	e.status = status
+}
+
+// withDeletionCost sets DeletionCost of the status if the pod can be drained.
+func withDeletionCost(status drainability.Status, pod *apiv1.Pod) drainability.Status {
+	if status.Outcome == drainability.DrainOk || status.Outcome == drainability.UndefinedOutcome {
+		status.DeletionCost = pod_util.DeletionCost(pod)
+	}
+	return status
+}
+
+// withBlockingRule sets the rule which blocked the pod, and the default
+// remediation of the blocking reason if the rule didn't provide one.
+func withBlockingRule(status drainability.Status, rule string) drainability.Status {
+	if status.Outcome != drainability.BlockDrain {
+		return status
+	}
+	status.Rule = rule
+	if status.Remediation == "" {
+		status.Remediation = status.BlockingReason.Remediation()
+	}
+	return status
 }
 
 type overrideCandidate struct {
 	name   string
 	status drainability.Status
 }
+
+func recordOutcome(rule string, status drainability.Status) {
+	if status.Outcome == drainability.BlockDrain {
+		metrics.RegisterRuleBlockedPod(rule)
+	}
+}
@@ -17,12 +17,22 @@ limitations under the License.
 package rules
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/trace"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 func TestDrainable(t *testing.T) {
@@ -62,7 +72,7 @@ func TestDrainable(t *testing.T) {
 				}},
 				fakeRule{drainability.NewBlockedStatus(drain.NotEnoughPdb, nil)},
 			},
-			want: drainability.NewBlockedStatus(drain.NotEnoughPdb, nil),
+			want: blockedBy("FakeRule", drainability.NewBlockedStatus(drain.NotEnoughPdb, nil)),
 		},
 		"override unreachable": {
 			rules: Rules{
@@ -109,9 +119,39 @@ func TestDrainable(t *testing.T) {
 				Overrides: []drainability.OutcomeType{drainability.BlockDrain},
 			},
 		},
+		"higher priority evaluated first": {
+			rules: Rules{
+				WithPriority(fakeRule{drainability.NewBlockedStatus(drain.NotReplicated, nil)}, BlockingPriority),
+				WithPriority(fakeRule{drainability.NewBlockedStatus(drain.NotEnoughPdb, nil)}, BudgetPriority),
+			},
+			want: blockedBy("FakeRule", drainability.NewBlockedStatus(drain.NotEnoughPdb, nil)),
+		},
+		"rules without priority evaluated first": {
+			rules: Rules{
+				WithPriority(fakeRule{drainability.NewBlockedStatus(drain.NotEnoughPdb, nil)}, SkipPriority),
+				fakeRule{drainability.NewDrainableStatus()},
+			},
+			want: drainability.NewDrainableStatus(),
+		},
+		"remediation provided by the rule": {
+			rules: Rules{
+				fakeRule{drainability.Status{Outcome: drainability.BlockDrain, BlockingReason: drain.NotReplicated, Remediation: "example.com/Replicate"}},
+			},
+			want: drainability.Status{Outcome: drainability.BlockDrain, BlockingReason: drain.NotReplicated, Rule: "FakeRule", Remediation: "example.com/Replicate"},
+		},
+		"override limited to lower priority": {
+			rules: Rules{
+				WithPriority(fakeRule{drainability.Status{
+					Outcome:   drainability.DrainOk,
+					Overrides: []drainability.OutcomeType{drainability.BlockDrain},
+				}}, NonBlockingPriority),
+				WithPriority(fakeRule{drainability.NewBlockedStatus(drain.NotEnoughPdb, nil)}, BudgetPriority),
+			},
+			want: blockedBy("FakeRule", drainability.NewBlockedStatus(drain.NotEnoughPdb, nil)),
+		},
 	} {
 		t.Run(desc, func(t *testing.T) {
-			got := tc.rules.Drainable(nil, &apiv1.Pod{})
+			got := tc.rules.Drainable(nil, &apiv1.Pod{}, nil)
 			if diff := cmp.Diff(tc.want, got); diff != "" {
 				t.Errorf("Drainable(): got status diff (-want +got):\n%s", diff)
 			}
@@ -119,6 +159,232 @@ func TestDrainable(t *testing.T) {
 	}
 }
 
+func TestDefaultSafeToEvict(t *testing.T) {
+	zero := intstr.FromInt(0)
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: "ns",
+			Labels: map[string]string{
+				"app": "test",
+			},
+			Annotations: map[string]string{
+				drain.PodSafeToEvictKey: "true",
+			},
+		},
+		Spec: apiv1.PodSpec{
+			Volumes: []apiv1.Volume{
+				{
+					Name:         "scratch",
+					VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}},
+				},
+			},
+		},
+	}
+	budget := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pdb",
+			Namespace: "ns",
+		},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &zero,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app": "test",
+				},
+			},
+		},
+	}
+	rules := Default(options.NodeDeleteOptions{SkipNodesWithLocalStorage: true})
+
+	got := rules.Drainable(&drainability.DrainContext{DeleteOptions: options.NodeDeleteOptions{SkipNodesWithLocalStorage: true}}, pod, nil)
+	if got.Outcome != drainability.DrainOk {
+		t.Errorf("Drainable(): got outcome %v, want %v: safe to evict pod should override local storage", got.Outcome, drainability.DrainOk)
+	}
+
+	tracker := pdb.NewBasicRemainingPdbTracker()
+	if err := tracker.SetPdbs([]*policyv1.PodDisruptionBudget{budget}); err != nil {
+		t.Fatalf("SetPdbs(): unexpected error: %v", err)
+	}
+	got = rules.Drainable(&drainability.DrainContext{RemainingPdbTracker: tracker, DeleteOptions: options.NodeDeleteOptions{SkipNodesWithLocalStorage: true}}, pod, nil)
+	if got.Outcome != drainability.BlockDrain || got.BlockingReason != drain.NotEnoughPdb {
+		t.Errorf("Drainable(): got outcome %v (%v), want %v (%v): safe to evict pod shouldn't override PDB", got.Outcome, got.BlockingReason, drainability.BlockDrain, drain.NotEnoughPdb)
+	}
+}
+
+func TestDefaultWithoutDrainContext(t *testing.T) {
+	isController := true
+	ownerRefs := []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "rs", Controller: &isController}}
+	systemPod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "system",
+			Namespace:       "kube-system",
+			OwnerReferences: ownerRefs,
+		},
+	}
+	localStoragePod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "local-storage",
+			Namespace:       "ns",
+			OwnerReferences: ownerRefs,
+		},
+		Spec: apiv1.PodSpec{
+			Volumes: []apiv1.Volume{
+				{
+					Name:         "scratch",
+					VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{Medium: ""}},
+				},
+			},
+		},
+	}
+	rules := Default(options.NodeDeleteOptions{SkipNodesWithSystemPods: true, SkipNodesWithLocalStorage: true})
+
+	for _, tc := range []struct {
+		pod        *apiv1.Pod
+		wantReason drain.BlockingPodReason
+	}{
+		{pod: systemPod, wantReason: drain.UnmovableKubeSystemPod},
+		{pod: localStoragePod, wantReason: drain.LocalStorageRequested},
+	} {
+		for _, drainCtx := range []*drainability.DrainContext{nil, {}} {
+			got := rules.Drainable(drainCtx, tc.pod, nil)
+			if got.Outcome != drainability.BlockDrain || got.BlockingReason != tc.wantReason {
+				t.Errorf("Drainable(%v, %s): got outcome %v (%v), want %v (%v): rules should keep their delete options", drainCtx, tc.pod.Name, got.Outcome, got.BlockingReason, drainability.BlockDrain, tc.wantReason)
+			}
+		}
+	}
+}
+
+func TestDefaultWithCustomRule(t *testing.T) {
+	safeToEvict := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "safe-to-evict",
+			Namespace:   "ns",
+			Annotations: map[string]string{drain.PodSafeToEvictKey: "true"},
+		},
+	}
+	mirror := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "mirror",
+			Namespace:   "ns",
+			Annotations: map[string]string{"kubernetes.io/config.mirror": "mirror"},
+		},
+	}
+	custom := fakeRule{drainability.NewCustomBlockedStatus("Custom", nil, nil)}
+	blocked := drainability.NewCustomBlockedStatus("Custom", nil, nil)
+	tracker := test.NewFakeRemainingPdbTracker().BlockPods(drain.NotEnoughPdb, safeToEvict)
+	for priority, cases := range map[Priority][]test.TestCase{
+		BudgetPriority: {
+			{Name: "custom rule blocks safe to evict pod", Pod: safeToEvict, Want: blockedBy("FakeRule", blocked)},
+			{Name: "mirror pods are skipped first", Pod: mirror, Want: drainability.NewSkipStatus()},
+			{
+				Name:    "PDB wins over custom rule",
+				Pod:     safeToEvict,
+				Options: []test.DrainContextOption{test.WithRemainingPdbTracker(tracker)},
+				Want:    blockedBy("PDB", drainability.NewBlockedStatus(drain.NotEnoughPdb, nil)),
+			},
+		},
+		BlockingPriority: {
+			{Name: "safe to evict wins over custom rule", Pod: safeToEvict, Want: drainability.NewDrainableStatus()},
+		},
+	} {
+		t.Run(fmt.Sprintf("priority %d", priority), func(t *testing.T) {
+			rules := append(Default(options.NodeDeleteOptions{}), WithPriority(custom, priority))
+			test.RunDrainableTests(t, rules, cases)
+		})
+	}
+}
+
+func TestDrainableDeletionCost(t *testing.T) {
+	pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{apiv1.PodDeletionCost: "7"}}}
+	for desc, tc := range map[string]struct {
+		rules Rules
+		want  int32
+	}{
+		"undefined": {
+			want: 7,
+		},
+		"drainable": {
+			rules: Rules{fakeRule{drainability.NewDrainableStatus()}},
+			want:  7,
+		},
+		"blocked": {
+			rules: Rules{fakeRule{drainability.NewBlockedStatus(drain.NotEnoughPdb, nil)}},
+			want:  0,
+		},
+		"skipped": {
+			rules: Rules{fakeRule{drainability.NewSkipStatus()}},
+			want:  0,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			got := tc.rules.Drainable(nil, pod, nil)
+			if got.DeletionCost != tc.want {
+				t.Errorf("Drainable(): got deletion cost %v, want %v", got.DeletionCost, tc.want)
+			}
+		})
+	}
+}
+
+func TestDrainableTrace(t *testing.T) {
+	trace.SetEnabled(true)
+	defer trace.SetEnabled(false)
+	defer trace.Collect()
+
+	pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}, Spec: apiv1.PodSpec{NodeName: "node"}}
+	rules := Rules{
+		namedRule{"Undefined", drainability.NewUndefinedStatus()},
+		namedRule{"Override", drainability.Status{
+			Outcome:   drainability.DrainOk,
+			Overrides: []drainability.OutcomeType{drainability.BlockDrain},
+		}},
+		namedRule{"Blocking", drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("not replicated"))},
+		namedRule{"NotEvaluated", drainability.NewSkipStatus()},
+	}
+	rules.Drainable(nil, pod, nil)
+	Rules{}.Drainable(nil, pod, nil)
+
+	want := trace.Trace{Nodes: []trace.NodeTrace{{
+		Node: "node",
+		Pods: []trace.PodTrace{
+			{
+				Pod: "ns/pod",
+				Rules: []trace.RuleEvaluation{
+					{Rule: "Undefined", Outcome: "Undefined"},
+					{Rule: "Override", Outcome: "DrainOk", Overrides: []string{"BlockDrain"}},
+					{Rule: "Blocking", Outcome: "BlockDrain", BlockingReason: drain.NotReplicated.String(), Error: "not replicated"},
+				},
+				Outcome:   "DrainOk",
+				DecidedBy: "Override",
+			},
+			{
+				Pod:     "ns/pod",
+				Outcome: "Undefined",
+			},
+		},
+	}}}
+	if diff := cmp.Diff(want, trace.Collect()); diff != "" {
+		t.Errorf("trace.Collect(): got diff (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(trace.Trace{}, trace.Collect()); diff != "" {
+		t.Errorf("trace.Collect() after collecting: got diff (-want +got):\n%s", diff)
+	}
+
+	trace.SetEnabled(false)
+	rules.Drainable(nil, pod, nil)
+	if diff := cmp.Diff(trace.Trace{}, trace.Collect()); diff != "" {
+		t.Errorf("trace.Collect() with tracing disabled: got diff (-want +got):\n%s", diff)
+	}
+}
+
+// blockedBy returns the blocked status as returned by Rules.Drainable when
+// decided by the rule.
+func blockedBy(rule string, status drainability.Status) drainability.Status {
+	status.Rule = rule
+	status.Remediation = status.BlockingReason.Remediation()
+	return status
+}
+
 type fakeRule struct {
 	status drainability.Status
 }
@@ -127,6 +393,144 @@ func (r fakeRule) Name() string {
 	return "FakeRule"
 }
 
-func (r fakeRule) Drainable(*drainability.DrainContext, *apiv1.Pod) drainability.Status {
+func (r fakeRule) Drainable(*drainability.DrainContext, *apiv1.Pod, *framework.NodeInfo) drainability.Status {
 	return r.status
 }
+
+func TestDrainableShadow(t *testing.T) {
+	blocked := drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("not replicated"))
+	overridingOk := drainability.Status{Outcome: drainability.DrainOk, Overrides: []drainability.OutcomeType{drainability.BlockDrain}}
+	for desc, tc := range map[string]struct {
+		rules         Rules
+		want          drainability.Status
+		wantShadow    string
+		wantShadowOut string
+	}{
+		"shadow rule isn't enforced": {
+			rules:         Rules{Shadow(namedRule{"Shadow", blocked}), namedRule{"Ok", drainability.NewDrainableStatus()}},
+			want:          drainability.NewDrainableStatus(),
+			wantShadow:    "Shadow",
+			wantShadowOut: "BlockDrain",
+		},
+		"shadow rule with the same outcome isn't reported": {
+			rules: Rules{Shadow(namedRule{"Shadow", blocked}), namedRule{"Blocking", blocked}},
+			want:  blockedBy("Blocking", blocked),
+		},
+		"shadow rule evaluated after the deciding rule isn't reported": {
+			rules: Rules{namedRule{"Ok", drainability.NewDrainableStatus()}, Shadow(namedRule{"Shadow", blocked})},
+			want:  drainability.NewDrainableStatus(),
+		},
+		"shadow override isn't enforced": {
+			rules:         Rules{Shadow(namedRule{"Shadow", overridingOk}), namedRule{"Blocking", blocked}},
+			want:          blockedBy("Blocking", blocked),
+			wantShadow:    "Shadow",
+			wantShadowOut: "DrainOk",
+		},
+		"overridden shadow rule": {
+			rules:         Rules{namedRule{"Override", overridingOk}, Shadow(namedRule{"Shadow", blocked}), namedRule{"Undefined", drainability.NewUndefinedStatus()}},
+			want:          drainability.NewUndefinedStatus(),
+			wantShadow:    "Shadow",
+			wantShadowOut: "DrainOk",
+		},
+		"shadow rule with priority": {
+			rules:         Rules{WithPriority(namedRule{"Ok", drainability.NewDrainableStatus()}, BlockingPriority), Shadow(WithPriority(namedRule{"Shadow", blocked}, SkipPriority))},
+			want:          drainability.NewDrainableStatus(),
+			wantShadow:    "Shadow",
+			wantShadowOut: "BlockDrain",
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			trace.SetEnabled(true)
+			defer trace.SetEnabled(false)
+			defer trace.Collect()
+
+			pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}, Spec: apiv1.PodSpec{NodeName: "node"}}
+			got := tc.rules.Drainable(nil, pod, nil)
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("Drainable(): got status diff (-want +got):\n%s", diff)
+			}
+			podTrace := trace.Collect().Nodes[0].Pods[0]
+			if podTrace.ShadowDecidedBy != tc.wantShadow || podTrace.ShadowOutcome != tc.wantShadowOut {
+				t.Errorf("Drainable(): got shadow outcome %q decided by %q, want %q decided by %q", podTrace.ShadowOutcome, podTrace.ShadowDecidedBy, tc.wantShadowOut, tc.wantShadow)
+			}
+		})
+	}
+}
+
+func TestWithShadowRules(t *testing.T) {
+	rules := Rules{namedRule{"A", drainability.NewUndefinedStatus()}, WithPriority(namedRule{"B", drainability.NewUndefinedStatus()}, SkipPriority)}
+
+	shadowed, err := rules.WithShadowRules([]string{"B"})
+	if err != nil {
+		t.Fatalf("WithShadowRules(): unexpected error: %v", err)
+	}
+	if _, isShadow := shadowed[0].(ShadowRule); isShadow {
+		t.Errorf("WithShadowRules(): rule %s unexpectedly in shadow mode", shadowed[0].Name())
+	}
+	shadowRule, isShadow := shadowed[1].(ShadowRule)
+	if !isShadow {
+		t.Fatalf("WithShadowRules(): rule %s not in shadow mode", shadowed[1].Name())
+	}
+	if got := shadowRule.Name(); got != "B" {
+		t.Errorf("Name(): got %q, want %q", got, "B")
+	}
+	if got := PriorityOf(shadowRule); got != SkipPriority {
+		t.Errorf("PriorityOf(): got %v, want %v", got, SkipPriority)
+	}
+	if got := shadowRule.Drainable(nil, nil, nil); got.Outcome != drainability.UndefinedOutcome {
+		t.Errorf("Drainable(): got outcome %v, want %v", got.Outcome, drainability.UndefinedOutcome)
+	}
+
+	wantErr := "no drainability rules to evaluate in shadow mode named: C, D"
+	if _, err := rules.WithShadowRules([]string{"A", "C", "D"}); err == nil || err.Error() != wantErr {
+		t.Errorf("WithShadowRules(): got error %v, want %q", err, wantErr)
+	}
+}
+
+type namedRule struct {
+	name   string
+	status drainability.Status
+}
+
+func (r namedRule) Name() string {
+	return r.name
+}
+
+func (r namedRule) Drainable(*drainability.DrainContext, *apiv1.Pod, *framework.NodeInfo) drainability.Status {
+	return r.status
+}
+
+func TestDrainableChain(t *testing.T) {
+	blocked := drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("blocked"))
+	chain := &testChain{rules: Rules{namedRule{"A", drainability.NewUndefinedStatus()}, WithPriority(namedRule{"B", blocked}, BlockingPriority)}}
+	rules := Rules{WithPriority(namedRule{"C", drainability.NewDrainableStatus()}, NonBlockingPriority), chain}
+
+	if got := rules.Sorted(); len(got) != 3 || got[0].Name() != "A" || got[1].Name() != "C" || got[2].Name() != "B" {
+		t.Errorf("Sorted(): got %v, want rules A, C, B", got)
+	}
+	if got := rules.Drainable(&drainability.DrainContext{}, &apiv1.Pod{}, nil); got.Outcome != drainability.DrainOk {
+		t.Errorf("Drainable(): got outcome %v, want %v", got.Outcome, drainability.DrainOk)
+	}
+
+	// Changes of the chain take effect on the next evaluation.
+	chain.rules = Rules{WithPriority(namedRule{"B", blocked}, DefaultPriority)}
+	if got := rules.Drainable(&drainability.DrainContext{}, &apiv1.Pod{}, nil); got.Outcome != drainability.BlockDrain {
+		t.Errorf("Drainable(): got outcome %v, want %v", got.Outcome, drainability.BlockDrain)
+	}
+}
+
+type testChain struct {
+	rules Rules
+}
+
+func (c *testChain) Name() string {
+	return "Chain"
+}
+
+func (c *testChain) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	return c.rules.Drainable(drainCtx, pod, nodeInfo)
+}
+
+func (c *testChain) Rules() Rules {
+	return c.rules
+}
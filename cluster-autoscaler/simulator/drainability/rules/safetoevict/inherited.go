@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package safetoevict
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+)
+
+// Annotation returns the value of the safe-to-evict annotation in effect for
+// the pod and the object it was set on. The annotation on the pod takes
+// precedence over the one on its owning Deployment or StatefulSet, which takes
+// precedence over the one on its namespace. Owners and namespaces are resolved
+// with the listers of the DrainContext, and are skipped if a lister isn't
+// available.
+func Annotation(drainCtx *drainability.DrainContext, pod *apiv1.Pod) (value string, source string, found bool) {
+	if value, found := pod.GetAnnotations()[drain.PodSafeToEvictKey]; found {
+		return value, fmt.Sprintf("pod %s/%s", pod.Namespace, pod.Name), true
+	}
+	if drainCtx == nil || drainCtx.Listers == nil {
+		return "", "", false
+	}
+	if value, source, found := workloadAnnotation(drainCtx, pod); found {
+		return value, source, true
+	}
+	if lister := drainCtx.Listers.NamespaceLister(); lister != nil {
+		if namespace, err := lister.Get(pod.Namespace); err == nil {
+			if value, found := namespace.Annotations[drain.PodSafeToEvictKey]; found {
+				return value, fmt.Sprintf("namespace %s", namespace.Name), true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// workloadAnnotation returns the safe-to-evict annotation of the Deployment
+// owning the pod's ReplicaSet, or of the StatefulSet owning the pod.
+func workloadAnnotation(drainCtx *drainability.DrainContext, pod *apiv1.Pod) (value string, source string, found bool) {
+	controllerRef := drain.ControllerRef(pod)
+	if controllerRef == nil {
+		return "", "", false
+	}
+	var owner metav1.Object
+	var kind string
+	switch controllerRef.Kind {
+	case "ReplicaSet":
+		rsLister, deploymentLister := drainCtx.Listers.ReplicaSetLister(), drainCtx.Listers.DeploymentLister()
+		if rsLister == nil || deploymentLister == nil {
+			return "", "", false
+		}
+		rs, err := rsLister.ReplicaSets(pod.Namespace).Get(controllerRef.Name)
+		if err != nil {
+			return "", "", false
+		}
+		rsControllerRef := metav1.GetControllerOf(rs)
+		if rsControllerRef == nil || rsControllerRef.Kind != "Deployment" {
+			return "", "", false
+		}
+		deployment, err := deploymentLister.Deployments(pod.Namespace).Get(rsControllerRef.Name)
+		if err != nil {
+			return "", "", false
+		}
+		owner, kind = deployment, "Deployment"
+	case "StatefulSet":
+		ssLister := drainCtx.Listers.StatefulSetLister()
+		if ssLister == nil {
+			return "", "", false
+		}
+		ss, err := ssLister.StatefulSets(pod.Namespace).Get(controllerRef.Name)
+		if err != nil {
+			return "", "", false
+		}
+		owner, kind = ss, "StatefulSet"
+	default:
+		return "", "", false
+	}
+	value, found = owner.GetAnnotations()[drain.PodSafeToEvictKey]
+	if !found {
+		return "", "", false
+	}
+	return value, fmt.Sprintf("%s %s/%s", kind, owner.GetNamespace(), owner.GetName()), true
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package safetoevict
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	drainabilitytest "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotation(t *testing.T) {
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Annotations: safeToEvict("true")}}
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "deployment", Namespace: "ns", Annotations: safeToEvict("false")}}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "ns", OwnerReferences: test.GenerateOwnerReferences("deployment", "Deployment", "apps/v1", "")}}
+	orphanRs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "orphan-rs", Namespace: "ns"}}
+	ss := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "ss", Namespace: "ns", Annotations: safeToEvict("false")}}
+	unannotatedSs := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "unannotated-ss", Namespace: "ns"}}
+	owners := []runtime.Object{namespace, deployment, rs, orphanRs, ss, unannotatedSs}
+
+	for desc, tc := range map[string]struct {
+		pod        *apiv1.Pod
+		owners     []runtime.Object
+		nilContext bool
+		wantValue  string
+		wantSource string
+		wantFound  bool
+	}{
+		"pod annotation takes precedence": {
+			pod:        testPod(safeToEvict("true"), "ss", "StatefulSet"),
+			owners:     owners,
+			wantValue:  "true",
+			wantSource: "pod ns/pod",
+			wantFound:  true,
+		},
+		"inherited from deployment": {
+			pod:        testPod(nil, "rs", "ReplicaSet"),
+			owners:     owners,
+			wantValue:  "false",
+			wantSource: "Deployment ns/deployment",
+			wantFound:  true,
+		},
+		"inherited from statefulset": {
+			pod:        testPod(nil, "ss", "StatefulSet"),
+			owners:     owners,
+			wantValue:  "false",
+			wantSource: "StatefulSet ns/ss",
+			wantFound:  true,
+		},
+		"inherited from namespace without owner annotation": {
+			pod:        testPod(nil, "unannotated-ss", "StatefulSet"),
+			owners:     owners,
+			wantValue:  "true",
+			wantSource: "namespace ns",
+			wantFound:  true,
+		},
+		"inherited from namespace with replicaset not owned by deployment": {
+			pod:        testPod(nil, "orphan-rs", "ReplicaSet"),
+			owners:     owners,
+			wantValue:  "true",
+			wantSource: "namespace ns",
+			wantFound:  true,
+		},
+		"inherited from namespace with missing owner": {
+			pod:        testPod(nil, "missing", "ReplicaSet"),
+			owners:     owners,
+			wantValue:  "true",
+			wantSource: "namespace ns",
+			wantFound:  true,
+		},
+		"not annotated anywhere": {
+			pod:    testPod(nil, "unannotated-ss", "StatefulSet"),
+			owners: []runtime.Object{unannotatedSs},
+		},
+		"no listers": {
+			pod: testPod(nil, "ss", "StatefulSet"),
+		},
+		"no drain context": {
+			pod:        testPod(nil, "ss", "StatefulSet"),
+			nilContext: true,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			var drainCtx *drainability.DrainContext
+			if !tc.nilContext {
+				drainCtx = drainabilitytest.NewDrainContext(t, drainabilitytest.WithOwners(tc.owners...))
+			}
+			value, source, found := Annotation(drainCtx, tc.pod)
+			assert.Equal(t, tc.wantValue, value)
+			assert.Equal(t, tc.wantSource, source)
+			assert.Equal(t, tc.wantFound, found)
+		})
+	}
+}
+
+func TestDrainableInherited(t *testing.T) {
+	namespace := &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Annotations: safeToEvict("true")}}
+	drainabilitytest.RunDrainableTests(t, New(), []drainabilitytest.TestCase{
+		{
+			Name:    "inherited from namespace",
+			Pod:     testPod(nil, "", ""),
+			Options: []drainabilitytest.DrainContextOption{drainabilitytest.WithOwners(namespace)},
+			Want:    drainability.NewDrainableStatus(),
+		},
+		{
+			Name:    "pod annotation overrides namespace",
+			Pod:     testPod(safeToEvict("false"), "", ""),
+			Options: []drainabilitytest.DrainContextOption{drainabilitytest.WithOwners(namespace)},
+			Want:    drainability.NewUndefinedStatus(),
+		},
+	})
+}
+
+func testPod(annotations map[string]string, ownerName, ownerKind string) *apiv1.Pod {
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   "ns",
+			Annotations: annotations,
+		},
+	}
+	if ownerName != "" {
+		pod.OwnerReferences = test.GenerateOwnerReferences(ownerName, ownerKind, "apps/v1", "")
+	}
+	return pod
+}
+
+func safeToEvict(value string) map[string]string {
+	return map[string]string{drain.PodSafeToEvictKey: value}
+}
@@ -19,10 +19,12 @@ package safetoevict
 import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
-// Rule is a drainability rule on how to handle safe to evict pods.
+// Rule is a drainability rule on how to handle safe to evict pods. The
+// annotation can be set on the pod, or inherited from its owning workload or
+// namespace.
 type Rule struct{}
 
 // New creates a new Rule.
@@ -36,8 +38,8 @@ func (r *Rule) Name() string {
 }
 
 // Drainable decides what to do with safe to evict pods on node drain.
-func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
-	if drain.HasSafeToEvictAnnotation(pod) {
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if value, _, _ := Annotation(drainCtx, pod); value == "true" {
 		return drainability.NewDrainableStatus()
 	}
 	return drainability.NewUndefinedStatus()
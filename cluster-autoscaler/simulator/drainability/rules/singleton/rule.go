@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package singleton
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	podv1 "k8s.io/kubernetes/pkg/api/v1/pod"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Rule is a drainability rule on how to handle designated singleton
+// infrastructure pods, e.g. CSI controllers or admission webhook servers, which
+// the cluster can't do without even for the time it takes to reschedule them.
+// Pods matching one of the selectors block drain of their node unless another
+// pod matching the same selector, in the same namespace, is already running
+// and ready on a node which isn't being removed.
+type Rule struct {
+	selectors []labels.Selector
+}
+
+// New creates a new Rule.
+func New(selectors []labels.Selector) *Rule {
+	return &Rule{
+		selectors: selectors,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "SingletonInfraPod"
+}
+
+// Drainable decides what to do with singleton infrastructure pods on node drain.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	selector := r.selectorOf(pod)
+	if selector == nil || drainCtx.Listers == nil {
+		return drainability.NewUndefinedStatus()
+	}
+	found, err := hasReplacement(drainCtx, pod, selector)
+	if err != nil {
+		return drainability.NewBlockedStatus(drain.SingletonInfraPod, fmt.Errorf("can't check if singleton infrastructure pod %s/%s has a replacement: %v", pod.Namespace, pod.Name, err))
+	}
+	if !found {
+		return drainability.NewBlockedStatus(drain.SingletonInfraPod, fmt.Errorf("singleton infrastructure pod %s/%s matching %q has no replacement running on another node", pod.Namespace, pod.Name, selector.String()))
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+func (r *Rule) selectorOf(pod *apiv1.Pod) labels.Selector {
+	for _, selector := range r.selectors {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return selector
+		}
+	}
+	return nil
+}
+
+// hasReplacement tells if another pod matching the selector, in the pod's
+// namespace, is running and ready on a node which isn't cordoned or being
+// deleted.
+func hasReplacement(drainCtx *drainability.DrainContext, pod *apiv1.Pod, selector labels.Selector) (bool, error) {
+	pods, err := drainCtx.Listers.AllPodLister().List()
+	if err != nil {
+		return false, err
+	}
+	for _, other := range pods {
+		if other.UID == pod.UID || other.Namespace != pod.Namespace || other.Spec.NodeName == "" || other.Spec.NodeName == pod.Spec.NodeName {
+			continue
+		}
+		if other.DeletionTimestamp != nil || other.Status.Phase != apiv1.PodRunning || !podv1.IsPodReady(other) {
+			continue
+		}
+		if !selector.Matches(labels.Set(other.Labels)) {
+			continue
+		}
+		node, err := drainCtx.Listers.AllNodeLister().Get(other.Spec.NodeName)
+		if err != nil || node.Spec.Unschedulable || hasToBeDeletedTaint(node) {
+			continue
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func hasToBeDeletedTaint(node *apiv1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == drain.ToBeDeletedTaint {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package singleton
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	drainabilitytest "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	. "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainable(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	cordoned := BuildTestNode("cordoned", 1000, 1000)
+	cordoned.Spec.Unschedulable = true
+	deleted := BuildTestNode("deleted", 1000, 1000)
+	deleted.Spec.Taints = []apiv1.Taint{{Key: drain.ToBeDeletedTaint, Effect: apiv1.TaintEffectNoSchedule}}
+	nodeLister := NewTestNodeLister([]*apiv1.Node{n1, n2, cordoned, deleted})
+	selectors := []labels.Selector{labels.SelectorFromSet(labels.Set{"app": "csi-controller"})}
+
+	controller := testPod("controller", "n1", "csi-controller", true)
+	other := testPod("other", "n1", "web", true)
+	replacement := testPod("replacement", "n2", "csi-controller", true)
+	notReady := testPod("not-ready", "n2", "csi-controller", false)
+	terminating := replacement.DeepCopy()
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	sameNode := testPod("same-node", "n1", "csi-controller", true)
+	otherNamespace := replacement.DeepCopy()
+	otherNamespace.Namespace = "other"
+	onCordonedNode := testPod("on-cordoned-node", "cordoned", "csi-controller", true)
+	onDeletedNode := testPod("on-deleted-node", "deleted", "csi-controller", true)
+
+	for desc, tc := range map[string]struct {
+		pod         *apiv1.Pod
+		pods        []*apiv1.Pod
+		noListers   bool
+		wantOutcome drainability.OutcomeType
+	}{
+		"pod not matching selectors": {
+			pod:         other,
+			pods:        []*apiv1.Pod{other},
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"no replacement": {
+			pod:         controller,
+			pods:        []*apiv1.Pod{controller},
+			wantOutcome: drainability.BlockDrain,
+		},
+		"replacement running elsewhere": {
+			pod:         controller,
+			pods:        []*apiv1.Pod{controller, replacement},
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"replacement not ready": {
+			pod:         controller,
+			pods:        []*apiv1.Pod{controller, notReady},
+			wantOutcome: drainability.BlockDrain,
+		},
+		"replacement terminating": {
+			pod:         controller,
+			pods:        []*apiv1.Pod{controller, terminating},
+			wantOutcome: drainability.BlockDrain,
+		},
+		"replacement on the same node": {
+			pod:         controller,
+			pods:        []*apiv1.Pod{controller, sameNode},
+			wantOutcome: drainability.BlockDrain,
+		},
+		"replacement in another namespace": {
+			pod:         controller,
+			pods:        []*apiv1.Pod{controller, otherNamespace},
+			wantOutcome: drainability.BlockDrain,
+		},
+		"replacement on a cordoned node": {
+			pod:         controller,
+			pods:        []*apiv1.Pod{controller, onCordonedNode},
+			wantOutcome: drainability.BlockDrain,
+		},
+		"replacement on a node being deleted": {
+			pod:         controller,
+			pods:        []*apiv1.Pod{controller, onDeletedNode},
+			wantOutcome: drainability.BlockDrain,
+		},
+		"no listers": {
+			pod:         controller,
+			noListers:   true,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			drainCtx := &drainability.DrainContext{}
+			if !tc.noListers {
+				drainCtx.Listers = &drainabilitytest.Listers{Nodes: nodeLister, Pods: NewTestPodLister(tc.pods)}
+			}
+			got := New(selectors).Drainable(drainCtx, tc.pod, nil)
+			assert.Equal(t, tc.wantOutcome, got.Outcome)
+			if tc.wantOutcome == drainability.BlockDrain {
+				assert.Equal(t, drain.SingletonInfraPod, got.BlockingReason)
+			}
+		})
+	}
+}
+
+func testPod(name, nodeName, app string, ready bool) *apiv1.Pod {
+	pod := BuildScheduledTestPod(name, 100, 0, nodeName)
+	pod.Labels = map[string]string{"app": app}
+	pod.Status.Phase = apiv1.PodRunning
+	readyStatus := apiv1.ConditionFalse
+	if ready {
+		readyStatus = apiv1.ConditionTrue
+	}
+	pod.Status.Conditions = []apiv1.PodCondition{{Type: apiv1.PodReady, Status: readyStatus}}
+	return pod
+}
@@ -21,15 +21,22 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
 // Rule is a drainability rule on how to handle system pods.
-type Rule struct{}
+type Rule struct {
+	deleteOptions options.NodeDeleteOptions
+}
 
-// New creates a new Rule.
-func New() *Rule {
-	return &Rule{}
+// New creates a new Rule. The delete options are adjusted for the node pods
+// are drained from, see NodeDeleteOptions.ForNode.
+func New(deleteOptions options.NodeDeleteOptions) *Rule {
+	return &Rule{
+		deleteOptions: deleteOptions,
+	}
 }
 
 // Name returns the name of the rule.
@@ -37,10 +44,16 @@ func (r *Rule) Name() string {
 	return "System"
 }
 
-// Drainable decides what to do with system pods on node drain.
-func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
-	if pod.Namespace == "kube-system" && len(drainCtx.RemainingPdbTracker.MatchingPdbs(pod)) == 0 {
-		return drainability.NewBlockedStatus(drain.UnmovableKubeSystemPod, fmt.Errorf("non-daemonset, non-mirrored, non-pdb-assigned kube-system pod present: %s", pod.Name))
+// Drainable decides what to do with system pods on node drain. System pods,
+// i.e. pods from kube-system or one of SystemPodNamespaces, only block drain
+// if SkipNodesWithSystemPods is set.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	deleteOptions := r.deleteOptions.ForNode(nodeInfo.Node())
+	if !deleteOptions.SkipNodesWithSystemPods {
+		return drainability.NewUndefinedStatus()
+	}
+	if deleteOptions.IsSystemNamespace(pod.Namespace) && len(drainCtx.RemainingPdbTracker.MatchingPdbs(pod)) == 0 {
+		return drainability.NewBlockedStatus(drain.UnmovableKubeSystemPod, fmt.Errorf("non-daemonset, non-mirrored, non-pdb-assigned %s pod present: %s", pod.Namespace, pod.Name))
 	}
 	return drainability.NewUndefinedStatus()
 }
@@ -24,10 +24,11 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/autoscaler/cluster-autoscaler/core/scaledown/pdb"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -84,6 +85,19 @@ func TestDrainable(t *testing.T) {
 			},
 		}
 
+		monitoringPod = &apiv1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "bar",
+				Namespace: "monitoring",
+				Labels: map[string]string{
+					"k8s-app": "bar",
+				},
+			},
+			Spec: apiv1.PodSpec{
+				NodeName: "node",
+			},
+		}
+
 		emptyPDB = &policyv1.PodDisruptionBudget{}
 
 		kubeSystemPDB = &policyv1.PodDisruptionBudget{
@@ -112,6 +126,19 @@ func TestDrainable(t *testing.T) {
 			},
 		}
 
+		monitoringPDB = &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "monitoring",
+			},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{
+						"k8s-app": "bar",
+					},
+				},
+			},
+		}
+
 		defaultNamespacePDB = &policyv1.PodDisruptionBudget{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "default",
@@ -132,6 +159,8 @@ func TestDrainable(t *testing.T) {
 		rss  []*appsv1.ReplicaSet
 		pdbs []*policyv1.PodDisruptionBudget
 
+		systemPodNamespaces []string
+
 		wantReason drain.BlockingPodReason
 		wantError  bool
 	}{
@@ -164,6 +193,27 @@ func TestDrainable(t *testing.T) {
 			wantReason: drain.UnmovableKubeSystemPod,
 			wantError:  true,
 		},
+		"pod from namespace not configured as system": {
+			pod: monitoringPod,
+		},
+		"pod from namespace configured as system": {
+			pod:                 monitoringPod,
+			systemPodNamespaces: []string{"monitoring"},
+			wantReason:          drain.UnmovableKubeSystemPod,
+			wantError:           true,
+		},
+		"PDB with matching pod from namespace configured as system": {
+			pod:                 monitoringPod,
+			pdbs:                []*policyv1.PodDisruptionBudget{monitoringPDB},
+			systemPodNamespaces: []string{"monitoring"},
+		},
+		"kube-system pod with other namespaces configured as system": {
+			pod:                 kubeSystemRcPod,
+			rcs:                 []*apiv1.ReplicationController{&kubeSystemRc},
+			systemPodNamespaces: []string{"monitoring"},
+			wantReason:          drain.UnmovableKubeSystemPod,
+			wantError:           true,
+		},
 	} {
 		t.Run(desc, func(t *testing.T) {
 			tracker := pdb.NewBasicRemainingPdbTracker()
@@ -173,7 +223,8 @@ func TestDrainable(t *testing.T) {
 				RemainingPdbTracker: tracker,
 				Timestamp:           testTime,
 			}
-			status := New().Drainable(drainCtx, test.pod)
+			deleteOptions := options.NodeDeleteOptions{SkipNodesWithSystemPods: true, SystemPodNamespaces: test.systemPodNamespaces}
+			status := New(deleteOptions).Drainable(drainCtx, test.pod, nil)
 			assert.Equal(t, test.wantReason, status.BlockingReason)
 			assert.Equal(t, test.wantError, status.Error != nil)
 		})
@@ -19,10 +19,13 @@ package terminal
 import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
 )
 
-// Rule is a drainability rule on how to handle terminal pods.
+// Rule is a drainability rule on how to handle terminal pods. Terminal pods,
+// e.g. of finished Jobs, are skipped regardless of their controllers: they
+// don't have to be moved and don't make their node non-empty.
 type Rule struct{}
 
 // New creates a new Rule.
@@ -36,9 +39,9 @@ func (r *Rule) Name() string {
 }
 
 // Drainable decides what to do with terminal pods on node drain.
-func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
-	if drain.IsPodTerminal(pod) {
-		return drainability.NewDrainableStatus()
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if drain.IsPodCompleted(pod) {
+		return drainability.NewSkipStatus()
 	}
 	return drainability.NewUndefinedStatus()
 }
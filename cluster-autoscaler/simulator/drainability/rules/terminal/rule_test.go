@@ -23,6 +23,7 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 )
 
 func TestDrainable(t *testing.T) {
@@ -52,7 +53,7 @@ func TestDrainable(t *testing.T) {
 					Phase: apiv1.PodSucceeded,
 				},
 			},
-			want: drainability.NewDrainableStatus(),
+			want: drainability.NewSkipStatus(),
 		},
 		"failed pod": {
 			pod: &apiv1.Pod{
@@ -67,11 +68,54 @@ func TestDrainable(t *testing.T) {
 					Phase: apiv1.PodFailed,
 				},
 			},
-			want: drainability.NewDrainableStatus(),
+			want: drainability.NewSkipStatus(),
+		},
+		"evicted pod": {
+			pod: &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "bar",
+					Namespace: "default",
+				},
+				Spec: apiv1.PodSpec{
+					RestartPolicy: apiv1.RestartPolicyAlways,
+				},
+				Status: apiv1.PodStatus{
+					Phase: apiv1.PodFailed,
+				},
+			},
+			want: drainability.NewSkipStatus(),
+		},
+		"succeeded pod of deleted job": {
+			pod: &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "bar",
+					Namespace:       "default",
+					OwnerReferences: test.GenerateOwnerReferences("job", "Job", "batch/v1", ""),
+				},
+				Spec: apiv1.PodSpec{
+					RestartPolicy: apiv1.RestartPolicyNever,
+				},
+				Status: apiv1.PodStatus{
+					Phase: apiv1.PodSucceeded,
+				},
+			},
+			want: drainability.NewSkipStatus(),
+		},
+		"running pod": {
+			pod: &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "bar",
+					Namespace: "default",
+				},
+				Status: apiv1.PodStatus{
+					Phase: apiv1.PodRunning,
+				},
+			},
+			want: drainability.NewUndefinedStatus(),
 		},
 	} {
 		t.Run(desc, func(t *testing.T) {
-			got := New().Drainable(nil, tc.pod)
+			got := New().Drainable(nil, tc.pod, nil)
 			if diff := cmp.Diff(tc.want, got); diff != "" {
 				t.Errorf("Rule.Drainable(%v): got status diff (-want +got):\n%s", tc.pod.Name, diff)
 			}
@@ -0,0 +1,297 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/metrics"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// FailurePolicy defines how the Rule handles webhook errors.
+type FailurePolicy string
+
+const (
+	// Ignore means that webhook errors are ignored and the remaining rules
+	// decide about drainability of the pod (fail open).
+	Ignore FailurePolicy = "Ignore"
+	// Fail means that webhook errors block drain of the pod (fail closed).
+	Fail FailurePolicy = "Fail"
+)
+
+// ParseFailurePolicy parses a FailurePolicy from its name.
+func ParseFailurePolicy(name string) (FailurePolicy, error) {
+	switch policy := FailurePolicy(name); policy {
+	case Ignore, Fail:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown drainability webhook failure policy %q, expected one of: %s, %s", name, Ignore, Fail)
+}
+
+// Request is the body POSTed to the webhook for every evaluated pod.
+type Request struct {
+	// Pod is the pod whose drainability is evaluated.
+	Pod *apiv1.Pod `json:"pod"`
+	// Timestamp is the time of the drainability evaluation.
+	Timestamp time.Time `json:"timestamp"`
+	// DeleteOptions are the node delete options used for the node of the pod.
+	DeleteOptions DeleteOptions `json:"deleteOptions"`
+}
+
+// DeleteOptions are the node delete options passed to the webhook.
+type DeleteOptions struct {
+	SkipNodesWithSystemPods           bool     `json:"skipNodesWithSystemPods"`
+	SystemPodNamespaces               []string `json:"systemPodNamespaces,omitempty"`
+	SkipNodesWithLocalStorage         bool     `json:"skipNodesWithLocalStorage"`
+	SkipNodesWithCustomControllerPods bool     `json:"skipNodesWithCustomControllerPods"`
+	MinReplicaCount                   int      `json:"minReplicaCount"`
+}
+
+// Response is the body expected from the webhook.
+type Response struct {
+	// Outcome is one of DrainOk, BlockDrain or Undefined. Undefined (or an
+	// empty Outcome) leaves the decision to the remaining rules.
+	Outcome string `json:"outcome"`
+	// Message is an optional explanation of the outcome, reported when the
+	// pod blocks drain.
+	Message string `json:"message,omitempty"`
+	// Reason optionally identifies why the pod blocks drain. It is reported
+	// instead of RejectedByWebhook, e.g. in events and status.
+	Reason string `json:"reason,omitempty"`
+	// Details contains optional structured details of Reason.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Webhook delegates drainability decisions to an external webhook, shared by
+// Rule and DrainableRule. It is safe for concurrent use.
+type Webhook struct {
+	url           string
+	client        *http.Client
+	failurePolicy FailurePolicy
+	cacheTTL      time.Duration
+	now           func() time.Time
+
+	mutex         sync.Mutex
+	cache         map[types.UID]cacheEntry
+	lastPurge     time.Time
+	lastTimestamp time.Time
+}
+
+type cacheEntry struct {
+	resourceVersion string
+	status          drainability.Status
+	expires         time.Time
+	// timestamp is the drainability evaluation time of the response, which
+	// is reused during the same evaluation even if caching is disabled.
+	timestamp time.Time
+}
+
+// NewWebhook creates a new Webhook POSTing pods to the given url. Webhook
+// calls taking longer than timeout are treated as errors and handled
+// according to failurePolicy. Successful responses are cached for cacheTTL
+// per pod resource version. Caching is disabled if cacheTTL is not positive,
+// in which case the webhook is still called at most once per pod and
+// DrainContext timestamp.
+func NewWebhook(url string, timeout time.Duration, failurePolicy FailurePolicy, cacheTTL time.Duration) *Webhook {
+	return &Webhook{
+		url:           url,
+		client:        &http.Client{Timeout: timeout},
+		failurePolicy: failurePolicy,
+		cacheTTL:      cacheTTL,
+		now:           time.Now,
+		cache:         make(map[types.UID]cacheEntry),
+	}
+}
+
+// Rule is a drainability rule blocking drain of pods rejected by the webhook.
+// It is meant to be evaluated together with disruption budgets, so that the
+// webhook isn't called for pods skipped by other rules, e.g. DaemonSet or
+// terminal pods.
+type Rule struct {
+	webhook *Webhook
+}
+
+// New creates a new Rule.
+func New(webhook *Webhook) *Rule {
+	return &Rule{
+		webhook: webhook,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *Rule) Name() string {
+	return "Webhook"
+}
+
+// Drainable decides what to do with pods rejected by the webhook on node
+// drain. Webhook errors block drain only with the Fail failure policy.
+func (r *Rule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if status := r.webhook.status(drainCtx, pod); status.Outcome == drainability.BlockDrain {
+		return status
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+// DrainableRule is a drainability rule allowing drain of pods accepted by the
+// webhook. It makes pods drainable the same way the safe-to-evict annotation
+// does, so it's meant to be evaluated below disruption budgets.
+type DrainableRule struct {
+	webhook *Webhook
+}
+
+// NewDrainable creates a new DrainableRule.
+func NewDrainable(webhook *Webhook) *DrainableRule {
+	return &DrainableRule{
+		webhook: webhook,
+	}
+}
+
+// Name returns the name of the rule.
+func (r *DrainableRule) Name() string {
+	return "DrainableWebhook"
+}
+
+// Drainable decides what to do with pods accepted by the webhook on node
+// drain.
+func (r *DrainableRule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, nodeInfo *framework.NodeInfo) drainability.Status {
+	if status := r.webhook.status(drainCtx, pod); status.Outcome == drainability.DrainOk {
+		return status
+	}
+	return drainability.NewUndefinedStatus()
+}
+
+func (w *Webhook) status(drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
+	if status, found := w.cached(pod, drainCtx.Timestamp); found {
+		return status
+	}
+
+	status, err := w.call(drainCtx, pod)
+	if err != nil {
+		if w.failurePolicy == Fail {
+			status = drainability.NewBlockedStatus(drain.UnexpectedError, fmt.Errorf("drainability webhook failed for pod %s/%s: %v", pod.Namespace, pod.Name, err))
+		} else {
+			klog.Warningf("Drainability webhook failed for pod %s/%s, ignoring: %v", pod.Namespace, pod.Name, err)
+			status = drainability.NewUndefinedStatus()
+		}
+		// Errors are only reused during the same evaluation.
+		w.store(pod, status, drainCtx.Timestamp, 0)
+		return status
+	}
+	w.store(pod, status, drainCtx.Timestamp, w.cacheTTL)
+	return status
+}
+
+func (w *Webhook) call(drainCtx *drainability.DrainContext, pod *apiv1.Pod) (drainability.Status, error) {
+	body, err := json.Marshal(Request{
+		Pod:       pod,
+		Timestamp: drainCtx.Timestamp,
+		DeleteOptions: DeleteOptions{
+			SkipNodesWithSystemPods:           drainCtx.DeleteOptions.SkipNodesWithSystemPods,
+			SystemPodNamespaces:               drainCtx.DeleteOptions.SystemPodNamespaces,
+			SkipNodesWithLocalStorage:         drainCtx.DeleteOptions.SkipNodesWithLocalStorage,
+			SkipNodesWithCustomControllerPods: drainCtx.DeleteOptions.SkipNodesWithCustomControllerPods,
+			MinReplicaCount:                   drainCtx.DeleteOptions.MinReplicaCount,
+		},
+	})
+	if err != nil {
+		return drainability.Status{}, fmt.Errorf("can't encode request: %v", err)
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return drainability.Status{}, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return drainability.Status{}, fmt.Errorf("can't read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return drainability.Status{}, fmt.Errorf("unexpected response status %s: %s", resp.Status, respBody)
+	}
+
+	response := &Response{}
+	if err := json.Unmarshal(respBody, response); err != nil {
+		return drainability.Status{}, fmt.Errorf("can't decode response: %v", err)
+	}
+	switch response.Outcome {
+	case "", drainability.UndefinedOutcome.String():
+		return drainability.NewUndefinedStatus(), nil
+	case drainability.DrainOk.String():
+		return drainability.NewDrainableStatus(), nil
+	case drainability.BlockDrain.String():
+		err := fmt.Errorf("drainability webhook rejected drain of pod %s/%s: %s", pod.Namespace, pod.Name, response.Message)
+		if response.Reason == "" {
+			return drainability.NewBlockedStatus(drain.RejectedByWebhook, err), nil
+		}
+		status := drainability.NewCustomBlockedStatus(drain.BlockingReasonID(response.Reason), response.Details, err)
+		if status.BlockingReason == drain.CustomRuleReason {
+			status.BlockingReason = drain.RejectedByWebhook
+		}
+		return status, nil
+	}
+	return drainability.Status{}, fmt.Errorf("unknown outcome %q", response.Outcome)
+}
+
+func (w *Webhook) cached(pod *apiv1.Pod, timestamp time.Time) (drainability.Status, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	entry, found := w.cache[pod.UID]
+	if found && (entry.resourceVersion != pod.ResourceVersion || (!w.now().Before(entry.expires) && !entry.timestamp.Equal(timestamp))) {
+		delete(w.cache, pod.UID)
+		found = false
+	}
+	if w.cacheTTL > 0 {
+		metrics.RegisterCacheLookup(metrics.WebhookCache, found)
+	}
+	if !found {
+		return drainability.Status{}, false
+	}
+	return entry.status, true
+}
+
+func (w *Webhook) store(pod *apiv1.Pod, status drainability.Status, timestamp time.Time, ttl time.Duration) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	now := w.now()
+	// Drop expired entries of previous evaluations at most once per TTL and
+	// evaluation, so that the cache doesn't grow with pods that are gone.
+	if !timestamp.Equal(w.lastTimestamp) || (w.cacheTTL > 0 && now.Sub(w.lastPurge) >= w.cacheTTL) {
+		for k, entry := range w.cache {
+			if !now.Before(entry.expires) && !entry.timestamp.Equal(timestamp) {
+				delete(w.cache, k)
+			}
+		}
+		w.lastPurge = now
+		w.lastTimestamp = timestamp
+	}
+	w.cache[pod.UID] = cacheEntry{resourceVersion: pod.ResourceVersion, status: status, expires: now.Add(ttl), timestamp: timestamp}
+}
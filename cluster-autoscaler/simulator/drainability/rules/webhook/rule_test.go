@@ -0,0 +1,317 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
+	"k8s.io/kubernetes/pkg/kubelet/types"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainable(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+
+	for desc, test := range map[string]struct {
+		pod           *apiv1.Pod
+		status        int
+		response      string
+		failurePolicy FailurePolicy
+
+		wantCalls   int
+		wantOutcome drainability.OutcomeType
+		wantReason  drain.BlockingPodReason
+		wantCustom  drain.BlockingReasonID
+		wantDetails map[string]string
+		wantError   bool
+	}{
+		"drain ok": {
+			pod:         testPod(),
+			response:    `{"outcome": "DrainOk"}`,
+			wantCalls:   1,
+			wantOutcome: drainability.DrainOk,
+		},
+		"block drain": {
+			pod:         testPod(),
+			response:    `{"outcome": "BlockDrain", "message": "batch job in progress"}`,
+			wantCalls:   1,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.RejectedByWebhook,
+			wantError:   true,
+		},
+		"block drain with custom reason": {
+			pod:         testPod(),
+			response:    `{"outcome": "BlockDrain", "reason": "example.com/MigrationPending", "details": {"volume": "data"}}`,
+			wantCalls:   1,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.RejectedByWebhook,
+			wantCustom:  "example.com/MigrationPending",
+			wantDetails: map[string]string{"volume": "data"},
+			wantError:   true,
+		},
+		"block drain with built-in reason": {
+			pod:         testPod(),
+			response:    `{"outcome": "BlockDrain", "reason": "NotReplicated"}`,
+			wantCalls:   1,
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.NotReplicated,
+			wantCustom:  "NotReplicated",
+			wantError:   true,
+		},
+		"undefined": {
+			pod:         testPod(),
+			response:    `{"outcome": "Undefined"}`,
+			wantCalls:   1,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"empty outcome": {
+			pod:         testPod(),
+			response:    `{}`,
+			wantCalls:   1,
+			wantOutcome: drainability.UndefinedOutcome,
+		},
+		"error with ignore policy": {
+			pod:           testPod(),
+			status:        http.StatusInternalServerError,
+			failurePolicy: Ignore,
+			wantCalls:     1,
+			wantOutcome:   drainability.UndefinedOutcome,
+		},
+		"error with fail policy": {
+			pod:           testPod(),
+			status:        http.StatusInternalServerError,
+			failurePolicy: Fail,
+			wantCalls:     1,
+			wantOutcome:   drainability.BlockDrain,
+			wantReason:    drain.UnexpectedError,
+			wantError:     true,
+		},
+		"unknown outcome with fail policy": {
+			pod:           testPod(),
+			response:      `{"outcome": "Maybe"}`,
+			failurePolicy: Fail,
+			wantCalls:     1,
+			wantOutcome:   drainability.BlockDrain,
+			wantReason:    drain.UnexpectedError,
+			wantError:     true,
+		},
+		"malformed response with ignore policy": {
+			pod:           testPod(),
+			response:      `not json`,
+			failurePolicy: Ignore,
+			wantCalls:     1,
+			wantOutcome:   drainability.UndefinedOutcome,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				request := &Request{}
+				assert.NoError(t, json.NewDecoder(req.Body).Decode(request))
+				assert.Equal(t, test.pod.Name, request.Pod.Name)
+				assert.Equal(t, 3, request.DeleteOptions.MinReplicaCount)
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+					return
+				}
+				_, _ = w.Write([]byte(test.response))
+			}))
+			defer server.Close()
+
+			drainCtx := &drainability.DrainContext{
+				Timestamp:     testTime,
+				DeleteOptions: options.NodeDeleteOptions{MinReplicaCount: 3},
+			}
+			status := evaluate(NewWebhook(server.URL, time.Second, test.failurePolicy, 0), drainCtx, test.pod)
+			assert.Equal(t, test.wantCalls, calls)
+			assert.Equal(t, test.wantOutcome, status.Outcome)
+			assert.Equal(t, test.wantReason, status.BlockingReason)
+			assert.Equal(t, test.wantCustom, status.CustomBlockingReason)
+			assert.Equal(t, test.wantDetails, status.BlockingDetails)
+			assert.Equal(t, test.wantError, status.Error != nil)
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls > 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"outcome": "DrainOk"}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	webhook := NewWebhook(server.URL, time.Second, Ignore, time.Minute)
+	webhook.now = func() time.Time { return now }
+	pod := testPod()
+
+	assert.Equal(t, drainability.DrainOk, evaluate(webhook, &drainability.DrainContext{Timestamp: now}, pod).Outcome)
+	assert.Equal(t, drainability.DrainOk, evaluate(webhook, &drainability.DrainContext{Timestamp: now.Add(time.Second)}, pod).Outcome)
+	assert.Equal(t, 1, calls, "cached response should be reused")
+
+	updated := pod.DeepCopy()
+	updated.ResourceVersion = "2"
+	assert.Equal(t, drainability.DrainOk, evaluate(webhook, &drainability.DrainContext{Timestamp: now}, updated).Outcome)
+	assert.Equal(t, 2, calls, "pod update should invalidate cached response")
+
+	now = now.Add(2 * time.Minute)
+	assert.Equal(t, drainability.UndefinedOutcome, evaluate(webhook, &drainability.DrainContext{Timestamp: now}, updated).Outcome)
+	assert.Equal(t, 3, calls, "expired response should not be reused")
+
+	// Errors are not cached.
+	assert.Equal(t, drainability.UndefinedOutcome, evaluate(webhook, &drainability.DrainContext{Timestamp: now.Add(time.Second)}, updated).Outcome)
+	assert.Equal(t, 4, calls)
+}
+
+func TestCacheDisabled(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"outcome": "DrainOk"}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	webhook := NewWebhook(server.URL, time.Second, Ignore, 0)
+	webhook.now = func() time.Time { return now }
+	pod := testPod()
+
+	assert.Equal(t, drainability.DrainOk, evaluate(webhook, &drainability.DrainContext{Timestamp: now}, pod).Outcome)
+	assert.Equal(t, 1, calls, "response should be reused during the same evaluation")
+
+	now = now.Add(time.Second)
+	assert.Equal(t, drainability.DrainOk, evaluate(webhook, &drainability.DrainContext{Timestamp: now}, pod).Outcome)
+	assert.Equal(t, 2, calls, "response should not be reused in another evaluation")
+	assert.Len(t, webhook.cache, 1)
+}
+
+func TestDrainableWithDefaultRules(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"outcome": "DrainOk"}`))
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.URL, time.Second, Ignore, 0)
+	drainabilityRules := append(rules.Default(options.NodeDeleteOptions{}),
+		rules.WithPriority(New(webhook), rules.BudgetPriority),
+		rules.WithPriority(NewDrainable(webhook), rules.NonBlockingPriority))
+
+	mirrorPod := testPod()
+	mirrorPod.Annotations = map[string]string{types.ConfigMirrorAnnotationKey: "mirror"}
+	dsPod := testPod()
+	dsPod.OwnerReferences = test.GenerateOwnerReferences("ds", "DaemonSet", "apps/v1", "")
+	budgetedPod := testPod()
+	budgetedPod.Labels = map[string]string{"app": "job"}
+	zeroBudget := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "job"}},
+		},
+		Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 0},
+	}
+
+	for desc, tc := range map[string]struct {
+		pod         *apiv1.Pod
+		pdbs        []*policyv1.PodDisruptionBudget
+		wantCalls   int
+		wantOutcome drainability.OutcomeType
+		wantReason  drain.BlockingPodReason
+	}{
+		"pod accepted by webhook": {
+			pod:         testPod(),
+			wantCalls:   1,
+			wantOutcome: drainability.DrainOk,
+		},
+		"pod accepted by webhook without disruptions allowed": {
+			pod:         budgetedPod,
+			pdbs:        []*policyv1.PodDisruptionBudget{zeroBudget},
+			wantOutcome: drainability.BlockDrain,
+			wantReason:  drain.NotEnoughPdb,
+		},
+		"mirror pod": {
+			pod:         mirrorPod,
+			wantOutcome: drainability.SkipDrain,
+		},
+		"daemon set pod": {
+			pod:         dsPod,
+			wantOutcome: drainability.DrainOk,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			calls = 0
+			tracker := pdb.NewBasicRemainingPdbTracker()
+			assert.NoError(t, tracker.SetPdbs(tc.pdbs))
+			drainCtx := &drainability.DrainContext{RemainingPdbTracker: tracker, Timestamp: time.Now()}
+			status := drainabilityRules.Drainable(drainCtx, tc.pod, nil)
+			assert.Equal(t, tc.wantCalls, calls)
+			assert.Equal(t, tc.wantOutcome, status.Outcome)
+			assert.Equal(t, tc.wantReason, status.BlockingReason)
+		})
+	}
+}
+
+func TestParseFailurePolicy(t *testing.T) {
+	for _, name := range []string{"Ignore", "Fail"} {
+		policy, err := ParseFailurePolicy(name)
+		assert.NoError(t, err)
+		assert.Equal(t, FailurePolicy(name), policy)
+	}
+	_, err := ParseFailurePolicy("fail")
+	assert.Error(t, err)
+}
+
+// evaluate returns the status of the pod according to Rule and DrainableRule
+// of the webhook, in the order of their evaluation.
+func evaluate(webhook *Webhook, drainCtx *drainability.DrainContext, pod *apiv1.Pod) drainability.Status {
+	if status := New(webhook).Drainable(drainCtx, pod, nil); status.Outcome != drainability.UndefinedOutcome {
+		return status
+	}
+	return NewDrainable(webhook).Drainable(drainCtx, pod, nil)
+}
+
+func testPod() *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pod",
+			Namespace:       "default",
+			UID:             "pod-uid",
+			ResourceVersion: "1",
+		},
+	}
+}
@@ -17,7 +17,9 @@ limitations under the License.
 package drainability
 
 import (
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
+	"fmt"
+
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 )
 
 // OutcomeType identifies the action that should be taken when it comes to
@@ -37,8 +39,22 @@ const (
 	SkipDrain
 )
 
+// String returns a human readable name of the OutcomeType.
+func (o OutcomeType) String() string {
+	switch o {
+	case UndefinedOutcome:
+		return "Undefined"
+	case DrainOk:
+		return "DrainOk"
+	case BlockDrain:
+		return "BlockDrain"
+	case SkipDrain:
+		return "SkipDrain"
+	}
+	return fmt.Sprintf("OutcomeType(%d)", int(o))
+}
+
 // Status contains all information about drainability of a single pod.
-// TODO(x13n): Move values from drain.BlockingPodReason to some typed string.
 type Status struct {
 	// Outcome indicates what can happen when it comes to draining a
 	// specific pod.
@@ -52,8 +68,27 @@ type Status struct {
 	// Reason contains the reason why a pod is blocking node drain. It is
 	// set only when Outcome is BlockDrain.
 	BlockingReason drain.BlockingPodReason
+	// CustomBlockingReason identifies the reason why a pod is blocking node
+	// drain if it was provided by a custom rule. BlockingReason is then the
+	// matching drain.BlockingPodReason, or drain.CustomRuleReason.
+	CustomBlockingReason drain.BlockingReasonID
+	// BlockingDetails contains optional structured details of the reason why
+	// a pod is blocking node drain.
+	BlockingDetails map[string]string
+	// Rule is the name of the rule which blocked the pod. It is set by
+	// Rules.Drainable when Outcome is BlockDrain.
+	Rule string
+	// Remediation contains an optional hint on how to unblock the pod. If a
+	// rule doesn't provide one, Rules.Drainable sets the default hint of the
+	// BlockingReason.
+	Remediation drain.Remediation
 	// Error contains an optional error message.
 	Error error
+	// DeletionCost is the cost of deleting the pod, as set by the
+	// controller.kubernetes.io/pod-deletion-cost annotation. It is set by
+	// Rules.Drainable for pods that can be drained. Pods with lower costs are
+	// evicted first.
+	DeletionCost int32
 }
 
 // NewDrainableStatus returns a new Status indicating that a pod can be drained.
@@ -72,6 +107,26 @@ func NewBlockedStatus(reason drain.BlockingPodReason, err error) Status {
 	}
 }
 
+// NewCustomBlockedStatus returns a new Status indicating that a pod is blocked and cannot be drained, for a reason
+// identified by a custom rule.
+func NewCustomBlockedStatus(reason drain.BlockingReasonID, details map[string]string, err error) Status {
+	return Status{
+		Outcome:              BlockDrain,
+		BlockingReason:       reason.LegacyReason(),
+		CustomBlockingReason: reason,
+		BlockingDetails:      details,
+		Error:                err,
+	}
+}
+
+// BlockingReasonID returns the identifier of the reason why a pod is blocking node drain.
+func (s Status) BlockingReasonID() drain.BlockingReasonID {
+	if s.CustomBlockingReason != "" {
+		return s.CustomBlockingReason
+	}
+	return s.BlockingReason.ID()
+}
+
 // NewSkipStatus returns a new Status indicating that a pod should be skipped when draining a node.
 func NewSkipStatus() Status {
 	return Status{
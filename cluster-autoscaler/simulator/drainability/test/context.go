@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package test provides helpers for testing drainability rules, so that
+// authors of custom rules don't need to set up the simulator or the core loop
+// to exercise them.
+package test
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
+)
+
+// DefaultTimestamp is the Timestamp of DrainContexts built by NewDrainContext,
+// unless WithTimestamp is used.
+var DefaultTimestamp = time.Date(2023, time.October, 2, 12, 0, 0, 0, time.UTC)
+
+// DrainContextOption modifies a DrainContext built by NewDrainContext.
+type DrainContextOption func(*drainContextBuilder)
+
+type drainContextBuilder struct {
+	drainCtx *drainability.DrainContext
+	pdbs     []*policyv1.PodDisruptionBudget
+	owners   []runtime.Object
+}
+
+// NewDrainContext returns a DrainContext for evaluating rules in tests. By
+// default, it has DefaultTimestamp, an empty RemainingPdbTracker, no listers
+// and zero DeleteOptions. Invalid options fail the test.
+func NewDrainContext(t testing.TB, opts ...DrainContextOption) *drainability.DrainContext {
+	t.Helper()
+	b := &drainContextBuilder{
+		drainCtx: &drainability.DrainContext{
+			RemainingPdbTracker: pdb.NewBasicRemainingPdbTracker(),
+			Timestamp:           DefaultTimestamp,
+		},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	if len(b.pdbs) > 0 {
+		if err := b.drainCtx.RemainingPdbTracker.SetPdbs(b.pdbs); err != nil {
+			t.Fatalf("Failed to set PDBs: %v", err)
+		}
+	}
+	if len(b.owners) > 0 {
+		b.drainCtx.Listers = newOwnerListers(t, b.owners)
+	}
+	return b.drainCtx
+}
+
+// WithTimestamp sets the Timestamp of the DrainContext.
+func WithTimestamp(timestamp time.Time) DrainContextOption {
+	return func(b *drainContextBuilder) {
+		b.drainCtx.Timestamp = timestamp
+	}
+}
+
+// WithDeleteOptions sets the DeleteOptions of the DrainContext.
+func WithDeleteOptions(deleteOptions options.NodeDeleteOptions) DrainContextOption {
+	return func(b *drainContextBuilder) {
+		b.drainCtx.DeleteOptions = deleteOptions
+	}
+}
+
+// WithPdbs sets the remaining PDBs of the DrainContext's RemainingPdbTracker.
+func WithPdbs(pdbs ...*policyv1.PodDisruptionBudget) DrainContextOption {
+	return func(b *drainContextBuilder) {
+		b.pdbs = append(b.pdbs, pdbs...)
+	}
+}
+
+// WithRemainingPdbTracker sets the RemainingPdbTracker of the DrainContext,
+// e.g. to a FakeRemainingPdbTracker. PDBs passed with WithPdbs are set on it.
+func WithRemainingPdbTracker(tracker pdb.RemainingPdbTracker) DrainContextOption {
+	return func(b *drainContextBuilder) {
+		b.drainCtx.RemainingPdbTracker = tracker
+	}
+}
+
+// WithListers sets the Listers of the DrainContext. It takes precedence over
+// WithOwners.
+func WithListers(listers drainability.Listers) DrainContextOption {
+	return func(b *drainContextBuilder) {
+		b.drainCtx.Listers = listers
+		b.owners = nil
+	}
+}
+
+// WithOwners sets the Listers of the DrainContext to listers returning the
+// given controllers, which can be DaemonSets, ReplicationControllers, Jobs,
+// ReplicaSets, StatefulSets and Deployments, and the given Namespaces. Objects
+// of other types fail the test.
+func WithOwners(owners ...runtime.Object) DrainContextOption {
+	return func(b *drainContextBuilder) {
+		b.owners = append(b.owners, owners...)
+	}
+}
+
+func newOwnerListers(t testing.TB, owners []runtime.Object) drainability.Listers {
+	t.Helper()
+	var dss []*appsv1.DaemonSet
+	var rcs []*apiv1.ReplicationController
+	var jobs []*batchv1.Job
+	var rss []*appsv1.ReplicaSet
+	var sss []*appsv1.StatefulSet
+	var deployments []*appsv1.Deployment
+	var namespaces []*apiv1.Namespace
+	for _, owner := range owners {
+		switch o := owner.(type) {
+		case *appsv1.DaemonSet:
+			dss = append(dss, o)
+		case *apiv1.ReplicationController:
+			rcs = append(rcs, o)
+		case *batchv1.Job:
+			jobs = append(jobs, o)
+		case *appsv1.ReplicaSet:
+			rss = append(rss, o)
+		case *appsv1.StatefulSet:
+			sss = append(sss, o)
+		case *appsv1.Deployment:
+			deployments = append(deployments, o)
+		case *apiv1.Namespace:
+			namespaces = append(namespaces, o)
+		default:
+			t.Fatalf("Unsupported owner type %T", owner)
+		}
+	}
+	dsLister, err := test.NewTestDaemonSetLister(dss)
+	if err != nil {
+		t.Fatalf("Failed to create DaemonSet lister: %v", err)
+	}
+	rcLister, err := test.NewTestReplicationControllerLister(rcs)
+	if err != nil {
+		t.Fatalf("Failed to create ReplicationController lister: %v", err)
+	}
+	jobLister, err := test.NewTestJobLister(jobs)
+	if err != nil {
+		t.Fatalf("Failed to create Job lister: %v", err)
+	}
+	rsLister, err := test.NewTestReplicaSetLister(rss)
+	if err != nil {
+		t.Fatalf("Failed to create ReplicaSet lister: %v", err)
+	}
+	ssLister, err := test.NewTestStatefulSetLister(sss)
+	if err != nil {
+		t.Fatalf("Failed to create StatefulSet lister: %v", err)
+	}
+	deploymentLister, err := test.NewTestDeploymentLister(deployments)
+	if err != nil {
+		t.Fatalf("Failed to create Deployment lister: %v", err)
+	}
+	namespaceLister, err := test.NewTestNamespaceLister(namespaces)
+	if err != nil {
+		t.Fatalf("Failed to create Namespace lister: %v", err)
+	}
+	return &Listers{
+		DaemonSets:             dsLister,
+		ReplicationControllers: rcLister,
+		Jobs:                   jobLister,
+		ReplicaSets:            rsLister,
+		StatefulSets:           ssLister,
+		Namespaces:             namespaceLister,
+		Deployments:            deploymentLister,
+	}
+}
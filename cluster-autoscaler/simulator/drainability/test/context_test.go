@@ -0,0 +1,65 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDrainContext(t *testing.T) {
+	drainCtx := NewDrainContext(t)
+	assert.Equal(t, DefaultTimestamp, drainCtx.Timestamp)
+	assert.NotNil(t, drainCtx.RemainingPdbTracker)
+	assert.Empty(t, drainCtx.RemainingPdbTracker.GetPdbs())
+	assert.Nil(t, drainCtx.Listers)
+	assert.Equal(t, options.NodeDeleteOptions{}, drainCtx.DeleteOptions)
+
+	now := time.Date(2023, time.October, 3, 0, 0, 0, 0, time.UTC)
+	deleteOptions := options.NodeDeleteOptions{SkipNodesWithLocalStorage: true}
+	zero := intstr.FromInt(0)
+	budget := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "pdb", Namespace: "ns"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &zero,
+			Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+		},
+	}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "ns"}}
+	drainCtx = NewDrainContext(t, WithTimestamp(now), WithDeleteOptions(deleteOptions), WithPdbs(budget), WithOwners(rs))
+	assert.Equal(t, now, drainCtx.Timestamp)
+	assert.Equal(t, deleteOptions, drainCtx.DeleteOptions)
+	pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", Labels: map[string]string{"app": "test"}}}
+	assert.Len(t, drainCtx.RemainingPdbTracker.MatchingPdbs(pod), 1)
+	got, err := drainCtx.Listers.ReplicaSetLister().ReplicaSets("ns").Get("rs")
+	assert.NoError(t, err)
+	assert.Equal(t, rs, got)
+
+	tracker := NewFakeRemainingPdbTracker()
+	drainCtx = NewDrainContext(t, WithPdbs(budget), WithRemainingPdbTracker(tracker))
+	assert.Equal(t, tracker, drainCtx.RemainingPdbTracker)
+	assert.Equal(t, []*policyv1.PodDisruptionBudget{budget}, tracker.GetPdbs())
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	v1appslister "k8s.io/client-go/listers/apps/v1"
+	v1batchlister "k8s.io/client-go/listers/batch/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+)
+
+// Listers implements drainability.Listers with the listers set in its
+// fields. Listers which aren't set are nil.
+type Listers struct {
+	Nodes                  drainability.NodeLister
+	Pods                   drainability.PodLister
+	DaemonSets             v1appslister.DaemonSetLister
+	ReplicationControllers v1lister.ReplicationControllerLister
+	Jobs                   v1batchlister.JobLister
+	ReplicaSets            v1appslister.ReplicaSetLister
+	StatefulSets           v1appslister.StatefulSetLister
+	Namespaces             v1lister.NamespaceLister
+	Deployments            v1appslister.DeploymentLister
+}
+
+// AllNodeLister returns the node lister.
+func (l *Listers) AllNodeLister() drainability.NodeLister {
+	return l.Nodes
+}
+
+// AllPodLister returns the pod lister.
+func (l *Listers) AllPodLister() drainability.PodLister {
+	return l.Pods
+}
+
+// DaemonSetLister returns the DaemonSet lister.
+func (l *Listers) DaemonSetLister() v1appslister.DaemonSetLister {
+	return l.DaemonSets
+}
+
+// ReplicationControllerLister returns the ReplicationController lister.
+func (l *Listers) ReplicationControllerLister() v1lister.ReplicationControllerLister {
+	return l.ReplicationControllers
+}
+
+// JobLister returns the Job lister.
+func (l *Listers) JobLister() v1batchlister.JobLister {
+	return l.Jobs
+}
+
+// ReplicaSetLister returns the ReplicaSet lister.
+func (l *Listers) ReplicaSetLister() v1appslister.ReplicaSetLister {
+	return l.ReplicaSets
+}
+
+// StatefulSetLister returns the StatefulSet lister.
+func (l *Listers) StatefulSetLister() v1appslister.StatefulSetLister {
+	return l.StatefulSets
+}
+
+// NamespaceLister returns the Namespace lister.
+func (l *Listers) NamespaceLister() v1lister.NamespaceLister {
+	return l.Namespaces
+}
+
+// DeploymentLister returns the Deployment lister.
+func (l *Listers) DeploymentLister() v1appslister.DeploymentLister {
+	return l.Deployments
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+)
+
+// FakeRemainingPdbTracker is a pdb.RemainingPdbTracker whose answers are set
+// up by the test instead of computed from PDBs. Pods can be removed unless
+// they were blocked with BlockPods, and are removed in parallel unless they
+// were marked with RemoveSerially. Removed pods are recorded. It is safe for
+// concurrent use.
+type FakeRemainingPdbTracker struct {
+	mutex          sync.Mutex
+	pdbs           []*policyv1.PodDisruptionBudget
+	blocked        map[string]drain.BlockingPodReason
+	serial         map[string]bool
+	removed        []*apiv1.Pod
+	canRemoveCalls int
+}
+
+// NewFakeRemainingPdbTracker creates a new FakeRemainingPdbTracker allowing
+// removal of all pods.
+func NewFakeRemainingPdbTracker() *FakeRemainingPdbTracker {
+	return &FakeRemainingPdbTracker{
+		blocked: make(map[string]drain.BlockingPodReason),
+		serial:  make(map[string]bool),
+	}
+}
+
+// BlockPods makes CanRemovePods fail for sets containing any of the pods, with
+// the given reason.
+func (t *FakeRemainingPdbTracker) BlockPods(reason drain.BlockingPodReason, pods ...*apiv1.Pod) *FakeRemainingPdbTracker {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, pod := range pods {
+		t.blocked[podKey(pod)] = reason
+	}
+	return t
+}
+
+// RemoveSerially makes CanRemovePods report that sets containing any of the
+// pods can't be removed in parallel.
+func (t *FakeRemainingPdbTracker) RemoveSerially(pods ...*apiv1.Pod) *FakeRemainingPdbTracker {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, pod := range pods {
+		t.serial[podKey(pod)] = true
+	}
+	return t
+}
+
+// RemovedPods returns the pods passed to RemovePods so far, in order.
+func (t *FakeRemainingPdbTracker) RemovedPods() []*apiv1.Pod {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]*apiv1.Pod(nil), t.removed...)
+}
+
+// CanRemovePodsCalls returns the number of calls to CanRemovePods so far.
+func (t *FakeRemainingPdbTracker) CanRemovePodsCalls() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.canRemoveCalls
+}
+
+// SetPdbs sets PDBs returned by GetPdbs and MatchingPdbs. They don't affect
+// CanRemovePods.
+func (t *FakeRemainingPdbTracker) SetPdbs(pdbs []*policyv1.PodDisruptionBudget) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, pdb := range pdbs {
+		if _, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector); err != nil {
+			return err
+		}
+	}
+	t.pdbs = append([]*policyv1.PodDisruptionBudget(nil), pdbs...)
+	return nil
+}
+
+// GetPdbs returns the PDBs set with SetPdbs.
+func (t *FakeRemainingPdbTracker) GetPdbs() []*policyv1.PodDisruptionBudget {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]*policyv1.PodDisruptionBudget(nil), t.pdbs...)
+}
+
+// MatchingPdbs returns the PDBs set with SetPdbs which match the pod.
+func (t *FakeRemainingPdbTracker) MatchingPdbs(pod *apiv1.Pod) []*policyv1.PodDisruptionBudget {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var pdbs []*policyv1.PodDisruptionBudget
+	for _, pdb := range t.pdbs {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err == nil && pod.Namespace == pdb.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+			pdbs = append(pdbs, pdb)
+		}
+	}
+	return pdbs
+}
+
+// CanRemovePods checks if the pods were blocked or marked for serial removal.
+func (t *FakeRemainingPdbTracker) CanRemovePods(pods []*apiv1.Pod) (canRemove, inParallel bool, blockingPod *drain.BlockingPod) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.canRemoveCalls++
+	inParallel = true
+	for _, pod := range pods {
+		if reason, found := t.blocked[podKey(pod)]; found {
+			return false, false, &drain.BlockingPod{Pod: pod, Reason: reason}
+		}
+		if t.serial[podKey(pod)] {
+			inParallel = false
+			blockingPod = &drain.BlockingPod{Pod: pod, Reason: drain.NotEnoughPdb}
+		}
+	}
+	return true, inParallel, blockingPod
+}
+
+// RemovePods records removal of the pods.
+func (t *FakeRemainingPdbTracker) RemovePods(pods []*apiv1.Pod) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.removed = append(t.removed, pods...)
+}
+
+// Clear forgets PDBs, blocked pods and removed pods.
+func (t *FakeRemainingPdbTracker) Clear() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pdbs = nil
+	t.blocked = make(map[string]drain.BlockingPodReason)
+	t.serial = make(map[string]bool)
+	t.removed = nil
+}
+
+// Clone returns an independent copy of the tracker.
+func (t *FakeRemainingPdbTracker) Clone() pdb.RemainingPdbTracker {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	clone := NewFakeRemainingPdbTracker()
+	clone.pdbs = append(clone.pdbs, t.pdbs...)
+	for key, reason := range t.blocked {
+		clone.blocked[key] = reason
+	}
+	for key := range t.serial {
+		clone.serial[key] = true
+	}
+	clone.removed = append(clone.removed, t.removed...)
+	return clone
+}
+
+func podKey(pod *apiv1.Pod) string {
+	return pod.Namespace + "/" + pod.Name
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeRemainingPdbTracker(t *testing.T) {
+	free := testPod("free")
+	blocked := testPod("blocked")
+	serial := testPod("serial")
+	tracker := NewFakeRemainingPdbTracker().BlockPods(drain.NotEnoughPdb, blocked).RemoveSerially(serial)
+
+	canRemove, inParallel, blockingPod := tracker.CanRemovePods([]*apiv1.Pod{free})
+	assert.True(t, canRemove)
+	assert.True(t, inParallel)
+	assert.Nil(t, blockingPod)
+
+	canRemove, inParallel, blockingPod = tracker.CanRemovePods([]*apiv1.Pod{free, serial})
+	assert.True(t, canRemove)
+	assert.False(t, inParallel)
+	assert.Equal(t, &drain.BlockingPod{Pod: serial, Reason: drain.NotEnoughPdb}, blockingPod)
+
+	canRemove, _, blockingPod = tracker.CanRemovePods([]*apiv1.Pod{free, blocked})
+	assert.False(t, canRemove)
+	assert.Equal(t, &drain.BlockingPod{Pod: blocked, Reason: drain.NotEnoughPdb}, blockingPod)
+	assert.Equal(t, 3, tracker.CanRemovePodsCalls())
+
+	clone := tracker.Clone()
+	tracker.RemovePods([]*apiv1.Pod{free})
+	assert.Equal(t, []*apiv1.Pod{free}, tracker.RemovedPods())
+	assert.Empty(t, clone.(*FakeRemainingPdbTracker).RemovedPods())
+	canRemove, _, _ = clone.CanRemovePods([]*apiv1.Pod{blocked})
+	assert.False(t, canRemove)
+
+	tracker.Clear()
+	assert.Empty(t, tracker.RemovedPods())
+	canRemove, _, _ = tracker.CanRemovePods([]*apiv1.Pod{blocked})
+	assert.True(t, canRemove)
+}
+
+func testPod(name string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "ns",
+		},
+	}
+}
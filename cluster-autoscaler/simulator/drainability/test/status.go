@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// Drainer evaluates drainability of pods. It is implemented both by single
+// rules and by rules.Rules, so custom rules can be tested on their own as well
+// as combined with rules.Default, e.g.
+// append(rules.Default(deleteOptions), rules.WithPriority(rule, rules.BudgetPriority)).
+type Drainer interface {
+	Drainable(*drainability.DrainContext, *apiv1.Pod, *framework.NodeInfo) drainability.Status
+}
+
+// TestCase is a single case of RunDrainableTests.
+type TestCase struct {
+	// Name identifies the case in subtests.
+	Name string
+	// Pod is the pod to evaluate.
+	Pod *apiv1.Pod
+	// NodeInfo is the node the pod is drained from. It is optional.
+	NodeInfo *framework.NodeInfo
+	// DrainContext is passed to the Drainer. If it is nil, NewDrainContext
+	// with Options is used.
+	DrainContext *drainability.DrainContext
+	// Options are used to build the DrainContext if it isn't set.
+	Options []DrainContextOption
+	// Want is the expected status, compared with AssertStatus.
+	Want drainability.Status
+}
+
+// RunDrainableTests evaluates each case with the drainer in a subtest and
+// asserts that it returns the expected status.
+func RunDrainableTests(t *testing.T, drainer Drainer, cases []TestCase) {
+	t.Helper()
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			drainCtx := tc.DrainContext
+			if drainCtx == nil {
+				drainCtx = NewDrainContext(t, tc.Options...)
+			}
+			got := drainer.Drainable(drainCtx, tc.Pod, tc.NodeInfo)
+			AssertStatus(t, got, tc.Want)
+		})
+	}
+}
+
+// AssertStatus fails the test if the status differs from the expected one.
+// Errors are compared by their messages if the expected status has one, and
+// ignored otherwise, so that tests don't depend on error wording unless they
+// want to.
+func AssertStatus(t testing.TB, got, want drainability.Status) {
+	t.Helper()
+	if want.Error != nil {
+		if got.Error == nil {
+			t.Errorf("Drainable(): got no error, want %q", want.Error)
+		} else if got.Error.Error() != want.Error.Error() {
+			t.Errorf("Drainable(): got error %q, want %q", got.Error, want.Error)
+		}
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(drainability.Status{}, "Error"), cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("Drainable(): got status diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestAssertStatus(t *testing.T) {
+	for desc, tc := range map[string]struct {
+		got      drainability.Status
+		want     drainability.Status
+		wantFail bool
+	}{
+		"equal": {
+			got:  drainability.NewBlockedStatus(drain.NotEnoughPdb, nil),
+			want: drainability.NewBlockedStatus(drain.NotEnoughPdb, nil),
+		},
+		"different outcome": {
+			got:      drainability.NewSkipStatus(),
+			want:     drainability.NewDrainableStatus(),
+			wantFail: true,
+		},
+		"different reason": {
+			got:      drainability.NewBlockedStatus(drain.NotEnoughPdb, nil),
+			want:     drainability.NewBlockedStatus(drain.NotReplicated, nil),
+			wantFail: true,
+		},
+		"unexpected error is ignored": {
+			got:  drainability.NewBlockedStatus(drain.NotEnoughPdb, fmt.Errorf("no budget")),
+			want: drainability.NewBlockedStatus(drain.NotEnoughPdb, nil),
+		},
+		"matching error": {
+			got:  drainability.NewBlockedStatus(drain.NotEnoughPdb, fmt.Errorf("no budget")),
+			want: drainability.NewBlockedStatus(drain.NotEnoughPdb, fmt.Errorf("no budget")),
+		},
+		"different error": {
+			got:      drainability.NewBlockedStatus(drain.NotEnoughPdb, fmt.Errorf("no budget")),
+			want:     drainability.NewBlockedStatus(drain.NotEnoughPdb, fmt.Errorf("no luck")),
+			wantFail: true,
+		},
+		"missing error": {
+			got:      drainability.NewBlockedStatus(drain.NotEnoughPdb, nil),
+			want:     drainability.NewBlockedStatus(drain.NotEnoughPdb, fmt.Errorf("no budget")),
+			wantFail: true,
+		},
+		"empty and nil overrides": {
+			got:  drainability.Status{Outcome: drainability.DrainOk, Overrides: []drainability.OutcomeType{}},
+			want: drainability.NewDrainableStatus(),
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			recorder := &recordingT{}
+			AssertStatus(recorder, tc.got, tc.want)
+			if recorder.failed != tc.wantFail {
+				t.Errorf("AssertStatus(%v, %v): failed = %v, want %v", tc.got, tc.want, recorder.failed, tc.wantFail)
+			}
+		})
+	}
+}
+
+func TestRunDrainableTests(t *testing.T) {
+	pod := testPod("pod")
+	tracker := NewFakeRemainingPdbTracker().BlockPods(drain.NotEnoughPdb, pod)
+	RunDrainableTests(t, pdbRule{}, []TestCase{
+		{
+			Name: "default context",
+			Pod:  pod,
+			Want: drainability.NewUndefinedStatus(),
+		},
+		{
+			Name:    "options",
+			Pod:     pod,
+			Options: []DrainContextOption{WithRemainingPdbTracker(tracker)},
+			Want:    drainability.NewBlockedStatus(drain.NotEnoughPdb, nil),
+		},
+		{
+			Name:         "context",
+			Pod:          pod,
+			DrainContext: &drainability.DrainContext{RemainingPdbTracker: tracker},
+			Want:         drainability.NewBlockedStatus(drain.NotEnoughPdb, nil),
+		},
+	})
+}
+
+// recordingT records failures instead of failing the test.
+type recordingT struct {
+	testing.TB
+	failed bool
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(string, ...interface{}) {
+	t.failed = true
+}
+
+// pdbRule blocks pods which can't be removed according to RemainingPdbTracker.
+type pdbRule struct{}
+
+func (pdbRule) Drainable(drainCtx *drainability.DrainContext, pod *apiv1.Pod, _ *framework.NodeInfo) drainability.Status {
+	if canRemove, _, blockingPod := drainCtx.RemainingPdbTracker.CanRemovePods([]*apiv1.Pod{pod}); !canRemove {
+		return drainability.NewBlockedStatus(blockingPod.Reason, nil)
+	}
+	return drainability.NewUndefinedStatus()
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trace
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	klog "k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// RuleEvaluation is the status returned by a single drainability rule for a
+// pod. Statuses of rules in shadow mode are recorded, but not enforced.
+type RuleEvaluation struct {
+	Rule           string   `json:"rule"`
+	Shadow         bool     `json:"shadow,omitempty"`
+	Outcome        string   `json:"outcome"`
+	Overrides      []string `json:"overrides,omitempty"`
+	BlockingReason string   `json:"blockingReason,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// PodTrace records all rules evaluated for a pod, in the order of evaluation,
+// and the final outcome. If rules in shadow mode would have changed the
+// outcome, the outcome they would have decided is recorded as well.
+type PodTrace struct {
+	Pod             string           `json:"pod"`
+	Rules           []RuleEvaluation `json:"rules"`
+	Outcome         string           `json:"outcome"`
+	DecidedBy       string           `json:"decidedBy,omitempty"`
+	ShadowOutcome   string           `json:"shadowOutcome,omitempty"`
+	ShadowDecidedBy string           `json:"shadowDecidedBy,omitempty"`
+}
+
+// NodeTrace records drainability evaluations of pods on a node. A pod can be
+// evaluated several times per loop, e.g. by different simulations.
+type NodeTrace struct {
+	Node string     `json:"node"`
+	Pods []PodTrace `json:"pods"`
+}
+
+// Trace records drainability evaluations in a single CA loop.
+type Trace struct {
+	Nodes []NodeTrace `json:"nodes"`
+}
+
+var (
+	enabled atomic.Bool
+
+	mutex sync.Mutex
+	nodes = make(map[string][]PodTrace)
+)
+
+// SetEnabled enables or disables tracing of drainability evaluations.
+func SetEnabled(e bool) {
+	enabled.Store(e)
+}
+
+// Enabled tells if drainability evaluations are traced.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// NewPodTrace starts a trace of a drainability evaluation of the pod. It
+// returns nil if tracing is disabled, in which case all PodTrace methods are
+// no-ops.
+func NewPodTrace(pod *apiv1.Pod) *PodTrace {
+	if !Enabled() {
+		return nil
+	}
+	return &PodTrace{Pod: pod.Namespace + "/" + pod.Name}
+}
+
+// AddRule records the status returned by a rule.
+func (t *PodTrace) AddRule(rule string, status drainability.Status) {
+	t.addRule(rule, false, status)
+}
+
+// AddShadowRule records the status returned by a rule in shadow mode.
+func (t *PodTrace) AddShadowRule(rule string, status drainability.Status) {
+	t.addRule(rule, true, status)
+}
+
+func (t *PodTrace) addRule(rule string, shadow bool, status drainability.Status) {
+	if t == nil {
+		return
+	}
+	evaluation := RuleEvaluation{
+		Rule:           rule,
+		Shadow:         shadow,
+		Outcome:        status.Outcome.String(),
+		BlockingReason: blockingReason(status),
+	}
+	for _, override := range status.Overrides {
+		evaluation.Overrides = append(evaluation.Overrides, override.String())
+	}
+	if status.Error != nil {
+		evaluation.Error = status.Error.Error()
+	}
+	t.Rules = append(t.Rules, evaluation)
+}
+
+// SetShadowOutcome records the status that would have been decided by a rule
+// in shadow mode, if it was enforced.
+func (t *PodTrace) SetShadowOutcome(decidedBy string, status drainability.Status) {
+	if t == nil {
+		return
+	}
+	t.ShadowOutcome = status.Outcome.String()
+	t.ShadowDecidedBy = decidedBy
+}
+
+// Finish records the final status of the pod, decided by a given rule, and
+// adds the trace to the trace of the current loop. The rule is empty if no
+// rule decided the outcome.
+func (t *PodTrace) Finish(pod *apiv1.Pod, nodeInfo *framework.NodeInfo, decidedBy string, status drainability.Status) {
+	if t == nil {
+		return
+	}
+	t.Outcome = status.Outcome.String()
+	t.DecidedBy = decidedBy
+	node := pod.Spec.NodeName
+	if nodeInfo != nil && nodeInfo.Node() != nil {
+		node = nodeInfo.Node().Name
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	nodes[node] = append(nodes[node], *t)
+}
+
+// Collect returns the trace recorded since the previous call, with nodes
+// sorted by name, and starts a new one.
+func Collect() Trace {
+	mutex.Lock()
+	recorded := nodes
+	nodes = make(map[string][]PodTrace)
+	mutex.Unlock()
+
+	var trace Trace
+	for node, pods := range recorded {
+		trace.Nodes = append(trace.Nodes, NodeTrace{Node: node, Pods: pods})
+	}
+	sort.Slice(trace.Nodes, func(i, j int) bool {
+		return trace.Nodes[i].Node < trace.Nodes[j].Node
+	})
+	return trace
+}
+
+// Flush logs the trace recorded since the previous call as a single
+// structured log entry, if tracing is enabled and anything was recorded. It
+// should be called once per CA loop.
+func Flush() {
+	if !Enabled() {
+		return
+	}
+	trace := Collect()
+	if len(trace.Nodes) == 0 {
+		return
+	}
+	klog.InfoS("Drainability trace", "trace", trace)
+}
+
+func blockingReason(status drainability.Status) string {
+	if status.Outcome != drainability.BlockDrain {
+		return ""
+	}
+	if status.CustomBlockingReason != "" {
+		return string(status.CustomBlockingReason)
+	}
+	return status.BlockingReason.String()
+}
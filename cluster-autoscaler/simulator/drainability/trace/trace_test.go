@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trace
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
+	"k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+func TestPodTrace(t *testing.T) {
+	SetEnabled(true)
+	defer SetEnabled(false)
+	defer Collect()
+
+	pod := func(name, nodeName string) *apiv1.Pod {
+		return &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"}, Spec: apiv1.PodSpec{NodeName: nodeName}}
+	}
+	nodeInfo := framework.NewNodeInfo()
+	nodeInfo.SetNode(test.BuildTestNode("n1", 1000, 1000))
+
+	t1 := NewPodTrace(pod("p1", "n2"))
+	t1.AddRule("Custom", drainability.Status{Outcome: drainability.BlockDrain, BlockingReason: drain.CustomRuleReason, CustomBlockingReason: "example.com/Pending"})
+	t1.Finish(pod("p1", "n2"), nil, "Custom", drainability.NewBlockedStatus(drain.CustomRuleReason, nil))
+	// The node of the NodeInfo takes precedence over the node of the pod.
+	t2 := NewPodTrace(pod("p2", "n2"))
+	t2.AddRule("Skip", drainability.NewSkipStatus())
+	t2.Finish(pod("p2", "n2"), nodeInfo, "Skip", drainability.NewSkipStatus())
+
+	want := Trace{Nodes: []NodeTrace{
+		{Node: "n1", Pods: []PodTrace{{Pod: "ns/p2", Rules: []RuleEvaluation{{Rule: "Skip", Outcome: "SkipDrain"}}, Outcome: "SkipDrain", DecidedBy: "Skip"}}},
+		{Node: "n2", Pods: []PodTrace{{Pod: "ns/p1", Rules: []RuleEvaluation{{Rule: "Custom", Outcome: "BlockDrain", BlockingReason: "example.com/Pending"}}, Outcome: "BlockDrain", DecidedBy: "Custom"}}},
+	}}
+	if diff := cmp.Diff(want, Collect()); diff != "" {
+		t.Errorf("Collect(): got diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestPodTraceDisabled(t *testing.T) {
+	SetEnabled(false)
+	pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+	podTrace := NewPodTrace(pod)
+	if podTrace != nil {
+		t.Fatalf("NewPodTrace() with tracing disabled: got %v, want nil", podTrace)
+	}
+	podTrace.AddRule("Rule", drainability.NewBlockedStatus(drain.NotReplicated, fmt.Errorf("not replicated")))
+	podTrace.Finish(pod, nil, "Rule", drainability.NewBlockedStatus(drain.NotReplicated, nil))
+	if diff := cmp.Diff(Trace{}, Collect()); diff != "" {
+		t.Errorf("Collect(): got diff (-want +got):\n%s", diff)
+	}
+}
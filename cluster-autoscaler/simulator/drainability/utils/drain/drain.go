@@ -0,0 +1,413 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PodLongTerminatingExtraThreshold - time after which a pod, that is terminating and that has run over its terminationGracePeriod, should be ignored and considered as deleted
+	PodLongTerminatingExtraThreshold = 30 * time.Second
+)
+
+const (
+	// PodSafeToEvictKey - annotation that ignores constraints to evict a pod like not being replicated, being on
+	// kube-system namespace or having a local storage.
+	PodSafeToEvictKey = "cluster-autoscaler.kubernetes.io/safe-to-evict"
+	// SafeToEvictLocalVolumesKey - annotation that ignores (doesn't block on) a local storage volume during node scale down
+	SafeToEvictLocalVolumesKey = "cluster-autoscaler.kubernetes.io/safe-to-evict-local-volumes"
+	// PodDrainGracePeriodKey - annotation that overrides the maximum graceful termination time (in seconds) used when
+	// evicting a pod during node scale down.
+	PodDrainGracePeriodKey = "cluster-autoscaler.kubernetes.io/drain-grace-period"
+	// PodPreStopDurationKey - annotation with the expected time (in seconds) it takes the pod to shut down once evicted,
+	// e.g. the duration of its preStop hooks. It is used to estimate how long it takes to drain the node of the pod.
+	PodPreStopDurationKey = "cluster-autoscaler.kubernetes.io/pre-stop-duration"
+)
+
+const (
+	// ToBeDeletedTaint - taint put by Cluster Autoscaler on nodes it's draining and deleting.
+	ToBeDeletedTaint = "ToBeDeletedByClusterAutoscaler"
+)
+
+// BlockingPod represents a pod which is blocking the scale down of a node.
+type BlockingPod struct {
+	Pod *apiv1.Pod
+	// Reason is the reason why the pod is blocking scale down. It is
+	// CustomRuleReason for reasons identified only by CustomReason.
+	Reason BlockingPodReason
+	// CustomReason identifies the reason if it was provided by a custom
+	// drainability rule. If empty, the reason is identified by Reason.
+	CustomReason BlockingReasonID
+	// Details contains optional structured details of the reason.
+	Details map[string]string
+	// Rule is the name of the drainability rule which blocked the pod, if known.
+	Rule string
+	// Remediation is an optional hint on how to unblock the pod.
+	Remediation Remediation
+}
+
+// ReasonID returns the identifier of the reason why the pod is blocking the
+// scale down of a node.
+func (b *BlockingPod) ReasonID() BlockingReasonID {
+	if b.CustomReason != "" {
+		return b.CustomReason
+	}
+	return b.Reason.ID()
+}
+
+// BlockingPodReason represents a reason why a pod is blocking the scale down of a node.
+type BlockingPodReason int
+
+const (
+	// NoReason - sanity check, this should never be set explicitly. If this is found in the wild, it means that it was
+	// implicitly initialized and might indicate a bug.
+	NoReason BlockingPodReason = iota
+	// ControllerNotFound - pod is blocking scale down because its controller can't be found.
+	ControllerNotFound
+	// MinReplicasReached - pod is blocking scale down because its controller already has the minimum number of replicas.
+	MinReplicasReached
+	// NotReplicated - pod is blocking scale down because it's not replicated.
+	NotReplicated
+	// LocalStorageRequested - pod is blocking scale down because it requests local storage.
+	LocalStorageRequested
+	// NotSafeToEvictAnnotation - pod is blocking scale down because it has a "not safe to evict" annotation.
+	NotSafeToEvictAnnotation
+	// UnmovableKubeSystemPod - pod is blocking scale down because it's a non-daemonset, non-mirrored, non-pdb-assigned kube-system pod.
+	UnmovableKubeSystemPod
+	// NotEnoughPdb - pod is blocking scale down because it doesn't have enough PDB left.
+	NotEnoughPdb
+	// UnexpectedError - pod is blocking scale down because of an unexpected error.
+	UnexpectedError
+	// NonDrainableNamespace - pod is blocking scale down because its namespace is configured as never drainable.
+	NonDrainableNamespace
+	// RejectedByWebhook - pod is blocking scale down because the drainability webhook didn't allow its drain.
+	RejectedByWebhook
+	// DebugContainerRunning - pod is blocking scale down because it has a running ephemeral container, e.g. a debug session.
+	DebugContainerRunning
+	// EvictionBackoff - pod is blocking scale down because its recent evictions failed and it is backed off.
+	EvictionBackoff
+	// HeadroomReserved - pod is blocking scale down because it's a placeholder keeping spare capacity in the node group.
+	HeadroomReserved
+	// OutsideDisruptionWindow - pod is blocking scale down because its disruption window is closed.
+	OutsideDisruptionWindow
+	// DeniedByAdmissionWebhook - pod is blocking scale down because its recent evictions were denied by an admission
+	// webhook and it is backed off.
+	DeniedByAdmissionWebhook
+	// HostProcessPod - pod is blocking scale down because it runs Windows HostProcess containers, which are tied to the
+	// node they run on.
+	HostProcessPod
+	// PinnedToNode - pod is blocking scale down because its node selector or required node affinity doesn't match any
+	// other node.
+	PinnedToNode
+	// StaticPod - pod is blocking scale down because it's a kubelet static pod and nodes with static pods aren't
+	// removed.
+	StaticPod
+	// SingletonInfraPod - pod is blocking scale down because it's a designated singleton infrastructure pod, e.g. a
+	// CSI controller, and no replacement of it is running and ready on another node.
+	SingletonInfraPod
+	// OwnerRolloutInProgress - pod is blocking scale down because its ReplicaSet is being replaced by a newer one of the
+	// same Deployment, which doesn't have all of its replicas available yet.
+	OwnerRolloutInProgress
+	// CustomRuleReason - pod is blocking scale down for a reason provided by a custom drainability rule, which isn't
+	// one of the reasons above.
+	CustomRuleReason
+)
+
+var blockingPodReasonNames = map[BlockingPodReason]string{
+	NoReason:                 "NoReason",
+	ControllerNotFound:       "ControllerNotFound",
+	MinReplicasReached:       "MinReplicasReached",
+	NotReplicated:            "NotReplicated",
+	LocalStorageRequested:    "LocalStorageRequested",
+	NotSafeToEvictAnnotation: "NotSafeToEvictAnnotation",
+	UnmovableKubeSystemPod:   "UnmovableKubeSystemPod",
+	NotEnoughPdb:             "NotEnoughPdb",
+	UnexpectedError:          "UnexpectedError",
+	NonDrainableNamespace:    "NonDrainableNamespace",
+	RejectedByWebhook:        "RejectedByWebhook",
+	DebugContainerRunning:    "DebugContainerRunning",
+	EvictionBackoff:          "EvictionBackoff",
+	HeadroomReserved:         "HeadroomReserved",
+	OutsideDisruptionWindow:  "OutsideDisruptionWindow",
+	DeniedByAdmissionWebhook: "DeniedByAdmissionWebhook",
+	HostProcessPod:           "HostProcessPod",
+	PinnedToNode:             "PinnedToNode",
+	StaticPod:                "StaticPod",
+	SingletonInfraPod:        "SingletonInfraPod",
+	OwnerRolloutInProgress:   "OwnerRolloutInProgress",
+	CustomRuleReason:         "CustomRuleReason",
+}
+
+var blockingPodReasonsByID = func() map[BlockingReasonID]BlockingPodReason {
+	reasons := make(map[BlockingReasonID]BlockingPodReason, len(blockingPodReasonNames))
+	for reason, name := range blockingPodReasonNames {
+		reasons[BlockingReasonID(name)] = reason
+	}
+	return reasons
+}()
+
+// String returns a human readable name of the BlockingPodReason.
+func (r BlockingPodReason) String() string {
+	if name, found := blockingPodReasonNames[r]; found {
+		return name
+	}
+	return fmt.Sprintf("BlockingPodReason(%d)", int(r))
+}
+
+// ID returns the identifier of the BlockingPodReason.
+func (r BlockingPodReason) ID() BlockingReasonID {
+	return BlockingReasonID(r.String())
+}
+
+// BlockingReasonID is an extensible identifier of the reason why a pod is
+// blocking the scale down of a node. Identifiers of the reasons known to
+// Cluster Autoscaler are the names of BlockingPodReason values, custom
+// drainability rules can use their own, e.g. "example.com/MigrationPending".
+type BlockingReasonID string
+
+// LegacyReason returns the BlockingPodReason with the identifier, or
+// CustomRuleReason if the identifier isn't one of them.
+func (id BlockingReasonID) LegacyReason() BlockingPodReason {
+	if reason, found := blockingPodReasonsByID[id]; found {
+		return reason
+	}
+	return CustomRuleReason
+}
+
+// Remediation is a machine-readable hint on how to make a pod stop blocking
+// the scale down of its node. Custom drainability rules can use their own,
+// e.g. "example.com/FinishMigration".
+type Remediation string
+
+const (
+	// AddSafeToEvictAnnotation - annotate the pod with "cluster-autoscaler.kubernetes.io/safe-to-evict": "true".
+	AddSafeToEvictAnnotation Remediation = "AddSafeToEvictAnnotation"
+	// IncreaseReplicas - increase the number of replicas of the pod's controller above --min-replica-count.
+	IncreaseReplicas Remediation = "IncreaseReplicas"
+	// AddPodDisruptionBudget - cover the kube-system pod with a PodDisruptionBudget.
+	AddPodDisruptionBudget Remediation = "AddPodDisruptionBudget"
+	// IncreasePdbMaxUnavailable - increase maxUnavailable, or decrease minAvailable, of the pod's PodDisruptionBudget.
+	IncreasePdbMaxUnavailable Remediation = "IncreasePdbMaxUnavailable"
+	// AllowNamespaceDrain - remove the pod's namespace from the never drainable namespaces.
+	AllowNamespaceDrain Remediation = "AllowNamespaceDrain"
+	// CheckDrainabilityWebhook - check why the drainability webhook doesn't allow the drain of the pod.
+	CheckDrainabilityWebhook Remediation = "CheckDrainabilityWebhook"
+	// CheckAdmissionWebhook - check why an admission webhook denies evictions of the pod.
+	CheckAdmissionWebhook Remediation = "CheckAdmissionWebhook"
+	// EndDebugSession - end the debug session running in an ephemeral container of the pod.
+	EndDebugSession Remediation = "EndDebugSession"
+	// WaitForEvictionBackoff - wait until the backoff of the pod's failed evictions passes.
+	WaitForEvictionBackoff Remediation = "WaitForEvictionBackoff"
+	// WaitForDisruptionWindow - wait until the pod's disruption window opens.
+	WaitForDisruptionWindow Remediation = "WaitForDisruptionWindow"
+	// RelaxNodeAffinity - relax the pod's node selector or required node affinity, so that it matches other nodes.
+	RelaxNodeAffinity Remediation = "RelaxNodeAffinity"
+	// AddStaticPodDrainableAnnotation - annotate the static pod with
+	// "cluster-autoscaler.kubernetes.io/static-pod-drainable": "true".
+	AddStaticPodDrainableAnnotation Remediation = "AddStaticPodDrainableAnnotation"
+	// StartReplacementPod - run a ready replacement of the singleton infrastructure pod on another node.
+	StartReplacementPod Remediation = "StartReplacementPod"
+	// WaitForRollout - wait until the rollout of the pod's Deployment makes the new replicas available.
+	WaitForRollout Remediation = "WaitForRollout"
+)
+
+var blockingPodReasonRemediations = map[BlockingPodReason]Remediation{
+	ControllerNotFound:       AddSafeToEvictAnnotation,
+	MinReplicasReached:       IncreaseReplicas,
+	NotReplicated:            AddSafeToEvictAnnotation,
+	LocalStorageRequested:    AddSafeToEvictAnnotation,
+	NotSafeToEvictAnnotation: AddSafeToEvictAnnotation,
+	UnmovableKubeSystemPod:   AddPodDisruptionBudget,
+	NotEnoughPdb:             IncreasePdbMaxUnavailable,
+	NonDrainableNamespace:    AllowNamespaceDrain,
+	RejectedByWebhook:        CheckDrainabilityWebhook,
+	DebugContainerRunning:    EndDebugSession,
+	EvictionBackoff:          WaitForEvictionBackoff,
+	OutsideDisruptionWindow:  WaitForDisruptionWindow,
+	DeniedByAdmissionWebhook: CheckAdmissionWebhook,
+	PinnedToNode:             RelaxNodeAffinity,
+	StaticPod:                AddStaticPodDrainableAnnotation,
+	SingletonInfraPod:        StartReplacementPod,
+	OwnerRolloutInProgress:   WaitForRollout,
+}
+
+// Remediation returns the default hint on how to unblock a pod blocked for
+// the reason, or an empty one if there is none.
+func (r BlockingPodReason) Remediation() Remediation {
+	return blockingPodReasonRemediations[r]
+}
+
+// ControllerRef returns the OwnerReference to pod's controller.
+func ControllerRef(pod *apiv1.Pod) *metav1.OwnerReference {
+	return metav1.GetControllerOf(pod)
+}
+
+// IsPodTerminal checks whether the pod is in a terminal state.
+func IsPodTerminal(pod *apiv1.Pod) bool {
+	// pod will never be restarted
+	if pod.Spec.RestartPolicy == apiv1.RestartPolicyNever && (pod.Status.Phase == apiv1.PodSucceeded || pod.Status.Phase == apiv1.PodFailed) {
+		return true
+	}
+	// pod has run to completion and succeeded
+	if pod.Spec.RestartPolicy == apiv1.RestartPolicyOnFailure && pod.Status.Phase == apiv1.PodSucceeded {
+		return true
+	}
+	// kubelet has rejected this pod, due to eviction or some other constraint
+	return pod.Status.Phase == apiv1.PodFailed
+}
+
+// IsPodCompleted checks whether all containers of the pod have terminated and
+// won't be restarted, i.e. the pod is Succeeded or Failed. Completed pods don't
+// use any resources of their node.
+func IsPodCompleted(pod *apiv1.Pod) bool {
+	return pod.Status.Phase == apiv1.PodSucceeded || pod.Status.Phase == apiv1.PodFailed
+}
+
+// HasBlockingLocalStorage returns true if pod has any local storage
+// without pod annotation `<SafeToEvictLocalVolumeKey>: <volume-name-1>,<volume-name-2>...`
+func HasBlockingLocalStorage(pod *apiv1.Pod) bool {
+	return len(BlockingLocalVolumes(pod)) > 0
+}
+
+// BlockingLocalVolumes returns names of the pod's local volumes which are not
+// listed in the `<SafeToEvictLocalVolumeKey>` annotation.
+func BlockingLocalVolumes(pod *apiv1.Pod) []string {
+	var blocking []string
+	isNonBlocking := NonBlockingVolumes(pod)
+	for _, volume := range pod.Spec.Volumes {
+		if isLocalVolume(&volume) && !isNonBlocking[volume.Name] {
+			blocking = append(blocking, volume.Name)
+		}
+	}
+	return blocking
+}
+
+// NonBlockingVolumes returns names of the pod's volumes listed in the
+// `<SafeToEvictLocalVolumeKey>` annotation.
+func NonBlockingVolumes(pod *apiv1.Pod) map[string]bool {
+	isNonBlocking := map[string]bool{}
+	annotationVal := pod.GetAnnotations()[SafeToEvictLocalVolumesKey]
+	if annotationVal != "" {
+		vols := strings.Split(annotationVal, ",")
+		for _, v := range vols {
+			if v = strings.TrimSpace(v); v != "" {
+				isNonBlocking[v] = true
+			}
+		}
+	}
+	return isNonBlocking
+}
+
+func isLocalVolume(volume *apiv1.Volume) bool {
+	return volume.HostPath != nil || (volume.EmptyDir != nil && volume.EmptyDir.Medium != apiv1.StorageMediumMemory)
+}
+
+// HasSafeToEvictAnnotation checks if pod has PodSafeToEvictKey annotation.
+func HasSafeToEvictAnnotation(pod *apiv1.Pod) bool {
+	return pod.GetAnnotations()[PodSafeToEvictKey] == "true"
+}
+
+// HasNotSafeToEvictAnnotation checks if pod has PodSafeToEvictKey annotation
+// set to false.
+func HasNotSafeToEvictAnnotation(pod *apiv1.Pod) bool {
+	return pod.GetAnnotations()[PodSafeToEvictKey] == "false"
+}
+
+// IsPodLongTerminating checks if a pod has been terminating for a long time (pod's terminationGracePeriod + an additional const buffer)
+func IsPodLongTerminating(pod *apiv1.Pod, currentTime time.Time) bool {
+	return IsPodLongTerminatingWithThreshold(pod, currentTime, PodLongTerminatingExtraThreshold)
+}
+
+// IsPodLongTerminatingWithThreshold checks if a pod has been terminating for longer than pod's terminationGracePeriod + extraThreshold
+func IsPodLongTerminatingWithThreshold(pod *apiv1.Pod, currentTime time.Time, extraThreshold time.Duration) bool {
+	// pod has not even been deleted
+	if pod.DeletionTimestamp == nil {
+		return false
+	}
+
+	gracePeriod := pod.Spec.TerminationGracePeriodSeconds
+	if gracePeriod == nil {
+		defaultGracePeriod := int64(apiv1.DefaultTerminationGracePeriodSeconds)
+		gracePeriod = &defaultGracePeriod
+	}
+	return pod.DeletionTimestamp.Time.Add(time.Duration(*gracePeriod) * time.Second).Add(extraThreshold).Before(currentTime)
+}
+
+// IsAdmissionWebhookDenial tells if the error returned by an eviction, or another API request, means that the request
+// was denied by a validating admission webhook, i.e. the API server responded with 403 Forbidden and the message of the
+// webhook denial.
+func IsAdmissionWebhookDenial(err error) bool {
+	if !kube_errors.IsForbidden(err) {
+		return false
+	}
+	message := err.Error()
+	return strings.Contains(message, "admission webhook") && strings.Contains(message, "denied the request")
+}
+
+// GetPodDrainGracePeriod returns the grace period in seconds that should be used
+// when evicting the pod during node drain. The PodDrainGracePeriodKey annotation
+// takes precedence over maxGracefulTerminationSec. Otherwise, pod's
+// terminationGracePeriod is used, capped at maxGracefulTerminationSec.
+func GetPodDrainGracePeriod(pod *apiv1.Pod, maxGracefulTerminationSec int) int64 {
+	if gracePeriod, found := getDrainGracePeriodOverride(pod); found {
+		return gracePeriod
+	}
+	if pod.Spec.TerminationGracePeriodSeconds == nil {
+		return int64(apiv1.DefaultTerminationGracePeriodSeconds)
+	}
+	if *pod.Spec.TerminationGracePeriodSeconds < int64(maxGracefulTerminationSec) {
+		return *pod.Spec.TerminationGracePeriodSeconds
+	}
+	return int64(maxGracefulTerminationSec)
+}
+
+// GetPodDrainDuration returns the expected time in seconds it takes the pod to
+// terminate when evicted during node drain. It is the duration specified by the
+// PodPreStopDurationKey annotation, capped at the drain grace period of the
+// pod, or the drain grace period if the pod isn't annotated.
+func GetPodDrainDuration(pod *apiv1.Pod, maxGracefulTerminationSec int) int64 {
+	gracePeriod := GetPodDrainGracePeriod(pod, maxGracefulTerminationSec)
+	annotationVal, found := pod.GetAnnotations()[PodPreStopDurationKey]
+	if !found {
+		return gracePeriod
+	}
+	preStopDuration, err := strconv.ParseInt(annotationVal, 10, 64)
+	if err != nil || preStopDuration < 0 || preStopDuration > gracePeriod {
+		return gracePeriod
+	}
+	return preStopDuration
+}
+
+func getDrainGracePeriodOverride(pod *apiv1.Pod) (int64, bool) {
+	annotationVal, found := pod.GetAnnotations()[PodDrainGracePeriodKey]
+	if !found {
+		return 0, false
+	}
+	gracePeriod, err := strconv.ParseInt(annotationVal, 10, 64)
+	if err != nil || gracePeriod < 0 {
+		return 0, false
+	}
+	return gracePeriod, true
+}
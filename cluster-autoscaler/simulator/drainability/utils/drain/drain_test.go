@@ -0,0 +1,370 @@
+/*
+Copyright 2016 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsPodLongTerminating(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	twoMinGracePeriod := int64(2 * 60)
+	zeroGracePeriod := int64(0)
+
+	tests := []struct {
+		name string
+		pod  apiv1.Pod
+		want bool
+	}{
+		{
+			name: "No deletion timestamp",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: nil,
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &zeroGracePeriod,
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Just deleted no grace period defined",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: &metav1.Time{Time: testTime}, // default Grace Period is 30s so this pod can still be terminating
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: nil,
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Deleted for longer than PodLongTerminatingExtraThreshold with no grace period",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: &metav1.Time{Time: testTime.Add(-3 * PodLongTerminatingExtraThreshold)},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: nil,
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Just deleted with grace period defined to 0",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: &metav1.Time{Time: testTime},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &zeroGracePeriod,
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Deleted for longer than PodLongTerminatingExtraThreshold with grace period defined to 0",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: &metav1.Time{Time: testTime.Add(-2 * PodLongTerminatingExtraThreshold)},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &zeroGracePeriod,
+				},
+			},
+			want: true,
+		},
+		{
+			name: "Deleted for longer than PodLongTerminatingExtraThreshold but not longer than grace period (2 min)",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: &metav1.Time{Time: testTime.Add(-2 * PodLongTerminatingExtraThreshold)},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &twoMinGracePeriod,
+				},
+			},
+			want: false,
+		},
+		{
+			name: "Deleted for longer than grace period (2 min) and PodLongTerminatingExtraThreshold",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					DeletionTimestamp: &metav1.Time{Time: testTime.Add(-2*PodLongTerminatingExtraThreshold - time.Duration(twoMinGracePeriod)*time.Second)},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &twoMinGracePeriod,
+				},
+			},
+			want: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsPodLongTerminating(&tc.pod, testTime); got != tc.want {
+				t.Errorf("IsPodLongTerminating() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetPodDrainGracePeriod(t *testing.T) {
+	tenSecGracePeriod := int64(10)
+	fiveMinGracePeriod := int64(5 * 60)
+
+	tests := []struct {
+		name string
+		pod  apiv1.Pod
+		want int64
+	}{
+		{
+			name: "No grace period",
+			pod:  apiv1.Pod{},
+			want: int64(apiv1.DefaultTerminationGracePeriodSeconds),
+		},
+		{
+			name: "Grace period shorter than max",
+			pod: apiv1.Pod{
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &tenSecGracePeriod,
+				},
+			},
+			want: 10,
+		},
+		{
+			name: "Grace period longer than max",
+			pod: apiv1.Pod{
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &fiveMinGracePeriod,
+				},
+			},
+			want: 60,
+		},
+		{
+			name: "Annotation overrides max",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PodDrainGracePeriodKey: "300"},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &fiveMinGracePeriod,
+				},
+			},
+			want: 300,
+		},
+		{
+			name: "Annotation overrides pod grace period",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PodDrainGracePeriodKey: "5"},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &tenSecGracePeriod,
+				},
+			},
+			want: 5,
+		},
+		{
+			name: "Invalid annotation is ignored",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PodDrainGracePeriodKey: "5m"},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &tenSecGracePeriod,
+				},
+			},
+			want: 10,
+		},
+		{
+			name: "Negative annotation is ignored",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PodDrainGracePeriodKey: "-1"},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &tenSecGracePeriod,
+				},
+			},
+			want: 10,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := GetPodDrainGracePeriod(&tc.pod, 60); got != tc.want {
+				t.Errorf("GetPodDrainGracePeriod() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGetPodDrainDuration(t *testing.T) {
+	tenSecGracePeriod := int64(10)
+
+	tests := []struct {
+		name string
+		pod  apiv1.Pod
+		want int64
+	}{
+		{
+			name: "No annotation",
+			pod: apiv1.Pod{
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &tenSecGracePeriod,
+				},
+			},
+			want: 10,
+		},
+		{
+			name: "Pre-stop duration shorter than grace period",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PodPreStopDurationKey: "3"},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &tenSecGracePeriod,
+				},
+			},
+			want: 3,
+		},
+		{
+			name: "Pre-stop duration longer than grace period",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PodPreStopDurationKey: "20"},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &tenSecGracePeriod,
+				},
+			},
+			want: 10,
+		},
+		{
+			name: "Pre-stop duration capped at drain grace period override",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PodPreStopDurationKey: "8", PodDrainGracePeriodKey: "5"},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &tenSecGracePeriod,
+				},
+			},
+			want: 5,
+		},
+		{
+			name: "Invalid annotation is ignored",
+			pod: apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{PodPreStopDurationKey: "3s"},
+				},
+				Spec: apiv1.PodSpec{
+					TerminationGracePeriodSeconds: &tenSecGracePeriod,
+				},
+			},
+			want: 10,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := GetPodDrainDuration(&tc.pod, 60); got != tc.want {
+				t.Errorf("GetPodDrainDuration() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestIsAdmissionWebhookDenial(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "no error",
+		},
+		{
+			name: "denied by webhook",
+			err: &kube_errors.StatusError{ErrStatus: metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusForbidden,
+				Message: `admission webhook "deny.example.com" denied the request: pod is protected`,
+			}},
+			want: true,
+		},
+		{
+			name: "denied by webhook with custom code",
+			err: &kube_errors.StatusError{ErrStatus: metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusUnprocessableEntity,
+				Message: `admission webhook "deny.example.com" denied the request: pod is protected`,
+			}},
+		},
+		{
+			name: "forbidden by RBAC",
+			err:  kube_errors.NewForbidden(apiv1.Resource("pods/eviction"), "pod", fmt.Errorf("no permission")),
+		},
+		{
+			name: "rejected by disruption budget",
+			err:  kube_errors.NewTooManyRequests("budget exceeded", 0),
+		},
+		{
+			name: "not an API error",
+			err:  fmt.Errorf(`admission webhook "deny.example.com" denied the request`),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsAdmissionWebhookDenial(tc.err); got != tc.want {
+				t.Errorf("IsAdmissionWebhookDenial(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBlockingReasonID(t *testing.T) {
+	for _, reason := range []BlockingPodReason{NoReason, NotReplicated, DebugContainerRunning, CustomRuleReason} {
+		if got := reason.ID().LegacyReason(); got != reason {
+			t.Errorf("%v.ID().LegacyReason() = %v, want %v", reason, got, reason)
+		}
+	}
+	if got := BlockingReasonID("example.com/MigrationPending").LegacyReason(); got != CustomRuleReason {
+		t.Errorf("LegacyReason() = %v, want %v", got, CustomRuleReason)
+	}
+
+	for _, tc := range []struct {
+		pod  *BlockingPod
+		want BlockingReasonID
+	}{
+		{pod: &BlockingPod{Reason: NotReplicated}, want: "NotReplicated"},
+		{pod: &BlockingPod{Reason: CustomRuleReason, CustomReason: "example.com/MigrationPending"}, want: "example.com/MigrationPending"},
+	} {
+		if got := tc.pod.ReasonID(); got != tc.want {
+			t.Errorf("ReasonID() = %v, want %v", got, tc.want)
+		}
+	}
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package pod
 
 import (
+	"strconv"
+	"time"
+
 	"k8s.io/kubernetes/pkg/kubelet/types"
 
 	apiv1 "k8s.io/api/core/v1"
@@ -26,6 +29,9 @@ import (
 const (
 	// DaemonSetPodAnnotationKey - annotation use to informs the cluster-autoscaler controller when a pod needs to be considered as a Daemonset's Pod.
 	DaemonSetPodAnnotationKey = "cluster-autoscaler.kubernetes.io/daemonset-pod"
+	// HeadroomPlaceholderAnnotationKey - annotation of placeholder pods cluster-autoscaler simulates to keep spare capacity in node groups.
+	// The value is the id of the node group.
+	HeadroomPlaceholderAnnotationKey = "cluster-autoscaler.kubernetes.io/headroom-placeholder"
 )
 
 // IsDaemonSetPod returns true if the Pod should be considered as Pod managed by a DaemonSet
@@ -47,6 +53,13 @@ func IsMirrorPod(pod *apiv1.Pod) bool {
 	return found
 }
 
+// IsHeadroomPlaceholder checks whether the pod is a placeholder simulated to keep spare capacity in a node group.
+// Such pods don't exist in the cluster.
+func IsHeadroomPlaceholder(pod *apiv1.Pod) bool {
+	_, found := pod.Annotations[HeadroomPlaceholderAnnotationKey]
+	return found
+}
+
 // IsStaticPod returns true if the pod is a static pod.
 func IsStaticPod(pod *apiv1.Pod) bool {
 	if pod.Annotations != nil {
@@ -57,6 +70,32 @@ func IsStaticPod(pod *apiv1.Pod) bool {
 	return false
 }
 
+// DeletionCost returns the cost of deleting the pod, as set by the
+// controller.kubernetes.io/pod-deletion-cost annotation. Pods without a valid
+// annotation have a cost of 0.
+func DeletionCost(pod *apiv1.Pod) int32 {
+	value, found := pod.Annotations[apiv1.PodDeletionCost]
+	if !found {
+		return 0
+	}
+	cost, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return int32(cost)
+}
+
+// PendingSince returns the time since which the pod is unschedulable, according to its PodScheduled condition, or
+// its creation time if there is no such condition.
+func PendingSince(pod *apiv1.Pod) time.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == apiv1.PodScheduled && condition.Status == apiv1.ConditionFalse {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return pod.CreationTimestamp.Time
+}
+
 // FilterRecreatablePods filters pods that will be recreated by their controllers
 func FilterRecreatablePods(pods []*apiv1.Pod) []*apiv1.Pod {
 	filtered := make([]*apiv1.Pod, 0, len(pods))
@@ -18,11 +18,12 @@ package pod
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+	. "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 )
 
@@ -200,6 +201,88 @@ func TestIsStaticPod(t *testing.T) {
 	}
 }
 
+func TestDeletionCost(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        int32
+	}{
+		{
+			name: "no annotation",
+			want: 0,
+		},
+		{
+			name:        "positive cost",
+			annotations: map[string]string{apiv1.PodDeletionCost: "100"},
+			want:        100,
+		},
+		{
+			name:        "negative cost",
+			annotations: map[string]string{apiv1.PodDeletionCost: "-5"},
+			want:        -5,
+		},
+		{
+			name:        "invalid cost",
+			annotations: map[string]string{apiv1.PodDeletionCost: "high"},
+			want:        0,
+		},
+		{
+			name:        "cost out of range",
+			annotations: map[string]string{apiv1.PodDeletionCost: "4294967296"},
+			want:        0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			if got := DeletionCost(pod); got != tt.want {
+				t.Errorf("DeletionCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPendingSince(t *testing.T) {
+	created := time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC)
+	unschedulable := created.Add(time.Minute)
+	tests := []struct {
+		name       string
+		conditions []apiv1.PodCondition
+		want       time.Time
+	}{
+		{
+			name: "no conditions",
+			want: created,
+		},
+		{
+			name: "unschedulable",
+			conditions: []apiv1.PodCondition{
+				{Type: apiv1.PodReady, Status: apiv1.ConditionFalse, LastTransitionTime: metav1.NewTime(created)},
+				{Type: apiv1.PodScheduled, Status: apiv1.ConditionFalse, LastTransitionTime: metav1.NewTime(unschedulable)},
+			},
+			want: unschedulable,
+		},
+		{
+			name: "scheduled",
+			conditions: []apiv1.PodCondition{
+				{Type: apiv1.PodScheduled, Status: apiv1.ConditionTrue, LastTransitionTime: metav1.NewTime(unschedulable)},
+			},
+			want: created,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &apiv1.Pod{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(created)},
+				Status:     apiv1.PodStatus{Conditions: tt.conditions},
+			}
+			if got := PendingSince(pod); !got.Equal(tt.want) {
+				t.Errorf("PendingSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFilterRecreatablePods(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package test provides builders of objects for tests of the drainability
+// module. Cluster Autoscaler reuses them in its own test utilities.
+package test
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kube_types "k8s.io/kubernetes/pkg/kubelet/types"
+)
+
+// BuildTestPod creates a pod with specified resources.
+func BuildTestPod(name string, cpu int64, mem int64, options ...func(*apiv1.Pod)) *apiv1.Pod {
+	startTime := metav1.Unix(0, 0)
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:         types.UID(name),
+			Namespace:   "default",
+			Name:        name,
+			SelfLink:    fmt.Sprintf("/api/v1/namespaces/default/pods/%s", name),
+			Annotations: map[string]string{},
+		},
+		Spec: apiv1.PodSpec{
+			Containers: []apiv1.Container{
+				{
+					Resources: apiv1.ResourceRequirements{
+						Requests: apiv1.ResourceList{},
+					},
+				},
+			},
+		},
+		Status: apiv1.PodStatus{
+			StartTime: &startTime,
+		},
+	}
+
+	if cpu >= 0 {
+		pod.Spec.Containers[0].Resources.Requests[apiv1.ResourceCPU] = *resource.NewMilliQuantity(cpu, resource.DecimalSI)
+	}
+	if mem >= 0 {
+		pod.Spec.Containers[0].Resources.Requests[apiv1.ResourceMemory] = *resource.NewQuantity(mem, resource.DecimalSI)
+	}
+	for _, o := range options {
+		o(pod)
+	}
+	return pod
+}
+
+// BuildScheduledTestPod builds a scheduled test pod with a given spec
+func BuildScheduledTestPod(name string, cpu, memory int64, nodeName string) *apiv1.Pod {
+	p := BuildTestPod(name, cpu, memory)
+	p.Spec.NodeName = nodeName
+	return p
+}
+
+// SetStaticPodSpec sets pod spec to make it a static pod
+func SetStaticPodSpec(pod *apiv1.Pod) *apiv1.Pod {
+	pod.Annotations[kube_types.ConfigSourceAnnotationKey] = kube_types.FileSource
+	return pod
+}
+
+// SetMirrorPodSpec sets pod spec to make it a mirror pod
+func SetMirrorPodSpec(pod *apiv1.Pod) *apiv1.Pod {
+	pod.ObjectMeta.Annotations[kube_types.ConfigMirrorAnnotationKey] = "mirror"
+	return pod
+}
+
+// SetDSPodSpec sets pod spec to make it a DS pod
+func SetDSPodSpec(pod *apiv1.Pod) *apiv1.Pod {
+	pod.OwnerReferences = GenerateOwnerReferences("ds", "DaemonSet", "apps/v1", "api/v1/namespaces/default/daemonsets/ds")
+	return pod
+}
+
+// SetRSPodSpec sets pod spec to make it a RS pod
+func SetRSPodSpec(pod *apiv1.Pod, rsName string) *apiv1.Pod {
+	pod.OwnerReferences = GenerateOwnerReferences(rsName, "ReplicaSet", "extensions/v1beta1", types.UID(rsName))
+	return pod
+}
+
+// BuildTestNode creates a node with specified capacity.
+func BuildTestNode(name string, millicpu int64, mem int64) *apiv1.Node {
+	node := &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:     name,
+			SelfLink: fmt.Sprintf("/api/v1/nodes/%s", name),
+			Labels:   map[string]string{},
+		},
+		Spec: apiv1.NodeSpec{
+			ProviderID: name,
+		},
+		Status: apiv1.NodeStatus{
+			Capacity: apiv1.ResourceList{
+				apiv1.ResourcePods: *resource.NewQuantity(100, resource.DecimalSI),
+			},
+		},
+	}
+
+	if millicpu >= 0 {
+		node.Status.Capacity[apiv1.ResourceCPU] = *resource.NewMilliQuantity(millicpu, resource.DecimalSI)
+	}
+	if mem >= 0 {
+		node.Status.Capacity[apiv1.ResourceMemory] = *resource.NewQuantity(mem, resource.DecimalSI)
+	}
+
+	node.Status.Allocatable = apiv1.ResourceList{}
+	for k, v := range node.Status.Capacity {
+		node.Status.Allocatable[k] = v
+	}
+
+	return node
+}
+
+// GenerateOwnerReferences builds OwnerReferences with a single reference
+func GenerateOwnerReferences(name, kind, api string, uid types.UID) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		{
+			APIVersion:         api,
+			Kind:               kind,
+			Name:               name,
+			BlockOwnerDeletion: boolptr(true),
+			Controller:         boolptr(true),
+			UID:                uid,
+		},
+	}
+}
+
+func boolptr(val bool) *bool {
+	b := val
+	return &b
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timewindow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window is a set of minutes described by cron-like expressions, e.g.
+// "* 22-23,0-5 * * 1-5" for weekday nights. Each expression has five fields:
+// minute (0-59), hour (0-23), day of month (1-31), month (1-12) and day of
+// week (0-7, both 0 and 7 are Sunday). A field is either "*" or a comma
+// separated list of values and ranges, each optionally followed by a step,
+// e.g. "*/15" or "0-30/10". As in cron, if both day of month and day of week
+// are restricted, a day matching either of them matches. Multiple expressions
+// separated with ";" describe a union of windows. The expressions can be
+// prefixed with "CRON_TZ=<location>", e.g. "CRON_TZ=Europe/Warsaw", to be
+// evaluated in a time zone other than UTC.
+type Window struct {
+	location    *time.Location
+	expressions []expression
+}
+
+type expression struct {
+	minutes     field
+	hours       field
+	daysOfMonth field
+	months      field
+	daysOfWeek  field
+}
+
+// field is a bit set of the values matched by a field of an expression.
+type field struct {
+	bits       uint64
+	restricted bool
+}
+
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var fieldBounds = []bounds{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+const timeZonePrefix = "CRON_TZ="
+
+// Parse parses a Window from its spec.
+func Parse(spec string) (*Window, error) {
+	spec = strings.TrimSpace(spec)
+	window := &Window{location: time.UTC}
+	if strings.HasPrefix(spec, timeZonePrefix) {
+		zone, rest, _ := strings.Cut(strings.TrimPrefix(spec, timeZonePrefix), " ")
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %v", zone, err)
+		}
+		window.location = location
+		spec = rest
+	}
+	for _, expr := range strings.Split(spec, ";") {
+		parsed, err := parseExpression(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %q: %v", strings.TrimSpace(expr), err)
+		}
+		window.expressions = append(window.expressions, parsed)
+	}
+	return window, nil
+}
+
+// Contains tells if the minute of the timestamp is in the Window.
+func (w *Window) Contains(timestamp time.Time) bool {
+	timestamp = timestamp.In(w.location)
+	for _, expr := range w.expressions {
+		if expr.matches(timestamp) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseExpression(expr string) (expression, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(fieldBounds) {
+		return expression{}, fmt.Errorf("expected %d fields, got %d", len(fieldBounds), len(fields))
+	}
+	parsed := make([]field, len(fields))
+	for i, f := range fields {
+		var err error
+		if parsed[i], err = parseField(f, fieldBounds[i]); err != nil {
+			return expression{}, fmt.Errorf("invalid %s %q: %v", fieldBounds[i].name, f, err)
+		}
+	}
+	// Sunday is both 0 and 7.
+	if parsed[4].bits&(1<<7) != 0 {
+		parsed[4].bits |= 1
+	}
+	return expression{
+		minutes:     parsed[0],
+		hours:       parsed[1],
+		daysOfMonth: parsed[2],
+		months:      parsed[3],
+		daysOfWeek:  parsed[4],
+	}, nil
+}
+
+func parseField(f string, b bounds) (field, error) {
+	if f == "*" {
+		return field{bits: rangeBits(b.min, b.max, 1)}, nil
+	}
+	result := field{restricted: !strings.HasPrefix(f, "*")}
+	for _, part := range strings.Split(f, ",") {
+		valueRange, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step <= 0 {
+				return field{}, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+		low, high := b.min, b.max
+		if valueRange != "*" {
+			lowStr, highStr, isRange := strings.Cut(valueRange, "-")
+			var err error
+			if low, err = parseValue(lowStr, b); err != nil {
+				return field{}, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseValue(highStr, b); err != nil {
+					return field{}, err
+				}
+			} else if hasStep {
+				high = b.max
+			}
+			if low > high {
+				return field{}, fmt.Errorf("range start %d is after range end %d", low, high)
+			}
+		}
+		result.bits |= rangeBits(low, high, step)
+	}
+	return result, nil
+}
+
+func parseValue(value string, b bounds) (int, error) {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if parsed < b.min || parsed > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", parsed, b.min, b.max)
+	}
+	return parsed, nil
+}
+
+func rangeBits(low, high, step int) uint64 {
+	var bits uint64
+	for i := low; i <= high; i += step {
+		bits |= 1 << uint(i)
+	}
+	return bits
+}
+
+func (f field) contains(value int) bool {
+	return f.bits&(1<<uint(value)) != 0
+}
+
+func (e expression) matches(t time.Time) bool {
+	if !e.minutes.contains(t.Minute()) || !e.hours.contains(t.Hour()) || !e.months.contains(int(t.Month())) {
+		return false
+	}
+	dayOfMonth, dayOfWeek := e.daysOfMonth.contains(t.Day()), e.daysOfWeek.contains(int(t.Weekday()))
+	if e.daysOfMonth.restricted && e.daysOfWeek.restricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
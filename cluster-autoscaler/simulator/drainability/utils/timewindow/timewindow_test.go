@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package timewindow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContains(t *testing.T) {
+	// 2023-10-02 is a Monday.
+	monday := func(hour, minute int) time.Time {
+		return time.Date(2023, time.October, 2, hour, minute, 0, 0, time.UTC)
+	}
+	for desc, tc := range map[string]struct {
+		spec      string
+		timestamp time.Time
+		want      bool
+	}{
+		"always": {
+			spec:      "* * * * *",
+			timestamp: monday(12, 34),
+			want:      true,
+		},
+		"hour range": {
+			spec:      "* 9-17 * * *",
+			timestamp: monday(17, 59),
+			want:      true,
+		},
+		"outside hour range": {
+			spec:      "* 9-17 * * *",
+			timestamp: monday(18, 0),
+			want:      false,
+		},
+		"hour list": {
+			spec:      "* 22,23,0-5 * * *",
+			timestamp: monday(23, 0),
+			want:      true,
+		},
+		"minute step": {
+			spec:      "*/15 * * * *",
+			timestamp: monday(10, 45),
+			want:      true,
+		},
+		"outside minute step": {
+			spec:      "*/15 * * * *",
+			timestamp: monday(10, 46),
+			want:      false,
+		},
+		"step from value": {
+			spec:      "5/20 * * * *",
+			timestamp: monday(10, 25),
+			want:      true,
+		},
+		"weekdays": {
+			spec:      "* * * * 1-5",
+			timestamp: monday(10, 0),
+			want:      true,
+		},
+		"weekend": {
+			spec:      "* * * * 0,6",
+			timestamp: monday(10, 0),
+			want:      false,
+		},
+		"sunday as 7": {
+			spec:      "* * * * 7",
+			timestamp: monday(10, 0).AddDate(0, 0, 6),
+			want:      true,
+		},
+		"month": {
+			spec:      "* * * 11 *",
+			timestamp: monday(10, 0),
+			want:      false,
+		},
+		"day of month or day of week": {
+			spec:      "* * 15 * 1",
+			timestamp: monday(10, 0),
+			want:      true,
+		},
+		"day of month with unrestricted day of week": {
+			spec:      "* * 15 * *",
+			timestamp: monday(10, 0),
+			want:      false,
+		},
+		"union of expressions": {
+			spec:      "* 0-5 * * 1-5; * * * * 0,6",
+			timestamp: monday(10, 0).AddDate(0, 0, 5),
+			want:      true,
+		},
+		"outside union of expressions": {
+			spec:      "* 0-5 * * 1-5; * * * * 0,6",
+			timestamp: monday(10, 0),
+			want:      false,
+		},
+		"time zone": {
+			spec:      "CRON_TZ=Asia/Tokyo * 19 * * *",
+			timestamp: monday(10, 0),
+			want:      true,
+		},
+		"timestamp in other time zone": {
+			spec:      "* 10 * * *",
+			timestamp: monday(10, 0).In(time.FixedZone("UTC+2", 2*60*60)),
+			want:      true,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			window, err := Parse(tc.spec)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, window.Contains(tc.timestamp))
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* 5-1 * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"* * * * *;",
+		"CRON_TZ=Nowhere/Nothing * * * * *",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := Parse(spec)
+			assert.Error(t, err)
+		})
+	}
+}
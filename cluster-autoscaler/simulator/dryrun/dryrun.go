@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// PodVerdict contains the drainability verdict for a single pod.
+type PodVerdict struct {
+	Namespace      string `json:"namespace"`
+	Name           string `json:"name"`
+	Outcome        string `json:"outcome"`
+	BlockingReason string `json:"blockingReason,omitempty"`
+	// BlockingDetails contains optional structured details of BlockingReason.
+	BlockingDetails map[string]string `json:"blockingDetails,omitempty"`
+	Error           string            `json:"error,omitempty"`
+	// Action tells what happens to the pod if the node is drained. It is
+	// empty if the drain is blocked.
+	Action PodAction `json:"action,omitempty"`
+}
+
+// PodAction tells what happens to a pod when its node is drained.
+type PodAction string
+
+const (
+	// MoveAction means the pod is evicted and rescheduled elsewhere.
+	MoveAction PodAction = "Move"
+	// EvictAction means the DaemonSet pod is evicted without being
+	// rescheduled.
+	EvictAction PodAction = "Evict"
+	// LeaveAction means the pod is left on the node, e.g. because it's a
+	// mirror pod, a completed pod or a DaemonSet pod opted out of eviction.
+	LeaveAction PodAction = "Leave"
+)
+
+// NodeVerdict contains the drainability verdicts for all pods on a node.
+type NodeVerdict struct {
+	Node      string    `json:"node"`
+	Timestamp time.Time `json:"timestamp"`
+	// Drainable is true if none of the pods block node drain.
+	Drainable bool `json:"drainable"`
+	// BlockingPod is the first pod blocking node drain, the same one that
+	// scale down would report.
+	BlockingPod *PodVerdict `json:"blockingPod,omitempty"`
+	// DrainMode tells how pods would be removed from the node if it was
+	// scaled down.
+	DrainMode string       `json:"drainMode"`
+	Pods      []PodVerdict `json:"pods"`
+}
+
+// EvaluateNode simulates drain of the node the same way scale down does,
+// using podsToMove or simulator.SimulateDrain if nil, and reports the verdicts
+// of all pods, including the ones which don't block the drain.
+func EvaluateNode(nodeInfo *schedulerframework.NodeInfo, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, listers kube_util.ListerRegistry, remainingPdbTracker pdb.RemainingPdbTracker, timestamp time.Time, podsToMove simulator.PodsToMoveFunc) *NodeVerdict {
+	if podsToMove == nil {
+		podsToMove = simulator.SimulateDrain
+	}
+	result := podsToMove(nodeInfo, deleteOptions, drainabilityRules, listers, remainingPdbTracker, timestamp)
+
+	actions := make(map[*apiv1.Pod]PodAction, len(result.PodsToMove)+len(result.DaemonSetPods))
+	for _, pod := range result.PodsToMove {
+		actions[pod] = MoveAction
+	}
+	for _, pod := range result.DaemonSetPods {
+		actions[pod] = EvictAction
+	}
+	verdict := &NodeVerdict{
+		Node:      nodeInfo.Node().Name,
+		Timestamp: timestamp,
+		Drainable: result.BlockingPod == nil,
+		DrainMode: string(drainMode(deleteOptions.ForNode(nodeInfo.Node()))),
+		Pods:      make([]PodVerdict, 0, len(result.Verdicts)),
+	}
+	for _, v := range result.Verdicts {
+		podVerdict := newPodVerdict(v.Pod, v.Status)
+		if verdict.Drainable {
+			podVerdict.Action = LeaveAction
+			if action, found := actions[v.Pod]; found {
+				podVerdict.Action = action
+			}
+		}
+		if result.BlockingPod != nil && result.BlockingPod.Pod == v.Pod {
+			blockingPod := podVerdict
+			verdict.BlockingPod = &blockingPod
+		}
+		verdict.Pods = append(verdict.Pods, podVerdict)
+	}
+	return verdict
+}
+
+func drainMode(deleteOptions options.NodeDeleteOptions) options.DrainMode {
+	if deleteOptions.DrainMode == "" {
+		return options.EvictDrainMode
+	}
+	return deleteOptions.DrainMode
+}
+
+func newPodVerdict(pod *apiv1.Pod, status drainability.Status) PodVerdict {
+	verdict := PodVerdict{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Outcome:   status.Outcome.String(),
+	}
+	if status.Outcome == drainability.BlockDrain {
+		verdict.BlockingReason = string(status.BlockingReasonID())
+		verdict.BlockingDetails = status.BlockingDetails
+	}
+	if status.Error != nil {
+		verdict.Error = status.Error.Error()
+	}
+	return verdict
+}
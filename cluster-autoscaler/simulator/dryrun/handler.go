@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	klog "k8s.io/klog/v2"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+const (
+	// NodeQueryParam is the name of the query parameter holding the name of
+	// the evaluated node.
+	NodeQueryParam = "node"
+)
+
+// Handler is an http.Handler running drainability rules against a node
+// and returning the per-pod verdicts as JSON.
+type Handler struct {
+	mutex             sync.RWMutex
+	listers           kube_util.ListerRegistry
+	deleteOptions     options.NodeDeleteOptions
+	drainabilityRules rules.Rules
+	podsToMove        simulator.PodsToMoveFunc
+	now               func() time.Time
+}
+
+// NewHandler returns a new Handler. It responds with an error until
+// SetSource is called.
+func NewHandler() *Handler {
+	return &Handler{now: time.Now}
+}
+
+// SetSource sets the listers used to fetch the cluster state, as well as
+// the drainability configuration used by scale down. podsToMove is the
+// PodsToMoveFunc used by scale down, simulator.SimulateDrain if nil.
+func (h *Handler) SetSource(listers kube_util.ListerRegistry, deleteOptions options.NodeDeleteOptions, drainabilityRules rules.Rules, podsToMove simulator.PodsToMoveFunc) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.listers = listers
+	h.deleteOptions = deleteOptions
+	h.drainabilityRules = drainabilityRules
+	h.podsToMove = podsToMove
+}
+
+// ServeHTTP evaluates drainability of the node passed in NodeQueryParam.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	nodeName := r.URL.Query().Get(NodeQueryParam)
+	if nodeName == "" {
+		http.Error(w, fmt.Sprintf("missing %q query parameter", NodeQueryParam), http.StatusBadRequest)
+		return
+	}
+
+	h.mutex.RLock()
+	listers, deleteOptions, drainabilityRules, podsToMove := h.listers, h.deleteOptions, h.drainabilityRules, h.podsToMove
+	h.mutex.RUnlock()
+	if listers == nil {
+		http.Error(w, "drainability dry run is not ready yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	nodeInfo, tracker, err := clusterState(listers, nodeName)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if kube_errors.IsNotFound(err) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	verdict := EvaluateNode(nodeInfo, deleteOptions, drainabilityRules, listers, tracker, h.now(), podsToMove)
+	body, err := json.Marshal(verdict)
+	if err != nil {
+		klog.Errorf("Failed to marshal drainability verdict for node %s: %v", nodeName, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func clusterState(listers kube_util.ListerRegistry, nodeName string) (*schedulerframework.NodeInfo, pdb.RemainingPdbTracker, error) {
+	node, err := listers.AllNodeLister().Get(nodeName)
+	if err != nil {
+		return nil, nil, err
+	}
+	allPods, err := listers.AllPodLister().List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list pods: %v", err)
+	}
+	var pods []*apiv1.Pod
+	for _, pod := range allPods {
+		if pod.Spec.NodeName == nodeName {
+			pods = append(pods, pod)
+		}
+	}
+	pdbs, err := listers.PodDisruptionBudgetLister().List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list pod disruption budgets: %v", err)
+	}
+	tracker := pdb.NewBasicRemainingPdbTracker()
+	if err := tracker.SetPdbs(pdbs); err != nil {
+		return nil, nil, fmt.Errorf("failed to set pod disruption budgets: %v", err)
+	}
+
+	nodeInfo := schedulerframework.NewNodeInfo(pods...)
+	nodeInfo.SetNode(node)
+	return nodeInfo, tracker, nil
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestServeHTTP(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+
+	n1 := BuildTestNode("n1", 1000, 1000)
+	replicated := BuildTestPod("replicated", 100, 0)
+	replicated.Spec.NodeName = "n1"
+	replicated.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "")
+	unreplicated := BuildTestPod("unreplicated", 100, 0)
+	unreplicated.Spec.NodeName = "n1"
+	notSafeToEvict := BuildTestPod("not-safe-to-evict", 100, 0)
+	notSafeToEvict.Spec.NodeName = "n1"
+	notSafeToEvict.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "")
+	notSafeToEvict.Annotations = map[string]string{drain.PodSafeToEvictKey: "false"}
+	otherNode := BuildTestPod("other-node", 100, 0)
+	otherNode.Spec.NodeName = "n2"
+
+	// Nodes labeled to allow system pods are drainable even though the
+	// delete options skip nodes with system pods.
+	n3 := BuildTestNode("n3", 1000, 1000)
+	n3.Labels = map[string]string{options.SkipNodesWithSystemPodsLabelKey: "false"}
+	systemPod := BuildTestPod("system", 100, 0)
+	systemPod.Namespace = "kube-system"
+	systemPod.Spec.NodeName = "n3"
+	systemPod.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "")
+	dsPod := BuildTestPod("ds", 100, 0)
+	dsPod.Spec.NodeName = "n3"
+	dsPod.OwnerReferences = GenerateOwnerReferences("ds", "DaemonSet", "apps/v1", "")
+	optedOutDsPod := BuildTestPod("opted-out-ds", 100, 0)
+	optedOutDsPod.Spec.NodeName = "n3"
+	optedOutDsPod.OwnerReferences = GenerateOwnerReferences("ds", "DaemonSet", "apps/v1", "")
+	optedOutDsPod.Annotations = map[string]string{daemonset.EnableDsEvictionKey: "false"}
+
+	listers := kube_util.NewListerRegistry(
+		kube_util.NewTestNodeLister([]*apiv1.Node{n1, n3}),
+		nil,
+		kube_util.NewTestPodLister([]*apiv1.Pod{replicated, unreplicated, notSafeToEvict, otherNode, systemPod, dsPod, optedOutDsPod}),
+		kube_util.NewTestPodDisruptionBudgetLister([]*policyv1.PodDisruptionBudget{}),
+		nil, nil, nil, nil, nil)
+
+	for desc, tc := range map[string]struct {
+		url         string
+		noSource    bool
+		wantStatus  int
+		wantVerdict *NodeVerdict
+	}{
+		"no source": {
+			url:        "/drainabilityz?node=n1",
+			noSource:   true,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		"no node": {
+			url:        "/drainabilityz",
+			wantStatus: http.StatusBadRequest,
+		},
+		"unknown node": {
+			url:        "/drainabilityz?node=unknown",
+			wantStatus: http.StatusInternalServerError,
+		},
+		"all pods evaluated": {
+			url:        "/drainabilityz?node=n1",
+			wantStatus: http.StatusOK,
+			wantVerdict: &NodeVerdict{
+				Node:      "n1",
+				Timestamp: testTime,
+				Drainable: false,
+				BlockingPod: &PodVerdict{
+					Namespace:      "default",
+					Name:           "unreplicated",
+					Outcome:        "BlockDrain",
+					BlockingReason: "NotReplicated",
+					Error:          "default/unreplicated is not replicated",
+				},
+				DrainMode: "Evict",
+				Pods: []PodVerdict{
+					{
+						Namespace: "default",
+						Name:      "replicated",
+						Outcome:   "Undefined",
+					},
+					{
+						Namespace:      "default",
+						Name:           "unreplicated",
+						Outcome:        "BlockDrain",
+						BlockingReason: "NotReplicated",
+						Error:          "default/unreplicated is not replicated",
+					},
+					{
+						Namespace:      "default",
+						Name:           "not-safe-to-evict",
+						Outcome:        "BlockDrain",
+						BlockingReason: "NotSafeToEvictAnnotation",
+						Error:          "pod annotated as not safe to evict present: not-safe-to-evict",
+					},
+				},
+			},
+		},
+		"per node delete options and DaemonSet pods": {
+			url:        "/drainabilityz?node=n3",
+			wantStatus: http.StatusOK,
+			wantVerdict: &NodeVerdict{
+				Node:      "n3",
+				Timestamp: testTime,
+				Drainable: true,
+				DrainMode: "Evict",
+				Pods: []PodVerdict{
+					{
+						Namespace: "kube-system",
+						Name:      "system",
+						Outcome:   "Undefined",
+						Action:    MoveAction,
+					},
+					{
+						Namespace: "default",
+						Name:      "ds",
+						Outcome:   "DrainOk",
+						Action:    EvictAction,
+					},
+					{
+						Namespace: "default",
+						Name:      "opted-out-ds",
+						Outcome:   "DrainOk",
+						Action:    LeaveAction,
+					},
+				},
+			},
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			handler := NewHandler()
+			handler.now = func() time.Time { return testTime }
+			if !tc.noSource {
+				handler.SetSource(listers, options.NodeDeleteOptions{SkipNodesWithSystemPods: true}, nil, nil)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+			if tc.wantVerdict != nil {
+				verdict := &NodeVerdict{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), verdict))
+				assert.Equal(t, tc.wantVerdict, verdict)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dynamicresources makes simulations aware of ResourceClaims of
+// dynamic resource allocation (resource.k8s.io/v1alpha2).
+//
+// Devices are opaque to Cluster Autoscaler, so pods using claims are only
+// restricted to the nodes their claims can be available on: the nodes an
+// allocated claim is available on, or the nodes suitable for its
+// ResourceClass if the claim will be allocated anew, the same way the
+// DynamicResources scheduler plugin restricts them.
+package dynamicresources
+
+import (
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	resourcev1alpha2 "k8s.io/api/resource/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	resourcelisters "k8s.io/client-go/listers/resource/v1alpha2"
+	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
+)
+
+// Claims is a view of ResourceClaims, ResourceClaimTemplates and
+// ResourceClasses at a point in time. A nil Claims means dynamic resource
+// allocation isn't taken into account, so claims don't restrict pods.
+type Claims struct {
+	claims    map[string]*resourcev1alpha2.ResourceClaim
+	templates map[string]*resourcev1alpha2.ResourceClaimTemplate
+	classes   map[string]*resourcev1alpha2.ResourceClass
+}
+
+// NewClaims creates Claims from the given objects.
+func NewClaims(claims []*resourcev1alpha2.ResourceClaim, templates []*resourcev1alpha2.ResourceClaimTemplate, classes []*resourcev1alpha2.ResourceClass) *Claims {
+	c := &Claims{
+		claims:    make(map[string]*resourcev1alpha2.ResourceClaim, len(claims)),
+		templates: make(map[string]*resourcev1alpha2.ResourceClaimTemplate, len(templates)),
+		classes:   make(map[string]*resourcev1alpha2.ResourceClass, len(classes)),
+	}
+	for _, claim := range claims {
+		c.claims[key(claim.Namespace, claim.Name)] = claim
+	}
+	for _, template := range templates {
+		c.templates[key(template.Namespace, template.Name)] = template
+	}
+	for _, class := range classes {
+		c.classes[class.Name] = class
+	}
+	return c
+}
+
+// Provider lists Claims with listers.
+type Provider struct {
+	claimLister    resourcelisters.ResourceClaimLister
+	templateLister resourcelisters.ResourceClaimTemplateLister
+	classLister    resourcelisters.ResourceClassLister
+}
+
+// NewProvider creates a new Provider.
+func NewProvider(claimLister resourcelisters.ResourceClaimLister, templateLister resourcelisters.ResourceClaimTemplateLister, classLister resourcelisters.ResourceClassLister) *Provider {
+	return &Provider{
+		claimLister:    claimLister,
+		templateLister: templateLister,
+		classLister:    classLister,
+	}
+}
+
+// Claims lists the current Claims.
+func (p *Provider) Claims() (*Claims, error) {
+	claims, err := p.claimLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource claims: %v", err)
+	}
+	templates, err := p.templateLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource claim templates: %v", err)
+	}
+	classes, err := p.classLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource classes: %v", err)
+	}
+	return NewClaims(claims, templates, classes), nil
+}
+
+// NodeConstraint restricts the nodes a pod can be scheduled on because of its
+// claims. The zero value doesn't restrict nodes.
+type NodeConstraint struct {
+	selectors []*nodeaffinity.NodeSelector
+}
+
+// Match tells if the node satisfies the constraint.
+func (c NodeConstraint) Match(node *apiv1.Node) bool {
+	for _, selector := range c.selectors {
+		if !selector.Match(node) {
+			return false
+		}
+	}
+	return true
+}
+
+// PodNodeConstraint returns the constraint on the nodes the pod can be
+// scheduled on. Scheduled pods are considered to be moved, so claims generated
+// for them from templates will be allocated anew. An error means the pod
+// can't be scheduled on any node, e.g. because its claim is waiting for
+// allocation by the resource driver.
+func (c *Claims) PodNodeConstraint(pod *apiv1.Pod) (NodeConstraint, error) {
+	var constraint NodeConstraint
+	if c == nil {
+		return constraint, nil
+	}
+	for _, podClaim := range pod.Spec.ResourceClaims {
+		var selector *apiv1.NodeSelector
+		var err error
+		switch {
+		case podClaim.Source.ResourceClaimName != nil:
+			selector, err = c.claimNodeSelector(pod.Namespace, *podClaim.Source.ResourceClaimName)
+		case podClaim.Source.ResourceClaimTemplateName != nil:
+			if claimName, generated := generatedClaimName(pod, podClaim.Name); generated && claimName == nil {
+				// Generating a claim wasn't necessary.
+				continue
+			} else if generated && pod.Spec.NodeName == "" {
+				selector, err = c.claimNodeSelector(pod.Namespace, *claimName)
+			} else {
+				selector, err = c.templateNodeSelector(pod.Namespace, *podClaim.Source.ResourceClaimTemplateName)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return NodeConstraint{}, fmt.Errorf("resource claim %s of pod %s/%s: %v", podClaim.Name, pod.Namespace, pod.Name, err)
+		}
+		if selector == nil {
+			continue
+		}
+		nodeSelector, err := nodeaffinity.NewNodeSelector(selector)
+		if err != nil {
+			return NodeConstraint{}, fmt.Errorf("resource claim %s of pod %s/%s: invalid node selector: %v", podClaim.Name, pod.Namespace, pod.Name, err)
+		}
+		constraint.selectors = append(constraint.selectors, nodeSelector)
+	}
+	return constraint, nil
+}
+
+func (c *Claims) claimNodeSelector(namespace, name string) (*apiv1.NodeSelector, error) {
+	claim, found := c.claims[key(namespace, name)]
+	if !found {
+		return nil, fmt.Errorf("resource claim %s/%s not found", namespace, name)
+	}
+	if claim.Status.DeallocationRequested {
+		return nil, fmt.Errorf("resource claim %s/%s is being deallocated", namespace, name)
+	}
+	if claim.Status.Allocation != nil {
+		return claim.Status.Allocation.AvailableOnNodes, nil
+	}
+	if claim.Spec.AllocationMode == resourcev1alpha2.AllocationModeImmediate {
+		return nil, fmt.Errorf("resource claim %s/%s is waiting for immediate allocation", namespace, name)
+	}
+	return c.classNodeSelector(claim.Spec.ResourceClassName)
+}
+
+func (c *Claims) templateNodeSelector(namespace, name string) (*apiv1.NodeSelector, error) {
+	template, found := c.templates[key(namespace, name)]
+	if !found {
+		return nil, fmt.Errorf("resource claim template %s/%s not found", namespace, name)
+	}
+	return c.classNodeSelector(template.Spec.Spec.ResourceClassName)
+}
+
+func (c *Claims) classNodeSelector(name string) (*apiv1.NodeSelector, error) {
+	class, found := c.classes[name]
+	if !found {
+		return nil, fmt.Errorf("resource class %s not found", name)
+	}
+	return class.SuitableNodes, nil
+}
+
+// generatedClaimName returns the name of the claim generated for the pod from
+// a template, if the claim was generated. A nil name means generating the
+// claim wasn't necessary.
+func generatedClaimName(pod *apiv1.Pod, podClaimName string) (*string, bool) {
+	for _, status := range pod.Status.ResourceClaimStatuses {
+		if status.Name == podClaimName {
+			return status.ResourceClaimName, true
+		}
+	}
+	return nil, false
+}
+
+// InUseClaimProvidedBy returns a claim which is in use and is available only
+// on the node out of the given nodes, so removing the node would make devices
+// unavailable to their consumers. Reservations for the removed pods are
+// ignored, as the pods are rescheduled elsewhere, and so are claims generated
+// for them, as they are deleted together with the pods.
+func (c *Claims) InUseClaimProvidedBy(node *apiv1.Node, nodes []*apiv1.Node, removedPods []*apiv1.Pod) (*resourcev1alpha2.ResourceClaim, error) {
+	if c == nil {
+		return nil, nil
+	}
+	removedPodUIDs := make(map[types.UID]bool, len(removedPods))
+	for _, pod := range removedPods {
+		removedPodUIDs[pod.UID] = true
+	}
+	for _, claim := range c.claims {
+		if claim.Status.Allocation == nil || claim.Status.Allocation.AvailableOnNodes == nil || !reservedForOthers(claim, removedPodUIDs) {
+			continue
+		}
+		if owner := metav1.GetControllerOf(claim); owner != nil && owner.Kind == "Pod" && removedPodUIDs[owner.UID] {
+			continue
+		}
+		selector, err := nodeaffinity.NewNodeSelector(claim.Status.Allocation.AvailableOnNodes)
+		if err != nil {
+			return nil, fmt.Errorf("resource claim %s/%s: invalid node selector: %v", claim.Namespace, claim.Name, err)
+		}
+		if selector.Match(node) && !matchesOtherNode(selector, node, nodes) {
+			return claim, nil
+		}
+	}
+	return nil, nil
+}
+
+// Len returns the number of claims.
+func (c *Claims) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.claims)
+}
+
+func reservedForOthers(claim *resourcev1alpha2.ResourceClaim, podUIDs map[types.UID]bool) bool {
+	for _, consumer := range claim.Status.ReservedFor {
+		if consumer.Resource != "pods" || !podUIDs[consumer.UID] {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesOtherNode(selector *nodeaffinity.NodeSelector, node *apiv1.Node, nodes []*apiv1.Node) bool {
+	for _, other := range nodes {
+		if other.Name != node.Name && selector.Match(other) {
+			return true
+		}
+	}
+	return false
+}
+
+func key(namespace, name string) string {
+	return namespace + "/" + name
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dynamicresources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	resourcev1alpha2 "k8s.io/api/resource/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestPodNodeConstraint(t *testing.T) {
+	gpuA := testNode("gpu-a", "a")
+	gpuB := testNode("gpu-b", "b")
+	plain := testNode("plain", "")
+
+	claims := NewClaims(
+		[]*resourcev1alpha2.ResourceClaim{
+			testClaim("allocated", "gpu", resourcev1alpha2.AllocationModeWaitForFirstConsumer, selectorFor("a")),
+			testClaim("pending", "gpu", resourcev1alpha2.AllocationModeWaitForFirstConsumer, nil),
+			testClaim("immediate", "gpu", resourcev1alpha2.AllocationModeImmediate, nil),
+			testClaim("missing-class", "missing", resourcev1alpha2.AllocationModeWaitForFirstConsumer, nil),
+			testClaim("generated", "gpu", resourcev1alpha2.AllocationModeWaitForFirstConsumer, selectorFor("b")),
+			deallocating(testClaim("deallocating", "gpu", resourcev1alpha2.AllocationModeWaitForFirstConsumer, selectorFor("a"))),
+		},
+		[]*resourcev1alpha2.ResourceClaimTemplate{testTemplate("template", "gpu")},
+		[]*resourcev1alpha2.ResourceClass{testClass("gpu", selectorFor("a", "b"))},
+	)
+
+	for desc, tc := range map[string]struct {
+		claims    *Claims
+		pod       *apiv1.Pod
+		wantNodes []string
+		wantErr   bool
+	}{
+		"no claims": {
+			claims:    nil,
+			pod:       withClaim(testPod("", ""), "c", "allocated"),
+			wantNodes: []string{"gpu-a", "gpu-b", "plain"},
+		},
+		"pod without claims": {
+			claims:    claims,
+			pod:       testPod("", ""),
+			wantNodes: []string{"gpu-a", "gpu-b", "plain"},
+		},
+		"allocated claim": {
+			claims:    claims,
+			pod:       withClaim(testPod("", ""), "c", "allocated"),
+			wantNodes: []string{"gpu-a"},
+		},
+		"claim pending allocation": {
+			claims:    claims,
+			pod:       withClaim(testPod("", ""), "c", "pending"),
+			wantNodes: []string{"gpu-a", "gpu-b"},
+		},
+		"claim waiting for immediate allocation": {
+			claims:  claims,
+			pod:     withClaim(testPod("", ""), "c", "immediate"),
+			wantErr: true,
+		},
+		"claim being deallocated": {
+			claims:  claims,
+			pod:     withClaim(testPod("", ""), "c", "deallocating"),
+			wantErr: true,
+		},
+		"claim not found": {
+			claims:  claims,
+			pod:     withClaim(testPod("", ""), "c", "unknown"),
+			wantErr: true,
+		},
+		"class not found": {
+			claims:  claims,
+			pod:     withClaim(testPod("", ""), "c", "missing-class"),
+			wantErr: true,
+		},
+		"template without generated claim": {
+			claims:    claims,
+			pod:       withTemplate(testPod("", ""), "c", "template"),
+			wantNodes: []string{"gpu-a", "gpu-b"},
+		},
+		"pending pod with generated claim": {
+			claims:    claims,
+			pod:       withGeneratedClaim(withTemplate(testPod("", ""), "c", "template"), "c", stringPtr("generated")),
+			wantNodes: []string{"gpu-b"},
+		},
+		"scheduled pod with generated claim": {
+			claims:    claims,
+			pod:       withGeneratedClaim(withTemplate(testPod("uid", "gpu-b"), "c", "template"), "c", stringPtr("generated")),
+			wantNodes: []string{"gpu-a", "gpu-b"},
+		},
+		"claim generation not necessary": {
+			claims:    claims,
+			pod:       withGeneratedClaim(withTemplate(testPod("", ""), "c", "unknown"), "c", nil),
+			wantNodes: []string{"gpu-a", "gpu-b", "plain"},
+		},
+		"multiple claims": {
+			claims:    claims,
+			pod:       withClaim(withClaim(testPod("", ""), "c1", "pending"), "c2", "generated"),
+			wantNodes: []string{"gpu-b"},
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			constraint, err := tc.claims.PodNodeConstraint(tc.pod)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			var gotNodes []string
+			for _, node := range []*apiv1.Node{gpuA, gpuB, plain} {
+				if constraint.Match(node) {
+					gotNodes = append(gotNodes, node.Name)
+				}
+			}
+			assert.Equal(t, tc.wantNodes, gotNodes)
+		})
+	}
+}
+
+func TestInUseClaimProvidedBy(t *testing.T) {
+	gpuA := testNode("gpu-a", "a")
+	gpuB := testNode("gpu-b", "b")
+	nodes := []*apiv1.Node{gpuA, gpuB}
+	removedPod := testPod("removed", "gpu-a")
+
+	for desc, tc := range map[string]struct {
+		claim   *resourcev1alpha2.ResourceClaim
+		node    *apiv1.Node
+		wantUse bool
+	}{
+		"claim reserved on the node": {
+			claim:   reserved(testClaim("c", "gpu", resourcev1alpha2.AllocationModeImmediate, selectorFor("a")), "pending"),
+			node:    gpuA,
+			wantUse: true,
+		},
+		"claim reserved on another node": {
+			claim: reserved(testClaim("c", "gpu", resourcev1alpha2.AllocationModeImmediate, selectorFor("a")), "pending"),
+			node:  gpuB,
+		},
+		"claim available on multiple nodes": {
+			claim: reserved(testClaim("c", "gpu", resourcev1alpha2.AllocationModeImmediate, selectorFor("a", "b")), "pending"),
+			node:  gpuA,
+		},
+		"claim not reserved": {
+			claim: testClaim("c", "gpu", resourcev1alpha2.AllocationModeImmediate, selectorFor("a")),
+			node:  gpuA,
+		},
+		"claim reserved only for removed pods": {
+			claim: reserved(testClaim("c", "gpu", resourcev1alpha2.AllocationModeImmediate, selectorFor("a")), "removed"),
+			node:  gpuA,
+		},
+		"claim reserved for removed and other pods": {
+			claim:   reserved(testClaim("c", "gpu", resourcev1alpha2.AllocationModeImmediate, selectorFor("a")), "removed", "pending"),
+			node:    gpuA,
+			wantUse: true,
+		},
+		"claim generated for removed pod": {
+			claim: ownedBy(reserved(testClaim("c", "gpu", resourcev1alpha2.AllocationModeImmediate, selectorFor("a")), "pending"), removedPod),
+			node:  gpuA,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			claims := NewClaims([]*resourcev1alpha2.ResourceClaim{tc.claim}, nil, nil)
+			got, err := claims.InUseClaimProvidedBy(tc.node, nodes, []*apiv1.Pod{removedPod})
+			assert.NoError(t, err)
+			if tc.wantUse {
+				assert.Equal(t, tc.claim, got)
+			} else {
+				assert.Nil(t, got)
+			}
+		})
+	}
+
+	var nilClaims *Claims
+	got, err := nilClaims.InUseClaimProvidedBy(gpuA, nodes, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	assert.Equal(t, 0, nilClaims.Len())
+}
+
+func testNode(name, device string) *apiv1.Node {
+	node := &apiv1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+	if device != "" {
+		node.Labels["device"] = device
+	}
+	return node
+}
+
+func selectorFor(devices ...string) *apiv1.NodeSelector {
+	return &apiv1.NodeSelector{
+		NodeSelectorTerms: []apiv1.NodeSelectorTerm{{
+			MatchExpressions: []apiv1.NodeSelectorRequirement{{Key: "device", Operator: apiv1.NodeSelectorOpIn, Values: devices}},
+		}},
+	}
+}
+
+func testClaim(name, class string, mode resourcev1alpha2.AllocationMode, availableOn *apiv1.NodeSelector) *resourcev1alpha2.ResourceClaim {
+	claim := &resourcev1alpha2.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec:       resourcev1alpha2.ResourceClaimSpec{ResourceClassName: class, AllocationMode: mode},
+	}
+	if availableOn != nil {
+		claim.Status.Allocation = &resourcev1alpha2.AllocationResult{AvailableOnNodes: availableOn}
+	}
+	return claim
+}
+
+func deallocating(claim *resourcev1alpha2.ResourceClaim) *resourcev1alpha2.ResourceClaim {
+	claim.Status.DeallocationRequested = true
+	return claim
+}
+
+func reserved(claim *resourcev1alpha2.ResourceClaim, podUIDs ...string) *resourcev1alpha2.ResourceClaim {
+	for _, uid := range podUIDs {
+		claim.Status.ReservedFor = append(claim.Status.ReservedFor, resourcev1alpha2.ResourceClaimConsumerReference{Resource: "pods", Name: "pod-" + uid, UID: types.UID(uid)})
+	}
+	return claim
+}
+
+func ownedBy(claim *resourcev1alpha2.ResourceClaim, pod *apiv1.Pod) *resourcev1alpha2.ResourceClaim {
+	controller := true
+	claim.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: pod.Name, UID: pod.UID, Controller: &controller}}
+	return claim
+}
+
+func testTemplate(name, class string) *resourcev1alpha2.ResourceClaimTemplate {
+	return &resourcev1alpha2.ResourceClaimTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec:       resourcev1alpha2.ResourceClaimTemplateSpec{Spec: resourcev1alpha2.ResourceClaimSpec{ResourceClassName: class}},
+	}
+}
+
+func testClass(name string, suitableNodes *apiv1.NodeSelector) *resourcev1alpha2.ResourceClass {
+	return &resourcev1alpha2.ResourceClass{ObjectMeta: metav1.ObjectMeta{Name: name}, SuitableNodes: suitableNodes}
+}
+
+func testPod(uid, nodeName string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-" + uid, Namespace: "ns", UID: types.UID(uid)},
+		Spec:       apiv1.PodSpec{NodeName: nodeName},
+	}
+}
+
+func withClaim(pod *apiv1.Pod, name, claimName string) *apiv1.Pod {
+	pod.Spec.ResourceClaims = append(pod.Spec.ResourceClaims, apiv1.PodResourceClaim{Name: name, Source: apiv1.ClaimSource{ResourceClaimName: stringPtr(claimName)}})
+	return pod
+}
+
+func withTemplate(pod *apiv1.Pod, name, templateName string) *apiv1.Pod {
+	pod.Spec.ResourceClaims = append(pod.Spec.ResourceClaims, apiv1.PodResourceClaim{Name: name, Source: apiv1.ClaimSource{ResourceClaimTemplateName: stringPtr(templateName)}})
+	return pod
+}
+
+func withGeneratedClaim(pod *apiv1.Pod, name string, claimName *string) *apiv1.Pod {
+	pod.Status.ResourceClaimStatuses = append(pod.Status.ResourceClaimStatuses, apiv1.PodResourceClaimStatus{Name: name, ResourceClaimName: claimName})
+	return pod
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
+	v1appslister "k8s.io/client-go/listers/apps/v1"
+	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
+)
+
+// EvictionGroup is a group of pods evicted together when their node is
+// drained.
+type EvictionGroup struct {
+	// Pods are the pods of the group.
+	Pods []*apiv1.Pod
+	// AwaitReady are pods evicted in earlier groups whose replacements have
+	// to be running and ready before the group is evicted.
+	AwaitReady []*apiv1.Pod
+}
+
+// EvictionOrder groups pods in the order they are evicted in when their node
+// is drained. Pods with the lowest priority are evicted first and pods with
+// the highest priority last, so that critical pods keep running for as long
+// as possible. Pods with equal priority are evicted cheapest first, by the
+// controller.kubernetes.io/pod-deletion-cost annotation. Evictions of a group
+// start once all evictions of the previous group have been created.
+func EvictionOrder(pods []*apiv1.Pod) [][]*apiv1.Pod {
+	sorted := make([]*apiv1.Pod, len(pods))
+	copy(sorted, pods)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return evictedBefore(sorted[i], sorted[j])
+	})
+	var groups [][]*apiv1.Pod
+	for i, pod := range sorted {
+		if i == 0 || evictedBefore(sorted[i-1], pod) {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], pod)
+	}
+	return groups
+}
+
+func evictedBefore(pod, other *apiv1.Pod) bool {
+	if priority, otherPriority := corev1helpers.PodPriority(pod), corev1helpers.PodPriority(other); priority != otherPriority {
+		return priority < otherPriority
+	}
+	return pod_util.DeletionCost(pod) < pod_util.DeletionCost(other)
+}
+
+// evictionGroups groups pods in the order they are evicted in when their node
+// is drained, see EvictionOrder, preserving the order of StatefulSet pods if
+// statefulSetOrdinalOrder is set, see statefulSetEvictionGroups.
+func evictionGroups(pods []*apiv1.Pod, statefulSetOrdinalOrder bool, statefulSetLister v1appslister.StatefulSetLister) []EvictionGroup {
+	if statefulSetOrdinalOrder {
+		return statefulSetEvictionGroups(pods, statefulSetLister)
+	}
+	var groups []EvictionGroup
+	for _, group := range EvictionOrder(pods) {
+		groups = append(groups, EvictionGroup{Pods: group})
+	}
+	return groups
+}
+
+// statefulSetEvictionGroups works like EvictionOrder, but also preserves the
+// order in which StatefulSets remove their pods: pods of the same StatefulSet
+// are evicted one at a time, in reverse ordinal order. For StatefulSets with
+// OrderedReady pod management, which is the default, each eviction also waits
+// for the pod evicted before it to be replaced by a ready pod. StatefulSets
+// which can't be listed are treated as using OrderedReady pod management.
+func statefulSetEvictionGroups(pods []*apiv1.Pod, statefulSetLister v1appslister.StatefulSetLister) []EvictionGroup {
+	var groups []EvictionGroup
+	for _, pods := range EvictionOrder(pods) {
+		var rest []*apiv1.Pod
+		var setUIDs []types.UID
+		sets := make(map[types.UID][]*apiv1.Pod)
+		for _, pod := range pods {
+			uid, found := statefulSetUID(pod)
+			if !found {
+				rest = append(rest, pod)
+				continue
+			}
+			if _, seen := sets[uid]; !seen {
+				setUIDs = append(setUIDs, uid)
+			}
+			sets[uid] = append(sets[uid], pod)
+		}
+
+		first := EvictionGroup{Pods: rest}
+		var following []EvictionGroup
+		for _, uid := range setUIDs {
+			setPods := sets[uid]
+			sort.SliceStable(setPods, func(i, j int) bool {
+				return ordinal(setPods[i]) > ordinal(setPods[j])
+			})
+			first.Pods = append(first.Pods, setPods[0])
+			awaitReady := orderedReady(setPods[0], statefulSetLister)
+			for i, pod := range setPods[1:] {
+				if len(following) <= i {
+					following = append(following, EvictionGroup{})
+				}
+				following[i].Pods = append(following[i].Pods, pod)
+				if awaitReady {
+					following[i].AwaitReady = append(following[i].AwaitReady, setPods[i])
+				}
+			}
+		}
+		groups = append(groups, first)
+		groups = append(groups, following...)
+	}
+	return groups
+}
+
+// statefulSetUID returns the UID of the StatefulSet controlling the pod, if
+// the pod has a valid ordinal.
+func statefulSetUID(pod *apiv1.Pod) (types.UID, bool) {
+	ref := drain.ControllerRef(pod)
+	if ref == nil || ref.Kind != "StatefulSet" || ordinal(pod) < 0 {
+		return "", false
+	}
+	return ref.UID, true
+}
+
+// ordinal returns the ordinal of a StatefulSet pod, i.e. the suffix of its
+// name following the StatefulSet name, or -1 if it has none.
+func ordinal(pod *apiv1.Pod) int {
+	ref := drain.ControllerRef(pod)
+	if ref == nil || !strings.HasPrefix(pod.Name, ref.Name+"-") {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(strings.TrimPrefix(pod.Name, ref.Name+"-"))
+	if err != nil || ordinal < 0 {
+		return -1
+	}
+	return ordinal
+}
+
+// orderedReady tells if the StatefulSet controlling the pod uses OrderedReady
+// pod management.
+func orderedReady(pod *apiv1.Pod, statefulSetLister v1appslister.StatefulSetLister) bool {
+	if statefulSetLister == nil {
+		return true
+	}
+	statefulSet, err := statefulSetLister.StatefulSets(pod.Namespace).Get(drain.ControllerRef(pod).Name)
+	if err != nil {
+		return true
+	}
+	return statefulSet.Spec.PodManagementPolicy != appsv1.ParallelPodManagement
+}
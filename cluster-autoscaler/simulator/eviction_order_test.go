@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+)
+
+func TestEvictionOrder(t *testing.T) {
+	withPriority := func(pod *apiv1.Pod, priority int32) *apiv1.Pod {
+		pod.Spec.Priority = &priority
+		return pod
+	}
+	withCost := func(pod *apiv1.Pod, cost string) *apiv1.Pod {
+		pod.Annotations = map[string]string{apiv1.PodDeletionCost: cost}
+		return pod
+	}
+	critical := withPriority(BuildTestPod("critical", 100, 0), 2000000000)
+	high := withPriority(BuildTestPod("high", 100, 0), 1000)
+	expensiveHigh := withCost(withPriority(BuildTestPod("expensive-high", 100, 0), 1000), "10")
+	regular := BuildTestPod("regular", 100, 0)
+	otherRegular := BuildTestPod("other-regular", 100, 0)
+	cheapRegular := withCost(BuildTestPod("cheap-regular", 100, 0), "-10")
+	low := withCost(withPriority(BuildTestPod("low", 100, 0), -100), "1000")
+
+	for desc, tc := range map[string]struct {
+		pods []*apiv1.Pod
+		want [][]*apiv1.Pod
+	}{
+		"no pods": {
+			want: nil,
+		},
+		"equal pods are evicted together": {
+			pods: []*apiv1.Pod{regular, otherRegular},
+			want: [][]*apiv1.Pod{{regular, otherRegular}},
+		},
+		"lowest priority first, cheapest first among equal priority": {
+			pods: []*apiv1.Pod{critical, expensiveHigh, regular, high, low, cheapRegular, otherRegular},
+			want: [][]*apiv1.Pod{{low}, {cheapRegular}, {regular, otherRegular}, {high}, {expensiveHigh}, {critical}},
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			assert.Equal(t, tc.want, EvictionOrder(tc.pods))
+		})
+	}
+}
+
+func TestStatefulSetEvictionGroups(t *testing.T) {
+	statefulSetPod := func(name, statefulSet string) *apiv1.Pod {
+		pod := BuildTestPod(name, 100, 0)
+		pod.OwnerReferences = GenerateOwnerReferences(statefulSet, "StatefulSet", "apps/v1", types.UID(statefulSet))
+		return pod
+	}
+	web0 := statefulSetPod("web-0", "web")
+	web1 := statefulSetPod("web-1", "web")
+	web10 := statefulSetPod("web-10", "web")
+	db0 := statefulSetPod("db-0", "db")
+	db1 := statefulSetPod("db-1", "db")
+	cache0 := statefulSetPod("cache-0", "cache")
+	cache1 := statefulSetPod("cache-1", "cache")
+	noOrdinal := statefulSetPod("no-ordinal", "web")
+	regular := BuildTestPod("regular", 100, 0)
+	low := BuildTestPod("low", 100, 0)
+	priority := int32(-10)
+	low.Spec.Priority = &priority
+
+	statefulSetLister, err := kube_util.NewTestStatefulSetLister([]*appsv1.StatefulSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: appsv1.StatefulSetSpec{PodManagementPolicy: appsv1.OrderedReadyPodManagement}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cache", Namespace: "default"}, Spec: appsv1.StatefulSetSpec{PodManagementPolicy: appsv1.ParallelPodManagement}},
+	})
+	assert.NoError(t, err)
+
+	for desc, tc := range map[string]struct {
+		pods []*apiv1.Pod
+		want []EvictionGroup
+	}{
+		"no stateful set pods": {
+			pods: []*apiv1.Pod{regular, low},
+			want: []EvictionGroup{{Pods: []*apiv1.Pod{low}}, {Pods: []*apiv1.Pod{regular}}},
+		},
+		"single pod of a stateful set": {
+			pods: []*apiv1.Pod{web0, regular},
+			want: []EvictionGroup{{Pods: []*apiv1.Pod{regular, web0}}},
+		},
+		"reverse ordinal order, waiting for ordered ready replacements": {
+			pods: []*apiv1.Pod{web0, web10, regular, web1, noOrdinal},
+			want: []EvictionGroup{
+				{Pods: []*apiv1.Pod{regular, noOrdinal, web10}},
+				{Pods: []*apiv1.Pod{web1}, AwaitReady: []*apiv1.Pod{web10}},
+				{Pods: []*apiv1.Pod{web0}, AwaitReady: []*apiv1.Pod{web1}},
+			},
+		},
+		"parallel stateful sets don't wait": {
+			pods: []*apiv1.Pod{cache0, cache1},
+			want: []EvictionGroup{{Pods: []*apiv1.Pod{cache1}}, {Pods: []*apiv1.Pod{cache0}}},
+		},
+		"unknown stateful sets are ordered ready": {
+			pods: []*apiv1.Pod{db0, db1},
+			want: []EvictionGroup{{Pods: []*apiv1.Pod{db1}}, {Pods: []*apiv1.Pod{db0}, AwaitReady: []*apiv1.Pod{db1}}},
+		},
+		"stateful sets evicted side by side": {
+			pods: []*apiv1.Pod{web0, cache0, web1, cache1},
+			want: []EvictionGroup{
+				{Pods: []*apiv1.Pod{web1, cache1}},
+				{Pods: []*apiv1.Pod{web0, cache0}, AwaitReady: []*apiv1.Pod{web1}},
+			},
+		},
+		"priority groups are kept": {
+			pods: []*apiv1.Pod{web0, web1, low},
+			want: []EvictionGroup{
+				{Pods: []*apiv1.Pod{low}},
+				{Pods: []*apiv1.Pod{web1}},
+				{Pods: []*apiv1.Pod{web0}, AwaitReady: []*apiv1.Pod{web1}},
+			},
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			assert.Equal(t, tc.want, statefulSetEvictionGroups(tc.pods, statefulSetLister))
+		})
+	}
+}
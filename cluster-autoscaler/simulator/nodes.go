@@ -24,11 +24,14 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/utils/daemonset"
 	"k8s.io/autoscaler/cluster-autoscaler/utils/errors"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+	"k8s.io/kubernetes/pkg/scheduler/framework/plugins/noderesources"
 
-	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 )
 
 // BuildNodeInfoForNode build a NodeInfo structure for the given node as if the node was just created.
+// Besides the DS pods already running on the node, it synthesizes pods of the other DaemonSets which
+// would land on a new node. If forceDaemonSets is set, they are added even if they don't fit.
 func BuildNodeInfoForNode(node *apiv1.Node, scheduledPods []*apiv1.Pod, daemonsets []*appsv1.DaemonSet, forceDaemonSets bool) (*schedulerframework.NodeInfo, errors.AutoscalerError) {
 	nodeInfo := schedulerframework.NewNodeInfo()
 	nodeInfo.SetNode(node)
@@ -52,19 +55,20 @@ func addExpectedPods(nodeInfo *schedulerframework.NodeInfo, scheduledPods []*api
 			runningDS[controllerRef.UID] = true
 		}
 	}
-	// Add all pending DS pods if force scheduling DS
-	if forceDaemonSets {
-		var pendingDS []*appsv1.DaemonSet
-		for _, ds := range daemonsets {
-			if !runningDS[ds.UID] {
-				pendingDS = append(pendingDS, ds)
-			}
+	// Add pending DS pods tolerating the node's taints and matching its affinity. A new node would
+	// run them as long as their requests fit, or all of them if force scheduling DS.
+	var pendingDS []*appsv1.DaemonSet
+	for _, ds := range daemonsets {
+		if !runningDS[ds.UID] {
+			pendingDS = append(pendingDS, ds)
 		}
-		daemonPods, err := daemonset.GetDaemonSetPodsForNode(nodeInfo, pendingDS)
-		if err != nil {
-			return nil, errors.ToAutoscalerError(errors.InternalError, err)
-		}
-		for _, pod := range daemonPods {
+	}
+	daemonPods, err := daemonset.GetDaemonSetPodsForNode(nodeInfo, pendingDS)
+	if err != nil {
+		return nil, errors.ToAutoscalerError(errors.InternalError, err)
+	}
+	for _, pod := range daemonPods {
+		if forceDaemonSets || len(noderesources.Fits(pod, nodeInfo)) == 0 {
 			nodeInfo.AddPod(pod)
 		}
 	}
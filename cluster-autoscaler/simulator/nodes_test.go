@@ -62,6 +62,37 @@ func TestBuildNodeInfoForNode(t *testing.T) {
 		},
 	}
 
+	ds4 := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ds4",
+			Namespace: "ds4-namespace",
+			UID:       types.UID("ds4"),
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: apiv1.PodTemplateSpec{
+				Spec: test.BuildTestPod("p", 2000, 1).Spec,
+			},
+		},
+	}
+
+	ds5 := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ds5",
+			Namespace: "ds5-namespace",
+			UID:       types.UID("ds5"),
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: apiv1.PodTemplateSpec{
+				Spec: apiv1.PodSpec{
+					Tolerations: []apiv1.Toleration{{Key: "dedicated", Operator: apiv1.TolerationOpExists}},
+				},
+			},
+		},
+	}
+
+	taintedNode := test.BuildTestNode("n", 1000, 10)
+	taintedNode.Spec.Taints = []apiv1.Taint{{Key: "dedicated", Value: "gpu", Effect: apiv1.TaintEffectNoSchedule}}
+
 	testCases := []struct {
 		name       string
 		node       *apiv1.Node
@@ -126,6 +157,33 @@ func TestBuildNodeInfoForNode(t *testing.T) {
 			daemonSets: []*appsv1.DaemonSet{ds1, ds2, ds3},
 			wantPods: []*apiv1.Pod{
 				buildDSPod(ds1, "n"),
+				buildDSPod(ds2, "n"),
+			},
+		},
+		{
+			name:       "node with a pending DS pod too big for it [forceDS=false]",
+			node:       test.BuildTestNode("n", 1000, 10),
+			daemonSets: []*appsv1.DaemonSet{ds1, ds4},
+			wantPods: []*apiv1.Pod{
+				buildDSPod(ds1, "n"),
+			},
+		},
+		{
+			name:       "node with a pending DS pod too big for it [forceDS=true]",
+			node:       test.BuildTestNode("n", 1000, 10),
+			daemonSets: []*appsv1.DaemonSet{ds1, ds4},
+			forceDS:    true,
+			wantPods: []*apiv1.Pod{
+				buildDSPod(ds1, "n"),
+				buildDSPod(ds4, "n"),
+			},
+		},
+		{
+			name:       "tainted node with pending DS pods [forceDS=false]",
+			node:       taintedNode,
+			daemonSets: []*appsv1.DaemonSet{ds1, ds5},
+			wantPods: []*apiv1.Pod{
+				buildDSPod(ds5, "n"),
 			},
 		},
 		{
@@ -169,6 +227,7 @@ func TestBuildNodeInfoForNode(t *testing.T) {
 			wantPods: []*apiv1.Pod{
 				test.SetMirrorPodSpec(test.BuildScheduledTestPod("p3", 100, 1, "n")),
 				buildDSPod(ds1, "n"),
+				buildDSPod(ds2, "n"),
 			},
 		},
 		{
@@ -33,10 +33,16 @@ import (
 	schedulerframeworkruntime "k8s.io/kubernetes/pkg/scheduler/framework/runtime"
 )
 
+// dynamicResourcesFilterName is the name of the filter reported when a pod's
+// DRA resource claims can't be satisfied on a node.
+const dynamicResourcesFilterName = "DynamicResources"
+
 // SchedulerBasedPredicateChecker checks whether all required predicates pass for given Pod and Node.
 // The verification is done by calling out to scheduler code.
 type SchedulerBasedPredicateChecker struct {
-	framework              schedulerframework.Framework
+	// frameworks are keyed by scheduler name of the profile they run.
+	frameworks             map[string]schedulerframework.Framework
+	defaultFramework       schedulerframework.Framework
 	delegatingSharedLister *DelegatingSchedulerSharedLister
 	nodeLister             v1listers.NodeLister
 	podLister              v1listers.PodLister
@@ -44,6 +50,10 @@ type SchedulerBasedPredicateChecker struct {
 }
 
 // NewSchedulerBasedPredicateChecker builds scheduler based PredicateChecker.
+// Each profile of the scheduler config is simulated with its own enabled
+// plugins and plugin args, and pods are checked against the profile of their
+// scheduler. Pods of schedulers missing from the config are checked against
+// the first profile.
 func NewSchedulerBasedPredicateChecker(informerFactory informers.SharedInformerFactory, schedConfig *config.KubeSchedulerConfiguration) (*SchedulerBasedPredicateChecker, error) {
 	if schedConfig == nil {
 		var err error
@@ -53,27 +63,35 @@ func NewSchedulerBasedPredicateChecker(informerFactory informers.SharedInformerF
 		}
 	}
 
-	if len(schedConfig.Profiles) != 1 {
-		return nil, fmt.Errorf("unexpected scheduler config: expected one scheduler profile only (found %d profiles)", len(schedConfig.Profiles))
+	if len(schedConfig.Profiles) == 0 {
+		return nil, fmt.Errorf("unexpected scheduler config: expected at least one scheduler profile")
 	}
 	sharedLister := NewDelegatingSchedulerSharedLister()
 
-	framework, err := schedulerframeworkruntime.NewFramework(
-		context.TODO(),
-		scheduler_plugins.NewInTreeRegistry(),
-		&schedConfig.Profiles[0],
-		schedulerframeworkruntime.WithInformerFactory(informerFactory),
-		schedulerframeworkruntime.WithSnapshotSharedLister(sharedLister),
-	)
-
-	if err != nil {
-		return nil, fmt.Errorf("couldn't create scheduler framework; %v", err)
-	}
-
 	checker := &SchedulerBasedPredicateChecker{
-		framework:              framework,
+		frameworks:             make(map[string]schedulerframework.Framework, len(schedConfig.Profiles)),
 		delegatingSharedLister: sharedLister,
 	}
+	for i := range schedConfig.Profiles {
+		profile := &schedConfig.Profiles[i]
+		if _, found := checker.frameworks[profile.SchedulerName]; found {
+			return nil, fmt.Errorf("unexpected scheduler config: duplicate scheduler profile %q", profile.SchedulerName)
+		}
+		framework, err := schedulerframeworkruntime.NewFramework(
+			context.TODO(),
+			scheduler_plugins.NewInTreeRegistry(),
+			profile,
+			schedulerframeworkruntime.WithInformerFactory(informerFactory),
+			schedulerframeworkruntime.WithSnapshotSharedLister(sharedLister),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create scheduler framework for profile %q; %v", profile.SchedulerName, err)
+		}
+		checker.frameworks[profile.SchedulerName] = framework
+		if i == 0 {
+			checker.defaultFramework = framework
+		}
+	}
 
 	return checker, nil
 }
@@ -101,11 +119,17 @@ func (p *SchedulerBasedPredicateChecker) FitsAnyNodeMatching(clusterSnapshot clu
 		return "", fmt.Errorf("error obtaining nodeInfos from schedulerLister")
 	}
 
+	claimConstraint, err := clusterSnapshot.ResourceClaims().PodNodeConstraint(pod)
+	if err != nil {
+		return "", fmt.Errorf("cannot put pod %s on any node: %v", pod.Name, err)
+	}
+
 	p.delegatingSharedLister.UpdateDelegate(clusterSnapshot)
 	defer p.delegatingSharedLister.ResetDelegate()
 
+	framework := p.frameworkFor(pod)
 	state := schedulerframework.NewCycleState()
-	preFilterResult, preFilterStatus := p.framework.RunPreFilterPlugins(context.TODO(), state, pod)
+	preFilterResult, preFilterStatus := framework.RunPreFilterPlugins(context.TODO(), state, pod)
 	if !preFilterStatus.IsSuccess() {
 		return "", fmt.Errorf("error running pre filter plugins for pod %s; %s", pod.Name, preFilterStatus.Message())
 	}
@@ -125,7 +149,11 @@ func (p *SchedulerBasedPredicateChecker) FitsAnyNodeMatching(clusterSnapshot clu
 			continue
 		}
 
-		filterStatus := p.framework.RunFilterPlugins(context.TODO(), state, pod, nodeInfo)
+		if !claimConstraint.Match(nodeInfo.Node()) {
+			continue
+		}
+
+		filterStatus := framework.RunFilterPlugins(context.TODO(), state, pod, nodeInfo)
 		if filterStatus.IsSuccess() {
 			p.lastIndex = (p.lastIndex + i + 1) % len(nodeInfosList)
 			return nodeInfo.Node().Name, nil
@@ -145,11 +173,21 @@ func (p *SchedulerBasedPredicateChecker) CheckPredicates(clusterSnapshot cluster
 		return NewPredicateError(InternalPredicateError, "", errorMessage, nil, emptyString)
 	}
 
+	claimConstraint, err := clusterSnapshot.ResourceClaims().PodNodeConstraint(pod)
+	if err != nil {
+		return NewPredicateError(NotSchedulablePredicateError, dynamicResourcesFilterName, err.Error(), nil, emptyString)
+	}
+	if !claimConstraint.Match(nodeInfo.Node()) {
+		errorMessage := fmt.Sprintf("node %s doesn't provide resource claims of pod %s/%s", nodeName, pod.Namespace, pod.Name)
+		return NewPredicateError(NotSchedulablePredicateError, dynamicResourcesFilterName, errorMessage, nil, emptyString)
+	}
+
 	p.delegatingSharedLister.UpdateDelegate(clusterSnapshot)
 	defer p.delegatingSharedLister.ResetDelegate()
 
+	framework := p.frameworkFor(pod)
 	state := schedulerframework.NewCycleState()
-	_, preFilterStatus := p.framework.RunPreFilterPlugins(context.TODO(), state, pod)
+	_, preFilterStatus := framework.RunPreFilterPlugins(context.TODO(), state, pod)
 	if !preFilterStatus.IsSuccess() {
 		return NewPredicateError(
 			InternalPredicateError,
@@ -159,7 +197,7 @@ func (p *SchedulerBasedPredicateChecker) CheckPredicates(clusterSnapshot cluster
 			emptyString)
 	}
 
-	filterStatus := p.framework.RunFilterPlugins(context.TODO(), state, pod, nodeInfo)
+	filterStatus := framework.RunFilterPlugins(context.TODO(), state, pod, nodeInfo)
 
 	if !filterStatus.IsSuccess() {
 		filterName := filterStatus.FailedPlugin()
@@ -184,6 +222,18 @@ func (p *SchedulerBasedPredicateChecker) CheckPredicates(clusterSnapshot cluster
 	return nil
 }
 
+// frameworkFor returns the framework of the profile scheduling the pod.
+func (p *SchedulerBasedPredicateChecker) frameworkFor(pod *apiv1.Pod) schedulerframework.Framework {
+	schedulerName := pod.Spec.SchedulerName
+	if schedulerName == "" {
+		schedulerName = apiv1.DefaultSchedulerName
+	}
+	if framework, found := p.frameworks[schedulerName]; found {
+		return framework
+	}
+	return p.defaultFramework
+}
+
 func (p *SchedulerBasedPredicateChecker) buildDebugInfo(filterName string, nodeInfo *schedulerframework.NodeInfo) func() string {
 	switch filterName {
 	case "TaintToleration":
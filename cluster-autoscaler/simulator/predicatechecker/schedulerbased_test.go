@@ -24,12 +24,19 @@ import (
 
 	testconfig "k8s.io/autoscaler/cluster-autoscaler/config/test"
 	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/dynamicresources"
 	scheduler "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 
 	"github.com/stretchr/testify/assert"
 
 	apiv1 "k8s.io/api/core/v1"
+	resourcev1alpha2 "k8s.io/api/resource/v1alpha2"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
 )
 
 func TestCheckPredicate(t *testing.T) {
@@ -263,6 +270,158 @@ func TestFitsAnyNode(t *testing.T) {
 
 }
 
+func TestResourceClaims(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 2000000)
+	n2 := BuildTestNode("n2", 1000, 2000000)
+	n2.Labels["gpu"] = "true"
+
+	claimName, pendingClaimName := "gpu", "pending"
+	pod := BuildTestPod("p", 100, 1000)
+	pod.Spec.ResourceClaims = []apiv1.PodResourceClaim{{Name: "gpu", Source: apiv1.ClaimSource{ResourceClaimName: &claimName}}}
+	pendingPod := BuildTestPod("pending", 100, 1000)
+	pendingPod.Spec.ResourceClaims = []apiv1.PodResourceClaim{{Name: "gpu", Source: apiv1.ClaimSource{ResourceClaimName: &pendingClaimName}}}
+
+	claims := dynamicresources.NewClaims([]*resourcev1alpha2.ResourceClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: claimName, Namespace: pod.Namespace},
+			Status: resourcev1alpha2.ResourceClaimStatus{Allocation: &resourcev1alpha2.AllocationResult{
+				AvailableOnNodes: &apiv1.NodeSelector{NodeSelectorTerms: []apiv1.NodeSelectorTerm{{
+					MatchExpressions: []apiv1.NodeSelectorRequirement{{Key: "gpu", Operator: apiv1.NodeSelectorOpExists}},
+				}}},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: pendingClaimName, Namespace: pod.Namespace},
+			Spec:       resourcev1alpha2.ResourceClaimSpec{AllocationMode: resourcev1alpha2.AllocationModeImmediate},
+		},
+	}, nil, nil)
+
+	predicateChecker, err := NewTestPredicateChecker()
+	assert.NoError(t, err)
+	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, clusterSnapshot, []*apiv1.Node{n1, n2}, nil)
+
+	// Claims aren't taken into account unless set on the snapshot.
+	assert.Nil(t, predicateChecker.CheckPredicates(clusterSnapshot, pod, "n1"))
+
+	clusterSnapshot.SetResourceClaims(claims)
+	predicateError := predicateChecker.CheckPredicates(clusterSnapshot, pod, "n1")
+	if assert.NotNil(t, predicateError) {
+		assert.Equal(t, NotSchedulablePredicateError, predicateError.ErrorType())
+		assert.Equal(t, "DynamicResources", predicateError.PredicateName())
+	}
+	assert.Nil(t, predicateChecker.CheckPredicates(clusterSnapshot, pod, "n2"))
+	nodeName, err := predicateChecker.FitsAnyNode(clusterSnapshot, pod)
+	assert.NoError(t, err)
+	assert.Equal(t, "n2", nodeName)
+
+	assert.NotNil(t, predicateChecker.CheckPredicates(clusterSnapshot, pendingPod, "n2"))
+	_, err = predicateChecker.FitsAnyNode(clusterSnapshot, pendingPod)
+	assert.Error(t, err)
+
+	clusterSnapshot.Clear()
+	assert.Nil(t, clusterSnapshot.ResourceClaims())
+}
+
+func TestCheckPredicateVolumeLimits(t *testing.T) {
+	limit := int32(2)
+	csiNode := &storagev1.CSINode{
+		ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+		Spec: storagev1.CSINodeSpec{
+			Drivers: []storagev1.CSINodeDriver{{Name: "csi.example.com", NodeID: "n1", Allocatable: &storagev1.VolumeNodeResources{Count: &limit}}},
+		},
+	}
+	objects := []runtime.Object{csiNode}
+	for _, name := range []string{"v1", "v2", "v3"} {
+		objects = append(objects, testPersistentVolume(name), testPersistentVolumeClaim(name))
+	}
+	informerFactory := informers.NewSharedInformerFactory(clientsetfake.NewSimpleClientset(objects...), 0)
+	predicateChecker, err := NewSchedulerBasedPredicateChecker(informerFactory, nil)
+	assert.NoError(t, err)
+	stop := make(chan struct{})
+	defer close(stop)
+	informerFactory.Start(stop)
+	informerFactory.WaitForCacheSync(stop)
+
+	n1 := BuildTestNode("n1", 1000, 2000000)
+	SetNodeReadyState(n1, true, time.Time{})
+	existing := testPodWithVolumes("existing", "v1")
+	existing.Spec.NodeName = "n1"
+	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+	err = clusterSnapshot.AddNodeWithPods(n1, []*apiv1.Pod{existing})
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name        string
+		pod         *apiv1.Pod
+		expectError bool
+	}{
+		{
+			name: "pod without volumes",
+			pod:  testPodWithVolumes("p0"),
+		},
+		{
+			name: "pod with a volume within the limit",
+			pod:  testPodWithVolumes("p1", "v2"),
+		},
+		{
+			name: "pod sharing its volume with an existing pod",
+			pod:  testPodWithVolumes("p2", "v1", "v2"),
+		},
+		{
+			name:        "pod with volumes over the limit",
+			pod:         testPodWithVolumes("p3", "v2", "v3"),
+			expectError: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			predicateError := predicateChecker.CheckPredicates(clusterSnapshot, tc.pod, "n1")
+			if tc.expectError {
+				assert.NotNil(t, predicateError)
+				assert.Equal(t, NotSchedulablePredicateError, predicateError.ErrorType())
+				assert.Equal(t, "NodeVolumeLimits", predicateError.PredicateName())
+			} else {
+				assert.Nil(t, predicateError)
+			}
+		})
+	}
+}
+
+func testPersistentVolume(name string) *apiv1.PersistentVolume {
+	return &apiv1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: apiv1.PersistentVolumeSpec{
+			PersistentVolumeSource: apiv1.PersistentVolumeSource{
+				CSI: &apiv1.CSIPersistentVolumeSource{Driver: "csi.example.com", VolumeHandle: name},
+			},
+			ClaimRef: &apiv1.ObjectReference{Namespace: "default", Name: name},
+		},
+	}
+}
+
+func testPersistentVolumeClaim(name string) *apiv1.PersistentVolumeClaim {
+	return &apiv1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: map[string]string{"pv.kubernetes.io/bind-completed": "yes"},
+		},
+		Spec:   apiv1.PersistentVolumeClaimSpec{VolumeName: name},
+		Status: apiv1.PersistentVolumeClaimStatus{Phase: apiv1.ClaimBound},
+	}
+}
+
+func testPodWithVolumes(name string, claims ...string) *apiv1.Pod {
+	pod := BuildTestPod(name, 100, 1000)
+	for _, claim := range claims {
+		pod.Spec.Volumes = append(pod.Spec.Volumes, apiv1.Volume{
+			Name:         claim,
+			VolumeSource: apiv1.VolumeSource{PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{ClaimName: claim}},
+		})
+	}
+	return pod
+}
+
 func TestDebugInfo(t *testing.T) {
 	p1 := BuildTestPod("p1", 0, 0)
 	node1 := BuildTestNode("n1", 1000, 2000000)
@@ -316,3 +475,74 @@ func TestDebugInfo(t *testing.T) {
 	predicateErr = customPredicateChecker.CheckPredicates(clusterSnapshot, p1, "n1")
 	assert.Nil(t, predicateErr)
 }
+
+func TestSchedulerProfiles(t *testing.T) {
+	n1000 := BuildTestNode("n1000", 1000, 2000000)
+	SetNodeReadyState(n1000, true, time.Time{})
+
+	tmpDir, err := os.MkdirTemp("", "scheduler-configs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	customConfigFile := filepath.Join(tmpDir, "custom_config.yaml")
+	if err := os.WriteFile(customConfigFile,
+		[]byte(testconfig.SchedulerConfigMultipleProfiles),
+		os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	customConfig, err := scheduler.ConfigFromPath(customConfigFile)
+	assert.NoError(t, err)
+	predicateChecker, err := NewTestPredicateCheckerWithCustomConfig(customConfig)
+	assert.NoError(t, err)
+
+	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+	err = clusterSnapshot.AddNode(n1000)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		schedulerName string
+		expectError   bool
+	}{
+		{
+			name:        "default scheduler name",
+			expectError: true,
+		},
+		{
+			name:          "default scheduler",
+			schedulerName: apiv1.DefaultSchedulerName,
+			expectError:   true,
+		},
+		{
+			name:          "custom scheduler",
+			schedulerName: "custom-scheduler",
+			expectError:   false,
+		},
+		{
+			name:          "unknown scheduler uses the first profile",
+			schedulerName: "unknown-scheduler",
+			expectError:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := BuildTestPod("p8000", 8000, 0)
+			pod.Spec.SchedulerName = tc.schedulerName
+
+			predicateError := predicateChecker.CheckPredicates(clusterSnapshot, pod, n1000.Name)
+			nodeName, err := predicateChecker.FitsAnyNode(clusterSnapshot, pod)
+			if tc.expectError {
+				assert.NotNil(t, predicateError)
+				assert.Error(t, err)
+			} else {
+				assert.Nil(t, predicateError)
+				assert.NoError(t, err)
+				assert.Equal(t, n1000.Name, nodeName)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"k8s.io/autoscaler/cluster-autoscaler/simulator"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/pdb"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/rules"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/utilization"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+)
+
+// Recording is the state of the cluster scale-down simulation depends on,
+// captured before the simulation, so that it can be replayed offline.
+type Recording struct {
+	Timestamp time.Time `json:"timestamp"`
+	// Nodes are all nodes in the cluster snapshot, including upcoming ones.
+	Nodes []*apiv1.Node `json:"nodes"`
+	// Pods are the pods scheduled on the nodes.
+	Pods                   []*apiv1.Pod                    `json:"pods"`
+	PodDisruptionBudgets   []*policyv1.PodDisruptionBudget `json:"podDisruptionBudgets"`
+	DaemonSets             []*appsv1.DaemonSet             `json:"daemonSets"`
+	ReplicationControllers []*apiv1.ReplicationController  `json:"replicationControllers"`
+	Jobs                   []*batchv1.Job                  `json:"jobs"`
+	ReplicaSets            []*appsv1.ReplicaSet            `json:"replicaSets"`
+	StatefulSets           []*appsv1.StatefulSet           `json:"statefulSets"`
+	DeleteOptions          options.NodeDeleteOptions       `json:"deleteOptions"`
+	// IgnoreDaemonSetsUtilization and IgnoreMirrorPodsUtilization are the
+	// defaults used to calculate node utilization.
+	IgnoreDaemonSetsUtilization bool `json:"ignoreDaemonSetsUtilization"`
+	IgnoreMirrorPodsUtilization bool `json:"ignoreMirrorPodsUtilization"`
+	// Candidates are the names of scale-down candidates, in the order they
+	// were passed to the simulation.
+	Candidates []string `json:"candidates"`
+	// Destinations are the names of nodes pods can be moved to.
+	Destinations []string `json:"destinations"`
+}
+
+// Record captures the state of the cluster snapshot and the objects
+// drainability rules look up.
+func Record(snapshot clustersnapshot.ClusterSnapshot, listers kube_util.ListerRegistry, deleteOptions options.NodeDeleteOptions,
+	candidates, destinations []*apiv1.Node, timestamp time.Time) (*Recording, error) {
+	recording := &Recording{
+		Timestamp:     timestamp,
+		DeleteOptions: deleteOptions,
+		Candidates:    nodeNames(candidates),
+		Destinations:  nodeNames(destinations),
+	}
+	nodeInfos, err := snapshot.NodeInfos().List()
+	if err != nil {
+		return nil, err
+	}
+	for _, nodeInfo := range nodeInfos {
+		recording.Nodes = append(recording.Nodes, nodeInfo.Node())
+		for _, podInfo := range nodeInfo.Pods {
+			recording.Pods = append(recording.Pods, podInfo.Pod)
+		}
+	}
+	if recording.PodDisruptionBudgets, err = listers.PodDisruptionBudgetLister().List(); err != nil {
+		return nil, err
+	}
+	if recording.DaemonSets, err = listers.DaemonSetLister().List(labels.Everything()); err != nil {
+		return nil, err
+	}
+	if recording.ReplicationControllers, err = listers.ReplicationControllerLister().List(labels.Everything()); err != nil {
+		return nil, err
+	}
+	if recording.Jobs, err = listers.JobLister().List(labels.Everything()); err != nil {
+		return nil, err
+	}
+	if recording.ReplicaSets, err = listers.ReplicaSetLister().List(labels.Everything()); err != nil {
+		return nil, err
+	}
+	if recording.StatefulSets, err = listers.StatefulSetLister().List(labels.Everything()); err != nil {
+		return nil, err
+	}
+	return recording, nil
+}
+
+// WriteFile writes the recording to the file as JSON. The file is replaced
+// atomically, so that it can be copied while Cluster Autoscaler is running.
+func WriteFile(path string, recording *Recording) error {
+	data, err := json.Marshal(recording)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadFile reads a recording written by WriteFile.
+func ReadFile(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	recording := &Recording{}
+	if err := json.Unmarshal(data, recording); err != nil {
+		return nil, fmt.Errorf("can't parse recording %s: %v", path, err)
+	}
+	return recording, nil
+}
+
+// ClusterSnapshot returns a cluster snapshot with the recorded nodes and pods.
+func (r *Recording) ClusterSnapshot() (clustersnapshot.ClusterSnapshot, error) {
+	podsByNode := make(map[string][]*apiv1.Pod)
+	for _, pod := range r.Pods {
+		podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+	}
+	snapshot := clustersnapshot.NewBasicClusterSnapshot()
+	for _, node := range r.Nodes {
+		if err := snapshot.AddNodeWithPods(node, podsByNode[node.Name]); err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// ListerRegistry returns listers of the recorded objects.
+func (r *Recording) ListerRegistry() (kube_util.ListerRegistry, error) {
+	dsLister, err := kube_util.NewTestDaemonSetLister(r.DaemonSets)
+	if err != nil {
+		return nil, err
+	}
+	rcLister, err := kube_util.NewTestReplicationControllerLister(r.ReplicationControllers)
+	if err != nil {
+		return nil, err
+	}
+	jobLister, err := kube_util.NewTestJobLister(r.Jobs)
+	if err != nil {
+		return nil, err
+	}
+	rsLister, err := kube_util.NewTestReplicaSetLister(r.ReplicaSets)
+	if err != nil {
+		return nil, err
+	}
+	ssLister, err := kube_util.NewTestStatefulSetLister(r.StatefulSets)
+	if err != nil {
+		return nil, err
+	}
+	nodeLister := kube_util.NewTestNodeLister(r.Nodes)
+	return kube_util.NewListerRegistry(nodeLister, nodeLister, kube_util.NewTestPodLister(r.Pods), kube_util.NewTestPodDisruptionBudgetLister(r.PodDisruptionBudgets),
+		dsLister, rcLister, jobLister, rsLister, ssLister), nil
+}
+
+// PodRef identifies a pod in the replay result.
+type PodRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// NodeResult is the outcome of the simulated removal of a node.
+type NodeResult struct {
+	Node        string  `json:"node"`
+	Utilization float64 `json:"utilization"`
+	Removable   bool    `json:"removable"`
+	// Reason is why the node can't be removed, set only if it isn't removable.
+	Reason string `json:"reason,omitempty"`
+	// BlockingPod is the pod which can't be moved, set only for BlockedByPod.
+	BlockingPod *PodRef `json:"blockingPod,omitempty"`
+	// BlockingPods are all pods which can't be moved, starting with
+	// BlockingPod, set only if there is more than one.
+	BlockingPods []PodRef `json:"blockingPods,omitempty"`
+	// UnschedulablePod is the first pod which doesn't fit on any other node,
+	// set only for NoPlaceToMovePods.
+	UnschedulablePod *PodRef `json:"unschedulablePod,omitempty"`
+	// PodsToReschedule are the pods moved to other nodes, set only if the
+	// node is removable.
+	PodsToReschedule []PodRef `json:"podsToReschedule,omitempty"`
+}
+
+// Replay simulates removal of the candidates one by one, the same way scale
+// down does, with nodes found removable earlier no longer accepting pods and
+// their pods using up disruption budgets. If candidates are empty, the recorded
+// ones are used. Eligibility checks depending on the cloud provider, e.g. node
+// group min size or scale-down utilization threshold, are not replayed.
+func Replay(recording *Recording, predicateChecker predicatechecker.PredicateChecker, drainabilityRules rules.Rules, candidates []string) ([]NodeResult, error) {
+	snapshot, err := recording.ClusterSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	listers, err := recording.ListerRegistry()
+	if err != nil {
+		return nil, err
+	}
+	remainingPdbTracker := pdb.NewBasicRemainingPdbTracker()
+	if err := remainingPdbTracker.SetPdbs(recording.PodDisruptionBudgets); err != nil {
+		return nil, err
+	}
+	if drainabilityRules == nil {
+		drainabilityRules = rules.Default(recording.DeleteOptions)
+	}
+	if len(candidates) == 0 {
+		candidates = recording.Candidates
+	}
+	destinations := make(map[string]bool, len(recording.Destinations))
+	for _, destination := range recording.Destinations {
+		destinations[destination] = true
+	}
+
+	rs := simulator.NewRemovalSimulator(listers, snapshot, predicateChecker, simulator.NewUsageTracker(), recording.DeleteOptions, drainabilityRules, true)
+	var results []NodeResult
+	for _, candidate := range candidates {
+		nodeInfo, err := snapshot.NodeInfos().Get(candidate)
+		if err != nil {
+			return nil, fmt.Errorf("node %s not found in the recording", candidate)
+		}
+		result := NodeResult{Node: candidate}
+		utilInfo, err := utilization.Calculate(nodeInfo, recording.IgnoreDaemonSetsUtilization, recording.IgnoreMirrorPodsUtilization, nil, recording.Timestamp, recording.DeleteOptions.LongTerminatingPodThreshold)
+		if err == nil {
+			result.Utilization = utilInfo.Utilization
+		}
+		removable, unremovable := rs.SimulateNodeRemoval(candidate, destinations, recording.Timestamp, remainingPdbTracker)
+		if removable != nil {
+			result.Removable = true
+			for _, pod := range removable.PodsToReschedule {
+				result.PodsToReschedule = append(result.PodsToReschedule, PodRef{Namespace: pod.Namespace, Name: pod.Name})
+			}
+			delete(destinations, candidate)
+			remainingPdbTracker.RemovePods(removable.PodsToDisrupt(recording.DeleteOptions.RespectDaemonSetPdbs))
+		}
+		if unremovable != nil {
+			result.Reason = unremovable.Reason.String()
+			if unremovable.BlockingPod != nil && unremovable.BlockingPod.Pod != nil {
+				result.BlockingPod = &PodRef{Namespace: unremovable.BlockingPod.Pod.Namespace, Name: unremovable.BlockingPod.Pod.Name, Reason: string(unremovable.BlockingPod.ReasonID())}
+			}
+			if len(unremovable.BlockingPods) > 1 {
+				for _, blockingPod := range unremovable.BlockingPods {
+					result.BlockingPods = append(result.BlockingPods, PodRef{Namespace: blockingPod.Pod.Namespace, Name: blockingPod.Pod.Name, Reason: string(blockingPod.ReasonID())})
+				}
+			}
+			if unremovable.UnschedulablePod != nil && unremovable.UnschedulablePod.Pod != nil {
+				result.UnschedulablePod = &PodRef{Namespace: unremovable.UnschedulablePod.Pod.Namespace, Name: unremovable.UnschedulablePod.Pod.Name, Reason: unremovable.UnschedulablePod.Reason}
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func nodeNames(nodes []*apiv1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	return names
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package replay
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/options"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	n1 := BuildTestNode("n1", 1000, 1000)
+	n2 := BuildTestNode("n2", 1000, 1000)
+	n3 := BuildTestNode("n3", 1000, 1000)
+	for _, node := range []*apiv1.Node{n1, n2, n3} {
+		SetNodeReadyState(node, true, time.Time{})
+	}
+	rs := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default", UID: "rs-uid"}}
+	p1 := replicatedPod("p1", 600, "n1")
+	p2 := replicatedPod("p2", 600, "n2")
+	p3 := BuildTestPod("p3", 100, 100)
+	p3.Spec.NodeName = "n3"
+
+	recording := &Recording{
+		Timestamp:     time.Date(2023, time.October, 1, 10, 0, 0, 0, time.UTC),
+		Nodes:         []*apiv1.Node{n1, n2, n3},
+		Pods:          []*apiv1.Pod{p1, p2, p3},
+		ReplicaSets:   []*appsv1.ReplicaSet{rs},
+		DeleteOptions: options.NodeDeleteOptions{SkipNodesWithCustomControllerPods: true},
+		Candidates:    []string{"n1", "n2", "n3"},
+		Destinations:  []string{"n1", "n2", "n3"},
+	}
+
+	// Recording the state rebuilt from the recording results in the same state.
+	snapshot, err := recording.ClusterSnapshot()
+	assert.NoError(t, err)
+	listers, err := recording.ListerRegistry()
+	assert.NoError(t, err)
+	recorded, err := Record(snapshot, listers, recording.DeleteOptions, []*apiv1.Node{n1, n2, n3}, []*apiv1.Node{n1, n2, n3}, recording.Timestamp)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, recording.Nodes, recorded.Nodes)
+	assert.ElementsMatch(t, recording.Pods, recorded.Pods)
+	assert.Len(t, recorded.ReplicaSets, 1)
+	assert.Equal(t, recording.Candidates, recorded.Candidates)
+
+	path := filepath.Join(t.TempDir(), "recording.json")
+	assert.NoError(t, WriteFile(path, recorded))
+	read, err := ReadFile(path)
+	assert.NoError(t, err)
+
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+	results, err := Replay(read, predicateChecker, nil, nil)
+	assert.NoError(t, err)
+	if assert.Len(t, results, 3) {
+		assert.Equal(t, "n1", results[0].Node)
+		assert.True(t, results[0].Removable)
+		assert.Equal(t, 0.6, results[0].Utilization)
+		assert.Equal(t, []PodRef{{Namespace: "default", Name: "p1"}}, results[0].PodsToReschedule)
+
+		// p1 took the remaining space on n3 and n1 is being removed.
+		assert.Equal(t, "n2", results[1].Node)
+		assert.False(t, results[1].Removable)
+		assert.Equal(t, "NoPlaceToMovePods", results[1].Reason)
+		if assert.NotNil(t, results[1].UnschedulablePod) {
+			assert.Equal(t, "p2", results[1].UnschedulablePod.Name)
+		}
+
+		assert.Equal(t, "n3", results[2].Node)
+		assert.Equal(t, "BlockedByPod", results[2].Reason)
+		assert.Equal(t, &PodRef{Namespace: "default", Name: "p3", Reason: "NotReplicated"}, results[2].BlockingPod)
+	}
+
+	// Removal of a single node can be replayed.
+	results, err = Replay(read, predicateChecker, nil, []string{"n2"})
+	assert.NoError(t, err)
+	if assert.Len(t, results, 1) {
+		assert.True(t, results[0].Removable)
+	}
+
+	_, err = Replay(read, predicateChecker, nil, []string{"n4"})
+	assert.Error(t, err)
+}
+
+func replicatedPod(name string, cpu int64, nodeName string) *apiv1.Pod {
+	pod := BuildTestPod(name, cpu, 100)
+	pod.Namespace = "default"
+	pod.Spec.NodeName = nodeName
+	pod.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "apps/v1", "rs-uid")
+	return pod
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// scale-down-replay replays scale-down simulation against a recording written
+// by Cluster Autoscaler with --scale-down-recording-file, printing for each
+// candidate whether it is removable and why not.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+
+	"k8s.io/client-go/informers"
+	clientsetfake "k8s.io/client-go/kubernetes/fake"
+	klog "k8s.io/klog/v2"
+	scheduler_config "k8s.io/kubernetes/pkg/scheduler/apis/config"
+	scheduler_config_latest "k8s.io/kubernetes/pkg/scheduler/apis/config/latest"
+
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/replay"
+	scheduler_util "k8s.io/autoscaler/cluster-autoscaler/utils/scheduler"
+)
+
+func main() {
+	recordingFile := flag.String("recording", "", "Path of the recording written by Cluster Autoscaler with --scale-down-recording-file")
+	nodes := flag.String("nodes", "", "Comma-separated names of nodes whose removal is simulated, in order. The recorded scale-down candidates if empty.")
+	schedulerConfigFile := flag.String("scheduler-config-file", "", "Path of the scheduler config used by Cluster Autoscaler, if any")
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	if *recordingFile == "" {
+		klog.Fatalf("--recording is required")
+	}
+	recording, err := replay.ReadFile(*recordingFile)
+	if err != nil {
+		klog.Fatalf("Failed to read recording: %v", err)
+	}
+
+	var schedConfig *scheduler_config.KubeSchedulerConfiguration
+	if *schedulerConfigFile != "" {
+		schedConfig, err = scheduler_util.ConfigFromPath(*schedulerConfigFile)
+	} else {
+		schedConfig, err = scheduler_config_latest.Default()
+	}
+	if err != nil {
+		klog.Fatalf("Failed to load scheduler config: %v", err)
+	}
+	// The scheduler framework only needs informers for volume related plugins,
+	// which don't have the recorded objects either way.
+	predicateChecker, err := predicatechecker.NewSchedulerBasedPredicateChecker(informers.NewSharedInformerFactory(clientsetfake.NewSimpleClientset(), 0), schedConfig)
+	if err != nil {
+		klog.Fatalf("Failed to create predicate checker: %v", err)
+	}
+
+	var candidates []string
+	if *nodes != "" {
+		candidates = strings.Split(*nodes, ",")
+	}
+	results, err := replay.Replay(recording, predicateChecker, nil, candidates)
+	if err != nil {
+		klog.Fatalf("Failed to replay scale-down simulation: %v", err)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		klog.Fatalf("Failed to print results: %v", err)
+	}
+}
@@ -22,8 +22,8 @@ import (
 	"k8s.io/autoscaler/cluster-autoscaler/utils"
 
 	apiv1 "k8s.io/api/core/v1"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
-	pod_utils "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_utils "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 )
 
 // SimilarPodsSchedulingInfo data structure is used to avoid running predicates #pending_pods * #nodes
@@ -0,0 +1,173 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/scheduling"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+)
+
+// spreadConstraint is a group of pods spread across the topology domains
+// defined by a node label.
+type spreadConstraint struct {
+	namespace   string
+	topologyKey string
+	selector    labels.Selector
+}
+
+// spreadConstraints returns the distinct topology spread constraints of the
+// pods, both hard and soft ones.
+func spreadConstraints(pods []*apiv1.Pod) []spreadConstraint {
+	var result []spreadConstraint
+	seen := make(map[string]bool)
+	for _, pod := range pods {
+		for _, constraint := range pod.Spec.TopologySpreadConstraints {
+			selector, err := constraintSelector(pod, constraint)
+			if err != nil {
+				continue
+			}
+			key := fmt.Sprintf("%s/%s/%s", pod.Namespace, constraint.TopologyKey, selector.String())
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, spreadConstraint{namespace: pod.Namespace, topologyKey: constraint.TopologyKey, selector: selector})
+		}
+	}
+	return result
+}
+
+// constraintSelector returns the selector of pods the constraint applies to,
+// including the pod's values of matchLabelKeys, same as the scheduler.
+func constraintSelector(pod *apiv1.Pod, constraint apiv1.TopologySpreadConstraint) (labels.Selector, error) {
+	selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range constraint.MatchLabelKeys {
+		value, found := pod.Labels[key]
+		if !found {
+			continue
+		}
+		requirement, err := labels.NewRequirement(key, selection.Equals, []string{value})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*requirement)
+	}
+	return selector, nil
+}
+
+func hasHardSpreadConstraints(pod *apiv1.Pod) bool {
+	for _, constraint := range pod.Spec.TopologySpreadConstraints {
+		if constraint.WhenUnsatisfiable == apiv1.DoNotSchedule {
+			return true
+		}
+	}
+	return false
+}
+
+// spreadSkews returns the skew of each of the constraints, i.e. the difference
+// between the highest and the lowest number of matching pods in a topology
+// domain, ignoring excludedNode.
+func spreadSkews(nodeInfos []*schedulerframework.NodeInfo, constraints []spreadConstraint, excludedNode string) []int {
+	skews := make([]int, len(constraints))
+	for i, constraint := range constraints {
+		counts := make(map[string]int)
+		for _, nodeInfo := range nodeInfos {
+			node := nodeInfo.Node()
+			if node == nil || node.Name == excludedNode {
+				continue
+			}
+			domain, found := node.Labels[constraint.topologyKey]
+			if !found {
+				continue
+			}
+			counts[domain] += 0
+			for _, podInfo := range nodeInfo.Pods {
+				pod := podInfo.Pod
+				if pod.Namespace == constraint.namespace && pod.DeletionTimestamp == nil && constraint.selector.Matches(labels.Set(pod.Labels)) {
+					counts[domain]++
+				}
+			}
+		}
+		first := true
+		var min, max int
+		for _, count := range counts {
+			if first || count < min {
+				min = count
+			}
+			if first || count > max {
+				max = count
+			}
+			first = false
+		}
+		skews[i] = max - min
+	}
+	return skews
+}
+
+// spreadSkewIncrease sums up by how much the skews of the constraints grew.
+func spreadSkewIncrease(before, after []int) int {
+	increase := 0
+	if len(before) != len(after) {
+		return increase
+	}
+	for i := range before {
+		if after[i] > before[i] {
+			increase += after[i] - before[i]
+		}
+	}
+	return increase
+}
+
+// checkHardSpreadConstraints verifies that each of the rescheduled pods with
+// hard topology spread constraints still satisfies them once all the other
+// pods are rescheduled. The pods are scheduled one by one, so a pod placed
+// early could be invalidated by the following ones, while the controllers
+// can recreate them in any order. The first pod violating its constraints is
+// returned along with the reason.
+func (r *RemovalSimulator) checkHardSpreadConstraints(statuses []scheduling.Status) (*apiv1.Pod, string, error) {
+	for _, status := range statuses {
+		pod := status.Pod
+		if !hasHardSpreadConstraints(pod) {
+			continue
+		}
+		if err := r.clusterSnapshot.RemovePod(pod.Namespace, pod.Name, status.NodeName); err != nil {
+			return nil, "", err
+		}
+		predicateErr := r.predicateChecker.CheckPredicates(r.clusterSnapshot, pod, status.NodeName)
+		if err := r.clusterSnapshot.AddPod(pod, status.NodeName); err != nil {
+			return nil, "", err
+		}
+		if predicateErr != nil {
+			reasons := predicateErr.Reasons()
+			if len(reasons) == 0 {
+				reasons = []string{predicateErr.Message()}
+			}
+			return pod, fmt.Sprintf("pod wouldn't fit node %s once the other pods are rescheduled: %s", status.NodeName, strings.Join(reasons, ", ")), nil
+		}
+	}
+	return nil, "", nil
+}
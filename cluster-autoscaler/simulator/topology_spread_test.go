@@ -0,0 +1,149 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/clustersnapshot"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/predicatechecker"
+	kube_util "k8s.io/autoscaler/cluster-autoscaler/utils/kubernetes"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testZoneLabel = "topology.kubernetes.io/zone"
+
+func buildZonalTestNode(name, zone string, cpu int64) *apiv1.Node {
+	node := BuildTestNode(name, cpu, 2000000)
+	node.Labels[testZoneLabel] = zone
+	SetNodeReadyState(node, true, time.Time{})
+	return node
+}
+
+func buildSpreadTestPod(name, nodeName string, cpu int64, whenUnsatisfiable *apiv1.UnsatisfiableConstraintAction) *apiv1.Pod {
+	pod := BuildTestPod(name, cpu, 1000)
+	pod.OwnerReferences = GenerateOwnerReferences("rs", "ReplicaSet", "extensions/v1beta1", "")
+	pod.Labels = map[string]string{"tier": "web"}
+	pod.Spec.NodeName = nodeName
+	if whenUnsatisfiable != nil {
+		pod.Spec.TopologySpreadConstraints = []apiv1.TopologySpreadConstraint{{
+			MaxSkew:           1,
+			TopologyKey:       testZoneLabel,
+			WhenUnsatisfiable: *whenUnsatisfiable,
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web"}},
+		}}
+	}
+	return pod
+}
+
+func newSpreadTestRemovalSimulator(t *testing.T, nodes []*apiv1.Node, pods []*apiv1.Pod) *RemovalSimulator {
+	replicas := int32(10)
+	rsLister, err := kube_util.NewTestReplicaSetLister([]*appsv1.ReplicaSet{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "rs", Namespace: "default"},
+			Spec:       appsv1.ReplicaSetSpec{Replicas: &replicas},
+		},
+	})
+	assert.NoError(t, err)
+	registry := kube_util.NewListerRegistry(nil, nil, nil, nil, nil, nil, nil, rsLister, nil)
+	clusterSnapshot := clustersnapshot.NewBasicClusterSnapshot()
+	clustersnapshot.InitializeClusterSnapshotOrDie(t, clusterSnapshot, nodes, pods)
+	predicateChecker, err := predicatechecker.NewTestPredicateChecker()
+	assert.NoError(t, err)
+	return NewRemovalSimulator(registry, clusterSnapshot, predicateChecker, NewUsageTracker(), testDeleteOptions(), nil, false)
+}
+
+func TestSimulateNodeRemovalHardSpreadConstraints(t *testing.T) {
+	doNotSchedule := apiv1.DoNotSchedule
+	// Only a2 has room for the pods of r, so both of them land in zone a.
+	nodes := []*apiv1.Node{
+		buildZonalTestNode("r", "a", 1000),
+		buildZonalTestNode("a2", "a", 2000),
+		buildZonalTestNode("b", "b", 1000),
+	}
+	existing := []*apiv1.Pod{
+		buildSpreadTestPod("web-a2", "a2", 100, nil),
+		buildSpreadTestPod("web-b", "b", 1000, nil),
+	}
+
+	for desc, tc := range map[string]struct {
+		pods          []*apiv1.Pod
+		wantRemovable bool
+	}{
+		"constrained pod fits regardless of the order": {
+			pods:          []*apiv1.Pod{buildSpreadTestPod("spread", "r", 100, &doNotSchedule)},
+			wantRemovable: true,
+		},
+		"constrained pod doesn't fit once the other pod is rescheduled": {
+			pods: []*apiv1.Pod{
+				buildSpreadTestPod("spread", "r", 100, &doNotSchedule),
+				buildSpreadTestPod("other", "r", 100, nil),
+			},
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			r := newSpreadTestRemovalSimulator(t, nodes, append(append([]*apiv1.Pod{}, existing...), tc.pods...))
+			toRemove, unremovable := r.SimulateNodeRemoval("r", map[string]bool{"r": true, "a2": true, "b": true}, time.Now(), nil)
+			if tc.wantRemovable {
+				assert.NotNil(t, toRemove)
+				assert.Nil(t, unremovable)
+				return
+			}
+			assert.Nil(t, toRemove)
+			if assert.NotNil(t, unremovable) {
+				assert.Equal(t, NoPlaceToMovePods, unremovable.Reason)
+				if assert.NotNil(t, unremovable.UnschedulablePod) {
+					assert.Equal(t, "spread", unremovable.UnschedulablePod.Pod.Name)
+					assert.Contains(t, unremovable.UnschedulablePod.Reason, "once the other pods are rescheduled")
+				}
+			}
+		})
+	}
+}
+
+func TestSimulateNodeRemovalSpreadSkewIncrease(t *testing.T) {
+	scheduleAnyway := apiv1.ScheduleAnyway
+	// a2 has no room, so the pod of a1 moves to zone b.
+	nodes := []*apiv1.Node{
+		buildZonalTestNode("a1", "a", 1000),
+		buildZonalTestNode("a2", "a", 50),
+		buildZonalTestNode("b1", "b", 2000),
+	}
+	pods := []*apiv1.Pod{
+		buildSpreadTestPod("web-a1", "a1", 100, &scheduleAnyway),
+		buildSpreadTestPod("web-b1", "b1", 100, &scheduleAnyway),
+	}
+	r := newSpreadTestRemovalSimulator(t, nodes, pods)
+	destinations := map[string]bool{"a1": true, "a2": true, "b1": true}
+
+	toRemove, _ := r.SimulateNodeRemoval("a1", destinations, time.Now(), nil)
+	if assert.NotNil(t, toRemove) {
+		// Zone a goes from 1 to 0 matching pods, zone b from 1 to 2.
+		assert.Equal(t, 2, toRemove.SpreadSkewIncrease)
+	}
+	toRemove, _ = r.SimulateNodeRemoval("b1", destinations, time.Now(), nil)
+	if assert.NotNil(t, toRemove) {
+		// The pod of b1 can only move to a1, and zone b disappears.
+		assert.Equal(t, 0, toRemove.SpreadSkewIncrease)
+	}
+}
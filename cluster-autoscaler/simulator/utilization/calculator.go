@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utilization
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
+
+	apiv1 "k8s.io/api/core/v1"
+	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
+
+	klog "k8s.io/klog/v2"
+)
+
+// Mode selects how utilization of nodes is calculated for scale-down.
+type Mode string
+
+const (
+	// RequestsMode calculates utilization from resource requests of pods.
+	RequestsMode Mode = "requests"
+	// UsageMode calculates utilization from actual resource usage of the node.
+	UsageMode Mode = "usage"
+	// HybridMode calculates utilization of each resource as the larger of the
+	// requests and usage based utilization.
+	HybridMode Mode = "hybrid"
+)
+
+// ParseMode parses a utilization mode. Empty string means RequestsMode.
+func ParseMode(mode string) (Mode, error) {
+	switch Mode(mode) {
+	case "", RequestsMode:
+		return RequestsMode, nil
+	case UsageMode, HybridMode:
+		return Mode(mode), nil
+	}
+	return "", fmt.Errorf("unknown utilization mode %q, expected one of: %s, %s, %s", mode, RequestsMode, UsageMode, HybridMode)
+}
+
+// Calculator calculates utilization of nodes. Pods terminating for longer
+// than their termination grace period plus longTerminatingThreshold are
+// ignored.
+type Calculator interface {
+	Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMirrorPods bool, gpuConfig *cloudprovider.GpuConfig, currentTime time.Time, longTerminatingThreshold time.Duration) (Info, error)
+}
+
+// NewCalculator returns a Calculator for the mode. Usage based modes read
+// usage of nodes from the provider, which can be nil.
+func NewCalculator(mode Mode, usage UsageProvider) Calculator {
+	switch mode {
+	case UsageMode:
+		return &usageCalculator{usage: usage}
+	case HybridMode:
+		return &usageCalculator{usage: usage, hybrid: true}
+	}
+	return RequestsCalculator{}
+}
+
+// RequestsCalculator calculates utilization from resource requests of pods,
+// see Calculate.
+type RequestsCalculator struct{}
+
+// Calculate calculates utilization of a node from resource requests of pods.
+func (RequestsCalculator) Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMirrorPods bool, gpuConfig *cloudprovider.GpuConfig, currentTime time.Time, longTerminatingThreshold time.Duration) (Info, error) {
+	return Calculate(nodeInfo, skipDaemonSetPods, skipMirrorPods, gpuConfig, currentTime, longTerminatingThreshold)
+}
+
+// usageCalculator calculates utilization from actual usage of cpu and memory.
+// Usage isn't reported per pod, so requests of skipped DaemonSet and mirror
+// pods are subtracted from both the usage and the allocatable of the node.
+// Nodes with GPU and nodes without known usage fall back to requests.
+type usageCalculator struct {
+	usage UsageProvider
+	// hybrid takes the larger of requests and usage based utilization.
+	hybrid bool
+}
+
+// Calculate calculates utilization of a node from its usage.
+func (c *usageCalculator) Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMirrorPods bool, gpuConfig *cloudprovider.GpuConfig, currentTime time.Time, longTerminatingThreshold time.Duration) (Info, error) {
+	if gpuConfig != nil {
+		return Calculate(nodeInfo, skipDaemonSetPods, skipMirrorPods, gpuConfig, currentTime, longTerminatingThreshold)
+	}
+	var usage apiv1.ResourceList
+	found := false
+	if c.usage != nil {
+		usage, found = c.usage.NodeUsage(nodeInfo.Node().Name)
+	}
+	if !found {
+		klog.V(4).Infof("Usage of node %s is unknown, calculating its utilization from requests", nodeInfo.Node().Name)
+		return Calculate(nodeInfo, skipDaemonSetPods, skipMirrorPods, gpuConfig, currentTime, longTerminatingThreshold)
+	}
+
+	cpu, err := c.utilizationOfResource(nodeInfo, usage, apiv1.ResourceCPU, skipDaemonSetPods, skipMirrorPods, currentTime, longTerminatingThreshold)
+	if err != nil {
+		return Info{}, err
+	}
+	mem, err := c.utilizationOfResource(nodeInfo, usage, apiv1.ResourceMemory, skipDaemonSetPods, skipMirrorPods, currentTime, longTerminatingThreshold)
+	if err != nil {
+		return Info{}, err
+	}
+	return cpuAndMemoryInfo(cpu, mem), nil
+}
+
+func (c *usageCalculator) utilizationOfResource(nodeInfo *schedulerframework.NodeInfo, usage apiv1.ResourceList, resourceName apiv1.ResourceName, skipDaemonSetPods, skipMirrorPods bool, currentTime time.Time, longTerminatingThreshold time.Duration) (float64, error) {
+	requests, err := calculateRequestsOfResource(nodeInfo, resourceName, skipDaemonSetPods, skipMirrorPods, currentTime, longTerminatingThreshold)
+	if err != nil {
+		return 0, err
+	}
+	resourceUsage := usage[resourceName]
+	podsUsage := resourceUsage.MilliValue() - requests.skipped
+	if podsUsage < 0 {
+		podsUsage = 0
+	}
+	available := float64(requests.allocatable - requests.skipped)
+	utilization := float64(podsUsage) / available
+	if requestsUtilization := float64(requests.podsRequest) / available; c.hybrid && requestsUtilization > utilization {
+		utilization = requestsUtilization
+	}
+	return utilization, nil
+}
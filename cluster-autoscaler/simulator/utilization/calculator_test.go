@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utilization
+
+import (
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeUsage map[string]apiv1.ResourceList
+
+func (u fakeUsage) NodeUsage(nodeName string) (apiv1.ResourceList, bool) {
+	usage, found := u[nodeName]
+	return usage, found
+}
+
+func TestParseMode(t *testing.T) {
+	for value, want := range map[string]Mode{
+		"":         RequestsMode,
+		"requests": RequestsMode,
+		"usage":    UsageMode,
+		"hybrid":   HybridMode,
+	} {
+		got, err := ParseMode(value)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseMode("actual")
+	assert.Error(t, err)
+}
+
+func TestCalculator(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	node := BuildTestNode("node1", 2000, 2000000)
+	SetNodeReadyState(node, true, time.Time{})
+	pod := BuildTestPod("p1", 500, 200000)
+	dsPod := BuildDSTestPod("ds", 200, 100000)
+	usage := fakeUsage{"node1": {
+		apiv1.ResourceCPU:    resource.MustParse("1600m"),
+		apiv1.ResourceMemory: resource.MustParse("100000"),
+	}}
+
+	for desc, tc := range map[string]struct {
+		mode              Mode
+		usage             UsageProvider
+		skipDaemonSetPods bool
+		gpu               bool
+		wantCpu           float64
+		wantMem           float64
+	}{
+		"requests": {
+			mode:    RequestsMode,
+			usage:   usage,
+			wantCpu: 700.0 / 2000,
+			wantMem: 300000.0 / 2000000,
+		},
+		"usage": {
+			mode:    UsageMode,
+			usage:   usage,
+			wantCpu: 1600.0 / 2000,
+			wantMem: 100000.0 / 2000000,
+		},
+		"usage with skipped daemonset pods": {
+			mode:              UsageMode,
+			usage:             usage,
+			skipDaemonSetPods: true,
+			wantCpu:           1400.0 / 1800,
+			wantMem:           0,
+		},
+		"hybrid": {
+			mode:    HybridMode,
+			usage:   usage,
+			wantCpu: 1600.0 / 2000,
+			wantMem: 300000.0 / 2000000,
+		},
+		"usage without usage provider falls back to requests": {
+			mode:    UsageMode,
+			wantCpu: 700.0 / 2000,
+			wantMem: 300000.0 / 2000000,
+		},
+		"usage of unknown node falls back to requests": {
+			mode:    UsageMode,
+			usage:   fakeUsage{},
+			wantCpu: 700.0 / 2000,
+			wantMem: 300000.0 / 2000000,
+		},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			nodeInfo := newNodeInfo(node, pod, dsPod)
+			got, err := NewCalculator(tc.mode, tc.usage).Calculate(nodeInfo, tc.skipDaemonSetPods, false, nil, testTime, drain.PodLongTerminatingExtraThreshold)
+			assert.NoError(t, err)
+			assert.InDelta(t, tc.wantCpu, got.CpuUtil, 0.0001)
+			assert.InDelta(t, tc.wantMem, got.MemUtil, 0.0001)
+			assert.Equal(t, apiv1.ResourceCPU, got.ResourceName)
+			assert.InDelta(t, tc.wantCpu, got.Utilization, 0.0001)
+		})
+	}
+}
+
+func TestCalculatorGpuFallsBackToRequests(t *testing.T) {
+	testTime := time.Date(2020, time.December, 18, 17, 0, 0, 0, time.UTC)
+	node := BuildTestNode("node1", 2000, 2000000)
+	AddGpusToNode(node, 2)
+	pod := BuildTestPod("p1", 500, 200000)
+	RequestGpuForPod(pod, 1)
+	nodeInfo := newNodeInfo(node, pod)
+	got, err := NewCalculator(UsageMode, fakeUsage{"node1": {apiv1.ResourceCPU: resource.MustParse("2")}}).Calculate(nodeInfo, false, false, GetGpuConfigFromNode(node), testTime, drain.PodLongTerminatingExtraThreshold)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.5, got.Utilization, 0.0001)
+	assert.Equal(t, GetGpuConfigFromNode(node).ResourceName, got.ResourceName)
+}
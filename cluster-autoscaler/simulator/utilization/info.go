@@ -21,8 +21,8 @@ import (
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
-	"k8s.io/autoscaler/cluster-autoscaler/utils/drain"
-	pod_util "k8s.io/autoscaler/cluster-autoscaler/utils/pod"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
+	pod_util "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/pod"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -45,10 +45,11 @@ type Info struct {
 // Calculate calculates utilization of a node, defined as maximum of (cpu,
 // memory) or gpu utilization based on if the node has GPU or not. Per resource
 // utilization is the sum of requests for it divided by allocatable. It also
-// returns the individual cpu, memory and gpu utilization.
-func Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMirrorPods bool, gpuConfig *cloudprovider.GpuConfig, currentTime time.Time) (utilInfo Info, err error) {
+// returns the individual cpu, memory and gpu utilization. Pods terminating for
+// longer than their termination grace period plus longTerminatingThreshold are ignored.
+func Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMirrorPods bool, gpuConfig *cloudprovider.GpuConfig, currentTime time.Time, longTerminatingThreshold time.Duration) (utilInfo Info, err error) {
 	if gpuConfig != nil {
-		gpuUtil, err := CalculateUtilizationOfResource(nodeInfo, gpuConfig.ResourceName, skipDaemonSetPods, skipMirrorPods, currentTime)
+		gpuUtil, err := CalculateUtilizationOfResource(nodeInfo, gpuConfig.ResourceName, skipDaemonSetPods, skipMirrorPods, currentTime, longTerminatingThreshold)
 		if err != nil {
 			klog.V(3).Infof("node %s has unready GPU resource: %s", nodeInfo.Node().Name, gpuConfig.ResourceName)
 			// Return 0 if GPU is unready. This will guarantee we can still scale down a node with unready GPU.
@@ -58,15 +59,19 @@ func Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMir
 		return Info{GpuUtil: gpuUtil, ResourceName: gpuConfig.ResourceName, Utilization: gpuUtil}, err
 	}
 
-	cpu, err := CalculateUtilizationOfResource(nodeInfo, apiv1.ResourceCPU, skipDaemonSetPods, skipMirrorPods, currentTime)
+	cpu, err := CalculateUtilizationOfResource(nodeInfo, apiv1.ResourceCPU, skipDaemonSetPods, skipMirrorPods, currentTime, longTerminatingThreshold)
 	if err != nil {
 		return Info{}, err
 	}
-	mem, err := CalculateUtilizationOfResource(nodeInfo, apiv1.ResourceMemory, skipDaemonSetPods, skipMirrorPods, currentTime)
+	mem, err := CalculateUtilizationOfResource(nodeInfo, apiv1.ResourceMemory, skipDaemonSetPods, skipMirrorPods, currentTime, longTerminatingThreshold)
 	if err != nil {
 		return Info{}, err
 	}
 
+	return cpuAndMemoryInfo(cpu, mem), nil
+}
+
+func cpuAndMemoryInfo(cpu, mem float64) Info {
 	utilization := Info{CpuUtil: cpu, MemUtil: mem}
 
 	if cpu > mem {
@@ -77,17 +82,35 @@ func Calculate(nodeInfo *schedulerframework.NodeInfo, skipDaemonSetPods, skipMir
 		utilization.Utilization = mem
 	}
 
-	return utilization, nil
+	return utilization
 }
 
 // CalculateUtilizationOfResource calculates utilization of a given resource for a node.
-func CalculateUtilizationOfResource(nodeInfo *schedulerframework.NodeInfo, resourceName apiv1.ResourceName, skipDaemonSetPods, skipMirrorPods bool, currentTime time.Time) (float64, error) {
+func CalculateUtilizationOfResource(nodeInfo *schedulerframework.NodeInfo, resourceName apiv1.ResourceName, skipDaemonSetPods, skipMirrorPods bool, currentTime time.Time, longTerminatingThreshold time.Duration) (float64, error) {
+	requests, err := calculateRequestsOfResource(nodeInfo, resourceName, skipDaemonSetPods, skipMirrorPods, currentTime, longTerminatingThreshold)
+	if err != nil {
+		return 0, err
+	}
+	return float64(requests.podsRequest) / float64(requests.allocatable-requests.skipped), nil
+}
+
+// resourceRequests holds milli values of a resource on a node.
+type resourceRequests struct {
+	// podsRequest is the sum of requests of pods taken into account.
+	podsRequest int64
+	// skipped is the sum of requests of skipped DaemonSet and mirror pods.
+	skipped int64
+	// allocatable is the allocatable amount of the node.
+	allocatable int64
+}
+
+func calculateRequestsOfResource(nodeInfo *schedulerframework.NodeInfo, resourceName apiv1.ResourceName, skipDaemonSetPods, skipMirrorPods bool, currentTime time.Time, longTerminatingThreshold time.Duration) (resourceRequests, error) {
 	nodeAllocatable, found := nodeInfo.Node().Status.Allocatable[resourceName]
 	if !found {
-		return 0, fmt.Errorf("failed to get %v from %s", resourceName, nodeInfo.Node().Name)
+		return resourceRequests{}, fmt.Errorf("failed to get %v from %s", resourceName, nodeInfo.Node().Name)
 	}
 	if nodeAllocatable.MilliValue() == 0 {
-		return 0, fmt.Errorf("%v is 0 at %s", resourceName, nodeInfo.Node().Name)
+		return resourceRequests{}, fmt.Errorf("%v is 0 at %s", resourceName, nodeInfo.Node().Name)
 	}
 	podsRequest := resource.MustParse("0")
 
@@ -114,8 +137,8 @@ func CalculateUtilizationOfResource(nodeInfo *schedulerframework.NodeInfo, resou
 			}
 			continue
 		}
-		// ignore Pods that should be terminated
-		if drain.IsPodLongTerminating(podInfo.Pod, currentTime) {
+		// ignore Pods that should be terminated and Pods that have completed
+		if drain.IsPodLongTerminatingWithThreshold(podInfo.Pod, currentTime, longTerminatingThreshold) || drain.IsPodCompleted(podInfo.Pod) {
 			continue
 		}
 		for _, container := range podInfo.Pod.Spec.Containers {
@@ -124,5 +147,9 @@ func CalculateUtilizationOfResource(nodeInfo *schedulerframework.NodeInfo, resou
 			}
 		}
 	}
-	return float64(podsRequest.MilliValue()) / float64(nodeAllocatable.MilliValue()-daemonSetAndMirrorPodsUtilization.MilliValue()), nil
+	return resourceRequests{
+		podsRequest: podsRequest.MilliValue(),
+		skipped:     daemonSetAndMirrorPodsUtilization.MilliValue(),
+		allocatable: nodeAllocatable.MilliValue(),
+	}, nil
 }
@@ -22,6 +22,7 @@ import (
 
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/drain"
 	. "k8s.io/autoscaler/cluster-autoscaler/utils/test"
 	"k8s.io/kubernetes/pkg/kubelet/types"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
@@ -39,7 +40,7 @@ func TestCalculate(t *testing.T) {
 	nodeInfo := newNodeInfo(node, pod, pod, pod2)
 
 	gpuConfig := GetGpuConfigFromNode(nodeInfo.Node())
-	utilInfo, err := Calculate(nodeInfo, false, false, gpuConfig, testTime)
+	utilInfo, err := Calculate(nodeInfo, false, false, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/10, utilInfo.Utilization, 0.01)
 
@@ -47,7 +48,7 @@ func TestCalculate(t *testing.T) {
 	nodeInfo = newNodeInfo(node2, pod, pod, pod2)
 
 	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
-	_, err = Calculate(nodeInfo, false, false, gpuConfig, testTime)
+	_, err = Calculate(nodeInfo, false, false, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.Error(t, err)
 
 	daemonSetPod3 := BuildTestPod("p3", 100, 200000)
@@ -59,13 +60,13 @@ func TestCalculate(t *testing.T) {
 
 	nodeInfo = newNodeInfo(node, pod, pod, pod2, daemonSetPod3, daemonSetPod4)
 	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
-	utilInfo, err = Calculate(nodeInfo, true, false, gpuConfig, testTime)
+	utilInfo, err = Calculate(nodeInfo, true, false, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.5/10, utilInfo.Utilization, 0.01)
 
 	nodeInfo = newNodeInfo(node, pod, pod2, daemonSetPod3)
 	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
-	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime)
+	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/10, utilInfo.Utilization, 0.01)
 
@@ -73,7 +74,15 @@ func TestCalculate(t *testing.T) {
 	terminatedPod.DeletionTimestamp = &metav1.Time{Time: testTime.Add(-10 * time.Minute)}
 	nodeInfo = newNodeInfo(node, pod, pod, pod2, terminatedPod)
 	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
-	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime)
+	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 2.0/10, utilInfo.Utilization, 0.01)
+
+	completedPod := BuildTestPod("podCompleted", 100, 200000)
+	completedPod.Status.Phase = apiv1.PodSucceeded
+	nodeInfo = newNodeInfo(node, pod, pod, pod2, completedPod)
+	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
+	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/10, utilInfo.Utilization, 0.01)
 
@@ -84,19 +93,19 @@ func TestCalculate(t *testing.T) {
 
 	nodeInfo = newNodeInfo(node, pod, pod, pod2, mirrorPod)
 	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
-	utilInfo, err = Calculate(nodeInfo, false, true, gpuConfig, testTime)
+	utilInfo, err = Calculate(nodeInfo, false, true, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/9.0, utilInfo.Utilization, 0.01)
 
 	nodeInfo = newNodeInfo(node, pod, pod2, mirrorPod)
 	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
-	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime)
+	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 2.0/10, utilInfo.Utilization, 0.01)
 
 	nodeInfo = newNodeInfo(node, pod, mirrorPod, daemonSetPod3)
 	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
-	utilInfo, err = Calculate(nodeInfo, true, true, gpuConfig, testTime)
+	utilInfo, err = Calculate(nodeInfo, true, true, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 1.0/8.0, utilInfo.Utilization, 0.01)
 
@@ -107,7 +116,7 @@ func TestCalculate(t *testing.T) {
 	TolerateGpuForPod(gpuPod)
 	nodeInfo = newNodeInfo(gpuNode, pod, pod, gpuPod)
 	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
-	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime)
+	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.NoError(t, err)
 	assert.InEpsilon(t, 1/1, utilInfo.Utilization, 0.01)
 
@@ -116,7 +125,7 @@ func TestCalculate(t *testing.T) {
 	AddGpuLabelToNode(gpuNode)
 	nodeInfo = newNodeInfo(gpuNode, pod, pod)
 	gpuConfig = GetGpuConfigFromNode(nodeInfo.Node())
-	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime)
+	utilInfo, err = Calculate(nodeInfo, false, false, gpuConfig, testTime, drain.PodLongTerminatingExtraThreshold)
 	assert.NoError(t, err)
 	assert.Zero(t, utilInfo.Utilization)
 }
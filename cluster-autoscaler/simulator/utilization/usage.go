@@ -0,0 +1,112 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utilization
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// nodeMetricsPath is the path of node metrics served by metrics-server.
+	nodeMetricsPath = "/apis/metrics.k8s.io/v1beta1/nodes"
+	// metricsServerTimeout is the timeout of listing node metrics.
+	metricsServerTimeout = 10 * time.Second
+)
+
+// UsageProvider provides actual resource usage of nodes.
+type UsageProvider interface {
+	// NodeUsage returns the resource usage of the node, or false if it isn't known.
+	NodeUsage(nodeName string) (apiv1.ResourceList, bool)
+}
+
+// nodeMetricsList mirrors NodeMetricsList of the metrics.k8s.io API, of which
+// only usage of nodes is needed.
+type nodeMetricsList struct {
+	Items []nodeMetrics `json:"items"`
+}
+
+type nodeMetrics struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Usage             apiv1.ResourceList `json:"usage"`
+}
+
+// MetricsServerUsageProvider provides usage of nodes reported by
+// metrics-server. Usage of all nodes is listed lazily, at most once per
+// refresh interval. It is safe for concurrent use.
+type MetricsServerUsageProvider struct {
+	client          rest.Interface
+	refreshInterval time.Duration
+
+	mutex       sync.Mutex
+	usage       map[string]apiv1.ResourceList
+	lastRefresh time.Time
+	now         func() time.Time
+}
+
+// NewMetricsServerUsageProvider creates a new MetricsServerUsageProvider
+// listing node metrics with the client, e.g. the discovery client of the
+// Kubernetes clientset.
+func NewMetricsServerUsageProvider(client rest.Interface, refreshInterval time.Duration) *MetricsServerUsageProvider {
+	return &MetricsServerUsageProvider{
+		client:          client,
+		refreshInterval: refreshInterval,
+		now:             time.Now,
+	}
+}
+
+// NodeUsage returns the usage of the node reported by metrics-server. Usage
+// isn't known if listing node metrics failed, e.g. because metrics-server
+// isn't installed.
+func (p *MetricsServerUsageProvider) NodeUsage(nodeName string) (apiv1.ResourceList, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if now := p.now(); p.lastRefresh.IsZero() || now.Sub(p.lastRefresh) >= p.refreshInterval {
+		p.refresh(now)
+	}
+	usage, found := p.usage[nodeName]
+	return usage, found
+}
+
+func (p *MetricsServerUsageProvider) refresh(now time.Time) {
+	p.lastRefresh = now
+	p.usage = nil
+	ctx, cancel := context.WithTimeout(context.Background(), metricsServerTimeout)
+	defer cancel()
+	raw, err := p.client.Get().AbsPath(nodeMetricsPath).DoRaw(ctx)
+	if err != nil {
+		klog.Warningf("Failed to list node metrics, calculating utilization from requests: %v", err)
+		return
+	}
+	var list nodeMetricsList
+	if err := json.Unmarshal(raw, &list); err != nil {
+		klog.Warningf("Failed to decode node metrics, calculating utilization from requests: %v", err)
+		return
+	}
+	p.usage = make(map[string]apiv1.ResourceList, len(list.Items))
+	for _, item := range list.Items {
+		p.usage[item.Name] = item.Usage
+	}
+}
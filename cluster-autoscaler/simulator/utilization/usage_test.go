@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utilization
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsServerUsageProvider(t *testing.T) {
+	var requests, fail atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != nodeMetricsPath || fail.Load() > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"NodeMetricsList","apiVersion":"metrics.k8s.io/v1beta1","items":[
+			{"metadata":{"name":"node1"},"timestamp":"2023-10-02T12:00:00Z","window":"20s","usage":{"cpu":"1500m","memory":"2Gi"}}]}`))
+	}))
+	defer server.Close()
+	client, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	assert.NoError(t, err)
+
+	now := time.Date(2023, time.October, 2, 12, 0, 0, 0, time.UTC)
+	provider := NewMetricsServerUsageProvider(client.Discovery().RESTClient(), time.Minute)
+	provider.now = func() time.Time { return now }
+
+	usage, found := provider.NodeUsage("node1")
+	assert.True(t, found)
+	assert.Equal(t, apiv1.ResourceList{apiv1.ResourceCPU: resource.MustParse("1500m"), apiv1.ResourceMemory: resource.MustParse("2Gi")}, usage)
+	_, found = provider.NodeUsage("node2")
+	assert.False(t, found)
+	assert.Equal(t, int32(1), requests.Load())
+
+	// Usage is refreshed once per refresh interval, and is unknown if listing fails.
+	fail.Store(1)
+	now = now.Add(59 * time.Second)
+	_, found = provider.NodeUsage("node1")
+	assert.True(t, found)
+	assert.Equal(t, int32(1), requests.Load())
+	now = now.Add(time.Second)
+	_, found = provider.NodeUsage("node1")
+	assert.False(t, found)
+	assert.Equal(t, int32(2), requests.Load())
+}
@@ -34,3 +34,21 @@ type Backoff interface {
 	// RemoveStaleBackoffData removes stale backoff data.
 	RemoveStaleBackoffData(currentTime time.Time)
 }
+
+// Entry is the backoff of a node group, as identified by the key of the
+// Backoff implementation, e.g. the node group id.
+type Entry struct {
+	Key                 string        `json:"key"`
+	Duration            time.Duration `json:"duration"`
+	BackoffUntil        time.Time     `json:"backoffUntil"`
+	LastFailedExecution time.Time     `json:"lastFailedExecution"`
+}
+
+// Persistable is implemented by Backoffs whose state can be saved and
+// restored, e.g. across restarts of Cluster Autoscaler.
+type Persistable interface {
+	// Entries returns the backoffs of all node groups.
+	Entries() []Entry
+	// Restore adds the backoffs, replacing the ones of the same keys.
+	Restore(entries []Entry)
+}
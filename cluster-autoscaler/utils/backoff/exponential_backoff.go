@@ -17,6 +17,7 @@ limitations under the License.
 package backoff
 
 import (
+	"sort"
 	"time"
 
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
@@ -110,3 +111,31 @@ func (b *exponentialBackoff) RemoveStaleBackoffData(currentTime time.Time) {
 		}
 	}
 }
+
+// Entries returns the backoffs of all node groups, sorted by key.
+func (b *exponentialBackoff) Entries() []Entry {
+	entries := make([]Entry, 0, len(b.backoffInfo))
+	for key, backoffInfo := range b.backoffInfo {
+		entries = append(entries, Entry{
+			Key:                 key,
+			Duration:            backoffInfo.duration,
+			BackoffUntil:        backoffInfo.backoffUntil,
+			LastFailedExecution: backoffInfo.lastFailedExecution,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// Restore adds the backoffs, replacing the ones of the same keys.
+func (b *exponentialBackoff) Restore(entries []Entry) {
+	for _, entry := range entries {
+		b.backoffInfo[entry.Key] = exponentialBackoffInfo{
+			duration:            entry.Duration,
+			backoffUntil:        entry.BackoffUntil,
+			lastFailedExecution: entry.LastFailedExecution,
+		}
+	}
+}
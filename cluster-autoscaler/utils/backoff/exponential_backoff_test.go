@@ -101,3 +101,20 @@ func TestIncreaseExistingBackoff(t *testing.T) {
 	assert.False(t, backoff.IsBackedOff(nodeGroup1, nil, time.Now()))
 	// Result: existing backoff duration was scaled up beyond initial duration
 }
+
+func TestRestoreBackoff(t *testing.T) {
+	backoff := NewIdBasedExponentialBackoff(1*time.Minute, 10*time.Minute, 3*time.Hour)
+	startTime := time.Now()
+	backoff.Backoff(nodeGroup1, nil, cloudprovider.OtherErrorClass, "", startTime)
+	backoff.Backoff(nodeGroup1, nil, cloudprovider.OtherErrorClass, "", startTime.Add(2*time.Minute))
+	entries := backoff.(Persistable).Entries()
+	assert.Equal(t, []Entry{{Key: "id1", Duration: 2 * time.Minute, BackoffUntil: startTime.Add(4 * time.Minute), LastFailedExecution: startTime.Add(2 * time.Minute)}}, entries)
+
+	restored := NewIdBasedExponentialBackoff(1*time.Minute, 10*time.Minute, 3*time.Hour)
+	restored.(Persistable).Restore(entries)
+	assert.True(t, restored.IsBackedOff(nodeGroup1, nil, startTime.Add(3*time.Minute)))
+	assert.False(t, restored.IsBackedOff(nodeGroup2, nil, startTime.Add(3*time.Minute)))
+	// The next failure keeps doubling the restored backoff.
+	backoffUntil := restored.Backoff(nodeGroup1, nil, cloudprovider.OtherErrorClass, "", startTime.Add(5*time.Minute))
+	assert.Equal(t, startTime.Add(9*time.Minute), backoffUntil)
+}
@@ -22,6 +22,8 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1appslister "k8s.io/client-go/listers/apps/v1"
 	"k8s.io/kubernetes/pkg/controller/daemon"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 )
@@ -30,6 +32,9 @@ const (
 	// EnableDsEvictionKey is the name of annotation controlling whether a
 	// certain DaemonSet pod should be evicted.
 	EnableDsEvictionKey = "cluster-autoscaler.kubernetes.io/enable-ds-eviction"
+	// EvictDaemonSetPodKey is the name of annotation controlling whether pods
+	// of a certain DaemonSet should be evicted. It is set on the DaemonSet.
+	EvictDaemonSetPodKey = "cluster-autoscaler.kubernetes.io/evict-daemonset-pod"
 )
 
 // GetDaemonSetPodsForNode returns daemonset nodes for the given pod.
@@ -47,10 +52,13 @@ func GetDaemonSetPodsForNode(nodeInfo *schedulerframework.NodeInfo, daemonsets [
 }
 
 // PodsToEvict returns a list of DaemonSet pods that should be evicted during scale down.
-func PodsToEvict(pods []*apiv1.Pod, evictByDefault bool) (evictable []*apiv1.Pod) {
+// Annotations of pods take precedence over annotations of their DaemonSets, which
+// take precedence over evictByDefault. DaemonSets are looked up only if dsLister
+// is not nil.
+func PodsToEvict(pods []*apiv1.Pod, dsLister v1appslister.DaemonSetLister, evictByDefault bool) (evictable []*apiv1.Pod) {
 	for _, pod := range pods {
-		if a, ok := pod.Annotations[EnableDsEvictionKey]; ok {
-			if a == "true" {
+		if evict, found := EvictionAnnotation(pod, dsLister); found {
+			if evict {
 				evictable = append(evictable, pod)
 			}
 		} else if evictByDefault {
@@ -59,3 +67,27 @@ func PodsToEvict(pods []*apiv1.Pod, evictByDefault bool) (evictable []*apiv1.Pod
 	}
 	return
 }
+
+// EvictionAnnotation tells whether eviction of the DaemonSet pod is enabled or
+// disabled with an annotation of the pod or, if dsLister is not nil, of its
+// DaemonSet. If neither of them is annotated, found is false.
+func EvictionAnnotation(pod *apiv1.Pod, dsLister v1appslister.DaemonSetLister) (evict, found bool) {
+	if a, ok := pod.Annotations[EnableDsEvictionKey]; ok {
+		return a == "true", true
+	}
+	if dsLister == nil {
+		return false, false
+	}
+	controllerRef := metav1.GetControllerOf(pod)
+	if controllerRef == nil || controllerRef.Kind != "DaemonSet" {
+		return false, false
+	}
+	ds, err := dsLister.DaemonSets(pod.Namespace).Get(controllerRef.Name)
+	if err != nil {
+		return false, false
+	}
+	if a, ok := ds.Annotations[EvictDaemonSetPodKey]; ok {
+		return a == "true", true
+	}
+	return false, false
+}
@@ -27,6 +27,8 @@ import (
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1appslister "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
 	schedulerframework "k8s.io/kubernetes/pkg/scheduler/framework"
 
 	"github.com/stretchr/testify/assert"
@@ -131,7 +133,7 @@ func TestEvictedPodsFilter(t *testing.T) {
 				}
 				dsPods = append(dsPods, p)
 			}
-			pte := PodsToEvict(dsPods, tc.evictionDefault)
+			pte := PodsToEvict(dsPods, nil, tc.evictionDefault)
 			got := make([]string, len(pte))
 			for i, p := range pte {
 				got[i] = p.Name
@@ -141,6 +143,45 @@ func TestEvictedPodsFilter(t *testing.T) {
 	}
 }
 
+func TestPodsToEvictDaemonSetAnnotation(t *testing.T) {
+	dsIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for name, value := range map[string]string{"ds-evict": "true", "ds-keep": "false", "ds-default": ""} {
+		ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: map[string]string{}}}
+		if value != "" {
+			ds.Annotations[EvictDaemonSetPodKey] = value
+		}
+		assert.NoError(t, dsIndexer.Add(ds))
+	}
+	dsLister := v1appslister.NewDaemonSetLister(dsIndexer)
+
+	testCases := []struct {
+		name            string
+		ds              string
+		podAnnotation   string
+		dsLister        v1appslister.DaemonSetLister
+		evictionDefault bool
+		wantEvicted     bool
+	}{
+		{name: "DaemonSet opts in", ds: "ds-evict", dsLister: dsLister, wantEvicted: true},
+		{name: "DaemonSet opts out", ds: "ds-keep", dsLister: dsLister, evictionDefault: true},
+		{name: "DaemonSet not annotated", ds: "ds-default", dsLister: dsLister, evictionDefault: true, wantEvicted: true},
+		{name: "DaemonSet missing", ds: "ds-missing", dsLister: dsLister, evictionDefault: true, wantEvicted: true},
+		{name: "pod annotation takes precedence", ds: "ds-keep", podAnnotation: "true", dsLister: dsLister, wantEvicted: true},
+		{name: "DaemonSet annotation ignored without lister", ds: "ds-keep", evictionDefault: true, wantEvicted: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := BuildTestPod("p", 100, 0)
+			pod.OwnerReferences = GenerateOwnerReferences(tc.ds, "DaemonSet", "apps/v1", "")
+			if tc.podAnnotation != "" {
+				pod.Annotations[EnableDsEvictionKey] = tc.podAnnotation
+			}
+			evicted := PodsToEvict([]*apiv1.Pod{pod}, tc.dsLister, tc.evictionDefault)
+			assert.Equal(t, tc.wantEvicted, len(evicted) == 1)
+		})
+	}
+}
+
 func newDaemonSet(name, cpu, memory string, selector map[string]string) *appsv1.DaemonSet {
 	return &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package intern
+
+import (
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultMaxEntries is the default number of strings kept by an Interner.
+	DefaultMaxEntries = 1 << 20
+	// maxLength is the length of the longest string interned. Longer strings
+	// are rarely shared, e.g. annotation values, and aren't worth keeping.
+	maxLength = 256
+)
+
+// Interner deduplicates strings repeated across objects, so that equal labels,
+// namespaces or image names of objects decoded separately share the same
+// memory. Objects are interned in place, so they must be owned by the caller,
+// e.g. in an informer transform before they are stored. It is safe for
+// concurrent use.
+type Interner struct {
+	maxEntries int
+
+	mutex   sync.Mutex
+	strings map[string]string
+}
+
+// NewInterner creates a new Interner keeping at most maxEntries strings. Once
+// the limit is reached the strings are forgotten, so that strings of deleted
+// objects don't accumulate. Strings interned before still share memory.
+func NewInterner(maxEntries int) *Interner {
+	return &Interner{
+		maxEntries: maxEntries,
+		strings:    make(map[string]string),
+	}
+}
+
+// String returns a string equal to s, shared with previous calls if possible.
+func (i *Interner) String(s string) string {
+	if s == "" || len(s) > maxLength {
+		return s
+	}
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if interned, found := i.strings[s]; found {
+		return interned
+	}
+	if len(i.strings) >= i.maxEntries {
+		i.strings = make(map[string]string)
+	}
+	i.strings[s] = s
+	return s
+}
+
+// Len returns the number of strings kept by the interner.
+func (i *Interner) Len() int {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return len(i.strings)
+}
+
+// Pod interns the namespace, labels, annotation keys, owners, container names
+// and images and scheduling related strings of the pod in place.
+func (i *Interner) Pod(pod *apiv1.Pod) {
+	if pod == nil {
+		return
+	}
+	pod.Namespace = i.String(pod.Namespace)
+	pod.GenerateName = i.String(pod.GenerateName)
+	pod.Labels = i.stringMap(pod.Labels)
+	pod.Annotations = i.keys(pod.Annotations)
+	for j := range pod.OwnerReferences {
+		pod.OwnerReferences[j].APIVersion = i.String(pod.OwnerReferences[j].APIVersion)
+		pod.OwnerReferences[j].Kind = i.String(pod.OwnerReferences[j].Kind)
+		pod.OwnerReferences[j].Name = i.String(pod.OwnerReferences[j].Name)
+	}
+	pod.Spec.NodeName = i.String(pod.Spec.NodeName)
+	pod.Spec.SchedulerName = i.String(pod.Spec.SchedulerName)
+	pod.Spec.ServiceAccountName = i.String(pod.Spec.ServiceAccountName)
+	pod.Spec.PriorityClassName = i.String(pod.Spec.PriorityClassName)
+	pod.Spec.NodeSelector = i.stringMap(pod.Spec.NodeSelector)
+	i.containers(pod.Spec.InitContainers)
+	i.containers(pod.Spec.Containers)
+	for j := range pod.Spec.Tolerations {
+		pod.Spec.Tolerations[j].Key = i.String(pod.Spec.Tolerations[j].Key)
+		pod.Spec.Tolerations[j].Value = i.String(pod.Spec.Tolerations[j].Value)
+	}
+	pod.Status.HostIP = i.String(pod.Status.HostIP)
+	for j := range pod.Status.ContainerStatuses {
+		pod.Status.ContainerStatuses[j].Name = i.String(pod.Status.ContainerStatuses[j].Name)
+		pod.Status.ContainerStatuses[j].Image = i.String(pod.Status.ContainerStatuses[j].Image)
+		pod.Status.ContainerStatuses[j].ImageID = i.String(pod.Status.ContainerStatuses[j].ImageID)
+	}
+}
+
+// Node interns the labels, annotation keys, taints and image names of the
+// node in place.
+func (i *Interner) Node(node *apiv1.Node) {
+	if node == nil {
+		return
+	}
+	node.Labels = i.stringMap(node.Labels)
+	node.Annotations = i.keys(node.Annotations)
+	for j := range node.Spec.Taints {
+		node.Spec.Taints[j].Key = i.String(node.Spec.Taints[j].Key)
+		node.Spec.Taints[j].Value = i.String(node.Spec.Taints[j].Value)
+	}
+	for j := range node.Status.Images {
+		for k := range node.Status.Images[j].Names {
+			node.Status.Images[j].Names[k] = i.String(node.Status.Images[j].Names[k])
+		}
+	}
+}
+
+// Transform interns pods and nodes in place, and returns other objects as
+// they are. It can be used as an informer transform.
+func (i *Interner) Transform(obj interface{}) (interface{}, error) {
+	switch o := obj.(type) {
+	case *apiv1.Pod:
+		i.Pod(o)
+	case *apiv1.Node:
+		i.Node(o)
+	}
+	return obj, nil
+}
+
+func (i *Interner) containers(containers []apiv1.Container) {
+	for j := range containers {
+		containers[j].Name = i.String(containers[j].Name)
+		containers[j].Image = i.String(containers[j].Image)
+	}
+}
+
+// stringMap returns a copy of the map with interned keys and values, as map
+// keys can't be replaced in place.
+func (i *Interner) stringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	interned := make(map[string]string, len(m))
+	for k, v := range m {
+		interned[i.String(k)] = i.String(v)
+	}
+	return interned
+}
+
+// keys returns a copy of the map with interned keys.
+func (i *Interner) keys(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	interned := make(map[string]string, len(m))
+	for k, v := range m {
+		interned[i.String(k)] = v
+	}
+	return interned
+}
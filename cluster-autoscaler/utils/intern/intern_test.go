@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package intern
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestString(t *testing.T) {
+	interner := NewInterner(2)
+	a := interner.String(strings.Clone("app"))
+	b := interner.String(strings.Clone("app"))
+	assert.Equal(t, "app", b)
+	assert.True(t, sameMemory(a, b))
+	assert.Equal(t, 1, interner.Len())
+
+	long := strings.Repeat("x", maxLength+1)
+	assert.False(t, sameMemory(long, interner.String(strings.Clone(long))))
+	assert.Equal(t, "", interner.String(""))
+	assert.Equal(t, 1, interner.Len())
+
+	interner.String("web")
+	assert.Equal(t, 2, interner.Len())
+	// The interner forgets its strings once it's full.
+	interner.String("db")
+	assert.Equal(t, 1, interner.Len())
+	assert.False(t, sameMemory(a, interner.String(strings.Clone("app"))))
+}
+
+func TestPod(t *testing.T) {
+	interner := NewInterner(DefaultMaxEntries)
+	p1, p2 := decodedPod(t, "p1"), decodedPod(t, "p2")
+	assert.False(t, sameMemory(p1.Spec.Containers[0].Image, p2.Spec.Containers[0].Image))
+
+	interner.Pod(p1)
+	interner.Pod(p2)
+	assert.Equal(t, testPod("p2"), p2)
+	assert.True(t, sameMemory(p1.Namespace, p2.Namespace))
+	assert.True(t, sameMemory(p1.Labels["app"], p2.Labels["app"]))
+	assert.True(t, sameMemory(p1.OwnerReferences[0].Name, p2.OwnerReferences[0].Name))
+	assert.True(t, sameMemory(p1.Spec.NodeName, p2.Spec.NodeName))
+	assert.True(t, sameMemory(p1.Spec.Containers[0].Image, p2.Spec.Containers[0].Image))
+	assert.True(t, sameMemory(p1.Spec.Tolerations[0].Key, p2.Spec.Tolerations[0].Key))
+	assert.False(t, sameMemory(p1.Annotations["note"], p2.Annotations["note"]))
+	interner.Pod(nil)
+}
+
+func TestNode(t *testing.T) {
+	interner := NewInterner(DefaultMaxEntries)
+	n1, n2 := decodedNode(t, "n1"), decodedNode(t, "n2")
+
+	interner.Node(n1)
+	interner.Node(n2)
+	assert.Equal(t, testNode("n2"), n2)
+	assert.True(t, sameMemory(n1.Labels["zone"], n2.Labels["zone"]))
+	assert.True(t, sameMemory(n1.Spec.Taints[0].Value, n2.Spec.Taints[0].Value))
+	assert.True(t, sameMemory(n1.Status.Images[0].Names[0], n2.Status.Images[0].Names[0]))
+	interner.Node(nil)
+}
+
+func TestTransform(t *testing.T) {
+	interner := NewInterner(DefaultMaxEntries)
+	pod := decodedPod(t, "p1")
+	obj, err := interner.Transform(pod)
+	assert.NoError(t, err)
+	assert.Same(t, pod, obj)
+	assert.True(t, sameMemory(pod.Namespace, interner.String(strings.Clone(pod.Namespace))))
+
+	node := decodedNode(t, "n1")
+	obj, err = interner.Transform(node)
+	assert.NoError(t, err)
+	assert.Same(t, node, obj)
+	assert.True(t, sameMemory(node.Labels["zone"], interner.String(strings.Clone("zone-a"))))
+
+	cm := &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}}
+	obj, err = interner.Transform(cm)
+	assert.NoError(t, err)
+	assert.Same(t, cm, obj)
+}
+
+// BenchmarkDecodedPodsMemory compares the heap used by pods decoded from
+// protobuf separately, like by an informer, with and without interning them.
+func BenchmarkDecodedPodsMemory(b *testing.B) {
+	const podCount = 100000
+	data, err := testPod("p").Marshal()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, withInterner := range []bool{false, true} {
+		b.Run(fmt.Sprintf("interned=%v", withInterner), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				interner := NewInterner(DefaultMaxEntries)
+				before := heapAlloc()
+				pods := make([]*apiv1.Pod, podCount)
+				for i := range pods {
+					pod := &apiv1.Pod{}
+					if err := pod.Unmarshal(data); err != nil {
+						b.Fatal(err)
+					}
+					if withInterner {
+						interner.Pod(pod)
+					}
+					pods[i] = pod
+				}
+				b.ReportMetric(float64(heapAlloc()-before)/podCount, "heap-bytes/pod")
+				runtime.KeepAlive(pods)
+			}
+		})
+	}
+}
+
+func heapAlloc() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func sameMemory(a, b string) bool {
+	return len(a) == len(b) && unsafe.StringData(a) == unsafe.StringData(b)
+}
+
+func decodedPod(t *testing.T, name string) *apiv1.Pod {
+	data, err := testPod(name).Marshal()
+	assert.NoError(t, err)
+	pod := &apiv1.Pod{}
+	assert.NoError(t, pod.Unmarshal(data))
+	return pod
+}
+
+func decodedNode(t *testing.T, name string) *apiv1.Node {
+	data, err := testNode(name).Marshal()
+	assert.NoError(t, err)
+	node := &apiv1.Node{}
+	assert.NoError(t, node.Unmarshal(data))
+	return node
+}
+
+func testPod(name string) *apiv1.Pod {
+	return &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "web", "pod-template-hash": "5d8f7c9b4"},
+			Annotations: map[string]string{"note": "a note"},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "web-5d8f7c9b4"},
+			},
+		},
+		Spec: apiv1.PodSpec{
+			NodeName:           "node-1",
+			SchedulerName:      "default-scheduler",
+			ServiceAccountName: "default",
+			Containers: []apiv1.Container{
+				{Name: "web", Image: "registry.k8s.io/nginx:1.25"},
+				{Name: "sidecar", Image: "registry.k8s.io/proxy:2.0"},
+			},
+			Tolerations: []apiv1.Toleration{
+				{Key: "node.kubernetes.io/not-ready", Operator: apiv1.TolerationOpExists, Effect: apiv1.TaintEffectNoExecute},
+			},
+		},
+	}
+}
+
+func testNode(name string) *apiv1.Node {
+	return &apiv1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"zone": "zone-a", "kubernetes.io/hostname": name},
+		},
+		Spec: apiv1.NodeSpec{
+			Taints: []apiv1.Taint{{Key: "dedicated", Value: "web", Effect: apiv1.TaintEffectNoSchedule}},
+		},
+		Status: apiv1.NodeStatus{
+			Images: []apiv1.ContainerImage{{Names: []string{"registry.k8s.io/nginx:1.25"}, SizeBytes: 1000}},
+		},
+	}
+}
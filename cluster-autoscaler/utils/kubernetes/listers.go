@@ -23,6 +23,7 @@ import (
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability"
 	"k8s.io/client-go/informers"
 	client "k8s.io/client-go/kubernetes"
 	v1appslister "k8s.io/client-go/listers/apps/v1"
@@ -30,7 +31,6 @@ import (
 	v1lister "k8s.io/client-go/listers/core/v1"
 	v1policylister "k8s.io/client-go/listers/policy/v1"
 	"k8s.io/client-go/tools/cache"
-	podv1 "k8s.io/kubernetes/pkg/api/v1/pod"
 )
 
 // ListerRegistry is a registry providing various listers to list pods or nodes matching conditions
@@ -44,6 +44,8 @@ type ListerRegistry interface {
 	JobLister() v1batchlister.JobLister
 	ReplicaSetLister() v1appslister.ReplicaSetLister
 	StatefulSetLister() v1appslister.StatefulSetLister
+	NamespaceLister() v1lister.NamespaceLister
+	DeploymentLister() v1appslister.DeploymentLister
 }
 
 type listerRegistryImpl struct {
@@ -56,6 +58,8 @@ type listerRegistryImpl struct {
 	jobLister                   v1batchlister.JobLister
 	replicaSetLister            v1appslister.ReplicaSetLister
 	statefulSetLister           v1appslister.StatefulSetLister
+	namespaceLister             v1lister.NamespaceLister
+	deploymentLister            v1appslister.DeploymentLister
 }
 
 // NewListerRegistry returns a registry providing various listers to list pods or nodes matching conditions
@@ -76,6 +80,25 @@ func NewListerRegistry(allNode NodeLister, readyNode NodeLister, allPodLister Po
 	}
 }
 
+// NewListerRegistryWithWorkloadListers returns a registry like NewListerRegistry,
+// which additionally provides listers of namespaces and deployments.
+func NewListerRegistryWithWorkloadListers(registry ListerRegistry, namespaceLister v1lister.NamespaceLister,
+	deploymentLister v1appslister.DeploymentLister) ListerRegistry {
+	return listerRegistryImpl{
+		allNodeLister:               registry.AllNodeLister(),
+		readyNodeLister:             registry.ReadyNodeLister(),
+		allPodLister:                registry.AllPodLister(),
+		podDisruptionBudgetLister:   registry.PodDisruptionBudgetLister(),
+		daemonSetLister:             registry.DaemonSetLister(),
+		replicationControllerLister: registry.ReplicationControllerLister(),
+		jobLister:                   registry.JobLister(),
+		replicaSetLister:            registry.ReplicaSetLister(),
+		statefulSetLister:           registry.StatefulSetLister(),
+		namespaceLister:             namespaceLister,
+		deploymentLister:            deploymentLister,
+	}
+}
+
 // NewListerRegistryWithDefaultListers returns a registry filled with listers of the default implementations
 func NewListerRegistryWithDefaultListers(informerFactory informers.SharedInformerFactory) ListerRegistry {
 	allPodLister := NewAllPodLister(informerFactory.Core().V1().Pods().Lister())
@@ -88,9 +111,11 @@ func NewListerRegistryWithDefaultListers(informerFactory informers.SharedInforme
 	jobLister := informerFactory.Batch().V1().Jobs().Lister()
 	replicaSetLister := informerFactory.Apps().V1().ReplicaSets().Lister()
 	statefulSetLister := informerFactory.Apps().V1().StatefulSets().Lister()
-	return NewListerRegistry(allNodeLister, readyNodeLister, allPodLister,
+	namespaceLister := informerFactory.Core().V1().Namespaces().Lister()
+	deploymentLister := informerFactory.Apps().V1().Deployments().Lister()
+	return NewListerRegistryWithWorkloadListers(NewListerRegistry(allNodeLister, readyNodeLister, allPodLister,
 		podDisruptionBudgetLister, daemonSetLister, replicationControllerLister,
-		jobLister, replicaSetLister, statefulSetLister)
+		jobLister, replicaSetLister, statefulSetLister), namespaceLister, deploymentLister)
 }
 
 // AllPodLister returns the AllPodLister registered to this registry
@@ -138,11 +163,19 @@ func (r listerRegistryImpl) StatefulSetLister() v1appslister.StatefulSetLister {
 	return r.statefulSetLister
 }
 
+// NamespaceLister returns the namespaceLister registered to this registry
+func (r listerRegistryImpl) NamespaceLister() v1lister.NamespaceLister {
+	return r.namespaceLister
+}
+
+// DeploymentLister returns the deploymentLister registered to this registry
+func (r listerRegistryImpl) DeploymentLister() v1appslister.DeploymentLister {
+	return r.deploymentLister
+}
+
 // PodLister lists all pods.
 // To filter out the scheduled or unschedulable pods the helper methods ScheduledPods and UnschedulablePods should be used.
-type PodLister interface {
-	List() ([]*apiv1.Pod, error)
-}
+type PodLister = drainability.PodLister
 
 // ScheduledPods is a helper method that returns all scheduled pods from given pod list.
 func ScheduledPods(allPods []*apiv1.Pod) []*apiv1.Pod {
@@ -160,12 +193,9 @@ func ScheduledPods(allPods []*apiv1.Pod) []*apiv1.Pod {
 func UnschedulablePods(allPods []*apiv1.Pod) []*apiv1.Pod {
 	var unschedulablePods []*apiv1.Pod
 	for _, pod := range allPods {
-		if pod.Spec.NodeName == "" {
-			_, condition := podv1.GetPodCondition(&pod.Status, apiv1.PodScheduled)
-			if condition != nil && condition.Status == apiv1.ConditionFalse && condition.Reason == apiv1.PodReasonUnschedulable {
-				if pod.GetDeletionTimestamp() == nil {
-					unschedulablePods = append(unschedulablePods, pod)
-				}
+		if pod.Spec.NodeName == "" && !IsSchedulingGated(pod) && isUnschedulable(pod) {
+			if pod.GetDeletionTimestamp() == nil {
+				unschedulablePods = append(unschedulablePods, pod)
 			}
 		}
 	}
@@ -201,10 +231,7 @@ func NewAllPodLister(pl v1lister.PodLister) PodLister {
 }
 
 // NodeLister lists nodes.
-type NodeLister interface {
-	List() ([]*apiv1.Node, error)
-	Get(name string) (*apiv1.Node, error)
-}
+type NodeLister = drainability.NodeLister
 
 // nodeLister implementation.
 type nodeListerImpl struct {
@@ -39,6 +39,11 @@ const (
 	// to indicate nodes that appear Ready in the API, but are treated as
 	// still upcoming due to applied startup taint.
 	StartupNodes NodeNotReadyReason = "cluster-autoscaler.kubernetes.io/startup-taint"
+
+	// ReadinessGatesUnready is a fake identifier used internally by Cluster
+	// Autoscaler to indicate nodes that appear Ready in the API, but are
+	// treated as still upcoming until they pass readiness gates.
+	ReadinessGatesUnready NodeNotReadyReason = "cluster-autoscaler.kubernetes.io/readiness-gates-not-passed"
 )
 
 // IsNodeReadyAndSchedulable returns true if the node is ready and schedulable.
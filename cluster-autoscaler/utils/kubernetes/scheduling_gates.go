@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"sync"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	podv1 "k8s.io/kubernetes/pkg/api/v1/pod"
+)
+
+// IsSchedulingGated tells if the pod has unresolved scheduling gates, so it
+// can't be scheduled and shouldn't trigger scale up.
+func IsSchedulingGated(pod *apiv1.Pod) bool {
+	return len(pod.Spec.SchedulingGates) > 0
+}
+
+// SchedulingGatesWatcher notifies when pods released from their scheduling
+// gates are found unschedulable, so that scale up doesn't wait for the next
+// scan interval. Notifications are batched, so a gang of pods released at
+// once results in a single notification.
+type SchedulingGatesWatcher struct {
+	batchWindow time.Duration
+	released    chan struct{}
+
+	mutex   sync.Mutex
+	ungated map[types.UID]bool
+	pending bool
+}
+
+// NewSchedulingGatesWatcher returns a new SchedulingGatesWatcher, notifying
+// at most once per batchWindow.
+func NewSchedulingGatesWatcher(batchWindow time.Duration) *SchedulingGatesWatcher {
+	return &SchedulingGatesWatcher{
+		batchWindow: batchWindow,
+		released:    make(chan struct{}, 1),
+		ungated:     make(map[types.UID]bool),
+	}
+}
+
+// Watch starts watching pods of the informer.
+func (w *SchedulingGatesWatcher) Watch(informer cache.SharedIndexInformer) error {
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: w.onUpdate,
+		DeleteFunc: w.onDelete,
+	})
+	return err
+}
+
+// Released returns the channel on which notifications are sent. It's nil for
+// a nil watcher, so receiving from it blocks forever.
+func (w *SchedulingGatesWatcher) Released() <-chan struct{} {
+	if w == nil {
+		return nil
+	}
+	return w.released
+}
+
+func (w *SchedulingGatesWatcher) onUpdate(oldObj, newObj interface{}) {
+	oldPod, ok := oldObj.(*apiv1.Pod)
+	if !ok {
+		return
+	}
+	newPod, ok := newObj.(*apiv1.Pod)
+	if !ok {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if newPod.Spec.NodeName != "" || newPod.DeletionTimestamp != nil {
+		delete(w.ungated, newPod.UID)
+		return
+	}
+	if IsSchedulingGated(oldPod) && !IsSchedulingGated(newPod) {
+		w.ungated[newPod.UID] = true
+	}
+	if w.ungated[newPod.UID] && isUnschedulable(newPod) {
+		delete(w.ungated, newPod.UID)
+		w.notify()
+	}
+}
+
+func (w *SchedulingGatesWatcher) onDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if pod, ok := obj.(*apiv1.Pod); ok {
+		w.mutex.Lock()
+		defer w.mutex.Unlock()
+		delete(w.ungated, pod.UID)
+	}
+}
+
+// notify sends a notification after the batch window, unless one is already
+// scheduled. Has to be called with the mutex held.
+func (w *SchedulingGatesWatcher) notify() {
+	if w.pending {
+		return
+	}
+	w.pending = true
+	time.AfterFunc(w.batchWindow, func() {
+		w.mutex.Lock()
+		w.pending = false
+		w.mutex.Unlock()
+		select {
+		case w.released <- struct{}{}:
+		default:
+		}
+	})
+}
+
+func isUnschedulable(pod *apiv1.Pod) bool {
+	_, condition := podv1.GetPodCondition(&pod.Status, apiv1.PodScheduled)
+	return condition != nil && condition.Status == apiv1.ConditionFalse && condition.Reason == apiv1.PodReasonUnschedulable
+}
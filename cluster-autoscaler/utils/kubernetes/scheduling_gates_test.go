@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestUnschedulablePodsSkipsSchedulingGated(t *testing.T) {
+	gated := testUnschedulablePod("gated", true)
+	ungated := testUnschedulablePod("ungated", false)
+	assert.Equal(t, []*apiv1.Pod{ungated}, UnschedulablePods([]*apiv1.Pod{gated, ungated}))
+}
+
+func TestSchedulingGatesWatcher(t *testing.T) {
+	w := NewSchedulingGatesWatcher(10 * time.Millisecond)
+
+	gated := testPendingPod("p1", true)
+	released := testPendingPod("p1", false)
+	// Gates removed, but the scheduler didn't try to schedule the pod yet.
+	w.onUpdate(gated, released)
+	assertNotReleased(t, w)
+
+	unschedulable := testUnschedulablePod("p1", false)
+	w.onUpdate(released, unschedulable)
+	// Another pod of the gang in the same batch.
+	w.onUpdate(testPendingPod("p2", true), testUnschedulablePod("p2", false))
+	assertReleased(t, w)
+	assertNotReleased(t, w)
+
+	// Pods which were never gated don't notify.
+	w.onUpdate(testPendingPod("p3", false), testUnschedulablePod("p3", false))
+	assertNotReleased(t, w)
+
+	// Pods deleted before the scheduler's verdict are forgotten.
+	w.onUpdate(testPendingPod("p4", true), testPendingPod("p4", false))
+	w.onDelete(testPendingPod("p4", false))
+	w.onUpdate(testPendingPod("p4", false), testUnschedulablePod("p4", false))
+	assertNotReleased(t, w)
+}
+
+func TestNilSchedulingGatesWatcher(t *testing.T) {
+	var w *SchedulingGatesWatcher
+	assert.Nil(t, w.Released())
+}
+
+func assertReleased(t *testing.T, w *SchedulingGatesWatcher) {
+	t.Helper()
+	select {
+	case <-w.Released():
+	case <-time.After(time.Second):
+		t.Errorf("Released pods not notified")
+	}
+}
+
+func assertNotReleased(t *testing.T, w *SchedulingGatesWatcher) {
+	t.Helper()
+	select {
+	case <-w.Released():
+		t.Errorf("Unexpected notification")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func testPendingPod(name string, gated bool) *apiv1.Pod {
+	pod := &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)}}
+	if gated {
+		pod.Spec.SchedulingGates = []apiv1.PodSchedulingGate{{Name: "example.com/gang"}}
+	}
+	return pod
+}
+
+func testUnschedulablePod(name string, gated bool) *apiv1.Pod {
+	pod := testPendingPod(name, gated)
+	pod.Status.Conditions = []apiv1.PodCondition{{
+		Type:   apiv1.PodScheduled,
+		Status: apiv1.ConditionFalse,
+		Reason: apiv1.PodReasonUnschedulable,
+	}}
+	return pod
+}
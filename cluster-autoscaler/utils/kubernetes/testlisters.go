@@ -17,31 +17,22 @@ limitations under the License.
 package kubernetes
 
 import (
-	"fmt"
-
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 	v1appslister "k8s.io/client-go/listers/apps/v1"
 	v1batchlister "k8s.io/client-go/listers/batch/v1"
 	v1lister "k8s.io/client-go/listers/core/v1"
-	"k8s.io/client-go/tools/cache"
 )
 
 // TestPodLister is used in tests involving listers
-type TestPodLister struct {
-	pods []*apiv1.Pod
-}
-
-// List returns all pods in test lister.
-func (lister TestPodLister) List() ([]*apiv1.Pod, error) {
-	return lister.pods, nil
-}
+type TestPodLister = test.TestPodLister
 
 // NewTestPodLister returns a lister that returns provided pods
 func NewTestPodLister(pods []*apiv1.Pod) PodLister {
-	return TestPodLister{pods: pods}
+	return test.NewTestPodLister(pods)
 }
 
 // TestPodDisruptionBudgetLister is used in tests involving listers
@@ -60,103 +51,49 @@ func NewTestPodDisruptionBudgetLister(pdbs []*policyv1.PodDisruptionBudget) PodD
 }
 
 // TestNodeLister is used in tests involving listers
-type TestNodeLister struct {
-	nodes []*apiv1.Node
-}
-
-// List returns all nodes in test lister.
-func (l *TestNodeLister) List() ([]*apiv1.Node, error) {
-	return l.nodes, nil
-}
-
-// Get returns node from test lister.
-func (l *TestNodeLister) Get(name string) (*apiv1.Node, error) {
-	for _, node := range l.nodes {
-		if node.Name == name {
-			return node, nil
-		}
-	}
-	return nil, fmt.Errorf("Node %s not found", name)
-}
-
-// SetNodes sets nodes in test lister.
-func (l *TestNodeLister) SetNodes(nodes []*apiv1.Node) {
-	l.nodes = nodes
-}
+type TestNodeLister = test.TestNodeLister
 
 // NewTestNodeLister returns a lister that returns provided nodes
 func NewTestNodeLister(nodes []*apiv1.Node) *TestNodeLister {
-	return &TestNodeLister{nodes: nodes}
+	return test.NewTestNodeLister(nodes)
 }
 
 // NewTestDaemonSetLister returns a lister that returns provided DaemonSets
 func NewTestDaemonSetLister(dss []*appsv1.DaemonSet) (v1appslister.DaemonSetLister, error) {
-	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-	for _, ds := range dss {
-		err := store.Add(ds)
-		if err != nil {
-			return nil, fmt.Errorf("Error adding object to cache: %v", err)
-		}
-	}
-	return v1appslister.NewDaemonSetLister(store), nil
+	return test.NewTestDaemonSetLister(dss)
 }
 
 // NewTestReplicationControllerLister returns a lister that returns provided ReplicationControllers
 func NewTestReplicationControllerLister(rcs []*apiv1.ReplicationController) (v1lister.ReplicationControllerLister, error) {
-	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-	for _, rc := range rcs {
-		err := store.Add(rc)
-		if err != nil {
-			return nil, fmt.Errorf("Error adding object to cache: %v", err)
-		}
-	}
-	return v1lister.NewReplicationControllerLister(store), nil
+	return test.NewTestReplicationControllerLister(rcs)
 }
 
 // NewTestJobLister returns a lister that returns provided Jobs
 func NewTestJobLister(jobs []*batchv1.Job) (v1batchlister.JobLister, error) {
-	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-	for _, job := range jobs {
-		err := store.Add(job)
-		if err != nil {
-			return nil, fmt.Errorf("Error adding object to cache: %v", err)
-		}
-	}
-	return v1batchlister.NewJobLister(store), nil
+	return test.NewTestJobLister(jobs)
 }
 
 // NewTestReplicaSetLister returns a lister that returns provided ReplicaSets
 func NewTestReplicaSetLister(rss []*appsv1.ReplicaSet) (v1appslister.ReplicaSetLister, error) {
-	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-	for _, rs := range rss {
-		err := store.Add(rs)
-		if err != nil {
-			return nil, fmt.Errorf("Error adding object to cache: %v", err)
-		}
-	}
-	return v1appslister.NewReplicaSetLister(store), nil
+	return test.NewTestReplicaSetLister(rss)
 }
 
 // NewTestStatefulSetLister returns a lister that returns provided StatefulSets
 func NewTestStatefulSetLister(sss []*appsv1.StatefulSet) (v1appslister.StatefulSetLister, error) {
-	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-	for _, ss := range sss {
-		err := store.Add(ss)
-		if err != nil {
-			return nil, fmt.Errorf("Error adding object to cache: %v", err)
-		}
-	}
-	return v1appslister.NewStatefulSetLister(store), nil
+	return test.NewTestStatefulSetLister(sss)
+}
+
+// NewTestDeploymentLister returns a lister that returns provided Deployments
+func NewTestDeploymentLister(deployments []*appsv1.Deployment) (v1appslister.DeploymentLister, error) {
+	return test.NewTestDeploymentLister(deployments)
+}
+
+// NewTestNamespaceLister returns a lister that returns provided Namespaces
+func NewTestNamespaceLister(namespaces []*apiv1.Namespace) (v1lister.NamespaceLister, error) {
+	return test.NewTestNamespaceLister(namespaces)
 }
 
 // NewTestConfigMapLister returns a lister that returns provided ConfigMaps
 func NewTestConfigMapLister(cms []*apiv1.ConfigMap) (v1lister.ConfigMapLister, error) {
-	store := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
-	for _, cm := range cms {
-		err := store.Add(cm)
-		if err != nil {
-			return nil, fmt.Errorf("Error adding object to cache: %v", err)
-		}
-	}
-	return v1lister.NewConfigMapLister(store), nil
+	return test.NewTestConfigMapLister(cms)
 }
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package persistentstate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	kube_errors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
+	kube_client "k8s.io/client-go/kubernetes"
+)
+
+// StateKey is the key of the ConfigMap data holding the state.
+const StateKey = "state"
+
+// State is the state of Cluster Autoscaler which has to survive its restarts.
+type State struct {
+	// NodeDeletions are the nodes tainted and being deleted by scale down.
+	NodeDeletions []NodeDeletion `json:"nodeDeletions,omitempty"`
+	// NodeGroupBackoffs are the scale-up backoffs of node groups.
+	NodeGroupBackoffs []backoff.Entry `json:"nodeGroupBackoffs,omitempty"`
+}
+
+// NodeDeletion is a node being deleted by scale down.
+type NodeDeletion struct {
+	Node string `json:"node"`
+	// Since is when the deletion was first saved.
+	Since time.Time `json:"since"`
+}
+
+// Store saves the State in a ConfigMap. It isn't safe for concurrent use.
+type Store struct {
+	kubeClient kube_client.Interface
+	namespace  string
+	name       string
+	// lastData is the data last loaded or saved, so that unchanged state isn't
+	// written again.
+	lastData string
+}
+
+// NewStore creates a new Store saving the State in the named ConfigMap.
+func NewStore(kubeClient kube_client.Interface, namespace, name string) *Store {
+	return &Store{
+		kubeClient: kubeClient,
+		namespace:  namespace,
+		name:       name,
+	}
+}
+
+// Load returns the saved State, or an empty one if there is none.
+func (s *Store) Load() (*State, error) {
+	configMap, err := s.kubeClient.CoreV1().ConfigMaps(s.namespace).Get(context.TODO(), s.name, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state configmap %s/%s: %v", s.namespace, s.name, err)
+	}
+	data, found := configMap.Data[StateKey]
+	if !found {
+		return &State{}, nil
+	}
+	state := &State{}
+	if err := json.Unmarshal([]byte(data), state); err != nil {
+		return nil, fmt.Errorf("failed to parse state configmap %s/%s: %v", s.namespace, s.name, err)
+	}
+	s.lastData = data
+	return state, nil
+}
+
+// Save saves the State, unless it didn't change since it was last loaded or
+// saved.
+func (s *Store) Save(state *State) error {
+	bytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	data := string(bytes)
+	if data == s.lastData {
+		return nil
+	}
+	maps := s.kubeClient.CoreV1().ConfigMaps(s.namespace)
+	configMap, err := maps.Get(context.TODO(), s.name, metav1.GetOptions{})
+	if kube_errors.IsNotFound(err) {
+		configMap = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.namespace,
+				Name:      s.name,
+			},
+			Data: map[string]string{StateKey: data},
+		}
+		_, err = maps.Create(context.TODO(), configMap, metav1.CreateOptions{})
+	} else if err == nil {
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[StateKey] = data
+		_, err = maps.Update(context.TODO(), configMap, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write state configmap %s/%s: %v", s.namespace, s.name, err)
+	}
+	s.lastData = data
+	return nil
+}
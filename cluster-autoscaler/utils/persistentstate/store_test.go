@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package persistentstate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/autoscaler/cluster-autoscaler/utils/backoff"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStore(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	store := NewStore(client, "kube-system", "cluster-autoscaler-state")
+
+	state, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, &State{}, state)
+
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := &State{
+		NodeDeletions:     []NodeDeletion{{Node: "n1", Since: now}},
+		NodeGroupBackoffs: []backoff.Entry{{Key: "ng1", Duration: time.Minute, BackoffUntil: now.Add(time.Minute), LastFailedExecution: now}},
+	}
+	assert.NoError(t, store.Save(want))
+	state, err = NewStore(client, "kube-system", "cluster-autoscaler-state").Load()
+	assert.NoError(t, err)
+	assert.Equal(t, want, state)
+
+	// Unchanged state isn't written again.
+	actions := len(client.Actions())
+	assert.NoError(t, store.Save(want))
+	assert.Equal(t, actions, len(client.Actions()))
+
+	// Other data of the configmap is kept.
+	configMap, err := client.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), "cluster-autoscaler-state", metav1.GetOptions{})
+	assert.NoError(t, err)
+	configMap.Data["other"] = "value"
+	_, err = client.CoreV1().ConfigMaps("kube-system").Update(context.TODO(), configMap, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save(&State{}))
+	configMap, err = client.CoreV1().ConfigMaps("kube-system").Get(context.TODO(), "cluster-autoscaler-state", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{StateKey: "{}", "other": "value"}, configMap.Data)
+}
+
+func TestLoadInvalidState(t *testing.T) {
+	client := fake.NewSimpleClientset(&apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "cluster-autoscaler-state"},
+		Data:       map[string]string{StateKey: "{"},
+	})
+	_, err := NewStore(client, "kube-system", "cluster-autoscaler-state").Load()
+	assert.Error(t, err)
+}
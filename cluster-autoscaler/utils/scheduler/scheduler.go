@@ -80,9 +80,11 @@ func isHugePageResourceName(name apiv1.ResourceName) bool {
 
 // DeepCopyTemplateNode copies NodeInfo object used as a template. It changes
 // names of UIDs of both node and pods running on it, so that copies can be used
-// to represent multiple nodes.
+// to represent multiple nodes. To save memory when many copies are made, the
+// image list of the node and the specs of the pods are shared with the template
+// and mustn't be modified in place.
 func DeepCopyTemplateNode(nodeTemplate *schedulerframework.NodeInfo, suffix string) *schedulerframework.NodeInfo {
-	node := nodeTemplate.Node().DeepCopy()
+	node := copyTemplateNode(nodeTemplate.Node())
 	node.Name = fmt.Sprintf("%s-%s", node.Name, suffix)
 	node.UID = uuid.NewUUID()
 	if node.Labels == nil {
@@ -92,7 +94,7 @@ func DeepCopyTemplateNode(nodeTemplate *schedulerframework.NodeInfo, suffix stri
 	nodeInfo := schedulerframework.NewNodeInfo()
 	nodeInfo.SetNode(node)
 	for _, podInfo := range nodeTemplate.Pods {
-		pod := podInfo.Pod.DeepCopy()
+		pod := copyTemplatePod(podInfo.Pod)
 		pod.Name = fmt.Sprintf("%s-%s", podInfo.Pod.Name, suffix)
 		pod.UID = uuid.NewUUID()
 		nodeInfo.AddPod(pod)
@@ -100,6 +102,27 @@ func DeepCopyTemplateNode(nodeTemplate *schedulerframework.NodeInfo, suffix stri
 	return nodeInfo
 }
 
+// copyTemplateNode deep copies the node, except for its image list.
+func copyTemplateNode(template *apiv1.Node) *apiv1.Node {
+	// The images are the biggest part of the node, and nothing modifies them.
+	images := template.Status.Images
+	withoutImages := *template
+	withoutImages.Status.Images = nil
+	node := withoutImages.DeepCopy()
+	node.Status.Images = images
+	return node
+}
+
+// copyTemplatePod deep copies the pod, except for its spec.
+func copyTemplatePod(template *apiv1.Pod) *apiv1.Pod {
+	return &apiv1.Pod{
+		TypeMeta:   template.TypeMeta,
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       template.Spec,
+		Status:     *template.Status.DeepCopy(),
+	}
+}
+
 // ResourceToResourceList returns a resource list of the resource.
 func ResourceToResourceList(r *schedulerframework.Resource) apiv1.ResourceList {
 	result := apiv1.ResourceList{
@@ -189,3 +189,69 @@ func TestConfigFromPath(t *testing.T) {
 
 	}
 }
+
+func TestDeepCopyTemplateNode(t *testing.T) {
+	node := BuildTestNode("template", 1000, 1000)
+	node.Status.Images = []apiv1.ContainerImage{{Names: []string{"registry.k8s.io/pause:3.9"}, SizeBytes: 100}}
+	pod := BuildTestPod("p1", 100, 100)
+	pod.Spec.NodeName = node.Name
+	template := schedulerframework.NewNodeInfo(pod)
+	template.SetNode(node)
+
+	nodeInfo := DeepCopyTemplateNode(template, "copy")
+
+	assert.Equal(t, "template-copy", nodeInfo.Node().Name)
+	assert.Equal(t, "template-copy", nodeInfo.Node().Labels["kubernetes.io/hostname"])
+	assert.NotEqual(t, node.UID, nodeInfo.Node().UID)
+	assert.Equal(t, node.Status.Images, nodeInfo.Node().Status.Images)
+	assert.Equal(t, node.Status.Capacity, nodeInfo.Node().Status.Capacity)
+	assert.Len(t, nodeInfo.Pods, 1)
+	copied := nodeInfo.Pods[0].Pod
+	assert.Equal(t, "p1-copy", copied.Name)
+	assert.NotEqual(t, pod.UID, copied.UID)
+	assert.Equal(t, pod.Spec, copied.Spec)
+
+	// The template isn't modified through the copies.
+	assert.Equal(t, "template", node.Name)
+	assert.Empty(t, node.Labels["kubernetes.io/hostname"])
+	assert.Equal(t, "p1", pod.Name)
+	copied.Labels = map[string]string{"copy": "true"}
+	nodeInfo.Node().Status.Capacity[apiv1.ResourceCPU] = resource.MustParse("2")
+	assert.Empty(t, pod.Labels["copy"])
+	assert.Equal(t, int64(1000), node.Status.Capacity.Cpu().MilliValue())
+}
+
+// BenchmarkDeepCopyTemplateNode compares copying a template node with the
+// pod specs and node images shared, to deep copying it entirely.
+func BenchmarkDeepCopyTemplateNode(b *testing.B) {
+	node := BuildTestNode("template", 1000, 1000)
+	for i := 0; i < 50; i++ {
+		node.Status.Images = append(node.Status.Images, apiv1.ContainerImage{Names: []string{fmt.Sprintf("registry.k8s.io/image-%d:1.0", i)}, SizeBytes: 100})
+	}
+	var pods []*apiv1.Pod
+	for i := 0; i < 10; i++ {
+		pod := BuildTestPod(fmt.Sprintf("ds-%d", i), 100, 100)
+		pod.Spec.Volumes = []apiv1.Volume{{Name: "config"}, {Name: "logs"}}
+		pod.Spec.Containers[0].Env = []apiv1.EnvVar{{Name: "NODE_NAME"}, {Name: "POD_NAME"}}
+		pods = append(pods, pod)
+	}
+	template := schedulerframework.NewNodeInfo(pods...)
+	template.SetNode(node)
+
+	b.Run("DeepCopyTemplateNode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			DeepCopyTemplateNode(template, fmt.Sprintf("copy-%d", i))
+		}
+	})
+	b.Run("deep copy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			nodeInfo := schedulerframework.NewNodeInfo()
+			nodeInfo.SetNode(template.Node().DeepCopy())
+			for _, podInfo := range template.Pods {
+				nodeInfo.AddPod(podInfo.Pod.DeepCopy())
+			}
+		}
+	})
+}
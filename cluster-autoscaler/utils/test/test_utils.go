@@ -29,44 +29,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/autoscaler/cluster-autoscaler/cloudprovider"
-	kube_types "k8s.io/kubernetes/pkg/kubelet/types"
+	drainabilitytest "k8s.io/autoscaler/cluster-autoscaler/simulator/drainability/utils/test"
 )
 
 // BuildTestPod creates a pod with specified resources.
 func BuildTestPod(name string, cpu int64, mem int64, options ...func(*apiv1.Pod)) *apiv1.Pod {
-	startTime := metav1.Unix(0, 0)
-	pod := &apiv1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			UID:         types.UID(name),
-			Namespace:   "default",
-			Name:        name,
-			SelfLink:    fmt.Sprintf("/api/v1/namespaces/default/pods/%s", name),
-			Annotations: map[string]string{},
-		},
-		Spec: apiv1.PodSpec{
-			Containers: []apiv1.Container{
-				{
-					Resources: apiv1.ResourceRequirements{
-						Requests: apiv1.ResourceList{},
-					},
-				},
-			},
-		},
-		Status: apiv1.PodStatus{
-			StartTime: &startTime,
-		},
-	}
-
-	if cpu >= 0 {
-		pod.Spec.Containers[0].Resources.Requests[apiv1.ResourceCPU] = *resource.NewMilliQuantity(cpu, resource.DecimalSI)
-	}
-	if mem >= 0 {
-		pod.Spec.Containers[0].Resources.Requests[apiv1.ResourceMemory] = *resource.NewQuantity(mem, resource.DecimalSI)
-	}
-	for _, o := range options {
-		o(pod)
-	}
-	return pod
+	return drainabilitytest.BuildTestPod(name, cpu, mem, options...)
 }
 
 // MarkUnschedulable marks pod as unschedulable.
@@ -131,33 +99,27 @@ func BuildTestPodWithEphemeralStorage(name string, cpu, mem, ephemeralStorage in
 
 // BuildScheduledTestPod builds a scheduled test pod with a given spec
 func BuildScheduledTestPod(name string, cpu, memory int64, nodeName string) *apiv1.Pod {
-	p := BuildTestPod(name, cpu, memory)
-	p.Spec.NodeName = nodeName
-	return p
+	return drainabilitytest.BuildScheduledTestPod(name, cpu, memory, nodeName)
 }
 
 // SetStaticPodSpec sets pod spec to make it a static pod
 func SetStaticPodSpec(pod *apiv1.Pod) *apiv1.Pod {
-	pod.Annotations[kube_types.ConfigSourceAnnotationKey] = kube_types.FileSource
-	return pod
+	return drainabilitytest.SetStaticPodSpec(pod)
 }
 
 // SetMirrorPodSpec sets pod spec to make it a mirror pod
 func SetMirrorPodSpec(pod *apiv1.Pod) *apiv1.Pod {
-	pod.ObjectMeta.Annotations[kube_types.ConfigMirrorAnnotationKey] = "mirror"
-	return pod
+	return drainabilitytest.SetMirrorPodSpec(pod)
 }
 
 // SetDSPodSpec sets pod spec to make it a DS pod
 func SetDSPodSpec(pod *apiv1.Pod) *apiv1.Pod {
-	pod.OwnerReferences = GenerateOwnerReferences("ds", "DaemonSet", "apps/v1", "api/v1/namespaces/default/daemonsets/ds")
-	return pod
+	return drainabilitytest.SetDSPodSpec(pod)
 }
 
 // SetRSPodSpec sets pod spec to make it a RS pod
 func SetRSPodSpec(pod *apiv1.Pod, rsName string) *apiv1.Pod {
-	pod.OwnerReferences = GenerateOwnerReferences(rsName, "ReplicaSet", "extensions/v1beta1", types.UID(rsName))
-	return pod
+	return drainabilitytest.SetRSPodSpec(pod, rsName)
 }
 
 // BuildServiceTokenProjectedVolumeSource returns a ProjectedVolumeSource with SA token
@@ -201,35 +163,7 @@ func TolerateGpuForPod(pod *apiv1.Pod) {
 
 // BuildTestNode creates a node with specified capacity.
 func BuildTestNode(name string, millicpu int64, mem int64) *apiv1.Node {
-	node := &apiv1.Node{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:     name,
-			SelfLink: fmt.Sprintf("/api/v1/nodes/%s", name),
-			Labels:   map[string]string{},
-		},
-		Spec: apiv1.NodeSpec{
-			ProviderID: name,
-		},
-		Status: apiv1.NodeStatus{
-			Capacity: apiv1.ResourceList{
-				apiv1.ResourcePods: *resource.NewQuantity(100, resource.DecimalSI),
-			},
-		},
-	}
-
-	if millicpu >= 0 {
-		node.Status.Capacity[apiv1.ResourceCPU] = *resource.NewMilliQuantity(millicpu, resource.DecimalSI)
-	}
-	if mem >= 0 {
-		node.Status.Capacity[apiv1.ResourceMemory] = *resource.NewQuantity(mem, resource.DecimalSI)
-	}
-
-	node.Status.Allocatable = apiv1.ResourceList{}
-	for k, v := range node.Status.Capacity {
-		node.Status.Allocatable[k] = v
-	}
-
-	return node
+	return drainabilitytest.BuildTestNode(name, millicpu, mem)
 }
 
 // AddEphemeralStorageToNode adds ephemeral storage capacity to a given node.
@@ -330,21 +264,7 @@ func SetNodeCondition(node *apiv1.Node, conditionType apiv1.NodeConditionType, s
 
 // GenerateOwnerReferences builds OwnerReferences with a single reference
 func GenerateOwnerReferences(name, kind, api string, uid types.UID) []metav1.OwnerReference {
-	return []metav1.OwnerReference{
-		{
-			APIVersion:         api,
-			Kind:               kind,
-			Name:               name,
-			BlockOwnerDeletion: boolptr(true),
-			Controller:         boolptr(true),
-			UID:                uid,
-		},
-	}
-}
-
-func boolptr(val bool) *bool {
-	b := val
-	return &b
+	return drainabilitytest.GenerateOwnerReferences(name, kind, api, uid)
 }
 
 // HttpServerMock mocks server HTTP.
@@ -73,7 +73,12 @@ func sanitizePodSpec(podSpec apiv1.PodSpec) apiv1.PodSpec {
 	return podSpec
 }
 
+// dropProjectedVolumesAndMounts modifies the spec, which must be a copy, but
+// the containers are shared with the original and are copied before dropping
+// their mounts.
 func dropProjectedVolumesAndMounts(podSpec *apiv1.PodSpec) {
+	podSpec.Containers = append([]apiv1.Container(nil), podSpec.Containers...)
+	podSpec.InitContainers = append([]apiv1.Container(nil), podSpec.InitContainers...)
 	projectedVolumeNames := map[string]bool{}
 	var volumes []apiv1.Volume
 	for _, v := range podSpec.Volumes {
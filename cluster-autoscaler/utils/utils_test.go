@@ -184,8 +184,10 @@ func TestSanitizePodSpec(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			input := tt.inputPodSpec.DeepCopy()
 			got := sanitizePodSpec(tt.inputPodSpec)
 			assert.True(t, assert.ObjectsAreEqualValues(tt.outputPodSpec, got), "\ngot: %#v\nwant: %#v", got, tt.outputPodSpec)
+			assert.Equal(t, input, &tt.inputPodSpec, "input pod spec was modified")
 		})
 	}
 }